@@ -18,24 +18,61 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/orders": {
-            "get": {
-                "description": "Returns list of all orders",
+        "/clients/erase": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Anonymize every order (including deleted and archived ones) placed from the given phone. Non-personal fields are kept for statistics; the returned audit record holds only order IDs. Admin only",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "orders"
+                    "clients"
+                ],
+                "summary": "Erase client personal data",
+                "parameters": [
+                    {
+                        "description": "Client to erase",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.EraseRequest"
+                        }
+                    }
                 ],
-                "summary": "Get all orders",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/orders.Order"
-                            }
+                            "$ref": "#/definitions/orders.Erasure"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "500": {
@@ -46,35 +83,39 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "Create order with PrimaryOrder payload",
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/employees/{id}/route": {
+            "get": {
+                "description": "Returns the employee's scheduled orders for the date in an optimized visiting order (nearest neighbour + 2-opt over the geocoded addresses) with estimated travel times between stops. Visits scheduled at a specific time must be reached within the arrival window; orders without coordinates are listed in unrouted",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "orders"
+                    "employees"
                 ],
-                "summary": "Create a new order",
+                "summary": "Get an employee's route for a day",
                 "parameters": [
                     {
-                        "description": "Primary order payload",
-                        "name": "order",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/orders.PrimaryOrder"
-                        }
+                        "type": "integer",
+                        "description": "Employee ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Date, YYYY-MM-DD",
+                        "name": "date",
+                        "in": "query",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "new order UUID",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/orders.Route"
                         }
                     },
                     "400": {
@@ -94,31 +135,32 @@ const docTemplate = `{
                 }
             }
         },
-        "/orders/{id}": {
+        "/inventory/events": {
             "get": {
-                "description": "Get single order by UUID",
+                "description": "Low stock alerts in the order they were raised; pass the last seen id as after_id to get the next page",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "orders"
+                    "inventory"
                 ],
-                "summary": "Get order by ID",
+                "summary": "List inventory events",
                 "parameters": [
                     {
-                        "type": "string",
-                        "format": "uuid",
-                        "description": "Order ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "type": "integer",
+                        "description": "Return events with a greater id",
+                        "name": "after_id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/orders.Order"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/inventory.Event"
+                            }
                         }
                     },
                     "400": {
@@ -138,36 +180,46 @@ const docTemplate = `{
                 }
             }
         },
-        "/orders/{id}/assign/{empID}": {
-            "patch": {
-                "description": "Assign employee by numeric ID to an order",
+        "/orders": {
+            "get": {
+                "description": "Returns list of all orders. Deleted orders are omitted unless include_deleted is set; archived orders are available only by ID. With overdue=true only orders that breached their SLA in the current status are returned; template_id selects orders generated from a recurring order template",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "orders"
                 ],
-                "summary": "Assign employee to order",
+                "summary": "Get all orders",
                 "parameters": [
                     {
-                        "type": "string",
-                        "format": "uuid",
-                        "description": "Order ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "type": "boolean",
+                        "description": "Include soft-deleted orders",
+                        "name": "include_deleted",
+                        "in": "query"
                     },
                     {
-                        "type": "integer",
-                        "description": "Employee ID",
-                        "name": "empID",
-                        "in": "path",
-                        "required": true
+                        "type": "boolean",
+                        "description": "Only orders that breached their SLA in the current status",
+                        "name": "overdue",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Only orders generated from this recurring order template",
+                        "name": "template_id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK"
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/orders.Order"
+                            }
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
@@ -184,11 +236,9 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/orders/{id}/cancel": {
-            "patch": {
-                "description": "Cancel with a reason",
+            },
+            "post": {
+                "description": "Create order from the public form. Requests are rate limited per IP and per phone; suspected spam is created in the Quarantined status",
                 "consumes": [
                     "application/json"
                 ],
@@ -198,29 +248,24 @@ const docTemplate = `{
                 "tags": [
                     "orders"
                 ],
-                "summary": "Cancel an order",
+                "summary": "Create a new order",
                 "parameters": [
                     {
-                        "type": "string",
-                        "format": "uuid",
-                        "description": "Order ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Cancel payload",
-                        "name": "body",
+                        "description": "Primary order payload",
+                        "name": "order",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.CancelRequest"
+                            "$ref": "#/definitions/handlers.CreateOrderRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK"
+                    "201": {
+                        "description": "new order UUID",
+                        "schema": {
+                            "type": "string"
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
@@ -229,6 +274,20 @@ const docTemplate = `{
                             "additionalProperties": true
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -239,28 +298,55 @@ const docTemplate = `{
                 }
             }
         },
-        "/orders/{id}/close": {
-            "patch": {
+        "/orders/export": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Streams the order list with the same filters as GET /orders. CSV is UTF-8 with BOM; times are in the order time zone",
                 "produces": [
-                    "application/json"
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
                 ],
                 "tags": [
                     "orders"
                 ],
-                "summary": "Close an order",
+                "summary": "Export orders to CSV or XLSX",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "csv (default) or xlsx",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include soft-deleted orders",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only orders that breached their SLA in the current status",
+                        "name": "overdue",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
                         "format": "uuid",
-                        "description": "Order ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Only orders generated from this recurring order template",
+                        "name": "template_id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK"
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
@@ -269,6 +355,20 @@ const docTemplate = `{
                             "additionalProperties": true
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -279,28 +379,45 @@ const docTemplate = `{
                 }
             }
         },
-        "/orders/{id}/complete": {
-            "patch": {
+        "/orders/import": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Each row of the first sheet is validated as a new order and created unless dry_run is set. The first row is the header; columns are named as in the export: client_name, client_phone, address (one line) or city/street/house/apartment/entrance/floor/intercom, client_description, category, timezone, date (preliminary work date, YYYY-MM-DD or DD.MM.YYYY). Rows with the same phone, address and date as an existing order or a row above are reported as duplicates. CSV may use comma or semicolon",
+                "consumes": [
+                    "multipart/form-data"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "orders"
                 ],
-                "summary": "Mark order as completed",
+                "summary": "Import orders from CSV or XLSX",
                 "parameters": [
                     {
-                        "type": "string",
-                        "format": "uuid",
-                        "description": "Order ID",
-                        "name": "id",
-                        "in": "path",
+                        "type": "file",
+                        "description": "CSV or XLSX file",
+                        "name": "file",
+                        "in": "formData",
                         "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only validate rows, do not create orders",
+                        "name": "dry_run",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK"
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/spreadsheets.ImportReport"
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
@@ -309,6 +426,27 @@ const docTemplate = `{
                             "additionalProperties": true
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -319,41 +457,46 @@ const docTemplate = `{
                 }
             }
         },
-        "/orders/{id}/preschedule": {
-            "patch": {
-                "description": "Set or update a provisional scheduled time for the order",
-                "consumes": [
-                    "application/json"
-                ],
+        "/orders/stream": {
+            "get": {
+                "description": "Server-Sent Events stream of order changes. Supports resuming via Last-Event-ID header.\nAn event may be committed after events with greater IDs, so a resumed stream repeats\nup to 100 events before Last-Event-ID; clients should skip events with IDs they already have",
                 "produces": [
-                    "application/json"
+                    "text/event-stream"
                 ],
                 "tags": [
                     "orders"
                 ],
-                "summary": "Preschedule an order (provisional scheduling)",
+                "summary": "Stream order changes",
                 "parameters": [
                     {
-                        "type": "string",
-                        "format": "uuid",
-                        "description": "Order ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Order statuses to include",
+                        "name": "status",
+                        "in": "query"
                     },
                     {
-                        "description": "Preschedule payload",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.PrescheduleRequest"
-                        }
+                        "type": "integer",
+                        "description": "Employee ID to filter by",
+                        "name": "employee_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Resume after this event ID (alternative to Last-Event-ID header)",
+                        "name": "last_event_id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK"
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/orders.Event"
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
@@ -372,19 +515,16 @@ const docTemplate = `{
                 }
             }
         },
-        "/orders/{id}/progress": {
-            "patch": {
-                "description": "Attach employee progress/notes to an order",
-                "consumes": [
-                    "application/json"
-                ],
+        "/orders/{id}": {
+            "get": {
+                "description": "Get single order by UUID, including archived orders",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "orders"
                 ],
-                "summary": "Report progress for an order",
+                "summary": "Get order by ID",
                 "parameters": [
                     {
                         "type": "string",
@@ -393,15 +533,53 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/orders.Order"
+                        }
                     },
-                    {
-                        "description": "Progress payload",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ProgressRequest"
+                            "type": "object",
+                            "additionalProperties": true
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Soft-delete an order: it disappears from listings but stays in the database. Admin only",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Delete an order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -415,6 +593,27 @@ const docTemplate = `{
                             "additionalProperties": true
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -425,19 +624,16 @@ const docTemplate = `{
                 }
             }
         },
-        "/orders/{id}/schedule": {
+        "/orders/{id}/assign/{empID}": {
             "patch": {
-                "description": "Set the final scheduled time for an order",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Assign employee by numeric ID to an order",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "orders"
                 ],
-                "summary": "Schedule an order (final scheduling)",
+                "summary": "Assign employee to order",
                 "parameters": [
                     {
                         "type": "string",
@@ -448,13 +644,11 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Schedule payload",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.PrescheduleRequest"
-                        }
+                        "type": "integer",
+                        "description": "Employee ID",
+                        "name": "empID",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -477,62 +671,2519 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "auth.Employee": {
-            "type": "object",
-            "properties": {
-                "id": {
-                    "type": "integer"
-                },
-                "name": {
-                    "type": "string"
-                }
-            }
-        },
-        "handlers.CancelRequest": {
-            "type": "object",
-            "properties": {
-                "cancel_reason": {
-                    "type": "string"
-                }
-            }
         },
-        "handlers.PrescheduleRequest": {
-            "type": "object",
-            "properties": {
-                "scheduled_for": {
-                    "type": "string"
+        "/orders/{id}/attachments": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Admin only",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "attachments"
+                ],
+                "summary": "List order attachments",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/attachments.Attachment"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
                 }
-            }
-        },
-        "handlers.ProgressRequest": {
-            "type": "object",
-            "properties": {
-                "employee_description": {
-                    "type": "string"
+            },
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Attach a photo (before/after works) or a document (e.g. a signed act) to an order in status Scheduled, InProgress or Done. The type is detected from the content; images get a JPEG thumbnail. Admin only",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "attachments"
+                ],
+                "summary": "Upload an attachment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Attachment file",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/attachments.Attachment"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
                 }
             }
         },
-        "orders.Order": {
-            "type": "object",
-            "properties": {
-                "address": {
-                    "type": "string"
-                },
-                "cancel_reason": {
-                    "type": "string"
-                },
-                "client_description": {
-                    "type": "string"
-                },
-                "client_name": {
-                    "type": "string"
-                },
+        "/orders/{id}/attachments/{attID}": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Returns the attachment content, or its JPEG thumbnail with thumbnail=true. The attachment is served only through the order it belongs to. Admin only",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "attachments"
+                ],
+                "summary": "Download an attachment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Attachment ID",
+                        "name": "attID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return the thumbnail instead of the original",
+                        "name": "thumbnail",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{id}/cancel": {
+            "patch": {
+                "description": "Cancel with a reason",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Cancel an order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Cancel payload",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CancelRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{id}/checkin": {
+            "post": {
+                "description": "The assigned employee marks arrival with GPS coordinates (time defaults to now). The current visit starts and the order moves to InProgress. A check-in farther than the configured radius from the geocoded address is flagged with far_from_address",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Check in at the order address",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Check-in payload",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CheckInRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/orders.Visit"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{id}/checkout": {
+            "post": {
+                "description": "The assigned employee marks departure with GPS coordinates and reports the work done. The visit is completed, its duration is added to the order work time",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Check out from the order address",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Check-out payload",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CheckOutRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/orders.Visit"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{id}/close": {
+            "patch": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Close an order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{id}/complete": {
+            "patch": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Mark order as completed",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{id}/line-items": {
+            "get": {
+                "description": "Parts consumed when the order was completed, at the price at that moment, less returns. Parts reported with a catalog sku are consumed automatically",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "inventory"
+                ],
+                "summary": "Get order line items",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/inventory.LineItem"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{id}/parts": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "inventory"
+                ],
+                "summary": "Get the parts needed for an order and their reservation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/inventory.OrderPart"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replaces the list. Parts are reserved when the order is scheduled: from the assigned technician van first, the rest from the warehouse. If the order is already scheduled they are re-reserved at once",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "inventory"
+                ],
+                "summary": "Set the parts needed for an order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Parts",
+                        "name": "parts",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/inventory.OrderPart"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/inventory.OrderPart"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{id}/parts/returns": {
+            "post": {
+                "description": "Puts the parts back into the given location and reduces the order line item",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "inventory"
+                ],
+                "summary": "Return parts consumed by an order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Returned parts",
+                        "name": "return",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/inventory.Return"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{id}/preschedule": {
+            "patch": {
+                "description": "Set or update a provisional scheduled time for the order",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Preschedule an order (provisional scheduling)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Preschedule payload",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PrescheduleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{id}/progress": {
+            "patch": {
+                "description": "Complete the current visit with employee notes, actual arrival/departure (departure defaults to now) and parts used. The order becomes InProgress until the next visit is scheduled",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Report progress for an order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Progress payload",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ProgressRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{id}/release": {
+            "patch": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Accept an order that was held as suspected spam: it becomes a regular new order. Admin only",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Release a quarantined order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{id}/schedule": {
+            "patch": {
+                "description": "Set the final scheduled time for an order",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Schedule an order (final scheduling)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Schedule payload",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PrescheduleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{id}/visits": {
+            "get": {
+                "description": "Returns all visits of an order (including archived orders) in creation order",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Get order visits",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/orders.Visit"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/parts": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "inventory"
+                ],
+                "summary": "List the parts catalog",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/inventory.Part"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "price is per unit, in kopecks. A low_stock event is recorded when the total available stock of the part drops to low_stock_threshold",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "inventory"
+                ],
+                "summary": "Add a part to the catalog",
+                "parameters": [
+                    {
+                        "description": "Part",
+                        "name": "part",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/inventory.PrimaryPart"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/inventory.Part"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/parts/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "inventory"
+                ],
+                "summary": "Get part by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Part ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/inventory.Part"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/recurring-orders": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "recurring-orders"
+                ],
+                "summary": "List recurring order templates",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only templates of the client with this phone",
+                        "name": "client_phone",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/recurring.Template"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a template (e.g. a yearly boiler maintenance contract) from which orders are generated ahead of each occurrence. rrule supports FREQ=MONTHLY|YEARLY with INTERVAL, COUNT and UNTIL; with preschedule=true generated orders are prescheduled for the occurrence day",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "recurring-orders"
+                ],
+                "summary": "Create a recurring order template",
+                "parameters": [
+                    {
+                        "description": "Recurring order template",
+                        "name": "template",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/recurring.PrimaryTemplate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/recurring.Template"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/recurring-orders/{id}": {
+            "get": {
+                "description": "Orders generated from the template are listed by GET /orders?template_id=",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "recurring-orders"
+                ],
+                "summary": "Get recurring order template by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/recurring.Template"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/recurring-orders/{id}/end": {
+            "patch": {
+                "description": "No more orders are generated; already generated orders are kept",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "recurring-orders"
+                ],
+                "summary": "End a recurring order template",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/recurring.Template"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/recurring-orders/{id}/pause": {
+            "patch": {
+                "description": "No orders are generated while the template is paused",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "recurring-orders"
+                ],
+                "summary": "Pause a recurring order template",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/recurring.Template"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/recurring-orders/{id}/resume": {
+            "patch": {
+                "description": "Occurrences that passed while the template was paused are skipped",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "recurring-orders"
+                ],
+                "summary": "Resume a paused recurring order template",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/recurring.Template"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/cancellations": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Orders canceled in the period grouped by cancel reason (lower-cased, extra spaces removed; \"unspecified\" if empty)",
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Cancellation reasons breakdown",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "First day of the period (YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last day of the period, inclusive (YYYY-MM-DD)",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "json (default) or csv",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/reports.Cancellations"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/employees": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Orders completed per employee",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "First day of the period (YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last day of the period, inclusive (YYYY-MM-DD)",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "json (default) or csv",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/reports.EmployeePerformance"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/revenue": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Orders paid in the period, per day of payment, with the cost of parts written off for them at the prices at the time of write-off, in kopecks",
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Revenue",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "First day of the period (YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last day of the period, inclusive (YYYY-MM-DD)",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "json (default) or csv",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/reports.Revenue"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/throughput": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Counts per day of the period (in the company time zone) and totals. Deleted orders are not counted; archived ones are",
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Orders created, completed and canceled",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "First day of the period (YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last day of the period, inclusive (YYYY-MM-DD)",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "json (default) or csv",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/reports.Throughput"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/transitions": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "For every pair of statuses: how many transitions were made in the period and the median time spent in the source status, in seconds",
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Median time per status transition",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "First day of the period (YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last day of the period, inclusive (YYYY-MM-DD)",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "json (default) or csv",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/reports.Transitions"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/stock": {
+            "get": {
+                "description": "Stock per location: a warehouse (\"warehouse:main\") or a technician van (\"van:\u003cemployee id\u003e\"). Reserved parts are included in on_hand but not in available",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "inventory"
+                ],
+                "summary": "List stock levels",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Only this part",
+                        "name": "part_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only this location",
+                        "name": "location",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/inventory.Stock"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/stock/receipts": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "inventory"
+                ],
+                "summary": "Receive parts into a location",
+                "parameters": [
+                    {
+                        "description": "Receipt",
+                        "name": "receipt",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/inventory.Receipt"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/stock/transfers": {
+            "post": {
+                "description": "For example, load parts from the warehouse into a technician van. Only available (not reserved) parts can be moved",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "inventory"
+                ],
+                "summary": "Move parts between locations",
+                "parameters": [
+                    {
+                        "description": "Transfer",
+                        "name": "transfer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/inventory.Transfer"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "attachments.Attachment": {
+            "type": "object",
+            "properties": {
+                "content_type": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "file_name": {
+                    "type": "string"
+                },
+                "has_thumbnail": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "kind": {
+                    "$ref": "#/definitions/attachments.Kind"
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "attachments.Kind": {
+            "type": "string",
+            "enum": [
+                "photo",
+                "document"
+            ],
+            "x-enum-varnames": [
+                "KindPhoto",
+                "KindDocument"
+            ]
+        },
+        "auth.Employee": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.CancelRequest": {
+            "type": "object",
+            "properties": {
+                "cancel_reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.CheckInRequest": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "employee_id": {
+                    "type": "integer"
+                },
+                "lat": {
+                    "type": "number"
+                },
+                "lon": {
+                    "type": "number"
+                }
+            }
+        },
+        "handlers.CheckOutRequest": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "employee_description": {
+                    "type": "string"
+                },
+                "employee_id": {
+                    "type": "integer"
+                },
+                "lat": {
+                    "type": "number"
+                },
+                "lon": {
+                    "type": "number"
+                },
+                "parts_used": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/orders.UsedPart"
+                    }
+                }
+            }
+        },
+        "handlers.CreateOrderRequest": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "$ref": "#/definitions/orders.Address"
+                },
+                "captcha_token": {
+                    "description": "Captcha provider response received by the form",
+                    "type": "string"
+                },
+                "category": {
+                    "description": "Вид работ (например, boiler); от него зависят сроки SLA",
+                    "type": "string"
+                },
+                "client_description": {
+                    "type": "string"
+                },
+                "client_name": {
+                    "type": "string"
+                },
+                "client_phone": {
+                    "type": "string"
+                },
+                "timezone": {
+                    "description": "Часовой пояс клиента (IANA, например Asia/Yekaterinburg); по умолчанию — пояс компании",
+                    "type": "string"
+                },
+                "website": {
+                    "description": "Honeypot: a hidden form field that people leave empty",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.EraseRequest": {
+            "type": "object",
+            "properties": {
+                "client_phone": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.PrescheduleRequest": {
+            "type": "object",
+            "properties": {
+                "scheduled_for": {
+                    "type": "string",
+                    "example": "2026-03-02T09:00:00"
+                }
+            }
+        },
+        "handlers.ProgressRequest": {
+            "type": "object",
+            "properties": {
+                "arrived_at": {
+                    "type": "string"
+                },
+                "departed_at": {
+                    "type": "string"
+                },
+                "employee_description": {
+                    "type": "string"
+                },
+                "parts_used": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/orders.UsedPart"
+                    }
+                }
+            }
+        },
+        "inventory.Event": {
+            "type": "object",
+            "properties": {
+                "available": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "part_id": {
+                    "type": "string"
+                },
+                "sku": {
+                    "type": "string"
+                },
+                "threshold": {
+                    "type": "integer"
+                },
+                "type": {
+                    "$ref": "#/definitions/inventory.EventType"
+                }
+            }
+        },
+        "inventory.EventType": {
+            "type": "string",
+            "enum": [
+                "low_stock"
+            ],
+            "x-enum-varnames": [
+                "EventLowStock"
+            ]
+        },
+        "inventory.LineItem": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "part_id": {
+                    "type": "string"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "sku": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "unit_price": {
+                    "type": "integer"
+                }
+            }
+        },
+        "inventory.OrderPart": {
+            "type": "object",
+            "properties": {
+                "part_id": {
+                    "type": "string"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "reserved": {
+                    "description": "Сколько зарезервировано; меньше Quantity, если запчастей не хватило",
+                    "type": "integer"
+                }
+            }
+        },
+        "inventory.Part": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "low_stock_threshold": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "integer"
+                },
+                "sku": {
+                    "type": "string"
+                }
+            }
+        },
+        "inventory.PrimaryPart": {
+            "type": "object",
+            "properties": {
+                "low_stock_threshold": {
+                    "description": "Порог общего доступного остатка, при снижении до которого отправляется оповещение; 0 — без оповещений",
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "price": {
+                    "description": "Цена за единицу в копейках",
+                    "type": "integer"
+                },
+                "sku": {
+                    "type": "string"
+                }
+            }
+        },
+        "inventory.Receipt": {
+            "type": "object",
+            "properties": {
+                "location": {
+                    "type": "string"
+                },
+                "part_id": {
+                    "type": "string"
+                },
+                "quantity": {
+                    "type": "integer"
+                }
+            }
+        },
+        "inventory.Return": {
+            "type": "object",
+            "properties": {
+                "location": {
+                    "type": "string"
+                },
+                "part_id": {
+                    "type": "string"
+                },
+                "quantity": {
+                    "type": "integer"
+                }
+            }
+        },
+        "inventory.Stock": {
+            "type": "object",
+            "properties": {
+                "available": {
+                    "type": "integer"
+                },
+                "location": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "on_hand": {
+                    "type": "integer"
+                },
+                "part_id": {
+                    "type": "string"
+                },
+                "reserved": {
+                    "type": "integer"
+                },
+                "sku": {
+                    "type": "string"
+                }
+            }
+        },
+        "inventory.Transfer": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "part_id": {
+                    "type": "string"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "orders.Address": {
+            "type": "object",
+            "properties": {
+                "apartment": {
+                    "type": "string"
+                },
+                "city": {
+                    "type": "string"
+                },
+                "entrance": {
+                    "type": "string"
+                },
+                "floor": {
+                    "type": "string"
+                },
+                "house": {
+                    "type": "string"
+                },
+                "intercom": {
+                    "type": "string"
+                },
+                "line": {
+                    "description": "Адрес одной строкой: так хранятся заявки, оформленные до появления\nструктурированного адреса, и так его передают старые клиенты API",
+                    "type": "string"
+                },
+                "location": {
+                    "description": "Координаты; nil, пока адрес не геокодирован",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/orders.GeoPoint"
+                        }
+                    ]
+                },
+                "street": {
+                    "type": "string"
+                }
+            }
+        },
+        "orders.Erasure": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "order_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "reason": {
+                    "$ref": "#/definitions/orders.ErasureReason"
+                },
+                "request_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "orders.ErasureReason": {
+            "type": "string",
+            "enum": [
+                "request",
+                "retention"
+            ],
+            "x-enum-varnames": [
+                "ErasureByRequest",
+                "ErasureByRetention"
+            ]
+        },
+        "orders.Event": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "employee_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/orders.Status"
+                },
+                "type": {
+                    "$ref": "#/definitions/orders.EventType"
+                }
+            }
+        },
+        "orders.EventType": {
+            "type": "string",
+            "enum": [
+                "created",
+                "released",
+                "prescheduled",
+                "assigned",
+                "scheduled",
+                "progressed",
+                "checked_in",
+                "checked_out",
+                "completed",
+                "closed",
+                "canceled",
+                "deleted",
+                "sla_breached"
+            ],
+            "x-enum-varnames": [
+                "EventCreated",
+                "EventReleased",
+                "EventPrescheduled",
+                "EventAssigned",
+                "EventScheduled",
+                "EventProgressed",
+                "EventCheckedIn",
+                "EventCheckedOut",
+                "EventCompleted",
+                "EventClosed",
+                "EventCanceled",
+                "EventDeleted",
+                "EventSLABreached"
+            ]
+        },
+        "orders.GeoPoint": {
+            "type": "object",
+            "properties": {
+                "lat": {
+                    "type": "number"
+                },
+                "lon": {
+                    "type": "number"
+                }
+            }
+        },
+        "orders.Order": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "$ref": "#/definitions/orders.Address"
+                },
+                "anonymized_at": {
+                    "type": "string"
+                },
+                "archived_at": {
+                    "type": "string"
+                },
+                "cancel_reason": {
+                    "type": "string"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "client_description": {
+                    "type": "string"
+                },
+                "client_name": {
+                    "type": "string"
+                },
                 "client_phone": {
                     "type": "string"
                 },
+                "deleted_at": {
+                    "description": "Service",
+                    "type": "string"
+                },
                 "employee": {
                     "$ref": "#/definitions/auth.Employee"
                 },
@@ -546,30 +3197,120 @@ const docTemplate = `{
                 "scheduled_for": {
                     "type": "string"
                 },
-                "status": {
-                    "description": "Mutable",
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/orders.Status"
-                        }
-                    ]
+                "status": {
+                    "description": "Mutable",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/orders.Status"
+                        }
+                    ]
+                },
+                "status_changed_at": {
+                    "type": "string"
+                },
+                "template_id": {
+                    "description": "Шаблон повторяющейся заявки, по которому создана заявка",
+                    "type": "string"
+                },
+                "timezone": {
+                    "type": "string"
+                },
+                "work_seconds": {
+                    "description": "Суммарное время работ на месте по визитам с отмеченными прибытием и отъездом",
+                    "type": "integer"
+                }
+            }
+        },
+        "orders.PrimaryOrder": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "$ref": "#/definitions/orders.Address"
+                },
+                "category": {
+                    "description": "Вид работ (например, boiler); от него зависят сроки SLA",
+                    "type": "string"
+                },
+                "client_description": {
+                    "type": "string"
+                },
+                "client_name": {
+                    "type": "string"
+                },
+                "client_phone": {
+                    "type": "string"
+                },
+                "timezone": {
+                    "description": "Часовой пояс клиента (IANA, например Asia/Yekaterinburg); по умолчанию — пояс компании",
+                    "type": "string"
+                }
+            }
+        },
+        "orders.Route": {
+            "type": "object",
+            "properties": {
+                "date": {
+                    "type": "string"
+                },
+                "distance_m": {
+                    "type": "number"
+                },
+                "employee_id": {
+                    "type": "integer"
+                },
+                "late": {
+                    "description": "Сколько визитов не укладывается в окно прибытия",
+                    "type": "integer"
+                },
+                "stops": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/orders.RouteStop"
+                    }
+                },
+                "travel_seconds": {
+                    "type": "integer"
+                },
+                "unrouted": {
+                    "description": "Заявки с негеокодированным адресом: место в маршруте для них не определить",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
-        "orders.PrimaryOrder": {
+        "orders.RouteStop": {
             "type": "object",
             "properties": {
                 "address": {
+                    "$ref": "#/definitions/orders.Address"
+                },
+                "arrival": {
                     "type": "string"
                 },
-                "client_description": {
+                "departure": {
                     "type": "string"
                 },
-                "client_name": {
+                "distance_m": {
+                    "description": "Путь от предыдущей точки маршрута",
+                    "type": "number"
+                },
+                "late": {
+                    "type": "boolean"
+                },
+                "order_id": {
                     "type": "string"
                 },
-                "client_phone": {
+                "scheduled_for": {
                     "type": "string"
+                },
+                "start_at": {
+                    "description": "Начало работ: прибытие или начало окна, если сотрудник приехал раньше",
+                    "type": "string"
+                },
+                "travel_seconds": {
+                    "type": "integer"
                 }
             }
         },
@@ -584,7 +3325,8 @@ const docTemplate = `{
                 4,
                 5,
                 6,
-                -1
+                -1,
+                -2
             ],
             "x-enum-comments": {
                 "StatusAssigned": "\"Назначен сотрудник\"",
@@ -594,6 +3336,7 @@ const docTemplate = `{
                 "StatusNew": "\"Оформлена\"",
                 "StatusPaid": "\"Оплачена\"",
                 "StatusPrescheduled": "\"Назначена предварительная дата работ\"",
+                "StatusQuarantined": "\"На проверке (похожа на спам)\"",
                 "StatusScheduled": "\"Назначены работы\""
             },
             "x-enum-descriptions": [
@@ -604,7 +3347,8 @@ const docTemplate = `{
                 "\"Работы частично проведены\"",
                 "\"Выполнена\"",
                 "\"Оплачена\"",
-                "\"Отменена\""
+                "\"Отменена\"",
+                "\"На проверке (похожа на спам)\""
             ],
             "x-enum-varnames": [
                 "StatusNew",
@@ -614,9 +3358,413 @@ const docTemplate = `{
                 "StatusInProgress",
                 "StatusDone",
                 "StatusPaid",
-                "StatusCanceled"
+                "StatusCanceled",
+                "StatusQuarantined"
+            ]
+        },
+        "orders.UsedPart": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "sku": {
+                    "description": "Артикул по каталогу запчастей; по нему запчасть списывается со склада при выполнении заявки",
+                    "type": "string"
+                }
+            }
+        },
+        "orders.Visit": {
+            "type": "object",
+            "properties": {
+                "arrived_at": {
+                    "type": "string"
+                },
+                "checkin_distance_m": {
+                    "type": "number"
+                },
+                "checkin_location": {
+                    "description": "Отметки сотрудника на месте. Расстояние до адреса заявки известно, если адрес геокодирован",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/orders.GeoPoint"
+                        }
+                    ]
+                },
+                "checkout_distance_m": {
+                    "type": "number"
+                },
+                "checkout_location": {
+                    "$ref": "#/definitions/orders.GeoPoint"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "departed_at": {
+                    "type": "string"
+                },
+                "employee": {
+                    "$ref": "#/definitions/auth.Employee"
+                },
+                "far_from_address": {
+                    "description": "Отметка сделана дальше допустимого радиуса от адреса",
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "parts_used": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/orders.UsedPart"
+                    }
+                },
+                "planned_for": {
+                    "type": "string"
+                }
+            }
+        },
+        "recurring.PrimaryTemplate": {
+            "type": "object",
+            "properties": {
+                "order": {
+                    "$ref": "#/definitions/orders.PrimaryOrder"
+                },
+                "preschedule": {
+                    "description": "Создавать заявки с предварительной датой работ — днём повторения",
+                    "type": "boolean"
+                },
+                "rrule": {
+                    "description": "Правило повторения, например FREQ=YEARLY или FREQ=MONTHLY;INTERVAL=6;COUNT=4",
+                    "type": "string"
+                },
+                "start": {
+                    "description": "Дата первого повторения",
+                    "type": "string",
+                    "format": "date"
+                }
+            }
+        },
+        "recurring.Status": {
+            "type": "string",
+            "enum": [
+                "active",
+                "paused",
+                "ended"
+            ],
+            "x-enum-varnames": [
+                "StatusActive",
+                "StatusPaused",
+                "StatusEnded"
+            ]
+        },
+        "recurring.Template": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "next_on": {
+                    "description": "Дата следующего повторения; nil у завершённого шаблона",
+                    "type": "string",
+                    "format": "date"
+                },
+                "occurrences": {
+                    "description": "Сколько повторений пройдено: по ним созданы заявки или они пропущены на паузе",
+                    "type": "integer"
+                },
+                "order": {
+                    "description": "Клиент, адрес и описание работ для создаваемых заявок",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/orders.PrimaryOrder"
+                        }
+                    ]
+                },
+                "preschedule": {
+                    "type": "boolean"
+                },
+                "rrule": {
+                    "type": "string"
+                },
+                "start": {
+                    "type": "string",
+                    "format": "date"
+                },
+                "status": {
+                    "$ref": "#/definitions/recurring.Status"
+                }
+            }
+        },
+        "reports.Cancellations": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "reasons": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/reports.ReasonCount"
+                    }
+                },
+                "to": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "reports.DayRevenue": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "paid_orders": {
+                    "type": "integer"
+                }
+            }
+        },
+        "reports.DayThroughput": {
+            "type": "object",
+            "properties": {
+                "canceled": {
+                    "type": "integer"
+                },
+                "completed": {
+                    "type": "integer"
+                },
+                "created": {
+                    "type": "integer"
+                },
+                "date": {
+                    "type": "string"
+                }
+            }
+        },
+        "reports.EmployeePerformance": {
+            "type": "object",
+            "properties": {
+                "employees": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/reports.EmployeeStats"
+                    }
+                },
+                "from": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "reports.EmployeeStats": {
+            "type": "object",
+            "properties": {
+                "completed": {
+                    "type": "integer"
+                },
+                "employee_id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "reports.ReasonCount": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "description": "Причина в нижнем регистре без лишних пробелов; UnspecifiedReason, если не указана",
+                    "type": "string"
+                }
+            }
+        },
+        "reports.Revenue": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "days": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/reports.DayRevenue"
+                    }
+                },
+                "from": {
+                    "type": "string"
+                },
+                "paid_orders": {
+                    "type": "integer"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "reports.Throughput": {
+            "type": "object",
+            "properties": {
+                "canceled": {
+                    "type": "integer"
+                },
+                "completed": {
+                    "type": "integer"
+                },
+                "created": {
+                    "type": "integer"
+                },
+                "days": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/reports.DayThroughput"
+                    }
+                },
+                "from": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "reports.Transition": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "from_status": {
+                    "type": "string"
+                },
+                "median_seconds": {
+                    "type": "number"
+                },
+                "to_status": {
+                    "type": "string"
+                }
+            }
+        },
+        "reports.Transitions": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "transitions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/reports.Transition"
+                    }
+                }
+            }
+        },
+        "spreadsheets.ImportReport": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "integer"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "duplicates": {
+                    "type": "integer"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "invalid": {
+                    "type": "integer"
+                },
+                "rows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/spreadsheets.RowResult"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "valid": {
+                    "type": "integer"
+                }
+            }
+        },
+        "spreadsheets.RowResult": {
+            "type": "object",
+            "properties": {
+                "duplicate_of_row": {
+                    "description": "Строка файла, дублем которой является эта",
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "order_id": {
+                    "description": "Созданная заявка или уже существующая заявка-дубль",
+                    "type": "string"
+                },
+                "row": {
+                    "description": "Номер строки в файле (заголовок — строка 1)",
+                    "type": "integer"
+                },
+                "status": {
+                    "$ref": "#/definitions/spreadsheets.RowStatus"
+                }
+            }
+        },
+        "spreadsheets.RowStatus": {
+            "type": "string",
+            "enum": [
+                "created",
+                "valid",
+                "duplicate",
+                "invalid",
+                "failed"
+            ],
+            "x-enum-varnames": [
+                "RowCreated",
+                "RowValid",
+                "RowDuplicate",
+                "RowInvalid",
+                "RowFailed"
             ]
         }
+    },
+    "securityDefinitions": {
+        "AdminToken": {
+            "description": "Bearer token from auth.admin_token",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
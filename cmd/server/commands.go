@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Owouwun/spkuznetsov/internal/app"
+	"github.com/Owouwun/spkuznetsov/internal/config"
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/auth"
+	repository_auth "github.com/Owouwun/spkuznetsov/internal/core/repository/services/auth"
+	repository_orders "github.com/Owouwun/spkuznetsov/internal/core/repository/services/orders"
+	repository_recurring "github.com/Owouwun/spkuznetsov/internal/core/repository/services/recurring"
+	"github.com/Owouwun/spkuznetsov/migrations"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+)
+
+const commandsUsage = `Usage: server [flags] [command]
+
+Commands:
+  serve                  run the HTTP server (default)
+  migrate up             apply all pending migrations
+  migrate down N         roll back the last N migrations
+  migrate goto V         migrate up or down to version V
+  migrate version        print the current schema version
+  migrate force V        set version V without running migrations (clears the dirty state)
+  seed                   load demo data
+  create-employee NAME   create an employee and print its ID
+  rotate-keys            re-encrypt client data with the primary key and rebuild the phone index
+
+`
+
+var errUsage = errors.New("invalid command line")
+
+func usage(w io.Writer) {
+	fmt.Fprint(w, commandsUsage)
+	config.Usage(w)
+}
+
+func usageErrorf(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", errUsage, fmt.Sprintf(format, args...))
+}
+
+// run выполняет команду из аргументов командной строки; без команды запускается сервер
+func run(ctx context.Context, cfg config.Config, args []string) error {
+	if len(args) == 0 {
+		return serve(ctx, cfg)
+	}
+
+	command, params := args[0], args[1:]
+	switch command {
+	case "serve":
+		return serve(ctx, cfg)
+	case "migrate":
+		return migrate(ctx, cfg, params)
+	case "seed":
+		return seed(ctx, cfg)
+	case "create-employee":
+		return createEmployee(ctx, cfg, params)
+	case "rotate-keys":
+		return rotateKeys(ctx, cfg, params)
+	default:
+		return usageErrorf("unknown command %q", command)
+	}
+}
+
+func serve(ctx context.Context, cfg config.Config) error {
+	db, err := app.PrepareDB(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	application, err := app.New(db, cfg)
+	if err != nil {
+		return fmt.Errorf("prepare application: %w", err)
+	}
+
+	if cfg.Features.Swagger {
+		application.Router().GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	}
+
+	return application.Run(ctx, cfg.Server)
+}
+
+func migrate(ctx context.Context, cfg config.Config, params []string) error {
+	// Аргументы проверяются до подключения к БД
+	action, err := parseMigrateAction(params)
+	if err != nil {
+		return err
+	}
+
+	sqlDB, err := app.Connect(ctx, cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = sqlDB.Close()
+	}()
+
+	mg, err := app.NewMigrator(ctx, sqlDB)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = mg.Close()
+	}()
+
+	if err := action(mg); err != nil {
+		return err
+	}
+
+	version, dirty, err := mg.Version()
+	if err != nil {
+		return err
+	}
+	// Слепой индекс телефона строится ключом приложения, поэтому не в SQL-миграциях
+	if !dirty && version >= app.PhoneIndexVersion {
+		if err := backfillPhoneIndex(ctx, cfg, sqlDB); err != nil {
+			return err
+		}
+	}
+	if dirty {
+		fmt.Fprintf(os.Stdout, "version %d (dirty)\n", version)
+	} else {
+		fmt.Fprintf(os.Stdout, "version %d\n", version)
+	}
+	return nil
+}
+
+func parseMigrateAction(params []string) (func(*app.Migrator) error, error) {
+	if len(params) == 0 {
+		return nil, usageErrorf("migrate requires a subcommand: up, down N, goto V, version or force V")
+	}
+
+	action, rest := params[0], params[1:]
+	if (action == "up" || action == "version") && len(rest) > 0 {
+		return nil, usageErrorf("%s takes no arguments", action)
+	}
+
+	switch action {
+	case "up":
+		return (*app.Migrator).Up, nil
+	case "version":
+		return func(*app.Migrator) error { return nil }, nil
+	case "down":
+		steps, err := intParam(action, rest)
+		if err != nil {
+			return nil, err
+		}
+		if steps <= 0 {
+			return nil, usageErrorf("down: number of steps must be positive")
+		}
+		return func(mg *app.Migrator) error { return mg.Down(steps) }, nil
+	case "goto":
+		version, err := intParam(action, rest)
+		if err != nil {
+			return nil, err
+		}
+		if version < 0 {
+			return nil, usageErrorf("goto: version must not be negative")
+		}
+		return func(mg *app.Migrator) error { return mg.Goto(uint(version)) }, nil
+	case "force":
+		version, err := intParam(action, rest)
+		if err != nil {
+			return nil, err
+		}
+		return func(mg *app.Migrator) error { return mg.Force(version) }, nil
+	default:
+		return nil, usageErrorf("unknown migrate subcommand %q", action)
+	}
+}
+
+func intParam(action string, rest []string) (int, error) {
+	if len(rest) != 1 {
+		return 0, usageErrorf("%s requires exactly one numeric argument", action)
+	}
+	n, err := strconv.Atoi(rest[0])
+	if err != nil {
+		return 0, usageErrorf("%s: invalid number %q", action, rest[0])
+	}
+	return n, nil
+}
+
+func seed(ctx context.Context, cfg config.Config) error {
+	sqlDB, err := app.Connect(ctx, cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = sqlDB.Close()
+	}()
+
+	if err := migrations.Seed(ctx, sqlDB); err != nil {
+		return err
+	}
+	return backfillPhoneIndex(ctx, cfg, sqlDB)
+}
+
+func backfillPhoneIndex(ctx context.Context, cfg config.Config, sqlDB *sql.DB) error {
+	db, err := app.OpenGorm(sqlDB, cfg.Log)
+	if err != nil {
+		return err
+	}
+	return app.BackfillPhoneIndex(ctx, db, cfg.Encryption)
+}
+
+func createEmployee(ctx context.Context, cfg config.Config, params []string) error {
+	name := strings.TrimSpace(strings.Join(params, " "))
+	if name == "" {
+		return usageErrorf("create-employee requires a name")
+	}
+
+	sqlDB, err := app.Connect(ctx, cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = sqlDB.Close()
+	}()
+
+	db, err := app.OpenGorm(sqlDB, cfg.Log)
+	if err != nil {
+		return err
+	}
+
+	authService := auth.NewAuthService(repository_auth.NewAuthRepository(db))
+	id, err := authService.CreateEmployee(ctx, name)
+	if err != nil {
+		return fmt.Errorf("create employee: %w", err)
+	}
+
+	slog.InfoContext(ctx, "employee created", slog.Uint64("employee_id", uint64(id)))
+	fmt.Fprintln(os.Stdout, id)
+	return nil
+}
+
+const rotateKeysBatchSize = 500
+
+func rotateKeys(ctx context.Context, cfg config.Config, params []string) error {
+	if len(params) > 0 {
+		return usageErrorf("rotate-keys takes no arguments")
+	}
+	if cfg.Encryption.KeysFile == "" {
+		return usageErrorf("rotate-keys requires encryption.keys_file (ENCRYPTION_KEYS_FILE)")
+	}
+
+	cipher, err := app.NewPIICipher(cfg.Encryption)
+	if err != nil {
+		return err
+	}
+
+	sqlDB, err := app.Connect(ctx, cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = sqlDB.Close()
+	}()
+
+	db, err := app.OpenGorm(sqlDB, cfg.Log)
+	if err != nil {
+		return err
+	}
+
+	repo := repository_orders.NewOrderRepository(db, repository_orders.WithCipher(cipher))
+	updated, err := repo.ReencryptPII(ctx, rotateKeysBatchSize)
+	if err != nil {
+		return fmt.Errorf("re-encrypt client data (%d orders done): %w", updated, err)
+	}
+
+	templateRepo := repository_recurring.NewTemplateRepository(db, repository_recurring.WithCipher(cipher))
+	templates, err := templateRepo.ReencryptPII(ctx, rotateKeysBatchSize)
+	if err != nil {
+		return fmt.Errorf("re-encrypt client data (%d recurring order templates done): %w", templates, err)
+	}
+
+	slog.InfoContext(ctx, "client data re-encrypted", slog.Int("orders", updated), slog.Int("templates", templates))
+	fmt.Fprintln(os.Stdout, updated+templates)
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseMigrateAction(t *testing.T) {
+	cases := []struct {
+		name     string
+		params   []string
+		expUsage bool
+	}{
+		{name: "Применить все миграции", params: []string{"up"}},
+		{name: "Текущая версия", params: []string{"version"}},
+		{name: "Откат на две миграции", params: []string{"down", "2"}},
+		{name: "Переход к версии", params: []string{"goto", "1"}},
+		{name: "Принудительная версия", params: []string{"force", "-1"}},
+		{name: "Без подкоманды", params: nil, expUsage: true},
+		{name: "Неизвестная подкоманда", params: []string{"redo"}, expUsage: true},
+		{name: "Откат без числа шагов", params: []string{"down"}, expUsage: true},
+		{name: "Откат на ноль шагов", params: []string{"down", "0"}, expUsage: true},
+		{name: "Нечисловая версия", params: []string{"goto", "latest"}, expUsage: true},
+		{name: "Отрицательная версия для goto", params: []string{"goto", "-1"}, expUsage: true},
+		{name: "Лишний аргумент", params: []string{"up", "1"}, expUsage: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			action, err := parseMigrateAction(tc.params)
+			if tc.expUsage {
+				if !errors.Is(err, errUsage) {
+					t.Fatalf("expected usage error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if action == nil {
+				t.Fatal("expected action")
+			}
+		})
+	}
+}
@@ -1,12 +1,18 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 
 	_ "github.com/Owouwun/spkuznetsov/cmd/docs"
-	"github.com/Owouwun/spkuznetsov/internal/app"
-	swaggerFiles "github.com/swaggo/files"
-	ginSwagger "github.com/swaggo/gin-swagger"
+	"github.com/Owouwun/spkuznetsov/internal/config"
+	"github.com/Owouwun/spkuznetsov/internal/logging"
 )
 
 // @title           Orders Management Service
@@ -17,14 +23,48 @@ import (
 // @host            localhost:8080
 // @BasePath        /api/v1
 
+// @securityDefinitions.apikey AdminToken
+// @in                         header
+// @name                       Authorization
+// @description                Bearer token from auth.admin_token
+
 func main() {
-	db := app.PrepareDB()
-	router := app.PrepareRouter(db)
+	cfg, opts, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		usage(os.Stdout)
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration:\n%v\n", err)
+		os.Exit(1)
+	}
+
+	if opts.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to print configuration: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(1)
+	}
+	if opts.PrintConfig {
+		return
+	}
+
+	slog.SetDefault(logging.New(os.Stdout, cfg.Log.Level))
 
-	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	log.Println("Starting server on :8080")
-	if err := router.Run(":8080"); err != nil {
-		log.Fatal("Server failed to start:", err)
+	if err := run(ctx, cfg, opts.Args); err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprintf(os.Stderr, "%v\n\n", err)
+			usage(os.Stderr)
+			os.Exit(2)
+		}
+		slog.Error("command failed", slog.Any("error", err))
+		os.Exit(1)
 	}
 }
@@ -2,90 +2,454 @@ package app
 
 import (
 	"context"
-	"log"
-	"os"
+	"fmt"
+	"log/slog"
+	"strings"
 	"time"
 
+	"github.com/Owouwun/spkuznetsov/internal/blobstore"
+	"github.com/Owouwun/spkuznetsov/internal/config"
 	"github.com/Owouwun/spkuznetsov/internal/core/api/handlers"
+	"github.com/Owouwun/spkuznetsov/internal/core/api/middleware"
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/attachments"
 	"github.com/Owouwun/spkuznetsov/internal/core/logic/auth"
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/idempotency"
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/intake"
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/inventory"
 	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/recurring"
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/reports"
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/spreadsheets"
+	"github.com/Owouwun/spkuznetsov/internal/core/repository/entities"
+	repository_attachments "github.com/Owouwun/spkuznetsov/internal/core/repository/services/attachments"
 	repository_auth "github.com/Owouwun/spkuznetsov/internal/core/repository/services/auth"
+	repository_idempotency "github.com/Owouwun/spkuznetsov/internal/core/repository/services/idempotency"
+	repository_inventory "github.com/Owouwun/spkuznetsov/internal/core/repository/services/inventory"
 	repository_orders "github.com/Owouwun/spkuznetsov/internal/core/repository/services/orders"
+	repository_recurring "github.com/Owouwun/spkuznetsov/internal/core/repository/services/recurring"
+	repository_reports "github.com/Owouwun/spkuznetsov/internal/core/repository/services/reports"
+	"github.com/Owouwun/spkuznetsov/internal/geocoding"
+	"github.com/Owouwun/spkuznetsov/internal/metrics"
+	"github.com/Owouwun/spkuznetsov/internal/tracing"
+	"github.com/Owouwun/spkuznetsov/pkg/clock"
+	"github.com/Owouwun/spkuznetsov/pkg/ratelimit"
 	"github.com/gin-gonic/gin"
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-const (
-	dbConnectionTimeout = 30 * time.Second
-)
+type App struct {
+	cfg     config.Config
+	db      *gorm.DB
+	router  *gin.Engine
+	workers *Workers
+	health  *handlers.HealthHandler
+	metrics *metrics.Metrics
+	tracing *tracing.Provider
+	clock   clock.Clock
+	// Шифрование данных клиентов: заявок, шаблонов и сохранённых ответов
+	cipher entities.PIICipher
+
+	// Вызываются в начале остановки сервера, чтобы завершить долгоживущие запросы (SSE)
+	shutdownHooks []func()
+}
+
+func New(db *gorm.DB, cfg config.Config) (*App, error) {
+	if cfg.Log.Level == "debug" {
+		gin.SetMode(gin.DebugMode)
+	} else {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	gin.DebugPrintFunc = func(format string, values ...any) {
+		slog.Debug(strings.TrimSpace(fmt.Sprintf(format, values...)))
+	}
+
+	a := &App{
+		cfg:     cfg,
+		db:      db,
+		router:  gin.New(),
+		workers: NewWorkers(),
+		clock:   clock.System{},
+	}
+	var err error
+	if a.tracing, err = tracing.Setup(context.Background(), cfg.Tracing); err != nil {
+		return nil, err
+	}
+	if a.cipher, err = NewPIICipher(cfg.Encryption); err != nil {
+		return nil, err
+	}
+	if err := tracing.RegisterGorm(db); err != nil {
+		return nil, err
+	}
+
+	// Без доверенных прокси c.ClientIP() — адрес соединения, а не подставленный клиентом X-Forwarded-For
+	if err := a.router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		return nil, err
+	}
+
+	// Контекст запроса (отмена, request_id, спан) доступен через *gin.Context, передаваемый в сервисы
+	a.router.ContextWithFallback = true
+	a.router.Use(middleware.RequestID(), middleware.Tracing(), middleware.Logger(), middleware.Recovery())
+
+	if cfg.Features.Metrics {
+		if err := a.prepareMetrics(); err != nil {
+			return nil, err
+		}
+	}
 
-func PrepareRouter(db *gorm.DB) *gin.Engine {
-	router := gin.Default()
+	if len(cfg.CORS.AllowedOrigins) > 0 {
+		a.router.Use(middleware.CORS(cfg.CORS.AllowedOrigins))
+	}
+
+	if cfg.Idempotency.Enabled {
+		a.prepareIdempotency()
+	}
 
-	prepareOrders(router, db)
-	prepareEmployees(router, db)
+	if err := a.prepareHealth(); err != nil {
+		return nil, err
+	}
+	attachmentService, err := a.prepareAttachments()
+	if err != nil {
+		return nil, err
+	}
+	if err := a.prepareOrders(attachmentService); err != nil {
+		return nil, err
+	}
+	a.prepareEmployees()
+
+	return a, nil
+}
 
-	return router
+func (a *App) Router() *gin.Engine {
+	return a.router
 }
 
-func prepareOrders(router *gin.Engine, db *gorm.DB) {
-	orderRepo := repository_orders.NewOrderRepository(db)
-	orderService := orders.NewOrderService(orderRepo)
-	orderHandler := handlers.NewOrderHandler(orderService)
+func (a *App) prepareMetrics() error {
+	a.metrics = metrics.New()
+	if err := a.metrics.RegisterDB(a.db); err != nil {
+		return err
+	}
+
+	a.router.Use(middleware.Metrics(a.metrics))
+	a.router.GET("/metrics", gin.WrapH(a.metrics.Handler()))
+	return nil
+}
+
+// Повтор изменяющего запроса с тем же Idempotency-Key получает сохранённый ответ.
+// Тело запроса читается целиком, поэтому ограничено наибольшим допустимым файлом с запасом на multipart
+func (a *App) prepareIdempotency() {
+	cfg := a.cfg.Idempotency
+	idempotencyService := idempotency.NewIdempotencyService(
+		repository_idempotency.NewKeyRepository(a.db, repository_idempotency.WithCipher(a.cipher)),
+		idempotency.WithTTL(cfg.TTL),
+		idempotency.WithClock(a.clock),
+	)
+
+	a.workers.Every("idempotency keys cleanup", cfg.Interval, func(ctx context.Context) error {
+		_, err := idempotencyService.Cleanup(ctx, cfg.BatchSize)
+		return err
+	})
+
+	maxBodySize := int64(max(a.cfg.Attachments.MaxSizeMB, a.cfg.Import.MaxSizeMB)+1) << 20
+	a.router.Use(middleware.Idempotency(idempotencyService, maxBodySize))
+}
+
+func (a *App) prepareHealth() error {
+	expectedVersion, err := latestMigrationVersion()
+	if err != nil {
+		return err
+	}
+
+	a.health = handlers.NewHealthHandler(&dbHealthChecker{db: a.db}, expectedVersion)
+
+	a.router.GET("/healthz", a.health.Liveness)
+	a.router.GET("/readyz", a.health.Readiness)
+	return nil
+}
+
+// Вложения заявок удаляются вместе с данными клиента при обезличивании
+func (a *App) prepareOrders(attachmentService *attachments.AttachmentService) error {
+	cipher := a.cipher
+	cal, err := NewCalendar(a.cfg.Calendar)
+	if err != nil {
+		return err
+	}
+	scheduling := orders.Scheduling{Clock: a.clock, Calendar: cal}
+	orderRepo := repository_orders.NewOrderRepository(a.db,
+		repository_orders.WithCipher(cipher),
+		repository_orders.WithScheduling(scheduling),
+	)
+	eventRepo := repository_orders.NewEventRepository(a.db)
+	broker := orders.NewBroker()
+	opts := []orders.OrderServiceOption{
+		orders.WithEventLog(eventRepo),
+		orders.WithTransactor(repository_orders.NewTransactor(a.db)),
+		orders.WithBroker(broker),
+		orders.WithScheduling(scheduling),
+		orders.WithCheckInRadius(a.cfg.Visits.CheckInRadiusM),
+		orders.WithAttachmentEraser(attachmentService),
+		orders.WithRouteSettings(orders.RouteSettings{
+			DayStart:      a.cfg.Routing.DayStart,
+			ArrivalWindow: a.cfg.Routing.ArrivalWindow,
+			VisitDuration: a.cfg.Routing.VisitDuration,
+			SpeedKmh:      a.cfg.Routing.SpeedKmh,
+			DetourFactor:  a.cfg.Routing.DetourFactor,
+		}),
+	}
+	if a.metrics != nil {
+		opts = append(opts, orders.WithMetrics(a.metrics))
+	}
+	geocoder, err := geocoding.New(a.cfg.Geocoding)
+	if err != nil {
+		return err
+	}
+	if geocoder != nil {
+		opts = append(opts, orders.WithGeocoder(geocoder))
+	}
+	if a.cfg.SLA.Enabled {
+		rules, err := orders.ParseSLARules(a.cfg.SLA.Rules)
+		if err != nil {
+			return fmt.Errorf("sla.rules: %w", err)
+		}
+		opts = append(opts, orders.WithSLA(rules))
+	}
+	orderService := orders.NewOrderService(orderRepo, opts...)
+
+	if a.metrics != nil {
+		if err := a.metrics.RegisterOrderStatusGauge(orderService.CountByStatus); err != nil {
+			return err
+		}
+	}
+	orderHandler := handlers.NewOrderHandler(orderService, handlers.WithIntake(a.intakeService(orderService)))
+
+	if archive := a.cfg.Archive; archive.Enabled {
+		a.workers.Every("orders archiver", archive.Interval, func(ctx context.Context) error {
+			_, err := orderService.Archive(ctx, a.clock.Now().AddDate(0, -archive.AfterMonths, 0), archive.BatchSize)
+			return err
+		})
+	}
+	if retention := a.cfg.Retention; retention.Enabled {
+		a.workers.Every("orders retention", retention.Interval, func(ctx context.Context) error {
+			_, err := orderService.ApplyRetention(ctx, a.clock.Now().AddDate(-retention.AfterYears, 0, 0), retention.BatchSize)
+			return err
+		})
+	}
+
+	if sla := a.cfg.SLA; sla.Enabled {
+		a.workers.Every("sla evaluator", sla.Interval, func(ctx context.Context) error {
+			_, err := orderService.EvaluateSLA(ctx, a.clock.Now())
+			return err
+		})
+	}
+
+	if geocoder != nil {
+		a.workers.Go("address geocoder", func(ctx context.Context) error {
+			return orderService.RunGeocoder(ctx, a.cfg.Geocoding.Interval, a.cfg.Geocoding.BatchSize)
+		})
+	}
+
+	if a.cfg.Features.OrderStream {
+		streamHandler := handlers.NewOrderStreamHandler(orderService)
+		a.router.GET("/api/v1/orders/stream", streamHandler.Stream)
 
-	apiOrders := router.Group("/api/v1/orders")
+		a.workers.Go("order events listener", func(ctx context.Context) error {
+			return eventRepo.Listen(ctx, broker.Publish)
+		})
+		a.shutdownHooks = append(a.shutdownHooks, broker.Close)
+	}
+
+	apiOrders := a.router.Group("/api/v1/orders")
 	{
 		apiOrders.GET("", orderHandler.GetAll)
 		apiOrders.GET("/:id", orderHandler.GetByID)
+		apiOrders.GET("/:id/visits", orderHandler.GetVisits)
 		apiOrders.POST("", orderHandler.Create)
+		apiOrders.DELETE("/:id", middleware.AdminOnly(string(a.cfg.Auth.AdminToken)), orderHandler.Delete)
 	}
 
-	apiOrdersPatch := router.Group("/api/v1/orders/:id")
+	a.router.GET("/api/v1/employees/:id/route", orderHandler.GetRoute)
+
+	clientHandler := handlers.NewClientHandler(orderService)
+	a.router.POST("/api/v1/clients/erase", middleware.AdminOnly(string(a.cfg.Auth.AdminToken)), clientHandler.Erase)
+
+	apiOrdersPatch := a.router.Group("/api/v1/orders/:id")
 	{
+		apiOrdersPatch.PATCH("/release", middleware.AdminOnly(string(a.cfg.Auth.AdminToken)), orderHandler.Release)
 		apiOrdersPatch.PATCH("/preschedule", orderHandler.Preschedule)
 		apiOrdersPatch.PATCH("/assign/:empID", orderHandler.Assign)
 		apiOrdersPatch.PATCH("/schedule", orderHandler.Schedule)
 		apiOrdersPatch.PATCH("/progress", orderHandler.Progress)
+		apiOrdersPatch.POST("/checkin", orderHandler.CheckIn)
+		apiOrdersPatch.POST("/checkout", orderHandler.CheckOut)
 		apiOrdersPatch.PATCH("/complete", orderHandler.Complete)
 		apiOrdersPatch.PATCH("/close", orderHandler.Close)
 		apiOrdersPatch.PATCH("/cancel", orderHandler.Cancel)
 	}
+
+	a.prepareSpreadsheets(orderService, scheduling)
+	a.prepareRecurring(orderService, cipher, scheduling)
+	a.prepareInventory(orderService)
+	a.prepareReports(cal.Location())
+	return nil
 }
 
-func prepareEmployees(router *gin.Engine, db *gorm.DB) {
-	authRepo := repository_auth.NewAuthRepository(db)
-	authService := auth.NewAuthService(authRepo)
-	authHandler := handlers.NewAuthHandler(authService)
+// Приём заявок с сайта: ограничение частоты, капча и карантин для похожих на спам.
+// Счётчики частоты хранятся в памяти, поэтому при нескольких репликах лимит действует на каждую
+func (a *App) intakeService(orderService *orders.OrderService) *intake.IntakeService {
+	cfg := a.cfg.Intake
+	opts := []intake.IntakeServiceOption{
+		intake.WithClock(a.clock),
+		intake.WithPolicy(intake.Policy{
+			PerIP:           ratelimit.Rate{Burst: cfg.IPBurst, Every: cfg.IPEvery},
+			PerPhone:        ratelimit.Rate{Burst: cfg.PhoneBurst, Every: cfg.PhoneEvery},
+			QuarantineScore: cfg.QuarantineScore,
+		}),
+	}
+	if cfg.Captcha == "static" {
+		opts = append(opts, intake.WithCaptcha(intake.StaticCaptcha{Token: string(cfg.CaptchaToken)}))
+	}
+	return intake.NewIntakeService(orderService, opts...)
+}
+
+// Выгрузка заявок в CSV/XLSX и загрузка из них. Заявки из таблицы создаются через сервис заявок
+func (a *App) prepareSpreadsheets(orderService *orders.OrderService, scheduling orders.Scheduling) {
+	spreadsheetService := spreadsheets.NewSpreadsheetService(orderService, spreadsheets.WithScheduling(scheduling))
+	spreadsheetHandler := handlers.NewSpreadsheetHandler(spreadsheetService, int64(a.cfg.Import.MaxSizeMB)<<20)
+
+	adminOnly := middleware.AdminOnly(string(a.cfg.Auth.AdminToken))
+	a.router.GET("/api/v1/orders/export", adminOnly, spreadsheetHandler.Export)
+	a.router.POST("/api/v1/orders/import", adminOnly, spreadsheetHandler.Import)
+}
 
-	apiEmployees := router.Group("/api/v1/employees")
+// Шаблоны повторяющихся заявок. Заявки по ним создаются через сервис заявок,
+// поэтому для них так же пишутся события, метрики и ставится геокодирование
+func (a *App) prepareRecurring(orderService *orders.OrderService, cipher entities.PIICipher, scheduling orders.Scheduling) {
+	cfg := a.cfg.Recurring
+	templateService := recurring.NewTemplateService(
+		repository_recurring.NewTemplateRepository(a.db, repository_recurring.WithCipher(cipher)),
+		orderService,
+		recurring.WithScheduling(scheduling),
+		recurring.WithLeadTime(cfg.LeadTime),
+	)
+	recurringHandler := handlers.NewRecurringHandler(templateService)
+
+	if cfg.Enabled {
+		a.workers.Every("recurring orders", cfg.Interval, func(ctx context.Context) error {
+			_, err := templateService.Generate(ctx, cfg.BatchSize)
+			return err
+		})
+	}
+
+	apiRecurring := a.router.Group("/api/v1/recurring-orders")
 	{
-		apiEmployees.GET("/", authHandler.GetEmployees)
-		apiEmployees.GET("/:id", authHandler.GetEmployeeByID)
-		apiEmployees.POST("", authHandler.Create)
+		apiRecurring.GET("", recurringHandler.GetAll)
+		apiRecurring.GET("/:id", recurringHandler.GetByID)
+		apiRecurring.POST("", recurringHandler.Create)
+		apiRecurring.PATCH("/:id/pause", recurringHandler.Pause)
+		apiRecurring.PATCH("/:id/resume", recurringHandler.Resume)
+		apiRecurring.PATCH("/:id/end", recurringHandler.End)
 	}
 }
 
-func PrepareDB() *gorm.DB {
-	dbConn := os.Getenv("DATABASE_CONN")
-	if dbConn == "" {
-		log.Fatal("DATABASE_CONN environment variable is not set")
+// Склад запчастей. Резерв и списание выполняются по журналу событий заявок,
+// поэтому не зависят от того, на какой реплике изменили заявку
+func (a *App) prepareInventory(orderService *orders.OrderService) {
+	cfg := a.cfg.Inventory
+	inventoryService := inventory.NewInventoryService(
+		repository_inventory.NewInventoryRepository(a.db),
+		orderService,
+		inventory.WithWarehouse(cfg.Warehouse),
+	)
+	inventoryHandler := handlers.NewInventoryHandler(inventoryService)
+
+	if cfg.Enabled {
+		a.workers.Every("inventory", cfg.Interval, func(ctx context.Context) error {
+			_, err := inventoryService.ProcessOrderEvents(ctx, cfg.BatchSize)
+			return err
+		})
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), dbConnectionTimeout)
-	defer cancel()
+	apiParts := a.router.Group("/api/v1/parts")
+	{
+		apiParts.GET("", inventoryHandler.GetParts)
+		apiParts.GET("/:id", inventoryHandler.GetPart)
+		apiParts.POST("", inventoryHandler.CreatePart)
+	}
 
-	if err := waitForDBReady(ctx, dbConn); err != nil {
-		log.Fatalf("Failed to connect to the database: %v", err)
+	apiStock := a.router.Group("/api/v1/stock")
+	{
+		apiStock.GET("", inventoryHandler.GetStock)
+		apiStock.POST("/receipts", inventoryHandler.Receive)
+		apiStock.POST("/transfers", inventoryHandler.Transfer)
 	}
+	a.router.GET("/api/v1/inventory/events", inventoryHandler.GetEvents)
 
-	runMigrations(dbConn)
+	apiOrderParts := a.router.Group("/api/v1/orders/:id")
+	{
+		apiOrderParts.GET("/parts", inventoryHandler.GetOrderParts)
+		apiOrderParts.PUT("/parts", inventoryHandler.SetOrderParts)
+		apiOrderParts.POST("/parts/returns", inventoryHandler.ReturnParts)
+		apiOrderParts.GET("/line-items", inventoryHandler.GetLineItems)
+	}
+}
 
-	log.Println("Connecting to the PostgreSQL database...")
-	db, err := gorm.Open(postgres.Open(dbConn), &gorm.Config{})
+// Отчёты для руководства; дни периода отсчитываются в часовом поясе компании
+func (a *App) prepareReports(loc *time.Location) {
+	reportService := reports.NewReportService(
+		repository_reports.NewReportRepository(a.db),
+		reports.WithLocation(loc),
+	)
+	reportHandler := handlers.NewReportHandler(reportService)
+
+	apiReports := a.router.Group("/api/v1/reports", middleware.AdminOnly(string(a.cfg.Auth.AdminToken)))
+	{
+		apiReports.GET("/throughput", reportHandler.Throughput)
+		apiReports.GET("/transitions", reportHandler.Transitions)
+		apiReports.GET("/cancellations", reportHandler.Cancellations)
+		apiReports.GET("/revenue", reportHandler.Revenue)
+		apiReports.GET("/employees", reportHandler.EmployeePerformance)
+	}
+}
+
+// Фото и документы заявок содержат данные клиента, поэтому доступны только с токеном администратора
+func (a *App) prepareAttachments() (*attachments.AttachmentService, error) {
+	cfg := a.cfg.Attachments
+	blobs, err := blobstore.New(context.Background(), cfg.Storage)
 	if err != nil {
-		log.Fatalf("Failed to connect to the database: %v", err)
+		return nil, err
 	}
 
-	return db
+	maxSize := int64(cfg.MaxSizeMB) << 20
+	attachmentService := attachments.NewAttachmentService(
+		repository_attachments.NewAttachmentRepository(a.db),
+		blobs,
+		attachments.Policy{
+			MaxSize:       maxSize,
+			AllowedTypes:  cfg.AllowedTypes,
+			ThumbnailSize: cfg.ThumbnailSize,
+		},
+		attachments.WithClock(a.clock),
+	)
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentService, maxSize)
+
+	apiAttachments := a.router.Group("/api/v1/orders/:id/attachments", middleware.AdminOnly(string(a.cfg.Auth.AdminToken)))
+	{
+		apiAttachments.GET("", attachmentHandler.List)
+		apiAttachments.GET("/:attID", attachmentHandler.Download)
+		apiAttachments.POST("", attachmentHandler.Upload)
+	}
+	return attachmentService, nil
+}
+
+func (a *App) prepareEmployees() {
+	authRepo := repository_auth.NewAuthRepository(a.db)
+	authService := auth.NewAuthService(authRepo)
+	authHandler := handlers.NewAuthHandler(authService)
+
+	apiEmployees := a.router.Group("/api/v1/employees")
+	{
+		apiEmployees.GET("/", authHandler.GetEmployees)
+		apiEmployees.GET("/:id", authHandler.GetEmployeeByID)
+		apiEmployees.POST("", authHandler.Create)
+	}
 }
@@ -0,0 +1,25 @@
+package app
+
+import (
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/config"
+	"github.com/Owouwun/spkuznetsov/pkg/calendar"
+)
+
+// NewCalendar создаёт рабочий календарь компании в её часовом поясе
+// (по умолчанию — в часовом поясе сервера)
+func NewCalendar(cfg config.CalendarConfig) (*calendar.Calendar, error) {
+	loc := time.Local
+	if cfg.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(cfg.Timezone); err != nil {
+			return nil, err
+		}
+	}
+	workdays, err := calendar.ParseWeekdays(cfg.Workdays)
+	if err != nil {
+		return nil, err
+	}
+	return calendar.New(loc, cfg.WorkStart, cfg.WorkEnd, workdays, cfg.Holidays)
+}
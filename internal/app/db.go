@@ -3,66 +3,167 @@ package app
 import (
 	"context"
 	"database/sql"
-	"log"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
 	"time"
 
-	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/postgres" // Postgres migration
-	_ "github.com/golang-migrate/migrate/v4/source/file"       // Migrations from file
-	_ "github.com/lib/pq"                                      // Register Postgres driver
+	"github.com/Owouwun/spkuznetsov/internal/config"
+	"github.com/Owouwun/spkuznetsov/internal/logging"
+	"github.com/Owouwun/spkuznetsov/migrations"
+	"github.com/Owouwun/spkuznetsov/pkg/utils"
+	_ "github.com/jackc/pgx/v5/stdlib" // Register pgx database/sql driver
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
 )
 
-func runMigrations(dbConn string) {
-	log.Println("Running database migrations...")
+// PrepareDB подключается к БД, при database.auto_migrate применяет миграции
+// и при database.seed загружает демонстрационные данные, после чего строит слепой
+// индекс телефона. Без построенного индекса сервис не запускается.
+// Миграции, gorm и сидирование работают через один общий пул соединений
+func PrepareDB(ctx context.Context, cfg config.Config) (*gorm.DB, error) {
+	sqlDB, err := Connect(ctx, cfg.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	closeOnErr := func(err error) (*gorm.DB, error) {
+		_ = sqlDB.Close()
+		return nil, err
+	}
 
-	m, err := migrate.New(
-		"file://migrations", // Путь к папке с миграциями
-		dbConn,
-	)
+	if cfg.Database.AutoMigrate {
+		slog.Info("running database migrations")
+		if err := migrateUp(ctx, sqlDB); err != nil {
+			return closeOnErr(err)
+		}
+		slog.Info("database migrations applied")
+	} else {
+		slog.Info("automatic migrations disabled, expecting schema to be migrated separately")
+	}
+
+	if cfg.Database.Seed {
+		if err := migrations.Seed(ctx, sqlDB); err != nil {
+			return closeOnErr(fmt.Errorf("seed the database: %w", err))
+		}
+	}
+
+	db, err := OpenGorm(sqlDB, cfg.Log)
 	if err != nil {
-		log.Fatalf("Failed to create migrate instance: %v", err)
+		return closeOnErr(err)
+	}
+
+	if cfg.Database.AutoMigrate || cfg.Database.Seed {
+		if err := BackfillPhoneIndex(ctx, db, cfg.Encryption); err != nil {
+			return closeOnErr(err)
+		}
+	}
+	if err := checkPhoneIndex(ctx, db); err != nil {
+		return closeOnErr(err)
 	}
+	return db, nil
+}
 
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		log.Fatalf("Failed to run migrations: %v", err)
+// Connect открывает пул соединений с настройками из конфигурации и ждёт готовности БД,
+// повторяя попытки с экспоненциальной задержкой не дольше database.connect_timeout.
+// Ожидание прерывается отменой ctx
+func Connect(ctx context.Context, cfg config.DatabaseConfig) (*sql.DB, error) {
+	sqlDB, err := sql.Open("pgx", string(cfg.DSN))
+	if err != nil {
+		return nil, fmt.Errorf("open database pool: %w", err)
 	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
-	log.Println("Database migrations applied successfully!")
+	if err := waitForDB(ctx, sqlDB, cfg); err != nil {
+		_ = sqlDB.Close()
+		return nil, err
+	}
+	return sqlDB, nil
 }
 
-func waitForDBReady(ctx context.Context, dbConn string) error {
-	log.Println("Waiting for database to be ready...")
-
-	done := make(chan error)
-
-	go func() {
-		for {
-			db, err := sql.Open("postgres", dbConn)
-			if err != nil {
-				done <- err
-				return
-			}
-			defer func() {
-				err := db.Close()
-				if err != nil {
-					log.Fatal(err)
-				}
-			}()
-
-			if err := db.Ping(); err == nil {
-				done <- nil
-				return
-			}
-
-			// Wait till the next try
-			time.Sleep(100 * time.Millisecond)
+func waitForDB(ctx context.Context, sqlDB *sql.DB, cfg config.DatabaseConfig) error {
+	ctx, cancel := context.WithTimeout(ctx, cfg.ConnectTimeout)
+	defer cancel()
+
+	slog.Info("waiting for database to be ready")
+	backoff := utils.NewBackoff(cfg.RetryMinDelay, cfg.RetryMaxDelay)
+
+	for attempt := 1; ; attempt++ {
+		err := sqlDB.PingContext(ctx)
+		if err == nil {
+			slog.Info("database is ready", slog.Int("attempts", attempt))
+			return nil
 		}
-	}()
 
-	select {
-	case err := <-done:
+		delay := backoff.Next()
+		slog.Warn("database is not ready",
+			slog.Int("attempt", attempt),
+			slog.Duration("retry_in", delay),
+			slog.Any("error", err),
+		)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("connect to the database after %d attempts: %w", attempt, errors.Join(ctx.Err(), err))
+		case <-timer.C:
+		}
+	}
+}
+
+// OpenGorm оборачивает готовый пул соединений в gorm
+func OpenGorm(sqlDB *sql.DB, cfg config.LogConfig) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger: logging.NewGormLogger(slog.Default(), cfg.SlowQueryThreshold),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("open gorm: %w", err)
+	}
+	return db, nil
+}
+
+func migrateUp(ctx context.Context, sqlDB *sql.DB) error {
+	mg, err := NewMigrator(ctx, sqlDB)
+	if err != nil {
 		return err
-	case <-ctx.Done(): // Timeout
-		return ctx.Err()
+	}
+	defer func() {
+		_ = mg.Close()
+	}()
+
+	if err := mg.Up(); err != nil {
+		return fmt.Errorf("run migrations: %w", err)
+	}
+	return nil
+}
+
+// Версия последней встроенной миграции — ожидаемая версия схемы БД
+func latestMigrationVersion() (uint, error) {
+	src, err := migrations.Source()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = src.Close()
+	}()
+
+	version, err := src.First()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		version = next
 	}
 }
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/config"
+)
+
+// Адрес, на котором гарантированно никто не слушает
+func closedPortDSN(t *testing.T) config.DSN {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	_ = l.Close()
+
+	return config.DSN("postgres://user:password@" + addr + "/db?sslmode=disable&connect_timeout=1")
+}
+
+func TestConnect_GivesUp(t *testing.T) {
+	cases := []struct {
+		name    string
+		timeout time.Duration
+		cancel  bool
+		expErr  error
+	}{
+		{
+			name:    "Истёк таймаут подключения",
+			timeout: 300 * time.Millisecond,
+			expErr:  context.DeadlineExceeded,
+		},
+		{
+			name:    "Отмена контекста",
+			timeout: time.Minute,
+			cancel:  true,
+			expErr:  context.Canceled,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.Default().Database
+			cfg.DSN = closedPortDSN(t)
+			cfg.ConnectTimeout = tc.timeout
+			cfg.RetryMinDelay = 10 * time.Millisecond
+			cfg.RetryMaxDelay = 50 * time.Millisecond
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.cancel {
+				time.AfterFunc(200*time.Millisecond, cancel)
+			}
+
+			start := time.Now()
+			db, err := Connect(ctx, cfg)
+			if db != nil {
+				t.Error("expected no pool on failure")
+			}
+			if !errors.Is(err, tc.expErr) {
+				t.Fatalf("expected %v, got %v", tc.expErr, err)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("gave up too late: %s", elapsed)
+			}
+		})
+	}
+}
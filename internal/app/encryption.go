@@ -0,0 +1,61 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/Owouwun/spkuznetsov/internal/config"
+	"github.com/Owouwun/spkuznetsov/internal/core/repository/entities"
+	repository_orders "github.com/Owouwun/spkuznetsov/internal/core/repository/services/orders"
+	"github.com/Owouwun/spkuznetsov/internal/pii"
+	"gorm.io/gorm"
+)
+
+// Версия схемы, в которой у заявок появился слепой индекс телефона
+const PhoneIndexVersion = 6
+
+const phoneIndexBatchSize = 500
+
+// NewPIICipher загружает ключи шифрования персональных данных.
+// Если файл ключей не задан, данные клиентов хранятся открыто
+func NewPIICipher(cfg config.EncryptionConfig) (entities.PIICipher, error) {
+	if cfg.KeysFile == "" {
+		slog.Warn("client data encryption is disabled: encryption.keys_file is not set")
+		return pii.Plaintext{}, nil
+	}
+	return pii.LoadKeyring(cfg.KeysFile)
+}
+
+// BackfillPhoneIndex строит слепой индекс телефона у заявок, у которых его нет.
+// Миграции не знают ключа, поэтому индекс строится после них и после загрузки демонстрационных данных
+func BackfillPhoneIndex(ctx context.Context, db *gorm.DB, cfg config.EncryptionConfig) error {
+	cipher, err := NewPIICipher(cfg)
+	if err != nil {
+		return err
+	}
+
+	repo := repository_orders.NewOrderRepository(db, repository_orders.WithCipher(cipher))
+	updated, err := repo.BackfillPhoneIndex(ctx, phoneIndexBatchSize)
+	if err != nil {
+		return fmt.Errorf("build client phone index (%d orders done): %w", updated, err)
+	}
+	if updated > 0 {
+		slog.InfoContext(ctx, "client phone index built", slog.Int("orders", updated))
+	}
+	return nil
+}
+
+// Без слепого индекса заявки клиента не находятся при обезличивании, поэтому сервис
+// не запускается, пока индекс не построен
+func checkPhoneIndex(ctx context.Context, db *gorm.DB) error {
+	pending, err := repository_orders.NewOrderRepository(db).PhoneIndexPending(ctx)
+	if err != nil {
+		return fmt.Errorf("check client phone index: %w", err)
+	}
+	if pending {
+		return errors.New("client phone index is not built for some orders: run server migrate up")
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package app
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type dbHealthChecker struct {
+	db *gorm.DB
+}
+
+func (hc *dbHealthChecker) Ping(ctx context.Context) error {
+	sqlDB, err := hc.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func (hc *dbHealthChecker) MigrationVersion(ctx context.Context) (uint, bool, error) {
+	var state struct {
+		Version uint
+		Dirty   bool
+	}
+	result := hc.db.WithContext(ctx).
+		Raw("SELECT version, dirty FROM schema_migrations LIMIT 1").
+		Scan(&state)
+	if result.Error != nil {
+		return 0, false, result.Error
+	}
+
+	return state.Version, state.Dirty, nil
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/Owouwun/spkuznetsov/migrations"
+	"github.com/golang-migrate/migrate/v4"
+	migpostgres "github.com/golang-migrate/migrate/v4/database/postgres"
+)
+
+// Migrator управляет версией схемы БД по встроенным миграциям.
+// golang-migrate берёт advisory lock, поэтому параллельный запуск с нескольких реплик безопасен,
+// но лишние реплики будут ждать блокировку
+type Migrator struct {
+	m *migrate.Migrate
+}
+
+// NewMigrator берёт из общего пула одно соединение на время работы с миграциями.
+// Close возвращает соединение в пул, не закрывая сам пул
+func NewMigrator(ctx context.Context, sqlDB *sql.DB) (*Migrator, error) {
+	src, err := migrations.Source()
+	if err != nil {
+		return nil, fmt.Errorf("open embedded migrations: %w", err)
+	}
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		_ = src.Close()
+		return nil, fmt.Errorf("get connection for migrations: %w", err)
+	}
+
+	driver, err := migpostgres.WithConnection(ctx, conn, &migpostgres.Config{})
+	if err != nil {
+		_ = conn.Close()
+		_ = src.Close()
+		return nil, fmt.Errorf("create migrate driver: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", src, "postgres", driver)
+	if err != nil {
+		_ = driver.Close()
+		_ = src.Close()
+		return nil, fmt.Errorf("create migrate instance: %w", err)
+	}
+	m.Log = migrateLogger{}
+
+	return &Migrator{m: m}, nil
+}
+
+// Up применяет все ещё не применённые миграции
+func (mg *Migrator) Up() error {
+	return ignoreNoChange(mg.m.Up())
+}
+
+// Down откатывает steps последних миграций
+func (mg *Migrator) Down(steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("number of steps must be positive, got %d", steps)
+	}
+	return ignoreNoChange(mg.m.Steps(-steps))
+}
+
+// Goto переводит схему к указанной версии (вверх или вниз)
+func (mg *Migrator) Goto(version uint) error {
+	return ignoreNoChange(mg.m.Migrate(version))
+}
+
+// Version возвращает текущую версию схемы; 0 — миграции не применялись
+func (mg *Migrator) Version() (uint, bool, error) {
+	version, dirty, err := mg.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// Force записывает версию без выполнения миграций и снимает признак dirty.
+// Используется после ручного исправления схемы; -1 означает «миграции не применялись»
+func (mg *Migrator) Force(version int) error {
+	return mg.m.Force(version)
+}
+
+func (mg *Migrator) Close() error {
+	srcErr, dbErr := mg.m.Close()
+	return errors.Join(srcErr, dbErr)
+}
+
+func ignoreNoChange(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
+
+// migrateLogger передаёт сообщения golang-migrate в slog
+type migrateLogger struct{}
+
+func (migrateLogger) Printf(format string, v ...any) {
+	slog.Info(strings.TrimSpace(fmt.Sprintf(format, v...)), slog.String("component", "migrate"))
+}
+
+func (migrateLogger) Verbose() bool {
+	return false
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Owouwun/spkuznetsov/internal/config"
+)
+
+// Run обслуживает HTTP-запросы до отмены ctx, после чего корректно останавливает сервер:
+// помечает его неготовым, дожидается завершения текущих запросов и фоновых задач
+// и закрывает пул соединений с БД
+func (a *App) Run(ctx context.Context, cfg config.ServerConfig) error {
+	srv := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           a.router,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+	for _, hook := range a.shutdownHooks {
+		srv.RegisterOnShutdown(hook)
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("starting server", slog.String("addr", cfg.Addr))
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down server")
+	a.health.SetDraining()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	var errs []error
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, err)
+	}
+	if err := a.workers.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, err)
+	}
+	if err := a.tracing.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, err)
+	}
+	if sqlDB, err := a.db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	slog.Info("server stopped")
+	return errors.Join(errs...)
+}
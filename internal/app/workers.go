@@ -0,0 +1,73 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Workers запускает фоновые задачи сервиса и дожидается их завершения при остановке
+type Workers struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewWorkers() *Workers {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Workers{
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Go запускает задачу. Контекст задачи отменяется при вызове Shutdown
+func (w *Workers) Go(name string, fn func(ctx context.Context) error) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		if err := fn(w.ctx); err != nil {
+			slog.Error("background worker stopped", slog.String("worker", name), slog.Any("error", err))
+		}
+	}()
+}
+
+// Every запускает задачу сразу и затем с периодом interval. Ошибка запуска пишется в лог
+// и не останавливает расписание
+func (w *Workers) Every(name string, interval time.Duration, fn func(ctx context.Context) error) {
+	w.Go(name, func(ctx context.Context) error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := fn(ctx); err != nil && ctx.Err() == nil {
+				slog.Error("scheduled job failed", slog.String("worker", name), slog.Any("error", err))
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// Shutdown отменяет контекст задач и ждёт их завершения, но не дольше, чем живёт ctx
+func (w *Workers) Shutdown(ctx context.Context) error {
+	w.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
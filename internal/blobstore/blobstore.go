@@ -0,0 +1,45 @@
+// Package blobstore хранит содержимое файлов (вложений заявок) вне БД.
+// Отсутствующий объект во всех реализациях — ошибка, удовлетворяющая errors.Is(err, fs.ErrNotExist)
+package blobstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/Owouwun/spkuznetsov/internal/config"
+)
+
+// Store — общий интерфейс хранилищ
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// New создаёт хранилище, выбранное в конфигурации
+func New(ctx context.Context, cfg config.BlobStoreConfig) (Store, error) {
+	switch cfg.Backend {
+	case "local":
+		return NewLocal(cfg.LocalDir)
+	case "s3":
+		return NewS3(ctx, cfg.S3)
+	default:
+		return nil, fmt.Errorf("blobstore: unknown backend %q", cfg.Backend)
+	}
+}
+
+// Ключ — относительный путь из сегментов через "/", без "." и ".."
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key {
+		return fmt.Errorf("blobstore: invalid key %q", key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "." || segment == ".." {
+			return fmt.Errorf("blobstore: invalid key %q", key)
+		}
+	}
+	return nil
+}
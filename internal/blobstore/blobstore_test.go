@@ -0,0 +1,188 @@
+package blobstore_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Owouwun/spkuznetsov/internal/blobstore"
+	"github.com/Owouwun/spkuznetsov/internal/config"
+)
+
+const testBucket = "attachments"
+
+// s3Stub — минимальное S3-совместимое хранилище в памяти (path-style, без проверки подписи)
+type s3Stub struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (s *s3Stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != testBucket {
+		s3Error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	if key == "" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		body, err := readPayload(r)
+		if err != nil {
+			s3Error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		s.objects[key] = body
+		w.Header().Set("ETag", `"stub"`)
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet, http.MethodHead:
+		body, ok := s.objects[key]
+		if !ok {
+			s3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("ETag", `"stub"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(body)
+		}
+	case http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func s3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+// По HTTP клиент передаёт тело в формате aws-chunked: "<hex-размер>[;подпись]\r\n<данные>\r\n"
+func readPayload(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+
+	var payload bytes.Buffer
+	br := bufio.NewReader(r.Body)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return payload.Bytes(), nil
+		}
+		if _, err := io.CopyN(&payload, br, size); err != nil {
+			return nil, err
+		}
+		if _, err := br.Discard(2); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func newStores(t *testing.T) map[string]blobstore.Store {
+	t.Helper()
+
+	local, err := blobstore.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(&s3Stub{objects: map[string][]byte{}})
+	t.Cleanup(srv.Close)
+	s3, err := blobstore.NewS3(context.Background(), config.S3Config{
+		Endpoint:  srv.URL,
+		Region:    "us-east-1",
+		Bucket:    testBucket,
+		AccessKey: "access",
+		SecretKey: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]blobstore.Store{"Локальный каталог": local, "S3": s3}
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	content := []byte("акт выполненных работ")
+
+	for name, store := range newStores(t) {
+		t.Run(name, func(t *testing.T) {
+			key := "orders/42/act.pdf"
+			if err := store.Put(ctx, key, bytes.NewReader(content), int64(len(content)), "application/pdf"); err != nil {
+				t.Fatal(err)
+			}
+
+			rc, err := store.Get(ctx, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(rc)
+			_ = rc.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, content) {
+				t.Errorf("expected %q, got %q", content, got)
+			}
+
+			if err := store.Delete(ctx, key); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.Get(ctx, key); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("expected fs.ErrNotExist after delete, got %v", err)
+			}
+			if err := store.Delete(ctx, key); err != nil {
+				t.Errorf("deleting a missing object must succeed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestStore_InvalidKey(t *testing.T) {
+	ctx := context.Background()
+	keys := []string{"", "/etc/passwd", "../secret", "orders/../../secret", "orders//file"}
+
+	for name, store := range newStores(t) {
+		for _, key := range keys {
+			t.Run(name+"/"+key, func(t *testing.T) {
+				if err := store.Put(ctx, key, strings.NewReader("x"), 1, "text/plain"); err == nil {
+					t.Errorf("expected an error for key %q", key)
+				}
+				if _, err := store.Get(ctx, key); err == nil || errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("expected a key validation error for %q, got %v", key, err)
+				}
+			})
+		}
+	}
+}
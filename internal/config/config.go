@@ -0,0 +1,680 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/pkg/calendar"
+)
+
+// Config — полная конфигурация сервиса.
+// Источники применяются в порядке: значения по умолчанию, YAML-файл, переменные окружения, флаги;
+// каждый следующий источник переопределяет предыдущий.
+type Config struct {
+	Server        ServerConfig        `yaml:"server"`
+	Database      DatabaseConfig      `yaml:"database"`
+	Log           LogConfig           `yaml:"log"`
+	CORS          CORSConfig          `yaml:"cors"`
+	Tracing       TracingConfig       `yaml:"tracing"`
+	Auth          AuthConfig          `yaml:"auth"`
+	Encryption    EncryptionConfig    `yaml:"encryption"`
+	Archive       ArchiveConfig       `yaml:"archive"`
+	Retention     RetentionConfig     `yaml:"retention"`
+	Attachments   AttachmentsConfig   `yaml:"attachments"`
+	Visits        VisitsConfig        `yaml:"visits"`
+	Geocoding     GeocodingConfig     `yaml:"geocoding"`
+	Routing       RoutingConfig       `yaml:"routing"`
+	Calendar      CalendarConfig      `yaml:"calendar"`
+	SLA           SLAConfig           `yaml:"sla"`
+	Recurring     RecurringConfig     `yaml:"recurring"`
+	Inventory     InventoryConfig     `yaml:"inventory"`
+	Import        ImportConfig        `yaml:"import"`
+	Intake        IntakeConfig        `yaml:"intake"`
+	Idempotency   IdempotencyConfig   `yaml:"idempotency"`
+	Features      FeaturesConfig      `yaml:"features"`
+	Notifications NotificationsConfig `yaml:"notifications"`
+}
+
+type ServerConfig struct {
+	Addr            string        `yaml:"addr" env:"SERVER_ADDR" usage:"HTTP listen address"`
+	ReadTimeout     time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT" usage:"HTTP read timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT" usage:"HTTP write timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT" usage:"HTTP keep-alive idle timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT" usage:"graceful shutdown timeout"`
+	// Пусто — X-Forwarded-For не учитывается, адрес клиента берётся из соединения
+	TrustedProxies []string `yaml:"trusted_proxies" env:"SERVER_TRUSTED_PROXIES" usage:"comma-separated IPs or CIDRs of reverse proxies allowed to set X-Forwarded-For; empty trusts none"`
+}
+
+type DatabaseConfig struct {
+	DSN             DSN           `yaml:"dsn" env:"DATABASE_CONN" usage:"PostgreSQL connection string"`
+	AutoMigrate     bool          `yaml:"auto_migrate" env:"DATABASE_AUTO_MIGRATE" usage:"apply migrations on server start; disable when running the migrate command separately"`
+	Seed            bool          `yaml:"seed" env:"DATABASE_SEED" usage:"load demo data after migrations"`
+	ConnectTimeout  time.Duration `yaml:"connect_timeout" env:"DATABASE_CONNECT_TIMEOUT" usage:"time to wait for the database on startup"`
+	RetryMinDelay   time.Duration `yaml:"retry_min_delay" env:"DATABASE_RETRY_MIN_DELAY" usage:"initial delay between connection attempts"`
+	RetryMaxDelay   time.Duration `yaml:"retry_max_delay" env:"DATABASE_RETRY_MAX_DELAY" usage:"maximum delay between connection attempts"`
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DATABASE_MAX_OPEN_CONNS" usage:"maximum open connections in the pool"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DATABASE_MAX_IDLE_CONNS" usage:"maximum idle connections in the pool"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DATABASE_CONN_MAX_LIFETIME" usage:"maximum lifetime of a pooled connection"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"DATABASE_CONN_MAX_IDLE_TIME" usage:"maximum idle time of a pooled connection"`
+}
+
+type LogConfig struct {
+	Level              string        `yaml:"level" env:"LOG_LEVEL" usage:"log level: debug, info, warn, error"`
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold" env:"LOG_SLOW_QUERY_THRESHOLD" usage:"SQL queries slower than this are logged as warnings"`
+}
+
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS" usage:"comma-separated list of allowed origins, * for any"`
+}
+
+type TracingConfig struct {
+	Exporter     string  `yaml:"exporter" env:"TRACING_EXPORTER" usage:"trace exporter: none, stdout, otlp, memory"`
+	OTLPEndpoint string  `yaml:"otlp_endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" usage:"OTLP/HTTP collector URL, e.g. http://otel-collector:4318"`
+	SampleRatio  float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO" usage:"fraction of new traces to sample, from 0 to 1"`
+	ServiceName  string  `yaml:"service_name" env:"OTEL_SERVICE_NAME" usage:"service name reported in traces"`
+}
+
+type AuthConfig struct {
+	AdminToken Secret `yaml:"admin_token" env:"AUTH_ADMIN_TOKEN" usage:"bearer token for admin-only endpoints; empty disables them"`
+}
+
+type EncryptionConfig struct {
+	KeysFile string `yaml:"keys_file" env:"ENCRYPTION_KEYS_FILE" usage:"YAML file with keys for client data encryption; empty stores client data unencrypted"`
+}
+
+type ArchiveConfig struct {
+	Enabled     bool          `yaml:"enabled" env:"ARCHIVE_ENABLED" usage:"periodically move old paid and canceled orders to the archive"`
+	AfterMonths int           `yaml:"after_months" env:"ARCHIVE_AFTER_MONTHS" usage:"archive orders whose status has not changed for this many months"`
+	Interval    time.Duration `yaml:"interval" env:"ARCHIVE_INTERVAL" usage:"how often the archive job runs"`
+	BatchSize   int           `yaml:"batch_size" env:"ARCHIVE_BATCH_SIZE" usage:"orders moved per transaction"`
+}
+
+type RetentionConfig struct {
+	Enabled    bool          `yaml:"enabled" env:"RETENTION_ENABLED" usage:"anonymize client data of closed orders after the retention period"`
+	AfterYears int           `yaml:"after_years" env:"RETENTION_AFTER_YEARS" usage:"retention period of client data in closed orders, years"`
+	Interval   time.Duration `yaml:"interval" env:"RETENTION_INTERVAL" usage:"how often the retention job runs"`
+	BatchSize  int           `yaml:"batch_size" env:"RETENTION_BATCH_SIZE" usage:"orders anonymized per transaction"`
+}
+
+type AttachmentsConfig struct {
+	MaxSizeMB     int             `yaml:"max_size_mb" env:"ATTACHMENTS_MAX_SIZE_MB" usage:"maximum attachment size, MiB"`
+	AllowedTypes  []string        `yaml:"allowed_types" env:"ATTACHMENTS_ALLOWED_TYPES" usage:"comma-separated MIME types accepted for upload"`
+	ThumbnailSize int             `yaml:"thumbnail_size" env:"ATTACHMENTS_THUMBNAIL_SIZE" usage:"longest side of image thumbnails, px"`
+	Storage       BlobStoreConfig `yaml:"storage"`
+}
+
+type BlobStoreConfig struct {
+	Backend  string   `yaml:"backend" env:"BLOB_BACKEND" usage:"attachment storage: local or s3"`
+	LocalDir string   `yaml:"local_dir" env:"BLOB_LOCAL_DIR" usage:"directory for attachments when backend is local"`
+	S3       S3Config `yaml:"s3"`
+}
+
+type S3Config struct {
+	Endpoint  string `yaml:"endpoint" env:"BLOB_S3_ENDPOINT" usage:"S3-compatible endpoint URL, e.g. http://minio:9000"`
+	Region    string `yaml:"region" env:"BLOB_S3_REGION" usage:"S3 region"`
+	Bucket    string `yaml:"bucket" env:"BLOB_S3_BUCKET" usage:"S3 bucket for attachments"`
+	AccessKey string `yaml:"access_key" env:"BLOB_S3_ACCESS_KEY" usage:"S3 access key ID"`
+	SecretKey Secret `yaml:"secret_key" env:"BLOB_S3_SECRET_KEY" usage:"S3 secret access key"`
+}
+
+type VisitsConfig struct {
+	CheckInRadiusM float64 `yaml:"checkin_radius_m" env:"VISITS_CHECKIN_RADIUS_M" usage:"check-ins farther than this from the geocoded order address are flagged, meters; 0 disables the check"`
+}
+
+type GeocodingConfig struct {
+	Provider    string        `yaml:"provider" env:"GEOCODING_PROVIDER" usage:"address geocoder: none, fixture or http"`
+	URL         string        `yaml:"url" env:"GEOCODING_URL" usage:"base URL of a Nominatim-compatible geocoding API"`
+	APIKey      Secret        `yaml:"api_key" env:"GEOCODING_API_KEY" usage:"geocoding API key, sent as the key query parameter"`
+	FixtureFile string        `yaml:"fixture_file" env:"GEOCODING_FIXTURE_FILE" usage:"YAML file with known addresses when provider is fixture"`
+	Timeout     time.Duration `yaml:"timeout" env:"GEOCODING_TIMEOUT" usage:"timeout of a single geocoding request"`
+	Interval    time.Duration `yaml:"interval" env:"GEOCODING_INTERVAL" usage:"how often orders left without coordinates are retried"`
+	BatchSize   int           `yaml:"batch_size" env:"GEOCODING_BATCH_SIZE" usage:"orders geocoded per retry run"`
+}
+
+type RoutingConfig struct {
+	DayStart      time.Duration `yaml:"day_start" env:"ROUTING_DAY_START" usage:"start of the working day as an offset from midnight, e.g. 9h"`
+	ArrivalWindow time.Duration `yaml:"arrival_window" env:"ROUTING_ARRIVAL_WINDOW" usage:"arrival window starting at the scheduled time of a visit"`
+	VisitDuration time.Duration `yaml:"visit_duration" env:"ROUTING_VISIT_DURATION" usage:"expected duration of a visit"`
+	SpeedKmh      float64       `yaml:"speed_kmh" env:"ROUTING_SPEED_KMH" usage:"average travel speed, km/h"`
+	DetourFactor  float64       `yaml:"detour_factor" env:"ROUTING_DETOUR_FACTOR" usage:"ratio of road distance to straight-line distance"`
+}
+
+type CalendarConfig struct {
+	Timezone  string        `yaml:"timezone" env:"CALENDAR_TIMEZONE" usage:"business time zone (IANA, e.g. Europe/Moscow); empty means the server time zone"`
+	WorkStart time.Duration `yaml:"work_start" env:"CALENDAR_WORK_START" usage:"start of working hours as an offset from midnight, e.g. 9h"`
+	WorkEnd   time.Duration `yaml:"work_end" env:"CALENDAR_WORK_END" usage:"end of working hours as an offset from midnight, e.g. 18h"`
+	Workdays  []string      `yaml:"workdays" env:"CALENDAR_WORKDAYS" usage:"working days of the week: mon, tue, ..., sun"`
+	Holidays  []string      `yaml:"holidays" env:"CALENDAR_HOLIDAYS" usage:"non-working dates, YYYY-MM-DD"`
+}
+
+type SLAConfig struct {
+	Enabled  bool          `yaml:"enabled" env:"SLA_ENABLED" usage:"detect orders that stay in a status longer than allowed"`
+	Interval time.Duration `yaml:"interval" env:"SLA_INTERVAL" usage:"how often SLA breaches are detected"`
+	Rules    []string      `yaml:"rules" env:"SLA_RULES" usage:"SLA rules status[/category]:duration, e.g. new:4h,done:14d; hours count working time, days count working days"`
+}
+
+type RecurringConfig struct {
+	Enabled   bool          `yaml:"enabled" env:"RECURRING_ENABLED" usage:"generate orders from recurring order templates"`
+	Interval  time.Duration `yaml:"interval" env:"RECURRING_INTERVAL" usage:"how often recurring order templates are checked"`
+	LeadTime  time.Duration `yaml:"lead_time" env:"RECURRING_LEAD_TIME" usage:"how long before an occurrence its order is created"`
+	BatchSize int           `yaml:"batch_size" env:"RECURRING_BATCH_SIZE" usage:"templates processed per query"`
+}
+
+type InventoryConfig struct {
+	Enabled   bool          `yaml:"enabled" env:"INVENTORY_ENABLED" usage:"reserve and consume spare parts on order changes"`
+	Warehouse string        `yaml:"warehouse" env:"INVENTORY_WAREHOUSE" usage:"code of the warehouse parts are reserved from when a technician van lacks them"`
+	Interval  time.Duration `yaml:"interval" env:"INVENTORY_INTERVAL" usage:"how often new order events are applied to stock"`
+	BatchSize int           `yaml:"batch_size" env:"INVENTORY_BATCH_SIZE" usage:"order events applied per pass"`
+}
+
+type ImportConfig struct {
+	MaxSizeMB int `yaml:"max_size_mb" env:"IMPORT_MAX_SIZE_MB" usage:"maximum size of an uploaded orders spreadsheet, MiB"`
+}
+
+type IntakeConfig struct {
+	IPBurst         int           `yaml:"ip_burst" env:"INTAKE_IP_BURST" usage:"orders accepted in a row from one IP via POST /api/v1/orders; 0 disables the limit"`
+	IPEvery         time.Duration `yaml:"ip_every" env:"INTAKE_IP_EVERY" usage:"one more order from an IP is accepted every this interval"`
+	PhoneBurst      int           `yaml:"phone_burst" env:"INTAKE_PHONE_BURST" usage:"orders accepted in a row for one client phone; 0 disables the limit"`
+	PhoneEvery      time.Duration `yaml:"phone_every" env:"INTAKE_PHONE_EVERY" usage:"one more order for a phone is accepted every this interval"`
+	QuarantineScore int           `yaml:"quarantine_score" env:"INTAKE_QUARANTINE_SCORE" usage:"spam score from which new orders are quarantined; 0 disables spam scoring"`
+	Captcha         string        `yaml:"captcha" env:"INTAKE_CAPTCHA" usage:"captcha check of new orders: none or static (for tests)"`
+	CaptchaToken    Secret        `yaml:"captcha_token" env:"INTAKE_CAPTCHA_TOKEN" usage:"the only captcha response accepted by the static check"`
+}
+
+type IdempotencyConfig struct {
+	Enabled   bool          `yaml:"enabled" env:"IDEMPOTENCY_ENABLED" usage:"replay stored responses to mutating requests with an Idempotency-Key header"`
+	TTL       time.Duration `yaml:"ttl" env:"IDEMPOTENCY_TTL" usage:"how long a response is stored for its idempotency key"`
+	Interval  time.Duration `yaml:"interval" env:"IDEMPOTENCY_INTERVAL" usage:"how often expired idempotency keys are deleted"`
+	BatchSize int           `yaml:"batch_size" env:"IDEMPOTENCY_BATCH_SIZE" usage:"expired idempotency keys deleted per query"`
+}
+
+type FeaturesConfig struct {
+	OrderStream bool `yaml:"order_stream" env:"FEATURE_ORDER_STREAM" usage:"enable SSE stream of order changes"`
+	Swagger     bool `yaml:"swagger" env:"FEATURE_SWAGGER" usage:"serve Swagger UI"`
+	Metrics     bool `yaml:"metrics" env:"FEATURE_METRICS" usage:"serve Prometheus metrics at /metrics"`
+}
+
+type NotificationsConfig struct {
+	Email EmailConfig `yaml:"email"`
+	SMS   SMSConfig   `yaml:"sms"`
+}
+
+type EmailConfig struct {
+	Enabled  bool   `yaml:"enabled" env:"NOTIFY_EMAIL_ENABLED" usage:"send e-mail notifications"`
+	SMTPHost string `yaml:"smtp_host" env:"NOTIFY_EMAIL_SMTP_HOST" usage:"SMTP server host"`
+	SMTPPort int    `yaml:"smtp_port" env:"NOTIFY_EMAIL_SMTP_PORT" usage:"SMTP server port"`
+	Username string `yaml:"username" env:"NOTIFY_EMAIL_USERNAME" usage:"SMTP user"`
+	Password Secret `yaml:"password" env:"NOTIFY_EMAIL_PASSWORD" usage:"SMTP password"`
+	From     string `yaml:"from" env:"NOTIFY_EMAIL_FROM" usage:"sender address"`
+}
+
+type SMSConfig struct {
+	Enabled bool   `yaml:"enabled" env:"NOTIFY_SMS_ENABLED" usage:"send SMS notifications"`
+	APIURL  string `yaml:"api_url" env:"NOTIFY_SMS_API_URL" usage:"SMS gateway URL"`
+	APIKey  Secret `yaml:"api_key" env:"NOTIFY_SMS_API_KEY" usage:"SMS gateway API key"`
+	Sender  string `yaml:"sender" env:"NOTIFY_SMS_SENDER" usage:"SMS sender name"`
+}
+
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Addr:            ":8080",
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     120 * time.Second,
+			ShutdownTimeout: 30 * time.Second,
+		},
+		Database: DatabaseConfig{
+			AutoMigrate:     true,
+			ConnectTimeout:  30 * time.Second,
+			RetryMinDelay:   100 * time.Millisecond,
+			RetryMaxDelay:   5 * time.Second,
+			MaxOpenConns:    20,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+		},
+		Log: LogConfig{
+			Level:              "info",
+			SlowQueryThreshold: 200 * time.Millisecond,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			SampleRatio: 1,
+			ServiceName: "spkuznetsov",
+		},
+		Archive: ArchiveConfig{
+			Enabled:     true,
+			AfterMonths: 12,
+			Interval:    24 * time.Hour,
+			BatchSize:   1000,
+		},
+		Retention: RetentionConfig{
+			AfterYears: 3,
+			Interval:   24 * time.Hour,
+			BatchSize:  1000,
+		},
+		Attachments: AttachmentsConfig{
+			MaxSizeMB:     10,
+			AllowedTypes:  []string{"image/jpeg", "image/png", "application/pdf"},
+			ThumbnailSize: 320,
+			Storage: BlobStoreConfig{
+				Backend:  "local",
+				LocalDir: "data/attachments",
+				S3: S3Config{
+					Region: "us-east-1",
+				},
+			},
+		},
+		Visits: VisitsConfig{
+			CheckInRadiusM: 300,
+		},
+		Geocoding: GeocodingConfig{
+			Provider:  "none",
+			Timeout:   10 * time.Second,
+			Interval:  10 * time.Minute,
+			BatchSize: 100,
+		},
+		Routing: RoutingConfig{
+			DayStart:      9 * time.Hour,
+			ArrivalWindow: 2 * time.Hour,
+			VisitDuration: time.Hour,
+			SpeedKmh:      30,
+			DetourFactor:  1.4,
+		},
+		Calendar: CalendarConfig{
+			WorkStart: 9 * time.Hour,
+			WorkEnd:   18 * time.Hour,
+			Workdays:  []string{"mon", "tue", "wed", "thu", "fri"},
+		},
+		SLA: SLAConfig{
+			Interval: 5 * time.Minute,
+			Rules:    []string{"new:4h", "done:14d"},
+		},
+		Recurring: RecurringConfig{
+			Enabled:   true,
+			Interval:  time.Hour,
+			LeadTime:  30 * 24 * time.Hour,
+			BatchSize: 100,
+		},
+		Inventory: InventoryConfig{
+			Enabled:   true,
+			Warehouse: "main",
+			Interval:  10 * time.Second,
+			BatchSize: 100,
+		},
+		Import: ImportConfig{
+			MaxSizeMB: 5,
+		},
+		Intake: IntakeConfig{
+			IPBurst:         5,
+			IPEvery:         10 * time.Minute,
+			PhoneBurst:      3,
+			PhoneEvery:      time.Hour,
+			QuarantineScore: 2,
+			Captcha:         "none",
+		},
+		Idempotency: IdempotencyConfig{
+			Enabled:   true,
+			TTL:       24 * time.Hour,
+			Interval:  time.Hour,
+			BatchSize: 1000,
+		},
+		Features: FeaturesConfig{
+			OrderStream: true,
+			Swagger:     true,
+			Metrics:     true,
+		},
+		Notifications: NotificationsConfig{
+			Email: EmailConfig{
+				SMTPPort: 587,
+			},
+		},
+	}
+}
+
+var (
+	logLevels      = []string{"debug", "info", "warn", "error"}
+	traceExporters = []string{"none", "stdout", "otlp", "memory"}
+	blobBackends   = []string{"local", "s3"}
+	geocoders      = []string{"none", "fixture", "http"}
+	captchas       = []string{"none", "static"}
+)
+
+// Validate проверяет конфигурацию и возвращает все найденные ошибки сразу
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(field, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	if c.Server.Addr == "" {
+		fail("server.addr", "must not be empty")
+	}
+	positiveDurations := map[string]time.Duration{
+		"server.read_timeout":      c.Server.ReadTimeout,
+		"server.write_timeout":     c.Server.WriteTimeout,
+		"server.idle_timeout":      c.Server.IdleTimeout,
+		"server.shutdown_timeout":  c.Server.ShutdownTimeout,
+		"database.connect_timeout": c.Database.ConnectTimeout,
+		"database.retry_min_delay": c.Database.RetryMinDelay,
+		"database.retry_max_delay": c.Database.RetryMaxDelay,
+	}
+	for _, field := range slices.Sorted(maps.Keys(positiveDurations)) {
+		if positiveDurations[field] <= 0 {
+			fail(field, "must be positive, got %s", positiveDurations[field])
+		}
+	}
+
+	if c.Database.DSN == "" {
+		fail("database.dsn", "must be set (DATABASE_CONN)")
+	}
+	if c.Database.RetryMinDelay > c.Database.RetryMaxDelay {
+		fail("database.retry_min_delay", "must not exceed database.retry_max_delay (%s > %s)",
+			c.Database.RetryMinDelay, c.Database.RetryMaxDelay)
+	}
+	if c.Database.MaxOpenConns <= 0 {
+		fail("database.max_open_conns", "must be positive, got %d", c.Database.MaxOpenConns)
+	}
+	if c.Database.MaxIdleConns < 0 {
+		fail("database.max_idle_conns", "must not be negative, got %d", c.Database.MaxIdleConns)
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		fail("database.max_idle_conns", "must not exceed database.max_open_conns (%d > %d)",
+			c.Database.MaxIdleConns, c.Database.MaxOpenConns)
+	}
+	if c.Database.ConnMaxLifetime < 0 {
+		fail("database.conn_max_lifetime", "must not be negative")
+	}
+	if c.Database.ConnMaxIdleTime < 0 {
+		fail("database.conn_max_idle_time", "must not be negative")
+	}
+
+	if !slices.Contains(logLevels, c.Log.Level) {
+		fail("log.level", "must be one of %v, got %q", logLevels, c.Log.Level)
+	}
+	if c.Log.SlowQueryThreshold < 0 {
+		fail("log.slow_query_threshold", "must not be negative")
+	}
+
+	if !slices.Contains(traceExporters, c.Tracing.Exporter) {
+		fail("tracing.exporter", "must be one of %v, got %q", traceExporters, c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		fail("tracing.sample_ratio", "must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+	}
+	if c.Tracing.OTLPEndpoint != "" {
+		if u, err := url.Parse(c.Tracing.OTLPEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("tracing.otlp_endpoint", "invalid URL %q, expected http(s)://host:port", c.Tracing.OTLPEndpoint)
+		}
+	}
+	if c.Tracing.ServiceName == "" {
+		fail("tracing.service_name", "must not be empty")
+	}
+
+	if archive := c.Archive; archive.Enabled {
+		if archive.AfterMonths <= 0 {
+			fail("archive.after_months", "must be positive, got %d", archive.AfterMonths)
+		}
+		if archive.Interval <= 0 {
+			fail("archive.interval", "must be positive, got %s", archive.Interval)
+		}
+		if archive.BatchSize <= 0 {
+			fail("archive.batch_size", "must be positive, got %d", archive.BatchSize)
+		}
+	}
+
+	if retention := c.Retention; retention.Enabled {
+		if retention.AfterYears <= 0 {
+			fail("retention.after_years", "must be positive, got %d", retention.AfterYears)
+		}
+		if retention.Interval <= 0 {
+			fail("retention.interval", "must be positive, got %s", retention.Interval)
+		}
+		if retention.BatchSize <= 0 {
+			fail("retention.batch_size", "must be positive, got %d", retention.BatchSize)
+		}
+	}
+
+	att := c.Attachments
+	if att.MaxSizeMB <= 0 {
+		fail("attachments.max_size_mb", "must be positive, got %d", att.MaxSizeMB)
+	}
+	if len(att.AllowedTypes) == 0 {
+		fail("attachments.allowed_types", "must not be empty")
+	}
+	if att.ThumbnailSize <= 0 {
+		fail("attachments.thumbnail_size", "must be positive, got %d", att.ThumbnailSize)
+	}
+	switch att.Storage.Backend {
+	case "local":
+		if att.Storage.LocalDir == "" {
+			fail("attachments.storage.local_dir", "must be set for the local backend")
+		}
+	case "s3":
+		if u, err := url.Parse(att.Storage.S3.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("attachments.storage.s3.endpoint", "invalid URL %q, expected http(s)://host[:port]", att.Storage.S3.Endpoint)
+		}
+		if att.Storage.S3.Bucket == "" {
+			fail("attachments.storage.s3.bucket", "must be set for the s3 backend")
+		}
+	default:
+		fail("attachments.storage.backend", "must be one of %v, got %q", blobBackends, att.Storage.Backend)
+	}
+
+	if c.Visits.CheckInRadiusM < 0 {
+		fail("visits.checkin_radius_m", "must not be negative, got %v", c.Visits.CheckInRadiusM)
+	}
+
+	geo := c.Geocoding
+	switch geo.Provider {
+	case "none":
+	case "fixture":
+		if geo.FixtureFile == "" {
+			fail("geocoding.fixture_file", "must be set for the fixture provider")
+		}
+	case "http":
+		if u, err := url.Parse(geo.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("geocoding.url", "invalid URL %q, expected http(s)://host[:port][/path]", geo.URL)
+		}
+		if geo.Timeout <= 0 {
+			fail("geocoding.timeout", "must be positive, got %s", geo.Timeout)
+		}
+	default:
+		fail("geocoding.provider", "must be one of %v, got %q", geocoders, geo.Provider)
+	}
+	if geo.Provider != "none" {
+		if geo.Interval <= 0 {
+			fail("geocoding.interval", "must be positive, got %s", geo.Interval)
+		}
+		if geo.BatchSize <= 0 {
+			fail("geocoding.batch_size", "must be positive, got %d", geo.BatchSize)
+		}
+	}
+
+	if r := c.Routing; r.DayStart < 0 || r.DayStart >= 24*time.Hour {
+		fail("routing.day_start", "must be between 0 and 24h, got %s", r.DayStart)
+	}
+	if c.Routing.ArrivalWindow <= 0 {
+		fail("routing.arrival_window", "must be positive, got %s", c.Routing.ArrivalWindow)
+	}
+	if c.Routing.VisitDuration <= 0 {
+		fail("routing.visit_duration", "must be positive, got %s", c.Routing.VisitDuration)
+	}
+	if c.Routing.SpeedKmh <= 0 {
+		fail("routing.speed_kmh", "must be positive, got %v", c.Routing.SpeedKmh)
+	}
+	if c.Routing.DetourFactor < 1 {
+		fail("routing.detour_factor", "must be at least 1, got %v", c.Routing.DetourFactor)
+	}
+
+	cal := c.Calendar
+	loc := time.Local
+	if cal.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(cal.Timezone); err != nil {
+			fail("calendar.timezone", "unknown time zone %q", cal.Timezone)
+			loc = time.Local
+		}
+	}
+	workdays, err := calendar.ParseWeekdays(cal.Workdays)
+	if err != nil {
+		fail("calendar.workdays", "%v", err)
+	} else if _, err := calendar.New(loc, cal.WorkStart, cal.WorkEnd, workdays, cal.Holidays); err != nil {
+		fail("calendar", "%v", err)
+	}
+
+	if sla := c.SLA; sla.Enabled {
+		if sla.Interval <= 0 {
+			fail("sla.interval", "must be positive, got %s", sla.Interval)
+		}
+		if len(sla.Rules) == 0 {
+			fail("sla.rules", "must not be empty when SLA detection is enabled")
+		}
+	}
+
+	if rec := c.Recurring; rec.Enabled {
+		if rec.Interval <= 0 {
+			fail("recurring.interval", "must be positive, got %s", rec.Interval)
+		}
+		if rec.LeadTime < 0 {
+			fail("recurring.lead_time", "must not be negative, got %s", rec.LeadTime)
+		}
+		if rec.BatchSize <= 0 {
+			fail("recurring.batch_size", "must be positive, got %d", rec.BatchSize)
+		}
+	}
+
+	if inv := c.Inventory; inv.Enabled {
+		if inv.Warehouse == "" {
+			fail("inventory.warehouse", "must not be empty")
+		}
+		if inv.Interval <= 0 {
+			fail("inventory.interval", "must be positive, got %s", inv.Interval)
+		}
+		if inv.BatchSize <= 0 {
+			fail("inventory.batch_size", "must be positive, got %d", inv.BatchSize)
+		}
+	}
+
+	if c.Import.MaxSizeMB <= 0 {
+		fail("import.max_size_mb", "must be positive, got %d", c.Import.MaxSizeMB)
+	}
+
+	in := c.Intake
+	if in.IPBurst < 0 {
+		fail("intake.ip_burst", "must not be negative, got %d", in.IPBurst)
+	}
+	if in.IPBurst > 0 && in.IPEvery <= 0 {
+		fail("intake.ip_every", "must be positive, got %s", in.IPEvery)
+	}
+	if in.PhoneBurst < 0 {
+		fail("intake.phone_burst", "must not be negative, got %d", in.PhoneBurst)
+	}
+	if in.PhoneBurst > 0 && in.PhoneEvery <= 0 {
+		fail("intake.phone_every", "must be positive, got %s", in.PhoneEvery)
+	}
+	if in.QuarantineScore < 0 {
+		fail("intake.quarantine_score", "must not be negative, got %d", in.QuarantineScore)
+	}
+	switch in.Captcha {
+	case "none":
+	case "static":
+		if in.CaptchaToken == "" {
+			fail("intake.captcha_token", "must be set for the static captcha")
+		}
+	default:
+		fail("intake.captcha", "must be one of %v, got %q", captchas, in.Captcha)
+	}
+
+	if idem := c.Idempotency; idem.Enabled {
+		if idem.TTL <= 0 {
+			fail("idempotency.ttl", "must be positive, got %s", idem.TTL)
+		}
+		if idem.Interval <= 0 {
+			fail("idempotency.interval", "must be positive, got %s", idem.Interval)
+		}
+		if idem.BatchSize <= 0 {
+			fail("idempotency.batch_size", "must be positive, got %d", idem.BatchSize)
+		}
+	}
+
+	for i, proxy := range c.Server.TrustedProxies {
+		if _, err := netip.ParsePrefix(proxy); err == nil {
+			continue
+		}
+		if _, err := netip.ParseAddr(proxy); err != nil {
+			fail(fmt.Sprintf("server.trusted_proxies[%d]", i), "invalid proxy %q, expected IP or CIDR", proxy)
+		}
+	}
+
+	for i, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" {
+			fail(fmt.Sprintf("cors.allowed_origins[%d]", i), "invalid origin %q, expected scheme://host[:port] or *", origin)
+		}
+	}
+
+	if email := c.Notifications.Email; email.Enabled {
+		if email.SMTPHost == "" {
+			fail("notifications.email.smtp_host", "must be set when e-mail notifications are enabled")
+		}
+		if email.SMTPPort <= 0 || email.SMTPPort > 65535 {
+			fail("notifications.email.smtp_port", "must be a valid port, got %d", email.SMTPPort)
+		}
+		if email.From == "" {
+			fail("notifications.email.from", "must be set when e-mail notifications are enabled")
+		}
+	}
+	if sms := c.Notifications.SMS; sms.Enabled {
+		if _, err := url.ParseRequestURI(sms.APIURL); err != nil {
+			fail("notifications.sms.api_url", "must be a valid URL when SMS notifications are enabled")
+		}
+		if sms.APIKey == "" {
+			fail("notifications.sms.api_key", "must be set when SMS notifications are enabled")
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Secret — строковое значение, которое не должно попадать в логи и вывод конфигурации
+type Secret string
+
+const redacted = "[REDACTED]"
+
+var dsnPasswordRe = regexp.MustCompile(`(password=)\S+`)
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+func (s Secret) MarshalYAML() (any, error) {
+	return s.String(), nil
+}
+
+// DSN — строка подключения к БД. При выводе из неё удаляется только пароль
+type DSN string
+
+func (d DSN) String() string {
+	if u, err := url.Parse(string(d)); err == nil && u.Scheme != "" {
+		return u.Redacted()
+	}
+	return dsnPasswordRe.ReplaceAllString(string(d), "${1}"+redacted)
+}
+
+func (d DSN) MarshalYAML() (any, error) {
+	return d.String(), nil
+}
@@ -0,0 +1,366 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/config"
+)
+
+func envFrom(m map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := m[key]
+		return v, ok
+	}
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeConfigFile(t, `
+server:
+  addr: ":7000"
+  read_timeout: 5s
+database:
+  max_open_conns: 50
+log:
+  level: warn
+`)
+
+	cases := []struct {
+		name        string
+		args        []string
+		env         map[string]string
+		expAddr     string
+		expTimeout  time.Duration
+		expMaxOpen  int
+		expLogLevel string
+	}{
+		{
+			name:        "Только значения по умолчанию",
+			expAddr:     ":8080",
+			expTimeout:  15 * time.Second,
+			expMaxOpen:  20,
+			expLogLevel: "info",
+		},
+		{
+			name:        "Файл переопределяет значения по умолчанию",
+			args:        []string{"--config", path},
+			expAddr:     ":7000",
+			expTimeout:  5 * time.Second,
+			expMaxOpen:  50,
+			expLogLevel: "warn",
+		},
+		{
+			name:        "Файл из CONFIG_FILE, окружение переопределяет файл",
+			env:         map[string]string{"CONFIG_FILE": path, "SERVER_ADDR": ":7500", "LOG_LEVEL": "debug"},
+			expAddr:     ":7500",
+			expTimeout:  5 * time.Second,
+			expMaxOpen:  50,
+			expLogLevel: "debug",
+		},
+		{
+			name:        "Флаги переопределяют окружение и файл",
+			args:        []string{"--config", path, "--server.addr", ":9000", "--database.max_open_conns=5"},
+			env:         map[string]string{"SERVER_ADDR": ":7500"},
+			expAddr:     ":9000",
+			expTimeout:  5 * time.Second,
+			expMaxOpen:  5,
+			expLogLevel: "warn",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg, _, err := config.Load(c.args, envFrom(c.env))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.Server.Addr != c.expAddr {
+				t.Errorf("Field 'Server.Addr': expected '%v', got '%v'", c.expAddr, cfg.Server.Addr)
+			}
+			if cfg.Server.ReadTimeout != c.expTimeout {
+				t.Errorf("Field 'Server.ReadTimeout': expected '%v', got '%v'", c.expTimeout, cfg.Server.ReadTimeout)
+			}
+			if cfg.Database.MaxOpenConns != c.expMaxOpen {
+				t.Errorf("Field 'Database.MaxOpenConns': expected '%v', got '%v'", c.expMaxOpen, cfg.Database.MaxOpenConns)
+			}
+			if cfg.Log.Level != c.expLogLevel {
+				t.Errorf("Field 'Log.Level': expected '%v', got '%v'", c.expLogLevel, cfg.Log.Level)
+			}
+		})
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	cases := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		content string
+		expErr  string
+	}{
+		{
+			name:   "Некорректная длительность в окружении",
+			env:    map[string]string{"SERVER_READ_TIMEOUT": "soon"},
+			expErr: "env SERVER_READ_TIMEOUT",
+		},
+		{
+			name:   "Некорректное число во флаге",
+			args:   []string{"--database.max_open_conns=many"},
+			expErr: "flag --database.max_open_conns",
+		},
+		{
+			name:   "Некорректная дробь в окружении",
+			env:    map[string]string{"TRACING_SAMPLE_RATIO": "half"},
+			expErr: "env TRACING_SAMPLE_RATIO",
+		},
+		{
+			name:    "Неизвестное поле в файле",
+			content: "server:\n  port: 8080\n",
+			expErr:  "field port not found",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			args := c.args
+			if c.content != "" {
+				args = append(args, "--config", writeConfigFile(t, c.content))
+			}
+			_, _, err := config.Load(args, envFrom(c.env))
+			if err == nil || !strings.Contains(err.Error(), c.expErr) {
+				t.Fatalf("expected error containing %q, got: %v", c.expErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() config.Config {
+		cfg := config.Default()
+		cfg.Database.DSN = "postgres://user:password@db:5432/app_db"
+		return cfg
+	}
+
+	cases := []struct {
+		name   string
+		modify func(*config.Config)
+		expErr string
+	}{
+		{
+			name:   "Корректная конфигурация",
+			modify: func(*config.Config) {},
+		},
+		{
+			name:   "Не задана строка подключения",
+			modify: func(c *config.Config) { c.Database.DSN = "" },
+			expErr: "database.dsn",
+		},
+		{
+			name:   "Неизвестный уровень логирования",
+			modify: func(c *config.Config) { c.Log.Level = "trace" },
+			expErr: "log.level",
+		},
+		{
+			name:   "Пул простаивающих соединений больше общего",
+			modify: func(c *config.Config) { c.Database.MaxIdleConns = c.Database.MaxOpenConns + 1 },
+			expErr: "database.max_idle_conns",
+		},
+		{
+			name:   "Некорректный адрес доверенного прокси",
+			modify: func(c *config.Config) { c.Server.TrustedProxies = []string{"10.0.0.0/8", "proxy.local"} },
+			expErr: "server.trusted_proxies[1]",
+		},
+		{
+			name:   "Некорректный CORS-источник",
+			modify: func(c *config.Config) { c.CORS.AllowedOrigins = []string{"https://ok.example", "example.com"} },
+			expErr: "cors.allowed_origins[1]",
+		},
+		{
+			name:   "Доля сэмплирования больше единицы",
+			modify: func(c *config.Config) { c.Tracing.SampleRatio = 1.5 },
+			expErr: "tracing.sample_ratio",
+		},
+		{
+			name:   "Неизвестный экспортёр трасс",
+			modify: func(c *config.Config) { c.Tracing.Exporter = "jaeger" },
+			expErr: "tracing.exporter",
+		},
+		{
+			name:   "Архивация без срока хранения",
+			modify: func(c *config.Config) { c.Archive.AfterMonths = 0 },
+			expErr: "archive.after_months",
+		},
+		{
+			name: "Обезличивание по сроку хранения без интервала",
+			modify: func(c *config.Config) {
+				c.Retention.Enabled = true
+				c.Retention.Interval = 0
+			},
+			expErr: "retention.interval",
+		},
+		{
+			name: "Хранилище S3 без бакета",
+			modify: func(c *config.Config) {
+				c.Attachments.Storage.Backend = "s3"
+				c.Attachments.Storage.S3.Endpoint = "http://minio:9000"
+			},
+			expErr: "attachments.storage.s3.bucket",
+		},
+		{
+			name:   "Отрицательный радиус отметок",
+			modify: func(c *config.Config) { c.Visits.CheckInRadiusM = -1 },
+			expErr: "visits.checkin_radius_m",
+		},
+		{
+			name: "HTTP-геокодер без адреса API",
+			modify: func(c *config.Config) {
+				c.Geocoding.Provider = "http"
+				c.Geocoding.URL = "nominatim"
+			},
+			expErr: "geocoding.url",
+		},
+		{
+			name:   "Начало рабочего дня за пределами суток",
+			modify: func(c *config.Config) { c.Routing.DayStart = 25 * time.Hour },
+			expErr: "routing.day_start",
+		},
+		{
+			name:   "Неизвестный день недели в рабочем календаре",
+			modify: func(c *config.Config) { c.Calendar.Workdays = []string{"mon", "friday"} },
+			expErr: "calendar.workdays",
+		},
+		{
+			name:   "Неизвестный часовой пояс компании",
+			modify: func(c *config.Config) { c.Calendar.Timezone = "Europe/Atlantis" },
+			expErr: "calendar.timezone",
+		},
+		{
+			name:   "Праздник не в формате YYYY-MM-DD",
+			modify: func(c *config.Config) { c.Calendar.Holidays = []string{"01.01.2027"} },
+			expErr: "calendar",
+		},
+		{
+			name: "SLA без правил",
+			modify: func(c *config.Config) {
+				c.SLA.Enabled = true
+				c.SLA.Rules = nil
+			},
+			expErr: "sla.rules",
+		},
+		{
+			name:   "Отрицательный запас для повторяющихся заявок",
+			modify: func(c *config.Config) { c.Recurring.LeadTime = -time.Hour },
+			expErr: "recurring.lead_time",
+		},
+		{
+			name:   "Пустой код склада",
+			modify: func(c *config.Config) { c.Inventory.Warehouse = "" },
+			expErr: "inventory.warehouse",
+		},
+		{
+			name:   "Нулевой размер файла импорта",
+			modify: func(c *config.Config) { c.Import.MaxSizeMB = 0 },
+			expErr: "import.max_size_mb",
+		},
+		{
+			name:   "Частота заявок с IP без интервала",
+			modify: func(c *config.Config) { c.Intake.IPEvery = 0 },
+			expErr: "intake.ip_every",
+		},
+		{
+			name:   "Без ограничения частоты интервал не нужен",
+			modify: func(c *config.Config) { c.Intake.PhoneBurst, c.Intake.PhoneEvery = 0, 0 },
+		},
+		{
+			name:   "Статическая капча без ответа",
+			modify: func(c *config.Config) { c.Intake.Captcha = "static" },
+			expErr: "intake.captcha_token",
+		},
+		{
+			name:   "Неизвестная капча",
+			modify: func(c *config.Config) { c.Intake.Captcha = "recaptcha" },
+			expErr: "intake.captcha",
+		},
+		{
+			name:   "Без срока хранения ответов",
+			modify: func(c *config.Config) { c.Idempotency.TTL = 0 },
+			expErr: "idempotency.ttl",
+		},
+		{
+			name:   "Выключенные ключи идемпотентности не проверяются",
+			modify: func(c *config.Config) { c.Idempotency = config.IdempotencyConfig{} },
+		},
+		{
+			name:   "Неизвестный геокодер",
+			modify: func(c *config.Config) { c.Geocoding.Provider = "google" },
+			expErr: "geocoding.provider",
+		},
+		{
+			name:   "Неизвестное хранилище вложений",
+			modify: func(c *config.Config) { c.Attachments.Storage.Backend = "ftp" },
+			expErr: "attachments.storage.backend",
+		},
+		{
+			name:   "Отключённая архивация не проверяется",
+			modify: func(c *config.Config) { c.Archive = config.ArchiveConfig{} },
+		},
+		{
+			name: "SMS включены без ключа",
+			modify: func(c *config.Config) {
+				c.Notifications.SMS = config.SMSConfig{Enabled: true, APIURL: "https://sms.example/api"}
+			},
+			expErr: "notifications.sms.api_key",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := valid()
+			c.modify(&cfg)
+			err := cfg.Validate()
+			if c.expErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), c.expErr) {
+				t.Fatalf("expected error containing %q, got: %v", c.expErr, err)
+			}
+		})
+	}
+}
+
+func TestPrint_RedactsSecrets(t *testing.T) {
+	cfg := config.Default()
+	cfg.Database.DSN = "host=db user=app password=topsecret dbname=app"
+	cfg.Notifications.Email.Password = "mailsecret"
+	cfg.Notifications.SMS.APIKey = "smssecret"
+	cfg.Auth.AdminToken = "admintoken"
+
+	var out strings.Builder
+	if err := cfg.Print(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, secret := range []string{"topsecret", "mailsecret", "smssecret", "admintoken"} {
+		if strings.Contains(out.String(), secret) {
+			t.Errorf("printed config contains secret %q:\n%s", secret, out.String())
+		}
+	}
+	if !strings.Contains(out.String(), "user=app") {
+		t.Errorf("printed config lost non-secret part of DSN:\n%s", out.String())
+	}
+}
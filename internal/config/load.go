@@ -0,0 +1,197 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const configFileEnv = "CONFIG_FILE"
+
+// Options — флаги запуска, не являющиеся полями конфигурации
+type Options struct {
+	ConfigPath  string
+	PrintConfig bool
+	// Аргументы после флагов: команда и её параметры
+	Args []string
+}
+
+type pendingFlag struct {
+	name  string
+	value reflect.Value
+	raw   string
+}
+
+// Load собирает конфигурацию из значений по умолчанию, YAML-файла (--config или CONFIG_FILE),
+// переменных окружения и флагов командной строки. Каждое поле доступно флагом
+// с именем вида --server.addr. Проверка значений выполняется отдельно, через Validate.
+func Load(args []string, lookupEnv func(string) (string, bool)) (Config, Options, error) {
+	cfg := Default()
+	var opts Options
+
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&opts.ConfigPath, "config", "", "path to YAML config file (env "+configFileEnv+")")
+	fs.BoolVar(&opts.PrintConfig, "print-config", false, "print effective configuration with secrets redacted and exit")
+
+	var pending []pendingFlag
+	walkFields(reflect.ValueOf(&cfg).Elem(), "", func(path string, field reflect.StructField, value reflect.Value) {
+		collect := func(raw string) error {
+			pending = append(pending, pendingFlag{name: path, value: value, raw: raw})
+			return nil
+		}
+		if value.Kind() == reflect.Bool {
+			fs.BoolFunc(path, field.Tag.Get("usage"), collect)
+		} else {
+			fs.Func(path, field.Tag.Get("usage"), collect)
+		}
+	})
+
+	if err := fs.Parse(args); err != nil {
+		return cfg, opts, fmt.Errorf("flags: %w", err)
+	}
+	opts.Args = fs.Args()
+
+	if opts.ConfigPath == "" {
+		opts.ConfigPath, _ = lookupEnv(configFileEnv)
+	}
+	if opts.ConfigPath != "" {
+		if err := loadFile(&cfg, opts.ConfigPath); err != nil {
+			return cfg, opts, err
+		}
+	}
+
+	var errs []error
+	walkFields(reflect.ValueOf(&cfg).Elem(), "", func(path string, field reflect.StructField, value reflect.Value) {
+		env := field.Tag.Get("env")
+		if env == "" {
+			return
+		}
+		raw, ok := lookupEnv(env)
+		if !ok || raw == "" {
+			return
+		}
+		if err := setValue(value, raw); err != nil {
+			errs = append(errs, fmt.Errorf("env %s: %w", env, err))
+		}
+	})
+
+	for _, pf := range pending {
+		if err := setValue(pf.value, pf.raw); err != nil {
+			errs = append(errs, fmt.Errorf("flag --%s: %w", pf.name, err))
+		}
+	}
+
+	return cfg, opts, errors.Join(errs...)
+}
+
+// Usage выводит список поддерживаемых флагов
+func Usage(w io.Writer) {
+	cfg := Default()
+	fmt.Fprintln(w, "Flags:")
+	fmt.Fprintf(w, "  --config string\n\tpath to YAML config file (env %s)\n", configFileEnv)
+	fmt.Fprintln(w, "  --print-config\n\tprint effective configuration with secrets redacted and exit")
+	walkFields(reflect.ValueOf(&cfg).Elem(), "", func(path string, field reflect.StructField, value reflect.Value) {
+		fmt.Fprintf(w, "  --%s\n\t%s (env %s)\n", path, field.Tag.Get("usage"), field.Tag.Get("env"))
+	})
+}
+
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Print выводит конфигурацию в формате YAML со скрытыми секретами
+func (c Config) Print(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// walkFields обходит листовые поля конфигурации, передавая путь из yaml-тегов
+func walkFields(v reflect.Value, prefix string, fn func(path string, field reflect.StructField, value reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		value := v.Field(i)
+		if value.Kind() == reflect.Struct && value.Type() != durationType {
+			walkFields(value, path, fn)
+			continue
+		}
+		fn(path, field, value)
+	}
+}
+
+func setValue(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported config field type %s", v.Type())
+	}
+	return nil
+}
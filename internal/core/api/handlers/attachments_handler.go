@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/attachments"
+	deterrs "github.com/Owouwun/spkuznetsov/internal/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Запас на служебные части multipart-запроса сверх размера файла
+const multipartOverhead = 1 << 20
+
+// Задаёт методы бизнес-логики
+type AttachmentService interface {
+	Upload(ctx context.Context, orderID uuid.UUID, fileName string, size int64, r io.Reader) (*attachments.Attachment, error)
+	List(ctx context.Context, orderID uuid.UUID) ([]*attachments.Attachment, error)
+	Open(ctx context.Context, orderID, id uuid.UUID, thumbnail bool) (*attachments.Attachment, io.ReadCloser, error)
+}
+
+// AttachmentHandler обрабатывает загрузку и скачивание вложений заявок
+type AttachmentHandler struct {
+	attachmentService AttachmentService
+	maxSize           int64
+}
+
+func NewAttachmentHandler(as AttachmentService, maxSize int64) *AttachmentHandler {
+	return &AttachmentHandler{
+		attachmentService: as,
+		maxSize:           maxSize,
+	}
+}
+
+func (h *AttachmentHandler) respondError(c *gin.Context, action string, err error) {
+	var detErr *deterrs.DetErr
+	switch {
+	case errors.As(err, &detErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, attachments.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "attachment not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to " + action, "details": err.Error()})
+	}
+}
+
+// Upload godoc
+// @Summary Upload an attachment
+// @Description Attach a photo (before/after works) or a document (e.g. a signed act) to an order in status Scheduled, InProgress or Done. The type is detected from the content; images get a JPEG thumbnail. Admin only
+// @Tags attachments
+// @Accept multipart/form-data
+// @Produce json
+// @Security AdminToken
+// @Param id path string true "Order ID" Format(uuid)
+// @Param file formData file true "Attachment file"
+// @Success 201 {object} attachments.Attachment
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 413 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /orders/{id}/attachments [post]
+func (h *AttachmentHandler) Upload(c *gin.Context) {
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id", "details": err.Error()})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxSize+multipartOverhead)
+	fh, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file is too large", "max_size": h.maxSize})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid file", "details": err.Error()})
+		return
+	}
+
+	file, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid file", "details": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	att, err := h.attachmentService.Upload(c, orderID, fh.Filename, fh.Size, file)
+	if err != nil {
+		h.respondError(c, "upload attachment", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, att)
+}
+
+// List godoc
+// @Summary List order attachments
+// @Description Admin only
+// @Tags attachments
+// @Produce json
+// @Security AdminToken
+// @Param id path string true "Order ID" Format(uuid)
+// @Success 200 {array} attachments.Attachment
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /orders/{id}/attachments [get]
+func (h *AttachmentHandler) List(c *gin.Context) {
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id", "details": err.Error()})
+		return
+	}
+
+	atts, err := h.attachmentService.List(c, orderID)
+	if err != nil {
+		h.respondError(c, "get attachments", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, atts)
+}
+
+// Download godoc
+// @Summary Download an attachment
+// @Description Returns the attachment content, or its JPEG thumbnail with thumbnail=true. The attachment is served only through the order it belongs to. Admin only
+// @Tags attachments
+// @Produce octet-stream
+// @Security AdminToken
+// @Param id path string true "Order ID" Format(uuid)
+// @Param attID path string true "Attachment ID" Format(uuid)
+// @Param thumbnail query bool false "Return the thumbnail instead of the original"
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /orders/{id}/attachments/{attID} [get]
+func (h *AttachmentHandler) Download(c *gin.Context) {
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id", "details": err.Error()})
+		return
+	}
+	attID, err := uuid.Parse(c.Param("attID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid attachment id", "details": err.Error()})
+		return
+	}
+	var thumbnail bool
+	if raw := c.Query("thumbnail"); raw != "" {
+		if thumbnail, err = strconv.ParseBool(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid thumbnail", "details": err.Error()})
+			return
+		}
+	}
+
+	att, content, err := h.attachmentService.Open(c, orderID, attID, thumbnail)
+	if err != nil {
+		h.respondError(c, "get attachment", err)
+		return
+	}
+	defer content.Close()
+
+	contentType, size, fileName := att.ContentType, att.Size, att.FileName
+	if thumbnail {
+		contentType, size, fileName = "image/jpeg", -1, strings.TrimSuffix(att.FileName, path.Ext(att.FileName))+"-thumbnail.jpg"
+	}
+
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+	c.DataFromReader(http.StatusOK, size, contentType, content, nil)
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/attachments"
+	deterrs "github.com/Owouwun/spkuznetsov/internal/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type MockAttachmentService struct {
+	UploadFn func(ctx context.Context, orderID uuid.UUID, fileName string, size int64, r io.Reader) (*attachments.Attachment, error)
+	ListFn   func(ctx context.Context, orderID uuid.UUID) ([]*attachments.Attachment, error)
+	OpenFn   func(ctx context.Context, orderID, id uuid.UUID, thumbnail bool) (*attachments.Attachment, io.ReadCloser, error)
+}
+
+func (m *MockAttachmentService) Upload(ctx context.Context, orderID uuid.UUID, fileName string, size int64, r io.Reader) (*attachments.Attachment, error) {
+	if m.UploadFn == nil {
+		return &attachments.Attachment{}, nil
+	}
+	return m.UploadFn(ctx, orderID, fileName, size, r)
+}
+
+func (m *MockAttachmentService) List(ctx context.Context, orderID uuid.UUID) ([]*attachments.Attachment, error) {
+	if m.ListFn == nil {
+		return []*attachments.Attachment{}, nil
+	}
+	return m.ListFn(ctx, orderID)
+}
+
+func (m *MockAttachmentService) Open(ctx context.Context, orderID, id uuid.UUID, thumbnail bool) (*attachments.Attachment, io.ReadCloser, error) {
+	if m.OpenFn == nil {
+		return nil, nil, attachments.ErrNotFound
+	}
+	return m.OpenFn(ctx, orderID, id, thumbnail)
+}
+
+func multipartBody(t *testing.T, field, fileName string, content []byte) ([]byte, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, err := mw.CreateFormFile(field, fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fw.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes(), mw.FormDataContentType()
+}
+
+func TestUploadAttachment_Handler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	orderID := uuid.New()
+	pdf := []byte("%PDF-1.4")
+	body, contentType := multipartBody(t, "file", "act.pdf", pdf)
+	wrongField, wrongFieldType := multipartBody(t, "document", "act.pdf", pdf)
+	tooLarge, tooLargeType := multipartBody(t, "file", "big.pdf", bytes.Repeat([]byte{'x'}, 2<<20))
+
+	cases := []struct {
+		name        string
+		path        string
+		body        []byte
+		contentType string
+		mock        *MockAttachmentService
+		wantStatus  int
+	}{
+		{
+			name:        "Некорректный UUID -> 400",
+			path:        "/orders/not-a-uuid/attachments",
+			body:        body,
+			contentType: contentType,
+			mock:        &MockAttachmentService{},
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "Нет поля file -> 400",
+			path:        "/orders/" + orderID.String() + "/attachments",
+			body:        wrongField,
+			contentType: wrongFieldType,
+			mock:        &MockAttachmentService{},
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "Слишком большой запрос -> 413",
+			path:        "/orders/" + orderID.String() + "/attachments",
+			body:        tooLarge,
+			contentType: tooLargeType,
+			mock:        &MockAttachmentService{},
+			wantStatus:  http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:        "Недопустимый тип или статус -> 400",
+			path:        "/orders/" + orderID.String() + "/attachments",
+			body:        body,
+			contentType: contentType,
+			mock: &MockAttachmentService{
+				UploadFn: func(ctx context.Context, id uuid.UUID, fileName string, size int64, r io.Reader) (*attachments.Attachment, error) {
+					return nil, deterrs.NewDetErr(deterrs.InvalidValue, deterrs.WithField("content type"))
+				},
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "Заявка не найдена -> 404",
+			path:        "/orders/" + orderID.String() + "/attachments",
+			body:        body,
+			contentType: contentType,
+			mock: &MockAttachmentService{
+				UploadFn: func(ctx context.Context, id uuid.UUID, fileName string, size int64, r io.Reader) (*attachments.Attachment, error) {
+					return nil, attachments.ErrNotFound
+				},
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:        "Ошибка хранилища -> 500",
+			path:        "/orders/" + orderID.String() + "/attachments",
+			body:        body,
+			contentType: contentType,
+			mock: &MockAttachmentService{
+				UploadFn: func(ctx context.Context, id uuid.UUID, fileName string, size int64, r io.Reader) (*attachments.Attachment, error) {
+					return nil, errors.New("storage down")
+				},
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:        "Успех -> 201 и содержимое файла передано сервису",
+			path:        "/orders/" + orderID.String() + "/attachments",
+			body:        body,
+			contentType: contentType,
+			mock: &MockAttachmentService{
+				UploadFn: func(ctx context.Context, id uuid.UUID, fileName string, size int64, r io.Reader) (*attachments.Attachment, error) {
+					got, _ := io.ReadAll(r)
+					if id != orderID || fileName != "act.pdf" || size != int64(len(pdf)) || !bytes.Equal(got, pdf) {
+						return nil, errors.New("unexpected upload")
+					}
+					return &attachments.Attachment{ID: uuid.New(), OrderID: id, FileName: fileName}, nil
+				},
+			},
+			wantStatus: http.StatusCreated,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewAttachmentHandler(tc.mock, 1<<20)
+			r := gin.New()
+			r.POST("/orders/:id/attachments", h.Upload)
+
+			w := performRequest(r, "POST", tc.path, tc.body, tc.contentType)
+			if w.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d, body: %s", tc.wantStatus, w.Code, w.Body.String())
+			}
+			requireJSONObj(t, w.Body.Bytes())
+		})
+	}
+}
+
+func TestDownloadAttachment_Handler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	orderID, attID := uuid.New(), uuid.New()
+	base := "/orders/" + orderID.String() + "/attachments/"
+
+	open := func(ctx context.Context, oid, id uuid.UUID, thumbnail bool) (*attachments.Attachment, io.ReadCloser, error) {
+		if oid != orderID || id != attID {
+			return nil, nil, attachments.ErrNotFound
+		}
+		content := "%PDF-1.4"
+		if thumbnail {
+			content = "\xff\xd8thumb"
+		}
+		att := &attachments.Attachment{ID: id, OrderID: oid, FileName: "act.pdf", ContentType: "application/pdf", Size: int64(len(content))}
+		return att, io.NopCloser(strings.NewReader(content)), nil
+	}
+
+	cases := []struct {
+		name        string
+		path        string
+		wantStatus  int
+		wantType    string
+		wantBody    string
+		wantDispose string
+	}{
+		{name: "Некорректный UUID вложения -> 400", path: base + "bad", wantStatus: http.StatusBadRequest},
+		{name: "Некорректный параметр thumbnail -> 400", path: base + attID.String() + "?thumbnail=maybe", wantStatus: http.StatusBadRequest},
+		{name: "Вложение другой заявки -> 404", path: "/orders/" + uuid.NewString() + "/attachments/" + attID.String(), wantStatus: http.StatusNotFound},
+		{
+			name: "Оригинал -> 200", path: base + attID.String(), wantStatus: http.StatusOK,
+			wantType: "application/pdf", wantBody: "%PDF-1.4", wantDispose: `attachment; filename=act.pdf`,
+		},
+		{
+			name: "Превью -> 200 в JPEG", path: base + attID.String() + "?thumbnail=true", wantStatus: http.StatusOK,
+			wantType: "image/jpeg", wantBody: "\xff\xd8thumb", wantDispose: `attachment; filename=act-thumbnail.jpg`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewAttachmentHandler(&MockAttachmentService{OpenFn: open}, 1<<20)
+			r := gin.New()
+			r.GET("/orders/:id/attachments/:attID", h.Download)
+
+			req := httptest.NewRequest("GET", tc.path, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d, body: %s", tc.wantStatus, w.Code, w.Body.String())
+			}
+			if tc.wantStatus != http.StatusOK {
+				requireJSONObj(t, w.Body.Bytes())
+				return
+			}
+			if got := w.Header().Get("Content-Type"); got != tc.wantType {
+				t.Errorf("expected Content-Type %s, got %s", tc.wantType, got)
+			}
+			if got := w.Header().Get("Content-Disposition"); got != tc.wantDispose {
+				t.Errorf("expected Content-Disposition %s, got %s", tc.wantDispose, got)
+			}
+			if w.Body.String() != tc.wantBody {
+				t.Errorf("expected body %q, got %q", tc.wantBody, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	deterrs "github.com/Owouwun/spkuznetsov/internal/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// Задаёт методы бизнес-логики
+type ClientService interface {
+	EraseClient(ctx context.Context, clientPhone string) (*orders.Erasure, error)
+}
+
+// ClientHandler обрабатывает запросы, связанные с персональными данными клиентов
+type ClientHandler struct {
+	clientService ClientService
+}
+
+func NewClientHandler(cs ClientService) *ClientHandler {
+	return &ClientHandler{
+		clientService: cs,
+	}
+}
+
+// EraseRequest identifies the client whose personal data must be erased.
+// swagger:model EraseRequest
+type EraseRequest struct {
+	ClientPhone string `json:"client_phone"`
+}
+
+// Erase godoc
+// @Summary Erase client personal data
+// @Description Anonymize every order (including deleted and archived ones) placed from the given phone. Non-personal fields are kept for statistics; the returned audit record holds only order IDs. Admin only
+// @Tags clients
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param body body EraseRequest true "Client to erase"
+// @Success 200 {object} orders.Erasure
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /clients/erase [post]
+func (h *ClientHandler) Erase(c *gin.Context) {
+	var req EraseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+
+	erasure, err := h.clientService.EraseClient(c, req.ClientPhone)
+	if err != nil {
+		var detErr *deterrs.DetErr
+		if errors.As(err, &detErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to erase client data", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, erasure)
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	deterrs "github.com/Owouwun/spkuznetsov/internal/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type MockClientService struct {
+	EraseClientFn func(ctx context.Context, clientPhone string) (*orders.Erasure, error)
+}
+
+func (m *MockClientService) EraseClient(ctx context.Context, clientPhone string) (*orders.Erasure, error) {
+	if m.EraseClientFn == nil {
+		return &orders.Erasure{}, nil
+	}
+	return m.EraseClientFn(ctx, clientPhone)
+}
+
+func TestErase_Handler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		name       string
+		body       []byte
+		mock       *MockClientService
+		wantStatus int
+	}{
+		{
+			name:       "Некорректный JSON -> 400",
+			body:       []byte("not json"),
+			mock:       &MockClientService{},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Некорректный телефон -> 400",
+			body: []byte(`{"client_phone":"123"}`),
+			mock: &MockClientService{
+				EraseClientFn: func(ctx context.Context, clientPhone string) (*orders.Erasure, error) {
+					return nil, deterrs.NewDetErr(deterrs.InvalidValue, deterrs.WithField("client phone"))
+				},
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Ошибка сервиса -> 500",
+			body: []byte(`{"client_phone":"+71112223344"}`),
+			mock: &MockClientService{
+				EraseClientFn: func(ctx context.Context, clientPhone string) (*orders.Erasure, error) {
+					return nil, errors.New("db down")
+				},
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name: "Успех -> 200 и запись журнала",
+			body: []byte(`{"client_phone":"+71112223344"}`),
+			mock: &MockClientService{
+				EraseClientFn: func(ctx context.Context, clientPhone string) (*orders.Erasure, error) {
+					return &orders.Erasure{ID: 1, Reason: orders.ErasureByRequest, OrderIDs: []uuid.UUID{uuid.New()}}, nil
+				},
+			},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewClientHandler(tc.mock)
+			r := gin.New()
+			r.POST("/clients/erase", h.Erase)
+
+			w := performRequest(r, "POST", "/clients/erase", tc.body, "application/json")
+			if w.Code != tc.wantStatus {
+				t.Fatalf("want %d got %d body: %s", tc.wantStatus, w.Code, w.Body.String())
+			}
+			if tc.wantStatus == http.StatusOK {
+				requireJSONObj(t, w.Body.Bytes())
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const readinessCheckTimeout = 2 * time.Second
+
+// Задаёт проверки состояния зависимостей сервиса
+type HealthChecker interface {
+	Ping(ctx context.Context) error
+	MigrationVersion(ctx context.Context) (version uint, dirty bool, err error)
+}
+
+// HealthHandler отвечает на проверки живости и готовности от оркестратора.
+type HealthHandler struct {
+	checker         HealthChecker
+	expectedVersion uint
+	draining        atomic.Bool
+}
+
+func NewHealthHandler(hc HealthChecker, expectedVersion uint) *HealthHandler {
+	return &HealthHandler{
+		checker:         hc,
+		expectedVersion: expectedVersion,
+	}
+}
+
+// SetDraining переводит сервис в состояние остановки: /readyz начинает отвечать 503,
+// чтобы балансировщик перестал направлять новые запросы
+func (h *HealthHandler) SetDraining() {
+	h.draining.Store(true)
+}
+
+// Liveness отвечает 200, пока процесс способен обслуживать HTTP-запросы
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readiness проверяет доступность БД и то, что схема мигрирована до ожидаемой версии
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	if h.draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "details": "server is shutting down"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, readinessCheckTimeout)
+	defer cancel()
+
+	if err := h.checker.Ping(ctx); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "details": "database unavailable: " + err.Error()})
+		return
+	}
+
+	version, dirty, err := h.checker.MigrationVersion(ctx)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "details": "failed to get migration version: " + err.Error()})
+		return
+	}
+	if dirty {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "details": fmt.Sprintf("migration %d is dirty", version)})
+		return
+	}
+	if version < h.expectedVersion {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "not ready",
+			"details": fmt.Sprintf("migration version %d, expected %d", version, h.expectedVersion),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "ready", "migration_version": version})
+}
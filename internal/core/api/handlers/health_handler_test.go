@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- Mock checker ---------------------------------------------------------
+
+type MockHealthChecker struct {
+	PingFn             func(ctx context.Context) error
+	MigrationVersionFn func(ctx context.Context) (uint, bool, error)
+}
+
+func (m *MockHealthChecker) Ping(ctx context.Context) error {
+	if m.PingFn == nil {
+		return nil
+	}
+	return m.PingFn(ctx)
+}
+func (m *MockHealthChecker) MigrationVersion(ctx context.Context) (uint, bool, error) {
+	if m.MigrationVersionFn == nil {
+		return 0, false, nil
+	}
+	return m.MigrationVersionFn(ctx)
+}
+
+// --- Tests ---------------
+
+func TestLiveness_Handler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h := NewHealthHandler(&MockHealthChecker{}, 1)
+	r := gin.New()
+	r.GET("/healthz", h.Liveness)
+
+	w := performRequest(r, "GET", "/healthz", nil, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("want %d got %d body: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+}
+
+func TestReadiness_Handler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const expectedVersion = 2
+
+	cases := []struct {
+		name       string
+		checker    *MockHealthChecker
+		draining   bool
+		wantStatus int
+	}{
+		{
+			name: "БД недоступна -> 503",
+			checker: &MockHealthChecker{
+				PingFn: func(ctx context.Context) error { return errors.New("conn refused") },
+			},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name: "Ошибка чтения версии миграций -> 503",
+			checker: &MockHealthChecker{
+				MigrationVersionFn: func(ctx context.Context) (uint, bool, error) {
+					return 0, false, errors.New("no table")
+				},
+			},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name: "Грязная миграция -> 503",
+			checker: &MockHealthChecker{
+				MigrationVersionFn: func(ctx context.Context) (uint, bool, error) { return expectedVersion, true, nil },
+			},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name: "Схема отстаёт от ожидаемой версии -> 503",
+			checker: &MockHealthChecker{
+				MigrationVersionFn: func(ctx context.Context) (uint, bool, error) { return expectedVersion - 1, false, nil },
+			},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name: "Сервер останавливается -> 503",
+			checker: &MockHealthChecker{
+				MigrationVersionFn: func(ctx context.Context) (uint, bool, error) { return expectedVersion, false, nil },
+			},
+			draining:   true,
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name: "Готов -> 200",
+			checker: &MockHealthChecker{
+				MigrationVersionFn: func(ctx context.Context) (uint, bool, error) { return expectedVersion, false, nil },
+			},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHealthHandler(tc.checker, expectedVersion)
+			if tc.draining {
+				h.SetDraining()
+			}
+			r := gin.New()
+			r.GET("/readyz", h.Readiness)
+
+			w := performRequest(r, "GET", "/readyz", nil, "")
+			if w.Code != tc.wantStatus {
+				t.Fatalf("want %d got %d body: %s", tc.wantStatus, w.Code, w.Body.String())
+			}
+			requireJSONObj(t, w.Body.Bytes())
+		})
+	}
+}
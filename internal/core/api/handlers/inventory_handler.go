@@ -0,0 +1,366 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/inventory"
+	deterrs "github.com/Owouwun/spkuznetsov/internal/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Задаёт методы бизнес-логики
+type InventoryService interface {
+	CreatePart(ctx context.Context, pp *inventory.PrimaryPart) (*inventory.Part, error)
+	GetPart(ctx context.Context, id uuid.UUID) (*inventory.Part, error)
+	GetParts(ctx context.Context) ([]*inventory.Part, error)
+	GetStock(ctx context.Context, filter inventory.StockFilter) ([]*inventory.Stock, error)
+	Receive(ctx context.Context, r *inventory.Receipt) error
+	Transfer(ctx context.Context, t *inventory.Transfer) error
+	SetOrderParts(ctx context.Context, orderID uuid.UUID, parts []inventory.OrderPart) ([]*inventory.OrderPart, error)
+	GetOrderParts(ctx context.Context, orderID uuid.UUID) ([]*inventory.OrderPart, error)
+	ReturnParts(ctx context.Context, orderID uuid.UUID, r *inventory.Return) error
+	GetLineItems(ctx context.Context, orderID uuid.UUID) ([]*inventory.LineItem, error)
+	EventsSince(ctx context.Context, afterID int64, limit int) ([]*inventory.Event, error)
+}
+
+// Сколько событий склада отдаётся за один запрос
+const inventoryEventsPageSize = 100
+
+// InventoryHandler обрабатывает запросы к каталогу запчастей, остаткам и запчастям заявок
+type InventoryHandler struct {
+	inventoryService InventoryService
+}
+
+func NewInventoryHandler(is InventoryService) *InventoryHandler {
+	return &InventoryHandler{
+		inventoryService: is,
+	}
+}
+
+func (h *InventoryHandler) respondError(c *gin.Context, action string, err error) {
+	var detErr *deterrs.DetErr
+	switch {
+	case errors.As(err, &detErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, inventory.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "part not found"})
+	case errors.Is(err, inventory.ErrOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+	case errors.Is(err, inventory.ErrDuplicateSKU), errors.Is(err, inventory.ErrInsufficientStock):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to " + action, "details": err.Error()})
+	}
+}
+
+// CreatePart godoc
+// @Summary Add a part to the catalog
+// @Description price is per unit, in kopecks. A low_stock event is recorded when the total available stock of the part drops to low_stock_threshold
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Param part body inventory.PrimaryPart true "Part"
+// @Success 201 {object} inventory.Part
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /parts [post]
+func (h *InventoryHandler) CreatePart(c *gin.Context) {
+	var pp inventory.PrimaryPart
+	if err := c.ShouldBindJSON(&pp); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+
+	part, err := h.inventoryService.CreatePart(c, &pp)
+	if err != nil {
+		h.respondError(c, "create part", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, part)
+}
+
+// GetParts godoc
+// @Summary List the parts catalog
+// @Tags inventory
+// @Produce json
+// @Success 200 {array} inventory.Part
+// @Failure 500 {object} map[string]interface{}
+// @Router /parts [get]
+func (h *InventoryHandler) GetParts(c *gin.Context) {
+	parts, err := h.inventoryService.GetParts(c)
+	if err != nil {
+		h.respondError(c, "get parts", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, parts)
+}
+
+// GetPart godoc
+// @Summary Get part by ID
+// @Tags inventory
+// @Produce json
+// @Param id path string true "Part ID" Format(uuid)
+// @Success 200 {object} inventory.Part
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /parts/{id} [get]
+func (h *InventoryHandler) GetPart(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid part id", "details": err.Error()})
+		return
+	}
+
+	part, err := h.inventoryService.GetPart(c, id)
+	if err != nil {
+		h.respondError(c, "get part", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, part)
+}
+
+// GetStock godoc
+// @Summary List stock levels
+// @Description Stock per location: a warehouse ("warehouse:main") or a technician van ("van:<employee id>"). Reserved parts are included in on_hand but not in available
+// @Tags inventory
+// @Produce json
+// @Param part_id query string false "Only this part" Format(uuid)
+// @Param location query string false "Only this location"
+// @Success 200 {array} inventory.Stock
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /stock [get]
+func (h *InventoryHandler) GetStock(c *gin.Context) {
+	filter := inventory.StockFilter{Location: inventory.Location(c.Query("location"))}
+	if s := c.Query("part_id"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid part id", "details": err.Error()})
+			return
+		}
+		filter.PartID = &id
+	}
+
+	stock, err := h.inventoryService.GetStock(c, filter)
+	if err != nil {
+		h.respondError(c, "get stock", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stock)
+}
+
+// Receive godoc
+// @Summary Receive parts into a location
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Param receipt body inventory.Receipt true "Receipt"
+// @Success 200 {object} nil
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /stock/receipts [post]
+func (h *InventoryHandler) Receive(c *gin.Context) {
+	var r inventory.Receipt
+	if err := c.ShouldBindJSON(&r); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+
+	if err := h.inventoryService.Receive(c, &r); err != nil {
+		h.respondError(c, "receive parts", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, nil)
+}
+
+// Transfer godoc
+// @Summary Move parts between locations
+// @Description For example, load parts from the warehouse into a technician van. Only available (not reserved) parts can be moved
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Param transfer body inventory.Transfer true "Transfer"
+// @Success 200 {object} nil
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /stock/transfers [post]
+func (h *InventoryHandler) Transfer(c *gin.Context) {
+	var t inventory.Transfer
+	if err := c.ShouldBindJSON(&t); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+
+	if err := h.inventoryService.Transfer(c, &t); err != nil {
+		h.respondError(c, "transfer parts", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, nil)
+}
+
+// GetEvents godoc
+// @Summary List inventory events
+// @Description Low stock alerts in the order they were raised; pass the last seen id as after_id to get the next page
+// @Tags inventory
+// @Produce json
+// @Param after_id query int false "Return events with a greater id"
+// @Success 200 {array} inventory.Event
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /inventory/events [get]
+func (h *InventoryHandler) GetEvents(c *gin.Context) {
+	var afterID int64
+	if s := c.Query("after_id"); s != "" {
+		var err error
+		if afterID, err = strconv.ParseInt(s, 10, 64); err != nil || afterID < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid after_id"})
+			return
+		}
+	}
+
+	events, err := h.inventoryService.EventsSince(c, afterID, inventoryEventsPageSize)
+	if err != nil {
+		h.respondError(c, "get inventory events", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// SetOrderParts godoc
+// @Summary Set the parts needed for an order
+// @Description Replaces the list. Parts are reserved when the order is scheduled: from the assigned technician van first, the rest from the warehouse. If the order is already scheduled they are re-reserved at once
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID" Format(uuid)
+// @Param parts body []inventory.OrderPart true "Parts"
+// @Success 200 {array} inventory.OrderPart
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /orders/{id}/parts [put]
+func (h *InventoryHandler) SetOrderParts(c *gin.Context) {
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id", "details": err.Error()})
+		return
+	}
+
+	var parts []inventory.OrderPart
+	if err := c.ShouldBindJSON(&parts); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+
+	result, err := h.inventoryService.SetOrderParts(c, orderID, parts)
+	if err != nil {
+		h.respondError(c, "set order parts", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetOrderParts godoc
+// @Summary Get the parts needed for an order and their reservation
+// @Tags inventory
+// @Produce json
+// @Param id path string true "Order ID" Format(uuid)
+// @Success 200 {array} inventory.OrderPart
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /orders/{id}/parts [get]
+func (h *InventoryHandler) GetOrderParts(c *gin.Context) {
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id", "details": err.Error()})
+		return
+	}
+
+	parts, err := h.inventoryService.GetOrderParts(c, orderID)
+	if err != nil {
+		h.respondError(c, "get order parts", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, parts)
+}
+
+// ReturnParts godoc
+// @Summary Return parts consumed by an order
+// @Description Puts the parts back into the given location and reduces the order line item
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID" Format(uuid)
+// @Param return body inventory.Return true "Returned parts"
+// @Success 200 {object} nil
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /orders/{id}/parts/returns [post]
+func (h *InventoryHandler) ReturnParts(c *gin.Context) {
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id", "details": err.Error()})
+		return
+	}
+
+	var r inventory.Return
+	if err := c.ShouldBindJSON(&r); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+
+	if err := h.inventoryService.ReturnParts(c, orderID, &r); err != nil {
+		h.respondError(c, "return parts", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, nil)
+}
+
+// GetLineItems godoc
+// @Summary Get order line items
+// @Description Parts consumed when the order was completed, at the price at that moment, less returns. Parts reported with a catalog sku are consumed automatically
+// @Tags inventory
+// @Produce json
+// @Param id path string true "Order ID" Format(uuid)
+// @Success 200 {array} inventory.LineItem
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /orders/{id}/line-items [get]
+func (h *InventoryHandler) GetLineItems(c *gin.Context) {
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id", "details": err.Error()})
+		return
+	}
+
+	items, err := h.inventoryService.GetLineItems(c, orderID)
+	if err != nil {
+		h.respondError(c, "get order line items", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
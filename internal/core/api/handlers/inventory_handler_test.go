@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/inventory"
+	deterrs "github.com/Owouwun/spkuznetsov/internal/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type MockInventoryService struct {
+	CreatePartFn    func(ctx context.Context, pp *inventory.PrimaryPart) (*inventory.Part, error)
+	GetPartFn       func(ctx context.Context, id uuid.UUID) (*inventory.Part, error)
+	GetStockFn      func(ctx context.Context, filter inventory.StockFilter) ([]*inventory.Stock, error)
+	TransferFn      func(ctx context.Context, t *inventory.Transfer) error
+	SetOrderPartsFn func(ctx context.Context, orderID uuid.UUID, parts []inventory.OrderPart) ([]*inventory.OrderPart, error)
+	ReturnPartsFn   func(ctx context.Context, orderID uuid.UUID, r *inventory.Return) error
+	EventsSinceFn   func(ctx context.Context, afterID int64, limit int) ([]*inventory.Event, error)
+}
+
+func (m *MockInventoryService) CreatePart(ctx context.Context, pp *inventory.PrimaryPart) (*inventory.Part, error) {
+	if m.CreatePartFn == nil {
+		return &inventory.Part{ID: uuid.New(), SKU: pp.SKU, Name: pp.Name}, nil
+	}
+	return m.CreatePartFn(ctx, pp)
+}
+
+func (m *MockInventoryService) GetPart(ctx context.Context, id uuid.UUID) (*inventory.Part, error) {
+	if m.GetPartFn == nil {
+		return &inventory.Part{ID: id}, nil
+	}
+	return m.GetPartFn(ctx, id)
+}
+
+func (m *MockInventoryService) GetParts(ctx context.Context) ([]*inventory.Part, error) {
+	return nil, nil
+}
+
+func (m *MockInventoryService) GetStock(ctx context.Context, filter inventory.StockFilter) ([]*inventory.Stock, error) {
+	if m.GetStockFn == nil {
+		return nil, nil
+	}
+	return m.GetStockFn(ctx, filter)
+}
+
+func (m *MockInventoryService) Receive(ctx context.Context, r *inventory.Receipt) error {
+	return nil
+}
+
+func (m *MockInventoryService) Transfer(ctx context.Context, t *inventory.Transfer) error {
+	if m.TransferFn == nil {
+		return nil
+	}
+	return m.TransferFn(ctx, t)
+}
+
+func (m *MockInventoryService) SetOrderParts(ctx context.Context, orderID uuid.UUID, parts []inventory.OrderPart) ([]*inventory.OrderPart, error) {
+	if m.SetOrderPartsFn == nil {
+		return nil, nil
+	}
+	return m.SetOrderPartsFn(ctx, orderID, parts)
+}
+
+func (m *MockInventoryService) GetOrderParts(ctx context.Context, orderID uuid.UUID) ([]*inventory.OrderPart, error) {
+	return nil, nil
+}
+
+func (m *MockInventoryService) ReturnParts(ctx context.Context, orderID uuid.UUID, r *inventory.Return) error {
+	if m.ReturnPartsFn == nil {
+		return nil
+	}
+	return m.ReturnPartsFn(ctx, orderID, r)
+}
+
+func (m *MockInventoryService) GetLineItems(ctx context.Context, orderID uuid.UUID) ([]*inventory.LineItem, error) {
+	return nil, nil
+}
+
+func (m *MockInventoryService) EventsSince(ctx context.Context, afterID int64, limit int) ([]*inventory.Event, error) {
+	if m.EventsSinceFn == nil {
+		return nil, nil
+	}
+	return m.EventsSinceFn(ctx, afterID, limit)
+}
+
+func TestInventory_Handler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	partID := uuid.New()
+	orderID := uuid.New()
+	cases := []struct {
+		name       string
+		method     string
+		path       string
+		body       []byte
+		mock       *MockInventoryService
+		wantStatus int
+	}{
+		{
+			name:       "Новая запчасть -> 201",
+			method:     "POST",
+			path:       "/parts",
+			body:       []byte(`{"sku":"VALVE-1","name":"Клапан","price":150000}`),
+			mock:       &MockInventoryService{},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:   "Занятый артикул -> 409",
+			method: "POST",
+			path:   "/parts",
+			body:   []byte(`{"sku":"VALVE-1","name":"Клапан"}`),
+			mock: &MockInventoryService{
+				CreatePartFn: func(ctx context.Context, pp *inventory.PrimaryPart) (*inventory.Part, error) {
+					return nil, inventory.ErrDuplicateSKU
+				},
+			},
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:   "Запчасть не найдена -> 404",
+			method: "GET",
+			path:   "/parts/" + partID.String(),
+			mock: &MockInventoryService{
+				GetPartFn: func(ctx context.Context, id uuid.UUID) (*inventory.Part, error) {
+					return nil, inventory.ErrNotFound
+				},
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Некорректный part_id в фильтре остатков -> 400",
+			method:     "GET",
+			path:       "/stock?part_id=abc",
+			mock:       &MockInventoryService{},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "Остатки машины сотрудника -> 200",
+			method: "GET",
+			path:   fmt.Sprintf("/stock?part_id=%s&location=van:7", partID),
+			mock: &MockInventoryService{
+				GetStockFn: func(ctx context.Context, filter inventory.StockFilter) ([]*inventory.Stock, error) {
+					if filter.PartID == nil || *filter.PartID != partID || filter.Location != inventory.Van(7) {
+						return nil, errors.New("unexpected filter")
+					}
+					return []*inventory.Stock{{PartID: partID, Location: filter.Location}}, nil
+				},
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "Перемещение больше доступного -> 409",
+			method: "POST",
+			path:   "/stock/transfers",
+			body:   []byte(fmt.Sprintf(`{"part_id":"%s","from":"warehouse:main","to":"van:7","quantity":5}`, partID)),
+			mock: &MockInventoryService{
+				TransferFn: func(ctx context.Context, t *inventory.Transfer) error {
+					return inventory.ErrInsufficientStock
+				},
+			},
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "Некорректный after_id -> 400",
+			method:     "GET",
+			path:       "/inventory/events?after_id=-1",
+			mock:       &MockInventoryService{},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "Запчасти для выполненной заявки -> 400",
+			method: "PUT",
+			path:   "/orders/" + orderID.String() + "/parts",
+			body:   []byte(fmt.Sprintf(`[{"part_id":"%s","quantity":2}]`, partID)),
+			mock: &MockInventoryService{
+				SetOrderPartsFn: func(ctx context.Context, orderID uuid.UUID, parts []inventory.OrderPart) ([]*inventory.OrderPart, error) {
+					return nil, deterrs.NewDetErr(deterrs.OrderActionNotPermittedByStatus)
+				},
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Запчасти заявки не списком -> 400",
+			method:     "PUT",
+			path:       "/orders/" + orderID.String() + "/parts",
+			body:       []byte(fmt.Sprintf(`{"part_id":"%s","quantity":2}`, partID)),
+			mock:       &MockInventoryService{},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "Возврат по неизвестной заявке -> 404",
+			method: "POST",
+			path:   "/orders/" + orderID.String() + "/parts/returns",
+			body:   []byte(fmt.Sprintf(`{"part_id":"%s","location":"van:7","quantity":1}`, partID)),
+			mock: &MockInventoryService{
+				ReturnPartsFn: func(ctx context.Context, orderID uuid.UUID, r *inventory.Return) error {
+					return inventory.ErrOrderNotFound
+				},
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:   "Ошибка сервиса -> 500",
+			method: "POST",
+			path:   "/orders/" + orderID.String() + "/parts/returns",
+			body:   []byte(fmt.Sprintf(`{"part_id":"%s","location":"van:7","quantity":1}`, partID)),
+			mock: &MockInventoryService{
+				ReturnPartsFn: func(ctx context.Context, orderID uuid.UUID, r *inventory.Return) error {
+					return errors.New("db down")
+				},
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewInventoryHandler(tc.mock)
+			r := gin.New()
+			r.POST("/parts", h.CreatePart)
+			r.GET("/parts/:id", h.GetPart)
+			r.GET("/stock", h.GetStock)
+			r.POST("/stock/transfers", h.Transfer)
+			r.GET("/inventory/events", h.GetEvents)
+			r.PUT("/orders/:id/parts", h.SetOrderParts)
+			r.POST("/orders/:id/parts/returns", h.ReturnParts)
+
+			contentType := ""
+			if tc.body != nil {
+				contentType = "application/json"
+			}
+			w := performRequest(r, tc.method, tc.path, tc.body, contentType)
+			if w.Code != tc.wantStatus {
+				t.Fatalf("want %d, got %d, body: %s", tc.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
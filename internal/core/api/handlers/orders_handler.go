@@ -2,52 +2,118 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/intake"
 	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	deterrs "github.com/Owouwun/spkuznetsov/internal/errors"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // Задаёт методы бизнес-логики
 type OrderService interface {
 	Create(ctx context.Context, pord *orders.PrimaryOrder) (uuid.UUID, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*orders.Order, error)
-	GetAll(ctx context.Context) ([]*orders.Order, error)
+	GetAll(ctx context.Context, filter orders.ListFilter) ([]*orders.Order, error)
+	Release(ctx context.Context, id uuid.UUID) error
 	Preschedule(ctx context.Context, id uuid.UUID, scheduledFor *time.Time) error
 	Assign(ctx context.Context, id uuid.UUID, empID uint) error
 	Schedule(ctx context.Context, id uuid.UUID, scheduledFor *time.Time) error
-	Progress(ctx context.Context, id uuid.UUID, empDescr string) error
+	Progress(ctx context.Context, id uuid.UUID, report *orders.VisitReport) error
 	Complete(ctx context.Context, id uuid.UUID) error
 	Close(ctx context.Context, id uuid.UUID) error
 	Cancel(ctx context.Context, id uuid.UUID, reason string) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	GetVisits(ctx context.Context, id uuid.UUID) ([]*orders.Visit, error)
+	CheckIn(ctx context.Context, id uuid.UUID, p *orders.Presence) (*orders.Visit, error)
+	CheckOut(ctx context.Context, id uuid.UUID, p *orders.Presence, report *orders.VisitReport) (*orders.Visit, error)
+	PlanRoute(ctx context.Context, employeeID uint, day time.Time) (*orders.Route, error)
+}
+
+// Приём заявок с публичного сайта
+type IntakeService interface {
+	Submit(ctx context.Context, sub *intake.Submission) (uuid.UUID, error)
 }
 
 // OrderHandler содержит зависимости и логику HTTP-обработчиков.
 type OrderHandler struct {
 	orderService OrderService
+	// nil — новые заявки создаются без проверок на спам
+	intakeService IntakeService
+}
+
+type OrderHandlerOption func(*OrderHandler)
+
+// WithIntake направляет новые заявки через приём заявок с сайта
+func WithIntake(is IntakeService) OrderHandlerOption {
+	return func(h *OrderHandler) {
+		h.intakeService = is
+	}
 }
 
-func NewOrderHandler(os OrderService) *OrderHandler {
-	return &OrderHandler{
+func NewOrderHandler(os OrderService, opts ...OrderHandlerOption) *OrderHandler {
+	h := &OrderHandler{
 		orderService: os,
 	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 // DTO
 
+// CreateOrderRequest is the public order form: the order itself plus anti-bot fields.
+// swagger:model CreateOrderRequest
+type CreateOrderRequest struct {
+	orders.PrimaryOrder
+	// Captcha provider response received by the form
+	CaptchaToken string `json:"captcha_token,omitempty"`
+	// Honeypot: a hidden form field that people leave empty
+	Website string `json:"website,omitempty"`
+}
+
 // PrescheduleRequest represents a request to set or update a scheduled time for an order.
+// A time without offset (2026-03-02T09:00:00) is the local time of the order.
 // swagger:model PrescheduleRequest
 type PrescheduleRequest struct {
-	ScheduledFor *time.Time `json:"scheduled_for"`
+	ScheduledFor *orders.LocalTime `json:"scheduled_for" swaggertype:"string" example:"2026-03-02T09:00:00"`
 }
 
-// ProgressRequest represents data to report progress on an order.
+// ProgressRequest reports the current visit: work notes, actual arrival/departure and parts used.
 // swagger:model ProgressRequest
 type ProgressRequest struct {
-	EmployeeDescription string `json:"employee_description"`
+	EmployeeDescription string            `json:"employee_description"`
+	ArrivedAt           *time.Time        `json:"arrived_at,omitempty"`
+	DepartedAt          *time.Time        `json:"departed_at,omitempty"`
+	PartsUsed           []orders.UsedPart `json:"parts_used,omitempty"`
+}
+
+// CheckInRequest marks the assigned employee's arrival at the order address.
+// swagger:model CheckInRequest
+type CheckInRequest struct {
+	EmployeeID uint       `json:"employee_id"`
+	Latitude   float64    `json:"lat"`
+	Longitude  float64    `json:"lon"`
+	At         *time.Time `json:"at,omitempty"`
+}
+
+// CheckOutRequest marks the employee's departure and reports the visit.
+// swagger:model CheckOutRequest
+type CheckOutRequest struct {
+	EmployeeID          uint              `json:"employee_id"`
+	Latitude            float64           `json:"lat"`
+	Longitude           float64           `json:"lon"`
+	At                  *time.Time        `json:"at,omitempty"`
+	EmployeeDescription string            `json:"employee_description"`
+	PartsUsed           []orders.UsedPart `json:"parts_used,omitempty"`
 }
 
 // CancelRequest represents reason for cancelling an order.
@@ -56,18 +122,57 @@ type CancelRequest struct {
 	CancelReason string `json:"cancel_reason"`
 }
 
+// Фильтр списка заявок из параметров запроса; false, если ответ об ошибке уже отправлен
+func listFilter(c *gin.Context) (orders.ListFilter, bool) {
+	var filter orders.ListFilter
+	if raw := c.Query("include_deleted"); raw != "" {
+		includeDeleted, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid include_deleted", "details": err.Error()})
+			return filter, false
+		}
+		filter.IncludeDeleted = includeDeleted
+	}
+	if raw := c.Query("overdue"); raw != "" {
+		overdue, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid overdue", "details": err.Error()})
+			return filter, false
+		}
+		filter.Overdue = overdue
+	}
+	if raw := c.Query("template_id"); raw != "" {
+		templateID, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid template_id", "details": err.Error()})
+			return filter, false
+		}
+		filter.TemplateID = &templateID
+	}
+	return filter, true
+}
+
 // Handlers
 
 // GetAll godoc
 // @Summary Get all orders
-// @Description Returns list of all orders
+// @Description Returns list of all orders. Deleted orders are omitted unless include_deleted is set; archived orders are available only by ID. With overdue=true only orders that breached their SLA in the current status are returned; template_id selects orders generated from a recurring order template
 // @Tags orders
 // @Produce json
+// @Param include_deleted query bool false "Include soft-deleted orders"
+// @Param overdue query bool false "Only orders that breached their SLA in the current status"
+// @Param template_id query string false "Only orders generated from this recurring order template" Format(uuid)
 // @Success 200 {array} orders.Order
+// @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /orders [get]
 func (h *OrderHandler) GetAll(c *gin.Context) {
-	orders, err := h.orderService.GetAll(c)
+	filter, ok := listFilter(c)
+	if !ok {
+		return
+	}
+
+	orders, err := h.orderService.GetAll(c, filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get orders", "details": err.Error()})
 		return
@@ -78,7 +183,7 @@ func (h *OrderHandler) GetAll(c *gin.Context) {
 
 // GetByID godoc
 // @Summary Get order by ID
-// @Description Get single order by UUID
+// @Description Get single order by UUID, including archived orders
 // @Tags orders
 // @Produce json
 // @Param id path string true "Order ID" Format(uuid)
@@ -104,33 +209,86 @@ func (h *OrderHandler) GetByID(c *gin.Context) {
 
 // Create godoc
 // @Summary Create a new order
-// @Description Create order with PrimaryOrder payload
+// @Description Create order from the public form. Requests are rate limited per IP and per phone; suspected spam is created in the Quarantined status
 // @Tags orders
 // @Accept json
 // @Produce json
-// @Param order body orders.PrimaryOrder true "Primary order payload"
+// @Param order body CreateOrderRequest true "Primary order payload"
 // @Success 201 {string} string "new order UUID"
 // @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /orders [post]
 func (h *OrderHandler) Create(c *gin.Context) {
-	var primaryOrder orders.PrimaryOrder
+	var req CreateOrderRequest
 
-	if err := c.ShouldBindJSON(&primaryOrder); err != nil {
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
 		return
 	}
 
-	newOrderID, err := h.orderService.Create(c, &primaryOrder)
+	var newOrderID uuid.UUID
+	var err error
+	if h.intakeService != nil {
+		newOrderID, err = h.intakeService.Submit(c, &intake.Submission{
+			Order:        &req.PrimaryOrder,
+			IP:           c.ClientIP(),
+			CaptchaToken: req.CaptchaToken,
+			Honeypot:     req.Website,
+		})
+	} else {
+		newOrderID, err = h.orderService.Create(c, &req.PrimaryOrder)
+	}
 	if err != nil {
-		// TODO: Check on various errors
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create new order", "details": err.Error()})
+		var limitErr *intake.RateLimitError
+		var detErr *deterrs.DetErr
+		switch {
+		case errors.As(err, &limitErr):
+			retryAfter := max(1, int(math.Ceil(limitErr.RetryAfter.Seconds())))
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		case errors.Is(err, intake.ErrCaptchaFailed):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.As(err, &detErr):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create new order", "details": err.Error()})
+		}
 		return
 	}
 
 	c.JSON(http.StatusCreated, newOrderID)
 }
 
+// Release godoc
+// @Summary Release a quarantined order
+// @Description Accept an order that was held as suspected spam: it becomes a regular new order. Admin only
+// @Tags orders
+// @Produce json
+// @Security AdminToken
+// @Param id path string true "Order ID" Format(uuid)
+// @Success 200 {object} nil
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /orders/{id}/release [patch]
+func (h *OrderHandler) Release(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id", "details": err.Error()})
+		return
+	}
+
+	if err := h.orderService.Release(c, id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, nil)
+}
+
 // Preschedule godoc
 // @Summary Preschedule an order (provisional scheduling)
 // @Description Set or update a provisional scheduled time for the order
@@ -156,7 +314,7 @@ func (h *OrderHandler) Preschedule(c *gin.Context) {
 		return
 	}
 
-	if err := h.orderService.Preschedule(c, id, req.ScheduledFor); err != nil {
+	if err := h.orderService.Preschedule(c, id, req.ScheduledFor.Ptr()); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -221,7 +379,7 @@ func (h *OrderHandler) Schedule(c *gin.Context) {
 		return
 	}
 
-	if err := h.orderService.Schedule(c, id, req.ScheduledFor); err != nil {
+	if err := h.orderService.Schedule(c, id, req.ScheduledFor.Ptr()); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -231,7 +389,7 @@ func (h *OrderHandler) Schedule(c *gin.Context) {
 
 // Progress godoc
 // @Summary Report progress for an order
-// @Description Attach employee progress/notes to an order
+// @Description Complete the current visit with employee notes, actual arrival/departure (departure defaults to now) and parts used. The order becomes InProgress until the next visit is scheduled
 // @Tags orders
 // @Accept json
 // @Produce json
@@ -254,7 +412,13 @@ func (h *OrderHandler) Progress(c *gin.Context) {
 		return
 	}
 
-	if err := h.orderService.Progress(c, id, req.EmployeeDescription); err != nil {
+	report := &orders.VisitReport{
+		EmployeeDescription: req.EmployeeDescription,
+		ArrivedAt:           req.ArrivedAt,
+		DepartedAt:          req.DepartedAt,
+		PartsUsed:           req.PartsUsed,
+	}
+	if err := h.orderService.Progress(c, id, report); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -262,6 +426,153 @@ func (h *OrderHandler) Progress(c *gin.Context) {
 	c.JSON(http.StatusOK, nil)
 }
 
+// GetVisits godoc
+// @Summary Get order visits
+// @Description Returns all visits of an order (including archived orders) in creation order
+// @Tags orders
+// @Produce json
+// @Param id path string true "Order ID" Format(uuid)
+// @Success 200 {array} orders.Visit
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /orders/{id}/visits [get]
+func (h *OrderHandler) GetVisits(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id", "details": err.Error()})
+		return
+	}
+
+	visits, err := h.orderService.GetVisits(c, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get visits", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, visits)
+}
+
+// GetRoute godoc
+// @Summary Get an employee's route for a day
+// @Description Returns the employee's scheduled orders for the date in an optimized visiting order (nearest neighbour + 2-opt over the geocoded addresses) with estimated travel times between stops. Visits scheduled at a specific time must be reached within the arrival window; orders without coordinates are listed in unrouted
+// @Tags employees
+// @Produce json
+// @Param id path int true "Employee ID"
+// @Param date query string true "Date, YYYY-MM-DD"
+// @Success 200 {object} orders.Route
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /employees/{id}/route [get]
+func (h *OrderHandler) GetRoute(c *gin.Context) {
+	empID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid employee id", "details": err.Error()})
+		return
+	}
+
+	day, err := time.ParseInLocation(time.DateOnly, c.Query("date"), time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date, expected YYYY-MM-DD", "details": err.Error()})
+		return
+	}
+
+	route, err := h.orderService.PlanRoute(c, uint(empID), day)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to plan route", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, route)
+}
+
+// CheckIn godoc
+// @Summary Check in at the order address
+// @Description The assigned employee marks arrival with GPS coordinates (time defaults to now). The current visit starts and the order moves to InProgress. A check-in farther than the configured radius from the geocoded address is flagged with far_from_address
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID" Format(uuid)
+// @Param body body CheckInRequest true "Check-in payload"
+// @Success 200 {object} orders.Visit
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /orders/{id}/checkin [post]
+func (h *OrderHandler) CheckIn(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id", "details": err.Error()})
+		return
+	}
+
+	var req CheckInRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+
+	visit, err := h.orderService.CheckIn(c, id, &orders.Presence{
+		EmployeeID: req.EmployeeID,
+		Position:   orders.GeoPoint{Lat: req.Latitude, Lon: req.Longitude},
+		At:         req.At,
+	})
+	if err != nil {
+		respondPresenceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, visit)
+}
+
+// CheckOut godoc
+// @Summary Check out from the order address
+// @Description The assigned employee marks departure with GPS coordinates and reports the work done. The visit is completed, its duration is added to the order work time
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID" Format(uuid)
+// @Param body body CheckOutRequest true "Check-out payload"
+// @Success 200 {object} orders.Visit
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /orders/{id}/checkout [post]
+func (h *OrderHandler) CheckOut(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id", "details": err.Error()})
+		return
+	}
+
+	var req CheckOutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+
+	visit, err := h.orderService.CheckOut(c, id, &orders.Presence{
+		EmployeeID: req.EmployeeID,
+		Position:   orders.GeoPoint{Lat: req.Latitude, Lon: req.Longitude},
+		At:         req.At,
+	}, &orders.VisitReport{
+		EmployeeDescription: req.EmployeeDescription,
+		PartsUsed:           req.PartsUsed,
+	})
+	if err != nil {
+		respondPresenceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, visit)
+}
+
+// Отметку может сделать только назначенный на заявку сотрудник
+func respondPresenceError(c *gin.Context, err error) {
+	if errors.Is(err, deterrs.NewDetErr(deterrs.EmployeeNotAssigned)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
 // Complete godoc
 // @Summary Mark order as completed
 // @Tags orders
@@ -342,3 +653,36 @@ func (h *OrderHandler) Cancel(c *gin.Context) {
 
 	c.JSON(http.StatusOK, nil)
 }
+
+// Delete godoc
+// @Summary Delete an order
+// @Description Soft-delete an order: it disappears from listings but stays in the database. Admin only
+// @Tags orders
+// @Produce json
+// @Security AdminToken
+// @Param id path string true "Order ID" Format(uuid)
+// @Success 200 {object} nil
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /orders/{id} [delete]
+func (h *OrderHandler) Delete(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id", "details": err.Error()})
+		return
+	}
+
+	if err := h.orderService.Delete(c, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete order", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, nil)
+}
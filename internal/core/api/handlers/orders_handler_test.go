@@ -5,15 +5,20 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/intake"
 	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	deterrs "github.com/Owouwun/spkuznetsov/internal/errors"
+	"github.com/Owouwun/spkuznetsov/pkg/ratelimit"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // --- Mock service ---------------------------------------------------------
@@ -21,14 +26,20 @@ import (
 type MockOrderService struct {
 	CreateFn      func(ctx context.Context, pord *orders.PrimaryOrder) (uuid.UUID, error)
 	GetByIDFn     func(ctx context.Context, id uuid.UUID) (*orders.Order, error)
-	GetAllFn      func(ctx context.Context) ([]*orders.Order, error)
+	GetAllFn      func(ctx context.Context, filter orders.ListFilter) ([]*orders.Order, error)
+	ReleaseFn     func(ctx context.Context, id uuid.UUID) error
 	PrescheduleFn func(ctx context.Context, id uuid.UUID, scheduledFor *time.Time) error
 	AssignFn      func(ctx context.Context, id uuid.UUID, empID uint) error
 	ScheduleFn    func(ctx context.Context, id uuid.UUID, scheduledFor *time.Time) error
-	ProgressFn    func(ctx context.Context, id uuid.UUID, empDescr string) error
+	ProgressFn    func(ctx context.Context, id uuid.UUID, report *orders.VisitReport) error
 	CompleteFn    func(ctx context.Context, id uuid.UUID) error
 	CloseFn       func(ctx context.Context, id uuid.UUID) error
 	CancelFn      func(ctx context.Context, id uuid.UUID, reason string) error
+	DeleteFn      func(ctx context.Context, id uuid.UUID) error
+	GetVisitsFn   func(ctx context.Context, id uuid.UUID) ([]*orders.Visit, error)
+	CheckInFn     func(ctx context.Context, id uuid.UUID, p *orders.Presence) (*orders.Visit, error)
+	CheckOutFn    func(ctx context.Context, id uuid.UUID, p *orders.Presence, report *orders.VisitReport) (*orders.Visit, error)
+	PlanRouteFn   func(ctx context.Context, employeeID uint, day time.Time) (*orders.Route, error)
 }
 
 func (m *MockOrderService) Create(ctx context.Context, pord *orders.PrimaryOrder) (uuid.UUID, error) {
@@ -43,11 +54,17 @@ func (m *MockOrderService) GetByID(ctx context.Context, id uuid.UUID) (*orders.O
 	}
 	return m.GetByIDFn(ctx, id)
 }
-func (m *MockOrderService) GetAll(ctx context.Context) ([]*orders.Order, error) {
+func (m *MockOrderService) GetAll(ctx context.Context, filter orders.ListFilter) ([]*orders.Order, error) {
 	if m.GetAllFn == nil {
 		return nil, nil
 	}
-	return m.GetAllFn(ctx)
+	return m.GetAllFn(ctx, filter)
+}
+func (m *MockOrderService) Release(ctx context.Context, id uuid.UUID) error {
+	if m.ReleaseFn == nil {
+		return nil
+	}
+	return m.ReleaseFn(ctx, id)
 }
 func (m *MockOrderService) Preschedule(ctx context.Context, id uuid.UUID, scheduledFor *time.Time) error {
 	if m.PrescheduleFn == nil {
@@ -67,11 +84,11 @@ func (m *MockOrderService) Schedule(ctx context.Context, id uuid.UUID, scheduled
 	}
 	return m.ScheduleFn(ctx, id, scheduledFor)
 }
-func (m *MockOrderService) Progress(ctx context.Context, id uuid.UUID, empDescr string) error {
+func (m *MockOrderService) Progress(ctx context.Context, id uuid.UUID, report *orders.VisitReport) error {
 	if m.ProgressFn == nil {
 		return nil
 	}
-	return m.ProgressFn(ctx, id, empDescr)
+	return m.ProgressFn(ctx, id, report)
 }
 func (m *MockOrderService) Complete(ctx context.Context, id uuid.UUID) error {
 	if m.CompleteFn == nil {
@@ -91,6 +108,36 @@ func (m *MockOrderService) Cancel(ctx context.Context, id uuid.UUID, reason stri
 	}
 	return m.CancelFn(ctx, id, reason)
 }
+func (m *MockOrderService) Delete(ctx context.Context, id uuid.UUID) error {
+	if m.DeleteFn == nil {
+		return nil
+	}
+	return m.DeleteFn(ctx, id)
+}
+func (m *MockOrderService) CheckIn(ctx context.Context, id uuid.UUID, p *orders.Presence) (*orders.Visit, error) {
+	if m.CheckInFn == nil {
+		return &orders.Visit{}, nil
+	}
+	return m.CheckInFn(ctx, id, p)
+}
+func (m *MockOrderService) CheckOut(ctx context.Context, id uuid.UUID, p *orders.Presence, report *orders.VisitReport) (*orders.Visit, error) {
+	if m.CheckOutFn == nil {
+		return &orders.Visit{}, nil
+	}
+	return m.CheckOutFn(ctx, id, p, report)
+}
+func (m *MockOrderService) PlanRoute(ctx context.Context, employeeID uint, day time.Time) (*orders.Route, error) {
+	if m.PlanRouteFn == nil {
+		return &orders.Route{EmployeeID: employeeID}, nil
+	}
+	return m.PlanRouteFn(ctx, employeeID, day)
+}
+func (m *MockOrderService) GetVisits(ctx context.Context, id uuid.UUID) ([]*orders.Visit, error) {
+	if m.GetVisitsFn == nil {
+		return []*orders.Visit{}, nil
+	}
+	return m.GetVisitsFn(ctx, id)
+}
 
 // --- Helpers --------------------------------------------------------------
 
@@ -125,28 +172,119 @@ func TestGetAll_Handler(t *testing.T) {
 
 	cases := []struct {
 		name       string
-		mockSetup  MockSetupSimple
+		path       string
+		mockSetup  MockSetupWithCheck
 		wantStatus int
 	}{
 		{
-			name: "Успешно — возвращает список заявок",
-			mockSetup: func() *MockOrderService {
-				return &MockOrderService{
-					GetAllFn: func(ctx context.Context) ([]*orders.Order, error) {
+			name: "Успешно — возвращает список заявок без удалённых",
+			path: "/orders",
+			mockSetup: func() (*MockOrderService, func(t *testing.T)) {
+				var got orders.ListFilter
+				m := &MockOrderService{
+					GetAllFn: func(ctx context.Context, filter orders.ListFilter) ([]*orders.Order, error) {
+						got = filter
 						return []*orders.Order{{}}, nil
 					},
 				}
+				return m, func(t *testing.T) {
+					if got.IncludeDeleted {
+						t.Errorf("deleted orders must be excluded by default")
+					}
+				}
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "include_deleted=true передаётся в сервис",
+			path: "/orders?include_deleted=true",
+			mockSetup: func() (*MockOrderService, func(t *testing.T)) {
+				var got orders.ListFilter
+				m := &MockOrderService{
+					GetAllFn: func(ctx context.Context, filter orders.ListFilter) ([]*orders.Order, error) {
+						got = filter
+						return nil, nil
+					},
+				}
+				return m, func(t *testing.T) {
+					if !got.IncludeDeleted {
+						t.Errorf("expected IncludeDeleted filter")
+					}
+				}
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "Некорректный include_deleted -> 400",
+			path: "/orders?include_deleted=maybe",
+			mockSetup: func() (*MockOrderService, func(t *testing.T)) {
+				return &MockOrderService{}, func(t *testing.T) {}
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "overdue=true передаётся в сервис",
+			path: "/orders?overdue=true",
+			mockSetup: func() (*MockOrderService, func(t *testing.T)) {
+				var got orders.ListFilter
+				m := &MockOrderService{
+					GetAllFn: func(ctx context.Context, filter orders.ListFilter) ([]*orders.Order, error) {
+						got = filter
+						return nil, nil
+					},
+				}
+				return m, func(t *testing.T) {
+					if !got.Overdue || got.IncludeDeleted {
+						t.Errorf("expected only Overdue filter, got %+v", got)
+					}
+				}
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "Некорректный overdue -> 400",
+			path: "/orders?overdue=soon",
+			mockSetup: func() (*MockOrderService, func(t *testing.T)) {
+				return &MockOrderService{}, func(t *testing.T) {}
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "template_id передаётся в сервис",
+			path: "/orders?template_id=6f1c2a1e-3b4d-4c5e-8f90-1a2b3c4d5e6f",
+			mockSetup: func() (*MockOrderService, func(t *testing.T)) {
+				var got orders.ListFilter
+				m := &MockOrderService{
+					GetAllFn: func(ctx context.Context, filter orders.ListFilter) ([]*orders.Order, error) {
+						got = filter
+						return nil, nil
+					},
+				}
+				return m, func(t *testing.T) {
+					if got.TemplateID == nil || got.TemplateID.String() != "6f1c2a1e-3b4d-4c5e-8f90-1a2b3c4d5e6f" {
+						t.Errorf("expected TemplateID filter, got %+v", got)
+					}
+				}
 			},
 			wantStatus: http.StatusOK,
 		},
+		{
+			name: "Некорректный template_id -> 400",
+			path: "/orders?template_id=boiler",
+			mockSetup: func() (*MockOrderService, func(t *testing.T)) {
+				return &MockOrderService{}, func(t *testing.T) {}
+			},
+			wantStatus: http.StatusBadRequest,
+		},
 		{
 			name: "Ошибка сервиса -> 500",
-			mockSetup: func() *MockOrderService {
+			path: "/orders",
+			mockSetup: func() (*MockOrderService, func(t *testing.T)) {
 				return &MockOrderService{
-					GetAllFn: func(ctx context.Context) ([]*orders.Order, error) {
+					GetAllFn: func(ctx context.Context, filter orders.ListFilter) ([]*orders.Order, error) {
 						return nil, errors.New("boom")
 					},
-				}
+				}, func(t *testing.T) {}
 			},
 			wantStatus: http.StatusInternalServerError,
 		},
@@ -154,15 +292,16 @@ func TestGetAll_Handler(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			mock := tc.mockSetup()
+			mock, check := tc.mockSetup()
 			h := NewOrderHandler(mock)
 			r := gin.New()
 			r.GET("/orders", h.GetAll)
 
-			w := performRequest(r, "GET", "/orders", nil, "")
+			w := performRequest(r, "GET", tc.path, nil, "")
 			if w.Code != tc.wantStatus {
 				t.Fatalf("want %d, got %d, body: %s", tc.wantStatus, w.Code, w.Body.String())
 			}
+			check(t)
 		})
 	}
 }
@@ -230,6 +369,148 @@ func TestGetByID_Handler(t *testing.T) {
 	}
 }
 
+func TestGetVisits_Handler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testID := uuid.New()
+	cases := []struct {
+		name       string
+		targetPath string
+		mockSetup  MockSetupSimple
+		wantStatus int
+		wantLen    int
+	}{
+		{
+			name:       "Неправильный UUID -> 400",
+			targetPath: "/orders/not-a-uuid/visits",
+			mockSetup:  func() *MockOrderService { return &MockOrderService{} },
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Ошибка сервиса -> 500",
+			targetPath: "/orders/" + testID.String() + "/visits",
+			mockSetup: func() *MockOrderService {
+				return &MockOrderService{
+					GetVisitsFn: func(ctx context.Context, id uuid.UUID) ([]*orders.Visit, error) {
+						return nil, errors.New("db err")
+					},
+				}
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "Успех -> 200 и список визитов",
+			targetPath: "/orders/" + testID.String() + "/visits",
+			mockSetup: func() *MockOrderService {
+				return &MockOrderService{
+					GetVisitsFn: func(ctx context.Context, id uuid.UUID) ([]*orders.Visit, error) {
+						return []*orders.Visit{{ID: uuid.New(), OrderID: id}, {ID: uuid.New(), OrderID: id}}, nil
+					},
+				}
+			},
+			wantStatus: http.StatusOK,
+			wantLen:    2,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewOrderHandler(tc.mockSetup())
+			r := gin.New()
+			r.GET("/orders/:id/visits", h.GetVisits)
+
+			w := performRequest(r, "GET", tc.targetPath, nil, "")
+			if w.Code != tc.wantStatus {
+				t.Fatalf("want %d got %d body: %s", tc.wantStatus, w.Code, w.Body.String())
+			}
+
+			if tc.wantStatus == http.StatusOK {
+				var visits []orders.Visit
+				if err := json.Unmarshal(w.Body.Bytes(), &visits); err != nil {
+					t.Fatalf("expected JSON array: %v", err)
+				}
+				if len(visits) != tc.wantLen {
+					t.Errorf("expected %d visits, got %d", tc.wantLen, len(visits))
+				}
+			}
+		})
+	}
+}
+
+func TestGetRoute_Handler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		name       string
+		targetPath string
+		mockSetup  MockSetupSimple
+		wantStatus int
+	}{
+		{
+			name:       "Неправильный ID сотрудника -> 400",
+			targetPath: "/employees/abc/route?date=2026-03-02",
+			mockSetup:  func() *MockOrderService { return &MockOrderService{} },
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Дата не указана -> 400",
+			targetPath: "/employees/1/route",
+			mockSetup:  func() *MockOrderService { return &MockOrderService{} },
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Ошибка сервиса -> 500",
+			targetPath: "/employees/1/route?date=2026-03-02",
+			mockSetup: func() *MockOrderService {
+				return &MockOrderService{
+					PlanRouteFn: func(ctx context.Context, employeeID uint, day time.Time) (*orders.Route, error) {
+						return nil, errors.New("db err")
+					},
+				}
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "Успех -> 200 и маршрут на указанный день",
+			targetPath: "/employees/7/route?date=2026-03-02",
+			mockSetup: func() *MockOrderService {
+				return &MockOrderService{
+					PlanRouteFn: func(ctx context.Context, employeeID uint, day time.Time) (*orders.Route, error) {
+						if employeeID != 7 || day.Format(time.DateOnly) != "2026-03-02" || day.Hour() != 0 {
+							return nil, errors.New("unexpected arguments")
+						}
+						return &orders.Route{EmployeeID: employeeID, Date: day.Format(time.DateOnly)}, nil
+					},
+				}
+			},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewOrderHandler(tc.mockSetup())
+			r := gin.New()
+			r.GET("/employees/:id/route", h.GetRoute)
+
+			w := performRequest(r, "GET", tc.targetPath, nil, "")
+			if w.Code != tc.wantStatus {
+				t.Fatalf("want %d got %d body: %s", tc.wantStatus, w.Code, w.Body.String())
+			}
+
+			if tc.wantStatus == http.StatusOK {
+				var route orders.Route
+				if err := json.Unmarshal(w.Body.Bytes(), &route); err != nil {
+					t.Fatalf("expected JSON object: %v", err)
+				}
+				if route.EmployeeID != 7 || route.Date != "2026-03-02" {
+					t.Errorf("unexpected route: %+v", route)
+				}
+			}
+		})
+	}
+}
+
 func TestCreate_Handler(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -251,6 +532,18 @@ func TestCreate_Handler(t *testing.T) {
 			},
 			wantStatus: http.StatusBadRequest,
 		},
+		{
+			name: "Некорректные данные заявки -> 400",
+			body: validJSON,
+			mockSetup: func() *MockOrderService {
+				return &MockOrderService{
+					CreateFn: func(ctx context.Context, pord *orders.PrimaryOrder) (uuid.UUID, error) {
+						return uuid.Nil, deterrs.NewDetErr(deterrs.EmptyField, deterrs.WithField("client_name"))
+					},
+				}
+			},
+			wantStatus: http.StatusBadRequest,
+		},
 		{
 			name: "Ошибка сервиса -> 500",
 			body: validJSON,
@@ -296,11 +589,194 @@ func TestCreate_Handler(t *testing.T) {
 	}
 }
 
+type MockIntakeService struct {
+	SubmitFn func(ctx context.Context, sub *intake.Submission) (uuid.UUID, error)
+}
+
+func (m *MockIntakeService) Submit(ctx context.Context, sub *intake.Submission) (uuid.UUID, error) {
+	if m.SubmitFn == nil {
+		return uuid.Nil, nil
+	}
+	return m.SubmitFn(ctx, sub)
+}
+
+func TestCreate_Handler_Intake(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	expectedID := uuid.New()
+	body := []byte(`{"client_name":"Иван","client_phone":"89161234567","address":{"line":"Москва, Тверская, 1"},"captcha_token":"ok","website":"spam"}`)
+
+	cases := []struct {
+		name       string
+		submitFn   func(ctx context.Context, sub *intake.Submission) (uuid.UUID, error)
+		wantStatus int
+		wantHeader string
+	}{
+		{
+			name: "Заявка передаётся с полями защиты от ботов -> 201",
+			submitFn: func(ctx context.Context, sub *intake.Submission) (uuid.UUID, error) {
+				if sub.Order.ClientName != "Иван" || sub.CaptchaToken != "ok" || sub.Honeypot != "spam" || sub.IP != "192.0.2.1" {
+					t.Errorf("unexpected submission %+v, order %+v", sub, sub.Order)
+				}
+				return expectedID, nil
+			},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "Превышена частота -> 429 с Retry-After",
+			submitFn: func(ctx context.Context, sub *intake.Submission) (uuid.UUID, error) {
+				return uuid.Nil, &intake.RateLimitError{Scope: intake.ScopePhone, RetryAfter: 90*time.Second + time.Millisecond}
+			},
+			wantStatus: http.StatusTooManyRequests,
+			wantHeader: "91",
+		},
+		{
+			name: "Некорректные данные заявки -> 400",
+			submitFn: func(ctx context.Context, sub *intake.Submission) (uuid.UUID, error) {
+				return uuid.Nil, deterrs.NewDetErr(deterrs.InvalidValue, deterrs.WithField("client_phone"))
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Капча не пройдена -> 403",
+			submitFn: func(ctx context.Context, sub *intake.Submission) (uuid.UUID, error) {
+				return uuid.Nil, intake.ErrCaptchaFailed
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "Ошибка сервиса -> 500",
+			submitFn: func(ctx context.Context, sub *intake.Submission) (uuid.UUID, error) {
+				return uuid.Nil, errors.New("db down")
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			orderService := &MockOrderService{
+				CreateFn: func(ctx context.Context, pord *orders.PrimaryOrder) (uuid.UUID, error) {
+					t.Error("expected order to be submitted through intake")
+					return uuid.Nil, nil
+				},
+			}
+			h := NewOrderHandler(orderService, WithIntake(&MockIntakeService{SubmitFn: tc.submitFn}))
+			r := gin.New()
+			r.POST("/orders", h.Create)
+
+			w := performRequest(r, "POST", "/orders", body, "application/json")
+			if w.Code != tc.wantStatus {
+				t.Fatalf("want %d got %d body: %s", tc.wantStatus, w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("Retry-After"); got != tc.wantHeader {
+				t.Errorf("want Retry-After %q, got %q", tc.wantHeader, got)
+			}
+		})
+	}
+}
+
+func TestCreate_Handler_SpoofedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	orderService := &MockOrderService{
+		CreateFn: func(ctx context.Context, pord *orders.PrimaryOrder) (uuid.UUID, error) {
+			return uuid.New(), nil
+		},
+	}
+	intakeService := intake.NewIntakeService(orderService, intake.WithPolicy(intake.Policy{
+		PerIP:    ratelimit.Rate{Burst: 1, Every: time.Hour},
+		PerPhone: ratelimit.Rate{Burst: 10, Every: time.Hour},
+	}))
+	h := NewOrderHandler(orderService, WithIntake(intakeService))
+	r := gin.New()
+	// Как в приложении без настроенных прокси
+	if err := r.SetTrustedProxies(nil); err != nil {
+		t.Fatal(err)
+	}
+	r.POST("/orders", h.Create)
+
+	wantStatuses := []int{http.StatusCreated, http.StatusTooManyRequests}
+	for i, want := range wantStatuses {
+		body := []byte(fmt.Sprintf(`{"client_name":"Иван","client_phone":"8916123456%d","address":{"line":"Москва, Тверская, 1"}}`, i))
+		req := httptest.NewRequest(http.MethodPost, "/orders", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		// Каждый запрос подставляет новый адрес, но приходит с того же соединения
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("203.0.113.%d", i+1))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != want {
+			t.Fatalf("request %d: want %d got %d body: %s", i+1, want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestRelease_Handler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	id := uuid.New()
+	cases := []struct {
+		name       string
+		path       string
+		mockSetup  MockSetupSimple
+		wantStatus int
+	}{
+		{
+			name:       "Неверный UUID -> 400",
+			path:       "/orders/zzz/release",
+			mockSetup:  func() *MockOrderService { return &MockOrderService{} },
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Заявка не на проверке -> 400",
+			path: "/orders/" + id.String() + "/release",
+			mockSetup: func() *MockOrderService {
+				return &MockOrderService{
+					ReleaseFn: func(ctx context.Context, id uuid.UUID) error {
+						return deterrs.NewDetErr(deterrs.OrderActionNotPermittedByStatus)
+					},
+				}
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Успех -> 200",
+			path: "/orders/" + id.String() + "/release",
+			mockSetup: func() *MockOrderService {
+				return &MockOrderService{
+					ReleaseFn: func(ctx context.Context, got uuid.UUID) error {
+						if got != id {
+							t.Errorf("want id %s, got %s", id, got)
+						}
+						return nil
+					},
+				}
+			},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := tc.mockSetup()
+			h := NewOrderHandler(mock)
+			r := gin.New()
+			r.PATCH("/orders/:id/release", h.Release)
+
+			w := performRequest(r, "PATCH", tc.path, nil, "")
+			if w.Code != tc.wantStatus {
+				t.Fatalf("want %d got %d body: %s", tc.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
 func TestPreschedule_Handler(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	id := uuid.New()
-	validPayload, _ := json.Marshal(PrescheduleRequest{ScheduledFor: func() *time.Time { t := time.Now().Add(time.Hour); return &t }()})
+	validPayload, _ := json.Marshal(PrescheduleRequest{ScheduledFor: &orders.LocalTime{Time: time.Now().Add(time.Hour)}})
 
 	cases := []struct {
 		name       string
@@ -336,6 +812,29 @@ func TestPreschedule_Handler(t *testing.T) {
 			},
 			wantStatus: http.StatusBadRequest,
 		},
+		{
+			name: "Время без смещения передаётся как местное время заявки",
+			path: "/orders/" + id.String() + "/preschedule",
+			body: []byte(`{"scheduled_for":"2026-03-02T09:00:00"}`),
+			mockSetup: func() *MockOrderService {
+				return &MockOrderService{
+					PrescheduleFn: func(ctx context.Context, id uuid.UUID, scheduledFor *time.Time) error {
+						if scheduledFor == nil || scheduledFor.Location() != orders.OrderLocal || scheduledFor.Hour() != 9 {
+							return fmt.Errorf("expected 9:00 local time of the order, got %v", scheduledFor)
+						}
+						return nil
+					},
+				}
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Некорректное время -> 400",
+			path:       "/orders/" + id.String() + "/preschedule",
+			body:       []byte(`{"scheduled_for":"02.03.2026 9:00"}`),
+			mockSetup:  func() *MockOrderService { return &MockOrderService{} },
+			wantStatus: http.StatusBadRequest,
+		},
 		{
 			name: "Успех -> 200",
 			path: "/orders/" + id.String() + "/preschedule",
@@ -445,7 +944,7 @@ func TestSchedule_Handler(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	id := uuid.New()
-	payload, _ := json.Marshal(PrescheduleRequest{ScheduledFor: func() *time.Time { t := time.Now().Add(2 * time.Hour); return &t }()})
+	payload, _ := json.Marshal(PrescheduleRequest{ScheduledFor: &orders.LocalTime{Time: time.Now().Add(2 * time.Hour)}})
 
 	cases := []struct {
 		name       string
@@ -542,7 +1041,7 @@ func TestProgress_Handler(t *testing.T) {
 			body: validBody,
 			mockSetup: func() (*MockOrderService, func(t *testing.T)) {
 				return &MockOrderService{
-					ProgressFn: func(ctx context.Context, id uuid.UUID, empDescr string) error {
+					ProgressFn: func(ctx context.Context, id uuid.UUID, report *orders.VisitReport) error {
 						return errors.New("cannot progress")
 					},
 				}, nil
@@ -555,16 +1054,37 @@ func TestProgress_Handler(t *testing.T) {
 			body: validBody,
 			mockSetup: func() (*MockOrderService, func(t *testing.T)) {
 				return &MockOrderService{
-					ProgressFn: func(ctx context.Context, id uuid.UUID, empDescr string) error { return nil },
+					ProgressFn: func(ctx context.Context, id uuid.UUID, report *orders.VisitReport) error { return nil },
 				}, nil
 			},
 			wantStatus: http.StatusOK,
 		},
+		{
+			name: "Отчёт о визите передаётся сервису -> 200",
+			path: "/orders/" + id.String() + "/progress",
+			body: []byte(`{"employee_description":"заменён насос","arrived_at":"2025-01-10T09:00:00Z","departed_at":"2025-01-10T11:30:00Z","parts_used":[{"name":"насос","quantity":1}]}`),
+			mockSetup: func() (*MockOrderService, func(t *testing.T)) {
+				var got *orders.VisitReport
+				mock := &MockOrderService{
+					ProgressFn: func(ctx context.Context, id uuid.UUID, report *orders.VisitReport) error {
+						got = report
+						return nil
+					},
+				}
+				return mock, func(t *testing.T) {
+					if got == nil || got.EmployeeDescription != "заменён насос" || got.ArrivedAt == nil || got.DepartedAt == nil ||
+						len(got.PartsUsed) != 1 || got.PartsUsed[0].Quantity != 1 {
+						t.Errorf("unexpected visit report: %+v", got)
+					}
+				}
+			},
+			wantStatus: http.StatusOK,
+		},
 	}
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			mock, _ := tc.mockSetup()
+			mock, check := tc.mockSetup()
 			h := NewOrderHandler(mock)
 			r := gin.New()
 			r.PATCH("/orders/:id/progress", h.Progress)
@@ -573,6 +1093,107 @@ func TestProgress_Handler(t *testing.T) {
 			if w.Code != tc.wantStatus {
 				t.Fatalf("want %d got %d body: %s", tc.wantStatus, w.Code, w.Body.String())
 			}
+			if check != nil {
+				check(t)
+			}
+		})
+	}
+}
+
+func TestCheckInOut_Handler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	id := uuid.New()
+	body := []byte(`{"employee_id":1,"lat":55.75,"lon":37.61,"employee_description":"готово","parts_used":[{"name":"кран","quantity":1}]}`)
+	checkPresence := func(p *orders.Presence) error {
+		if p.EmployeeID != 1 || p.Position.Lat != 55.75 || p.Position.Lon != 37.61 {
+			return errors.New("unexpected presence")
+		}
+		return nil
+	}
+
+	cases := []struct {
+		name       string
+		path       string
+		body       []byte
+		mock       *MockOrderService
+		wantStatus int
+	}{
+		{
+			name:       "Неверный UUID -> 400",
+			path:       "/orders/bad/checkin",
+			body:       body,
+			mock:       &MockOrderService{},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Плохое тело -> 400",
+			path:       "/orders/" + id.String() + "/checkout",
+			body:       []byte("nojson"),
+			mock:       &MockOrderService{},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Сотрудник не назначен на заявку -> 403",
+			path: "/orders/" + id.String() + "/checkin",
+			body: body,
+			mock: &MockOrderService{
+				CheckInFn: func(ctx context.Context, id uuid.UUID, p *orders.Presence) (*orders.Visit, error) {
+					return nil, deterrs.NewDetErr(deterrs.EmployeeNotAssigned)
+				},
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "Недопустимый статус -> 400",
+			path: "/orders/" + id.String() + "/checkout",
+			body: body,
+			mock: &MockOrderService{
+				CheckOutFn: func(ctx context.Context, id uuid.UUID, p *orders.Presence, report *orders.VisitReport) (*orders.Visit, error) {
+					return nil, deterrs.NewDetErr(deterrs.OrderActionNotPermittedByStatus)
+				},
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Прибытие -> 200 и визит",
+			path: "/orders/" + id.String() + "/checkin",
+			body: body,
+			mock: &MockOrderService{
+				CheckInFn: func(ctx context.Context, id uuid.UUID, p *orders.Presence) (*orders.Visit, error) {
+					return &orders.Visit{ID: uuid.New()}, checkPresence(p)
+				},
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "Отъезд -> 200 и визит",
+			path: "/orders/" + id.String() + "/checkout",
+			body: body,
+			mock: &MockOrderService{
+				CheckOutFn: func(ctx context.Context, id uuid.UUID, p *orders.Presence, report *orders.VisitReport) (*orders.Visit, error) {
+					if report.EmployeeDescription != "готово" || len(report.PartsUsed) != 1 {
+						return nil, errors.New("unexpected report")
+					}
+					return &orders.Visit{ID: uuid.New()}, checkPresence(p)
+				},
+			},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewOrderHandler(tc.mock)
+			r := gin.New()
+			r.POST("/orders/:id/checkin", h.CheckIn)
+			r.POST("/orders/:id/checkout", h.CheckOut)
+
+			w := performRequest(r, "POST", tc.path, tc.body, "application/json")
+			if w.Code != tc.wantStatus {
+				t.Fatalf("want %d got %d body: %s", tc.wantStatus, w.Code, w.Body.String())
+			}
+			requireJSONObj(t, w.Body.Bytes())
 		})
 	}
 }
@@ -758,3 +1379,71 @@ func TestCancel_Handler(t *testing.T) {
 		})
 	}
 }
+
+func TestDelete_Handler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	id := uuid.New()
+	cases := []struct {
+		name       string
+		path       string
+		mockSetup  MockSetupSimple
+		wantStatus int
+	}{
+		{
+			name:       "Неверный UUID -> 400",
+			path:       "/orders/zzz",
+			mockSetup:  func() *MockOrderService { return &MockOrderService{} },
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Заявка не найдена -> 404",
+			path: "/orders/" + id.String(),
+			mockSetup: func() *MockOrderService {
+				return &MockOrderService{
+					DeleteFn: func(ctx context.Context, id uuid.UUID) error { return gorm.ErrRecordNotFound },
+				}
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "Сервис вернул ошибку -> 500",
+			path: "/orders/" + id.String(),
+			mockSetup: func() *MockOrderService {
+				return &MockOrderService{
+					DeleteFn: func(ctx context.Context, id uuid.UUID) error { return errors.New("bad") },
+				}
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name: "Успех -> 200",
+			path: "/orders/" + id.String(),
+			mockSetup: func() *MockOrderService {
+				return &MockOrderService{
+					DeleteFn: func(ctx context.Context, got uuid.UUID) error {
+						if got != id {
+							return errors.New("unexpected id")
+						}
+						return nil
+					},
+				}
+			},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := tc.mockSetup()
+			h := NewOrderHandler(mock)
+			r := gin.New()
+			r.DELETE("/orders/:id", h.Delete)
+
+			w := performRequest(r, "DELETE", tc.path, nil, "")
+			if w.Code != tc.wantStatus {
+				t.Fatalf("want %d got %d body: %s", tc.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+const defaultHeartbeatInterval = 15 * time.Second
+
+// Сколько событий журнала читается за раз при возобновлении потока
+const orderEventsPageSize = 1000
+
+// Задаёт источник событий заявок для SSE-потока
+type OrderEventSource interface {
+	Subscribe(filter orders.EventFilter) (<-chan *orders.Event, func())
+	EventsSince(ctx context.Context, afterID int64, filter orders.EventFilter, limit int) ([]*orders.Event, error)
+}
+
+// OrderStreamHandler отдаёт изменения заявок в виде Server-Sent Events.
+type OrderStreamHandler struct {
+	source    OrderEventSource
+	heartbeat time.Duration
+}
+
+func NewOrderStreamHandler(src OrderEventSource) *OrderStreamHandler {
+	return &OrderStreamHandler{
+		source:    src,
+		heartbeat: defaultHeartbeatInterval,
+	}
+}
+
+func parseEventFilter(c *gin.Context) (orders.EventFilter, error) {
+	var filter orders.EventFilter
+
+	for _, rawStatus := range c.QueryArray("status") {
+		status, err := strconv.ParseInt(rawStatus, 10, 8)
+		if err != nil {
+			return filter, fmt.Errorf("invalid status %q: %w", rawStatus, err)
+		}
+		filter.Statuses = append(filter.Statuses, orders.Status(status))
+	}
+
+	if rawEmpID := c.Query("employee_id"); rawEmpID != "" {
+		empID, err := strconv.ParseUint(rawEmpID, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid employee id %q: %w", rawEmpID, err)
+		}
+		id := uint(empID)
+		filter.EmployeeID = &id
+	}
+
+	return filter, nil
+}
+
+func parseLastEventID(c *gin.Context) (int64, error) {
+	rawID := c.GetHeader("Last-Event-ID")
+	if rawID == "" {
+		rawID = c.Query("last_event_id")
+	}
+	if rawID == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(rawID, 10, 64)
+}
+
+func writeEvent(c *gin.Context, ev *orders.Event) {
+	c.Render(-1, sse.Event{
+		Id:    strconv.FormatInt(ev.ID, 10),
+		Event: string(ev.Type),
+		Data:  ev,
+	})
+	c.Writer.Flush()
+}
+
+// Stream godoc
+// @Summary Stream order changes
+// @Description Server-Sent Events stream of order changes. Supports resuming via Last-Event-ID header.
+// @Description An event may be committed after events with greater IDs, so a resumed stream repeats
+// @Description up to 100 events before Last-Event-ID; clients should skip events with IDs they already have
+// @Tags orders
+// @Produce text/event-stream
+// @Param status query []int false "Order statuses to include" collectionFormat(multi)
+// @Param employee_id query int false "Employee ID to filter by"
+// @Param last_event_id query int false "Resume after this event ID (alternative to Last-Event-ID header)"
+// @Success 200 {object} orders.Event
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /orders/stream [get]
+func (h *OrderStreamHandler) Stream(c *gin.Context) {
+	filter, err := parseEventFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid filter", "details": err.Error()})
+		return
+	}
+
+	lastID, err := parseLastEventID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid last event id", "details": err.Error()})
+		return
+	}
+
+	// Подписываемся до чтения журнала, чтобы не потерять события между выборкой и подпиской
+	events, unsubscribe := h.source.Subscribe(filter)
+	defer unsubscribe()
+
+	// Первая порция читается до ответа, чтобы ошибку журнала можно было вернуть статусом
+	seen := orders.NewSeenEvents()
+	afterID := orders.ReplayFrom(lastID)
+	var backlog []*orders.Event
+	if lastID > 0 {
+		backlog, err = h.source.EventsSince(c, afterID, filter, orderEventsPageSize)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get order events", "details": err.Error()})
+			return
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	// Поток живёт дольше, чем WriteTimeout сервера
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	for len(backlog) > 0 {
+		for _, ev := range backlog {
+			seen.Add(ev.ID)
+			writeEvent(c, ev)
+			afterID = ev.ID
+		}
+		if len(backlog) < orderEventsPageSize {
+			break
+		}
+		if backlog, err = h.source.EventsSince(c, afterID, filter, orderEventsPageSize); err != nil {
+			// Клиент переподключится с последним полученным событием
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			if seen.Add(ev.ID) {
+				writeEvent(c, ev)
+			}
+		case <-heartbeat.C:
+			_, _ = fmt.Fprint(c.Writer, ": ping\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/gin-gonic/gin"
+)
+
+// --- Mock source ----------------------------------------------------------
+
+type MockOrderEventSource struct {
+	Live          []*orders.Event
+	EventsSinceFn func(ctx context.Context, afterID int64, filter orders.EventFilter, limit int) ([]*orders.Event, error)
+
+	gotFilter orders.EventFilter
+}
+
+func (m *MockOrderEventSource) Subscribe(filter orders.EventFilter) (<-chan *orders.Event, func()) {
+	m.gotFilter = filter
+
+	ch := make(chan *orders.Event, len(m.Live))
+	for _, ev := range m.Live {
+		ch <- ev
+	}
+	// Закрытый канал завершает поток, как при отключении медленного подписчика
+	close(ch)
+	return ch, func() {}
+}
+
+func (m *MockOrderEventSource) EventsSince(ctx context.Context, afterID int64, filter orders.EventFilter, limit int) ([]*orders.Event, error) {
+	if m.EventsSinceFn == nil {
+		return nil, nil
+	}
+	return m.EventsSinceFn(ctx, afterID, filter, limit)
+}
+
+// События журнала с ID из [from, to]
+func eventRange(from, to int64) []*orders.Event {
+	var events []*orders.Event
+	for id := from; id <= to; id++ {
+		events = append(events, &orders.Event{ID: id, Type: orders.EventAssigned})
+	}
+	return events
+}
+
+func eventIDs(events []*orders.Event) []string {
+	ids := make([]string, 0, len(events))
+	for _, ev := range events {
+		ids = append(ids, strconv.FormatInt(ev.ID, 10))
+	}
+	return ids
+}
+
+// --- Tests ---------------
+
+func TestStream_Handler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		name         string
+		path         string
+		lastEventID  string
+		source       func(t *testing.T) *MockOrderEventSource
+		wantStatus   int
+		wantIDs      []string
+		wantEmployee *uint
+	}{
+		{
+			name:       "Некорректный статус -> 400",
+			path:       "/orders/stream?status=abc",
+			source:     func(t *testing.T) *MockOrderEventSource { return &MockOrderEventSource{} },
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "Некорректный Last-Event-ID -> 400",
+			path:        "/orders/stream",
+			lastEventID: "not-a-number",
+			source:      func(t *testing.T) *MockOrderEventSource { return &MockOrderEventSource{} },
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "Ошибка чтения журнала -> 500",
+			path:        "/orders/stream",
+			lastEventID: "10",
+			source: func(t *testing.T) *MockOrderEventSource {
+				return &MockOrderEventSource{
+					EventsSinceFn: func(ctx context.Context, afterID int64, filter orders.EventFilter, limit int) ([]*orders.Event, error) {
+						return nil, errors.New("db err")
+					},
+				}
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name: "Без Last-Event-ID — только новые события",
+			path: "/orders/stream?employee_id=7",
+			source: func(t *testing.T) *MockOrderEventSource {
+				return &MockOrderEventSource{
+					Live: []*orders.Event{{ID: 1, Type: orders.EventAssigned}},
+					EventsSinceFn: func(ctx context.Context, afterID int64, filter orders.EventFilter, limit int) ([]*orders.Event, error) {
+						t.Fatalf("journal must not be read without Last-Event-ID")
+						return nil, nil
+					},
+				}
+			},
+			wantStatus:   http.StatusOK,
+			wantIDs:      []string{"1"},
+			wantEmployee: func() *uint { id := uint(7); return &id }(),
+		},
+		{
+			name:        "Возобновление с Last-Event-ID без дублей",
+			path:        "/orders/stream?status=3&status=4",
+			lastEventID: "104",
+			source: func(t *testing.T) *MockOrderEventSource {
+				return &MockOrderEventSource{
+					Live: []*orders.Event{
+						{ID: 106, Type: orders.EventScheduled},
+						{ID: 103, Type: orders.EventCompleted},
+						{ID: 107, Type: orders.EventProgressed},
+					},
+					EventsSinceFn: func(ctx context.Context, afterID int64, filter orders.EventFilter, limit int) ([]*orders.Event, error) {
+						if afterID != 104-orders.EventsReplayWindow {
+							t.Errorf("want afterID %d, got %d", 104-orders.EventsReplayWindow, afterID)
+						}
+						if len(filter.Statuses) != 2 {
+							t.Errorf("want 2 statuses in filter, got %v", filter.Statuses)
+						}
+						return []*orders.Event{
+							{ID: 104, Type: orders.EventAssigned},
+							{ID: 105, Type: orders.EventAssigned},
+							{ID: 106, Type: orders.EventScheduled},
+						}, nil
+					},
+				}
+			},
+			wantStatus: http.StatusOK,
+			// 103 зафиксировано позже 106 и приходит после него
+			wantIDs: []string{"104", "105", "106", "103", "107"},
+		},
+		{
+			name:        "Пропущенные события читаются порциями",
+			path:        "/orders/stream",
+			lastEventID: "100",
+			source: func(t *testing.T) *MockOrderEventSource {
+				return &MockOrderEventSource{
+					EventsSinceFn: func(ctx context.Context, afterID int64, filter orders.EventFilter, limit int) ([]*orders.Event, error) {
+						switch afterID {
+						case 0:
+							return eventRange(1, int64(limit)), nil
+						case int64(limit):
+							return eventRange(int64(limit)+1, int64(limit)+2), nil
+						}
+						t.Errorf("unexpected afterID %d", afterID)
+						return nil, nil
+					},
+				}
+			},
+			wantStatus: http.StatusOK,
+			wantIDs:    eventIDs(eventRange(1, orderEventsPageSize+2)),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			source := tc.source(t)
+			h := NewOrderStreamHandler(source)
+			r := gin.New()
+			r.GET("/orders/stream", h.Stream)
+
+			req := httptest.NewRequest("GET", tc.path, nil)
+			if tc.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", tc.lastEventID)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tc.wantStatus {
+				t.Fatalf("want %d got %d body: %s", tc.wantStatus, w.Code, w.Body.String())
+			}
+			if tc.wantStatus != http.StatusOK {
+				return
+			}
+
+			if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+				t.Fatalf("want text/event-stream content type, got %q", ct)
+			}
+
+			var gotIDs []string
+			for _, line := range strings.Split(w.Body.String(), "\n") {
+				if id, ok := strings.CutPrefix(line, "id:"); ok {
+					gotIDs = append(gotIDs, id)
+				}
+			}
+			if strings.Join(gotIDs, ",") != strings.Join(tc.wantIDs, ",") {
+				t.Fatalf("want event ids %v, got %v (body: %s)", tc.wantIDs, gotIDs, w.Body.String())
+			}
+
+			if tc.wantEmployee != nil {
+				if source.gotFilter.EmployeeID == nil || *source.gotFilter.EmployeeID != *tc.wantEmployee {
+					t.Fatalf("want employee filter %d, got %v", *tc.wantEmployee, source.gotFilter.EmployeeID)
+				}
+			}
+		})
+	}
+}
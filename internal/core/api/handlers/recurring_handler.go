@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/api/middleware"
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/recurring"
+	deterrs "github.com/Owouwun/spkuznetsov/internal/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Задаёт методы бизнес-логики
+type RecurringService interface {
+	Create(ctx context.Context, pt *recurring.PrimaryTemplate) (*recurring.Template, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*recurring.Template, error)
+	GetAll(ctx context.Context, filter recurring.ListFilter) ([]*recurring.Template, error)
+	Pause(ctx context.Context, id uuid.UUID) (*recurring.Template, error)
+	Resume(ctx context.Context, id uuid.UUID) (*recurring.Template, error)
+	End(ctx context.Context, id uuid.UUID) (*recurring.Template, error)
+}
+
+// RecurringHandler обрабатывает запросы к шаблонам повторяющихся заявок
+type RecurringHandler struct {
+	recurringService RecurringService
+}
+
+func NewRecurringHandler(rs RecurringService) *RecurringHandler {
+	return &RecurringHandler{
+		recurringService: rs,
+	}
+}
+
+func (h *RecurringHandler) respondError(c *gin.Context, action string, err error) {
+	var detErr *deterrs.DetErr
+	switch {
+	case errors.As(err, &detErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, recurring.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "recurring order template not found"})
+	case errors.Is(err, recurring.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to " + action, "details": err.Error()})
+	}
+}
+
+// Create godoc
+// @Summary Create a recurring order template
+// @Description Create a template (e.g. a yearly boiler maintenance contract) from which orders are generated ahead of each occurrence. rrule supports FREQ=MONTHLY|YEARLY with INTERVAL, COUNT and UNTIL; with preschedule=true generated orders are prescheduled for the occurrence day
+// @Tags recurring-orders
+// @Accept json
+// @Produce json
+// @Param template body recurring.PrimaryTemplate true "Recurring order template"
+// @Success 201 {object} recurring.Template
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /recurring-orders [post]
+func (h *RecurringHandler) Create(c *gin.Context) {
+	var pt recurring.PrimaryTemplate
+	if err := c.ShouldBindJSON(&pt); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+
+	t, err := h.recurringService.Create(c, &pt)
+	if err != nil {
+		h.respondError(c, "create recurring order template", err)
+		return
+	}
+
+	middleware.IdempotencyClient(c, t.Order.ClientPhone)
+	c.JSON(http.StatusCreated, t)
+}
+
+// GetAll godoc
+// @Summary List recurring order templates
+// @Tags recurring-orders
+// @Produce json
+// @Param client_phone query string false "Only templates of the client with this phone"
+// @Success 200 {array} recurring.Template
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /recurring-orders [get]
+func (h *RecurringHandler) GetAll(c *gin.Context) {
+	templates, err := h.recurringService.GetAll(c, recurring.ListFilter{ClientPhone: c.Query("client_phone")})
+	if err != nil {
+		h.respondError(c, "get recurring order templates", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+// GetByID godoc
+// @Summary Get recurring order template by ID
+// @Description Orders generated from the template are listed by GET /orders?template_id=
+// @Tags recurring-orders
+// @Produce json
+// @Param id path string true "Template ID" Format(uuid)
+// @Success 200 {object} recurring.Template
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /recurring-orders/{id} [get]
+func (h *RecurringHandler) GetByID(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid template id", "details": err.Error()})
+		return
+	}
+
+	t, err := h.recurringService.GetByID(c, id)
+	if err != nil {
+		h.respondError(c, "get recurring order template", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, t)
+}
+
+// Pause godoc
+// @Summary Pause a recurring order template
+// @Description No orders are generated while the template is paused
+// @Tags recurring-orders
+// @Produce json
+// @Param id path string true "Template ID" Format(uuid)
+// @Success 200 {object} recurring.Template
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /recurring-orders/{id}/pause [patch]
+func (h *RecurringHandler) Pause(c *gin.Context) {
+	h.change(c, "pause recurring order template", h.recurringService.Pause)
+}
+
+// Resume godoc
+// @Summary Resume a paused recurring order template
+// @Description Occurrences that passed while the template was paused are skipped
+// @Tags recurring-orders
+// @Produce json
+// @Param id path string true "Template ID" Format(uuid)
+// @Success 200 {object} recurring.Template
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /recurring-orders/{id}/resume [patch]
+func (h *RecurringHandler) Resume(c *gin.Context) {
+	h.change(c, "resume recurring order template", h.recurringService.Resume)
+}
+
+// End godoc
+// @Summary End a recurring order template
+// @Description No more orders are generated; already generated orders are kept
+// @Tags recurring-orders
+// @Produce json
+// @Param id path string true "Template ID" Format(uuid)
+// @Success 200 {object} recurring.Template
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /recurring-orders/{id}/end [patch]
+func (h *RecurringHandler) End(c *gin.Context) {
+	h.change(c, "end recurring order template", h.recurringService.End)
+}
+
+func (h *RecurringHandler) change(c *gin.Context, action string, fn func(ctx context.Context, id uuid.UUID) (*recurring.Template, error)) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid template id", "details": err.Error()})
+		return
+	}
+
+	t, err := fn(c, id)
+	if err != nil {
+		h.respondError(c, action, err)
+		return
+	}
+
+	middleware.IdempotencyClient(c, t.Order.ClientPhone)
+	c.JSON(http.StatusOK, t)
+}
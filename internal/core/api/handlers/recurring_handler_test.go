@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/recurring"
+	deterrs "github.com/Owouwun/spkuznetsov/internal/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type MockRecurringService struct {
+	CreateFn  func(ctx context.Context, pt *recurring.PrimaryTemplate) (*recurring.Template, error)
+	GetByIDFn func(ctx context.Context, id uuid.UUID) (*recurring.Template, error)
+	GetAllFn  func(ctx context.Context, filter recurring.ListFilter) ([]*recurring.Template, error)
+	PauseFn   func(ctx context.Context, id uuid.UUID) (*recurring.Template, error)
+	ResumeFn  func(ctx context.Context, id uuid.UUID) (*recurring.Template, error)
+	EndFn     func(ctx context.Context, id uuid.UUID) (*recurring.Template, error)
+}
+
+func (m *MockRecurringService) Create(ctx context.Context, pt *recurring.PrimaryTemplate) (*recurring.Template, error) {
+	if m.CreateFn == nil {
+		return &recurring.Template{}, nil
+	}
+	return m.CreateFn(ctx, pt)
+}
+
+func (m *MockRecurringService) GetByID(ctx context.Context, id uuid.UUID) (*recurring.Template, error) {
+	if m.GetByIDFn == nil {
+		return &recurring.Template{ID: id}, nil
+	}
+	return m.GetByIDFn(ctx, id)
+}
+
+func (m *MockRecurringService) GetAll(ctx context.Context, filter recurring.ListFilter) ([]*recurring.Template, error) {
+	if m.GetAllFn == nil {
+		return nil, nil
+	}
+	return m.GetAllFn(ctx, filter)
+}
+
+func (m *MockRecurringService) Pause(ctx context.Context, id uuid.UUID) (*recurring.Template, error) {
+	if m.PauseFn == nil {
+		return &recurring.Template{ID: id, Status: recurring.StatusPaused}, nil
+	}
+	return m.PauseFn(ctx, id)
+}
+
+func (m *MockRecurringService) Resume(ctx context.Context, id uuid.UUID) (*recurring.Template, error) {
+	if m.ResumeFn == nil {
+		return &recurring.Template{ID: id, Status: recurring.StatusActive}, nil
+	}
+	return m.ResumeFn(ctx, id)
+}
+
+func (m *MockRecurringService) End(ctx context.Context, id uuid.UUID) (*recurring.Template, error) {
+	if m.EndFn == nil {
+		return &recurring.Template{ID: id, Status: recurring.StatusEnded}, nil
+	}
+	return m.EndFn(ctx, id)
+}
+
+func TestCreateRecurring_Handler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		name       string
+		body       []byte
+		mock       *MockRecurringService
+		check      func(t *testing.T, body []byte)
+		wantStatus int
+	}{
+		{
+			name:       "Некорректный JSON -> 400",
+			body:       []byte("not json"),
+			mock:       &MockRecurringService{},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Некорректная дата начала -> 400",
+			body:       []byte(`{"rrule":"FREQ=YEARLY","start":"02.03.2027"}`),
+			mock:       &MockRecurringService{},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Некорректное правило -> 400",
+			body: []byte(`{"rrule":"FREQ=DAILY","start":"2027-03-02"}`),
+			mock: &MockRecurringService{
+				CreateFn: func(ctx context.Context, pt *recurring.PrimaryTemplate) (*recurring.Template, error) {
+					return nil, deterrs.NewDetErr(deterrs.InvalidValue, deterrs.WithField("rrule"))
+				},
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Ошибка сервиса -> 500",
+			body: []byte(`{"rrule":"FREQ=YEARLY","start":"2027-03-02"}`),
+			mock: &MockRecurringService{
+				CreateFn: func(ctx context.Context, pt *recurring.PrimaryTemplate) (*recurring.Template, error) {
+					return nil, errors.New("db down")
+				},
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name: "Успех -> 201, дата передаётся без времени",
+			body: []byte(`{"order":{"client_name":"Иван","client_phone":"+71112223344","address":"Ленина, 1"},"rrule":"FREQ=YEARLY","start":"2027-03-02","preschedule":true}`),
+			mock: &MockRecurringService{
+				CreateFn: func(ctx context.Context, pt *recurring.PrimaryTemplate) (*recurring.Template, error) {
+					if pt.Start.String() != "2027-03-02" || !pt.Preschedule || pt.Order.Address.Line != "Ленина, 1" {
+						return nil, errors.New("unexpected template")
+					}
+					next := pt.Start
+					return &recurring.Template{ID: uuid.New(), RRule: pt.RRule, Start: pt.Start, NextOn: &next, Status: recurring.StatusActive}, nil
+				},
+			},
+			check: func(t *testing.T, body []byte) {
+				var got map[string]any
+				if err := json.Unmarshal(body, &got); err != nil {
+					t.Fatalf("unmarshal: %v", err)
+				}
+				if got["start"] != "2027-03-02" || got["next_on"] != "2027-03-02" || got["status"] != "active" {
+					t.Errorf("unexpected response: %s", body)
+				}
+			},
+			wantStatus: http.StatusCreated,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewRecurringHandler(tc.mock)
+			r := gin.New()
+			r.POST("/recurring-orders", h.Create)
+
+			w := performRequest(r, "POST", "/recurring-orders", tc.body, "application/json")
+			if w.Code != tc.wantStatus {
+				t.Fatalf("want %d, got %d, body: %s", tc.wantStatus, w.Code, w.Body.String())
+			}
+			if tc.check != nil {
+				tc.check(t, w.Body.Bytes())
+			}
+		})
+	}
+}
+
+func TestGetAllRecurring_Handler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var got recurring.ListFilter
+	h := NewRecurringHandler(&MockRecurringService{
+		GetAllFn: func(ctx context.Context, filter recurring.ListFilter) ([]*recurring.Template, error) {
+			got = filter
+			if filter.ClientPhone == "123" {
+				return nil, deterrs.NewDetErr(deterrs.InvalidValue, deterrs.WithField("client phone"))
+			}
+			return []*recurring.Template{{ID: uuid.New()}}, nil
+		},
+	})
+	r := gin.New()
+	r.GET("/recurring-orders", h.GetAll)
+
+	w := performRequest(r, "GET", "/recurring-orders?client_phone=%2B71112223344", nil, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("want 200, got %d, body: %s", w.Code, w.Body.String())
+	}
+	if got.ClientPhone != "+71112223344" {
+		t.Errorf("expected client phone filter, got %+v", got)
+	}
+
+	w = performRequest(r, "GET", "/recurring-orders?client_phone=123", nil, "")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("want 400, got %d, body: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestChangeRecurring_Handler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	id := uuid.New()
+	cases := []struct {
+		name       string
+		path       string
+		mock       *MockRecurringService
+		wantStatus int
+	}{
+		{
+			name:       "Некорректный UUID -> 400",
+			path:       "/recurring-orders/not-a-uuid/pause",
+			mock:       &MockRecurringService{},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Пауза -> 200",
+			path:       "/recurring-orders/" + id.String() + "/pause",
+			mock:       &MockRecurringService{},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "Возобновление не приостановленного -> 400",
+			path: "/recurring-orders/" + id.String() + "/resume",
+			mock: &MockRecurringService{
+				ResumeFn: func(ctx context.Context, id uuid.UUID) (*recurring.Template, error) {
+					return nil, deterrs.NewDetErr(deterrs.TemplateActionNotPermittedByStatus)
+				},
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Шаблон не найден -> 404",
+			path: "/recurring-orders/" + id.String() + "/end",
+			mock: &MockRecurringService{
+				EndFn: func(ctx context.Context, id uuid.UUID) (*recurring.Template, error) {
+					return nil, recurring.ErrNotFound
+				},
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "Параллельное изменение -> 409",
+			path: "/recurring-orders/" + id.String() + "/pause",
+			mock: &MockRecurringService{
+				PauseFn: func(ctx context.Context, id uuid.UUID) (*recurring.Template, error) {
+					return nil, recurring.ErrConflict
+				},
+			},
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name: "Ошибка сервиса -> 500",
+			path: "/recurring-orders/" + id.String() + "/end",
+			mock: &MockRecurringService{
+				EndFn: func(ctx context.Context, id uuid.UUID) (*recurring.Template, error) {
+					return nil, errors.New("db down")
+				},
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewRecurringHandler(tc.mock)
+			r := gin.New()
+			r.PATCH("/recurring-orders/:id/pause", h.Pause)
+			r.PATCH("/recurring-orders/:id/resume", h.Resume)
+			r.PATCH("/recurring-orders/:id/end", h.End)
+
+			w := performRequest(r, "PATCH", tc.path, nil, "")
+			if w.Code != tc.wantStatus {
+				t.Fatalf("want %d, got %d, body: %s", tc.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/reports"
+	deterrs "github.com/Owouwun/spkuznetsov/internal/errors"
+	"github.com/Owouwun/spkuznetsov/pkg/tabular"
+	"github.com/gin-gonic/gin"
+)
+
+// Задаёт методы бизнес-логики
+type ReportService interface {
+	Throughput(ctx context.Context, dr reports.DateRange) (*reports.Throughput, error)
+	Transitions(ctx context.Context, dr reports.DateRange) (*reports.Transitions, error)
+	Cancellations(ctx context.Context, dr reports.DateRange) (*reports.Cancellations, error)
+	Revenue(ctx context.Context, dr reports.DateRange) (*reports.Revenue, error)
+	EmployeePerformance(ctx context.Context, dr reports.DateRange) (*reports.EmployeePerformance, error)
+}
+
+// ReportHandler отдаёт отчёты для руководства в JSON или CSV
+type ReportHandler struct {
+	reportService ReportService
+}
+
+func NewReportHandler(rs ReportService) *ReportHandler {
+	return &ReportHandler{
+		reportService: rs,
+	}
+}
+
+// Отчёт в CSV: заголовок и строки таблицы
+type reportTable struct {
+	header []string
+	rows   [][]string
+}
+
+func (h *ReportHandler) respondError(c *gin.Context, action string, err error) {
+	var detErr *deterrs.DetErr
+	if errors.As(err, &detErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to " + action, "details": err.Error()})
+}
+
+// Формат отчёта из ?format=json|csv; false, если ответ об ошибке уже отправлен
+func reportFormat(c *gin.Context) (string, bool) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format, expected json or csv"})
+		return "", false
+	}
+	return format, true
+}
+
+func reportRange(c *gin.Context) reports.DateRange {
+	return reports.DateRange{From: c.Query("from"), To: c.Query("to")}
+}
+
+// Отдаёт отчёт в запрошенном формате. CSV начинается с BOM, чтобы Excel открывал его в UTF-8
+func respondReport(c *gin.Context, format, name string, p reports.Period, report any, table func() reportTable) {
+	if format == "json" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	t := table()
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s_%s_%s.csv"`, name, p.From, p.To))
+	c.Status(http.StatusOK)
+
+	_, _ = c.Writer.WriteString("\ufeff")
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write(t.header)
+	for _, row := range t.rows {
+		escaped := make([]string, len(row))
+		for i, v := range row {
+			escaped[i] = tabular.EscapeFormula(v)
+		}
+		_ = w.Write(escaped)
+	}
+	w.Flush()
+}
+
+func itoa(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
+
+// Throughput godoc
+// @Summary Orders created, completed and canceled
+// @Description Counts per day of the period (in the company time zone) and totals. Deleted orders are not counted; archived ones are
+// @Tags reports
+// @Security AdminToken
+// @Produce json
+// @Produce text/csv
+// @Param from query string true "First day of the period (YYYY-MM-DD)"
+// @Param to query string true "Last day of the period, inclusive (YYYY-MM-DD)"
+// @Param format query string false "json (default) or csv"
+// @Success 200 {object} reports.Throughput
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /reports/throughput [get]
+func (h *ReportHandler) Throughput(c *gin.Context) {
+	format, ok := reportFormat(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.reportService.Throughput(c, reportRange(c))
+	if err != nil {
+		h.respondError(c, "build throughput report", err)
+		return
+	}
+
+	respondReport(c, format, "throughput", report.Period, report, func() reportTable {
+		t := reportTable{header: []string{"date", "created", "completed", "canceled"}}
+		for _, d := range report.Days {
+			t.rows = append(t.rows, []string{d.Date, itoa(d.Created), itoa(d.Completed), itoa(d.Canceled)})
+		}
+		return t
+	})
+}
+
+// Transitions godoc
+// @Summary Median time per status transition
+// @Description For every pair of statuses: how many transitions were made in the period and the median time spent in the source status, in seconds
+// @Tags reports
+// @Security AdminToken
+// @Produce json
+// @Produce text/csv
+// @Param from query string true "First day of the period (YYYY-MM-DD)"
+// @Param to query string true "Last day of the period, inclusive (YYYY-MM-DD)"
+// @Param format query string false "json (default) or csv"
+// @Success 200 {object} reports.Transitions
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /reports/transitions [get]
+func (h *ReportHandler) Transitions(c *gin.Context) {
+	format, ok := reportFormat(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.reportService.Transitions(c, reportRange(c))
+	if err != nil {
+		h.respondError(c, "build transitions report", err)
+		return
+	}
+
+	respondReport(c, format, "transitions", report.Period, report, func() reportTable {
+		t := reportTable{header: []string{"from_status", "to_status", "count", "median_seconds"}}
+		for _, tr := range report.Transitions {
+			t.rows = append(t.rows, []string{
+				tr.FromStatus, tr.ToStatus, itoa(tr.Count), strconv.FormatFloat(tr.MedianSeconds, 'f', 0, 64),
+			})
+		}
+		return t
+	})
+}
+
+// Cancellations godoc
+// @Summary Cancellation reasons breakdown
+// @Description Orders canceled in the period grouped by cancel reason (lower-cased, extra spaces removed; "unspecified" if empty)
+// @Tags reports
+// @Security AdminToken
+// @Produce json
+// @Produce text/csv
+// @Param from query string true "First day of the period (YYYY-MM-DD)"
+// @Param to query string true "Last day of the period, inclusive (YYYY-MM-DD)"
+// @Param format query string false "json (default) or csv"
+// @Success 200 {object} reports.Cancellations
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /reports/cancellations [get]
+func (h *ReportHandler) Cancellations(c *gin.Context) {
+	format, ok := reportFormat(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.reportService.Cancellations(c, reportRange(c))
+	if err != nil {
+		h.respondError(c, "build cancellations report", err)
+		return
+	}
+
+	respondReport(c, format, "cancellations", report.Period, report, func() reportTable {
+		t := reportTable{header: []string{"reason", "count"}}
+		for _, r := range report.Reasons {
+			t.rows = append(t.rows, []string{r.Reason, itoa(r.Count)})
+		}
+		return t
+	})
+}
+
+// Revenue godoc
+// @Summary Revenue
+// @Description Orders paid in the period, per day of payment, with the cost of parts written off for them at the prices at the time of write-off, in kopecks
+// @Tags reports
+// @Security AdminToken
+// @Produce json
+// @Produce text/csv
+// @Param from query string true "First day of the period (YYYY-MM-DD)"
+// @Param to query string true "Last day of the period, inclusive (YYYY-MM-DD)"
+// @Param format query string false "json (default) or csv"
+// @Success 200 {object} reports.Revenue
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /reports/revenue [get]
+func (h *ReportHandler) Revenue(c *gin.Context) {
+	format, ok := reportFormat(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.reportService.Revenue(c, reportRange(c))
+	if err != nil {
+		h.respondError(c, "build revenue report", err)
+		return
+	}
+
+	respondReport(c, format, "revenue", report.Period, report, func() reportTable {
+		t := reportTable{header: []string{"date", "paid_orders", "amount"}}
+		for _, d := range report.Days {
+			t.rows = append(t.rows, []string{d.Date, itoa(d.PaidOrders), itoa(d.Amount)})
+		}
+		return t
+	})
+}
+
+// EmployeePerformance godoc
+// @Summary Orders completed per employee
+// @Tags reports
+// @Security AdminToken
+// @Produce json
+// @Produce text/csv
+// @Param from query string true "First day of the period (YYYY-MM-DD)"
+// @Param to query string true "Last day of the period, inclusive (YYYY-MM-DD)"
+// @Param format query string false "json (default) or csv"
+// @Success 200 {object} reports.EmployeePerformance
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /reports/employees [get]
+func (h *ReportHandler) EmployeePerformance(c *gin.Context) {
+	format, ok := reportFormat(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.reportService.EmployeePerformance(c, reportRange(c))
+	if err != nil {
+		h.respondError(c, "build employee performance report", err)
+		return
+	}
+
+	respondReport(c, format, "employees", report.Period, report, func() reportTable {
+		t := reportTable{header: []string{"employee_id", "name", "completed"}}
+		for _, e := range report.Employees {
+			t.rows = append(t.rows, []string{strconv.FormatUint(uint64(e.EmployeeID), 10), e.Name, itoa(e.Completed)})
+		}
+		return t
+	})
+}
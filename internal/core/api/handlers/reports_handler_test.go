@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/reports"
+	"github.com/gin-gonic/gin"
+)
+
+// Отчёты строятся настоящей бизнес-логикой по данным из хранилища-заглушки
+type stubReportRepository struct {
+	err error
+}
+
+func (r *stubReportRepository) Throughput(ctx context.Context, p reports.Period) ([]reports.DayThroughput, error) {
+	return []reports.DayThroughput{{Date: p.From, Created: 2, Completed: 1}}, r.err
+}
+
+func (r *stubReportRepository) Transitions(ctx context.Context, p reports.Period) ([]reports.Transition, error) {
+	return []reports.Transition{{FromStatus: "New", ToStatus: "Assigned", Count: 3, MedianSeconds: 5400.4}}, r.err
+}
+
+func (r *stubReportRepository) CancelReasons(ctx context.Context, p reports.Period) ([]reports.ReasonCount, error) {
+	return []reports.ReasonCount{{Reason: "дорого, долго", Count: 2}}, r.err
+}
+
+func (r *stubReportRepository) Revenue(ctx context.Context, p reports.Period) ([]reports.DayRevenue, error) {
+	return nil, r.err
+}
+
+func (r *stubReportRepository) EmployeeCompletions(ctx context.Context, p reports.Period) ([]reports.EmployeeStats, error) {
+	return []reports.EmployeeStats{{EmployeeID: 7, Name: "Пётр", Completed: 5}}, r.err
+}
+
+func TestReports_Handler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		name       string
+		path       string
+		repoErr    error
+		wantStatus int
+		wantType   string
+		wantBody   string
+	}{
+		{
+			name:       "JSON по умолчанию",
+			path:       "/reports/throughput?from=2026-03-02&to=2026-03-03",
+			wantStatus: http.StatusOK,
+			wantType:   "application/json",
+			wantBody:   `"from":"2026-03-02","to":"2026-03-03","created":2,"completed":1,"canceled":0,"days":[{"date":"2026-03-02","created":2,"completed":1,"canceled":0},{"date":"2026-03-03"`,
+		},
+		{
+			name:       "CSV по дням",
+			path:       "/reports/throughput?from=2026-03-02&to=2026-03-03&format=csv",
+			wantStatus: http.StatusOK,
+			wantType:   "text/csv",
+			wantBody:   "\ufeffdate,created,completed,canceled\n2026-03-02,2,1,0\n2026-03-03,0,0,0\n",
+		},
+		{
+			name:       "CSV с запятой в значении",
+			path:       "/reports/cancellations?from=2026-03-02&to=2026-03-02&format=csv",
+			wantStatus: http.StatusOK,
+			wantType:   "text/csv",
+			wantBody:   "\ufeffreason,count\n\"дорого, долго\",2\n",
+		},
+		{
+			name:       "Медиана в CSV в целых секундах",
+			path:       "/reports/transitions?from=2026-03-02&to=2026-03-02&format=csv",
+			wantStatus: http.StatusOK,
+			wantBody:   "New,Assigned,3,5400\n",
+		},
+		{
+			name:       "Сотрудники",
+			path:       "/reports/employees?from=2026-03-02&to=2026-03-02",
+			wantStatus: http.StatusOK,
+			wantBody:   `"employees":[{"employee_id":7,"name":"Пётр","completed":5}]`,
+		},
+		{
+			name:       "Неизвестный формат -> 400",
+			path:       "/reports/revenue?from=2026-03-02&to=2026-03-02&format=xml",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Без периода -> 400",
+			path:       "/reports/revenue",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Ошибка хранилища -> 500",
+			path:       "/reports/revenue?from=2026-03-02&to=2026-03-02",
+			repoErr:    errors.New("db down"),
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := reports.NewReportService(&stubReportRepository{err: tc.repoErr}, reports.WithLocation(time.UTC))
+			h := NewReportHandler(svc)
+			r := gin.New()
+			r.GET("/reports/throughput", h.Throughput)
+			r.GET("/reports/transitions", h.Transitions)
+			r.GET("/reports/cancellations", h.Cancellations)
+			r.GET("/reports/revenue", h.Revenue)
+			r.GET("/reports/employees", h.EmployeePerformance)
+
+			w := performRequest(r, "GET", tc.path, nil, "")
+			if w.Code != tc.wantStatus {
+				t.Fatalf("want %d, got %d, body: %s", tc.wantStatus, w.Code, w.Body.String())
+			}
+			if tc.wantType != "" && !strings.HasPrefix(w.Header().Get("Content-Type"), tc.wantType) {
+				t.Errorf("want content type %s, got %s", tc.wantType, w.Header().Get("Content-Type"))
+			}
+			if !strings.Contains(w.Body.String(), tc.wantBody) {
+				t.Errorf("want body containing %q, got %q", tc.wantBody, w.Body.String())
+			}
+		})
+	}
+}
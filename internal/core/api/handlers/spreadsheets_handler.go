@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/spreadsheets"
+	deterrs "github.com/Owouwun/spkuznetsov/internal/errors"
+	"github.com/Owouwun/spkuznetsov/pkg/tabular"
+	"github.com/gin-gonic/gin"
+)
+
+// Задаёт методы бизнес-логики
+type SpreadsheetService interface {
+	Export(ctx context.Context, filter orders.ListFilter, w tabular.Writer) error
+	Import(ctx context.Context, rows [][]string, dryRun bool) (*spreadsheets.ImportReport, error)
+}
+
+// SpreadsheetHandler выгружает заявки в CSV/XLSX и загружает их из таблицы
+type SpreadsheetHandler struct {
+	spreadsheetService SpreadsheetService
+	maxSize            int64
+}
+
+func NewSpreadsheetHandler(ss SpreadsheetService, maxSize int64) *SpreadsheetHandler {
+	return &SpreadsheetHandler{
+		spreadsheetService: ss,
+		maxSize:            maxSize,
+	}
+}
+
+// Тело ответа-файла: заголовки и статус 200 отправляются с первыми байтами файла,
+// поэтому до них ещё можно ответить ошибкой
+type attachmentWriter struct {
+	c           *gin.Context
+	contentType string
+	fileName    string
+	started     bool
+}
+
+func (w *attachmentWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.c.Header("Content-Type", w.contentType)
+		w.c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, w.fileName))
+		w.c.Status(http.StatusOK)
+	}
+	return w.c.Writer.Write(p)
+}
+
+// Export godoc
+// @Summary Export orders to CSV or XLSX
+// @Description Streams the order list with the same filters as GET /orders. CSV is UTF-8 with BOM; times are in the order time zone
+// @Tags orders
+// @Security AdminToken
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param format query string false "csv (default) or xlsx"
+// @Param include_deleted query bool false "Include soft-deleted orders"
+// @Param overdue query bool false "Only orders that breached their SLA in the current status"
+// @Param template_id query string false "Only orders generated from this recurring order template" Format(uuid)
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /orders/export [get]
+func (h *SpreadsheetHandler) Export(c *gin.Context) {
+	format, err := tabular.ParseFormat(c.DefaultQuery("format", string(tabular.CSV)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter, ok := listFilter(c)
+	if !ok {
+		return
+	}
+
+	out := &attachmentWriter{
+		c:           c,
+		contentType: format.ContentType(),
+		fileName:    fmt.Sprintf("orders_%s.%s", time.Now().Format("2006-01-02"), format),
+	}
+	w, err := tabular.NewWriter(out, format)
+	if err == nil {
+		if err = h.spreadsheetService.Export(c, filter, w); err == nil {
+			err = w.Close()
+		} else {
+			w.Abort()
+		}
+	}
+	if err == nil {
+		return
+	}
+
+	if !out.started {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export orders", "details": err.Error()})
+		return
+	}
+	// Часть файла уже отправлена: клиент получит его обрезанным
+	slog.ErrorContext(c, "order export interrupted", slog.Any("error", err))
+}
+
+// Import godoc
+// @Summary Import orders from CSV or XLSX
+// @Description Each row of the first sheet is validated as a new order and created unless dry_run is set. The first row is the header; columns are named as in the export: client_name, client_phone, address (one line) or city/street/house/apartment/entrance/floor/intercom, client_description, category, timezone, date (preliminary work date, YYYY-MM-DD or DD.MM.YYYY). Rows with the same phone, address and date as an existing order or a row above are reported as duplicates. CSV may use comma or semicolon
+// @Tags orders
+// @Security AdminToken
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV or XLSX file"
+// @Param dry_run query bool false "Only validate rows, do not create orders"
+// @Success 200 {object} spreadsheets.ImportReport
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 413 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /orders/import [post]
+func (h *SpreadsheetHandler) Import(c *gin.Context) {
+	var dryRun bool
+	if raw := c.Query("dry_run"); raw != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dry_run", "details": err.Error()})
+			return
+		}
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxSize+multipartOverhead)
+	fh, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file is too large", "max_size": h.maxSize})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid file", "details": err.Error()})
+		return
+	}
+	if fh.Size > h.maxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file is too large", "max_size": h.maxSize})
+		return
+	}
+
+	file, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid file", "details": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid file", "details": err.Error()})
+		return
+	}
+	rows, err := tabular.ReadAll(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid file", "details": err.Error()})
+		return
+	}
+
+	report, err := h.spreadsheetService.Import(c, rows, dryRun)
+	if err != nil {
+		var detErr *deterrs.DetErr
+		if errors.As(err, &detErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to import orders", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/spreadsheets"
+	deterrs "github.com/Owouwun/spkuznetsov/internal/errors"
+	"github.com/Owouwun/spkuznetsov/pkg/tabular"
+	"github.com/gin-gonic/gin"
+)
+
+type MockSpreadsheetService struct {
+	ExportFn func(ctx context.Context, filter orders.ListFilter, w tabular.Writer) error
+	ImportFn func(ctx context.Context, rows [][]string, dryRun bool) (*spreadsheets.ImportReport, error)
+}
+
+func (m *MockSpreadsheetService) Export(ctx context.Context, filter orders.ListFilter, w tabular.Writer) error {
+	if m.ExportFn == nil {
+		return w.Write([]string{"id", "status"})
+	}
+	return m.ExportFn(ctx, filter, w)
+}
+
+func (m *MockSpreadsheetService) Import(ctx context.Context, rows [][]string, dryRun bool) (*spreadsheets.ImportReport, error) {
+	if m.ImportFn == nil {
+		return &spreadsheets.ImportReport{DryRun: dryRun, Total: len(rows) - 1, Rows: []spreadsheets.RowResult{}}, nil
+	}
+	return m.ImportFn(ctx, rows, dryRun)
+}
+
+func TestExportOrders_Handler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		name       string
+		path       string
+		mock       *MockSpreadsheetService
+		wantStatus int
+		wantType   string
+		wantBody   string
+	}{
+		{
+			name:       "CSV по умолчанию",
+			path:       "/orders/export?overdue=true",
+			mock:       &MockSpreadsheetService{},
+			wantStatus: http.StatusOK,
+			wantType:   "text/csv",
+			wantBody:   "\ufeffid,status\n",
+		},
+		{
+			name:       "XLSX",
+			path:       "/orders/export?format=xlsx",
+			mock:       &MockSpreadsheetService{},
+			wantStatus: http.StatusOK,
+			wantType:   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+			wantBody:   "PK",
+		},
+		{
+			name:       "Неизвестный формат -> 400",
+			path:       "/orders/export?format=xls",
+			mock:       &MockSpreadsheetService{},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Неверный фильтр -> 400",
+			path:       "/orders/export?overdue=maybe",
+			mock:       &MockSpreadsheetService{},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Ошибка до начала файла -> 500",
+			path: "/orders/export",
+			mock: &MockSpreadsheetService{ExportFn: func(ctx context.Context, filter orders.ListFilter, w tabular.Writer) error {
+				_ = w.Write([]string{"id"})
+				return errors.New("db down")
+			}},
+			wantStatus: http.StatusInternalServerError,
+			wantType:   "application/json",
+			wantBody:   "failed to export orders",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewSpreadsheetHandler(tc.mock, 1<<20)
+			r := gin.New()
+			r.GET("/orders/export", h.Export)
+
+			w := performRequest(r, "GET", tc.path, nil, "")
+			if w.Code != tc.wantStatus {
+				t.Fatalf("want %d, got %d, body: %s", tc.wantStatus, w.Code, w.Body.String())
+			}
+			if tc.wantType != "" && !strings.HasPrefix(w.Header().Get("Content-Type"), tc.wantType) {
+				t.Errorf("want content type %s, got %s", tc.wantType, w.Header().Get("Content-Type"))
+			}
+			if !strings.Contains(w.Body.String(), tc.wantBody) {
+				t.Errorf("want body with %q, got %q", tc.wantBody, w.Body.String())
+			}
+			if tc.wantStatus == http.StatusOK && !strings.Contains(w.Header().Get("Content-Disposition"), "attachment") {
+				t.Errorf("want attachment, got %q", w.Header().Get("Content-Disposition"))
+			}
+		})
+	}
+}
+
+func TestImportOrders_Handler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	csvFile, csvType := multipartBody(t, "file", "orders.csv", []byte("client_name;client_phone;address\nИван;89161234567;Москва, Тверская, 1\n"))
+	brokenFile, brokenType := multipartBody(t, "file", "orders.csv", []byte("a,b\n\"1,2\n"))
+	wrongField, wrongFieldType := multipartBody(t, "document", "orders.csv", []byte("a\n"))
+	tooLarge, tooLargeType := multipartBody(t, "file", "orders.csv", []byte(strings.Repeat("x", 2<<20)))
+
+	cases := []struct {
+		name        string
+		path        string
+		body        []byte
+		contentType string
+		mock        *MockSpreadsheetService
+		wantStatus  int
+		wantBody    string
+	}{
+		{
+			name:        "Пробный импорт",
+			path:        "/orders/import?dry_run=true",
+			body:        csvFile,
+			contentType: csvType,
+			mock: &MockSpreadsheetService{ImportFn: func(ctx context.Context, rows [][]string, dryRun bool) (*spreadsheets.ImportReport, error) {
+				if !dryRun || len(rows) != 2 || rows[1][2] != "Москва, Тверская, 1" {
+					t.Errorf("unexpected rows %q, dry run %v", rows, dryRun)
+				}
+				return &spreadsheets.ImportReport{DryRun: true, Total: 1, Valid: 1, Rows: []spreadsheets.RowResult{{Row: 2, Status: spreadsheets.RowValid}}}, nil
+			}},
+			wantStatus: http.StatusOK,
+			wantBody:   `"rows":[{"row":2,"status":"valid"}]`,
+		},
+		{
+			name:        "Неверный dry_run -> 400",
+			path:        "/orders/import?dry_run=maybe",
+			body:        csvFile,
+			contentType: csvType,
+			mock:        &MockSpreadsheetService{},
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "Без файла -> 400",
+			path:        "/orders/import",
+			body:        wrongField,
+			contentType: wrongFieldType,
+			mock:        &MockSpreadsheetService{},
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "Нечитаемый CSV -> 400",
+			path:        "/orders/import",
+			body:        brokenFile,
+			contentType: brokenType,
+			mock:        &MockSpreadsheetService{},
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    "invalid csv",
+		},
+		{
+			name:        "Слишком большой файл -> 413",
+			path:        "/orders/import",
+			body:        tooLarge,
+			contentType: tooLargeType,
+			mock:        &MockSpreadsheetService{},
+			wantStatus:  http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:        "Неверный заголовок -> 400",
+			path:        "/orders/import",
+			body:        csvFile,
+			contentType: csvType,
+			mock: &MockSpreadsheetService{ImportFn: func(ctx context.Context, rows [][]string, dryRun bool) (*spreadsheets.ImportReport, error) {
+				return nil, deterrs.NewDetErr(deterrs.InvalidValue, deterrs.WithField("header"))
+			}},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "Ошибка сервиса -> 500",
+			path:        "/orders/import",
+			body:        csvFile,
+			contentType: csvType,
+			mock: &MockSpreadsheetService{ImportFn: func(ctx context.Context, rows [][]string, dryRun bool) (*spreadsheets.ImportReport, error) {
+				return nil, errors.New("db down")
+			}},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewSpreadsheetHandler(tc.mock, 1<<20)
+			r := gin.New()
+			r.POST("/orders/import", h.Import)
+
+			w := performRequest(r, "POST", tc.path, tc.body, tc.contentType)
+			if w.Code != tc.wantStatus {
+				t.Fatalf("want %d, got %d, body: %s", tc.wantStatus, w.Code, w.Body.String())
+			}
+			requireJSONObj(t, w.Body.Bytes())
+			if !strings.Contains(w.Body.String(), tc.wantBody) {
+				t.Errorf("want body containing %q, got %q", tc.wantBody, w.Body.String())
+			}
+		})
+	}
+}
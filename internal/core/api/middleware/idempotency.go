@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/idempotency"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// Ключ контекста запроса с телефоном клиента, данные которого отданы в ответе
+const idempotencyClientKey = "idempotency_client_phone"
+
+// IdempotencyClient отмечает, что в ответе есть данные клиента с телефоном phone:
+// сохранённый ответ удалится при его обезличивании
+func IdempotencyClient(c *gin.Context, phone string) {
+	c.Set(idempotencyClientKey, phone)
+}
+
+// Задаёт хранилище ответов на запросы с ключом идемпотентности
+type IdempotencyService interface {
+	Begin(ctx context.Context, key, fingerprint string) (*idempotency.Record, error)
+	Complete(ctx context.Context, key, fingerprint string, resp *idempotency.Response) error
+	Release(ctx context.Context, key, fingerprint string) error
+}
+
+// Копирует тело ответа, чтобы сохранить его после обработки запроса
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency выполняет изменяющий запрос с заголовком Idempotency-Key один раз: ответ сохраняется,
+// и повтор с тем же ключом получает его с заголовком Idempotent-Replayed: true. Тот же ключ
+// с другим запросом отклоняется с 422, повтор до ответа на первый запрос — с 409.
+// Запросы без заголовка и читающие запросы проходят как есть. Тело запроса с ключом
+// читается целиком до обработчика, поэтому его размер ограничен maxBodySize
+func Idempotency(service IdempotencyService, maxBodySize int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || !mutating(c.Request.Method) {
+			c.Next()
+			return
+		}
+		if len(key) > idempotency.MaxKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "idempotency key is too long", "max_length": idempotency.MaxKeyLength})
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBodySize))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "request body is too large", "max_size": maxBodySize})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		fingerprint := idempotency.Fingerprint(c.Request.Method, c.Request.URL.RequestURI(), c.GetHeader("Authorization"), body)
+
+		stored, err := service.Begin(c, key, fingerprint)
+		switch {
+		case errors.Is(err, idempotency.ErrKeyMismatch):
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, idempotency.ErrInProgress):
+			c.Header("Retry-After", "1")
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to check idempotency key", "details": err.Error()})
+			return
+		case stored != nil:
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(stored.StatusCode, stored.ContentType, stored.Body)
+			c.Abort()
+			return
+		}
+
+		// Ключ занят этим запросом: его нужно освободить или сохранить ответ, даже если
+		// клиент уже отключился или обработчик упал
+		ctx := context.WithoutCancel(c)
+		completed := false
+		defer func() {
+			if !completed {
+				_ = service.Release(ctx, key, fingerprint)
+			}
+		}()
+
+		w := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+
+		status := c.Writer.Status()
+		if !idempotency.Storable(status) {
+			return
+		}
+		err = service.Complete(ctx, key, fingerprint, &idempotency.Response{
+			StatusCode:  status,
+			ContentType: c.Writer.Header().Get("Content-Type"),
+			Body:        w.body.Bytes(),
+			ClientPhone: c.GetString(idempotencyClientKey),
+		})
+		if err != nil {
+			slog.WarnContext(ctx, "idempotent response not saved, key released", slog.Any("error", err))
+			return
+		}
+		completed = true
+	}
+}
+
+func mutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
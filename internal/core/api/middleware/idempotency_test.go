@@ -0,0 +1,214 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/idempotency"
+	"github.com/gin-gonic/gin"
+)
+
+type memoryIdempotency struct {
+	records  map[string]*idempotency.Record
+	beginErr error
+}
+
+func (m *memoryIdempotency) Begin(ctx context.Context, key, fingerprint string) (*idempotency.Record, error) {
+	if m.beginErr != nil {
+		return nil, m.beginErr
+	}
+	rec, ok := m.records[key]
+	switch {
+	case !ok:
+		m.records[key] = &idempotency.Record{Key: key, Fingerprint: fingerprint}
+		return nil, nil
+	case rec.Fingerprint != fingerprint:
+		return nil, idempotency.ErrKeyMismatch
+	case rec.Pending():
+		return nil, idempotency.ErrInProgress
+	}
+	return rec, nil
+}
+
+func (m *memoryIdempotency) Complete(ctx context.Context, key, fingerprint string, resp *idempotency.Response) error {
+	m.records[key].Response = *resp
+	return nil
+}
+
+func (m *memoryIdempotency) Release(ctx context.Context, key, fingerprint string) error {
+	delete(m.records, key)
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	type request struct {
+		method, key, body string
+		wantStatus        int
+		wantBody          string
+		wantReplayed      bool
+	}
+	cases := []struct {
+		name      string
+		service   *memoryIdempotency
+		status    int
+		requests  []request
+		wantCalls int
+	}{
+		{
+			name:   "Повтор получает сохранённый ответ",
+			status: http.StatusCreated,
+			requests: []request{
+				{method: "POST", key: "k1", body: `{"n":1}`, wantStatus: http.StatusCreated, wantBody: `{"call":1}`},
+				{method: "POST", key: "k1", body: `{"n":1}`, wantStatus: http.StatusCreated, wantBody: `{"call":1}`, wantReplayed: true},
+			},
+			wantCalls: 1,
+		},
+		{
+			name:   "Тот же ключ с другим телом -> 422",
+			status: http.StatusOK,
+			requests: []request{
+				{method: "PATCH", key: "k1", body: `{"n":1}`, wantStatus: http.StatusOK},
+				{method: "PATCH", key: "k1", body: `{"n":2}`, wantStatus: http.StatusUnprocessableEntity},
+			},
+			wantCalls: 1,
+		},
+		{
+			name:   "Ошибка клиента тоже сохраняется",
+			status: http.StatusBadRequest,
+			requests: []request{
+				{method: "PATCH", key: "k1", wantStatus: http.StatusBadRequest},
+				{method: "PATCH", key: "k1", wantStatus: http.StatusBadRequest, wantReplayed: true},
+			},
+			wantCalls: 1,
+		},
+		{
+			name:   "Сбой сервера не сохраняется: повтор выполняется заново",
+			status: http.StatusInternalServerError,
+			requests: []request{
+				{method: "POST", key: "k1", wantStatus: http.StatusInternalServerError},
+				{method: "POST", key: "k1", wantStatus: http.StatusInternalServerError, wantBody: `{"call":2}`},
+			},
+			wantCalls: 2,
+		},
+		{
+			name:   "Без ключа и для чтения ответы не сохраняются",
+			status: http.StatusOK,
+			requests: []request{
+				{method: "POST", wantStatus: http.StatusOK},
+				{method: "POST", wantStatus: http.StatusOK},
+				{method: "GET", key: "k1", wantStatus: http.StatusOK},
+				{method: "GET", key: "k1", wantStatus: http.StatusOK, wantBody: `{"call":4}`},
+			},
+			wantCalls: 4,
+		},
+		{
+			name:   "Первый запрос ещё выполняется -> 409",
+			status: http.StatusOK,
+			service: &memoryIdempotency{records: map[string]*idempotency.Record{
+				"k1": {Key: "k1", Fingerprint: idempotency.Fingerprint("POST", "/orders", "", nil)},
+			}},
+			requests: []request{
+				{method: "POST", key: "k1", wantStatus: http.StatusConflict},
+			},
+		},
+		{
+			name:   "Слишком длинный ключ -> 400",
+			status: http.StatusOK,
+			requests: []request{
+				{method: "POST", key: strings.Repeat("k", idempotency.MaxKeyLength+1), wantStatus: http.StatusBadRequest},
+			},
+		},
+		{
+			name:   "Слишком большое тело -> 413",
+			status: http.StatusOK,
+			requests: []request{
+				{method: "POST", key: "k1", body: strings.Repeat("x", 1025), wantStatus: http.StatusRequestEntityTooLarge},
+			},
+		},
+		{
+			name:    "Хранилище недоступно -> 500",
+			status:  http.StatusOK,
+			service: &memoryIdempotency{beginErr: errors.New("db down")},
+			requests: []request{
+				{method: "DELETE", key: "k1", wantStatus: http.StatusInternalServerError},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := tc.service
+			if service == nil {
+				service = &memoryIdempotency{records: map[string]*idempotency.Record{}}
+			}
+			calls := 0
+			handler := func(c *gin.Context) {
+				calls++
+				c.JSON(tc.status, gin.H{"call": calls})
+			}
+			r := gin.New()
+			r.Use(Idempotency(service, 1024))
+			r.Handle(http.MethodPost, "/orders", handler)
+			r.Handle(http.MethodPatch, "/orders", handler)
+			r.Handle(http.MethodDelete, "/orders", handler)
+			r.Handle(http.MethodGet, "/orders", handler)
+
+			for i, step := range tc.requests {
+				req := httptest.NewRequest(step.method, "/orders", strings.NewReader(step.body))
+				if step.key != "" {
+					req.Header.Set(IdempotencyKeyHeader, step.key)
+				}
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+
+				if w.Code != step.wantStatus {
+					t.Fatalf("request %d: expected status %d, got %d, body: %s", i+1, step.wantStatus, w.Code, w.Body.String())
+				}
+				if step.wantBody != "" && w.Body.String() != step.wantBody {
+					t.Errorf("request %d: expected body %s, got %s", i+1, step.wantBody, w.Body.String())
+				}
+				if replayed := w.Header().Get(IdempotentReplayedHeader) == "true"; replayed != step.wantReplayed {
+					t.Errorf("request %d: expected replayed %v, got %v", i+1, step.wantReplayed, replayed)
+				}
+				if step.wantReplayed && !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+					t.Errorf("request %d: expected stored content type, got %q", i+1, w.Header().Get("Content-Type"))
+				}
+			}
+			if calls != tc.wantCalls {
+				t.Errorf("expected handler to run %d times, got %d", tc.wantCalls, calls)
+			}
+		})
+	}
+}
+
+func TestIdempotency_ClientData(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	service := &memoryIdempotency{records: map[string]*idempotency.Record{}}
+	r := gin.New()
+	r.Use(Idempotency(service, 1024))
+	r.POST("/recurring-orders", func(c *gin.Context) {
+		IdempotencyClient(c, "+79161234567")
+		c.JSON(http.StatusCreated, gin.H{"client_phone": "+79161234567"})
+	})
+	r.POST("/parts", func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"sku": "VALVE-1"})
+	})
+
+	for path, expPhone := range map[string]string{"/recurring-orders": "+79161234567", "/parts": ""} {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set(IdempotencyKeyHeader, path)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+
+		rec := service.records[path]
+		if rec == nil || rec.Pending() || rec.ClientPhone != expPhone {
+			t.Errorf("%s: expected stored response with client phone %q, got %+v", path, expPhone, rec)
+		}
+	}
+}
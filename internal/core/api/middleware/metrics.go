@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Задаёт получателя метрик HTTP-запросов
+type HTTPObserver interface {
+	ObserveHTTPRequest(method, route string, status int, duration time.Duration)
+}
+
+// Metrics передаёт в observer длительность и статус каждого запроса.
+// Запросы группируются по шаблону маршрута, чтобы не плодить метки на каждый ID
+func Metrics(observer HTTPObserver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		observer.ObserveHTTPRequest(c.Request.Method, c.FullPath(), c.Writer.Status(), time.Since(start))
+	}
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Owouwun/spkuznetsov/internal/logging"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestRequestID_Middleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		name      string
+		requestID string
+		wantSame  bool
+	}{
+		{
+			name:      "Идентификатор клиента сохраняется",
+			requestID: "client-request-1",
+			wantSame:  true,
+		},
+		{
+			name:      "Без заголовка генерируется UUID",
+			requestID: "",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			logger := logging.New(&logs, "info")
+
+			r := gin.New()
+			r.ContextWithFallback = true
+			r.Use(RequestID())
+			r.GET("/ping", func(c *gin.Context) {
+				logger.InfoContext(c, "handled")
+				c.String(http.StatusOK, logging.RequestID(c))
+			})
+
+			req := httptest.NewRequest("GET", "/ping", nil)
+			if tc.requestID != "" {
+				req.Header.Set(RequestIDHeader, tc.requestID)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			got := w.Header().Get(RequestIDHeader)
+			if tc.wantSame && got != tc.requestID {
+				t.Fatalf("want request id %q, got %q", tc.requestID, got)
+			}
+			if !tc.wantSame {
+				if _, err := uuid.Parse(got); err != nil {
+					t.Fatalf("want generated UUID, got %q", got)
+				}
+			}
+			if w.Body.String() != got {
+				t.Fatalf("request id in context %q differs from header %q", w.Body.String(), got)
+			}
+
+			var record map[string]any
+			if err := json.Unmarshal(logs.Bytes(), &record); err != nil {
+				t.Fatalf("expected JSON log record, got %q: %v", logs.String(), err)
+			}
+			if record["request_id"] != got {
+				t.Fatalf("want request_id %q in log, got %v", got, record["request_id"])
+			}
+			if record["level"] != slog.LevelInfo.String() {
+				t.Fatalf("want level INFO, got %v", record["level"])
+			}
+		})
+	}
+}
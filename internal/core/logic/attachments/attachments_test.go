@@ -0,0 +1,333 @@
+package attachments_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/attachments"
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	deterrs "github.com/Owouwun/spkuznetsov/internal/errors"
+	"github.com/Owouwun/spkuznetsov/pkg/clock"
+	"github.com/google/uuid"
+)
+
+var (
+	policy = attachments.Policy{
+		MaxSize:       1 << 20,
+		AllowedTypes:  []string{"image/jpeg", "image/png", "application/pdf"},
+		ThumbnailSize: 64,
+	}
+	pdf = []byte("%PDF-1.4\n% акт выполненных работ")
+)
+
+func pngImage(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 200, 100))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestNewAttachment(t *testing.T) {
+	img := pngImage(t)
+
+	cases := []struct {
+		name     string
+		fileName string
+		content  []byte
+		size     int64
+		expKind  attachments.Kind
+		expType  string
+		expName  string
+		expErr   error
+	}{
+		{
+			name: "Фото", fileName: "before.png", content: img, size: int64(len(img)),
+			expKind: attachments.KindPhoto, expType: "image/png", expName: "before.png",
+		},
+		{
+			name: "Документ", fileName: "act.pdf", content: pdf, size: int64(len(pdf)),
+			expKind: attachments.KindDocument, expType: "application/pdf", expName: "act.pdf",
+		},
+		{
+			name: "Тип определяется по содержимому, а не по расширению", fileName: "act.png", content: pdf, size: int64(len(pdf)),
+			expKind: attachments.KindDocument, expType: "application/pdf", expName: "act.png",
+		},
+		{
+			name: "Путь в имени файла отбрасывается", fileName: `..\..\etc/"act".pdf`, content: pdf, size: int64(len(pdf)),
+			expKind: attachments.KindDocument, expType: "application/pdf", expName: "act.pdf",
+		},
+		{
+			name: "Пустое имя файла заменяется", fileName: "", content: pdf, size: int64(len(pdf)),
+			expKind: attachments.KindDocument, expType: "application/pdf", expName: "attachment",
+		},
+		{
+			name: "Попытка загрузить пустой файл", fileName: "empty.pdf", content: nil, size: 0,
+			expErr: deterrs.NewDetErr(deterrs.EmptyField),
+		},
+		{
+			name: "Попытка загрузить слишком большой файл", fileName: "big.pdf", content: pdf, size: policy.MaxSize + 1,
+			expErr: deterrs.NewDetErr(deterrs.InvalidValue),
+		},
+		{
+			name: "Попытка загрузить файл недопустимого типа", fileName: "script.pdf", content: []byte("<html><script></script>"), size: 23,
+			expErr: deterrs.NewDetErr(deterrs.InvalidValue),
+		},
+	}
+
+	orderID := uuid.New()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			att, err := policy.NewAttachment(orderID, tc.fileName, tc.size, tc.content)
+			if !errors.Is(err, tc.expErr) {
+				t.Fatalf("expected error %v, got %v", tc.expErr, err)
+			}
+			if tc.expErr != nil {
+				return
+			}
+
+			if att.Kind != tc.expKind || att.ContentType != tc.expType || att.FileName != tc.expName {
+				t.Errorf("expected %s %s %q, got %s %s %q", tc.expKind, tc.expType, tc.expName, att.Kind, att.ContentType, att.FileName)
+			}
+			if att.OrderID != orderID || att.ID == uuid.Nil {
+				t.Errorf("attachment is not bound to the order: %+v", att)
+			}
+		})
+	}
+}
+
+func TestCanAttach(t *testing.T) {
+	cases := []struct {
+		name   string
+		status orders.Status
+		expErr error
+	}{
+		{name: "Назначены работы", status: orders.StatusScheduled},
+		{name: "Работы частично проведены", status: orders.StatusInProgress},
+		{name: "Выполнена", status: orders.StatusDone},
+		{name: "Попытка добавить вложение к новой заявке", status: orders.StatusNew, expErr: deterrs.NewDetErr(deterrs.OrderActionNotPermittedByStatus)},
+		{name: "Попытка добавить вложение к оплаченной заявке", status: orders.StatusPaid, expErr: deterrs.NewDetErr(deterrs.OrderActionNotPermittedByStatus)},
+		{name: "Попытка добавить вложение к отменённой заявке", status: orders.StatusCanceled, expErr: deterrs.NewDetErr(deterrs.OrderActionNotPermittedByStatus)},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := attachments.CanAttach(tc.status); !errors.Is(err, tc.expErr) {
+				t.Errorf("expected error %v, got %v", tc.expErr, err)
+			}
+		})
+	}
+}
+
+// Хранилища в памяти для проверки сервиса
+
+type memRepo struct {
+	statuses    map[uuid.UUID]orders.Status
+	attachments map[uuid.UUID]*attachments.Attachment
+}
+
+func (r *memRepo) Create(_ context.Context, att *attachments.Attachment) error {
+	r.attachments[att.ID] = att
+	return nil
+}
+
+func (r *memRepo) ListByOrder(_ context.Context, orderID uuid.UUID) ([]*attachments.Attachment, error) {
+	var res []*attachments.Attachment
+	for _, att := range r.attachments {
+		if att.OrderID == orderID {
+			res = append(res, att)
+		}
+	}
+	return res, nil
+}
+
+func (r *memRepo) ListByOrders(ctx context.Context, orderIDs []uuid.UUID) ([]*attachments.Attachment, error) {
+	var res []*attachments.Attachment
+	for _, orderID := range orderIDs {
+		atts, _ := r.ListByOrder(ctx, orderID)
+		res = append(res, atts...)
+	}
+	return res, nil
+}
+
+func (r *memRepo) DeleteByOrders(_ context.Context, orderIDs []uuid.UUID) error {
+	for _, orderID := range orderIDs {
+		for id, att := range r.attachments {
+			if att.OrderID == orderID {
+				delete(r.attachments, id)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *memRepo) GetByID(_ context.Context, id uuid.UUID) (*attachments.Attachment, error) {
+	if att, ok := r.attachments[id]; ok {
+		return att, nil
+	}
+	return nil, attachments.ErrNotFound
+}
+
+func (r *memRepo) OrderStatus(_ context.Context, orderID uuid.UUID) (orders.Status, error) {
+	if status, ok := r.statuses[orderID]; ok {
+		return status, nil
+	}
+	return 0, attachments.ErrNotFound
+}
+
+type memBlobs map[string][]byte
+
+func (b memBlobs) Put(_ context.Context, key string, r io.Reader, _ int64, _ string) error {
+	data, err := io.ReadAll(r)
+	b[key] = data
+	return err
+}
+
+func (b memBlobs) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	data, ok := b[key]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (b memBlobs) Delete(_ context.Context, key string) error {
+	delete(b, key)
+	return nil
+}
+
+func TestAttachmentService(t *testing.T) {
+	ctx := context.Background()
+	img := pngImage(t)
+	inProgress, canceled, other := uuid.New(), uuid.New(), uuid.New()
+	repo := &memRepo{
+		statuses: map[uuid.UUID]orders.Status{
+			inProgress: orders.StatusInProgress,
+			canceled:   orders.StatusCanceled,
+			other:      orders.StatusDone,
+		},
+		attachments: map[uuid.UUID]*attachments.Attachment{},
+	}
+	blobs := memBlobs{}
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	svc := attachments.NewAttachmentService(repo, blobs, policy, attachments.WithClock(clock.NewManual(now)))
+
+	photo, err := svc.Upload(ctx, inProgress, "after.png", int64(len(img)), bytes.NewReader(img))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !photo.CreatedAt.Equal(now) {
+		t.Errorf("expected upload time from the service clock, got %v", photo.CreatedAt)
+	}
+	if !photo.HasThumbnail {
+		t.Errorf("expected a thumbnail for the photo")
+	}
+	act, err := svc.Upload(ctx, inProgress, "act.pdf", int64(len(pdf)), bytes.NewReader(pdf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if act.HasThumbnail {
+		t.Errorf("documents must not have thumbnails")
+	}
+
+	if _, err := svc.Upload(ctx, canceled, "act.pdf", int64(len(pdf)), bytes.NewReader(pdf)); !errors.Is(err, deterrs.NewDetErr(deterrs.OrderActionNotPermittedByStatus)) {
+		t.Errorf("expected status error for a canceled order, got %v", err)
+	}
+	if _, err := svc.Upload(ctx, uuid.New(), "act.pdf", int64(len(pdf)), bytes.NewReader(pdf)); !errors.Is(err, attachments.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing order, got %v", err)
+	}
+
+	list, err := svc.List(ctx, inProgress)
+	if err != nil || len(list) != 2 {
+		t.Errorf("expected 2 attachments, got %d (%v)", len(list), err)
+	}
+
+	cases := []struct {
+		name       string
+		orderID    uuid.UUID
+		id         uuid.UUID
+		thumbnail  bool
+		expContent string
+		expErr     error
+	}{
+		{name: "Скачивание документа", orderID: inProgress, id: act.ID, expContent: string(pdf)},
+		{name: "Скачивание оригинала фото", orderID: inProgress, id: photo.ID, expContent: string(img)},
+		{name: "Скачивание превью фото", orderID: inProgress, id: photo.ID, thumbnail: true, expContent: "\xff\xd8"},
+		{name: "Попытка скачать превью документа", orderID: inProgress, id: act.ID, thumbnail: true, expErr: attachments.ErrNotFound},
+		{name: "Попытка скачать вложение через чужую заявку", orderID: other, id: act.ID, expErr: attachments.ErrNotFound},
+		{name: "Попытка скачать несуществующее вложение", orderID: inProgress, id: uuid.New(), expErr: attachments.ErrNotFound},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, rc, err := svc.Open(ctx, tc.orderID, tc.id, tc.thumbnail)
+			if !errors.Is(err, tc.expErr) {
+				t.Fatalf("expected error %v, got %v", tc.expErr, err)
+			}
+			if tc.expErr != nil {
+				return
+			}
+			defer rc.Close()
+
+			got, err := io.ReadAll(rc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(string(got), tc.expContent) {
+				t.Errorf("unexpected content %q", got[:min(len(got), 16)])
+			}
+		})
+	}
+}
+
+func TestAttachmentService_EraseOrders(t *testing.T) {
+	ctx := context.Background()
+	img := pngImage(t)
+	erased, kept := uuid.New(), uuid.New()
+	repo := &memRepo{
+		statuses: map[uuid.UUID]orders.Status{
+			erased: orders.StatusDone,
+			kept:   orders.StatusDone,
+		},
+		attachments: map[uuid.UUID]*attachments.Attachment{},
+	}
+	blobs := memBlobs{}
+	svc := attachments.NewAttachmentService(repo, blobs, policy)
+
+	photo, err := svc.Upload(ctx, erased, "after.png", int64(len(img)), bytes.NewReader(img))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Upload(ctx, erased, "act.pdf", int64(len(pdf)), bytes.NewReader(pdf)); err != nil {
+		t.Fatal(err)
+	}
+	other, err := svc.Upload(ctx, kept, "act.pdf", int64(len(pdf)), bytes.NewReader(pdf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Файл мог быть удалён прошлой, прерванной попыткой
+	delete(blobs, photo.BlobKey())
+
+	if err := svc.EraseOrders(ctx, []uuid.UUID{erased}); err != nil {
+		t.Fatalf("failed to erase attachments: %v", err)
+	}
+
+	if list, _ := svc.List(ctx, erased); len(list) != 0 {
+		t.Errorf("expected no attachments of the erased order, got %d", len(list))
+	}
+	if _, ok := blobs[photo.ThumbnailKey()]; ok || len(blobs) != 1 {
+		t.Errorf("expected files and thumbnails of the erased order to be deleted, left %d files", len(blobs))
+	}
+	if _, ok := blobs[other.BlobKey()]; !ok {
+		t.Errorf("attachment of another order must stay")
+	}
+}
@@ -0,0 +1,118 @@
+package attachments
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	deterrs "github.com/Owouwun/spkuznetsov/internal/errors"
+	"github.com/google/uuid"
+)
+
+// Сколько первых байт файла нужно для определения его типа
+const sniffLen = 512
+
+const maxFileNameLen = 255
+
+// Вложения добавляются, пока идут работы: перед началом, по ходу и по завершении
+var attachableStatuses = []orders.Status{
+	orders.StatusScheduled,
+	orders.StatusInProgress,
+	orders.StatusDone,
+}
+
+// Проверить, можно ли добавить вложение к заявке в этом статусе
+func CanAttach(status orders.Status) error {
+	for _, s := range attachableStatuses {
+		if s == status {
+			return nil
+		}
+	}
+	return deterrs.NewDetErr(
+		deterrs.OrderActionNotPermittedByStatus,
+	)
+}
+
+// Оформить новое вложение. Тип определяется по содержимому (head — первые байты файла),
+// а не по имени файла или заголовкам клиента
+func (p *Policy) NewAttachment(orderID uuid.UUID, fileName string, size int64, head []byte) (*Attachment, error) {
+	if size <= 0 || len(head) == 0 {
+		return nil, deterrs.NewDetErr(
+			deterrs.EmptyField,
+			deterrs.WithField("file"),
+		)
+	}
+	if size > p.MaxSize {
+		return nil, deterrs.NewDetErr(
+			deterrs.InvalidValue,
+			deterrs.WithField("file size"),
+		)
+	}
+
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if !p.allows(contentType) {
+		return nil, deterrs.NewDetErr(
+			deterrs.InvalidValue,
+			deterrs.WithField("content type"),
+		)
+	}
+
+	kind := KindDocument
+	if strings.HasPrefix(contentType, "image/") {
+		kind = KindPhoto
+	}
+
+	return &Attachment{
+		ID:          uuid.New(),
+		OrderID:     orderID,
+		Kind:        kind,
+		FileName:    sanitizeFileName(fileName),
+		ContentType: contentType,
+		Size:        size,
+	}, nil
+}
+
+func (p *Policy) allows(contentType string) bool {
+	for _, t := range p.AllowedTypes {
+		if t == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// Оставить от присланного имени только базовое имя без управляющих символов:
+// оно попадает в Content-Disposition при скачивании
+func sanitizeFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+
+	for len(name) > maxFileNameLen {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	return name
+}
+
+// Ключ содержимого вложения в хранилище файлов
+func (a *Attachment) BlobKey() string {
+	return "orders/" + a.OrderID.String() + "/" + a.ID.String()
+}
+
+// Ключ превью вложения в хранилище файлов
+func (a *Attachment) ThumbnailKey() string {
+	return a.BlobKey() + ".thumb.jpg"
+}
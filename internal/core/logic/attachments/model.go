@@ -0,0 +1,38 @@
+package attachments
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Вид вложения: фото до/после работ или документ (например, подписанный акт)
+type Kind string
+
+const (
+	KindPhoto    Kind = "photo"
+	KindDocument Kind = "document"
+)
+
+type Attachment struct {
+	ID           uuid.UUID `json:"id"`
+	OrderID      uuid.UUID `json:"order_id"`
+	Kind         Kind      `json:"kind"`
+	FileName     string    `json:"file_name"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	HasThumbnail bool      `json:"has_thumbnail"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Ограничения на загружаемые файлы
+type Policy struct {
+	MaxSize      int64
+	AllowedTypes []string
+	// Размер большей стороны превью; 0 — превью не создаются
+	ThumbnailSize int
+}
+
+// Вложение или заявка не найдены (или заявка удалена)
+var ErrNotFound = errors.New("attachment not found")
@@ -0,0 +1,234 @@
+package attachments
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/Owouwun/spkuznetsov/internal/logging"
+	"github.com/Owouwun/spkuznetsov/internal/tracing"
+	"github.com/Owouwun/spkuznetsov/pkg/clock"
+	"github.com/Owouwun/spkuznetsov/pkg/utils"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type AttachmentRepository interface {
+	Create(ctx context.Context, att *Attachment) error
+	ListByOrder(ctx context.Context, orderID uuid.UUID) ([]*Attachment, error)
+	// ErrNotFound, если вложения нет
+	GetByID(ctx context.Context, id uuid.UUID) (*Attachment, error)
+	// Вложения нескольких заявок, в т.ч. удалённых и архивных
+	ListByOrders(ctx context.Context, orderIDs []uuid.UUID) ([]*Attachment, error)
+	DeleteByOrders(ctx context.Context, orderIDs []uuid.UUID) error
+	// Статус действующей или архивной заявки; ErrNotFound, если заявки нет или она удалена
+	OrderStatus(ctx context.Context, orderID uuid.UUID) (orders.Status, error)
+}
+
+// Хранилище содержимого файлов. Отсутствующий объект — ошибка fs.ErrNotExist
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+type AttachmentService struct {
+	repo   AttachmentRepository
+	blobs  BlobStore
+	policy Policy
+	clock  clock.Clock
+}
+
+type AttachmentServiceOption func(*AttachmentService)
+
+func WithClock(clk clock.Clock) AttachmentServiceOption {
+	return func(s *AttachmentService) {
+		s.clock = clk
+	}
+}
+
+func NewAttachmentService(repo AttachmentRepository, blobs BlobStore, policy Policy, opts ...AttachmentServiceOption) *AttachmentService {
+	s := &AttachmentService{
+		repo:   repo,
+		blobs:  blobs,
+		policy: policy,
+		clock:  clock.System{},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func startSpan(ctx context.Context, method string, orderID uuid.UUID) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "AttachmentService."+method,
+		trace.WithAttributes(attribute.String("order.id", orderID.String())))
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Загрузить файл размером size к заявке. Для изображений дополнительно сохраняется превью
+func (s *AttachmentService) Upload(ctx context.Context, orderID uuid.UUID, fileName string, size int64, r io.Reader) (_ *Attachment, err error) {
+	ctx, span := startSpan(ctx, "Upload", orderID)
+	defer func() { endSpan(span, err) }()
+	ctx = logging.WithAttrs(ctx, slog.String("order_id", orderID.String()))
+
+	status, err := s.repo.OrderStatus(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := CanAttach(status); err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReaderSize(r, sniffLen)
+	head, err := br.Peek(sniffLen)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	att, err := s.policy.NewAttachment(orderID, fileName, size, head)
+	if err != nil {
+		slog.WarnContext(ctx, "attachment rejected", slog.Any("error", err))
+		return nil, err
+	}
+	ctx = logging.WithAttrs(ctx, slog.String("attachment_id", att.ID.String()))
+
+	var content io.Reader = br
+	// Изображение нужно прочитать дважды: для сохранения и для превью. Размер уже ограничен политикой
+	var image []byte
+	if att.Kind == KindPhoto && s.policy.ThumbnailSize > 0 {
+		if image, err = io.ReadAll(io.LimitReader(br, size+1)); err != nil {
+			return nil, err
+		}
+		content = bytes.NewReader(image)
+	}
+
+	if err := s.blobs.Put(ctx, att.BlobKey(), content, size, att.ContentType); err != nil {
+		slog.ErrorContext(ctx, "failed to store attachment", slog.Any("error", err))
+		return nil, err
+	}
+	stored := []string{att.BlobKey()}
+
+	if image != nil {
+		att.HasThumbnail = s.storeThumbnail(ctx, att, image)
+		if att.HasThumbnail {
+			stored = append(stored, att.ThumbnailKey())
+		}
+	}
+
+	att.CreatedAt = s.clock.Now()
+	if err := s.repo.Create(ctx, att); err != nil {
+		slog.ErrorContext(ctx, "failed to save attachment", slog.Any("error", err))
+		for _, key := range stored {
+			if delErr := s.blobs.Delete(ctx, key); delErr != nil {
+				slog.WarnContext(ctx, "failed to remove orphan attachment file", slog.String("key", key), slog.Any("error", delErr))
+			}
+		}
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "attachment uploaded", slog.String("kind", string(att.Kind)), slog.Int64("size", att.Size))
+	return att, nil
+}
+
+// Превью необязательно: если изображение не удалось уменьшить, вложение сохраняется без него
+func (s *AttachmentService) storeThumbnail(ctx context.Context, att *Attachment, image []byte) bool {
+	thumb, err := utils.Thumbnail(bytes.NewReader(image), s.policy.ThumbnailSize)
+	if err == nil {
+		err = s.blobs.Put(ctx, att.ThumbnailKey(), bytes.NewReader(thumb), int64(len(thumb)), "image/jpeg")
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "failed to create thumbnail", slog.Any("error", err))
+		return false
+	}
+	return true
+}
+
+func (s *AttachmentService) List(ctx context.Context, orderID uuid.UUID) (_ []*Attachment, err error) {
+	ctx, span := startSpan(ctx, "List", orderID)
+	defer func() { endSpan(span, err) }()
+
+	if _, err := s.repo.OrderStatus(ctx, orderID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByOrder(ctx, orderID)
+}
+
+// Открыть содержимое вложения (или его превью). Вложение отдаётся только в составе своей заявки
+func (s *AttachmentService) Open(ctx context.Context, orderID, id uuid.UUID, thumbnail bool) (_ *Attachment, _ io.ReadCloser, err error) {
+	ctx, span := startSpan(ctx, "Open", orderID)
+	defer func() { endSpan(span, err) }()
+
+	if _, err := s.repo.OrderStatus(ctx, orderID); err != nil {
+		return nil, nil, err
+	}
+	att, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if att.OrderID != orderID || (thumbnail && !att.HasThumbnail) {
+		return nil, nil, ErrNotFound
+	}
+
+	key := att.BlobKey()
+	if thumbnail {
+		key = att.ThumbnailKey()
+	}
+	rc, err := s.blobs.Get(ctx, key)
+	if errors.Is(err, fs.ErrNotExist) {
+		slog.ErrorContext(ctx, "attachment file is missing", slog.String("key", key))
+		return nil, nil, fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return att, rc, nil
+}
+
+// Удалить вложения заявок вместе с файлами и превью (при обезличивании заявок).
+// Файлы удаляются раньше записей: после сбоя повторный вызов найдёт оставшиеся
+func (s *AttachmentService) EraseOrders(ctx context.Context, orderIDs []uuid.UUID) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "AttachmentService.EraseOrders",
+		trace.WithAttributes(attribute.Int("order.count", len(orderIDs))))
+	defer func() { endSpan(span, err) }()
+
+	atts, err := s.repo.ListByOrders(ctx, orderIDs)
+	if err != nil {
+		return err
+	}
+	for _, att := range atts {
+		keys := []string{att.BlobKey()}
+		if att.HasThumbnail {
+			keys = append(keys, att.ThumbnailKey())
+		}
+		for _, key := range keys {
+			if err := s.blobs.Delete(ctx, key); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				slog.ErrorContext(ctx, "failed to delete attachment file", slog.String("key", key), slog.Any("error", err))
+				return err
+			}
+		}
+	}
+	if err := s.repo.DeleteByOrders(ctx, orderIDs); err != nil {
+		return err
+	}
+
+	if len(atts) > 0 {
+		slog.InfoContext(ctx, "attachments erased", slog.Int("attachments", len(atts)), slog.Int("orders", len(orderIDs)))
+	}
+	return nil
+}
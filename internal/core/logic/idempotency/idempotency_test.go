@@ -0,0 +1,195 @@
+package idempotency_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/idempotency"
+	"github.com/Owouwun/spkuznetsov/pkg/clock"
+)
+
+type keyStore struct {
+	records map[string]*idempotency.Record
+}
+
+func (s *keyStore) Reserve(ctx context.Context, rec *idempotency.Record) (*idempotency.Record, error) {
+	if existing, ok := s.records[rec.Key]; ok && existing.ExpiresAt.After(rec.CreatedAt) {
+		return existing, nil
+	}
+	copied := *rec
+	s.records[rec.Key] = &copied
+	return nil, nil
+}
+
+func (s *keyStore) Complete(ctx context.Context, key, fingerprint string, resp *idempotency.Response, expiresAt time.Time) error {
+	if rec, ok := s.records[key]; ok && rec.Fingerprint == fingerprint {
+		rec.Response = *resp
+		rec.ExpiresAt = expiresAt
+	}
+	return nil
+}
+
+func (s *keyStore) Release(ctx context.Context, key, fingerprint string) error {
+	if rec, ok := s.records[key]; ok && rec.Fingerprint == fingerprint && rec.Pending() {
+		delete(s.records, key)
+	}
+	return nil
+}
+
+func (s *keyStore) DeleteExpired(ctx context.Context, now time.Time, limit int) (int, error) {
+	n := 0
+	for key, rec := range s.records {
+		if n < limit && !rec.ExpiresAt.After(now) {
+			delete(s.records, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func TestIdempotencyService(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewManual(time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC))
+	store := &keyStore{records: map[string]*idempotency.Record{}}
+	svc := idempotency.NewIdempotencyService(store, idempotency.WithClock(clk), idempotency.WithTTL(time.Hour))
+	created := &idempotency.Response{StatusCode: http.StatusCreated, ContentType: "application/json", Body: []byte(`"id"`)}
+
+	steps := []struct {
+		name        string
+		do          func() (*idempotency.Record, error)
+		expErr      error
+		expReplayed bool
+	}{
+		{
+			name: "Первый запрос выполняется",
+			do:   func() (*idempotency.Record, error) { return svc.Begin(ctx, "k1", "a") },
+		},
+		{
+			name:   "Повтор во время выполнения",
+			do:     func() (*idempotency.Record, error) { return svc.Begin(ctx, "k1", "a") },
+			expErr: idempotency.ErrInProgress,
+		},
+		{
+			name: "Ответ сохранён и повторяется",
+			do: func() (*idempotency.Record, error) {
+				if err := svc.Complete(ctx, "k1", "a", created); err != nil {
+					return nil, err
+				}
+				return svc.Begin(ctx, "k1", "a")
+			},
+			expReplayed: true,
+		},
+		{
+			name:   "Тот же ключ с другим запросом",
+			do:     func() (*idempotency.Record, error) { return svc.Begin(ctx, "k1", "b") },
+			expErr: idempotency.ErrKeyMismatch,
+		},
+		{
+			name: "После срока хранения ключ свободен",
+			do: func() (*idempotency.Record, error) {
+				clk.Advance(time.Hour)
+				return svc.Begin(ctx, "k1", "b")
+			},
+		},
+		{
+			name: "Освобождённый ключ можно занять снова",
+			do: func() (*idempotency.Record, error) {
+				if err := svc.Release(ctx, "k1", "b"); err != nil {
+					return nil, err
+				}
+				return svc.Begin(ctx, "k1", "c")
+			},
+		},
+		{
+			name: "Ключ, брошенный без ответа, освобождается через LockTimeout",
+			do: func() (*idempotency.Record, error) {
+				clk.Advance(idempotency.LockTimeout)
+				return svc.Begin(ctx, "k1", "c")
+			},
+		},
+	}
+
+	for _, step := range steps {
+		rec, err := step.do()
+		if !errors.Is(err, step.expErr) {
+			t.Fatalf("%s: expected error %v, got %v", step.name, step.expErr, err)
+		}
+		if replayed := rec != nil; replayed != step.expReplayed {
+			t.Fatalf("%s: expected replayed %v, got %+v", step.name, step.expReplayed, rec)
+		}
+		if rec != nil && (rec.StatusCode != created.StatusCode || string(rec.Body) != `"id"`) {
+			t.Errorf("%s: unexpected response %+v", step.name, rec.Response)
+		}
+	}
+}
+
+func TestIdempotencyService_Cleanup(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewManual(time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC))
+	store := &keyStore{records: map[string]*idempotency.Record{}}
+	svc := idempotency.NewIdempotencyService(store, idempotency.WithClock(clk), idempotency.WithTTL(time.Hour))
+
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		if _, err := svc.Begin(ctx, key, key); err != nil {
+			t.Fatal(err)
+		}
+		if key != "e" {
+			if err := svc.Complete(ctx, key, key, &idempotency.Response{StatusCode: http.StatusOK}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// Брошенный ключ "e" истекает раньше сохранённых ответов
+	clk.Advance(idempotency.LockTimeout)
+	if n, err := svc.Cleanup(ctx, 2); err != nil || n != 1 {
+		t.Errorf("expected 1 deleted, got %d, %v", n, err)
+	}
+	clk.Advance(time.Hour)
+	if n, err := svc.Cleanup(ctx, 2); err != nil || n != 4 {
+		t.Errorf("expected 4 deleted in batches, got %d, %v", n, err)
+	}
+	if len(store.records) != 0 {
+		t.Errorf("expected no records left, got %d", len(store.records))
+	}
+}
+
+func TestStorable(t *testing.T) {
+	cases := map[int]bool{
+		http.StatusOK:                  true,
+		http.StatusCreated:             true,
+		http.StatusBadRequest:          true,
+		http.StatusNotFound:            true,
+		http.StatusUnprocessableEntity: true,
+		http.StatusUnauthorized:        false,
+		http.StatusForbidden:           false,
+		http.StatusTooManyRequests:     false,
+		http.StatusInternalServerError: false,
+		http.StatusServiceUnavailable:  false,
+	}
+	for status, exp := range cases {
+		if got := idempotency.Storable(status); got != exp {
+			t.Errorf("status %d: expected %v, got %v", status, exp, got)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	base := idempotency.Fingerprint("POST", "/api/v1/orders", "", []byte(`{"a":1}`))
+	if base != idempotency.Fingerprint("POST", "/api/v1/orders", "", []byte(`{"a":1}`)) {
+		t.Error("expected equal requests to have equal fingerprints")
+	}
+	for name, other := range map[string]string{
+		"метод": idempotency.Fingerprint("PATCH", "/api/v1/orders", "", []byte(`{"a":1}`)),
+		"адрес": idempotency.Fingerprint("POST", "/api/v1/orders?dry_run=true", "", []byte(`{"a":1}`)),
+		"токен": idempotency.Fingerprint("POST", "/api/v1/orders", "Bearer x", []byte(`{"a":1}`)),
+		"тело":  idempotency.Fingerprint("POST", "/api/v1/orders", "", []byte(`{"a":2}`)),
+	} {
+		if other == base {
+			t.Errorf("expected different fingerprint when %s differs", name)
+		}
+	}
+}
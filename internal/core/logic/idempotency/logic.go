@@ -0,0 +1,36 @@
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+)
+
+// Хэш запроса. Токен входит в него, чтобы сохранённый ответ нельзя было получить без доступа к маршруту
+func Fingerprint(method, uri, authorization string, body []byte) string {
+	h := sha256.New()
+	for _, part := range []string{method, uri, authorization} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Сохраняются ответы на выполненные запросы, в том числе отклонённые как неверные.
+// Сбой сервера и отказ в доступе или по частоте не означают, что запрос выполнен:
+// повтор с тем же ключом выполняется заново
+func Storable(status int) bool {
+	switch {
+	case status < http.StatusOK, status >= http.StatusInternalServerError:
+		return false
+	case status == http.StatusUnauthorized, status == http.StatusForbidden,
+		status == http.StatusRequestTimeout, status == http.StatusTooManyRequests:
+		return false
+	}
+	return true
+}
+
+func (r *Record) Pending() bool {
+	return r.StatusCode == 0
+}
@@ -0,0 +1,39 @@
+package idempotency
+
+import (
+	"errors"
+	"time"
+)
+
+// Максимальная длина ключа Idempotency-Key
+const MaxKeyLength = 255
+
+// Столько ключ остаётся занятым первым запросом. Если сервер не дождался ответа
+// (например, упал), после этого срока запрос с тем же ключом выполняется заново
+const LockTimeout = 5 * time.Minute
+
+// Запрос с ключом и ответ на него. Пока запрос выполняется, ответа нет (StatusCode = 0)
+type Record struct {
+	Key string
+	// Хэш запроса: тот же ключ с другим запросом отклоняется
+	Fingerprint string
+	Response
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// Сохранённый ответ. Тело хранится зашифрованным: в нём могут быть данные клиента
+type Response struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	// Телефон клиента, данные которого есть в теле: ответ удаляется при обезличивании клиента
+	ClientPhone string
+}
+
+var (
+	// Ключ уже использован с другим запросом
+	ErrKeyMismatch = errors.New("idempotency key was used with a different request")
+	// Запрос с этим ключом ещё выполняется
+	ErrInProgress = errors.New("request with this idempotency key is in progress")
+)
@@ -0,0 +1,158 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/tracing"
+	"github.com/Owouwun/spkuznetsov/pkg/clock"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type KeyRepository interface {
+	// Занимает ключ записью rec, если он свободен или его срок истёк к rec.CreatedAt;
+	// иначе возвращает существующую запись
+	Reserve(ctx context.Context, rec *Record) (*Record, error)
+	// Сохраняет ответ на запрос, занявший ключ, и продлевает хранение до expiresAt
+	Complete(ctx context.Context, key, fingerprint string, resp *Response, expiresAt time.Time) error
+	// Освобождает ключ, занятый запросом без ответа
+	Release(ctx context.Context, key, fingerprint string) error
+	// Удаляет не более limit записей, срок которых истёк к now; возвращает число удалённых
+	DeleteExpired(ctx context.Context, now time.Time, limit int) (int, error)
+}
+
+// IdempotencyService хранит ответы на запросы с ключом идемпотентности
+type IdempotencyService struct {
+	repo KeyRepository
+	// Сколько хранится ответ
+	ttl   time.Duration
+	clock clock.Clock
+}
+
+type IdempotencyServiceOption func(*IdempotencyService)
+
+func WithTTL(ttl time.Duration) IdempotencyServiceOption {
+	return func(s *IdempotencyService) {
+		s.ttl = ttl
+	}
+}
+
+func WithClock(clk clock.Clock) IdempotencyServiceOption {
+	return func(s *IdempotencyService) {
+		s.clock = clk
+	}
+}
+
+func NewIdempotencyService(repo KeyRepository, opts ...IdempotencyServiceOption) *IdempotencyService {
+	s := &IdempotencyService{
+		repo:  repo,
+		ttl:   24 * time.Hour,
+		clock: clock.System{},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func startSpan(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "IdempotencyService."+method, trace.WithAttributes(attrs...))
+}
+
+func endSpan(span trace.Span, err error) {
+	// Повтор с другим запросом или во время выполнения — ошибка клиента, а не сбой
+	if err != nil && !errors.Is(err, ErrKeyMismatch) && !errors.Is(err, ErrInProgress) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Begin занимает ключ под запрос. nil означает, что запрос нужно выполнить и затем вызвать
+// Complete или Release; запись — что запрос уже выполнен и её ответ нужно повторить.
+// ErrKeyMismatch, если ключ занят другим запросом, ErrInProgress — если тот же запрос ещё выполняется
+func (s *IdempotencyService) Begin(ctx context.Context, key, fingerprint string) (_ *Record, err error) {
+	ctx, span := startSpan(ctx, "Begin")
+	defer func() { endSpan(span, err) }()
+
+	now := s.clock.Now()
+	existing, err := s.repo.Reserve(ctx, &Record{
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(LockTimeout),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to reserve idempotency key", slog.Any("error", err))
+		return nil, err
+	}
+	if existing == nil {
+		return nil, nil
+	}
+
+	span.SetAttributes(attribute.Bool("idempotency.replayed", true))
+	if existing.Fingerprint != fingerprint {
+		slog.WarnContext(ctx, "idempotency key reused with a different request")
+		return nil, ErrKeyMismatch
+	}
+	if existing.Pending() {
+		return nil, ErrInProgress
+	}
+	return existing, nil
+}
+
+// Complete сохраняет ответ на запрос, занявший ключ
+func (s *IdempotencyService) Complete(ctx context.Context, key, fingerprint string, resp *Response) (err error) {
+	ctx, span := startSpan(ctx, "Complete", attribute.Int("http.response.status_code", resp.StatusCode))
+	defer func() { endSpan(span, err) }()
+
+	if err := s.repo.Complete(ctx, key, fingerprint, resp, s.clock.Now().Add(s.ttl)); err != nil {
+		slog.ErrorContext(ctx, "failed to save idempotent response", slog.Any("error", err))
+		return err
+	}
+	return nil
+}
+
+// Release освобождает ключ, если ответ сохранять не нужно
+func (s *IdempotencyService) Release(ctx context.Context, key, fingerprint string) (err error) {
+	ctx, span := startSpan(ctx, "Release")
+	defer func() { endSpan(span, err) }()
+
+	if err := s.repo.Release(ctx, key, fingerprint); err != nil {
+		slog.ErrorContext(ctx, "failed to release idempotency key", slog.Any("error", err))
+		return err
+	}
+	return nil
+}
+
+// Удалить записи с истёкшим сроком порциями по batchSize
+func (s *IdempotencyService) Cleanup(ctx context.Context, batchSize int) (total int, err error) {
+	ctx, span := startSpan(ctx, "Cleanup")
+	defer func() {
+		span.SetAttributes(attribute.Int("idempotency.deleted", total))
+		endSpan(span, err)
+	}()
+
+	now := s.clock.Now()
+	for {
+		n, err := s.repo.DeleteExpired(ctx, now, batchSize)
+		total += n
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to delete expired idempotency keys", slog.Int("deleted", total), slog.Any("error", err))
+			return total, err
+		}
+		if n < batchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		slog.InfoContext(ctx, "expired idempotency keys deleted", slog.Int("deleted", total))
+	}
+	return total, nil
+}
@@ -0,0 +1,229 @@
+package intake_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/intake"
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/Owouwun/spkuznetsov/pkg/clock"
+	"github.com/Owouwun/spkuznetsov/pkg/ratelimit"
+	"github.com/google/uuid"
+)
+
+type orderBook struct {
+	existing []*orders.Order
+	created  []*orders.PrimaryOrder
+	getErr   error
+}
+
+func (b *orderBook) Create(ctx context.Context, pord *orders.PrimaryOrder) (uuid.UUID, error) {
+	b.created = append(b.created, pord)
+	return uuid.New(), nil
+}
+
+func (b *orderBook) GetAll(ctx context.Context, filter orders.ListFilter) ([]*orders.Order, error) {
+	var found []*orders.Order
+	for _, ord := range b.existing {
+		if ord.ClientPhone == filter.ClientPhone {
+			found = append(found, ord)
+		}
+	}
+	return found, b.getErr
+}
+
+func submission(phone, description string) *intake.Submission {
+	return &intake.Submission{
+		Order: &orders.PrimaryOrder{
+			ClientName:        "Иван",
+			ClientPhone:       phone,
+			Address:           orders.Address{City: "Москва", Street: "Тверская", House: "1"},
+			ClientDescription: description,
+		},
+		IP:           "203.0.113.7",
+		CaptchaToken: "ok",
+	}
+}
+
+func TestSubmit(t *testing.T) {
+	ctx := context.Background()
+
+	cases := []struct {
+		name           string
+		sub            *intake.Submission
+		existing       []*orders.Order
+		getErr         error
+		expErr         error
+		expCreated     bool
+		expQuarantined bool
+	}{
+		{
+			name:       "Обычная заявка",
+			sub:        submission("8 (916) 382-91-04", "Течёт кран на кухне"),
+			expCreated: true,
+		},
+		{
+			name:           "Ссылка в описании",
+			sub:            submission("89163829104", "Дешёвые кредиты на https://example.com"),
+			expCreated:     true,
+			expQuarantined: true,
+		},
+		{
+			name:           "Домен без протокола",
+			sub:            submission("89163829104", "Заходите на kredit-bystro.ru"),
+			expCreated:     true,
+			expQuarantined: true,
+		},
+		{
+			name:       "Сокращения в описании ссылкой не считаются",
+			sub:        submission("89163829104", "Котёл, батареи и т.д. Ул. Тверская"),
+			expCreated: true,
+		},
+		{
+			name:           "Номер из одной цифры",
+			sub:            submission("+7 999 999-99-99", "Течёт кран"),
+			expCreated:     true,
+			expQuarantined: true,
+		},
+		{
+			name:           "Цифры подряд",
+			sub:            submission("+7 916 123-45-67", "Течёт кран"),
+			expCreated:     true,
+			expQuarantined: true,
+		},
+		{
+			name:           "Несуществующий код",
+			sub:            submission("+7 016 382-91-04", "Течёт кран"),
+			expCreated:     true,
+			expQuarantined: true,
+		},
+		{
+			name: "Одна необработанная заявка на телефон",
+			sub:  submission("89163829104", "Течёт кран"),
+			existing: []*orders.Order{
+				{ClientPhone: "+79163829104", Status: orders.StatusNew},
+				{ClientPhone: "+79163829104", Status: orders.StatusPaid},
+			},
+			expCreated: true,
+		},
+		{
+			name: "Повторные заявки",
+			sub:  submission("89163829104", "Течёт кран"),
+			existing: []*orders.Order{
+				{ClientPhone: "+79163829104", Status: orders.StatusNew},
+				{ClientPhone: "+79163829104", Status: orders.StatusQuarantined},
+			},
+			expCreated:     true,
+			expQuarantined: true,
+		},
+		{
+			name: "Заполнено скрытое поле",
+			sub: func() *intake.Submission {
+				sub := submission("89163829104", "Течёт кран")
+				sub.Honeypot = "http://spam.example"
+				return sub
+			}(),
+		},
+		{
+			name: "Неверная капча",
+			sub: func() *intake.Submission {
+				sub := submission("89163829104", "Течёт кран")
+				sub.CaptchaToken = "wrong"
+				return sub
+			}(),
+			expErr: intake.ErrCaptchaFailed,
+		},
+		{
+			name:       "Неверный телефон проверяет сервис заявок",
+			sub:        submission("abc", "Течёт кран"),
+			expCreated: true,
+		},
+		{
+			name:   "Заявки клиента недоступны",
+			sub:    submission("89163829104", "Течёт кран"),
+			getErr: errors.New("db down"),
+			expErr: errors.New("db down"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			book := &orderBook{existing: tc.existing, getErr: tc.getErr}
+			svc := intake.NewIntakeService(book, intake.WithCaptcha(intake.StaticCaptcha{Token: "ok"}))
+
+			id, err := svc.Submit(ctx, tc.sub)
+			if tc.expErr != nil {
+				if err == nil || (!errors.Is(err, tc.expErr) && err.Error() != tc.expErr.Error()) {
+					t.Fatalf("expected %v, got %v", tc.expErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id == uuid.Nil {
+				t.Error("expected order id")
+			}
+
+			if created := len(book.created) == 1; created != tc.expCreated {
+				t.Fatalf("expected created %v, got %d orders", tc.expCreated, len(book.created))
+			}
+			if tc.expCreated && book.created[0].Quarantined != tc.expQuarantined {
+				t.Errorf("expected quarantined %v, got %v", tc.expQuarantined, book.created[0].Quarantined)
+			}
+			if tc.sub.Order.Quarantined {
+				t.Error("expected submission to stay unchanged")
+			}
+		})
+	}
+}
+
+func TestSubmit_RateLimit(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewManual(time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC))
+	book := &orderBook{}
+	svc := intake.NewIntakeService(book,
+		intake.WithClock(clk),
+		intake.WithLimitStore(ratelimit.NewMemory()),
+		intake.WithPolicy(intake.Policy{
+			PerIP:    ratelimit.Rate{Burst: 3, Every: time.Minute},
+			PerPhone: ratelimit.Rate{Burst: 2, Every: time.Hour},
+		}),
+	)
+
+	expectLimit := func(sub *intake.Submission, scope intake.Scope, retryAfter time.Duration) {
+		t.Helper()
+		_, err := svc.Submit(ctx, sub)
+		var limitErr *intake.RateLimitError
+		if !errors.As(err, &limitErr) || limitErr.Scope != scope || limitErr.RetryAfter != retryAfter {
+			t.Fatalf("expected %s limit for %s, got %v", scope, retryAfter, err)
+		}
+	}
+
+	for range 2 {
+		if _, err := svc.Submit(ctx, submission("89163829104", "")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Третья заявка на тот же телефон, даже в другом написании
+	expectLimit(submission("+7 916 382-91-04", ""), intake.ScopePhone, time.Hour)
+
+	// Жетоны IP кончились на третьей заявке
+	other := submission("89161112233", "")
+	expectLimit(other, intake.ScopeIP, time.Minute)
+
+	other.IP = "198.51.100.1"
+	if _, err := svc.Submit(ctx, other); err != nil {
+		t.Errorf("expected other ip to pass, got %v", err)
+	}
+
+	clk.Advance(time.Minute)
+	if _, err := svc.Submit(ctx, submission("89161112244", "")); err != nil {
+		t.Errorf("expected ip bucket to refill, got %v", err)
+	}
+	if len(book.created) != 4 {
+		t.Errorf("expected 4 orders, got %d", len(book.created))
+	}
+}
@@ -0,0 +1,57 @@
+package intake
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+)
+
+// Ссылка или адрес сайта: протокол, www, t.me или домен в популярной зоне
+var linkPattern = regexp.MustCompile(`(?i)https?://|www\.|t\.me/|\b[a-z0-9-]+\.(?:ru|su|com|net|org|info|biz|xyz|top|site|online|shop|io)\b|[а-яё0-9-]+\.рф`)
+
+// Признаки спама в заявке. phone — стандартизованный телефон, pending — число
+// заявок на этот телефон, которые ещё не начали обрабатывать
+func detectSignals(pord *orders.PrimaryOrder, phone string, pending int) []Signal {
+	var found []Signal
+	if linkPattern.MatchString(pord.ClientName) || linkPattern.MatchString(pord.ClientDescription) {
+		found = append(found, SignalLink)
+	}
+	if suspiciousPhone(phone) {
+		found = append(found, SignalSuspiciousPhone)
+	}
+	for range pending {
+		found = append(found, SignalRepeated)
+	}
+	return found
+}
+
+func score(signals []Signal) int {
+	total := 0
+	for _, s := range signals {
+		total += signalWeights[s]
+	}
+	return total
+}
+
+// Номер из одной повторяющейся цифры или цифр подряд, а также номер зоны +7
+// с кодом, который не выдаётся (настоящие начинаются с 3, 4, 6, 7, 8 или 9)
+func suspiciousPhone(phone string) bool {
+	digits := strings.TrimPrefix(phone, "+")
+	if len(digits) < 7 {
+		return false
+	}
+	if len(digits) == 11 && digits[0] == '7' && strings.ContainsRune("0125", rune(digits[1])) {
+		return true
+	}
+
+	subscriber := digits[len(digits)-7:]
+	return strings.Count(subscriber, subscriber[:1]) == len(subscriber) ||
+		strings.Contains("0123456789", subscriber) ||
+		strings.Contains("9876543210", subscriber)
+}
+
+// Заявка, которую ещё не начали обрабатывать: повторная заявка к ней ничего не добавит
+func unprocessed(ord *orders.Order) bool {
+	return ord.Status == orders.StatusNew || ord.Status == orders.StatusQuarantined
+}
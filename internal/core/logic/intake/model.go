@@ -0,0 +1,76 @@
+package intake
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/Owouwun/spkuznetsov/pkg/ratelimit"
+)
+
+// Заявка с публичного сайта вместе с признаками, по которым отсеиваются боты
+type Submission struct {
+	Order *orders.PrimaryOrder
+	// Адрес, с которого отправлена заявка
+	IP string
+	// Ответ капчи, полученный формой сайта от провайдера
+	CaptchaToken string
+	// Скрытое поле формы: человек его не видит и оставляет пустым
+	Honeypot string
+}
+
+// Признак спама
+type Signal string
+
+const (
+	// Ссылка в имени или описании
+	SignalLink Signal = "link"
+	// У телефона уже есть заявка, которую ещё не начали обрабатывать
+	SignalRepeated Signal = "repeated"
+	// Номер не похож на настоящий: одна цифра, цифры подряд, несуществующий код
+	SignalSuspiciousPhone Signal = "suspicious_phone"
+)
+
+// Вес признака; SignalRepeated учитывается за каждую необработанную заявку
+var signalWeights = map[Signal]int{
+	SignalLink:            2,
+	SignalRepeated:        1,
+	SignalSuspiciousPhone: 2,
+}
+
+// Ограничения приёма заявок
+type Policy struct {
+	// Частота заявок с одного IP и на один телефон
+	PerIP    ratelimit.Rate
+	PerPhone ratelimit.Rate
+	// Заявка, сумма весов признаков спама которой не меньше QuarantineScore,
+	// оформляется в статусе Quarantined; 0 — не проверять
+	QuarantineScore int
+}
+
+var DefaultPolicy = Policy{
+	PerIP:           ratelimit.Rate{Burst: 5, Every: 10 * time.Minute},
+	PerPhone:        ratelimit.Rate{Burst: 3, Every: time.Hour},
+	QuarantineScore: 2,
+}
+
+// По чему ограничена частота заявок
+type Scope string
+
+const (
+	ScopeIP    Scope = "ip"
+	ScopePhone Scope = "phone"
+)
+
+// Превышена частота заявок; следующую можно отправить через RetryAfter
+type RateLimitError struct {
+	Scope      Scope
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("too many orders from this %s, retry after %s", e.Scope, e.RetryAfter.Round(time.Second))
+}
+
+var ErrCaptchaFailed = errors.New("captcha verification failed")
@@ -0,0 +1,193 @@
+package intake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	deterrs "github.com/Owouwun/spkuznetsov/internal/errors"
+	"github.com/Owouwun/spkuznetsov/internal/logging"
+	"github.com/Owouwun/spkuznetsov/internal/tracing"
+	"github.com/Owouwun/spkuznetsov/pkg/clock"
+	"github.com/Owouwun/spkuznetsov/pkg/ratelimit"
+	"github.com/Owouwun/spkuznetsov/pkg/utils"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type OrderService interface {
+	Create(ctx context.Context, pord *orders.PrimaryOrder) (uuid.UUID, error)
+	GetAll(ctx context.Context, filter orders.ListFilter) ([]*orders.Order, error)
+}
+
+// Проверка ответа капчи у провайдера (reCAPTCHA, hCaptcha, SmartCaptcha и т.п.)
+type CaptchaVerifier interface {
+	// false, если ответ неверный, просрочен или пуст
+	Verify(ctx context.Context, token, remoteIP string) (bool, error)
+}
+
+// StaticCaptcha принимает только ответ Token. Заменяет провайдера в тестах и при локальной разработке
+type StaticCaptcha struct {
+	Token string
+}
+
+func (c StaticCaptcha) Verify(_ context.Context, token, _ string) (bool, error) {
+	return token != "" && token == c.Token, nil
+}
+
+// IntakeService принимает заявки с публичного сайта: ограничивает их частоту,
+// отсеивает ботов и откладывает на проверку заявки, похожие на спам
+type IntakeService struct {
+	orders OrderService
+	limits ratelimit.Store
+	// nil — капча не проверяется
+	captcha CaptchaVerifier
+	policy  Policy
+	clock   clock.Clock
+}
+
+type IntakeServiceOption func(*IntakeService)
+
+// WithLimitStore задаёт хранилище счётчиков частоты, общее для реплик
+func WithLimitStore(store ratelimit.Store) IntakeServiceOption {
+	return func(s *IntakeService) {
+		s.limits = store
+	}
+}
+
+func WithCaptcha(verifier CaptchaVerifier) IntakeServiceOption {
+	return func(s *IntakeService) {
+		s.captcha = verifier
+	}
+}
+
+func WithPolicy(policy Policy) IntakeServiceOption {
+	return func(s *IntakeService) {
+		s.policy = policy
+	}
+}
+
+func WithClock(clk clock.Clock) IntakeServiceOption {
+	return func(s *IntakeService) {
+		s.clock = clk
+	}
+}
+
+func NewIntakeService(orderService OrderService, opts ...IntakeServiceOption) *IntakeService {
+	s := &IntakeService{
+		orders: orderService,
+		limits: ratelimit.NewMemory(),
+		policy: DefaultPolicy,
+		clock:  clock.System{},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func startSpan(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "IntakeService."+method, trace.WithAttributes(attrs...))
+}
+
+func endSpan(span trace.Span, err error) {
+	// Отклонённая заявка — ошибка клиента, а не сбой
+	var detErr *deterrs.DetErr
+	var limitErr *RateLimitError
+	if err != nil && !errors.As(err, &detErr) && !errors.As(err, &limitErr) && !errors.Is(err, ErrCaptchaFailed) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Submit оформляет заявку с сайта. Если заполнено скрытое поле, заявка не создаётся,
+// но возвращается случайный ID, чтобы бот не понял, что его отсеяли
+func (s *IntakeService) Submit(ctx context.Context, sub *Submission) (_ uuid.UUID, err error) {
+	ctx, span := startSpan(ctx, "Submit")
+	defer func() { endSpan(span, err) }()
+
+	ctx = logging.WithAttrs(ctx, slog.String("client_ip", sub.IP))
+
+	if err := s.take(ctx, ScopeIP, sub.IP, s.policy.PerIP); err != nil {
+		return uuid.Nil, err
+	}
+
+	if strings.TrimSpace(sub.Honeypot) != "" {
+		span.SetAttributes(attribute.Bool("intake.honeypot", true))
+		slog.WarnContext(ctx, "honeypot field filled, order dropped")
+		return uuid.New(), nil
+	}
+
+	if s.captcha != nil {
+		ok, err := s.captcha.Verify(ctx, sub.CaptchaToken, sub.IP)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to verify captcha", slog.Any("error", err))
+			return uuid.Nil, fmt.Errorf("verify captcha: %w", err)
+		}
+		if !ok {
+			slog.WarnContext(ctx, "captcha verification failed")
+			return uuid.Nil, ErrCaptchaFailed
+		}
+	}
+
+	pord := *sub.Order
+	// Телефон, который не удаётся стандартизовать, отклонит сервис заявок
+	if phone, err := utils.StandartizePhoneNumber(pord.ClientPhone); err == nil {
+		if err := s.take(ctx, ScopePhone, phone, s.policy.PerPhone); err != nil {
+			return uuid.Nil, err
+		}
+
+		if s.policy.QuarantineScore > 0 {
+			signals, err := s.signals(ctx, &pord, phone)
+			if err != nil {
+				return uuid.Nil, err
+			}
+			total := score(signals)
+			span.SetAttributes(attribute.Int("intake.spam_score", total))
+			if total >= s.policy.QuarantineScore {
+				pord.Quarantined = true
+				slog.WarnContext(ctx, "order quarantined as suspected spam",
+					slog.Any("signals", signals), slog.Int("score", total))
+			}
+		}
+	}
+
+	return s.orders.Create(ctx, &pord)
+}
+
+// Забрать жетон из корзины scope/key
+func (s *IntakeService) take(ctx context.Context, scope Scope, key string, rate ratelimit.Rate) error {
+	ok, retryAfter, err := s.limits.Take(ctx, string(scope)+":"+key, rate, s.clock.Now())
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to check rate limit", slog.String("scope", string(scope)), slog.Any("error", err))
+		return fmt.Errorf("check %s rate limit: %w", scope, err)
+	}
+	if !ok {
+		slog.WarnContext(ctx, "order rate limit exceeded", slog.String("scope", string(scope)))
+		return &RateLimitError{Scope: scope, RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+func (s *IntakeService) signals(ctx context.Context, pord *orders.PrimaryOrder, phone string) ([]Signal, error) {
+	existing, err := s.orders.GetAll(ctx, orders.ListFilter{ClientPhone: phone})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get client orders", slog.Any("error", err))
+		return nil, err
+	}
+
+	pending := 0
+	for _, ord := range existing {
+		if unprocessed(ord) {
+			pending++
+		}
+	}
+	return detectSignals(pord, phone, pending), nil
+}
@@ -0,0 +1,584 @@
+package inventory_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/auth"
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/inventory"
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	deterrs "github.com/Owouwun/spkuznetsov/internal/errors"
+	"github.com/google/uuid"
+)
+
+func TestCreateNewPart(t *testing.T) {
+	cases := []struct {
+		name   string
+		part   inventory.PrimaryPart
+		expErr error
+	}{
+		{name: "Корректная запчасть", part: inventory.PrimaryPart{SKU: " VALVE-1 ", Name: "Клапан", Price: 150000, LowStockThreshold: 3}},
+		{name: "Без артикула", part: inventory.PrimaryPart{Name: "Клапан"}, expErr: deterrs.NewDetErr(deterrs.EmptyField)},
+		{name: "Без названия", part: inventory.PrimaryPart{SKU: "VALVE-1", Name: " "}, expErr: deterrs.NewDetErr(deterrs.EmptyField)},
+		{name: "Отрицательная цена", part: inventory.PrimaryPart{SKU: "VALVE-1", Name: "Клапан", Price: -1}, expErr: deterrs.NewDetErr(deterrs.InvalidValue)},
+		{name: "Отрицательный порог", part: inventory.PrimaryPart{SKU: "VALVE-1", Name: "Клапан", LowStockThreshold: -1}, expErr: deterrs.NewDetErr(deterrs.InvalidValue)},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			part, err := tc.part.CreateNewPart()
+			if !errors.Is(err, tc.expErr) {
+				t.Fatalf("expected %v, got %v", tc.expErr, err)
+			}
+			if tc.expErr == nil && (part.SKU != "VALVE-1" || part.ID == uuid.Nil) {
+				t.Errorf("unexpected part %+v", part)
+			}
+		})
+	}
+}
+
+// Хранилище склада в памяти: остатки не моделируются, записываются вызовы
+// и обработка журнала событий заявок
+type stockStore struct {
+	parts      map[uuid.UUID]*inventory.Part
+	statuses   map[uuid.UUID]orders.Status
+	orderParts map[uuid.UUID][]inventory.OrderPart
+	// Видимые события заявок, отметки обработанных и позиция, до которой обработаны все
+	journal   []*orders.Event
+	processed map[int64]bool
+	cursor    int64
+	// Ошибки применения событий и число неудачных попыток
+	failOn   map[int64]error
+	attempts map[int64]int
+	// События до settled включительно старше времени ожидания
+	settled int64
+	calls   []string
+	used    []inventory.Usage
+	// Изменения остатков, которые вернут резерв и списание
+	reserveChanges []inventory.LevelChange
+	consumeChanges []inventory.LevelChange
+	events         []*inventory.Event
+}
+
+func newStockStore(parts ...*inventory.Part) *stockStore {
+	s := &stockStore{
+		parts:      make(map[uuid.UUID]*inventory.Part),
+		statuses:   make(map[uuid.UUID]orders.Status),
+		orderParts: make(map[uuid.UUID][]inventory.OrderPart),
+		processed:  make(map[int64]bool),
+		failOn:     make(map[int64]error),
+		attempts:   make(map[int64]int),
+	}
+	for _, p := range parts {
+		s.parts[p.ID] = p
+	}
+	return s
+}
+
+func (s *stockStore) mark(eventID int64) error {
+	if eventID == 0 {
+		return nil
+	}
+	if err := s.failOn[eventID]; err != nil {
+		return err
+	}
+	if s.processed[eventID] || eventID <= s.cursor {
+		return inventory.ErrEventProcessed
+	}
+	s.processed[eventID] = true
+	return nil
+}
+
+func (s *stockStore) CreatePart(ctx context.Context, part *inventory.Part) error {
+	s.parts[part.ID] = part
+	return nil
+}
+
+func (s *stockStore) GetPart(ctx context.Context, id uuid.UUID) (*inventory.Part, error) {
+	p, ok := s.parts[id]
+	if !ok {
+		return nil, inventory.ErrNotFound
+	}
+	return p, nil
+}
+
+func (s *stockStore) GetParts(ctx context.Context) ([]*inventory.Part, error) {
+	return nil, nil
+}
+
+func (s *stockStore) GetPartsBySKU(ctx context.Context, skus []string) ([]*inventory.Part, error) {
+	var found []*inventory.Part
+	for _, p := range s.parts {
+		for _, sku := range skus {
+			if p.SKU == sku {
+				found = append(found, p)
+			}
+		}
+	}
+	return found, nil
+}
+
+func (s *stockStore) GetStock(ctx context.Context, filter inventory.StockFilter) ([]*inventory.Stock, error) {
+	return nil, nil
+}
+
+func (s *stockStore) Receive(ctx context.Context, r *inventory.Receipt) ([]inventory.LevelChange, error) {
+	s.calls = append(s.calls, fmt.Sprintf("receive %s %d", r.Location, r.Quantity))
+	return nil, nil
+}
+
+func (s *stockStore) Transfer(ctx context.Context, t *inventory.Transfer) error {
+	s.calls = append(s.calls, fmt.Sprintf("transfer %s %s %d", t.From, t.To, t.Quantity))
+	return nil
+}
+
+func (s *stockStore) OrderStatus(ctx context.Context, orderID uuid.UUID) (orders.Status, error) {
+	status, ok := s.statuses[orderID]
+	if !ok {
+		return 0, inventory.ErrOrderNotFound
+	}
+	return status, nil
+}
+
+func (s *stockStore) SetOrderParts(ctx context.Context, orderID uuid.UUID, parts []inventory.OrderPart) error {
+	s.orderParts[orderID] = parts
+	return nil
+}
+
+func (s *stockStore) GetOrderParts(ctx context.Context, orderID uuid.UUID) ([]*inventory.OrderPart, error) {
+	var parts []*inventory.OrderPart
+	for _, p := range s.orderParts[orderID] {
+		parts = append(parts, &p)
+	}
+	return parts, nil
+}
+
+func (s *stockStore) Reserve(ctx context.Context, orderID uuid.UUID, sources []inventory.Location, eventID int64) ([]inventory.LevelChange, error) {
+	if err := s.mark(eventID); err != nil {
+		return nil, err
+	}
+	locations := make([]string, 0, len(sources))
+	for _, l := range sources {
+		locations = append(locations, string(l))
+	}
+	s.calls = append(s.calls, fmt.Sprintf("reserve %s %s", orderID, strings.Join(locations, ",")))
+	return s.reserveChanges, nil
+}
+
+func (s *stockStore) Release(ctx context.Context, orderID uuid.UUID, eventID int64) ([]inventory.LevelChange, error) {
+	if err := s.mark(eventID); err != nil {
+		return nil, err
+	}
+	s.calls = append(s.calls, fmt.Sprintf("release %s", orderID))
+	return nil, nil
+}
+
+func (s *stockStore) Consume(ctx context.Context, orderID uuid.UUID, source inventory.Location, used []inventory.Usage, eventID int64) ([]inventory.LevelChange, error) {
+	if err := s.mark(eventID); err != nil {
+		return nil, err
+	}
+	s.calls = append(s.calls, fmt.Sprintf("consume %s %s", orderID, source))
+	s.used = append(s.used, used...)
+	return s.consumeChanges, nil
+}
+
+func (s *stockStore) Return(ctx context.Context, orderID uuid.UUID, r *inventory.Return) ([]inventory.LevelChange, error) {
+	s.calls = append(s.calls, fmt.Sprintf("return %s %s %d", orderID, r.Location, r.Quantity))
+	return nil, nil
+}
+
+func (s *stockStore) GetLineItems(ctx context.Context, orderID uuid.UUID) ([]*inventory.LineItem, error) {
+	return nil, nil
+}
+
+func (s *stockStore) PendingOrderEvents(ctx context.Context, limit int) ([]*orders.Event, error) {
+	var events, failed []*orders.Event
+	for _, ev := range s.journal {
+		if ev.ID <= s.cursor || s.processed[ev.ID] {
+			continue
+		}
+		if s.attempts[ev.ID] > 0 {
+			failed = append(failed, ev)
+		} else {
+			events = append(events, ev)
+		}
+	}
+	events = append(events, failed...)
+	return events[:min(limit, len(events))], nil
+}
+
+func (s *stockStore) EventFailed(ctx context.Context, eventID int64, cause error) error {
+	s.attempts[eventID]++
+	return nil
+}
+
+func (s *stockStore) MarkProcessed(ctx context.Context, eventIDs []int64) error {
+	for _, id := range eventIDs {
+		s.processed[id] = true
+	}
+	return nil
+}
+
+func (s *stockStore) CompactProcessed(ctx context.Context, settle time.Duration) error {
+	to := s.cursor
+	for _, ev := range s.journal {
+		if ev.ID <= s.cursor {
+			continue
+		}
+		if !s.processed[ev.ID] || ev.ID > s.settled {
+			break
+		}
+		to = ev.ID
+	}
+	for id := range s.processed {
+		if id <= to {
+			delete(s.processed, id)
+		}
+	}
+	s.cursor = to
+	return nil
+}
+
+func (s *stockStore) AppendEvent(ctx context.Context, ev *inventory.Event) (int64, error) {
+	s.events = append(s.events, ev)
+	return int64(len(s.events)), nil
+}
+
+func (s *stockStore) EventsSince(ctx context.Context, afterID int64, limit int) ([]*inventory.Event, error) {
+	return nil, nil
+}
+
+// Заявки с визитами
+type orderFeed struct {
+	orders map[uuid.UUID]*orders.Order
+	visits map[uuid.UUID][]*orders.Visit
+}
+
+func (f *orderFeed) GetByID(ctx context.Context, id uuid.UUID) (*orders.Order, error) {
+	o, ok := f.orders[id]
+	if !ok {
+		return nil, errors.New("record not found")
+	}
+	return o, nil
+}
+
+func (f *orderFeed) GetVisits(ctx context.Context, id uuid.UUID) ([]*orders.Visit, error) {
+	return f.visits[id], nil
+}
+
+func newValve() *inventory.Part {
+	return &inventory.Part{ID: uuid.New(), SKU: "VALVE-1", Name: "Клапан", Price: 150000, LowStockThreshold: 3}
+}
+
+func TestProcessOrderEvents(t *testing.T) {
+	valve := newValve()
+	scheduled := &orders.Order{ID: uuid.New(), Status: orders.StatusDone, Employee: &auth.Employee{ID: 7}}
+	canceled := &orders.Order{ID: uuid.New(), Status: orders.StatusCanceled}
+	deleted := uuid.New()
+
+	newFeed := func() *orderFeed {
+		return &orderFeed{
+			orders: map[uuid.UUID]*orders.Order{scheduled.ID: scheduled, canceled.ID: canceled},
+			visits: map[uuid.UUID][]*orders.Visit{
+				scheduled.ID: {
+					{PartsUsed: []orders.UsedPart{{SKU: "VALVE-1", Name: "Клапан", Quantity: 1}, {Name: "Изолента", Quantity: 1}}},
+					{PartsUsed: []orders.UsedPart{{SKU: " VALVE-1", Name: "Клапан", Quantity: 1}}},
+				},
+			},
+		}
+	}
+	newJournal := func() []*orders.Event {
+		return []*orders.Event{
+			{ID: 11, Type: orders.EventCreated, OrderID: scheduled.ID},
+			{ID: 12, Type: orders.EventScheduled, OrderID: scheduled.ID, Status: orders.StatusScheduled},
+			{ID: 13, Type: orders.EventProgressed, OrderID: scheduled.ID},
+			{ID: 14, Type: orders.EventCompleted, OrderID: scheduled.ID, Status: orders.StatusDone},
+			{ID: 15, Type: orders.EventCanceled, OrderID: canceled.ID, Status: orders.StatusCanceled},
+			{ID: 16, Type: orders.EventScheduled, OrderID: deleted, Status: orders.StatusScheduled},
+			{ID: 17, Type: orders.EventCreated, OrderID: canceled.ID},
+		}
+	}
+	newStore := func() *stockStore {
+		store := newStockStore(valve)
+		store.journal = newJournal()
+		store.cursor = 10
+		store.settled = 17
+		store.statuses[scheduled.ID] = scheduled.Status
+		store.statuses[canceled.ID] = canceled.Status
+		return store
+	}
+
+	t.Run("Резерв при планировании, списание при выполнении, снятие резерва при отмене", func(t *testing.T) {
+		store := newStore()
+		store.reserveChanges = []inventory.LevelChange{{PartID: valve.ID, Before: 6, After: 4}}
+		store.consumeChanges = []inventory.LevelChange{{PartID: valve.ID, Before: 4, After: 2}}
+		svc := inventory.NewInventoryService(store, newFeed(), inventory.WithWarehouse("central"))
+
+		done, err := svc.ProcessOrderEvents(context.Background(), 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if done != 7 || store.cursor != 17 || len(store.processed) != 0 {
+			t.Errorf("expected 7 events processed up to 17, got %d up to %d, marks %v", done, store.cursor, store.processed)
+		}
+
+		expCalls := []string{
+			fmt.Sprintf("reserve %s van:7,warehouse:central", scheduled.ID),
+			fmt.Sprintf("consume %s van:7", scheduled.ID),
+			fmt.Sprintf("release %s", canceled.ID),
+		}
+		if strings.Join(store.calls, "\n") != strings.Join(expCalls, "\n") {
+			t.Errorf("expected calls:\n%s\ngot:\n%s", strings.Join(expCalls, "\n"), strings.Join(store.calls, "\n"))
+		}
+		// Запчасти из разных визитов суммируются; запчасть не из каталога не списывается
+		if len(store.used) != 1 || store.used[0].PartID != valve.ID || store.used[0].Quantity != 2 {
+			t.Errorf("expected 2 valves consumed, got %+v", store.used)
+		}
+		// Оповещение только при переходе порога 3
+		if len(store.events) != 1 || store.events[0].Type != inventory.EventLowStock || store.events[0].Available != 2 {
+			t.Errorf("expected one low stock event, got %+v", store.events)
+		}
+
+		if done, err := svc.ProcessOrderEvents(context.Background(), 100); err != nil || done != 0 {
+			t.Errorf("expected nothing to process, got %d, %v", done, err)
+		}
+	})
+
+	t.Run("Порция ограничена batchSize", func(t *testing.T) {
+		store := newStore()
+		svc := inventory.NewInventoryService(store, newFeed())
+
+		done, err := svc.ProcessOrderEvents(context.Background(), 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if done != 3 || store.cursor != 13 || len(store.calls) != 1 {
+			t.Errorf("expected 3 events up to 13 with one reservation, got %d up to %d, calls %v", done, store.cursor, store.calls)
+		}
+	})
+
+	t.Run("Событие обработала другая реплика", func(t *testing.T) {
+		store := newStore()
+		// Другая реплика успела обработать событие 12, пока эта читала журнал
+		svc := inventory.NewInventoryService(&racingStore{stockStore: store, eventID: 12}, newFeed())
+
+		done, err := svc.ProcessOrderEvents(context.Background(), 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if done != 6 || store.cursor != 17 {
+			t.Errorf("expected 6 events processed up to 17, got %d up to %d", done, store.cursor)
+		}
+		for _, call := range store.calls {
+			if strings.HasPrefix(call, "reserve") {
+				t.Errorf("expected the event of another replica not to be applied again, got %v", store.calls)
+			}
+		}
+	})
+
+	t.Run("Событие зафиксировано позже событий с большим ID", func(t *testing.T) {
+		store := newStore()
+		// Транзакция с событием 12 ещё не зафиксирована, остальные события свежие
+		journal := newJournal()
+		late := journal[1]
+		store.journal = append(journal[:1:1], journal[2:]...)
+		store.settled = 10
+		svc := inventory.NewInventoryService(store, newFeed())
+
+		done, err := svc.ProcessOrderEvents(context.Background(), 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if done != 6 || store.cursor != 10 {
+			t.Errorf("expected 6 events processed with the cursor kept at 10, got %d up to %d", done, store.cursor)
+		}
+
+		store.journal = newJournal()
+		store.settled = 17
+		done, err = svc.ProcessOrderEvents(context.Background(), 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if done != 1 || store.cursor != 17 || len(store.processed) != 0 {
+			t.Errorf("expected the late event processed and the cursor moved to 17, got %d up to %d", done, store.cursor)
+		}
+		expReserve := fmt.Sprintf("reserve %s van:7,warehouse:main", late.OrderID)
+		if len(store.calls) != 3 || store.calls[2] != expReserve {
+			t.Errorf("expected the late event to reserve parts, got calls %v", store.calls)
+		}
+	})
+}
+
+func TestProcessOrderEvents_Failure(t *testing.T) {
+	valve := newValve()
+	broken := &orders.Order{ID: uuid.New(), Status: orders.StatusScheduled, Employee: &auth.Employee{ID: 7}}
+	done := &orders.Order{ID: uuid.New(), Status: orders.StatusDone, Employee: &auth.Employee{ID: 8}}
+	feed := &orderFeed{
+		orders: map[uuid.UUID]*orders.Order{broken.ID: broken, done.ID: done},
+		visits: map[uuid.UUID][]*orders.Visit{
+			done.ID: {{PartsUsed: []orders.UsedPart{{SKU: "VALVE-1", Name: "Клапан", Quantity: 1}}}},
+		},
+	}
+	store := newStockStore(valve)
+	store.journal = []*orders.Event{
+		{ID: 11, Type: orders.EventScheduled, OrderID: broken.ID, Status: orders.StatusScheduled},
+		{ID: 12, Type: orders.EventCompleted, OrderID: done.ID, Status: orders.StatusDone},
+	}
+	store.cursor = 10
+	store.settled = 12
+	store.statuses[broken.ID] = broken.Status
+	store.statuses[done.ID] = done.Status
+	store.failOn[11] = errors.New("deadlock detected")
+	svc := inventory.NewInventoryService(store, feed)
+
+	processed, err := svc.ProcessOrderEvents(context.Background(), 100)
+	if err == nil || !strings.Contains(err.Error(), "order event 11") {
+		t.Errorf("expected the error of event 11, got %v", err)
+	}
+	if processed != 1 || store.attempts[11] != 1 || !store.processed[12] {
+		t.Errorf("expected event 12 processed after the failed event 11, got %d, attempts %v, marks %v", processed, store.attempts, store.processed)
+	}
+	if store.cursor != 10 {
+		t.Errorf("expected the cursor to stay before the failed event, got %d", store.cursor)
+	}
+
+	// Сбой устранён: событие повторяется и позиция журнала сдвигается
+	delete(store.failOn, 11)
+	processed, err = svc.ProcessOrderEvents(context.Background(), 100)
+	if err != nil || processed != 1 || store.cursor != 12 {
+		t.Errorf("expected the failed event to be retried, got %d up to %d, %v", processed, store.cursor, err)
+	}
+	expCalls := []string{
+		fmt.Sprintf("consume %s van:8", done.ID),
+		fmt.Sprintf("reserve %s van:7,warehouse:main", broken.ID),
+	}
+	if strings.Join(store.calls, "\n") != strings.Join(expCalls, "\n") {
+		t.Errorf("expected calls:\n%s\ngot:\n%s", strings.Join(expCalls, "\n"), strings.Join(store.calls, "\n"))
+	}
+}
+
+// Хранилище, в котором событие обрабатывает другая реплика сразу после чтения журнала
+type racingStore struct {
+	*stockStore
+	eventID int64
+}
+
+func (r *racingStore) PendingOrderEvents(ctx context.Context, limit int) ([]*orders.Event, error) {
+	events, err := r.stockStore.PendingOrderEvents(ctx, limit)
+	r.stockStore.processed[r.eventID] = true
+	return events, err
+}
+
+func TestSetOrderParts(t *testing.T) {
+	valve := newValve()
+	cases := []struct {
+		name       string
+		status     *orders.Status
+		parts      []inventory.OrderPart
+		expErr     error
+		expReserve bool
+	}{
+		{
+			name:   "Новая заявка: только список, без резерва",
+			status: ptr(orders.StatusNew),
+			parts:  []inventory.OrderPart{{PartID: valve.ID, Quantity: 1}, {PartID: valve.ID, Quantity: 2}},
+		},
+		{
+			name:       "Запланированная заявка перерезервируется сразу",
+			status:     ptr(orders.StatusScheduled),
+			parts:      []inventory.OrderPart{{PartID: valve.ID, Quantity: 3}},
+			expReserve: true,
+		},
+		{
+			name:   "Выполненная заявка",
+			status: ptr(orders.StatusDone),
+			parts:  []inventory.OrderPart{{PartID: valve.ID, Quantity: 3}},
+			expErr: deterrs.NewDetErr(deterrs.OrderActionNotPermittedByStatus),
+		},
+		{
+			name:   "Нулевое количество",
+			status: ptr(orders.StatusNew),
+			parts:  []inventory.OrderPart{{PartID: valve.ID}},
+			expErr: deterrs.NewDetErr(deterrs.InvalidValue),
+		},
+		{
+			name:   "Запчасти нет в каталоге",
+			status: ptr(orders.StatusNew),
+			parts:  []inventory.OrderPart{{PartID: uuid.New(), Quantity: 1}},
+			expErr: inventory.ErrNotFound,
+		},
+		{
+			name:   "Заявки нет",
+			parts:  []inventory.OrderPart{{PartID: valve.ID, Quantity: 1}},
+			expErr: inventory.ErrOrderNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			order := &orders.Order{ID: uuid.New(), Employee: &auth.Employee{ID: 7}}
+			store := newStockStore(valve)
+			if tc.status != nil {
+				order.Status = *tc.status
+				store.statuses[order.ID] = *tc.status
+			}
+			feed := &orderFeed{orders: map[uuid.UUID]*orders.Order{order.ID: order}}
+			svc := inventory.NewInventoryService(store, feed)
+
+			parts, err := svc.SetOrderParts(context.Background(), order.ID, tc.parts)
+			if !errors.Is(err, tc.expErr) {
+				t.Fatalf("expected %v, got %v", tc.expErr, err)
+			}
+			if tc.expErr != nil {
+				return
+			}
+
+			if len(parts) != 1 || parts[0].Quantity != 3 {
+				t.Errorf("expected one merged part of 3, got %+v", parts)
+			}
+			if reserved := len(store.calls) == 1 && strings.HasPrefix(store.calls[0], "reserve"); reserved != tc.expReserve {
+				t.Errorf("expected reserve %v, got calls %v", tc.expReserve, store.calls)
+			}
+		})
+	}
+}
+
+func TestTransfer_Validation(t *testing.T) {
+	valve := newValve()
+	cases := []struct {
+		name     string
+		transfer inventory.Transfer
+		expErr   error
+	}{
+		{name: "Со склада в машину", transfer: inventory.Transfer{PartID: valve.ID, From: inventory.Warehouse("main"), To: inventory.Van(7), Quantity: 2}},
+		{name: "Неизвестный вид места хранения", transfer: inventory.Transfer{PartID: valve.ID, From: "shelf:1", To: inventory.Van(7), Quantity: 2}, expErr: deterrs.NewDetErr(deterrs.InvalidValue)},
+		{name: "Машина без сотрудника", transfer: inventory.Transfer{PartID: valve.ID, From: inventory.Warehouse("main"), To: "van:0", Quantity: 2}, expErr: deterrs.NewDetErr(deterrs.InvalidValue)},
+		{name: "Без места назначения", transfer: inventory.Transfer{PartID: valve.ID, From: inventory.Warehouse("main"), Quantity: 2}, expErr: deterrs.NewDetErr(deterrs.EmptyField)},
+		{name: "В то же место", transfer: inventory.Transfer{PartID: valve.ID, From: inventory.Van(7), To: inventory.Van(7), Quantity: 2}, expErr: deterrs.NewDetErr(deterrs.InvalidValue)},
+		{name: "Нулевое количество", transfer: inventory.Transfer{PartID: valve.ID, From: inventory.Warehouse("main"), To: inventory.Van(7)}, expErr: deterrs.NewDetErr(deterrs.InvalidValue)},
+		{name: "Запчасти нет в каталоге", transfer: inventory.Transfer{PartID: uuid.New(), From: inventory.Warehouse("main"), To: inventory.Van(7), Quantity: 2}, expErr: inventory.ErrNotFound},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newStockStore(valve)
+			svc := inventory.NewInventoryService(store, &orderFeed{})
+
+			err := svc.Transfer(context.Background(), &tc.transfer)
+			if !errors.Is(err, tc.expErr) {
+				t.Fatalf("expected %v, got %v", tc.expErr, err)
+			}
+			if tc.expErr == nil && (len(store.calls) != 1 || store.calls[0] != "transfer warehouse:main van:7 2") {
+				t.Errorf("unexpected calls %v", store.calls)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
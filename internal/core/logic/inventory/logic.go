@@ -0,0 +1,203 @@
+package inventory
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	deterrs "github.com/Owouwun/spkuznetsov/internal/errors"
+	"github.com/google/uuid"
+)
+
+// Склад с кодом code
+func Warehouse(code string) Location {
+	return Location(string(LocationWarehouse) + ":" + code)
+}
+
+// Машина сотрудника
+func Van(employeeID uint) Location {
+	return Location(string(LocationVan) + ":" + strconv.FormatUint(uint64(employeeID), 10))
+}
+
+func (l Location) validate(field string) error {
+	if l == "" {
+		return deterrs.NewDetErr(deterrs.EmptyField, deterrs.WithField(field))
+	}
+	kind, ref, ok := strings.Cut(string(l), ":")
+	valid := ok && ref != ""
+	switch LocationKind(kind) {
+	case LocationWarehouse:
+	case LocationVan:
+		id, err := strconv.ParseUint(ref, 10, 32)
+		valid = valid && err == nil && id > 0
+	default:
+		valid = false
+	}
+	if !valid {
+		return deterrs.NewDetErr(deterrs.InvalidValue, deterrs.WithField(field))
+	}
+	return nil
+}
+
+func validateQuantity(quantity int) error {
+	if quantity <= 0 {
+		return deterrs.NewDetErr(deterrs.InvalidValue, deterrs.WithField("quantity"))
+	}
+	return nil
+}
+
+func (pp *PrimaryPart) CreateNewPart() (*Part, error) {
+	sku := strings.TrimSpace(pp.SKU)
+	name := strings.TrimSpace(pp.Name)
+	if sku == "" {
+		return nil, deterrs.NewDetErr(deterrs.EmptyField, deterrs.WithField("sku"))
+	}
+	if name == "" {
+		return nil, deterrs.NewDetErr(deterrs.EmptyField, deterrs.WithField("name"))
+	}
+	if pp.Price < 0 {
+		return nil, deterrs.NewDetErr(deterrs.InvalidValue, deterrs.WithField("price"))
+	}
+	if pp.LowStockThreshold < 0 {
+		return nil, deterrs.NewDetErr(deterrs.InvalidValue, deterrs.WithField("low stock threshold"))
+	}
+
+	return &Part{
+		ID:                uuid.New(),
+		SKU:               sku,
+		Name:              name,
+		Price:             pp.Price,
+		LowStockThreshold: pp.LowStockThreshold,
+	}, nil
+}
+
+func (r *Receipt) validate() error {
+	if err := r.Location.validate("location"); err != nil {
+		return err
+	}
+	return validateQuantity(r.Quantity)
+}
+
+func (t *Transfer) validate() error {
+	if err := t.From.validate("from"); err != nil {
+		return err
+	}
+	if err := t.To.validate("to"); err != nil {
+		return err
+	}
+	if t.From == t.To {
+		return deterrs.NewDetErr(deterrs.InvalidValue, deterrs.WithField("to"))
+	}
+	return validateQuantity(t.Quantity)
+}
+
+func (r *Return) validate() error {
+	if err := r.Location.validate("location"); err != nil {
+		return err
+	}
+	return validateQuantity(r.Quantity)
+}
+
+// Проверить запчасти для заявки и объединить повторяющиеся позиции
+func normalizeOrderParts(parts []OrderPart) ([]OrderPart, error) {
+	merged := make([]OrderPart, 0, len(parts))
+	index := make(map[uuid.UUID]int, len(parts))
+	for _, p := range parts {
+		if p.PartID == uuid.Nil {
+			return nil, deterrs.NewDetErr(deterrs.EmptyField, deterrs.WithField("part id"))
+		}
+		if err := validateQuantity(p.Quantity); err != nil {
+			return nil, err
+		}
+		if i, ok := index[p.PartID]; ok {
+			merged[i].Quantity += p.Quantity
+			continue
+		}
+		index[p.PartID] = len(merged)
+		merged = append(merged, OrderPart{PartID: p.PartID, Quantity: p.Quantity})
+	}
+	return merged, nil
+}
+
+// Откуда резервируются запчасти для заявки: сначала из машины ответственного
+// сотрудника, недостающее — со склада
+func reservationSources(order *orders.Order, warehouse Location) []Location {
+	if order.Employee == nil {
+		return []Location{warehouse}
+	}
+	return []Location{Van(order.Employee.ID), warehouse}
+}
+
+// Откуда списываются запчасти, использованные сверх резерва
+func consumptionSource(order *orders.Order, warehouse Location) Location {
+	if order.Employee == nil {
+		return warehouse
+	}
+	return Van(order.Employee.ID)
+}
+
+// Списания по отчётам о визитах. Запчасти сопоставляются с каталогом по артикулу;
+// запчасти без артикула или не из каталога не списываются и возвращаются отдельно
+func usages(visits []*orders.Visit, bySKU map[string]*Part) (used []Usage, unknown []orders.UsedPart) {
+	index := make(map[uuid.UUID]int)
+	for _, v := range visits {
+		for _, up := range v.PartsUsed {
+			part, ok := bySKU[strings.TrimSpace(up.SKU)]
+			if !ok {
+				unknown = append(unknown, up)
+				continue
+			}
+			if i, ok := index[part.ID]; ok {
+				used[i].Quantity += up.Quantity
+				continue
+			}
+			index[part.ID] = len(used)
+			used = append(used, Usage{PartID: part.ID, Quantity: up.Quantity})
+		}
+	}
+	return used, unknown
+}
+
+// Артикулы запчастей из отчётов о визитах
+func usedSKUs(visits []*orders.Visit) []string {
+	seen := make(map[string]bool)
+	var skus []string
+	for _, v := range visits {
+		for _, up := range v.PartsUsed {
+			sku := strings.TrimSpace(up.SKU)
+			if sku == "" || seen[sku] {
+				continue
+			}
+			seen[sku] = true
+			skus = append(skus, sku)
+		}
+	}
+	return skus
+}
+
+// Оповещение о низком остатке отправляется один раз, когда доступный остаток
+// опускается до порога; следующее — после пополнения выше порога
+func (c LevelChange) lowStock(threshold int) bool {
+	return threshold > 0 && c.Before > threshold && c.After <= threshold
+}
+
+func NewLowStockEvent(part *Part, available int) *Event {
+	return &Event{
+		Type:      EventLowStock,
+		PartID:    part.ID,
+		SKU:       part.SKU,
+		Available: available,
+		Threshold: part.LowStockThreshold,
+	}
+}
+
+func NewLineItem(part *Part, quantity int) LineItem {
+	return LineItem{
+		PartID:    part.ID,
+		SKU:       part.SKU,
+		Name:      part.Name,
+		Quantity:  quantity,
+		UnitPrice: part.Price,
+		Total:     part.Price * int64(quantity),
+	}
+}
@@ -0,0 +1,147 @@
+package inventory
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Место хранения запчастей: склад ("warehouse:<код>") или машина сотрудника ("van:<ID сотрудника>")
+type Location string
+
+type LocationKind string
+
+const (
+	LocationWarehouse LocationKind = "warehouse"
+	LocationVan       LocationKind = "van"
+)
+
+// Позиция каталога запчастей для создания
+type PrimaryPart struct {
+	SKU  string `json:"sku"`
+	Name string `json:"name"`
+	// Цена за единицу в копейках
+	Price int64 `json:"price"`
+	// Порог общего доступного остатка, при снижении до которого отправляется оповещение; 0 — без оповещений
+	LowStockThreshold int `json:"low_stock_threshold"`
+}
+
+type Part struct {
+	ID                uuid.UUID `json:"id"`
+	SKU               string    `json:"sku"`
+	Name              string    `json:"name"`
+	Price             int64     `json:"price"`
+	LowStockThreshold int       `json:"low_stock_threshold"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// Остаток запчасти в месте хранения. Зарезервированное под заявки входит в OnHand,
+// но не в Available
+type Stock struct {
+	PartID    uuid.UUID `json:"part_id"`
+	SKU       string    `json:"sku"`
+	Name      string    `json:"name"`
+	Location  Location  `json:"location"`
+	OnHand    int       `json:"on_hand"`
+	Reserved  int       `json:"reserved"`
+	Available int       `json:"available"`
+}
+
+type StockFilter struct {
+	PartID   *uuid.UUID
+	Location Location
+}
+
+type MovementKind string
+
+const (
+	MovementReceipt     MovementKind = "receipt"
+	MovementTransferOut MovementKind = "transfer_out"
+	MovementTransferIn  MovementKind = "transfer_in"
+	MovementConsumption MovementKind = "consumption"
+	MovementReturn      MovementKind = "return"
+)
+
+// Поступление запчастей на склад или в машину
+type Receipt struct {
+	PartID   uuid.UUID `json:"part_id"`
+	Location Location  `json:"location"`
+	Quantity int       `json:"quantity"`
+}
+
+// Перемещение запчастей, например со склада в машину сотрудника
+type Transfer struct {
+	PartID   uuid.UUID `json:"part_id"`
+	From     Location  `json:"from"`
+	To       Location  `json:"to"`
+	Quantity int       `json:"quantity"`
+}
+
+// Запчасть, которая понадобится для заявки. Резервируется, когда заявка запланирована
+type OrderPart struct {
+	PartID   uuid.UUID `json:"part_id"`
+	Quantity int       `json:"quantity"`
+	// Сколько зарезервировано; меньше Quantity, если запчастей не хватило
+	Reserved int `json:"reserved"`
+}
+
+// Списание запчасти по заявке
+type Usage struct {
+	PartID   uuid.UUID
+	Quantity int
+}
+
+// Возврат неиспользованной или снятой запчасти по заявке
+type Return struct {
+	PartID   uuid.UUID `json:"part_id"`
+	Location Location  `json:"location"`
+	Quantity int       `json:"quantity"`
+}
+
+// Позиция заявки: запчасть, списанная при её выполнении, по цене на момент списания
+type LineItem struct {
+	PartID    uuid.UUID `json:"part_id"`
+	SKU       string    `json:"sku"`
+	Name      string    `json:"name"`
+	Quantity  int       `json:"quantity"`
+	UnitPrice int64     `json:"unit_price"`
+	Total     int64     `json:"total"`
+}
+
+// Изменение общего доступного остатка запчасти одним действием
+type LevelChange struct {
+	PartID uuid.UUID
+	Before int
+	After  int
+}
+
+type EventType string
+
+const (
+	// Общий доступный остаток запчасти опустился до порога
+	EventLowStock EventType = "low_stock"
+)
+
+// Событие склада. ID монотонно растёт, как у событий заявок
+type Event struct {
+	ID        int64     `json:"id"`
+	Type      EventType `json:"type"`
+	PartID    uuid.UUID `json:"part_id"`
+	SKU       string    `json:"sku"`
+	Available int       `json:"available"`
+	Threshold int       `json:"threshold"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+var (
+	ErrNotFound = errors.New("part not found")
+	// Заявки нет или она удалена
+	ErrOrderNotFound = errors.New("order not found")
+	// Запчасть с таким артикулом уже есть в каталоге
+	ErrDuplicateSKU = errors.New("part with this sku already exists")
+	// В месте хранения недостаточно доступных запчастей, или возвращается больше, чем списано
+	ErrInsufficientStock = errors.New("insufficient stock")
+	// Событие заявки уже обработано, например другой репликой
+	ErrEventProcessed = errors.New("order event is already processed by inventory")
+)
@@ -0,0 +1,522 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	deterrs "github.com/Owouwun/spkuznetsov/internal/errors"
+	"github.com/Owouwun/spkuznetsov/internal/logging"
+	"github.com/Owouwun/spkuznetsov/internal/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Действия, меняющие остатки, выполняются в одной транзакции с записью движений
+// и возвращают изменение общего доступного остатка затронутых запчастей.
+// Действие по событию заявки eventID сохраняется вместе с отметкой, что событие
+// обработано; ErrEventProcessed, если отметка уже есть. 0 — действие не по событию
+type InventoryRepository interface {
+	// ErrDuplicateSKU, если артикул занят
+	CreatePart(ctx context.Context, part *Part) error
+	// ErrNotFound, если запчасти нет
+	GetPart(ctx context.Context, id uuid.UUID) (*Part, error)
+	GetParts(ctx context.Context) ([]*Part, error)
+	GetPartsBySKU(ctx context.Context, skus []string) ([]*Part, error)
+	GetStock(ctx context.Context, filter StockFilter) ([]*Stock, error)
+	Receive(ctx context.Context, r *Receipt) ([]LevelChange, error)
+	// ErrInsufficientStock, если в From не хватает доступных запчастей
+	Transfer(ctx context.Context, t *Transfer) error
+	// Статус действующей или архивной заявки; ErrOrderNotFound, если заявки нет или она удалена
+	OrderStatus(ctx context.Context, orderID uuid.UUID) (orders.Status, error)
+	// Заменяет список запчастей для заявки; резерв не меняется
+	SetOrderParts(ctx context.Context, orderID uuid.UUID, parts []OrderPart) error
+	GetOrderParts(ctx context.Context, orderID uuid.UUID) ([]*OrderPart, error)
+	// Снимает прежний резерв заявки и резервирует её запчасти в sources по порядку, сколько
+	// есть доступных. Если по заявке уже были списания, ничего не меняет
+	Reserve(ctx context.Context, orderID uuid.UUID, sources []Location, eventID int64) ([]LevelChange, error)
+	// Снимает резерв заявки
+	Release(ctx context.Context, orderID uuid.UUID, eventID int64) ([]LevelChange, error)
+	// Списывает запчасти сначала из резерва заявки, сверх него — из source, даже если их там
+	// не числится; снимает остаток резерва и добавляет позиции заявки. Если по заявке уже были
+	// списания, ничего не меняет
+	Consume(ctx context.Context, orderID uuid.UUID, source Location, used []Usage, eventID int64) ([]LevelChange, error)
+	// Возвращает запчасти в место хранения и уменьшает позицию заявки;
+	// ErrInsufficientStock, если возвращается больше, чем списано
+	Return(ctx context.Context, orderID uuid.UUID, r *Return) ([]LevelChange, error)
+	GetLineItems(ctx context.Context, orderID uuid.UUID) ([]*LineItem, error)
+	// Не более limit необработанных событий заявок в порядке ID; события с неудачными
+	// попытками — после остальных, начиная с самой давней попытки
+	PendingOrderEvents(ctx context.Context, limit int) ([]*orders.Event, error)
+	// Отмечает неудачную попытку применить событие: оно остаётся необработанным,
+	// но не задерживает очередь остальных
+	EventFailed(ctx context.Context, eventID int64, cause error) error
+	// Отмечает обработанными события, не меняющие склад
+	MarkProcessed(ctx context.Context, eventIDs []int64) error
+	// Сдвигает позицию журнала, до которой все события обработаны, по событиям старше
+	// settle и удаляет отметки до неё. События моложе settle могут быть ещё не видны:
+	// транзакция, записавшая событие с меньшим ID, бывает зафиксирована позже
+	CompactProcessed(ctx context.Context, settle time.Duration) error
+	AppendEvent(ctx context.Context, ev *Event) (int64, error)
+	EventsSince(ctx context.Context, afterID int64, limit int) ([]*Event, error)
+}
+
+// Заявки, по изменениям которых резервируются и списываются запчасти
+type OrderService interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*orders.Order, error)
+	GetVisits(ctx context.Context, id uuid.UUID) ([]*orders.Visit, error)
+}
+
+type InventoryService struct {
+	repo   InventoryRepository
+	orders OrderService
+	// Склад, с которого резервируется недостающее в машине сотрудника
+	warehouse Location
+}
+
+type InventoryServiceOption func(*InventoryService)
+
+// WithWarehouse задаёт код основного склада
+func WithWarehouse(code string) InventoryServiceOption {
+	return func(s *InventoryService) {
+		s.warehouse = Warehouse(code)
+	}
+}
+
+func NewInventoryService(repo InventoryRepository, orderService OrderService, opts ...InventoryServiceOption) *InventoryService {
+	s := &InventoryService{
+		repo:      repo,
+		orders:    orderService,
+		warehouse: Warehouse("main"),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func startSpan(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "InventoryService."+method, trace.WithAttributes(attrs...))
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrOrderNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func partIDAttr(id uuid.UUID) attribute.KeyValue {
+	return attribute.String("part.id", id.String())
+}
+
+func orderIDAttr(id uuid.UUID) attribute.KeyValue {
+	return attribute.String("order.id", id.String())
+}
+
+func (s *InventoryService) CreatePart(ctx context.Context, pp *PrimaryPart) (_ *Part, err error) {
+	ctx, span := startSpan(ctx, "CreatePart")
+	defer func() { endSpan(span, err) }()
+
+	part, err := pp.CreateNewPart()
+	if err != nil {
+		slog.WarnContext(ctx, "invalid new part", slog.Any("error", err))
+		return nil, err
+	}
+	span.SetAttributes(partIDAttr(part.ID))
+
+	ctx = logging.WithAttrs(ctx, slog.String("part_id", part.ID.String()))
+	if err := s.repo.CreatePart(ctx, part); err != nil {
+		if !errors.Is(err, ErrDuplicateSKU) {
+			slog.ErrorContext(ctx, "failed to create part", slog.Any("error", err))
+		}
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "part created", slog.String("sku", part.SKU))
+	return part, nil
+}
+
+func (s *InventoryService) GetPart(ctx context.Context, id uuid.UUID) (_ *Part, err error) {
+	ctx, span := startSpan(ctx, "GetPart", partIDAttr(id))
+	defer func() { endSpan(span, err) }()
+
+	return s.repo.GetPart(ctx, id)
+}
+
+func (s *InventoryService) GetParts(ctx context.Context) (_ []*Part, err error) {
+	ctx, span := startSpan(ctx, "GetParts")
+	defer func() { endSpan(span, err) }()
+
+	return s.repo.GetParts(ctx)
+}
+
+func (s *InventoryService) GetStock(ctx context.Context, filter StockFilter) (_ []*Stock, err error) {
+	ctx, span := startSpan(ctx, "GetStock")
+	defer func() { endSpan(span, err) }()
+
+	if filter.Location != "" {
+		if err := filter.Location.validate("location"); err != nil {
+			return nil, err
+		}
+	}
+	return s.repo.GetStock(ctx, filter)
+}
+
+func (s *InventoryService) Receive(ctx context.Context, r *Receipt) (err error) {
+	ctx, span := startSpan(ctx, "Receive", partIDAttr(r.PartID))
+	defer func() { endSpan(span, err) }()
+
+	if err := r.validate(); err != nil {
+		return err
+	}
+	if _, err := s.repo.GetPart(ctx, r.PartID); err != nil {
+		return err
+	}
+
+	ctx = logging.WithAttrs(ctx, slog.String("part_id", r.PartID.String()))
+	changes, err := s.repo.Receive(ctx, r)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to receive parts", slog.Any("error", err))
+		return err
+	}
+
+	slog.InfoContext(ctx, "parts received", slog.String("location", string(r.Location)), slog.Int("quantity", r.Quantity))
+	s.notify(ctx, changes)
+	return nil
+}
+
+func (s *InventoryService) Transfer(ctx context.Context, t *Transfer) (err error) {
+	ctx, span := startSpan(ctx, "Transfer", partIDAttr(t.PartID))
+	defer func() { endSpan(span, err) }()
+
+	if err := t.validate(); err != nil {
+		return err
+	}
+	if _, err := s.repo.GetPart(ctx, t.PartID); err != nil {
+		return err
+	}
+
+	ctx = logging.WithAttrs(ctx, slog.String("part_id", t.PartID.String()))
+	if err := s.repo.Transfer(ctx, t); err != nil {
+		slog.WarnContext(ctx, "failed to transfer parts", slog.Any("error", err))
+		return err
+	}
+
+	slog.InfoContext(ctx, "parts transferred", slog.String("from", string(t.From)), slog.String("to", string(t.To)), slog.Int("quantity", t.Quantity))
+	return nil
+}
+
+// Задать запчасти для заявки. Если работы по заявке уже запланированы, запчасти сразу
+// перерезервируются; после выполнения заявки список не меняется
+func (s *InventoryService) SetOrderParts(ctx context.Context, orderID uuid.UUID, parts []OrderPart) (_ []*OrderPart, err error) {
+	ctx, span := startSpan(ctx, "SetOrderParts", orderIDAttr(orderID))
+	defer func() { endSpan(span, err) }()
+	ctx = logging.WithAttrs(ctx, slog.String("order_id", orderID.String()))
+
+	parts, err = normalizeOrderParts(parts)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range parts {
+		if _, err := s.repo.GetPart(ctx, p.PartID); err != nil {
+			return nil, err
+		}
+	}
+
+	status, err := s.repo.OrderStatus(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	switch status {
+	case orders.StatusDone, orders.StatusPaid, orders.StatusCanceled:
+		return nil, deterrs.NewDetErr(deterrs.OrderActionNotPermittedByStatus)
+	}
+
+	if err := s.repo.SetOrderParts(ctx, orderID, parts); err != nil {
+		slog.ErrorContext(ctx, "failed to set order parts", slog.Any("error", err))
+		return nil, err
+	}
+	slog.InfoContext(ctx, "order parts set", slog.Int("parts", len(parts)))
+
+	if status == orders.StatusScheduled || status == orders.StatusInProgress {
+		order, err := s.orders.GetByID(ctx, orderID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.reserve(ctx, order, 0); err != nil {
+			return nil, err
+		}
+	}
+	return s.repo.GetOrderParts(ctx, orderID)
+}
+
+func (s *InventoryService) GetOrderParts(ctx context.Context, orderID uuid.UUID) (_ []*OrderPart, err error) {
+	ctx, span := startSpan(ctx, "GetOrderParts", orderIDAttr(orderID))
+	defer func() { endSpan(span, err) }()
+
+	if _, err := s.repo.OrderStatus(ctx, orderID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetOrderParts(ctx, orderID)
+}
+
+func (s *InventoryService) GetLineItems(ctx context.Context, orderID uuid.UUID) (_ []*LineItem, err error) {
+	ctx, span := startSpan(ctx, "GetLineItems", orderIDAttr(orderID))
+	defer func() { endSpan(span, err) }()
+
+	if _, err := s.repo.OrderStatus(ctx, orderID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetLineItems(ctx, orderID)
+}
+
+// Вернуть в место хранения запчасти, списанные по заявке
+func (s *InventoryService) ReturnParts(ctx context.Context, orderID uuid.UUID, r *Return) (err error) {
+	ctx, span := startSpan(ctx, "ReturnParts", orderIDAttr(orderID), partIDAttr(r.PartID))
+	defer func() { endSpan(span, err) }()
+	ctx = logging.WithAttrs(ctx, slog.String("order_id", orderID.String()), slog.String("part_id", r.PartID.String()))
+
+	if err := r.validate(); err != nil {
+		return err
+	}
+	if _, err := s.repo.OrderStatus(ctx, orderID); err != nil {
+		return err
+	}
+
+	changes, err := s.repo.Return(ctx, orderID, r)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to return parts", slog.Any("error", err))
+		return err
+	}
+
+	slog.InfoContext(ctx, "parts returned", slog.String("location", string(r.Location)), slog.Int("quantity", r.Quantity))
+	s.notify(ctx, changes)
+	return nil
+}
+
+func (s *InventoryService) EventsSince(ctx context.Context, afterID int64, limit int) (_ []*Event, err error) {
+	ctx, span := startSpan(ctx, "EventsSince", attribute.Int64("event.after_id", afterID))
+	defer func() { endSpan(span, err) }()
+
+	return s.repo.EventsSince(ctx, afterID, limit)
+}
+
+// Время, за которое событие заявки наверняка становится видно: транзакции, записывающие
+// события, короче. Только после него позиция журнала сдвигается за событие
+const eventSettleTime = time.Hour
+
+// Обработать не более batchSize новых событий заявок: запланированные заявки резервируют
+// запчасти, выполненные — списывают их, отменённые и удалённые — снимают резерв.
+// Изменения склада сохраняются вместе с отметкой события, поэтому каждое событие
+// применяется ровно один раз, даже если обработчик запущен на нескольких репликах,
+// а событие, зафиксированное позже событий с большим ID, не пропускается. Событие,
+// которое не удалось применить, повторяется после остальных; ошибки по таким событиям
+// возвращаются вместе после обработки всей порции.
+// Возвращает число обработанных событий
+func (s *InventoryService) ProcessOrderEvents(ctx context.Context, batchSize int) (done int, err error) {
+	ctx, span := startSpan(ctx, "ProcessOrderEvents")
+	defer func() {
+		span.SetAttributes(attribute.Int("event.count", done))
+		endSpan(span, err)
+	}()
+
+	events, err := s.repo.PendingOrderEvents(ctx, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var skipped []int64
+	var failures []error
+	for _, ev := range events {
+		var applied bool
+		applied, err = s.apply(ctx, ev)
+		if errors.Is(err, ErrEventProcessed) {
+			slog.DebugContext(ctx, "order event is processed by another replica", slog.Int64("event_id", ev.ID))
+			err = nil
+			continue
+		}
+		if err != nil {
+			// Событие повторится после остальных: одно сбойное событие не останавливает склад
+			slog.ErrorContext(ctx, "failed to apply order event", slog.Int64("event_id", ev.ID), slog.Any("error", err))
+			failures = append(failures, fmt.Errorf("order event %d: %w", ev.ID, err))
+			if err = s.repo.EventFailed(ctx, ev.ID, err); err != nil {
+				break
+			}
+			continue
+		}
+		if !applied {
+			skipped = append(skipped, ev.ID)
+			continue
+		}
+		done++
+	}
+
+	// События, не меняющие склад, отмечаются одним запросом, даже если порция прервана ошибкой
+	if len(skipped) > 0 {
+		if markErr := s.repo.MarkProcessed(ctx, skipped); markErr != nil {
+			return done, errors.Join(err, markErr)
+		}
+		done += len(skipped)
+	}
+	if err != nil {
+		return done, errors.Join(append(failures, err)...)
+	}
+
+	if err := s.repo.CompactProcessed(ctx, eventSettleTime); err != nil {
+		slog.ErrorContext(ctx, "failed to compact processed order events", slog.Any("error", err))
+		return done, err
+	}
+
+	if done > 0 {
+		slog.DebugContext(ctx, "order events processed by inventory", slog.Int("events", done))
+	}
+	return done, errors.Join(failures...)
+}
+
+// Применить событие заявки к складу; false, если событие склад не меняет
+func (s *InventoryService) apply(ctx context.Context, ev *orders.Event) (bool, error) {
+	ctx = logging.WithAttrs(ctx, slog.String("order_id", ev.OrderID.String()), slog.Int64("event_id", ev.ID))
+
+	switch {
+	case ev.Type == orders.EventScheduled,
+		// Сотрудника заменили после планирования: резерв переносится в его машину
+		ev.Type == orders.EventAssigned && ev.Status == orders.StatusScheduled:
+		order, err := s.loadOrder(ctx, ev.OrderID)
+		if order == nil {
+			return false, err
+		}
+		return true, s.reserve(ctx, order, ev.ID)
+
+	case ev.Type == orders.EventCompleted:
+		order, err := s.loadOrder(ctx, ev.OrderID)
+		if order == nil {
+			return false, err
+		}
+		return true, s.consume(ctx, order, ev.ID)
+
+	case ev.Type == orders.EventCanceled, ev.Type == orders.EventDeleted:
+		changes, err := s.repo.Release(ctx, ev.OrderID, ev.ID)
+		if err != nil {
+			return false, err
+		}
+		slog.DebugContext(ctx, "order parts reservation released")
+		s.notify(ctx, changes)
+		return true, nil
+	}
+	return false, nil
+}
+
+// Заявка события; nil без ошибки, если её уже удалили: резерв снимет событие удаления
+func (s *InventoryService) loadOrder(ctx context.Context, id uuid.UUID) (*orders.Order, error) {
+	if _, err := s.repo.OrderStatus(ctx, id); err != nil {
+		if errors.Is(err, ErrOrderNotFound) {
+			slog.WarnContext(ctx, "order event skipped by inventory: order was deleted")
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	order, err := s.orders.GetByID(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to load order for inventory", slog.Any("error", err))
+		return nil, err
+	}
+	return order, nil
+}
+
+func (s *InventoryService) reserve(ctx context.Context, order *orders.Order, eventID int64) error {
+	changes, err := s.repo.Reserve(ctx, order.ID, reservationSources(order, s.warehouse), eventID)
+	if err != nil {
+		if !errors.Is(err, ErrEventProcessed) {
+			slog.ErrorContext(ctx, "failed to reserve order parts", slog.Any("error", err))
+		}
+		return err
+	}
+
+	parts, err := s.repo.GetOrderParts(ctx, order.ID)
+	if err != nil {
+		return err
+	}
+	for _, p := range parts {
+		if p.Reserved < p.Quantity {
+			slog.WarnContext(ctx, "not enough parts to reserve for order",
+				slog.String("part_id", p.PartID.String()), slog.Int("needed", p.Quantity), slog.Int("reserved", p.Reserved))
+		}
+	}
+
+	slog.DebugContext(ctx, "order parts reserved", slog.Int("parts", len(parts)))
+	s.notify(ctx, changes)
+	return nil
+}
+
+func (s *InventoryService) consume(ctx context.Context, order *orders.Order, eventID int64) error {
+	visits, err := s.orders.GetVisits(ctx, order.ID)
+	if err != nil {
+		return err
+	}
+
+	var bySKU map[string]*Part
+	if skus := usedSKUs(visits); len(skus) > 0 {
+		parts, err := s.repo.GetPartsBySKU(ctx, skus)
+		if err != nil {
+			return err
+		}
+		bySKU = make(map[string]*Part, len(parts))
+		for _, p := range parts {
+			bySKU[p.SKU] = p
+		}
+	}
+
+	used, unknown := usages(visits, bySKU)
+	for _, up := range unknown {
+		slog.WarnContext(ctx, "used part is not in the catalog and is not written off",
+			slog.String("sku", up.SKU), slog.String("name", up.Name), slog.Int("quantity", up.Quantity))
+	}
+
+	changes, err := s.repo.Consume(ctx, order.ID, consumptionSource(order, s.warehouse), used, eventID)
+	if err != nil {
+		if !errors.Is(err, ErrEventProcessed) {
+			slog.ErrorContext(ctx, "failed to consume order parts", slog.Any("error", err))
+		}
+		return err
+	}
+
+	slog.InfoContext(ctx, "order parts consumed", slog.Int("parts", len(used)))
+	s.notify(ctx, changes)
+	return nil
+}
+
+// Записать события о низком остатке запчастей, остаток которых опустился до порога.
+// Изменение остатков уже сохранено, поэтому ошибки только пишутся в лог
+func (s *InventoryService) notify(ctx context.Context, changes []LevelChange) {
+	for _, c := range changes {
+		if c.After >= c.Before {
+			continue
+		}
+		part, err := s.repo.GetPart(ctx, c.PartID)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to load part for low stock check", slog.String("part_id", c.PartID.String()), slog.Any("error", err))
+			continue
+		}
+		if !c.lowStock(part.LowStockThreshold) {
+			continue
+		}
+
+		ev := NewLowStockEvent(part, c.After)
+		if ev.ID, err = s.repo.AppendEvent(ctx, ev); err != nil {
+			slog.ErrorContext(ctx, "failed to record low stock event", slog.String("part_id", part.ID.String()), slog.Any("error", err))
+			continue
+		}
+		slog.WarnContext(ctx, "part stock is low",
+			slog.String("part_id", part.ID.String()), slog.String("sku", part.SKU),
+			slog.Int("available", c.After), slog.Int("threshold", part.LowStockThreshold), slog.Int64("event_id", ev.ID))
+	}
+}
@@ -0,0 +1,196 @@
+package orders
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type EventType string
+
+const (
+	EventCreated      EventType = "created"
+	EventReleased     EventType = "released"
+	EventPrescheduled EventType = "prescheduled"
+	EventAssigned     EventType = "assigned"
+	EventScheduled    EventType = "scheduled"
+	EventProgressed   EventType = "progressed"
+	EventCheckedIn    EventType = "checked_in"
+	EventCheckedOut   EventType = "checked_out"
+	EventCompleted    EventType = "completed"
+	EventClosed       EventType = "closed"
+	EventCanceled     EventType = "canceled"
+	EventDeleted      EventType = "deleted"
+	// Заявка не покинула статус в срок, заданный правилом SLA
+	EventSLABreached EventType = "sla_breached"
+)
+
+// Событие изменения заявки. ID растёт и используется для возобновления потока, но выдаётся
+// при записи события, а не при фиксации транзакции: событие с меньшим ID может появиться
+// в журнале позже события с большим
+type Event struct {
+	ID         int64     `json:"id"`
+	Type       EventType `json:"type"`
+	OrderID    uuid.UUID `json:"order_id"`
+	Status     Status    `json:"status"`
+	EmployeeID *uint     `json:"employee_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func NewEvent(evType EventType, ord *Order) *Event {
+	ev := &Event{
+		Type:    evType,
+		OrderID: ord.ID,
+		Status:  ord.Status,
+	}
+	if ord.Employee != nil {
+		empID := ord.Employee.ID
+		ev.EmployeeID = &empID
+	}
+	return ev
+}
+
+// Фильтр событий по статусу заявки и/или ответственному сотруднику
+type EventFilter struct {
+	Statuses   []Status
+	EmployeeID *uint
+}
+
+func (f *EventFilter) Match(ev *Event) bool {
+	if len(f.Statuses) > 0 && !ev.Status.isValid(&f.Statuses) {
+		return false
+	}
+	if f.EmployeeID != nil {
+		if ev.EmployeeID == nil || *ev.EmployeeID != *f.EmployeeID {
+			return false
+		}
+	}
+	return true
+}
+
+// Сколько последних ID журнала перечитывается при возобновлении потока, чтобы не пропустить
+// события, зафиксированные позже событий с большим ID. Событие пишется в одной короткой
+// транзакции с изменением заявки, поэтому такие события близки по ID
+const EventsReplayWindow = 100
+
+// Недавно доставленные события. Отсекать события по наибольшему доставленному ID нельзя:
+// событие с меньшим ID может прийти позже. Помнятся ID в окне EventsReplayWindow от наибольшего
+type SeenEvents struct {
+	ids  map[int64]struct{}
+	last int64
+}
+
+func NewSeenEvents() *SeenEvents {
+	return &SeenEvents{ids: make(map[int64]struct{})}
+}
+
+// Add отмечает событие доставленным; false, если оно уже было доставлено.
+// Событие старше окна считается новым: повтор лучше пропуска
+func (s *SeenEvents) Add(id int64) bool {
+	if _, ok := s.ids[id]; ok {
+		return false
+	}
+	s.ids[id] = struct{}{}
+
+	if id > s.last {
+		s.last = id
+		if len(s.ids) > 2*EventsReplayWindow {
+			for seen := range s.ids {
+				if seen <= s.last-EventsReplayWindow {
+					delete(s.ids, seen)
+				}
+			}
+		}
+	}
+	return true
+}
+
+// ReplayFrom — ID, после которого нужно перечитать журнал, чтобы получить пропущенные события
+func (s *SeenEvents) ReplayFrom() int64 {
+	return ReplayFrom(s.last)
+}
+
+// ReplayFrom — ID, после которого нужно перечитать журнал клиенту, получившему событие lastID
+func ReplayFrom(lastID int64) int64 {
+	return max(lastID-EventsReplayWindow, 0)
+}
+
+const subscriberBufferSize = 64
+
+type subscription struct {
+	ch     chan *Event
+	filter EventFilter
+}
+
+// Broker раздаёт события всем подписчикам текущего экземпляра сервиса.
+// Подписчик, не успевающий вычитывать события, отключается: его канал закрывается,
+// и клиент должен переподключиться с Last-Event-ID.
+type Broker struct {
+	mu     sync.Mutex
+	subs   map[*subscription]struct{}
+	closed bool
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		subs: make(map[*subscription]struct{}),
+	}
+}
+
+func (b *Broker) Subscribe(filter EventFilter) (<-chan *Event, func()) {
+	sub := &subscription{
+		ch:     make(chan *Event, subscriberBufferSize),
+		filter: filter,
+	}
+
+	b.mu.Lock()
+	if b.closed {
+		close(sub.ch)
+	} else {
+		b.subs[sub] = struct{}{}
+	}
+	b.mu.Unlock()
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.remove(sub)
+	}
+	return sub.ch, unsubscribe
+}
+
+func (b *Broker) Publish(ev *Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		if !sub.filter.Match(ev) {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			b.remove(sub)
+		}
+	}
+}
+
+// Close отключает всех подписчиков и запрещает новые подписки (при остановке сервера)
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subs {
+		b.remove(sub)
+	}
+}
+
+func (b *Broker) remove(sub *subscription) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+	delete(b.subs, sub)
+	close(sub.ch)
+}
@@ -0,0 +1,93 @@
+package orders_test
+
+import (
+	"testing"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+)
+
+func TestEventFilter(t *testing.T) {
+	empID := uint(1)
+	otherEmpID := uint(2)
+
+	cases := []struct {
+		name     string
+		filter   orders.EventFilter
+		event    *orders.Event
+		expMatch bool
+	}{
+		{
+			name:     "Пустой фильтр пропускает всё",
+			filter:   orders.EventFilter{},
+			event:    &orders.Event{Status: orders.StatusNew},
+			expMatch: true,
+		},
+		{
+			name:     "Совпадение по статусу",
+			filter:   orders.EventFilter{Statuses: []orders.Status{orders.StatusScheduled, orders.StatusInProgress}},
+			event:    &orders.Event{Status: orders.StatusInProgress},
+			expMatch: true,
+		},
+		{
+			name:     "Несовпадение по статусу",
+			filter:   orders.EventFilter{Statuses: []orders.Status{orders.StatusScheduled}},
+			event:    &orders.Event{Status: orders.StatusNew},
+			expMatch: false,
+		},
+		{
+			name:     "Совпадение по сотруднику",
+			filter:   orders.EventFilter{EmployeeID: &empID},
+			event:    &orders.Event{Status: orders.StatusAssigned, EmployeeID: &empID},
+			expMatch: true,
+		},
+		{
+			name:     "Другой сотрудник",
+			filter:   orders.EventFilter{EmployeeID: &empID},
+			event:    &orders.Event{Status: orders.StatusAssigned, EmployeeID: &otherEmpID},
+			expMatch: false,
+		},
+		{
+			name:     "Сотрудник не назначен",
+			filter:   orders.EventFilter{EmployeeID: &empID},
+			event:    &orders.Event{Status: orders.StatusNew},
+			expMatch: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.filter.Match(c.event); got != c.expMatch {
+				t.Errorf("expected match '%v', got '%v'", c.expMatch, got)
+			}
+		})
+	}
+}
+
+func TestBroker(t *testing.T) {
+	broker := orders.NewBroker()
+
+	all, unsubscribeAll := broker.Subscribe(orders.EventFilter{})
+	defer unsubscribeAll()
+	done, unsubscribeDone := broker.Subscribe(orders.EventFilter{Statuses: []orders.Status{orders.StatusDone}})
+	defer unsubscribeDone()
+
+	broker.Publish(&orders.Event{ID: 1, Status: orders.StatusNew})
+	broker.Publish(&orders.Event{ID: 2, Status: orders.StatusDone})
+
+	if ev := <-all; ev.ID != 1 {
+		t.Errorf("expected event 1, got %d", ev.ID)
+	}
+	if ev := <-all; ev.ID != 2 {
+		t.Errorf("expected event 2, got %d", ev.ID)
+	}
+	if ev := <-done; ev.ID != 2 {
+		t.Errorf("expected event 2, got %d", ev.ID)
+	}
+
+	// Переполнение буфера отключает подписчика
+	for i := 0; i < 1000; i++ {
+		broker.Publish(&orders.Event{ID: int64(3 + i), Status: orders.StatusNew})
+	}
+	for range all {
+	}
+}
@@ -1,11 +1,20 @@
 package orders
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Owouwun/spkuznetsov/internal/core/logic/auth"
 	deterrs "github.com/Owouwun/spkuznetsov/internal/errors"
+	"github.com/Owouwun/spkuznetsov/pkg/calendar"
+	"github.com/Owouwun/spkuznetsov/pkg/routing"
 	"github.com/Owouwun/spkuznetsov/pkg/utils"
+	"github.com/google/uuid"
 )
 
 func (s *Status) isValid(validStatuses *[]Status) bool {
@@ -20,47 +29,179 @@ func (s *Status) isInvalid(invalidStatuses *[]Status) bool {
 	return s.isValid(invalidStatuses)
 }
 
+// Статусы, после которых заявка больше не меняется: она может быть архивирована и обезличена
+var ClosedStatuses = []Status{
+	StatusPaid,
+	StatusCanceled,
+}
+
+// Сменить статус, запомнив момент перехода
+func (ord *Order) setStatus(s Status) {
+	now := ord.now()
+	ord.Status = s
+	ord.StatusChangedAt = &now
+}
+
+// UseScheduling задаёт часы и рабочий календарь, по которым заявка планируется
+func (ord *Order) UseScheduling(s Scheduling) {
+	ord.scheduling = s
+}
+
+func (ord *Order) now() time.Time {
+	if ord.scheduling.Clock == nil {
+		return time.Now()
+	}
+	return ord.scheduling.Clock.Now()
+}
+
+// Location — часовой пояс заявки: пояс клиента или, если он не задан, пояс компании
+func (ord *Order) Location() *time.Location {
+	if ord.Timezone != "" {
+		if loc, err := time.LoadLocation(ord.Timezone); err == nil {
+			return loc
+		}
+	}
+	if ord.scheduling.Calendar != nil {
+		return ord.scheduling.Calendar.Location()
+	}
+	return time.Local
+}
+
+func validateTimezone(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return deterrs.NewDetErr(
+			deterrs.InvalidValue,
+			deterrs.WithField("timezone"),
+			deterrs.WithOriginalError(err),
+		)
+	}
+	return nil
+}
+
+// Форматы времени без смещения, принимаемые LocalTime
+var localTimeLayouts = []string{"2006-01-02T15:04:05", "2006-01-02T15:04", time.DateOnly}
+
+func (t *LocalTime) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if parsed, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		t.Time = parsed
+		return nil
+	}
+	for _, layout := range localTimeLayouts {
+		if parsed, err := time.ParseInLocation(layout, s, OrderLocal); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid time %q, expected RFC 3339 or local time YYYY-MM-DDTHH:MM[:SS]", s)
+}
+
+// Ptr возвращает время как *time.Time; nil для nil
+func (t *LocalTime) Ptr() *time.Time {
+	if t == nil {
+		return nil
+	}
+	return &t.Time
+}
+
+// Привести время работ к часовому поясу заявки и проверить, что оно не в прошлом
+// и приходится на рабочее время. Время без смещения (OrderLocal) — местное время заявки.
+// Полночь означает «в течение дня»: проверяется только, что день рабочий
+func (ord *Order) checkScheduleTime(date *time.Time) (*time.Time, error) {
+	loc := ord.Location()
+	if date.Location() == OrderLocal {
+		localized := time.Date(date.Year(), date.Month(), date.Day(), date.Hour(), date.Minute(), date.Second(), date.Nanosecond(), loc)
+		date = &localized
+	}
+	local := date.In(loc)
+
+	invalid := func(err error) error {
+		return deterrs.NewDetErr(
+			deterrs.InvalidValue,
+			deterrs.WithField("scheduled date"),
+			deterrs.WithOriginalError(err),
+		)
+	}
+	if err := utils.MustNotPast(date, ord.now()); err != nil {
+		return nil, invalid(err)
+	}
+
+	if ord.scheduling.Calendar == nil {
+		return date, nil
+	}
+	cal := ord.scheduling.Calendar.In(loc)
+	wholeDay := local.Hour() == 0 && local.Minute() == 0 && local.Second() == 0 && local.Nanosecond() == 0
+	switch {
+	case !cal.IsWorkday(local):
+		return nil, invalid(errors.New("not a working day"))
+	case !wholeDay && !cal.IsWorkingTime(local):
+		return nil, invalid(errors.New("outside working hours"))
+	}
+	return date, nil
+}
+
 // Оформить новую заявку
-func (pord *PrimaryOrder) CreateNewOrder() (*Order, error) {
+func (pord *PrimaryOrder) CreateNewOrder(s Scheduling) (*Order, error) {
 	if pord.ClientName == "" {
 		return nil, deterrs.NewDetErr(
 			deterrs.EmptyField,
 			deterrs.WithField("client name"),
 		)
 	}
-	if pord.ClientPhone == "" {
-		return nil, deterrs.NewDetErr(
-			deterrs.EmptyField,
-			deterrs.WithField("client phone"),
-		)
+	stdPN, err := standardizeClientPhone(pord.ClientPhone)
+	if err != nil {
+		return nil, err
 	}
-	if pord.Address == "" {
-		return nil, deterrs.NewDetErr(
-			deterrs.EmptyField,
-			deterrs.WithField("address"),
-		)
+	address := pord.Address.normalize()
+	if err := address.validate(); err != nil {
+		return nil, err
 	}
 
-	stdPN, err := utils.StandartizePhoneNumber(pord.ClientPhone)
-	if err != nil {
-		return nil, deterrs.NewDetErr(
-			deterrs.InvalidValue,
-			deterrs.WithField("client phone"),
-			deterrs.WithOriginalError(err),
-		)
+	if err := validateTimezone(pord.Timezone); err != nil {
+		return nil, err
 	}
 
 	ord := &Order{
 		ClientName:        pord.ClientName,
 		ClientPhone:       stdPN,
-		Address:           pord.Address,
+		Address:           address,
 		ClientDescription: pord.ClientDescription,
-		Status:            StatusNew,
+		Category:          normalizeCategory(pord.Category),
+		Timezone:          pord.Timezone,
+		TemplateID:        pord.TemplateID,
+		scheduling:        s,
+	}
+	if pord.Quarantined {
+		ord.setStatus(StatusQuarantined)
+	} else {
+		ord.setStatus(StatusNew)
 	}
 
 	return ord, nil
 }
 
+// Принять заявку, отложенную как подозрительная: она становится обычной новой заявкой
+func (ord *Order) Release() error {
+	validStatuses := []Status{
+		StatusQuarantined,
+	}
+
+	if !ord.Status.isValid(&validStatuses) {
+		return deterrs.NewDetErr(
+			deterrs.OrderActionNotPermittedByStatus,
+		)
+	}
+
+	ord.setStatus(StatusNew)
+	return nil
+}
+
 // Назначить предварительную дату работ
 func (ord *Order) Preschedule(date *time.Time) error {
 	validStatuses := []Status{
@@ -78,16 +219,13 @@ func (ord *Order) Preschedule(date *time.Time) error {
 	}
 
 	if date != nil {
-		if err := utils.MustNotPast(date); err != nil {
-			return deterrs.NewDetErr(
-				deterrs.InvalidValue,
-				deterrs.WithField("scheduled date"),
-				deterrs.WithOriginalError(err),
-			)
+		var err error
+		if date, err = ord.checkScheduleTime(date); err != nil {
+			return err
 		}
 	}
 
-	ord.Status = StatusPrescheduled
+	ord.setStatus(StatusPrescheduled)
 	ord.ScheduledFor = date
 	return nil
 }
@@ -95,6 +233,7 @@ func (ord *Order) Preschedule(date *time.Time) error {
 // Назначить ответственного сотрудника
 func (ord *Order) Assign(emp *auth.Employee) error {
 	invalidStatuses := []Status{
+		StatusQuarantined,
 		StatusNew,
 		StatusCanceled,
 	}
@@ -106,7 +245,7 @@ func (ord *Order) Assign(emp *auth.Employee) error {
 	}
 
 	ord.Employee = emp
-	ord.Status = StatusAssigned
+	ord.setStatus(StatusAssigned)
 	return nil
 }
 
@@ -123,21 +262,17 @@ func (ord *Order) Schedule(date *time.Time) error {
 			deterrs.WithField("scheduled date"),
 		)
 	}
-	if err := utils.MustNotPast(date); err != nil {
-		return deterrs.NewDetErr(
-			deterrs.InvalidValue,
-			deterrs.WithField("sheduled date"),
-			deterrs.WithOriginalError(err),
-		)
+	date, err := ord.checkScheduleTime(date)
+	if err != nil {
+		return err
 	}
-	if !ord.Status.isValid(&validStatuses) {
+	if !ord.Status.isValid(&validStatuses) || ord.onSite() {
 		return deterrs.NewDetErr(
 			deterrs.OrderActionNotPermittedByStatus,
 		)
 	}
 
-	ord.Status = StatusScheduled
-	ord.ScheduledFor = date
+	ord.planVisit(date)
 	return nil
 }
 
@@ -160,12 +295,146 @@ func (ord *Order) ConfirmSchedule() error {
 		)
 	}
 
-	ord.Status = StatusScheduled
+	ord.planVisit(ord.ScheduledFor)
 	return nil
 }
 
-// Описать частично проведённые работы
-func (ord *Order) Progress(empDescription string) error {
+// Текущий визит: запланированный или начатый, но ещё не завершённый
+func (ord *Order) OpenVisit() *Visit {
+	for i := len(ord.Visits) - 1; i >= 0; i-- {
+		if ord.Visits[i].DepartedAt == nil {
+			return ord.Visits[i]
+		}
+	}
+	return nil
+}
+
+// Запланировать визит на дату date. Незавершённый визит, к которому сотрудник ещё не приступил,
+// переносится, а не дублируется
+func (ord *Order) planVisit(date *time.Time) {
+	visit := ord.OpenVisit()
+	if visit == nil || visit.ArrivedAt != nil {
+		visit = ord.newVisit()
+	}
+	visit.PlannedFor = date
+	visit.Employee = ord.Employee
+
+	ord.ScheduledFor = date
+	ord.setStatus(ord.statusFromVisits())
+}
+
+func (ord *Order) newVisit() *Visit {
+	visit := &Visit{
+		ID:        uuid.New(),
+		OrderID:   ord.ID,
+		Employee:  ord.Employee,
+		CreatedAt: ord.now(),
+	}
+	ord.Visits = append(ord.Visits, visit)
+	return visit
+}
+
+// Сотрудник отметил прибытие, но ещё не завершил визит
+func (ord *Order) onSite() bool {
+	visit := ord.OpenVisit()
+	return visit != nil && visit.ArrivedAt != nil
+}
+
+// Статус выполняемой заявки определяется визитами: есть визит, к которому сотрудник
+// ещё не приступил, — работы назначены, иначе — работы идут или частично проведены
+func (ord *Order) statusFromVisits() Status {
+	if visit := ord.OpenVisit(); visit != nil && visit.ArrivedAt == nil {
+		return StatusScheduled
+	}
+	return StatusInProgress
+}
+
+// Пересчитать время работ на месте по визитам с отмеченными прибытием и отъездом
+func (ord *Order) recalcWorkTime() {
+	var total time.Duration
+	for _, visit := range ord.Visits {
+		if visit.ArrivedAt != nil && visit.DepartedAt != nil {
+			total += visit.DepartedAt.Sub(*visit.ArrivedAt)
+		}
+	}
+	ord.WorkSeconds = int64(total / time.Second)
+}
+
+// Описать проведённые в ходе визита работы и завершить визит
+func (ord *Order) Progress(report *VisitReport) error {
+	if ord.Status != StatusScheduled && !(ord.Status == StatusInProgress && ord.onSite()) {
+		return deterrs.NewDetErr(
+			deterrs.OrderActionNotPermittedByStatus,
+		)
+	}
+
+	departedAt, err := ord.validateReport(report)
+	if err != nil {
+		return err
+	}
+
+	// Заявки, запланированные до появления визитов, могут не иметь текущего визита
+	visit := ord.OpenVisit()
+	if visit == nil {
+		visit = ord.newVisit()
+		visit.PlannedFor = ord.ScheduledFor
+	}
+	if report.ArrivedAt != nil {
+		visit.ArrivedAt = report.ArrivedAt
+	}
+	if visit.ArrivedAt != nil && visit.ArrivedAt.After(*departedAt) {
+		return deterrs.NewDetErr(
+			deterrs.InvalidValue,
+			deterrs.WithField("arrived at"),
+		)
+	}
+
+	ord.completeVisit(visit, departedAt, report)
+	return nil
+}
+
+func (ord *Order) validateReport(report *VisitReport) (*time.Time, error) {
+	now := ord.now()
+	departedAt := report.DepartedAt
+	if departedAt == nil {
+		departedAt = &now
+	}
+	if departedAt.After(now) {
+		return nil, deterrs.NewDetErr(
+			deterrs.InvalidValue,
+			deterrs.WithField("departed at"),
+		)
+	}
+	if report.ArrivedAt != nil && report.ArrivedAt.After(*departedAt) {
+		return nil, deterrs.NewDetErr(
+			deterrs.InvalidValue,
+			deterrs.WithField("arrived at"),
+		)
+	}
+	for _, part := range report.PartsUsed {
+		if part.Name == "" || part.Quantity <= 0 {
+			return nil, deterrs.NewDetErr(
+				deterrs.InvalidValue,
+				deterrs.WithField("parts used"),
+			)
+		}
+	}
+	return departedAt, nil
+}
+
+func (ord *Order) completeVisit(visit *Visit, departedAt *time.Time, report *VisitReport) {
+	visit.DepartedAt = departedAt
+	visit.Notes = report.EmployeeDescription
+	visit.PartsUsed = report.PartsUsed
+
+	ord.ScheduledFor = nil
+	ord.EmployeeDescription = report.EmployeeDescription
+	ord.recalcWorkTime()
+	ord.setStatus(ord.statusFromVisits())
+}
+
+// Отметить прибытие сотрудника на место: текущий визит начинается, заявка переходит в работу
+func (ord *Order) CheckIn(p *Presence) error {
 	validStatuses := []Status{
 		StatusScheduled,
 	}
@@ -175,26 +444,268 @@ func (ord *Order) Progress(empDescription string) error {
 			deterrs.OrderActionNotPermittedByStatus,
 		)
 	}
+	at, err := ord.validatePresence(p, "checkin")
+	if err != nil {
+		return err
+	}
 
-	ord.Status = StatusInProgress
-	ord.ScheduledFor = nil
-	ord.EmployeeDescription = empDescription
+	visit := ord.OpenVisit()
+	if visit == nil {
+		visit = ord.newVisit()
+		visit.PlannedFor = ord.ScheduledFor
+	}
+	visit.ArrivedAt = at
+	visit.CheckInLocation = &p.Position
+	visit.CheckInDistanceM = ord.distanceTo(p.Position)
+	visit.FarFromAddress = visit.FarFromAddress || isFar(visit.CheckInDistanceM, p.Radius)
+
+	ord.setStatus(ord.statusFromVisits())
 	return nil
 }
 
+// Отметить отъезд сотрудника: визит, начатый прибытием, завершается отчётом о работах
+func (ord *Order) CheckOut(p *Presence, report *VisitReport) error {
+	if ord.Status != StatusInProgress || !ord.onSite() {
+		return deterrs.NewDetErr(
+			deterrs.OrderActionNotPermittedByStatus,
+		)
+	}
+	at, err := ord.validatePresence(p, "checkout")
+	if err != nil {
+		return err
+	}
+
+	visit := ord.OpenVisit()
+	if at.Before(*visit.ArrivedAt) {
+		return deterrs.NewDetErr(
+			deterrs.InvalidValue,
+			deterrs.WithField("checkout at"),
+		)
+	}
+	// Время визита берётся из отметок, а не из отчёта
+	var timed VisitReport
+	if report != nil {
+		timed = *report
+	}
+	timed.ArrivedAt, timed.DepartedAt = nil, at
+	if _, err := ord.validateReport(&timed); err != nil {
+		return err
+	}
+
+	visit.CheckOutLocation = &p.Position
+	visit.CheckOutDistanceM = ord.distanceTo(p.Position)
+	visit.FarFromAddress = visit.FarFromAddress || isFar(visit.CheckOutDistanceM, p.Radius)
+	ord.completeVisit(visit, at, &timed)
+	return nil
+}
+
+// Проверить, что отметку делает назначенный сотрудник и что координаты и время корректны
+func (ord *Order) validatePresence(p *Presence, field string) (*time.Time, error) {
+	if ord.Employee == nil || ord.Employee.ID != p.EmployeeID {
+		return nil, deterrs.NewDetErr(
+			deterrs.EmployeeNotAssigned,
+		)
+	}
+	if !p.Position.isValid() {
+		return nil, deterrs.NewDetErr(
+			deterrs.InvalidValue,
+			deterrs.WithField(field+" position"),
+		)
+	}
+
+	now := ord.now()
+	at := p.At
+	if at == nil {
+		at = &now
+	}
+	if at.After(now) {
+		return nil, deterrs.NewDetErr(
+			deterrs.InvalidValue,
+			deterrs.WithField(field+" at"),
+		)
+	}
+	return at, nil
+}
+
+// Расстояние от точки до адреса заявки, м; nil, если адрес не геокодирован
+func (ord *Order) distanceTo(p GeoPoint) *float64 {
+	if ord.Address.Location == nil {
+		return nil
+	}
+	d := ord.Address.Location.DistanceTo(p)
+	return &d
+}
+
+func isFar(distance *float64, radius float64) bool {
+	return distance != nil && radius > 0 && *distance > radius
+}
+
+func (p GeoPoint) isValid() bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lon >= -180 && p.Lon <= 180 && !(p.Lat == 0 && p.Lon == 0)
+}
+
+// Расстояние по поверхности Земли, м
+func (p GeoPoint) DistanceTo(q GeoPoint) float64 {
+	return routing.Distance(p.point(), q.point())
+}
+
+func (p GeoPoint) point() routing.Point {
+	return routing.Point{Lat: p.Lat, Lon: p.Lon}
+}
+
+// Адрес принимается и объектом, и строкой — тогда он сохраняется в Line
+func (a *Address) UnmarshalJSON(data []byte) error {
+	var line string
+	if err := json.Unmarshal(data, &line); err == nil {
+		*a = Address{Line: line}
+		return nil
+	}
+
+	type plain Address
+	return json.Unmarshal(data, (*plain)(a))
+}
+
+// Адрес без пробелов по краям полей
+func (a Address) normalize() Address {
+	for _, f := range []*string{&a.City, &a.Street, &a.House, &a.Apartment, &a.Entrance, &a.Floor, &a.Intercom, &a.Line} {
+		*f = strings.TrimSpace(*f)
+	}
+	return a
+}
+
+// Проверить адрес: нужен либо адрес одной строкой, либо как минимум улица и дом
+func (a Address) validate() error {
+	if a.Query() == "" && a.City == "" {
+		return deterrs.NewDetErr(
+			deterrs.EmptyField,
+			deterrs.WithField("address"),
+		)
+	}
+	if a.Line == "" {
+		if a.Street == "" {
+			return deterrs.NewDetErr(
+				deterrs.EmptyField,
+				deterrs.WithField("address street"),
+			)
+		}
+		if a.House == "" {
+			return deterrs.NewDetErr(
+				deterrs.EmptyField,
+				deterrs.WithField("address house"),
+			)
+		}
+	}
+	if a.Location != nil && !a.Location.isValid() {
+		return deterrs.NewDetErr(
+			deterrs.InvalidValue,
+			deterrs.WithField("address location"),
+		)
+	}
+	return nil
+}
+
+// Строка поиска адреса для геокодера: город, улица и дом или, если их нет, адрес одной строкой
+func (a Address) Query() string {
+	if a.Street == "" && a.House == "" {
+		return a.Line
+	}
+
+	var parts []string
+	for _, p := range []string{a.City, a.Street, a.House} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Адреса относятся к одному зданию, а значит, и к одним координатам
+func (a Address) sameBuilding(b Address) bool {
+	return strings.EqualFold(a.Query(), b.Query())
+}
+
+// Окно прибытия к визиту. Время, отличное от полуночи, — договорённость с клиентом,
+// полночь означает «в течение дня» и окна не задаёт
+func (ord *Order) arrivalWindow(loc *time.Location, window time.Duration) routing.Window {
+	if ord.ScheduledFor == nil {
+		return routing.Window{}
+	}
+	// Визит «в течение дня» назначается на полночь по времени заявки
+	if ord.Timezone != "" {
+		loc = ord.Location()
+	}
+	t := ord.ScheduledFor.In(loc)
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+		return routing.Window{}
+	}
+	return routing.Window{From: t, To: t.Add(window)}
+}
+
+// Точки маршрута по заявкам; заявки без координат возвращаются отдельно
+func routeStops(ords []*Order, loc *time.Location, settings RouteSettings) ([]routing.Stop, []uuid.UUID) {
+	stops := make([]routing.Stop, 0, len(ords))
+	unrouted := []uuid.UUID{}
+	for _, ord := range ords {
+		if ord.Address.Location == nil {
+			unrouted = append(unrouted, ord.ID)
+			continue
+		}
+		stops = append(stops, routing.Stop{
+			ID:       ord.ID.String(),
+			Location: ord.Address.Location.point(),
+			Window:   ord.arrivalWindow(loc, settings.ArrivalWindow),
+			Duration: settings.VisitDuration,
+		})
+	}
+	return stops, unrouted
+}
+
+// Маршрут сотрудника по построенному плану объезда заявок
+func newRoute(employeeID uint, day time.Time, ords []*Order, plan *routing.Plan, unrouted []uuid.UUID) *Route {
+	byID := make(map[string]*Order, len(ords))
+	for _, ord := range ords {
+		byID[ord.ID.String()] = ord
+	}
+
+	route := &Route{
+		EmployeeID:    employeeID,
+		Date:          day.Format(time.DateOnly),
+		Stops:         make([]RouteStop, 0, len(plan.Visits)),
+		DistanceM:     math.Round(plan.DistanceM),
+		TravelSeconds: int64(plan.Travel / time.Second),
+		Late:          plan.Late,
+		Unrouted:      unrouted,
+	}
+	for _, v := range plan.Visits {
+		ord := byID[v.ID]
+		route.Stops = append(route.Stops, RouteStop{
+			OrderID:       ord.ID,
+			Address:       ord.Address,
+			ScheduledFor:  ord.ScheduledFor,
+			DistanceM:     math.Round(v.DistanceM),
+			TravelSeconds: int64(v.Travel / time.Second),
+			Arrival:       v.Arrival,
+			StartAt:       v.StartAt,
+			Departure:     v.Departure,
+			Late:          v.Late,
+		})
+	}
+	return route
+}
+
 // Пометить заявку как выполненную
 func (ord *Order) Complete() error {
 	validStatuses := []Status{
 		StatusInProgress,
 	}
 
-	if !ord.Status.isValid(&validStatuses) {
+	if !ord.Status.isValid(&validStatuses) || ord.onSite() {
 		return deterrs.NewDetErr(
 			deterrs.OrderActionNotPermittedByStatus,
 		)
 	}
 
-	ord.Status = StatusDone
+	ord.setStatus(StatusDone)
 	return nil
 }
 
@@ -210,7 +721,7 @@ func (ord *Order) Close() error {
 		)
 	}
 
-	ord.Status = StatusPaid
+	ord.setStatus(StatusPaid)
 	return nil
 }
 
@@ -228,7 +739,7 @@ func (ord *Order) Cancel(cause string) error {
 	}
 
 	ord.CancelReason = cause
-	ord.Status = StatusCanceled
+	ord.setStatus(StatusCanceled)
 	ord.ScheduledFor = nil
 	return nil
 }
@@ -249,11 +760,29 @@ func (ord *Order) Patch(patchedFields *OrderPatcher) error {
 	if patchedFields.ClientName != nil {
 		ord.ClientName = *patchedFields.ClientName
 	}
+	// Телефон приводится к тому же виду, что при оформлении: по нему ищутся заявки
+	// клиента, в том числе для обезличивания
 	if patchedFields.ClientPhone != nil {
-		ord.ClientPhone = *patchedFields.ClientPhone
+		stdPN, err := standardizeClientPhone(*patchedFields.ClientPhone)
+		if err != nil {
+			return err
+		}
+		ord.ClientPhone = stdPN
 	}
 	if patchedFields.Address != nil {
-		ord.Address = *patchedFields.Address
+		address := patchedFields.Address.normalize()
+		if err := address.validate(); err != nil {
+			return err
+		}
+		// Координаты здания сохраняются, если поменялись только квартира, подъезд и т.п.
+		if address.sameBuilding(ord.Address) {
+			if address.Location == nil {
+				address.Location = ord.Address.Location
+			}
+		} else {
+			ord.relocated = true
+		}
+		ord.Address = address
 	}
 	if patchedFields.ClientDescription != nil {
 		ord.ClientDescription = *patchedFields.ClientDescription
@@ -261,5 +790,172 @@ func (ord *Order) Patch(patchedFields *OrderPatcher) error {
 	if patchedFields.EmployeeDescription != nil {
 		ord.EmployeeDescription = *patchedFields.EmployeeDescription
 	}
+	if patchedFields.Category != nil {
+		ord.Category = normalizeCategory(*patchedFields.Category)
+	}
+	if patchedFields.Timezone != nil {
+		if err := validateTimezone(*patchedFields.Timezone); err != nil {
+			return err
+		}
+		ord.Timezone = *patchedFields.Timezone
+	}
 	return nil
 }
+
+// Подготовить обезличивание всех заявок клиента по его запросу
+func NewClientErasureScope(clientPhone string) (ErasureScope, error) {
+	stdPN, err := standardizeClientPhone(clientPhone)
+	if err != nil {
+		return ErasureScope{}, err
+	}
+	return ErasureScope{ClientPhone: stdPN}, nil
+}
+
+// Телефон клиента в едином виде (+7XXXXXXXXXX)
+func standardizeClientPhone(phone string) (string, error) {
+	if phone == "" {
+		return "", deterrs.NewDetErr(
+			deterrs.EmptyField,
+			deterrs.WithField("client phone"),
+		)
+	}
+
+	stdPN, err := utils.StandartizePhoneNumber(phone)
+	if err != nil {
+		return "", deterrs.NewDetErr(
+			deterrs.InvalidValue,
+			deterrs.WithField("client phone"),
+			deterrs.WithOriginalError(err),
+		)
+	}
+	return stdPN, nil
+}
+
+func normalizeCategory(category string) string {
+	return strings.ToLower(strings.TrimSpace(category))
+}
+
+// ParseStatus находит статус по имени (New, InProgress, ...) без учёта регистра
+func ParseStatus(name string) (Status, bool) {
+	for _, s := range Statuses {
+		if strings.EqualFold(s.ToString(), name) {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+// ParseSLARule разбирает правило SLA вида "статус[/категория]:срок", например "new:4h",
+// "new/boiler:2h" или "done:14d". Срок в часах и минутах отсчитывается в рабочем времени,
+// срок в днях (Nd) — в рабочих днях
+func ParseSLARule(s string) (SLARule, error) {
+	target, limit, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return SLARule{}, fmt.Errorf("sla rule %q: expected status[/category]:duration", s)
+	}
+
+	var rule SLARule
+	statusName, category, _ := strings.Cut(target, "/")
+	if rule.Status, ok = ParseStatus(strings.TrimSpace(statusName)); !ok {
+		return SLARule{}, fmt.Errorf("sla rule %q: unknown status %q", s, statusName)
+	}
+	if rule.Status.isValid(&ClosedStatuses) {
+		return SLARule{}, fmt.Errorf("sla rule %q: order never leaves status %s", s, rule.Status.ToString())
+	}
+	rule.Category = normalizeCategory(category)
+
+	limit = strings.TrimSpace(limit)
+	if days, isDays := strings.CutSuffix(limit, "d"); isDays {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return SLARule{}, fmt.Errorf("sla rule %q: invalid number of working days %q", s, days)
+		}
+		rule.Workdays = n
+		return rule, nil
+	}
+	d, err := time.ParseDuration(limit)
+	if err != nil || d <= 0 {
+		return SLARule{}, fmt.Errorf("sla rule %q: invalid duration %q, expected e.g. 4h or 14d", s, limit)
+	}
+	rule.Within = d
+	return rule, nil
+}
+
+// ParseSLARules разбирает список правил; для статуса и категории допускается одно правило
+func ParseSLARules(specs []string) ([]SLARule, error) {
+	rules := make([]SLARule, 0, len(specs))
+	seen := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		rule, err := ParseSLARule(spec)
+		if err != nil {
+			return nil, err
+		}
+		key := rule.Status.ToString() + "/" + rule.Category
+		if seen[key] {
+			return nil, fmt.Errorf("sla rule %q: duplicate rule for %s", spec, key)
+		}
+		seen[key] = true
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Срок, до которого заявка, перешедшая в статус в момент from, должна его покинуть.
+// Без календаря срок считается в календарном времени
+func (r SLARule) Deadline(from time.Time, cal *calendar.Calendar) time.Time {
+	switch {
+	case cal == nil && r.Workdays > 0:
+		return from.AddDate(0, 0, r.Workdays)
+	case cal == nil:
+		return from.Add(r.Within)
+	case r.Workdays > 0:
+		return cal.AddWorkdays(from, r.Workdays)
+	default:
+		return cal.AddWorkingTime(from, r.Within)
+	}
+}
+
+// Нижняя граница срока в календарном времени: раньше неё нарушения быть не может
+func (r SLARule) minDuration() time.Duration {
+	if r.Workdays > 0 {
+		return time.Duration(r.Workdays) * 24 * time.Hour
+	}
+	return r.Within
+}
+
+// Правило SLA для текущего статуса заявки; nil, если срок не ограничен
+func (ord *Order) slaRule(rules []SLARule) *SLARule {
+	var generic *SLARule
+	for i, r := range rules {
+		if r.Status != ord.Status {
+			continue
+		}
+		if r.Category == "" {
+			generic = &rules[i]
+		} else if r.Category == ord.Category {
+			return &rules[i]
+		}
+	}
+	return generic
+}
+
+// Проверить, нарушен ли к моменту now срок нахождения заявки в текущем статусе
+func (ord *Order) CheckSLA(rules []SLARule, cal *calendar.Calendar, now time.Time) *SLABreach {
+	rule := ord.slaRule(rules)
+	if rule == nil || ord.StatusChangedAt == nil || ord.DeletedAt != nil {
+		return nil
+	}
+
+	deadline := rule.Deadline(*ord.StatusChangedAt, cal)
+	if !now.After(deadline) {
+		return nil
+	}
+	return &SLABreach{
+		OrderID:         ord.ID,
+		Status:          ord.Status,
+		Category:        ord.Category,
+		StatusChangedAt: *ord.StatusChangedAt,
+		Deadline:        deadline,
+		DetectedAt:      now,
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"github.com/Owouwun/spkuznetsov/internal/core/logic/auth"
+	"github.com/Owouwun/spkuznetsov/pkg/calendar"
+	"github.com/Owouwun/spkuznetsov/pkg/clock"
 	"github.com/google/uuid"
 )
 
@@ -18,6 +20,7 @@ const (
 	StatusDone         Status = 5  // "Выполнена"
 	StatusPaid         Status = 6  // "Оплачена"
 	StatusCanceled     Status = -1 // "Отменена"
+	StatusQuarantined  Status = -2 // "На проверке (похожа на спам)"
 )
 
 func (s Status) ToString() string {
@@ -38,15 +41,56 @@ func (s Status) ToString() string {
 		return "Paid"
 	case StatusCanceled:
 		return "Canceled"
+	case StatusQuarantined:
+		return "Quarantined"
 	}
 	return ""
 }
 
+// Все статусы заявки в порядке жизненного цикла
+var Statuses = []Status{
+	StatusQuarantined,
+	StatusNew,
+	StatusPrescheduled,
+	StatusAssigned,
+	StatusScheduled,
+	StatusInProgress,
+	StatusDone,
+	StatusPaid,
+	StatusCanceled,
+}
+
 type PrimaryOrder struct {
-	ClientName        string `json:"client_name"`
-	ClientPhone       string `json:"client_phone"`
-	Address           string `json:"address"`
-	ClientDescription string `json:"client_description"`
+	ClientName        string  `json:"client_name"`
+	ClientPhone       string  `json:"client_phone"`
+	Address           Address `json:"address"`
+	ClientDescription string  `json:"client_description"`
+	// Вид работ (например, boiler); от него зависят сроки SLA
+	Category string `json:"category,omitempty"`
+	// Часовой пояс клиента (IANA, например Asia/Yekaterinburg); по умолчанию — пояс компании
+	Timezone string `json:"timezone,omitempty"`
+	// Шаблон повторяющейся заявки, по которому она создаётся; задаётся только планировщиком
+	TemplateID *uuid.UUID `json:"-"`
+	// Заявка с сайта похожа на спам и оформляется в статусе Quarantined до проверки;
+	// задаётся только приёмом заявок
+	Quarantined bool `json:"-"`
+}
+
+// Адрес заявки. Для геокодирования используются город, улица и дом (или Line);
+// квартира, подъезд, этаж и код домофона нужны только сотруднику на месте
+type Address struct {
+	City      string `json:"city,omitempty"`
+	Street    string `json:"street,omitempty"`
+	House     string `json:"house,omitempty"`
+	Apartment string `json:"apartment,omitempty"`
+	Entrance  string `json:"entrance,omitempty"`
+	Floor     string `json:"floor,omitempty"`
+	Intercom  string `json:"intercom,omitempty"`
+	// Адрес одной строкой: так хранятся заявки, оформленные до появления
+	// структурированного адреса, и так его передают старые клиенты API
+	Line string `json:"line,omitempty"`
+	// Координаты; nil, пока адрес не геокодирован
+	Location *GeoPoint `json:"location,omitempty"`
 }
 
 type Order struct {
@@ -54,21 +98,213 @@ type Order struct {
 	ID                uuid.UUID `json:"id"`
 	ClientName        string    `json:"client_name"`
 	ClientPhone       string    `json:"client_phone"`
-	Address           string    `json:"address"`
+	Address           Address   `json:"address"`
 	ClientDescription string    `json:"client_description"`
-	Employee          *auth.Employee
-	CancelReason      string `json:"cancel_reason"`
+	Category          string    `json:"category,omitempty"`
+	Timezone          string    `json:"timezone,omitempty"`
+	// Шаблон повторяющейся заявки, по которому создана заявка
+	TemplateID   *uuid.UUID `json:"template_id,omitempty"`
+	Employee     *auth.Employee
+	CancelReason string `json:"cancel_reason"`
 
 	// Mutable
 	Status              Status     `json:"status"`
 	EmployeeDescription string     `json:"employee_description"`
 	ScheduledFor        *time.Time `json:"scheduled_for"`
+	StatusChangedAt     *time.Time `json:"status_changed_at"`
+	// Суммарное время работ на месте по визитам с отмеченными прибытием и отъездом
+	WorkSeconds int64 `json:"work_seconds"`
+
+	// Service
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+	ArchivedAt   *time.Time `json:"archived_at,omitempty"`
+	AnonymizedAt *time.Time `json:"anonymized_at,omitempty"`
+
+	// Визиты по заявке в порядке создания. Загружаются репозиторием для действий,
+	// которые их меняют (Schedule, ConfirmSchedule, Progress); отдаются отдельным запросом
+	Visits []*Visit `json:"-"`
+
+	scheduling Scheduling
+	// Адрес изменён на другое здание (см. Patch): прежние координаты недействительны
+	relocated bool
+}
+
+// Адрес заявки изменён на другое здание, и его нужно геокодировать заново
+func (ord *Order) Relocated() bool {
+	return ord.relocated
+}
+
+// Условия планирования работ: источник текущего времени и рабочий календарь компании
+// (его часовой пояс — пояс компании). Нулевое значение — системные часы без проверки рабочего времени
+type Scheduling struct {
+	Clock    clock.Clock
+	Calendar *calendar.Calendar
+}
+
+// Часовой пояс, в котором задано время без смещения (2026-03-02T09:00:00): такое время
+// считается местным временем заявки и переводится в её часовой пояс при планировании
+var OrderLocal = time.FixedZone("order local time", 0)
+
+// Время в запросе: RFC 3339 со смещением или местное время заявки без смещения
+// (2026-03-02T09:00:00, 2026-03-02T09:00 или просто дата 2026-03-02)
+type LocalTime struct {
+	time.Time
+}
+
+// Выезд сотрудника по заявке. ScheduledFor и EmployeeDescription заявки отражают
+// текущий (незавершённый) и последний завершённый визиты
+type Visit struct {
+	ID         uuid.UUID      `json:"id"`
+	OrderID    uuid.UUID      `json:"order_id"`
+	Employee   *auth.Employee `json:"employee,omitempty"`
+	PlannedFor *time.Time     `json:"planned_for"`
+	ArrivedAt  *time.Time     `json:"arrived_at"`
+	DepartedAt *time.Time     `json:"departed_at"`
+	Notes      string         `json:"notes"`
+	PartsUsed  []UsedPart     `json:"parts_used"`
+	CreatedAt  time.Time      `json:"created_at"`
+
+	// Отметки сотрудника на месте. Расстояние до адреса заявки известно, если адрес геокодирован
+	CheckInLocation   *GeoPoint `json:"checkin_location,omitempty"`
+	CheckInDistanceM  *float64  `json:"checkin_distance_m,omitempty"`
+	CheckOutLocation  *GeoPoint `json:"checkout_location,omitempty"`
+	CheckOutDistanceM *float64  `json:"checkout_distance_m,omitempty"`
+	// Отметка сделана дальше допустимого радиуса от адреса
+	FarFromAddress bool `json:"far_from_address"`
+}
+
+// Географические координаты (WGS 84)
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// Отметка сотрудника о прибытии или отъезде. Если время не указано, используется текущий момент
+type Presence struct {
+	EmployeeID uint       `json:"employee_id"`
+	Position   GeoPoint   `json:"position"`
+	At         *time.Time `json:"at,omitempty"`
+	// Допустимое расстояние до адреса заявки, м; задаётся сервисом из конфигурации
+	Radius float64 `json:"-"`
+}
+
+type UsedPart struct {
+	// Артикул по каталогу запчастей; по нему запчасть списывается со склада при выполнении заявки
+	SKU      string `json:"sku,omitempty"`
+	Name     string `json:"name"`
+	Quantity int    `json:"quantity"`
+}
+
+// Отчёт сотрудника о проведённом визите. Если время отъезда не указано, визит завершается текущим моментом
+type VisitReport struct {
+	EmployeeDescription string     `json:"employee_description"`
+	ArrivedAt           *time.Time `json:"arrived_at,omitempty"`
+	DepartedAt          *time.Time `json:"departed_at,omitempty"`
+	PartsUsed           []UsedPart `json:"parts_used,omitempty"`
+}
+
+// Маршрут сотрудника на день по заявкам с запланированными визитами
+type Route struct {
+	EmployeeID    uint        `json:"employee_id"`
+	Date          string      `json:"date"`
+	Stops         []RouteStop `json:"stops"`
+	DistanceM     float64     `json:"distance_m"`
+	TravelSeconds int64       `json:"travel_seconds"`
+	// Сколько визитов не укладывается в окно прибытия
+	Late int `json:"late"`
+	// Заявки с негеокодированным адресом: место в маршруте для них не определить
+	Unrouted []uuid.UUID `json:"unrouted"`
+}
+
+type RouteStop struct {
+	OrderID      uuid.UUID  `json:"order_id"`
+	Address      Address    `json:"address"`
+	ScheduledFor *time.Time `json:"scheduled_for"`
+	// Путь от предыдущей точки маршрута
+	DistanceM     float64   `json:"distance_m"`
+	TravelSeconds int64     `json:"travel_seconds"`
+	Arrival       time.Time `json:"arrival"`
+	// Начало работ: прибытие или начало окна, если сотрудник приехал раньше
+	StartAt   time.Time `json:"start_at"`
+	Departure time.Time `json:"departure"`
+	Late      bool      `json:"late"`
+}
+
+// Параметры планирования маршрутов
+type RouteSettings struct {
+	// Начало рабочего дня, отсчитывается от полуночи
+	DayStart time.Duration
+	// Визит с назначенным временем (не полночь) ждут в промежутке [ScheduledFor, ScheduledFor+ArrivalWindow]
+	ArrivalWindow time.Duration
+	// Ожидаемая длительность визита
+	VisitDuration time.Duration
+	// Средняя скорость в пути, км/ч, и во сколько раз путь по дорогам длиннее прямой
+	SpeedKmh     float64
+	DetourFactor float64
+}
+
+// Основание обезличивания персональных данных клиента
+type ErasureReason string
+
+const (
+	ErasureByRequest   ErasureReason = "request"
+	ErasureByRetention ErasureReason = "retention"
+)
+
+// Какие заявки обезличить: все заявки клиента с указанным телефоном
+// или не более Limit закрытых заявок, статус которых не менялся с ClosedBefore
+type ErasureScope struct {
+	ClientPhone  string
+	ClosedBefore time.Time
+	Limit        int
+}
+
+// Запись журнала обезличивания. Стёртые данные в неё не попадают — только идентификаторы заявок
+type Erasure struct {
+	ID        int64         `json:"id"`
+	Reason    ErasureReason `json:"reason"`
+	OrderIDs  []uuid.UUID   `json:"order_ids"`
+	RequestID string        `json:"request_id,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// Срок нахождения заявки в статусе. Правило с категорией важнее общего правила для статуса
+type SLARule struct {
+	Status   Status
+	Category string
+	// Срок в рабочем времени (часы и минуты)...
+	Within time.Duration
+	// ...или в рабочих днях
+	Workdays int
+}
+
+// Нарушение SLA: заявка не покинула статус Status, в который перешла в StatusChangedAt, к сроку Deadline
+type SLABreach struct {
+	OrderID         uuid.UUID `json:"order_id"`
+	Status          Status    `json:"status"`
+	Category        string    `json:"category,omitempty"`
+	StatusChangedAt time.Time `json:"status_changed_at"`
+	Deadline        time.Time `json:"deadline"`
+	DetectedAt      time.Time `json:"detected_at"`
+}
+
+// Параметры выборки списка заявок
+type ListFilter struct {
+	IncludeDeleted bool
+	// Только заявки, нарушившие SLA в текущем статусе
+	Overdue bool
+	// Только заявки, созданные по шаблону повторяющейся заявки
+	TemplateID *uuid.UUID
+	// Только заявки клиента с этим телефоном (в стандартном виде, см. utils.StandartizePhoneNumber)
+	ClientPhone string
 }
 
 type OrderPatcher struct {
-	ClientName          *string `json:"client_name,omitempty"`
-	ClientPhone         *string `json:"client_phone,omitempty"`
-	Address             *string `json:"address,omitempty"`
-	ClientDescription   *string `json:"client_description,omitempty"`
-	EmployeeDescription *string `json:"employee_description,omitempty"`
+	ClientName          *string  `json:"client_name,omitempty"`
+	ClientPhone         *string  `json:"client_phone,omitempty"`
+	Address             *Address `json:"address,omitempty"`
+	ClientDescription   *string  `json:"client_description,omitempty"`
+	EmployeeDescription *string  `json:"employee_description,omitempty"`
+	Category            *string  `json:"category,omitempty"`
+	Timezone            *string  `json:"timezone,omitempty"`
 }
@@ -1,6 +1,10 @@
 package orders_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
 	"testing"
 	"time"
 
@@ -8,6 +12,9 @@ import (
 	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
 	deterrs "github.com/Owouwun/spkuznetsov/internal/errors"
 	"github.com/Owouwun/spkuznetsov/internal/testutils"
+	"github.com/Owouwun/spkuznetsov/pkg/calendar"
+	"github.com/Owouwun/spkuznetsov/pkg/clock"
+	"github.com/google/uuid"
 )
 
 var (
@@ -43,6 +50,26 @@ func TestNewOrder(t *testing.T) {
 			),
 			expErr: nil,
 		},
+		{
+			name: "Подозрительная заявка оформляется на проверку",
+			pReq: &orders.PrimaryOrder{
+				ClientName:        testutils.ClientName,
+				ClientPhone:       testutils.ClientPhone,
+				Address:           testutils.Address,
+				ClientDescription: testutils.ClientDescription,
+				Quarantined:       true,
+			},
+			expReq: testutils.NewTestOrder(
+				testutils.WithClientName(testutils.ClientName),
+				testutils.WithClientPhone(testutils.ClientPhone),
+				testutils.WithAddress(testutils.Address),
+				testutils.WithClientDescription(testutils.ClientDescription),
+				testutils.WithEmployee(nil),
+				testutils.WithStatus(orders.StatusQuarantined),
+				testutils.WithScheduledFor(nil),
+			),
+			expErr: nil,
+		},
 		{
 			name: "Попытка создать заявку с некорректным номером телефона клиента",
 			pReq: &orders.PrimaryOrder{
@@ -56,6 +83,64 @@ func TestNewOrder(t *testing.T) {
 				deterrs.InvalidValue,
 			),
 		},
+		{
+			name: "Успешное создание заявки с адресом одной строкой",
+			pReq: &orders.PrimaryOrder{
+				ClientName:        testutils.ClientName,
+				ClientPhone:       testutils.ClientPhone,
+				Address:           orders.Address{Line: " Москва, Тестовая улица, 1 "},
+				ClientDescription: testutils.ClientDescription,
+			},
+			expReq: testutils.NewTestOrder(
+				testutils.WithClientName(testutils.ClientName),
+				testutils.WithClientPhone(testutils.ClientPhone),
+				testutils.WithAddress(orders.Address{Line: "Москва, Тестовая улица, 1"}),
+				testutils.WithClientDescription(testutils.ClientDescription),
+				testutils.WithEmployee(nil),
+				testutils.WithStatus(orders.StatusNew),
+			),
+			expErr: nil,
+		},
+		{
+			name: "Попытка создать заявку без номера дома",
+			pReq: &orders.PrimaryOrder{
+				ClientName:        testutils.ClientName,
+				ClientPhone:       testutils.ClientPhone,
+				Address:           orders.Address{City: "Москва", Street: "Тестовая улица", Apartment: "2"},
+				ClientDescription: testutils.ClientDescription,
+			},
+			expReq: nil,
+			expErr: deterrs.NewDetErr(
+				deterrs.EmptyField,
+			),
+		},
+		{
+			name: "Попытка создать заявку с некорректными координатами адреса",
+			pReq: &orders.PrimaryOrder{
+				ClientName:        testutils.ClientName,
+				ClientPhone:       testutils.ClientPhone,
+				Address:           orders.Address{Street: "Тестовая улица", House: "1", Location: &orders.GeoPoint{Lat: 100, Lon: 37}},
+				ClientDescription: testutils.ClientDescription,
+			},
+			expReq: nil,
+			expErr: deterrs.NewDetErr(
+				deterrs.InvalidValue,
+			),
+		},
+		{
+			name: "Попытка создать заявку с неизвестным часовым поясом",
+			pReq: &orders.PrimaryOrder{
+				ClientName:        testutils.ClientName,
+				ClientPhone:       testutils.ClientPhone,
+				Address:           testutils.Address,
+				ClientDescription: testutils.ClientDescription,
+				Timezone:          "Asia/Atlantis",
+			},
+			expReq: nil,
+			expErr: deterrs.NewDetErr(
+				deterrs.InvalidValue,
+			),
+		},
 		{
 			name: "Попытка создать заявку без имени клиента",
 			pReq: &orders.PrimaryOrder{
@@ -73,7 +158,7 @@ func TestNewOrder(t *testing.T) {
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			req, err := c.pReq.CreateNewOrder()
+			req, err := c.pReq.CreateNewOrder(orders.Scheduling{})
 			testutils.AssertError(t, c.expErr, err)
 			testutils.ValidateOrder(t, c.expReq, req)
 		})
@@ -192,6 +277,21 @@ func TestAssign(t *testing.T) {
 			),
 			expErr: nil,
 		},
+		{
+			name: "Попытка назначить сотрудника на заявку на проверке",
+			req: testutils.NewTestOrder(
+				testutils.WithEmployee(nil),
+				testutils.WithStatus(orders.StatusQuarantined),
+			),
+			emp: employee,
+			expReq: testutils.NewTestOrder(
+				testutils.WithEmployee(nil),
+				testutils.WithStatus(orders.StatusQuarantined),
+			),
+			expErr: deterrs.NewDetErr(
+				deterrs.OrderActionNotPermittedByStatus,
+			),
+		},
 		{
 			name: "Попытка назначить сотрудника на отменённую заявку",
 			req: testutils.NewTestOrder(
@@ -398,13 +498,225 @@ func TestProgress(t *testing.T) {
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			err := c.req.Progress(c.empDesc)
+			err := c.req.Progress(&orders.VisitReport{EmployeeDescription: c.empDesc})
 			testutils.AssertError(t, c.expErr, err)
 			testutils.ValidateOrder(t, c.expReq, c.req)
 		})
 	}
 }
 
+func TestProgress_VisitReport(t *testing.T) {
+	hourAgo := time.Now().Add(-time.Hour)
+	twoHoursAgo := time.Now().Add(-2 * time.Hour)
+	inHour := time.Now().Add(time.Hour)
+
+	cases := []struct {
+		name   string
+		report *orders.VisitReport
+		expErr error
+	}{
+		{
+			name:   "Визит с фактическим временем и запчастями",
+			report: &orders.VisitReport{EmployeeDescription: "Заменён насос", ArrivedAt: &twoHoursAgo, DepartedAt: &hourAgo, PartsUsed: []orders.UsedPart{{Name: "Насос", Quantity: 1}}},
+		},
+		{
+			name:   "Попытка завершить визит в будущем",
+			report: &orders.VisitReport{DepartedAt: &inHour},
+			expErr: deterrs.NewDetErr(deterrs.InvalidValue),
+		},
+		{
+			name:   "Попытка указать прибытие позже отъезда",
+			report: &orders.VisitReport{ArrivedAt: &hourAgo, DepartedAt: &twoHoursAgo},
+			expErr: deterrs.NewDetErr(deterrs.InvalidValue),
+		},
+		{
+			name:   "Попытка указать запчасть без количества",
+			report: &orders.VisitReport{PartsUsed: []orders.UsedPart{{Name: "Насос"}}},
+			expErr: deterrs.NewDetErr(deterrs.InvalidValue),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ord := testutils.NewTestOrder(testutils.WithStatus(orders.StatusAssigned))
+			if err := ord.Schedule(&tomorrow); err != nil {
+				t.Fatal(err)
+			}
+
+			err := ord.Progress(c.report)
+			testutils.AssertError(t, c.expErr, err)
+			if c.expErr != nil {
+				if ord.Status != orders.StatusScheduled || ord.OpenVisit() == nil {
+					t.Errorf("rejected report must not change the order")
+				}
+				return
+			}
+
+			visit := ord.Visits[0]
+			if visit.DepartedAt != c.report.DepartedAt || visit.ArrivedAt != c.report.ArrivedAt ||
+				visit.Notes != c.report.EmployeeDescription || len(visit.PartsUsed) != len(c.report.PartsUsed) {
+				t.Errorf("visit does not match the report: %+v", visit)
+			}
+		})
+	}
+}
+
+func TestVisits(t *testing.T) {
+	ord := testutils.NewTestOrder(testutils.WithStatus(orders.StatusAssigned))
+
+	steps := []struct {
+		name      string
+		action    func() error
+		expStatus orders.Status
+		expVisits int
+		expOpen   bool
+	}{
+		{name: "Первый визит запланирован", action: func() error { return ord.Schedule(&tomorrow) }, expStatus: orders.StatusScheduled, expVisits: 1, expOpen: true},
+		{name: "Назначен другой сотрудник", action: func() error { return ord.Assign(&auth.Employee{ID: 2, Name: "Сидор Сидоров"}) }, expStatus: orders.StatusAssigned, expVisits: 1, expOpen: true},
+		{name: "Незавершённый визит переносится, а не дублируется", action: func() error { return ord.Schedule(&threeDaysLater) }, expStatus: orders.StatusScheduled, expVisits: 1, expOpen: true},
+		{name: "Первый визит завершён", action: func() error { return ord.Progress(&orders.VisitReport{EmployeeDescription: "Диагностика"}) }, expStatus: orders.StatusInProgress, expVisits: 1},
+		{name: "Второй визит запланирован", action: func() error { return ord.Schedule(&tomorrow) }, expStatus: orders.StatusScheduled, expVisits: 2, expOpen: true},
+		{name: "Второй визит завершён", action: func() error { return ord.Progress(&orders.VisitReport{EmployeeDescription: "Ремонт"}) }, expStatus: orders.StatusInProgress, expVisits: 2},
+		{name: "Заявка выполнена", action: ord.Complete, expStatus: orders.StatusDone, expVisits: 2},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			if err := step.action(); err != nil {
+				t.Fatal(err)
+			}
+			if ord.Status != step.expStatus {
+				t.Errorf("expected status %s, got %s", step.expStatus.ToString(), ord.Status.ToString())
+			}
+			if len(ord.Visits) != step.expVisits {
+				t.Errorf("expected %d visits, got %d", step.expVisits, len(ord.Visits))
+			}
+			if (ord.OpenVisit() != nil) != step.expOpen {
+				t.Errorf("expected open visit: %v", step.expOpen)
+			}
+		})
+	}
+
+	if ord.Visits[0].Notes != "Диагностика" || ord.Visits[1].Notes != "Ремонт" {
+		t.Errorf("notes of every visit must be kept, got %q and %q", ord.Visits[0].Notes, ord.Visits[1].Notes)
+	}
+	if ord.Visits[0].PlannedFor != &threeDaysLater || ord.Visits[0].Employee.ID != 2 {
+		t.Errorf("rescheduled visit must get the new date and employee")
+	}
+}
+
+func TestGeoPoint_DistanceTo(t *testing.T) {
+	moscow := orders.GeoPoint{Lat: 55.7558, Lon: 37.6173}
+	spb := orders.GeoPoint{Lat: 59.9343, Lon: 30.3351}
+
+	if d := moscow.DistanceTo(moscow); d != 0 {
+		t.Errorf("expected zero distance to itself, got %v", d)
+	}
+	if d := moscow.DistanceTo(spb); d < 630000 || d > 640000 {
+		t.Errorf("expected about 634 km between Moscow and Saint Petersburg, got %.0f m", d)
+	}
+}
+
+func TestCheckInOut(t *testing.T) {
+	address := orders.GeoPoint{Lat: 55.7558, Lon: 37.6173}
+	nearby := orders.GeoPoint{Lat: 55.7560, Lon: 37.6175}  // около 25 м
+	farAway := orders.GeoPoint{Lat: 55.7658, Lon: 37.6173} // около 1,1 км
+	hourAgo := time.Now().Add(-time.Hour)
+
+	cases := []struct {
+		name      string
+		location  *orders.GeoPoint
+		checkIn   *orders.Presence
+		checkOut  *orders.Presence
+		expErr    error
+		expFar    bool
+		expStatus orders.Status
+	}{
+		{
+			name:      "Отметки у адреса заявки",
+			location:  &address,
+			checkIn:   &orders.Presence{EmployeeID: 1, Position: nearby, At: &hourAgo},
+			checkOut:  &orders.Presence{EmployeeID: 1, Position: nearby},
+			expStatus: orders.StatusInProgress,
+		},
+		{
+			name:      "Прибытие далеко от адреса помечается",
+			location:  &address,
+			checkIn:   &orders.Presence{EmployeeID: 1, Position: farAway, At: &hourAgo},
+			checkOut:  &orders.Presence{EmployeeID: 1, Position: nearby},
+			expFar:    true,
+			expStatus: orders.StatusInProgress,
+		},
+		{
+			name:      "Адрес не геокодирован — расстояние не проверяется",
+			checkIn:   &orders.Presence{EmployeeID: 1, Position: farAway, At: &hourAgo},
+			checkOut:  &orders.Presence{EmployeeID: 1, Position: farAway},
+			expStatus: orders.StatusInProgress,
+		},
+		{
+			name:      "Попытка отметиться не назначенным сотрудником",
+			location:  &address,
+			checkIn:   &orders.Presence{EmployeeID: 2, Position: nearby},
+			expErr:    deterrs.NewDetErr(deterrs.EmployeeNotAssigned),
+			expStatus: orders.StatusScheduled,
+		},
+		{
+			name:      "Попытка отметиться с некорректными координатами",
+			checkIn:   &orders.Presence{EmployeeID: 1, Position: orders.GeoPoint{Lat: 91, Lon: 0}},
+			expErr:    deterrs.NewDetErr(deterrs.InvalidValue),
+			expStatus: orders.StatusScheduled,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ord := testutils.NewTestOrder(testutils.WithStatus(orders.StatusAssigned))
+			ord.Address.Location = c.location
+			if err := ord.Schedule(&tomorrow); err != nil {
+				t.Fatal(err)
+			}
+
+			c.checkIn.Radius = 300
+			err := ord.CheckIn(c.checkIn)
+			testutils.AssertError(t, c.expErr, err)
+			if c.expErr != nil {
+				if ord.Status != c.expStatus {
+					t.Errorf("expected status %s, got %s", c.expStatus.ToString(), ord.Status.ToString())
+				}
+				return
+			}
+			if ord.Status != orders.StatusInProgress {
+				t.Errorf("check-in must move the order to InProgress, got %s", ord.Status.ToString())
+			}
+			if err := ord.Complete(); err == nil {
+				t.Errorf("the order must not be completed while the employee is on site")
+			}
+
+			c.checkOut.Radius = 300
+			if err := ord.CheckOut(c.checkOut, &orders.VisitReport{EmployeeDescription: "Готово"}); err != nil {
+				t.Fatal(err)
+			}
+
+			visit := ord.Visits[0]
+			if visit.FarFromAddress != c.expFar {
+				t.Errorf("expected far_from_address %v, got %v (distance %v)", c.expFar, visit.FarFromAddress, visit.CheckInDistanceM)
+			}
+			if (visit.CheckInDistanceM != nil) != (c.location != nil) {
+				t.Errorf("distance must be known only for a geocoded address")
+			}
+			if ord.Status != c.expStatus || ord.OpenVisit() != nil {
+				t.Errorf("expected status %s without open visits, got %s", c.expStatus.ToString(), ord.Status.ToString())
+			}
+			if ord.WorkSeconds < 3590 || ord.WorkSeconds > 3610 {
+				t.Errorf("expected about an hour of work, got %d s", ord.WorkSeconds)
+			}
+			if visit.Notes != "Готово" {
+				t.Errorf("checkout must record the report, got %q", visit.Notes)
+			}
+		})
+	}
+}
+
 func TestComplete(t *testing.T) {
 	cases := []struct {
 		name   string
@@ -481,6 +793,46 @@ func TestComplete(t *testing.T) {
 	}
 }
 
+func TestRelease(t *testing.T) {
+	cases := []struct {
+		name   string
+		req    *orders.Order
+		expReq *orders.Order
+		expErr error
+	}{
+		{
+			name: "Заявка на проверке принимается как новая",
+			req: testutils.NewTestOrder(
+				testutils.WithStatus(orders.StatusQuarantined),
+			),
+			expReq: testutils.NewTestOrder(
+				testutils.WithStatus(orders.StatusNew),
+			),
+			expErr: nil,
+		},
+		{
+			name: "Попытка принять обычную новую заявку",
+			req: testutils.NewTestOrder(
+				testutils.WithStatus(orders.StatusNew),
+			),
+			expReq: testutils.NewTestOrder(
+				testutils.WithStatus(orders.StatusNew),
+			),
+			expErr: deterrs.NewDetErr(
+				deterrs.OrderActionNotPermittedByStatus,
+			),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.req.Release()
+			testutils.AssertError(t, c.expErr, err)
+			testutils.ValidateOrder(t, c.expReq, c.req)
+		})
+	}
+}
+
 func TestClose(t *testing.T) {
 	cases := []struct {
 		name   string
@@ -620,8 +972,9 @@ func TestCancel(t *testing.T) {
 
 func TestPatch(t *testing.T) {
 	patchedClientName := "Patched Cliend Name"
-	patchedClientPhone := "+72222222222"
-	patchedAddress := "Patched Test Address"
+	patchedClientPhone := "8 (922) 222-22-22"
+	invalidClientPhone := "+7 922 22"
+	patchedAddress := orders.Address{City: "Москва", Street: "Изменённая улица", House: "5"}
 	patchedCliendDescription := "Patched Cliend Descr"
 	patchedEmployeeDescription := "Patched Emp Descr"
 
@@ -644,13 +997,23 @@ func TestPatch(t *testing.T) {
 			},
 			expReq: testutils.NewTestOrder(
 				testutils.WithClientName(patchedClientName),
-				testutils.WithClientPhone(patchedClientPhone),
+				testutils.WithClientPhone("+79222222222"),
 				testutils.WithAddress(patchedAddress),
 				testutils.WithClientDescription(patchedCliendDescription),
 				testutils.WithEmployeeDescription(patchedEmployeeDescription),
 			),
 			expErr: nil,
 		},
+		{
+			name:          "Некорректный телефон",
+			req:           testutils.NewTestOrder(),
+			patchedFields: &orders.OrderPatcher{ClientPhone: &invalidClientPhone},
+			expReq:        testutils.NewTestOrder(),
+			expErr: deterrs.NewDetErr(
+				deterrs.InvalidValue,
+				deterrs.WithField("client phone"),
+			),
+		},
 		{
 			name: "Попытка модификации отменённой заявки",
 			req: testutils.NewTestOrder(
@@ -680,3 +1043,647 @@ func TestPatch(t *testing.T) {
 		})
 	}
 }
+
+func TestPatch_AddressLocation(t *testing.T) {
+	location := &orders.GeoPoint{Lat: 55.75, Lon: 37.62}
+
+	cases := []struct {
+		name         string
+		address      orders.Address
+		expLocation  *orders.GeoPoint
+		expRelocated bool
+	}{
+		{
+			name:        "Смена квартиры сохраняет координаты",
+			address:     orders.Address{City: "Москва", Street: "ул. примерная", House: "1", Apartment: "7"},
+			expLocation: location,
+		},
+		{
+			name:         "Смена дома сбрасывает координаты",
+			address:      orders.Address{City: "Москва", Street: "ул. Примерная", House: "3"},
+			expLocation:  nil,
+			expRelocated: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ord := testutils.NewTestOrder()
+			ord.Address.Location = location
+
+			if err := ord.Patch(&orders.OrderPatcher{Address: &c.address}); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(c.expLocation, ord.Address.Location) {
+				t.Errorf("Expected location %v, got %v", c.expLocation, ord.Address.Location)
+			}
+			if ord.Relocated() != c.expRelocated {
+				t.Errorf("Expected relocated %v, got %v", c.expRelocated, ord.Relocated())
+			}
+		})
+	}
+}
+
+func TestAddress_UnmarshalJSON(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+		exp  orders.Address
+	}{
+		{
+			name: "Структурированный адрес",
+			body: `{"city":"Москва","street":"Тестовая улица","house":"1","intercom":"12В"}`,
+			exp:  orders.Address{City: "Москва", Street: "Тестовая улица", House: "1", Intercom: "12В"},
+		},
+		{
+			name: "Адрес строкой от старых клиентов API",
+			body: `"Москва, Тестовая улица, 1"`,
+			exp:  orders.Address{Line: "Москва, Тестовая улица, 1"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var a orders.Address
+			if err := json.Unmarshal([]byte(c.body), &a); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(c.exp, a) {
+				t.Errorf("Expected %+v, got %+v", c.exp, a)
+			}
+		})
+	}
+}
+
+// Репозиторий для PlanRoute: остальные методы не вызываются
+type scheduledRepo struct {
+	orders.OrderRepository
+	scheduled []*orders.Order
+}
+
+func (r *scheduledRepo) GetScheduled(ctx context.Context, employeeID uint, from, to time.Time) ([]*orders.Order, error) {
+	return r.scheduled, nil
+}
+
+func TestSchedule_BusinessCalendar(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+	cal, err := calendar.New(moscow, 9*time.Hour, 18*time.Hour,
+		[]time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}, []string{"2026-03-09"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Пятница, 2026-03-06, 12:00 по Москве
+	clk := clock.NewManual(time.Date(2026, 3, 6, 12, 0, 0, 0, moscow))
+	at := func(day, hour int, loc *time.Location) *time.Time {
+		t := time.Date(2026, 3, day, hour, 0, 0, 0, loc)
+		return &t
+	}
+
+	cases := []struct {
+		name     string
+		timezone string
+		date     *time.Time
+		expDate  *time.Time
+		expErr   error
+	}{
+		{
+			name:    "Рабочее время",
+			date:    at(10, 10, moscow),
+			expDate: at(10, 10, moscow),
+		},
+		{
+			name:    "Весь рабочий день",
+			date:    at(10, 0, moscow),
+			expDate: at(10, 0, moscow),
+		},
+		{
+			name:   "Выходной",
+			date:   at(7, 10, moscow),
+			expErr: deterrs.NewDetErr(deterrs.InvalidValue),
+		},
+		{
+			name:   "Праздник",
+			date:   at(9, 0, moscow),
+			expErr: deterrs.NewDetErr(deterrs.InvalidValue),
+		},
+		{
+			name:   "После окончания рабочего дня",
+			date:   at(10, 19, moscow),
+			expErr: deterrs.NewDetErr(deterrs.InvalidValue),
+		},
+		{
+			name:   "Прошедшее время по часам сервиса",
+			date:   at(6, 11, moscow),
+			expErr: deterrs.NewDetErr(deterrs.InvalidValue),
+		},
+		{
+			name:     "Время без смещения — местное время клиента",
+			timezone: "Asia/Yekaterinburg",
+			date:     at(10, 9, orders.OrderLocal),
+			expDate:  at(10, 7, moscow),
+		},
+		{
+			name:     "Рабочие часы считаются по времени клиента",
+			timezone: "Asia/Yekaterinburg",
+			date:     at(10, 7, moscow),
+			expDate:  at(10, 7, moscow),
+		},
+		{
+			name:     "Ранний час по времени клиента",
+			timezone: "Asia/Yekaterinburg",
+			date:     at(10, 6, moscow),
+			expErr:   deterrs.NewDetErr(deterrs.InvalidValue),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ord := testutils.NewTestOrder(testutils.WithStatus(orders.StatusAssigned))
+			ord.Timezone = tc.timezone
+			ord.UseScheduling(orders.Scheduling{Clock: clk, Calendar: cal})
+
+			err := ord.Schedule(tc.date)
+			testutils.AssertError(t, tc.expErr, err)
+			if tc.expErr != nil {
+				return
+			}
+			if ord.ScheduledFor == nil || !ord.ScheduledFor.Equal(*tc.expDate) {
+				t.Errorf("Expected scheduled for %s, got %v", tc.expDate, ord.ScheduledFor)
+			}
+			if !ord.StatusChangedAt.Equal(clk.Now()) {
+				t.Errorf("Expected status change at %s, got %s", clk.Now(), ord.StatusChangedAt)
+			}
+		})
+	}
+}
+
+func TestPlanRoute(t *testing.T) {
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	at := func(h int) *time.Time {
+		t := day.Add(time.Duration(h) * time.Hour)
+		return &t
+	}
+	order := func(scheduledFor *time.Time, location *orders.GeoPoint) *orders.Order {
+		ord := testutils.NewTestOrder(testutils.WithScheduledFor(scheduledFor))
+		ord.ID = uuid.New()
+		ord.Address.Location = location
+		return ord
+	}
+
+	// Ближняя заявка назначена на 14:00, дальняя — на любое время дня, у третьей нет координат
+	near := order(at(14), &orders.GeoPoint{Lat: 55.75, Lon: 37.60})
+	far := order(&day, &orders.GeoPoint{Lat: 55.80, Lon: 37.60})
+	unknown := order(&day, nil)
+
+	svc := orders.NewOrderService(&scheduledRepo{scheduled: []*orders.Order{near, far, unknown}})
+	route, err := svc.PlanRoute(context.Background(), 1, day.Add(13*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if route.Date != "2026-03-02" || len(route.Stops) != 2 {
+		t.Fatalf("Expected 2 stops on 2026-03-02, got %+v", route)
+	}
+	if route.Stops[0].OrderID != far.ID || route.Stops[1].OrderID != near.ID {
+		t.Errorf("Expected the flexible visit before the 14:00 one, got %v, %v", route.Stops[0].OrderID, route.Stops[1].OrderID)
+	}
+	if !route.Stops[0].Arrival.Equal(day.Add(9*time.Hour)) || !route.Stops[1].StartAt.Equal(*at(14)) {
+		t.Errorf("Expected the day to start at 9:00 and the second visit at 14:00, got %s and %s", route.Stops[0].Arrival, route.Stops[1].StartAt)
+	}
+	if route.Stops[1].TravelSeconds == 0 || route.TravelSeconds != route.Stops[1].TravelSeconds || route.Late != 0 {
+		t.Errorf("Expected travel time between the stops, got %+v", route)
+	}
+	if len(route.Unrouted) != 1 || route.Unrouted[0] != unknown.ID {
+		t.Errorf("Expected the order without coordinates to be unrouted, got %v", route.Unrouted)
+	}
+}
+
+func TestParseSLARule(t *testing.T) {
+	cases := []struct {
+		name    string
+		spec    string
+		exp     orders.SLARule
+		wantErr bool
+	}{
+		{
+			name: "Срок в рабочих часах",
+			spec: "new:4h",
+			exp:  orders.SLARule{Status: orders.StatusNew, Within: 4 * time.Hour},
+		},
+		{
+			name: "Срок в рабочих днях для категории",
+			spec: "Done/Boiler:14d",
+			exp:  orders.SLARule{Status: orders.StatusDone, Category: "boiler", Workdays: 14},
+		},
+		{
+			name:    "Неизвестный статус",
+			spec:    "waiting:4h",
+			wantErr: true,
+		},
+		{
+			name:    "Заявка не покидает закрытый статус",
+			spec:    "paid:1d",
+			wantErr: true,
+		},
+		{
+			name:    "Некорректный срок",
+			spec:    "new:0d",
+			wantErr: true,
+		},
+		{
+			name:    "Нет срока",
+			spec:    "new",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule, err := orders.ParseSLARule(tc.spec)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("Expected error, got %+v", rule)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rule != tc.exp {
+				t.Errorf("Expected %+v, got %+v", tc.exp, rule)
+			}
+		})
+	}
+
+	if _, err := orders.ParseSLARules([]string{"new:4h", "New:2h"}); err == nil {
+		t.Error("Expected error for duplicate rules")
+	}
+}
+
+// Пн–пт с 9 до 18; 2026-03-02 — понедельник
+func newTestCalendar(t *testing.T) *calendar.Calendar {
+	t.Helper()
+	cal, err := calendar.New(time.UTC, 9*time.Hour, 18*time.Hour,
+		[]time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cal
+}
+
+func TestCheckSLA(t *testing.T) {
+	cal := newTestCalendar(t)
+	rules, err := orders.ParseSLARules([]string{"new:4h", "new/boiler:2h", "done:2d"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(day, hour int) *time.Time {
+		t := time.Date(2026, 3, day, hour, 0, 0, 0, time.UTC)
+		return &t
+	}
+
+	cases := []struct {
+		name        string
+		status      orders.Status
+		category    string
+		changedAt   *time.Time
+		now         *time.Time
+		expDeadline *time.Time
+	}{
+		{
+			name:      "Срок не истёк",
+			status:    orders.StatusNew,
+			changedAt: at(2, 10),
+			now:       at(2, 13),
+		},
+		{
+			name:        "Срок истёк",
+			status:      orders.StatusNew,
+			changedAt:   at(2, 10),
+			now:         at(2, 15),
+			expDeadline: at(2, 14),
+		},
+		{
+			name:        "Правило категории важнее общего",
+			status:      orders.StatusNew,
+			category:    "boiler",
+			changedAt:   at(2, 10),
+			now:         at(2, 13),
+			expDeadline: at(2, 12),
+		},
+		{
+			name:      "Нерабочее время не учитывается",
+			status:    orders.StatusNew,
+			changedAt: at(6, 17),
+			now:       at(8, 20),
+		},
+		{
+			name:        "Срок в рабочих днях переносится через выходные",
+			status:      orders.StatusDone,
+			changedAt:   at(6, 12),
+			now:         at(10, 13),
+			expDeadline: at(10, 12),
+		},
+		{
+			name:      "Для статуса нет правила",
+			status:    orders.StatusAssigned,
+			changedAt: at(2, 10),
+			now:       at(20, 10),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ord := testutils.NewTestOrder()
+			ord.Status, ord.Category, ord.StatusChangedAt = tc.status, tc.category, tc.changedAt
+
+			breach := ord.CheckSLA(rules, cal, *tc.now)
+			if tc.expDeadline == nil {
+				if breach != nil {
+					t.Fatalf("Expected no breach, got deadline %s", breach.Deadline)
+				}
+				return
+			}
+			if breach == nil {
+				t.Fatal("Expected breach, got none")
+			}
+			if !breach.Deadline.Equal(*tc.expDeadline) || breach.Status != tc.status || !breach.StatusChangedAt.Equal(*tc.changedAt) {
+				t.Errorf("Expected deadline %s, got %+v", tc.expDeadline, breach)
+			}
+		})
+	}
+}
+
+type slaRepo struct {
+	orders.OrderRepository
+	candidates []*orders.Order
+	breaches   map[uuid.UUID]*orders.SLABreach
+}
+
+func (r *slaRepo) SLACandidates(ctx context.Context, status orders.Status, changedBefore time.Time) ([]*orders.Order, error) {
+	var res []*orders.Order
+	for _, ord := range r.candidates {
+		if ord.Status == status && ord.StatusChangedAt.Before(changedBefore) && r.breaches[ord.ID] == nil {
+			res = append(res, ord)
+		}
+	}
+	return res, nil
+}
+
+func (r *slaRepo) RecordSLABreach(ctx context.Context, breach *orders.SLABreach) (bool, error) {
+	if r.breaches[breach.OrderID] != nil {
+		return false, nil
+	}
+	r.breaches[breach.OrderID] = breach
+	return true, nil
+}
+
+type eventLog struct {
+	events    []*orders.Event
+	appendErr error
+}
+
+func (l *eventLog) Append(ctx context.Context, ev *orders.Event) (int64, error) {
+	if l.appendErr != nil {
+		return 0, l.appendErr
+	}
+	l.events = append(l.events, ev)
+	return int64(len(l.events)), nil
+}
+
+func (l *eventLog) ListSince(ctx context.Context, afterID int64, filter orders.EventFilter, limit int) ([]*orders.Event, error) {
+	return l.events[afterID:], nil
+}
+
+func TestEvaluateSLA(t *testing.T) {
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) *time.Time {
+		t := now.Add(-d)
+		return &t
+	}
+	order := func(status orders.Status, changedAt *time.Time) *orders.Order {
+		ord := testutils.NewTestOrder()
+		ord.ID, ord.Status, ord.StatusChangedAt = uuid.New(), status, changedAt
+		return ord
+	}
+
+	stuck := order(orders.StatusNew, ago(5*time.Hour))
+	fresh := order(orders.StatusNew, ago(time.Hour))
+	unpaid := order(orders.StatusDone, ago(24*time.Hour))
+
+	rules, err := orders.ParseSLARules([]string{"new:4h", "done:14d"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &slaRepo{candidates: []*orders.Order{stuck, fresh, unpaid}, breaches: map[uuid.UUID]*orders.SLABreach{}}
+	events := &eventLog{}
+	svc := orders.NewOrderService(repo, orders.WithEventLog(events), orders.WithSLA(rules))
+
+	n, err := svc.EvaluateSLA(context.Background(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || repo.breaches[stuck.ID] == nil {
+		t.Fatalf("Expected only the stuck order to breach, got %d: %v", n, repo.breaches)
+	}
+	if len(events.events) != 1 || events.events[0].Type != orders.EventSLABreached || events.events[0].OrderID != stuck.ID {
+		t.Errorf("Expected one sla_breached event for the stuck order, got %+v", events.events)
+	}
+
+	// Повторная проверка не фиксирует нарушение второй раз
+	if n, err := svc.EvaluateSLA(context.Background(), now.Add(time.Minute)); err != nil || n != 0 {
+		t.Errorf("Expected no new breaches, got %d, %v", n, err)
+	}
+}
+
+// Одна заявка в памяти; cancelTx откатывает её изменения при ошибке
+type cancelRepo struct {
+	orders.OrderRepository
+	order orders.Order
+}
+
+func (r *cancelRepo) GetByID(ctx context.Context, id uuid.UUID) (*orders.Order, error) {
+	ord := r.order
+	return &ord, nil
+}
+
+func (r *cancelRepo) Cancel(ctx context.Context, id uuid.UUID, reason string) error {
+	return r.order.Cancel(reason)
+}
+
+type cancelTx struct {
+	repo *cancelRepo
+}
+
+func (tx cancelTx) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	saved := tx.repo.order
+	err := fn(ctx)
+	if err != nil {
+		tx.repo.order = saved
+	}
+	return err
+}
+
+func TestTransition_EventInTransaction(t *testing.T) {
+	cases := []struct {
+		name      string
+		appendErr error
+		expStatus orders.Status
+	}{
+		{name: "Событие записано вместе с изменением", expStatus: orders.StatusCanceled},
+		{name: "Событие не записано: изменение откатывается", appendErr: errors.New("db down"), expStatus: orders.StatusScheduled},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ord := testutils.NewTestOrder(testutils.WithEmployee(nil))
+			ord.ID = uuid.New()
+			repo := &cancelRepo{order: *ord}
+			events := &eventLog{appendErr: tc.appendErr}
+			svc := orders.NewOrderService(repo, orders.WithEventLog(events), orders.WithTransactor(cancelTx{repo: repo}))
+
+			err := svc.Cancel(context.Background(), ord.ID, "клиент передумал")
+			if !errors.Is(err, tc.appendErr) {
+				t.Fatalf("Expected %v, got %v", tc.appendErr, err)
+			}
+			if repo.order.Status != tc.expStatus {
+				t.Errorf("Expected status %s, got %s", tc.expStatus.ToString(), repo.order.Status.ToString())
+			}
+			if tc.appendErr == nil && (len(events.events) != 1 || events.events[0].Type != orders.EventCanceled) {
+				t.Errorf("Expected one canceled event, got %+v", events.events)
+			}
+		})
+	}
+}
+
+// Одна заявка в памяти для отметок сотрудника
+type visitRepo struct {
+	orders.OrderRepository
+	order orders.Order
+}
+
+func (r *visitRepo) GetByID(ctx context.Context, id uuid.UUID) (*orders.Order, error) {
+	ord := r.order
+	return &ord, nil
+}
+
+func (r *visitRepo) CheckIn(ctx context.Context, id uuid.UUID, p *orders.Presence) error {
+	return r.order.CheckIn(p)
+}
+
+func (r *visitRepo) GetVisits(ctx context.Context, id uuid.UUID) ([]*orders.Visit, error) {
+	return r.order.Visits, nil
+}
+
+func TestCheckIn_Service(t *testing.T) {
+	address := orders.GeoPoint{Lat: 55.7558, Lon: 37.6173}
+	farAway := orders.GeoPoint{Lat: 55.7658, Lon: 37.6173} // около 1,1 км
+
+	cases := []struct {
+		name       string
+		employeeID uint
+		expErr     error
+	}{
+		{name: "Назначенный сотрудник отмечается", employeeID: 1},
+		{
+			name:       "Не назначенный сотрудник не оставляет координат",
+			employeeID: 2,
+			expErr:     deterrs.NewDetErr(deterrs.EmployeeNotAssigned),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tomorrow := testutils.GetNDaysLater(1)
+			ord := testutils.NewTestOrder(testutils.WithStatus(orders.StatusAssigned))
+			ord.ID = uuid.New()
+			ord.Address.Location = &address
+			if err := ord.Schedule(&tomorrow); err != nil {
+				t.Fatal(err)
+			}
+			repo := &visitRepo{order: *ord}
+			svc := orders.NewOrderService(repo, orders.WithCheckInRadius(300))
+
+			presence := &orders.Presence{EmployeeID: tc.employeeID, Position: farAway}
+			visit, err := svc.CheckIn(context.Background(), ord.ID, presence)
+			testutils.AssertError(t, tc.expErr, err)
+			if presence.Radius != 0 {
+				t.Errorf("Expected the caller's presence to stay unchanged, got radius %v", presence.Radius)
+			}
+			if tc.expErr != nil && repo.order.Visits[0].CheckInLocation != nil {
+				t.Errorf("Expected no coordinates to be recorded, got %+v", repo.order.Visits[0].CheckInLocation)
+			}
+			if tc.expErr == nil && (visit == nil || !visit.FarFromAddress) {
+				t.Errorf("Expected the visit to be far from the address with the configured radius, got %+v", visit)
+			}
+		})
+	}
+}
+
+func TestSeenEvents(t *testing.T) {
+	seen := orders.NewSeenEvents()
+	for _, id := range []int64{5, 7} {
+		if !seen.Add(id) {
+			t.Fatalf("Expected event %d to be new", id)
+		}
+	}
+	// Событие с меньшим ID, зафиксированное позже, не отсекается
+	if !seen.Add(6) {
+		t.Error("Expected late event 6 to be delivered")
+	}
+	if seen.Add(7) || seen.Add(6) {
+		t.Error("Expected repeated events to be skipped")
+	}
+	if from := seen.ReplayFrom(); from != 0 {
+		t.Errorf("Expected replay from 0, got %d", from)
+	}
+
+	last := int64(10 * orders.EventsReplayWindow)
+	for id := int64(8); id <= last; id++ {
+		seen.Add(id)
+	}
+	if from := seen.ReplayFrom(); from != last-orders.EventsReplayWindow {
+		t.Errorf("Expected replay from %d, got %d", last-orders.EventsReplayWindow, from)
+	}
+	if seen.Add(last - 1) {
+		t.Error("Expected event inside the window to be remembered")
+	}
+}
+
+func TestNewClientErasureScope(t *testing.T) {
+	cases := []struct {
+		name     string
+		phone    string
+		expPhone string
+		expErr   error
+	}{
+		{
+			name:     "Телефон приводится к формату, в котором хранится в заявках",
+			phone:    "8 (111) 222-33-44",
+			expPhone: testutils.ClientPhone,
+		},
+		{
+			name:   "Пустой телефон",
+			phone:  "",
+			expErr: deterrs.NewDetErr(deterrs.EmptyField),
+		},
+		{
+			name:   "Некорректный телефон",
+			phone:  "+7111222334",
+			expErr: deterrs.NewDetErr(deterrs.InvalidValue),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			scope, err := orders.NewClientErasureScope(c.phone)
+			testutils.AssertError(t, c.expErr, err)
+			if scope.ClientPhone != c.expPhone {
+				t.Errorf("expected phone %q, got %q", c.expPhone, scope.ClientPhone)
+			}
+		})
+	}
+}
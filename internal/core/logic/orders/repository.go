@@ -2,81 +2,911 @@ package orders
 
 import (
 	"context"
+	"errors"
+	"log/slog"
 	"time"
 
+	"github.com/Owouwun/spkuznetsov/internal/logging"
+	"github.com/Owouwun/spkuznetsov/internal/tracing"
+	"github.com/Owouwun/spkuznetsov/pkg/routing"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type OrderRepository interface {
-	GetAll(ctx context.Context) ([]*Order, error)
+	GetAll(ctx context.Context, filter ListFilter) ([]*Order, error)
+	// Передаёт в fn заявки по фильтру в порядке ID, загружая их порциями по batchSize;
+	// ошибка fn прерывает обход
+	Each(ctx context.Context, filter ListFilter, batchSize int, fn func(*Order) error) error
+	// Ищет заявку и среди действующих, и в архиве
 	GetByID(ctx context.Context, id uuid.UUID) (*Order, error)
 	Create(ctx context.Context, order *Order) (uuid.UUID, error)
 	Update(ctx context.Context, order *Order) error
+	Release(ctx context.Context, id uuid.UUID) error
 	Preschedule(ctx context.Context, id uuid.UUID, scheduledFor *time.Time) error
 	Assign(ctx context.Context, id uuid.UUID, empID uint) error
 	Schedule(ctx context.Context, id uuid.UUID, scheduledFor *time.Time) error
-	Progress(ctx context.Context, id uuid.UUID, empDescr string) error
+	// Завершает текущий визит отчётом сотрудника
+	Progress(ctx context.Context, id uuid.UUID, report *VisitReport) error
+	// Отметки сотрудника о прибытии и отъезде; отъезд завершает текущий визит отчётом
+	CheckIn(ctx context.Context, id uuid.UUID, p *Presence) error
+	CheckOut(ctx context.Context, id uuid.UUID, p *Presence, report *VisitReport) error
 	Complete(ctx context.Context, id uuid.UUID) error
 	Close(ctx context.Context, id uuid.UUID) error
 	Cancel(ctx context.Context, id uuid.UUID, reason string) error
+	CountByStatus(ctx context.Context) (map[Status]int64, error)
+	// Заявки сотрудника в статусе Scheduled с визитом в промежутке [from, to)
+	GetScheduled(ctx context.Context, employeeID uint, from, to time.Time) ([]*Order, error)
+	// Визиты заявки в порядке создания
+	GetVisits(ctx context.Context, orderID uuid.UUID) ([]*Visit, error)
+	// Мягкое удаление; возвращает удалённую заявку
+	Delete(ctx context.Context, id uuid.UUID) (*Order, error)
+	// Переносит в архив не более limit оплаченных и отменённых заявок, статус которых
+	// не менялся с момента before; возвращает число перенесённых
+	Archive(ctx context.Context, before time.Time, limit int) (int, error)
+	// Обезличивает заявки (в т.ч. удалённые и архивные) из scope и в той же транзакции
+	// сохраняет запись журнала, заполняя её ID и OrderIDs. Если по сроку хранения
+	// обезличивать нечего, запись не сохраняется. Шаблоны повторяющихся заявок клиента удаляются
+	Anonymize(ctx context.Context, scope ErasureScope, audit *Erasure) error
+	// Не более limit действующих заявок, адрес которых ещё не геокодирован; заявки
+	// с неудачными попытками — после остальных, начиная с самой давней попытки
+	PendingGeocoding(ctx context.Context, limit int) ([]*Order, error)
+	// Отмечает неудачную попытку геокодирования: заявка остаётся в PendingGeocoding,
+	// но не задерживает очередь остальных
+	GeocodingFailed(ctx context.Context, id uuid.UUID) error
+	// Сохраняет координаты адреса заявки. nil означает, что адрес не найден:
+	// такая заявка тоже больше не попадает в PendingGeocoding. false, если координаты
+	// устарели: заявку обезличили, удалили или её адрес уже не совпадает с address
+	SetLocation(ctx context.Context, id uuid.UUID, address Address, location *GeoPoint) (bool, error)
+	// Действующие заявки в статусе status, перешедшие в него раньше changedBefore,
+	// нарушение SLA по которым в этом статусе ещё не зафиксировано
+	SLACandidates(ctx context.Context, status Status, changedBefore time.Time) ([]*Order, error)
+	// Фиксирует нарушение SLA; false, если оно уже было зафиксировано
+	RecordSLABreach(ctx context.Context, breach *SLABreach) (bool, error)
+}
+
+// Журнал событий заявок. Доставка событий подписчикам (в т.ч. на других репликах)
+// происходит через Broker после записи в журнал
+type EventRepository interface {
+	Append(ctx context.Context, ev *Event) (int64, error)
+	// Не более limit событий с ID больше afterID в порядке ID
+	ListSince(ctx context.Context, afterID int64, filter EventFilter, limit int) ([]*Event, error)
+}
+
+// Выполняет fn в одной транзакции. Репозитории заявок и журнала событий, вызванные
+// с ctx из fn, работают в этой транзакции: изменение заявки и событие о нём
+// сохраняются вместе или не сохраняются вовсе
+type Transactor interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Без транзакций: для хранилищ, в которых их нет
+type noTx struct{}
+
+func (noTx) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// Определение координат адреса внешним сервисом
+type Geocoder interface {
+	// Координаты адреса; nil без ошибки, если адрес не найден
+	Geocode(ctx context.Context, address Address) (*GeoPoint, error)
+}
+
+// Построение маршрута объезда; по умолчанию — эвристика из pkg/routing,
+// её можно заменить настоящим движком маршрутизации
+type RouteSolver interface {
+	Solve(ctx context.Context, stops []routing.Stop, opts routing.Options) (*routing.Plan, error)
+}
+
+// Вложения заявок (фото, документы): их файлы хранятся вне БД заявок
+type AttachmentEraser interface {
+	// Удаляет вложения заявок вместе с файлами
+	EraseOrders(ctx context.Context, orderIDs []uuid.UUID) error
+}
+
+// Получатель бизнес-метрик. Вызывается после успешного изменения заявки
+type MetricsRecorder interface {
+	OrderCreated()
+	// timeInStatus — сколько заявка провела в статусе from; 0, если момент входа в статус неизвестен
+	OrderTransitioned(action EventType, from, to Status, timeInStatus time.Duration)
+	OrderCanceled(reason string)
 }
 
 type OrderService struct {
-	repo OrderRepository
+	repo    OrderRepository
+	events  EventRepository
+	tx      Transactor
+	broker  *Broker
+	metrics MetricsRecorder
+	// Допустимое расстояние отметки сотрудника от адреса заявки, м; 0 — не проверяется
+	checkInRadius float64
+	geocoder      Geocoder
+	routeSolver   RouteSolver
+	routes        RouteSettings
+	slaRules      []SLARule
+	// Часы и рабочий календарь; по календарю отсчитываются сроки SLA и проверяется время работ
+	scheduling Scheduling
+	// Новые заявки, ожидающие геокодирования в RunGeocoder
+	geocodeQueue chan uuid.UUID
+	attachments  AttachmentEraser
+}
+
+// Сколько новых заявок может ждать геокодирования; остальные подберёт периодический проход
+const geocodeQueueSize = 256
+
+type OrderServiceOption func(*OrderService)
+
+func WithEventLog(events EventRepository) OrderServiceOption {
+	return func(s *OrderService) {
+		s.events = events
+	}
+}
+
+// WithTransactor задаёт транзакции, в которых изменение заявки сохраняется вместе с событием
+func WithTransactor(tx Transactor) OrderServiceOption {
+	return func(s *OrderService) {
+		s.tx = tx
+	}
+}
+
+func WithBroker(broker *Broker) OrderServiceOption {
+	return func(s *OrderService) {
+		s.broker = broker
+	}
+}
+
+func WithMetrics(metrics MetricsRecorder) OrderServiceOption {
+	return func(s *OrderService) {
+		s.metrics = metrics
+	}
+}
+
+func WithCheckInRadius(meters float64) OrderServiceOption {
+	return func(s *OrderService) {
+		s.checkInRadius = meters
+	}
+}
+
+// WithGeocoder включает геокодирование адресов заявок в RunGeocoder
+func WithGeocoder(geocoder Geocoder) OrderServiceOption {
+	return func(s *OrderService) {
+		s.geocoder = geocoder
+		s.geocodeQueue = make(chan uuid.UUID, geocodeQueueSize)
+	}
+}
+
+func WithRouteSettings(settings RouteSettings) OrderServiceOption {
+	return func(s *OrderService) {
+		s.routes = settings
+	}
+}
+
+func WithRouteSolver(solver RouteSolver) OrderServiceOption {
+	return func(s *OrderService) {
+		s.routeSolver = solver
+	}
+}
+
+// WithSLA задаёт сроки нахождения заявок в статусах, проверяемые EvaluateSLA
+func WithSLA(rules []SLARule) OrderServiceOption {
+	return func(s *OrderService) {
+		s.slaRules = rules
+	}
+}
+
+// WithScheduling задаёт часы и рабочий календарь компании. Репозиторий должен
+// получить те же условия: действия над заявками выполняются в нём
+func WithScheduling(scheduling Scheduling) OrderServiceOption {
+	return func(s *OrderService) {
+		s.scheduling = scheduling
+	}
+}
+
+// WithAttachmentEraser включает удаление вложений при обезличивании заявок
+func WithAttachmentEraser(eraser AttachmentEraser) OrderServiceOption {
+	return func(s *OrderService) {
+		s.attachments = eraser
+	}
+}
+
+func NewOrderService(repo OrderRepository, opts ...OrderServiceOption) *OrderService {
+	s := &OrderService{
+		repo:        repo,
+		tx:          noTx{},
+		broker:      NewBroker(),
+		routeSolver: routing.Heuristic{},
+		routes: RouteSettings{
+			DayStart:      9 * time.Hour,
+			ArrivalWindow: 2 * time.Hour,
+			VisitDuration: time.Hour,
+			SpeedKmh:      30,
+			DetourFactor:  1.4,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Записать событие об изменении заявки в журнал
+func (s *OrderService) recordEvent(ctx context.Context, order *Order, evType EventType) error {
+	if s.events == nil {
+		return nil
+	}
+
+	if order.Employee != nil {
+		ctx = logging.WithAttrs(ctx, slog.Uint64("employee_id", uint64(order.Employee.ID)))
+	}
+
+	var err error
+	ev := NewEvent(evType, order)
+	ev.ID, err = s.events.Append(ctx, ev)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to record order event", slog.String("event", string(evType)), slog.Any("error", err))
+		return err
+	}
+
+	slog.DebugContext(ctx, "order event recorded", slog.String("event", string(evType)), slog.Int64("event_id", ev.ID))
+	return nil
 }
 
-func NewOrderService(repo OrderRepository) *OrderService {
-	return &OrderService{
-		repo: repo,
+// Открыть спан метода сервиса заявок
+func startSpan(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "OrderService."+method, trace.WithAttributes(attrs...))
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 	}
+	span.End()
+}
+
+func orderIDAttr(id uuid.UUID) attribute.KeyValue {
+	return attribute.String("order.id", id.String())
 }
 
-func (s *OrderService) Create(ctx context.Context, pord *PrimaryOrder) (uuid.UUID, error) {
-	order, err := pord.CreateNewOrder()
+func orderStatusAttr(status Status) attribute.KeyValue {
+	return attribute.String("order.status", status.ToString())
+}
+
+// Выполнить действие над заявкой, записав результат в лог, трассу и журнал событий.
+// Действие и событие о нём сохраняются в одной транзакции
+func (s *OrderService) transition(ctx context.Context, method string, id uuid.UUID, evType EventType, action func(ctx context.Context) error, attrs ...slog.Attr) (err error) {
+	ctx, span := startSpan(ctx, method, orderIDAttr(id), attribute.String("order.action", string(evType)))
+	defer func() { endSpan(span, err) }()
+
+	ctx = logging.WithAttrs(ctx, append([]slog.Attr{slog.String("order_id", id.String())}, attrs...)...)
+
+	// Состояние до действия нужно только для метрик: из него берётся исходный статус и время в нём
+	var before *Order
+	if s.metrics != nil {
+		before, _ = s.repo.GetByID(ctx, id)
+	}
+
+	var after *Order
+	err = s.tx.InTx(ctx, func(ctx context.Context) error {
+		if err := action(ctx); err != nil {
+			return err
+		}
+		if s.events == nil && s.metrics == nil && !span.IsRecording() {
+			return nil
+		}
+
+		var err error
+		if after, err = s.repo.GetByID(ctx, id); err != nil {
+			return err
+		}
+		return s.recordEvent(ctx, after, evType)
+	})
 	if err != nil {
+		slog.WarnContext(ctx, "order action failed", slog.String("action", string(evType)), slog.Any("error", err))
+		return err
+	}
+
+	slog.InfoContext(ctx, "order action applied", slog.String("action", string(evType)))
+
+	if after != nil {
+		span.SetAttributes(orderStatusAttr(after.Status))
+		s.observeTransition(evType, before, after)
+	}
+	return nil
+}
+
+func (s *OrderService) observeTransition(evType EventType, before, after *Order) {
+	if s.metrics == nil || before == nil {
+		return
+	}
+
+	var timeInStatus time.Duration
+	if before.StatusChangedAt != nil && after.StatusChangedAt != nil {
+		timeInStatus = after.StatusChangedAt.Sub(*before.StatusChangedAt)
+	}
+	s.metrics.OrderTransitioned(evType, before.Status, after.Status, timeInStatus)
+
+	if after.Status == StatusCanceled {
+		s.metrics.OrderCanceled(after.CancelReason)
+	}
+}
+
+func (s *OrderService) Create(ctx context.Context, pord *PrimaryOrder) (_ uuid.UUID, err error) {
+	ctx, span := startSpan(ctx, "Create")
+	defer func() { endSpan(span, err) }()
+
+	order, err := pord.CreateNewOrder(s.scheduling)
+	if err != nil {
+		slog.WarnContext(ctx, "invalid new order", slog.Any("error", err))
 		return uuid.Nil, err
 	}
 
-	id, err := s.repo.Create(ctx, order)
+	var id uuid.UUID
+	err = s.tx.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		if id, err = s.repo.Create(ctx, order); err != nil {
+			slog.ErrorContext(ctx, "failed to create order", slog.Any("error", err))
+			return err
+		}
+		order.ID = id
+		return s.recordEvent(logging.WithAttrs(ctx, slog.String("order_id", id.String())), order, EventCreated)
+	})
 	if err != nil {
 		return uuid.Nil, err
 	}
+	span.SetAttributes(orderIDAttr(id), orderStatusAttr(order.Status))
+
+	ctx = logging.WithAttrs(ctx, slog.String("order_id", id.String()))
+	slog.InfoContext(ctx, "order created")
+
+	if s.metrics != nil {
+		s.metrics.OrderCreated()
+	}
 
+	if order.Address.Location == nil {
+		s.enqueueGeocoding(ctx, id)
+	}
 	return id, nil
 }
 
-func (s *OrderService) GetByID(ctx context.Context, id uuid.UUID) (*Order, error) {
-	return s.repo.GetByID(ctx, id)
+func (s *OrderService) GetByID(ctx context.Context, id uuid.UUID) (_ *Order, err error) {
+	ctx, span := startSpan(ctx, "GetByID", orderIDAttr(id))
+	defer func() { endSpan(span, err) }()
+
+	order, err := s.repo.GetByID(logging.WithAttrs(ctx, slog.String("order_id", id.String())), id)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(orderStatusAttr(order.Status))
+	return order, nil
+}
+
+func (s *OrderService) GetAll(ctx context.Context, filter ListFilter) (_ []*Order, err error) {
+	ctx, span := startSpan(ctx, "GetAll",
+		attribute.Bool("order.include_deleted", filter.IncludeDeleted),
+		attribute.Bool("order.overdue", filter.Overdue),
+	)
+	defer func() { endSpan(span, err) }()
+
+	ords, err := s.repo.GetAll(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("order.count", len(ords)))
+	return ords, nil
+}
+
+// Сколько заявок загружается за раз при выгрузке
+const exportBatchSize = 500
+
+// Export передаёт в fn заявки по фильтру, не загружая весь список в память
+func (s *OrderService) Export(ctx context.Context, filter ListFilter, fn func(*Order) error) (err error) {
+	ctx, span := startSpan(ctx, "Export",
+		attribute.Bool("order.include_deleted", filter.IncludeDeleted),
+		attribute.Bool("order.overdue", filter.Overdue),
+	)
+	defer func() { endSpan(span, err) }()
+
+	count := 0
+	err = s.repo.Each(ctx, filter, exportBatchSize, func(ord *Order) error {
+		count++
+		return fn(ord)
+	})
+	span.SetAttributes(attribute.Int("order.count", count))
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to export orders", slog.Int("exported", count), slog.Any("error", err))
+	}
+	return err
 }
 
-func (s *OrderService) GetAll(ctx context.Context) ([]*Order, error) {
-	return s.repo.GetAll(ctx)
+// Принять заявку, отложенную на проверку как похожую на спам
+func (s *OrderService) Release(ctx context.Context, id uuid.UUID) error {
+	return s.transition(ctx, "Release", id, EventReleased, func(ctx context.Context) error {
+		return s.repo.Release(ctx, id)
+	})
 }
 
 func (s *OrderService) Preschedule(ctx context.Context, id uuid.UUID, scheduledFor *time.Time) error {
-	return s.repo.Preschedule(ctx, id, scheduledFor)
+	return s.transition(ctx, "Preschedule", id, EventPrescheduled, func(ctx context.Context) error {
+		return s.repo.Preschedule(ctx, id, scheduledFor)
+	})
 }
 
 func (s *OrderService) Assign(ctx context.Context, id uuid.UUID, empID uint) error {
-	return s.repo.Assign(ctx, id, empID)
+	return s.transition(ctx, "Assign", id, EventAssigned, func(ctx context.Context) error {
+		return s.repo.Assign(ctx, id, empID)
+	}, slog.Uint64("employee_id", uint64(empID)))
 }
 
 func (s *OrderService) Schedule(ctx context.Context, id uuid.UUID, scheduledFor *time.Time) error {
-	return s.repo.Schedule(ctx, id, scheduledFor)
+	return s.transition(ctx, "Schedule", id, EventScheduled, func(ctx context.Context) error {
+		return s.repo.Schedule(ctx, id, scheduledFor)
+	})
 }
 
-func (s *OrderService) Progress(ctx context.Context, id uuid.UUID, empDescr string) error {
-	return s.repo.Progress(ctx, id, empDescr)
+func (s *OrderService) Progress(ctx context.Context, id uuid.UUID, report *VisitReport) error {
+	return s.transition(ctx, "Progress", id, EventProgressed, func(ctx context.Context) error {
+		return s.repo.Progress(ctx, id, report)
+	})
+}
+
+// Визиты заявки; заявка должна существовать (в т.ч. в архиве)
+func (s *OrderService) GetVisits(ctx context.Context, id uuid.UUID) (_ []*Visit, err error) {
+	ctx, span := startSpan(ctx, "GetVisits", orderIDAttr(id))
+	defer func() { endSpan(span, err) }()
+
+	ctx = logging.WithAttrs(ctx, slog.String("order_id", id.String()))
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	visits, err := s.repo.GetVisits(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("order.visits", len(visits)))
+	return visits, nil
+}
+
+// Маршрут сотрудника на день day (в часовом поясе day): порядок объезда заявок
+// с запланированными визитами и ожидаемое время в пути между ними
+func (s *OrderService) PlanRoute(ctx context.Context, employeeID uint, day time.Time) (_ *Route, err error) {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	ctx, span := startSpan(ctx, "PlanRoute",
+		attribute.Int64("employee.id", int64(employeeID)),
+		attribute.String("route.date", day.Format(time.DateOnly)))
+	defer func() { endSpan(span, err) }()
+
+	// День маршрута — календарная дата в часовом поясе компании
+	if cal := s.scheduling.Calendar; cal != nil {
+		y, m, d := day.Date()
+		day = time.Date(y, m, d, 0, 0, 0, 0, cal.Location())
+	}
+
+	ctx = logging.WithAttrs(ctx, slog.Uint64("employee_id", uint64(employeeID)))
+	ords, err := s.repo.GetScheduled(ctx, employeeID, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	stops, unrouted := routeStops(ords, day.Location(), s.routes)
+	plan, err := s.routeSolver.Solve(ctx, stops, routing.Options{
+		StartAt:      day.Add(s.routes.DayStart),
+		SpeedKmh:     s.routes.SpeedKmh,
+		DetourFactor: s.routes.DetourFactor,
+		StopDuration: s.routes.VisitDuration,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to plan route", slog.Any("error", err))
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("route.stops", len(plan.Visits)), attribute.Int("route.unrouted", len(unrouted)))
+
+	if len(unrouted) > 0 {
+		slog.WarnContext(ctx, "orders without coordinates left out of the route", slog.Int("unrouted", len(unrouted)))
+	}
+	return newRoute(employeeID, day, ords, plan, unrouted), nil
+}
+
+// Отметку сохраняет только назначенный на заявку сотрудник: Order.CheckIn проверяет его
+// до записи координат и возвращает EmployeeNotAssigned
+func (s *OrderService) CheckIn(ctx context.Context, id uuid.UUID, p *Presence) (*Visit, error) {
+	presence := s.presence(p)
+	err := s.transition(ctx, "CheckIn", id, EventCheckedIn, func(ctx context.Context) error {
+		return s.repo.CheckIn(ctx, id, presence)
+	}, slog.Uint64("employee_id", uint64(p.EmployeeID)))
+	if err != nil {
+		return nil, err
+	}
+	return s.lastVisit(ctx, id)
+}
+
+func (s *OrderService) CheckOut(ctx context.Context, id uuid.UUID, p *Presence, report *VisitReport) (*Visit, error) {
+	presence := s.presence(p)
+	err := s.transition(ctx, "CheckOut", id, EventCheckedOut, func(ctx context.Context) error {
+		return s.repo.CheckOut(ctx, id, presence, report)
+	}, slog.Uint64("employee_id", uint64(p.EmployeeID)))
+	if err != nil {
+		return nil, err
+	}
+	return s.lastVisit(ctx, id)
+}
+
+// Копия отметки с радиусом из конфигурации: отметку вызывающего сервис не меняет
+func (s *OrderService) presence(p *Presence) *Presence {
+	presence := *p
+	presence.Radius = s.checkInRadius
+	return &presence
+}
+
+// Визит, к которому относилась последняя отметка. Отметка далеко от адреса пишется в лог
+func (s *OrderService) lastVisit(ctx context.Context, id uuid.UUID) (*Visit, error) {
+	var visit *Visit
+	visits, err := s.repo.GetVisits(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range visits {
+		if v.ArrivedAt != nil && (visit == nil || v.ArrivedAt.After(*visit.ArrivedAt)) {
+			visit = v
+		}
+	}
+
+	if visit != nil && visit.FarFromAddress {
+		slog.WarnContext(ctx, "employee checked in far from the order address",
+			slog.String("order_id", id.String()),
+			slog.String("visit_id", visit.ID.String()),
+			slog.Float64("radius_m", s.checkInRadius))
+	}
+	return visit, nil
 }
 
 func (s *OrderService) Complete(ctx context.Context, id uuid.UUID) error {
-	return s.repo.Complete(ctx, id)
+	return s.transition(ctx, "Complete", id, EventCompleted, func(ctx context.Context) error {
+		return s.repo.Complete(ctx, id)
+	})
 }
 
 func (s *OrderService) Close(ctx context.Context, id uuid.UUID) error {
-	return s.repo.Close(ctx, id)
+	return s.transition(ctx, "Close", id, EventClosed, func(ctx context.Context) error {
+		return s.repo.Close(ctx, id)
+	})
 }
 
 func (s *OrderService) Cancel(ctx context.Context, id uuid.UUID, reason string) error {
-	return s.repo.Cancel(ctx, id, reason)
+	return s.transition(ctx, "Cancel", id, EventCanceled, func(ctx context.Context) error {
+		return s.repo.Cancel(ctx, id, reason)
+	})
+}
+
+// Мягко удалить заявку: она пропадает из выборок, но остаётся в БД и журнале событий
+func (s *OrderService) Delete(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := startSpan(ctx, "Delete", orderIDAttr(id))
+	defer func() { endSpan(span, err) }()
+
+	ctx = logging.WithAttrs(ctx, slog.String("order_id", id.String()))
+
+	var order *Order
+	err = s.tx.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		if order, err = s.repo.Delete(ctx, id); err != nil {
+			return err
+		}
+		return s.recordEvent(ctx, order, EventDeleted)
+	})
+	if err != nil {
+		slog.WarnContext(ctx, "failed to delete order", slog.Any("error", err))
+		return err
+	}
+	span.SetAttributes(orderStatusAttr(order.Status))
+	slog.InfoContext(ctx, "order deleted")
+	return nil
+}
+
+// Перенести в архив оплаченные и отменённые заявки, статус которых не менялся с момента before.
+// Заявки переносятся порциями по batchSize, чтобы не держать долгих блокировок
+func (s *OrderService) Archive(ctx context.Context, before time.Time, batchSize int) (total int, err error) {
+	ctx, span := startSpan(ctx, "Archive", attribute.String("order.archive_before", before.Format(time.RFC3339)))
+	defer func() {
+		span.SetAttributes(attribute.Int("order.count", total))
+		endSpan(span, err)
+	}()
+
+	for {
+		n, err := s.repo.Archive(ctx, before, batchSize)
+		total += n
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to archive orders", slog.Int("archived", total), slog.Any("error", err))
+			return total, err
+		}
+		if n < batchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		slog.InfoContext(ctx, "orders archived", slog.Int("archived", total), slog.Time("before", before))
+	}
+	return total, nil
+}
+
+// Обезличить все заявки клиента по его запросу (152-ФЗ, GDPR).
+// Статусы, даты и сотрудник сохраняются для статистики
+func (s *OrderService) EraseClient(ctx context.Context, clientPhone string) (_ *Erasure, err error) {
+	ctx, span := startSpan(ctx, "EraseClient")
+	defer func() { endSpan(span, err) }()
+
+	scope, err := NewClientErasureScope(clientPhone)
+	if err != nil {
+		slog.WarnContext(ctx, "invalid erasure request", slog.Any("error", err))
+		return nil, err
+	}
+
+	audit := &Erasure{
+		Reason:    ErasureByRequest,
+		RequestID: logging.RequestID(ctx),
+	}
+	if err := s.anonymize(ctx, scope, audit); err != nil {
+		slog.ErrorContext(ctx, "failed to erase client data", slog.Any("error", err))
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("order.count", len(audit.OrderIDs)))
+
+	slog.InfoContext(ctx, "client data erased", slog.Int64("erasure_id", audit.ID), slog.Int("orders", len(audit.OrderIDs)))
+	return audit, nil
+}
+
+// Обезличить закрытые заявки, статус которых не менялся с момента before.
+// Каждая порция из batchSize заявок журналируется отдельной записью
+func (s *OrderService) ApplyRetention(ctx context.Context, before time.Time, batchSize int) (total int, err error) {
+	ctx, span := startSpan(ctx, "ApplyRetention", attribute.String("order.retention_before", before.Format(time.RFC3339)))
+	defer func() {
+		span.SetAttributes(attribute.Int("order.count", total))
+		endSpan(span, err)
+	}()
+
+	for {
+		audit := &Erasure{Reason: ErasureByRetention}
+		scope := ErasureScope{ClosedBefore: before, Limit: batchSize}
+		if err := s.anonymize(ctx, scope, audit); err != nil {
+			slog.ErrorContext(ctx, "failed to anonymize expired orders", slog.Int("anonymized", total), slog.Any("error", err))
+			return total, err
+		}
+		total += len(audit.OrderIDs)
+		if len(audit.OrderIDs) < batchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		slog.InfoContext(ctx, "expired orders anonymized", slog.Int("anonymized", total), slog.Time("before", before))
+	}
+	return total, nil
+}
+
+// Обезличить заявки из scope и удалить их вложения. Если вложения удалить не удалось,
+// обезличивание откатывается и повторится целиком: иначе файлы заявок остались бы навсегда
+func (s *OrderService) anonymize(ctx context.Context, scope ErasureScope, audit *Erasure) error {
+	if s.attachments == nil {
+		return s.repo.Anonymize(ctx, scope, audit)
+	}
+	return s.tx.InTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.Anonymize(ctx, scope, audit); err != nil {
+			return err
+		}
+		if len(audit.OrderIDs) == 0 {
+			return nil
+		}
+		return s.attachments.EraseOrders(ctx, audit.OrderIDs)
+	})
+}
+
+// Зафиксировать нарушения SLA на момент now и записать по каждому новому нарушению
+// событие EventSLABreached. Нарушение фиксируется один раз на каждое пребывание заявки в статусе
+func (s *OrderService) EvaluateSLA(ctx context.Context, now time.Time) (total int, err error) {
+	ctx, span := startSpan(ctx, "EvaluateSLA")
+	defer func() {
+		span.SetAttributes(attribute.Int("order.count", total))
+		endSpan(span, err)
+	}()
+
+	// Кандидаты выбираются по статусу с запасом — по самому короткому сроку для статуса;
+	// точный срок зависит от категории и рабочего календаря
+	shortest := make(map[Status]time.Duration)
+	for _, r := range s.slaRules {
+		if d, ok := shortest[r.Status]; !ok || r.minDuration() < d {
+			shortest[r.Status] = r.minDuration()
+		}
+	}
+
+	for _, status := range Statuses {
+		d, ok := shortest[status]
+		if !ok {
+			continue
+		}
+		candidates, err := s.repo.SLACandidates(ctx, status, now.Add(-d))
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to select SLA candidates", slog.String("status", status.ToString()), slog.Any("error", err))
+			return total, err
+		}
+
+		for _, order := range candidates {
+			breach := order.CheckSLA(s.slaRules, s.scheduling.Calendar, now)
+			if breach == nil {
+				continue
+			}
+			octx := logging.WithAttrs(ctx, slog.String("order_id", order.ID.String()))
+			recorded := false
+			err := s.tx.InTx(octx, func(ctx context.Context) error {
+				var err error
+				if recorded, err = s.repo.RecordSLABreach(ctx, breach); err != nil || !recorded {
+					return err
+				}
+				return s.recordEvent(ctx, order, EventSLABreached)
+			})
+			if err != nil {
+				slog.ErrorContext(octx, "failed to record SLA breach", slog.Any("error", err))
+				return total, err
+			}
+			if !recorded {
+				continue
+			}
+			total++
+			slog.WarnContext(octx, "order SLA breached", slog.String("status", status.ToString()), slog.Time("deadline", breach.Deadline))
+		}
+	}
+	return total, nil
+}
+
+// Поставить заявку в очередь на геокодирование, не дожидаясь его
+func (s *OrderService) enqueueGeocoding(ctx context.Context, id uuid.UUID) {
+	if s.geocodeQueue == nil {
+		return
+	}
+
+	select {
+	case s.geocodeQueue <- id:
+	default:
+		slog.WarnContext(ctx, "geocoding queue is full, the order is left for the next pass")
+	}
+}
+
+// Геокодировать адреса заявок в фоне: новые заявки — сразу после оформления, остальные
+// (оформленные до включения геокодера или не геокодированные из-за ошибки) — порциями
+// по batchSize раз в interval. Работает до отмены ctx
+func (s *OrderService) RunGeocoder(ctx context.Context, interval time.Duration, batchSize int) error {
+	if s.geocoder == nil {
+		return nil
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.GeocodePending(ctx, batchSize); err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "failed to geocode pending orders", slog.Any("error", err))
+		}
+
+	queue:
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case id := <-s.geocodeQueue:
+				// Ошибка уже в логе; заявка останется в PendingGeocoding до следующего прохода
+				_ = s.geocodeByID(ctx, id)
+			case <-ticker.C:
+				break queue
+			}
+		}
+	}
+}
+
+// Геокодировать не более batchSize заявок без координат; возвращает число обработанных.
+// Ошибка по одной заявке не останавливает остальные
+func (s *OrderService) GeocodePending(ctx context.Context, batchSize int) (done int, err error) {
+	ctx, span := startSpan(ctx, "GeocodePending")
+	defer func() {
+		span.SetAttributes(attribute.Int("order.count", done))
+		endSpan(span, err)
+	}()
+
+	pending, err := s.repo.PendingGeocoding(ctx, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var errs []error
+	for _, order := range pending {
+		if err := s.geocode(ctx, order); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		done++
+	}
+
+	if done > 0 {
+		slog.InfoContext(ctx, "pending orders geocoded", slog.Int("geocoded", done), slog.Int("failed", len(errs)))
+	}
+	return done, errors.Join(errs...)
+}
+
+// Геокодировать адрес новой заявки из очереди
+func (s *OrderService) geocodeByID(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := startSpan(ctx, "Geocode", orderIDAttr(id))
+	defer func() { endSpan(span, err) }()
+
+	order, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to load order for geocoding", slog.String("order_id", id.String()), slog.Any("error", err))
+		return err
+	}
+	return s.geocode(ctx, order)
+}
+
+// Геокодировать адрес заявки и сохранить координаты
+func (s *OrderService) geocode(ctx context.Context, order *Order) error {
+	ctx = logging.WithAttrs(ctx, slog.String("order_id", order.ID.String()))
+
+	var location *GeoPoint
+	if order.Address.Query() != "" {
+		var err error
+		location, err = s.geocoder.Geocode(ctx, order.Address)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to geocode order address", slog.Any("error", err))
+			if err := s.repo.GeocodingFailed(ctx, order.ID); err != nil {
+				slog.ErrorContext(ctx, "failed to save geocoding attempt", slog.Any("error", err))
+			}
+			return err
+		}
+		if location != nil && !location.isValid() {
+			slog.WarnContext(ctx, "geocoder returned invalid coordinates", slog.Float64("lat", location.Lat), slog.Float64("lon", location.Lon))
+			location = nil
+		}
+	}
+
+	saved, err := s.repo.SetLocation(ctx, order.ID, order.Address, location)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to save order location", slog.Any("error", err))
+		return err
+	}
+	if !saved {
+		// Адрес изменился, пока работал геокодер: новый адрес геокодируется отдельно
+		slog.DebugContext(ctx, "order changed during geocoding, location skipped")
+		return nil
+	}
+
+	if location == nil {
+		slog.WarnContext(ctx, "order address not found by geocoder")
+	} else {
+		slog.DebugContext(ctx, "order address geocoded")
+	}
+	return nil
+}
+
+// Количество заявок в каждом статусе
+func (s *OrderService) CountByStatus(ctx context.Context) (_ map[Status]int64, err error) {
+	ctx, span := startSpan(ctx, "CountByStatus")
+	defer func() { endSpan(span, err) }()
+
+	return s.repo.CountByStatus(ctx)
+}
+
+func (s *OrderService) Subscribe(filter EventFilter) (<-chan *Event, func()) {
+	return s.broker.Subscribe(filter)
+}
+
+// Не более limit событий с ID больше afterID в порядке ID
+func (s *OrderService) EventsSince(ctx context.Context, afterID int64, filter EventFilter, limit int) (_ []*Event, err error) {
+	ctx, span := startSpan(ctx, "EventsSince", attribute.Int64("event.after_id", afterID))
+	defer func() { endSpan(span, err) }()
+
+	if s.events == nil {
+		return nil, nil
+	}
+	return s.events.ListSince(ctx, afterID, filter, limit)
 }
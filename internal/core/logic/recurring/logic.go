@@ -0,0 +1,323 @@
+package recurring
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	deterrs "github.com/Owouwun/spkuznetsov/internal/errors"
+	"github.com/Owouwun/spkuznetsov/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// NewDate — календарная дата момента t в его часовом поясе
+func NewDate(t time.Time) Date {
+	return Date{time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)}
+}
+
+// ParseDate разбирает дату вида 2026-03-02
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return Date{}, err
+	}
+	return Date{t}, nil
+}
+
+func (d Date) String() string {
+	return d.Format(time.DateOnly)
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseDate(s)
+	if err != nil {
+		return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", s)
+	}
+	*d = parsed
+	return nil
+}
+
+// Полночь даты в местном времени заявки: заявка планируется на этот день без точного времени
+func (d Date) OrderLocal() time.Time {
+	return time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, orders.OrderLocal)
+}
+
+// ParseRule разбирает правило повторения в формате RRULE, например "FREQ=YEARLY",
+// "FREQ=MONTHLY;INTERVAL=6;COUNT=4" или "FREQ=MONTHLY;INTERVAL=3;UNTIL=20301231"
+func ParseRule(s string) (Rule, error) {
+	invalid := func(err error) error {
+		return deterrs.NewDetErr(
+			deterrs.InvalidValue,
+			deterrs.WithField("rrule"),
+			deterrs.WithOriginalError(err),
+		)
+	}
+
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return Rule{}, deterrs.NewDetErr(
+			deterrs.EmptyField,
+			deterrs.WithField("rrule"),
+		)
+	}
+
+	rule := Rule{Interval: 1}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		key = strings.ToUpper(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if !ok || value == "" {
+			return Rule{}, invalid(fmt.Errorf("expected KEY=VALUE, got %q", part))
+		}
+		if seen[key] {
+			return Rule{}, invalid(fmt.Errorf("duplicate %s", key))
+		}
+		seen[key] = true
+
+		switch key {
+		case "FREQ":
+			rule.Freq = Frequency(strings.ToUpper(value))
+			if rule.Freq != FreqMonthly && rule.Freq != FreqYearly {
+				return Rule{}, invalid(fmt.Errorf("unsupported FREQ %q, expected MONTHLY or YEARLY", value))
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return Rule{}, invalid(fmt.Errorf("INTERVAL must be a positive integer, got %q", value))
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return Rule{}, invalid(fmt.Errorf("COUNT must be a positive integer, got %q", value))
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, err := parseUntil(value)
+			if err != nil {
+				return Rule{}, invalid(err)
+			}
+			rule.Until = &until
+		default:
+			return Rule{}, invalid(fmt.Errorf("unsupported rule part %s", key))
+		}
+	}
+
+	if rule.Freq == "" {
+		return Rule{}, invalid(errors.New("FREQ is required"))
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return Rule{}, invalid(errors.New("COUNT and UNTIL are mutually exclusive"))
+	}
+	return rule, nil
+}
+
+// UNTIL принимается как дата (20301231 или 2030-12-31) или момент UTC (20301231T000000Z)
+func parseUntil(value string) (Date, error) {
+	for _, layout := range []string{"20060102", time.DateOnly, "20060102T150405Z"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return NewDate(t), nil
+		}
+	}
+	return Date{}, fmt.Errorf("invalid UNTIL %q, expected YYYYMMDD", value)
+}
+
+// String — правило в каноническом виде RRULE
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Дата повторения с номером n (с нуля) или nil, если правило столько повторений не допускает.
+// День месяца берётся из start; в коротких месяцах — последний день месяца (31 января → 28 февраля)
+func (r Rule) occurrence(start Date, n int) *Date {
+	if r.Count > 0 && n >= r.Count {
+		return nil
+	}
+
+	months := n * r.Interval
+	if r.Freq == FreqYearly {
+		months *= 12
+	}
+	first := time.Date(start.Year(), start.Month()+time.Month(months), 1, 0, 0, 0, 0, time.UTC)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	date := Date{first.AddDate(0, 0, min(start.Day(), lastDay)-1)}
+
+	if r.Until != nil && date.After(r.Until.Time) {
+		return nil
+	}
+	return &date
+}
+
+// Оформить шаблон повторяющейся заявки. Данные заявки проверяются так же, как при оформлении
+// обычной заявки; первое повторение не может быть раньше today
+func (pt *PrimaryTemplate) CreateNewTemplate(today Date) (*Template, error) {
+	rule, err := ParseRule(pt.RRule)
+	if err != nil {
+		return nil, err
+	}
+
+	if pt.Start.IsZero() {
+		return nil, deterrs.NewDetErr(
+			deterrs.EmptyField,
+			deterrs.WithField("start"),
+		)
+	}
+	if pt.Start.Before(today.Time) {
+		return nil, deterrs.NewDetErr(
+			deterrs.InvalidValue,
+			deterrs.WithField("start"),
+			deterrs.WithOriginalError(errors.New("can't be past")),
+		)
+	}
+	first := rule.occurrence(pt.Start, 0)
+	if first == nil {
+		return nil, deterrs.NewDetErr(
+			deterrs.InvalidValue,
+			deterrs.WithField("rrule"),
+			deterrs.WithOriginalError(errors.New("UNTIL is before start")),
+		)
+	}
+
+	// Заявка по шаблону проверяется и нормализуется (телефон, адрес, вид работ) заранее,
+	// чтобы повторения не отклонялись из-за данных клиента
+	ord, err := pt.Order.CreateNewOrder(orders.Scheduling{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Template{
+		ID: uuid.New(),
+		Order: orders.PrimaryOrder{
+			ClientName:        ord.ClientName,
+			ClientPhone:       ord.ClientPhone,
+			Address:           ord.Address,
+			ClientDescription: ord.ClientDescription,
+			Category:          ord.Category,
+			Timezone:          ord.Timezone,
+		},
+		RRule:       rule.String(),
+		Start:       pt.Start,
+		Preschedule: pt.Preschedule,
+		Status:      StatusActive,
+		NextOn:      first,
+	}, nil
+}
+
+func (t *Template) rule() (Rule, error) {
+	rule, err := ParseRule(t.RRule)
+	if err != nil {
+		return Rule{}, fmt.Errorf("template %s: %w", t.ID, err)
+	}
+	return rule, nil
+}
+
+// Due — пора ли создать заявку на следующее повторение, если заявки создаются до даты horizon включительно
+func (t *Template) Due(horizon Date) bool {
+	return t.Status == StatusActive && t.NextOn != nil && !t.NextOn.After(horizon.Time)
+}
+
+// Заявка на следующее повторение
+func (t *Template) NewOrder() *orders.PrimaryOrder {
+	pord := t.Order
+	pord.TemplateID = &t.ID
+	return &pord
+}
+
+// Перейти к следующему повторению; после последнего шаблон завершается
+func (t *Template) Advance() error {
+	rule, err := t.rule()
+	if err != nil {
+		return err
+	}
+
+	t.Occurrences++
+	t.NextOn = rule.occurrence(t.Start, t.Occurrences)
+	if t.NextOn == nil {
+		t.Status = StatusEnded
+	}
+	return nil
+}
+
+// Приостановить создание заявок
+func (t *Template) Pause() error {
+	if t.Status != StatusActive {
+		return deterrs.NewDetErr(
+			deterrs.TemplateActionNotPermittedByStatus,
+		)
+	}
+
+	t.Status = StatusPaused
+	return nil
+}
+
+// Возобновить создание заявок. Повторения, дата которых прошла за время паузы
+// (раньше today), пропускаются
+func (t *Template) Resume(today Date) error {
+	if t.Status != StatusPaused {
+		return deterrs.NewDetErr(
+			deterrs.TemplateActionNotPermittedByStatus,
+		)
+	}
+
+	t.Status = StatusActive
+	for t.Status == StatusActive && t.NextOn.Before(today.Time) {
+		if err := t.Advance(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Завершить повторение досрочно. Уже созданные заявки не меняются
+func (t *Template) End() error {
+	if t.Status == StatusEnded {
+		return deterrs.NewDetErr(
+			deterrs.TemplateActionNotPermittedByStatus,
+		)
+	}
+
+	t.Status = StatusEnded
+	t.NextOn = nil
+	return nil
+}
+
+// Привести телефон клиента к виду, в котором он хранится в шаблонах
+func (f *ListFilter) normalize() error {
+	if f.ClientPhone == "" {
+		return nil
+	}
+
+	stdPN, err := utils.StandartizePhoneNumber(f.ClientPhone)
+	if err != nil {
+		return deterrs.NewDetErr(
+			deterrs.InvalidValue,
+			deterrs.WithField("client phone"),
+			deterrs.WithOriginalError(err),
+		)
+	}
+	f.ClientPhone = stdPN
+	return nil
+}
@@ -0,0 +1,77 @@
+package recurring
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/google/uuid"
+)
+
+// Частота повторения (FREQ в RRULE)
+type Frequency string
+
+const (
+	FreqMonthly Frequency = "MONTHLY"
+	FreqYearly  Frequency = "YEARLY"
+)
+
+// Правило повторения — подмножество RRULE (RFC 5545): FREQ, INTERVAL, COUNT и UNTIL
+type Rule struct {
+	Freq Frequency
+	// Каждые Interval месяцев или лет
+	Interval int
+	// Сколько всего повторений; 0 — без ограничения
+	Count int
+	// Последняя допустимая дата повторения; nil — без ограничения
+	Until *Date
+}
+
+type Status string
+
+const (
+	StatusActive Status = "active"
+	StatusPaused Status = "paused"
+	StatusEnded  Status = "ended"
+)
+
+// Календарная дата без времени и часового пояса (2026-03-02). Хранится как полночь UTC
+type Date struct {
+	time.Time
+}
+
+// Шаблон повторяющейся заявки (например, ежегодное обслуживание котла по договору).
+// По шаблону заранее создаются обычные заявки на каждую дату повторения
+type PrimaryTemplate struct {
+	Order orders.PrimaryOrder `json:"order"`
+	// Правило повторения, например FREQ=YEARLY или FREQ=MONTHLY;INTERVAL=6;COUNT=4
+	RRule string `json:"rrule"`
+	// Дата первого повторения
+	Start Date `json:"start" swaggertype:"string" format:"date"`
+	// Создавать заявки с предварительной датой работ — днём повторения
+	Preschedule bool `json:"preschedule"`
+}
+
+type Template struct {
+	ID uuid.UUID `json:"id"`
+	// Клиент, адрес и описание работ для создаваемых заявок
+	Order       orders.PrimaryOrder `json:"order"`
+	RRule       string              `json:"rrule"`
+	Start       Date                `json:"start" swaggertype:"string" format:"date"`
+	Preschedule bool                `json:"preschedule"`
+	Status      Status              `json:"status"`
+	// Дата следующего повторения; nil у завершённого шаблона
+	NextOn *Date `json:"next_on,omitempty" swaggertype:"string" format:"date"`
+	// Сколько повторений пройдено: по ним созданы заявки или они пропущены на паузе
+	Occurrences int       `json:"occurrences"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Параметры выборки шаблонов
+type ListFilter struct {
+	// Только шаблоны клиента с этим телефоном
+	ClientPhone string
+}
+
+// Шаблон не найден
+var ErrNotFound = errors.New("recurring order template not found")
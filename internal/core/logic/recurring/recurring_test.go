@@ -0,0 +1,443 @@
+package recurring_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/recurring"
+	deterrs "github.com/Owouwun/spkuznetsov/internal/errors"
+	"github.com/Owouwun/spkuznetsov/pkg/clock"
+	"github.com/google/uuid"
+)
+
+func date(s string) recurring.Date {
+	d, err := recurring.ParseDate(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+var client = orders.PrimaryOrder{
+	ClientName:  "Иван",
+	ClientPhone: "8 (911) 222-33-44",
+	Address:     orders.Address{City: "Москва", Street: "Ленина", House: "1"},
+	Category:    " Boiler ",
+}
+
+func TestParseRule(t *testing.T) {
+	cases := []struct {
+		name   string
+		rule   string
+		expStr string
+		expErr error
+	}{
+		{name: "Ежегодно", rule: "FREQ=YEARLY", expStr: "FREQ=YEARLY"},
+		{name: "Префикс RRULE и нижний регистр", rule: "RRULE:freq=monthly;interval=6", expStr: "FREQ=MONTHLY;INTERVAL=6"},
+		{name: "Ограничение числом повторений", rule: "FREQ=MONTHLY;INTERVAL=3;COUNT=4", expStr: "FREQ=MONTHLY;INTERVAL=3;COUNT=4"},
+		{name: "Ограничение датой", rule: "FREQ=YEARLY;UNTIL=20301231T000000Z", expStr: "FREQ=YEARLY;UNTIL=20301231"},
+		{name: "Пустое правило", rule: " ", expErr: deterrs.NewDetErr(deterrs.EmptyField)},
+		{name: "Неподдерживаемая частота", rule: "FREQ=DAILY", expErr: deterrs.NewDetErr(deterrs.InvalidValue)},
+		{name: "Нет частоты", rule: "INTERVAL=2", expErr: deterrs.NewDetErr(deterrs.InvalidValue)},
+		{name: "Нулевой интервал", rule: "FREQ=MONTHLY;INTERVAL=0", expErr: deterrs.NewDetErr(deterrs.InvalidValue)},
+		{name: "COUNT вместе с UNTIL", rule: "FREQ=MONTHLY;COUNT=2;UNTIL=20301231", expErr: deterrs.NewDetErr(deterrs.InvalidValue)},
+		{name: "Неподдерживаемая часть", rule: "FREQ=MONTHLY;BYDAY=MO", expErr: deterrs.NewDetErr(deterrs.InvalidValue)},
+		{name: "Повтор части", rule: "FREQ=MONTHLY;FREQ=YEARLY", expErr: deterrs.NewDetErr(deterrs.InvalidValue)},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule, err := recurring.ParseRule(tc.rule)
+			if tc.expErr != nil {
+				if !errors.Is(err, tc.expErr) {
+					t.Fatalf("expected %v, got %v", tc.expErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rule.String() != tc.expStr {
+				t.Errorf("expected %q, got %q", tc.expStr, rule.String())
+			}
+		})
+	}
+}
+
+func TestCreateNewTemplate(t *testing.T) {
+	today := date("2026-10-19")
+
+	cases := []struct {
+		name   string
+		pt     recurring.PrimaryTemplate
+		expErr error
+	}{
+		{
+			name: "Корректный шаблон",
+			pt:   recurring.PrimaryTemplate{Order: client, RRule: "FREQ=YEARLY", Start: date("2026-11-01")},
+		},
+		{
+			name: "Первое повторение сегодня",
+			pt:   recurring.PrimaryTemplate{Order: client, RRule: "FREQ=YEARLY", Start: today},
+		},
+		{
+			name:   "Без даты начала",
+			pt:     recurring.PrimaryTemplate{Order: client, RRule: "FREQ=YEARLY"},
+			expErr: deterrs.NewDetErr(deterrs.EmptyField),
+		},
+		{
+			name:   "Дата начала в прошлом",
+			pt:     recurring.PrimaryTemplate{Order: client, RRule: "FREQ=YEARLY", Start: date("2026-10-18")},
+			expErr: deterrs.NewDetErr(deterrs.InvalidValue),
+		},
+		{
+			name:   "UNTIL раньше начала",
+			pt:     recurring.PrimaryTemplate{Order: client, RRule: "FREQ=YEARLY;UNTIL=20261031", Start: date("2026-11-01")},
+			expErr: deterrs.NewDetErr(deterrs.InvalidValue),
+		},
+		{
+			name:   "Без телефона клиента",
+			pt:     recurring.PrimaryTemplate{Order: orders.PrimaryOrder{ClientName: "Иван", Address: client.Address}, RRule: "FREQ=YEARLY", Start: today},
+			expErr: deterrs.NewDetErr(deterrs.EmptyField),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpl, err := tc.pt.CreateNewTemplate(today)
+			if tc.expErr != nil {
+				if !errors.Is(err, tc.expErr) {
+					t.Fatalf("expected %v, got %v", tc.expErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tmpl.Status != recurring.StatusActive || tmpl.NextOn == nil || !tmpl.NextOn.Equal(tc.pt.Start.Time) {
+				t.Errorf("expected active template starting %s, got %+v", tc.pt.Start, tmpl)
+			}
+			if tmpl.Order.ClientPhone != "+79112223344" || tmpl.Order.Category != "boiler" {
+				t.Errorf("expected normalized order data, got %+v", tmpl.Order)
+			}
+		})
+	}
+}
+
+func TestTemplate_Advance(t *testing.T) {
+	cases := []struct {
+		name     string
+		rule     string
+		start    string
+		expDates []string
+		expEnded bool
+	}{
+		{
+			name:     "Ежегодно",
+			rule:     "FREQ=YEARLY",
+			start:    "2026-11-01",
+			expDates: []string{"2027-11-01", "2028-11-01", "2029-11-01"},
+		},
+		{
+			name:     "Раз в полгода ограниченное число раз",
+			rule:     "FREQ=MONTHLY;INTERVAL=6;COUNT=3",
+			start:    "2026-11-15",
+			expDates: []string{"2027-05-15", "2027-11-15"},
+			expEnded: true,
+		},
+		{
+			name:     "Конец месяца в коротких месяцах",
+			rule:     "FREQ=MONTHLY",
+			start:    "2027-01-31",
+			expDates: []string{"2027-02-28", "2027-03-31", "2027-04-30"},
+		},
+		{
+			name:     "29 февраля в невисокосный год",
+			rule:     "FREQ=YEARLY",
+			start:    "2028-02-29",
+			expDates: []string{"2029-02-28", "2030-02-28", "2031-02-28", "2032-02-29"},
+		},
+		{
+			name:     "До даты",
+			rule:     "FREQ=MONTHLY;INTERVAL=3;UNTIL=20270601",
+			start:    "2026-11-01",
+			expDates: []string{"2027-02-01", "2027-05-01"},
+			expEnded: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			pt := recurring.PrimaryTemplate{Order: client, RRule: tc.rule, Start: date(tc.start)}
+			tmpl, err := pt.CreateNewTemplate(date("2026-10-19"))
+			if err != nil {
+				t.Fatalf("create: %v", err)
+			}
+
+			for i, exp := range tc.expDates {
+				if err := tmpl.Advance(); err != nil {
+					t.Fatalf("advance: %v", err)
+				}
+				if tmpl.NextOn == nil || tmpl.NextOn.String() != exp {
+					t.Fatalf("occurrence %d: expected %s, got %v", i+1, exp, tmpl.NextOn)
+				}
+			}
+
+			if tc.expEnded {
+				if err := tmpl.Advance(); err != nil {
+					t.Fatalf("advance: %v", err)
+				}
+				if tmpl.Status != recurring.StatusEnded || tmpl.NextOn != nil {
+					t.Errorf("expected ended template, got %s next %v", tmpl.Status, tmpl.NextOn)
+				}
+			}
+		})
+	}
+}
+
+func TestTemplate_PauseResumeEnd(t *testing.T) {
+	pt := recurring.PrimaryTemplate{Order: client, RRule: "FREQ=MONTHLY", Start: date("2026-11-10")}
+	tmpl, err := pt.CreateNewTemplate(date("2026-10-19"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notPermitted := deterrs.NewDetErr(deterrs.TemplateActionNotPermittedByStatus)
+	if err := tmpl.Resume(date("2026-10-19")); !errors.Is(err, notPermitted) {
+		t.Fatalf("resume of active template: expected %v, got %v", notPermitted, err)
+	}
+
+	if err := tmpl.Pause(); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	if tmpl.Due(date("2027-01-01")) {
+		t.Error("paused template must not be due")
+	}
+	if err := tmpl.Pause(); !errors.Is(err, notPermitted) {
+		t.Fatalf("second pause: expected %v, got %v", notPermitted, err)
+	}
+
+	// За паузу прошли повторения 10 ноября и 10 декабря
+	if err := tmpl.Resume(date("2026-12-20")); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if tmpl.Status != recurring.StatusActive || tmpl.NextOn.String() != "2027-01-10" || tmpl.Occurrences != 2 {
+		t.Errorf("expected skipped occurrences, got %s next %v after %d", tmpl.Status, tmpl.NextOn, tmpl.Occurrences)
+	}
+
+	if err := tmpl.End(); err != nil {
+		t.Fatalf("end: %v", err)
+	}
+	if tmpl.Status != recurring.StatusEnded || tmpl.NextOn != nil {
+		t.Errorf("expected ended template, got %s next %v", tmpl.Status, tmpl.NextOn)
+	}
+	if err := tmpl.End(); !errors.Is(err, notPermitted) {
+		t.Fatalf("second end: expected %v, got %v", notPermitted, err)
+	}
+}
+
+// Хранилище шаблонов в памяти
+type templateStore struct {
+	templates map[uuid.UUID]*recurring.Template
+}
+
+func (s *templateStore) Create(ctx context.Context, t *recurring.Template) error {
+	cp := *t
+	s.templates[t.ID] = &cp
+	return nil
+}
+
+func (s *templateStore) GetByID(ctx context.Context, id uuid.UUID) (*recurring.Template, error) {
+	t, ok := s.templates[id]
+	if !ok {
+		return nil, recurring.ErrNotFound
+	}
+	cp := *t
+	return &cp, nil
+}
+
+func (s *templateStore) GetAll(ctx context.Context, filter recurring.ListFilter) ([]*recurring.Template, error) {
+	var all []*recurring.Template
+	for _, t := range s.templates {
+		if filter.ClientPhone == "" || t.Order.ClientPhone == filter.ClientPhone {
+			cp := *t
+			all = append(all, &cp)
+		}
+	}
+	return all, nil
+}
+
+func (s *templateStore) Save(ctx context.Context, t *recurring.Template, prevStatus recurring.Status, prevNextOn *recurring.Date) (bool, error) {
+	stored, ok := s.templates[t.ID]
+	if !ok || stored.Status != prevStatus || (stored.NextOn == nil) != (prevNextOn == nil) ||
+		(prevNextOn != nil && !stored.NextOn.Equal(prevNextOn.Time)) {
+		return false, nil
+	}
+	cp := *t
+	s.templates[t.ID] = &cp
+	return true, nil
+}
+
+func (s *templateStore) Due(ctx context.Context, horizon recurring.Date, limit int) ([]*recurring.Template, error) {
+	var due []*recurring.Template
+	for _, t := range s.templates {
+		if t.Due(horizon) && len(due) < limit {
+			cp := *t
+			due = append(due, &cp)
+		}
+	}
+	return due, nil
+}
+
+// Сервис заявок, запоминающий созданные по шаблонам заявки
+type orderBook struct {
+	created        []*orders.PrimaryOrder
+	prescheduled   map[uuid.UUID]time.Time
+	createErr      error
+	prescheduleErr error
+}
+
+func (b *orderBook) Create(ctx context.Context, pord *orders.PrimaryOrder) (uuid.UUID, error) {
+	if b.createErr != nil {
+		return uuid.Nil, b.createErr
+	}
+	b.created = append(b.created, pord)
+	return uuid.New(), nil
+}
+
+func (b *orderBook) Preschedule(ctx context.Context, id uuid.UUID, scheduledFor *time.Time) error {
+	if b.prescheduleErr != nil {
+		return b.prescheduleErr
+	}
+	b.prescheduled[id] = *scheduledFor
+	return nil
+}
+
+func TestGenerate(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Skipf("tzdata: %v", err)
+	}
+	clk := clock.NewManual(time.Date(2026, 10, 19, 10, 0, 0, 0, moscow))
+
+	newService := func(book *orderBook) (*recurring.TemplateService, *templateStore) {
+		store := &templateStore{templates: make(map[uuid.UUID]*recurring.Template)}
+		svc := recurring.NewTemplateService(store, book,
+			recurring.WithScheduling(orders.Scheduling{Clock: clk}),
+			recurring.WithLeadTime(30*24*time.Hour),
+		)
+		return svc, store
+	}
+
+	t.Run("Заявки создаются заранее и ссылаются на шаблон", func(t *testing.T) {
+		book := &orderBook{prescheduled: make(map[uuid.UUID]time.Time)}
+		svc, store := newService(book)
+
+		monthly, err := svc.Create(context.Background(), &recurring.PrimaryTemplate{
+			Order: client, RRule: "FREQ=MONTHLY", Start: date("2026-10-25"), Preschedule: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		yearly, err := svc.Create(context.Background(), &recurring.PrimaryTemplate{
+			Order: client, RRule: "FREQ=YEARLY", Start: date("2027-03-01"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Заявки создаются до 18 ноября: на 25 октября — да, на 25 ноября — ещё нет
+		n, err := svc.Generate(context.Background(), 1)
+		if err != nil {
+			t.Fatalf("generate: %v", err)
+		}
+		if n != 1 || len(book.created) != 1 {
+			t.Fatalf("expected 1 order, got %d", n)
+		}
+		if id := book.created[0].TemplateID; id == nil || *id != monthly.ID {
+			t.Errorf("expected order of template %s, got %v", monthly.ID, id)
+		}
+		for _, at := range book.prescheduled {
+			if at.Location() != orders.OrderLocal || at.Format(time.DateTime) != "2026-10-25 00:00:00" {
+				t.Errorf("expected preschedule for the occurrence day, got %v", at)
+			}
+		}
+		if got := store.templates[monthly.ID]; got.NextOn.String() != "2026-11-25" || got.Occurrences != 1 {
+			t.Errorf("expected template advanced to 2026-11-25, got %v", got.NextOn)
+		}
+
+		// Повторный запуск ничего не создаёт
+		if n, err := svc.Generate(context.Background(), 10); err != nil || n != 0 {
+			t.Fatalf("expected no new orders, got %d, %v", n, err)
+		}
+
+		// Через неделю подходит следующее повторение; годовой шаблон ждёт
+		clk.Advance(7 * 24 * time.Hour)
+		if n, err := svc.Generate(context.Background(), 10); err != nil || n != 1 {
+			t.Fatalf("expected 1 new order, got %d, %v", n, err)
+		}
+		if store.templates[yearly.ID].Occurrences != 0 {
+			t.Error("yearly template must not be generated yet")
+		}
+		clk.Set(time.Date(2026, 10, 19, 10, 0, 0, 0, moscow))
+	})
+
+	t.Run("Приостановленный шаблон пропускается", func(t *testing.T) {
+		book := &orderBook{prescheduled: make(map[uuid.UUID]time.Time)}
+		svc, _ := newService(book)
+
+		tmpl, err := svc.Create(context.Background(), &recurring.PrimaryTemplate{
+			Order: client, RRule: "FREQ=MONTHLY", Start: date("2026-10-25"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := svc.Pause(context.Background(), tmpl.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		if n, err := svc.Generate(context.Background(), 10); err != nil || n != 0 {
+			t.Fatalf("expected no orders, got %d, %v", n, err)
+		}
+	})
+
+	t.Run("Неудачное создание заявки освобождает повторение", func(t *testing.T) {
+		book := &orderBook{prescheduled: make(map[uuid.UUID]time.Time), createErr: errors.New("db down")}
+		svc, store := newService(book)
+
+		tmpl, err := svc.Create(context.Background(), &recurring.PrimaryTemplate{
+			Order: client, RRule: "FREQ=MONTHLY", Start: date("2026-10-25"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := svc.Generate(context.Background(), 10); err == nil {
+			t.Fatal("expected error")
+		}
+		if got := store.templates[tmpl.ID]; got.NextOn.String() != "2026-10-25" || got.Occurrences != 0 {
+			t.Errorf("expected occurrence released, got %v after %d", got.NextOn, got.Occurrences)
+		}
+	})
+
+	t.Run("Заявка остаётся новой, если дату назначить нельзя", func(t *testing.T) {
+		book := &orderBook{
+			prescheduled:   make(map[uuid.UUID]time.Time),
+			prescheduleErr: deterrs.NewDetErr(deterrs.InvalidValue, deterrs.WithField("scheduled date")),
+		}
+		svc, _ := newService(book)
+
+		if _, err := svc.Create(context.Background(), &recurring.PrimaryTemplate{
+			Order: client, RRule: "FREQ=MONTHLY", Start: date("2026-10-25"), Preschedule: true,
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		if n, err := svc.Generate(context.Background(), 10); err != nil || n != 1 {
+			t.Fatalf("expected 1 order, got %d, %v", n, err)
+		}
+	})
+}
@@ -0,0 +1,279 @@
+package recurring
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/Owouwun/spkuznetsov/internal/logging"
+	"github.com/Owouwun/spkuznetsov/internal/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type TemplateRepository interface {
+	Create(ctx context.Context, t *Template) error
+	// ErrNotFound, если шаблона нет
+	GetByID(ctx context.Context, id uuid.UUID) (*Template, error)
+	GetAll(ctx context.Context, filter ListFilter) ([]*Template, error)
+	// Сохраняет статус, дату следующего повторения и число повторений шаблона, если в хранилище
+	// он всё ещё в статусе prevStatus с датой prevNextOn; false — шаблон уже изменён
+	Save(ctx context.Context, t *Template, prevStatus Status, prevNextOn *Date) (bool, error)
+	// Не более limit активных шаблонов, следующее повторение которых не позже horizon
+	Due(ctx context.Context, horizon Date, limit int) ([]*Template, error)
+}
+
+// Оформление заявок по шаблонам
+type OrderService interface {
+	Create(ctx context.Context, pord *orders.PrimaryOrder) (uuid.UUID, error)
+	Preschedule(ctx context.Context, id uuid.UUID, scheduledFor *time.Time) error
+}
+
+// Шаблон изменён параллельно (например, приостановлен во время создания заявки)
+var ErrConflict = errors.New("recurring order template was changed concurrently")
+
+type TemplateService struct {
+	repo   TemplateRepository
+	orders OrderService
+	// Часы и календарь компании: по часовому поясу календаря определяется текущая дата
+	scheduling orders.Scheduling
+	// За сколько до даты повторения создаётся заявка
+	leadTime time.Duration
+}
+
+type TemplateServiceOption func(*TemplateService)
+
+func WithScheduling(scheduling orders.Scheduling) TemplateServiceOption {
+	return func(s *TemplateService) {
+		s.scheduling = scheduling
+	}
+}
+
+// WithLeadTime задаёт, за сколько до даты повторения создаётся заявка
+func WithLeadTime(lead time.Duration) TemplateServiceOption {
+	return func(s *TemplateService) {
+		s.leadTime = lead
+	}
+}
+
+func NewTemplateService(repo TemplateRepository, orderService OrderService, opts ...TemplateServiceOption) *TemplateService {
+	s := &TemplateService{
+		repo:   repo,
+		orders: orderService,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *TemplateService) now() time.Time {
+	if s.scheduling.Clock == nil {
+		return time.Now()
+	}
+	return s.scheduling.Clock.Now()
+}
+
+// Текущая дата в часовом поясе компании
+func (s *TemplateService) today() Date {
+	loc := time.Local
+	if s.scheduling.Calendar != nil {
+		loc = s.scheduling.Calendar.Location()
+	}
+	return NewDate(s.now().In(loc))
+}
+
+func startSpan(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "TemplateService."+method, trace.WithAttributes(attrs...))
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func templateIDAttr(id uuid.UUID) attribute.KeyValue {
+	return attribute.String("template.id", id.String())
+}
+
+func (s *TemplateService) Create(ctx context.Context, pt *PrimaryTemplate) (_ *Template, err error) {
+	ctx, span := startSpan(ctx, "Create")
+	defer func() { endSpan(span, err) }()
+
+	t, err := pt.CreateNewTemplate(s.today())
+	if err != nil {
+		slog.WarnContext(ctx, "invalid recurring order template", slog.Any("error", err))
+		return nil, err
+	}
+	t.CreatedAt = s.now()
+	span.SetAttributes(templateIDAttr(t.ID))
+
+	ctx = logging.WithAttrs(ctx, slog.String("template_id", t.ID.String()))
+	if err := s.repo.Create(ctx, t); err != nil {
+		slog.ErrorContext(ctx, "failed to create recurring order template", slog.Any("error", err))
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "recurring order template created", slog.String("rrule", t.RRule), slog.String("start", t.Start.String()))
+	return t, nil
+}
+
+func (s *TemplateService) GetByID(ctx context.Context, id uuid.UUID) (_ *Template, err error) {
+	ctx, span := startSpan(ctx, "GetByID", templateIDAttr(id))
+	defer func() { endSpan(span, err) }()
+
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *TemplateService) GetAll(ctx context.Context, filter ListFilter) (_ []*Template, err error) {
+	ctx, span := startSpan(ctx, "GetAll")
+	defer func() { endSpan(span, err) }()
+
+	if err := filter.normalize(); err != nil {
+		return nil, err
+	}
+
+	templates, err := s.repo.GetAll(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("template.count", len(templates)))
+	return templates, nil
+}
+
+func (s *TemplateService) Pause(ctx context.Context, id uuid.UUID) (*Template, error) {
+	return s.change(ctx, "Pause", id, (*Template).Pause)
+}
+
+func (s *TemplateService) Resume(ctx context.Context, id uuid.UUID) (*Template, error) {
+	today := s.today()
+	return s.change(ctx, "Resume", id, func(t *Template) error {
+		return t.Resume(today)
+	})
+}
+
+func (s *TemplateService) End(ctx context.Context, id uuid.UUID) (*Template, error) {
+	return s.change(ctx, "End", id, (*Template).End)
+}
+
+// Изменить состояние шаблона действием action и сохранить его
+func (s *TemplateService) change(ctx context.Context, method string, id uuid.UUID, action func(t *Template) error) (_ *Template, err error) {
+	ctx, span := startSpan(ctx, method, templateIDAttr(id))
+	defer func() { endSpan(span, err) }()
+	ctx = logging.WithAttrs(ctx, slog.String("template_id", id.String()))
+
+	t, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	prevStatus, prevNextOn := t.Status, t.NextOn
+	if err := action(t); err != nil {
+		slog.WarnContext(ctx, "recurring order template action failed", slog.String("action", method), slog.Any("error", err))
+		return nil, err
+	}
+
+	saved, err := s.repo.Save(ctx, t, prevStatus, prevNextOn)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to save recurring order template", slog.Any("error", err))
+		return nil, err
+	}
+	if !saved {
+		return nil, ErrConflict
+	}
+
+	slog.InfoContext(ctx, "recurring order template action applied", slog.String("action", method), slog.String("status", string(t.Status)))
+	return t, nil
+}
+
+// Создать заявки по активным шаблонам, дата повторения которых наступит в пределах
+// заданного запаса времени. Шаблоны выбираются порциями по batchSize; возвращает число созданных заявок
+func (s *TemplateService) Generate(ctx context.Context, batchSize int) (total int, err error) {
+	ctx, span := startSpan(ctx, "Generate")
+	defer func() {
+		span.SetAttributes(attribute.Int("order.count", total))
+		endSpan(span, err)
+	}()
+
+	horizon := NewDate(s.today().Add(s.leadTime))
+	for {
+		due, err := s.repo.Due(ctx, horizon, batchSize)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to select due recurring order templates", slog.Any("error", err))
+			return total, err
+		}
+
+		created := 0
+		for _, t := range due {
+			ok, err := s.generate(ctx, t)
+			if err != nil {
+				return total, err
+			}
+			if ok {
+				created++
+			}
+		}
+		total += created
+
+		// Пока шаблоны продвигаются, выборка повторяется: у одного шаблона в пределах
+		// запаса может быть несколько повторений
+		if created == 0 {
+			break
+		}
+	}
+
+	if total > 0 {
+		slog.InfoContext(ctx, "recurring orders generated", slog.Int("orders", total), slog.String("horizon", horizon.String()))
+	}
+	return total, nil
+}
+
+// Создать заявку на следующее повторение шаблона. Повторение сначала занимается в хранилище,
+// чтобы несколько реплик не создали по нему две заявки; если заявку создать не удалось, оно освобождается
+func (s *TemplateService) generate(ctx context.Context, t *Template) (bool, error) {
+	ctx = logging.WithAttrs(ctx, slog.String("template_id", t.ID.String()))
+	on := *t.NextOn
+
+	claimed := *t
+	if err := claimed.Advance(); err != nil {
+		return false, err
+	}
+	ok, err := s.repo.Save(ctx, &claimed, t.Status, t.NextOn)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to claim recurring order occurrence", slog.Any("error", err))
+		return false, err
+	}
+	if !ok {
+		return false, nil
+	}
+
+	id, err := s.orders.Create(ctx, t.NewOrder())
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create recurring order", slog.String("occurrence", on.String()), slog.Any("error", err))
+		if _, undoErr := s.repo.Save(ctx, t, claimed.Status, claimed.NextOn); undoErr != nil {
+			slog.ErrorContext(ctx, "failed to release recurring order occurrence", slog.Any("error", undoErr))
+		}
+		return false, err
+	}
+	ctx = logging.WithAttrs(ctx, slog.String("order_id", id.String()))
+
+	// Дата работ — день повторения без точного времени. Если день не рабочий или уже прошёл,
+	// заявка остаётся новой и дату назначает диспетчер
+	if t.Preschedule {
+		date := on.OrderLocal()
+		if err := s.orders.Preschedule(ctx, id, &date); err != nil {
+			slog.WarnContext(ctx, "recurring order left unscheduled", slog.String("occurrence", on.String()), slog.Any("error", err))
+		}
+	}
+
+	slog.InfoContext(ctx, "recurring order created", slog.String("occurrence", on.String()))
+	return true, nil
+}
@@ -0,0 +1,127 @@
+package reports
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	deterrs "github.com/Owouwun/spkuznetsov/internal/errors"
+)
+
+// NewPeriod — период с from по to включительно; дни отсчитываются в часовом поясе loc
+func NewPeriod(dr DateRange, loc *time.Location) (Period, error) {
+	from, err := parseDate(dr.From, "from", loc)
+	if err != nil {
+		return Period{}, err
+	}
+	to, err := parseDate(dr.To, "to", loc)
+	if err != nil {
+		return Period{}, err
+	}
+	if to.Before(from) {
+		return Period{}, deterrs.NewDetErr(
+			deterrs.InvalidValue,
+			deterrs.WithField("to"),
+			deterrs.WithOriginalError(fmt.Errorf("%s is before %s", dr.To, dr.From)),
+		)
+	}
+
+	p := Period{From: from.Format(time.DateOnly), To: to.Format(time.DateOnly)}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if len(p.days) == MaxPeriodDays {
+			return Period{}, deterrs.NewDetErr(
+				deterrs.InvalidValue,
+				deterrs.WithField("to"),
+				deterrs.WithOriginalError(fmt.Errorf("period is longer than %d days", MaxPeriodDays)),
+			)
+		}
+		p.days = append(p.days, day)
+	}
+	p.end = to.AddDate(0, 0, 1)
+	return p, nil
+}
+
+func parseDate(s, field string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, deterrs.NewDetErr(
+			deterrs.EmptyField,
+			deterrs.WithField(field),
+		)
+	}
+	t, err := time.ParseInLocation(time.DateOnly, s, loc)
+	if err != nil {
+		return time.Time{}, deterrs.NewDetErr(
+			deterrs.InvalidValue,
+			deterrs.WithField(field),
+			deterrs.WithOriginalError(err),
+		)
+	}
+	return t, nil
+}
+
+// Начало первого дня периода
+func (p Period) Start() time.Time {
+	if len(p.days) == 0 {
+		return time.Time{}
+	}
+	return p.days[0]
+}
+
+// Конец последнего дня периода (не включается)
+func (p Period) End() time.Time {
+	return p.end
+}
+
+// Начала дней периода по порядку: границы, по которым события раскладываются по дням
+func (p Period) DayStarts() []time.Time {
+	return p.days
+}
+
+// Дата i-го дня периода
+func (p Period) Date(i int) string {
+	return p.days[i].Format(time.DateOnly)
+}
+
+// Дни периода без событий дополняются нулями, итоги считаются по дням
+func (t *Throughput) fill(days []DayThroughput) {
+	t.Days = make([]DayThroughput, len(t.days))
+	for i := range t.Days {
+		t.Days[i].Date = t.Date(i)
+	}
+	for _, d := range days {
+		i := t.index(d.Date)
+		if i < 0 {
+			continue
+		}
+		t.Days[i] = d
+		t.Created += d.Created
+		t.Completed += d.Completed
+		t.Canceled += d.Canceled
+	}
+}
+
+func (r *Revenue) fill(days []DayRevenue) {
+	r.Days = make([]DayRevenue, len(r.days))
+	for i := range r.Days {
+		r.Days[i].Date = r.Date(i)
+	}
+	for _, d := range days {
+		i := r.index(d.Date)
+		if i < 0 {
+			continue
+		}
+		r.Days[i] = d
+		r.PaidOrders += d.PaidOrders
+		r.Amount += d.Amount
+	}
+}
+
+func (p Period) index(date string) int {
+	for i := range p.days {
+		if p.Date(i) == date {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,95 @@
+package reports
+
+import "time"
+
+// Период отчёта в календарных днях по часовому поясу компании; обе даты включаются
+type Period struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+
+	// Начала дней периода и конец последнего дня
+	days []time.Time
+	end  time.Time
+}
+
+// Период отчёта в запросе: даты вида 2026-03-02
+type DateRange struct {
+	From string
+	To   string
+}
+
+// Сколько заявок оформлено, выполнено и отменено за период и по дням
+type Throughput struct {
+	Period
+	Created   int64           `json:"created"`
+	Completed int64           `json:"completed"`
+	Canceled  int64           `json:"canceled"`
+	Days      []DayThroughput `json:"days"`
+}
+
+type DayThroughput struct {
+	Date      string `json:"date"`
+	Created   int64  `json:"created"`
+	Completed int64  `json:"completed"`
+	Canceled  int64  `json:"canceled"`
+}
+
+// Сколько времени заявки проводят в статусе перед переходом в следующий
+type Transitions struct {
+	Period
+	Transitions []Transition `json:"transitions"`
+}
+
+// Переход между статусами, совершённый в периоде, и медиана времени в исходном статусе
+type Transition struct {
+	FromStatus    string  `json:"from_status"`
+	ToStatus      string  `json:"to_status"`
+	Count         int64   `json:"count"`
+	MedianSeconds float64 `json:"median_seconds"`
+}
+
+// Причины отмен заявок, отменённых в периоде
+type Cancellations struct {
+	Period
+	Total   int64         `json:"total"`
+	Reasons []ReasonCount `json:"reasons"`
+}
+
+type ReasonCount struct {
+	// Причина в нижнем регистре без лишних пробелов; UnspecifiedReason, если не указана
+	Reason string `json:"reason"`
+	Count  int64  `json:"count"`
+}
+
+// Выручка по заявкам, оплаченным в периоде: стоимость списанных по ним запчастей
+// по ценам на момент списания, в копейках
+type Revenue struct {
+	Period
+	PaidOrders int64        `json:"paid_orders"`
+	Amount     int64        `json:"amount"`
+	Days       []DayRevenue `json:"days"`
+}
+
+type DayRevenue struct {
+	Date       string `json:"date"`
+	PaidOrders int64  `json:"paid_orders"`
+	Amount     int64  `json:"amount"`
+}
+
+// Заявки, выполненные сотрудниками в периоде
+type EmployeePerformance struct {
+	Period
+	Employees []EmployeeStats `json:"employees"`
+}
+
+type EmployeeStats struct {
+	EmployeeID uint   `json:"employee_id"`
+	Name       string `json:"name"`
+	Completed  int64  `json:"completed"`
+}
+
+// Причина отмены, если она не указана
+const UnspecifiedReason = "unspecified"
+
+// Наибольшая длина периода отчёта в днях
+const MaxPeriodDays = 366
@@ -0,0 +1,190 @@
+package reports_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/reports"
+	deterrs "github.com/Owouwun/spkuznetsov/internal/errors"
+)
+
+func TestNewPeriod(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*3600)
+	cases := []struct {
+		name    string
+		dr      reports.DateRange
+		expErr  error
+		expDays int
+	}{
+		{name: "Один день", dr: reports.DateRange{From: "2026-03-02", To: "2026-03-02"}, expDays: 1},
+		{name: "Месяц", dr: reports.DateRange{From: "2026-02-01", To: "2026-02-28"}, expDays: 28},
+		{name: "Год", dr: reports.DateRange{From: "2028-01-01", To: "2028-12-31"}, expDays: 366},
+		{name: "Без начала", dr: reports.DateRange{To: "2026-03-02"}, expErr: deterrs.NewDetErr(deterrs.EmptyField)},
+		{name: "Дата в другом формате", dr: reports.DateRange{From: "02.03.2026", To: "2026-03-02"}, expErr: deterrs.NewDetErr(deterrs.InvalidValue)},
+		{name: "Конец раньше начала", dr: reports.DateRange{From: "2026-03-02", To: "2026-03-01"}, expErr: deterrs.NewDetErr(deterrs.InvalidValue)},
+		{name: "Длиннее года", dr: reports.DateRange{From: "2026-01-01", To: "2027-01-05"}, expErr: deterrs.NewDetErr(deterrs.InvalidValue)},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := reports.NewPeriod(tc.dr, moscow)
+			if !errors.Is(err, tc.expErr) {
+				t.Fatalf("expected %v, got %v", tc.expErr, err)
+			}
+			if tc.expErr != nil {
+				return
+			}
+
+			if len(p.DayStarts()) != tc.expDays {
+				t.Errorf("expected %d days, got %d", tc.expDays, len(p.DayStarts()))
+			}
+			// Дни отсчитываются от полуночи по часовому поясу компании
+			start := time.Date(2026, 3, 1, 21, 0, 0, 0, time.UTC)
+			if tc.dr.From == "2026-03-02" && (!p.Start().Equal(start) || !p.End().Equal(start.Add(24*time.Hour))) {
+				t.Errorf("expected period from %v, got %v - %v", start, p.Start(), p.End())
+			}
+		})
+	}
+}
+
+// Переход на летнее время: в дне 23 часа, границы дней остаются полуночами
+func TestNewPeriod_DST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no tzdata")
+	}
+	p, err := reports.NewPeriod(reports.DateRange{From: "2026-03-29", To: "2026-03-30"}, berlin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	days := p.DayStarts()
+	if got := days[1].Sub(days[0]); got != 23*time.Hour {
+		t.Errorf("expected a 23h day, got %v", got)
+	}
+	if got := p.End().Sub(days[1]); got != 24*time.Hour {
+		t.Errorf("expected a 24h day, got %v", got)
+	}
+}
+
+type reportStore struct {
+	periods   []reports.Period
+	days      []reports.DayThroughput
+	reasons   []reports.ReasonCount
+	revenue   []reports.DayRevenue
+	employees []reports.EmployeeStats
+	err       error
+}
+
+func (s *reportStore) Throughput(ctx context.Context, p reports.Period) ([]reports.DayThroughput, error) {
+	s.periods = append(s.periods, p)
+	return s.days, s.err
+}
+
+func (s *reportStore) Transitions(ctx context.Context, p reports.Period) ([]reports.Transition, error) {
+	s.periods = append(s.periods, p)
+	return nil, s.err
+}
+
+func (s *reportStore) CancelReasons(ctx context.Context, p reports.Period) ([]reports.ReasonCount, error) {
+	s.periods = append(s.periods, p)
+	return s.reasons, s.err
+}
+
+func (s *reportStore) Revenue(ctx context.Context, p reports.Period) ([]reports.DayRevenue, error) {
+	s.periods = append(s.periods, p)
+	return s.revenue, s.err
+}
+
+func (s *reportStore) EmployeeCompletions(ctx context.Context, p reports.Period) ([]reports.EmployeeStats, error) {
+	s.periods = append(s.periods, p)
+	return s.employees, s.err
+}
+
+func TestReportService(t *testing.T) {
+	ctx := context.Background()
+	week := reports.DateRange{From: "2026-03-02", To: "2026-03-04"}
+
+	t.Run("Дни без событий дополняются нулями", func(t *testing.T) {
+		store := &reportStore{days: []reports.DayThroughput{
+			{Date: "2026-03-02", Created: 3, Completed: 1},
+			{Date: "2026-03-04", Created: 1, Canceled: 2},
+		}}
+		svc := reports.NewReportService(store, reports.WithLocation(time.UTC))
+
+		report, err := svc.Throughput(ctx, week)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp := "[{2026-03-02 3 1 0} {2026-03-03 0 0 0} {2026-03-04 1 0 2}]"
+		if got := fmt.Sprint(report.Days); got != exp {
+			t.Errorf("expected days %s, got %s", exp, got)
+		}
+		if report.Created != 4 || report.Completed != 1 || report.Canceled != 2 {
+			t.Errorf("unexpected totals %+v", report)
+		}
+		if report.From != week.From || report.To != week.To {
+			t.Errorf("expected period %+v, got %s - %s", week, report.From, report.To)
+		}
+	})
+
+	t.Run("Итоги выручки и отмен", func(t *testing.T) {
+		store := &reportStore{
+			revenue: []reports.DayRevenue{{Date: "2026-03-03", PaidOrders: 2, Amount: 450000}},
+			reasons: []reports.ReasonCount{{Reason: "дорого", Count: 3}, {Reason: reports.UnspecifiedReason, Count: 1}},
+		}
+		svc := reports.NewReportService(store)
+
+		revenue, err := svc.Revenue(ctx, week)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(revenue.Days) != 3 || revenue.PaidOrders != 2 || revenue.Amount != 450000 {
+			t.Errorf("unexpected revenue %+v", revenue)
+		}
+
+		cancellations, err := svc.Cancellations(ctx, week)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cancellations.Total != 4 {
+			t.Errorf("expected 4 cancellations, got %d", cancellations.Total)
+		}
+	})
+
+	t.Run("Пустые списки", func(t *testing.T) {
+		svc := reports.NewReportService(&reportStore{})
+
+		transitions, err := svc.Transitions(ctx, week)
+		if err != nil || transitions.Transitions == nil {
+			t.Errorf("expected empty transitions, got %+v, %v", transitions, err)
+		}
+		employees, err := svc.EmployeePerformance(ctx, week)
+		if err != nil || employees.Employees == nil {
+			t.Errorf("expected empty employees, got %+v, %v", employees, err)
+		}
+	})
+
+	t.Run("Неверный период не доходит до хранилища", func(t *testing.T) {
+		store := &reportStore{}
+		svc := reports.NewReportService(store)
+
+		_, err := svc.Throughput(ctx, reports.DateRange{From: "2026-03-04", To: "2026-03-02"})
+		if !errors.Is(err, deterrs.NewDetErr(deterrs.InvalidValue)) {
+			t.Errorf("expected InvalidValue, got %v", err)
+		}
+		if len(store.periods) != 0 {
+			t.Errorf("expected no queries, got %d", len(store.periods))
+		}
+	})
+
+	t.Run("Ошибка хранилища", func(t *testing.T) {
+		svc := reports.NewReportService(&reportStore{err: errors.New("db down")})
+
+		if _, err := svc.Revenue(ctx, week); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
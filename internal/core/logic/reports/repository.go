@@ -0,0 +1,182 @@
+package reports
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	deterrs "github.com/Owouwun/spkuznetsov/internal/errors"
+	"github.com/Owouwun/spkuznetsov/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Отчёты считаются агрегатами SQL по журналу событий заявок, поэтому учитывают и архивные
+// заявки; события удалённых заявок не учитываются. Дни в ответах — даты из p.Date
+type ReportRepository interface {
+	// Оформленные, выполненные и отменённые заявки по дням; дни без событий пропускаются
+	Throughput(ctx context.Context, p Period) ([]DayThroughput, error)
+	// Переходы между статусами, совершённые в периоде, по парам статусов
+	Transitions(ctx context.Context, p Period) ([]Transition, error)
+	// Причины отмен по убыванию числа заявок
+	CancelReasons(ctx context.Context, p Period) ([]ReasonCount, error)
+	// Оплаченные заявки и стоимость списанных по ним запчастей по дням оплаты
+	Revenue(ctx context.Context, p Period) ([]DayRevenue, error)
+	// Выполненные заявки по сотрудникам, по убыванию числа заявок
+	EmployeeCompletions(ctx context.Context, p Period) ([]EmployeeStats, error)
+}
+
+type ReportService struct {
+	repo ReportRepository
+	// Часовой пояс компании, по которому отсчитываются дни периода
+	loc *time.Location
+}
+
+type ReportServiceOption func(*ReportService)
+
+// WithLocation задаёт часовой пояс компании
+func WithLocation(loc *time.Location) ReportServiceOption {
+	return func(s *ReportService) {
+		s.loc = loc
+	}
+}
+
+func NewReportService(repo ReportRepository, opts ...ReportServiceOption) *ReportService {
+	s := &ReportService{
+		repo: repo,
+		loc:  time.Local,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func startSpan(ctx context.Context, method string, dr DateRange) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "ReportService."+method, trace.WithAttributes(
+		attribute.String("report.from", dr.From),
+		attribute.String("report.to", dr.To),
+	))
+}
+
+func endSpan(span trace.Span, err error) {
+	// Неверный период — ошибка клиента, а не сбой
+	var detErr *deterrs.DetErr
+	if err != nil && !errors.As(err, &detErr) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (s *ReportService) period(ctx context.Context, dr DateRange) (Period, error) {
+	p, err := NewPeriod(dr, s.loc)
+	if err != nil {
+		slog.WarnContext(ctx, "invalid report period", slog.Any("error", err))
+	}
+	return p, err
+}
+
+func (s *ReportService) Throughput(ctx context.Context, dr DateRange) (_ *Throughput, err error) {
+	ctx, span := startSpan(ctx, "Throughput", dr)
+	defer func() { endSpan(span, err) }()
+
+	p, err := s.period(ctx, dr)
+	if err != nil {
+		return nil, err
+	}
+	days, err := s.repo.Throughput(ctx, p)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to build throughput report", slog.Any("error", err))
+		return nil, err
+	}
+
+	report := &Throughput{Period: p}
+	report.fill(days)
+	return report, nil
+}
+
+func (s *ReportService) Transitions(ctx context.Context, dr DateRange) (_ *Transitions, err error) {
+	ctx, span := startSpan(ctx, "Transitions", dr)
+	defer func() { endSpan(span, err) }()
+
+	p, err := s.period(ctx, dr)
+	if err != nil {
+		return nil, err
+	}
+	transitions, err := s.repo.Transitions(ctx, p)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to build transitions report", slog.Any("error", err))
+		return nil, err
+	}
+
+	return &Transitions{Period: p, Transitions: nonNil(transitions)}, nil
+}
+
+func (s *ReportService) Cancellations(ctx context.Context, dr DateRange) (_ *Cancellations, err error) {
+	ctx, span := startSpan(ctx, "Cancellations", dr)
+	defer func() { endSpan(span, err) }()
+
+	p, err := s.period(ctx, dr)
+	if err != nil {
+		return nil, err
+	}
+	reasons, err := s.repo.CancelReasons(ctx, p)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to build cancellations report", slog.Any("error", err))
+		return nil, err
+	}
+
+	report := &Cancellations{Period: p, Reasons: nonNil(reasons)}
+	for _, r := range reasons {
+		report.Total += r.Count
+	}
+	return report, nil
+}
+
+func (s *ReportService) Revenue(ctx context.Context, dr DateRange) (_ *Revenue, err error) {
+	ctx, span := startSpan(ctx, "Revenue", dr)
+	defer func() { endSpan(span, err) }()
+
+	p, err := s.period(ctx, dr)
+	if err != nil {
+		return nil, err
+	}
+	days, err := s.repo.Revenue(ctx, p)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to build revenue report", slog.Any("error", err))
+		return nil, err
+	}
+
+	report := &Revenue{Period: p}
+	report.fill(days)
+	return report, nil
+}
+
+func (s *ReportService) EmployeePerformance(ctx context.Context, dr DateRange) (_ *EmployeePerformance, err error) {
+	ctx, span := startSpan(ctx, "EmployeePerformance", dr)
+	defer func() { endSpan(span, err) }()
+
+	p, err := s.period(ctx, dr)
+	if err != nil {
+		return nil, err
+	}
+	employees, err := s.repo.EmployeeCompletions(ctx, p)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to build employee performance report", slog.Any("error", err))
+		return nil, err
+	}
+
+	return &EmployeePerformance{Period: p, Employees: nonNil(employees)}, nil
+}
+
+// Пустой список отдаётся как [], а не null
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
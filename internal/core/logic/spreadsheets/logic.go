@@ -0,0 +1,223 @@
+package spreadsheets
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	deterrs "github.com/Owouwun/spkuznetsov/internal/errors"
+)
+
+// Строка выгрузки по заявке; время — в часовом поясе заявки
+func exportRecord(ord *orders.Order) []string {
+	loc := ord.Location()
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.In(loc).Format("2006-01-02 15:04")
+	}
+
+	var employeeID, employeeName string
+	if ord.Employee != nil {
+		employeeID = strconv.FormatUint(uint64(ord.Employee.ID), 10)
+		employeeName = ord.Employee.Name
+	}
+
+	a := ord.Address
+	return []string{
+		ord.ID.String(),
+		ord.Status.ToString(),
+		ord.ClientName,
+		ord.ClientPhone,
+		a.City,
+		a.Street,
+		a.House,
+		a.Apartment,
+		a.Entrance,
+		a.Floor,
+		a.Intercom,
+		a.Line,
+		ord.ClientDescription,
+		ord.Category,
+		ord.Timezone,
+		employeeID,
+		employeeName,
+		formatTime(ord.ScheduledFor),
+		formatTime(ord.StatusChangedAt),
+		ord.CancelReason,
+		formatTime(ord.DeletedAt),
+	}
+}
+
+// Номера известных столбцов импорта по их названиям
+type columns map[string]int
+
+func columnName(s string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(s)), " ", "_")
+}
+
+// Разобрать заголовок таблицы: нужны имя и телефон клиента и адрес одной строкой или улица
+func parseHeader(header []string) (columns, error) {
+	known := make(map[string]bool, len(ImportColumns))
+	for _, name := range ImportColumns {
+		known[name] = true
+	}
+
+	cols := columns{}
+	for i, cell := range header {
+		name := columnName(cell)
+		// Дата из выгрузки годится как дата работ, если отдельного столбца date нет
+		if name == "scheduled_for" {
+			if _, ok := cols["date"]; !ok {
+				cols["date"] = i
+			}
+			continue
+		}
+		if known[name] {
+			cols[name] = i
+		}
+	}
+
+	missing := func(name string) error {
+		return deterrs.NewDetErr(
+			deterrs.InvalidValue,
+			deterrs.WithField("header"),
+			deterrs.WithOriginalError(fmt.Errorf("missing column %s", name)),
+		)
+	}
+	for _, name := range []string{"client_name", "client_phone"} {
+		if _, ok := cols[name]; !ok {
+			return nil, missing(name)
+		}
+	}
+	_, hasLine := cols["address"]
+	_, hasStreet := cols["street"]
+	if !hasLine && !hasStreet {
+		return nil, missing("address or street")
+	}
+	return cols, nil
+}
+
+// Значение столбца в строке; строка может быть короче заголовка
+func (c columns) get(cells []string, name string) string {
+	i, ok := c[name]
+	if !ok || i >= len(cells) {
+		return ""
+	}
+	return strings.TrimSpace(cells[i])
+}
+
+func (c columns) parse(number int, cells []string) (*importRow, error) {
+	row := &importRow{
+		number: number,
+		order: &orders.PrimaryOrder{
+			ClientName:  c.get(cells, "client_name"),
+			ClientPhone: c.get(cells, "client_phone"),
+			Address: orders.Address{
+				City:      c.get(cells, "city"),
+				Street:    c.get(cells, "street"),
+				House:     c.get(cells, "house"),
+				Apartment: c.get(cells, "apartment"),
+				Entrance:  c.get(cells, "entrance"),
+				Floor:     c.get(cells, "floor"),
+				Intercom:  c.get(cells, "intercom"),
+				Line:      c.get(cells, "address"),
+			},
+			ClientDescription: c.get(cells, "client_description"),
+			Category:          c.get(cells, "category"),
+			Timezone:          c.get(cells, "timezone"),
+		},
+	}
+
+	if raw := c.get(cells, "date"); raw != "" {
+		date, err := parseDate(raw)
+		if err != nil {
+			return nil, err
+		}
+		row.date = &date
+	}
+	return row, nil
+}
+
+// Дата работ без времени — календарный день; часовой пояс заявки известен только после её разбора
+func parseDate(raw string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, deterrs.NewDetErr(
+		deterrs.InvalidValue,
+		deterrs.WithField("date"),
+		deterrs.WithOriginalError(fmt.Errorf("unrecognized date %q, expected YYYY-MM-DD or DD.MM.YYYY", raw)),
+	)
+}
+
+// Предварительная дата работ: полночь в часовом поясе заявки, то есть «в течение дня»; nil, если даты нет
+func (row *importRow) dateIn(loc *time.Location) *time.Time {
+	if row.date == nil {
+		return nil
+	}
+	date := time.Date(row.date.Year(), row.date.Month(), row.date.Day(), 0, 0, 0, 0, loc)
+	return &date
+}
+
+// Строка пустая, если во всех её ячейках только пробелы
+func isBlank(cells []string) bool {
+	for _, cell := range cells {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Ключ адреса для поиска дублей: здание и квартира без учёта регистра и лишних пробелов
+func addressKey(a orders.Address) string {
+	return strings.ToLower(strings.Join(strings.Fields(a.Query()+" / "+a.Apartment), " "))
+}
+
+// Ключ даты для поиска дублей: день в местном времени заявки; пустой, если даты нет
+func dateKey(date *time.Time) string {
+	if date == nil {
+		return ""
+	}
+	return date.Format(time.DateOnly)
+}
+
+// Ключ дублей: телефон, адрес и дата работ. Телефон ord — уже в стандартном виде
+func dedupeKey(ord *orders.Order, date *time.Time) string {
+	return ord.ClientPhone + "|" + addressKey(ord.Address) + "|" + dateKey(date)
+}
+
+// Существующая заявка совпадает со строкой: тот же адрес и тот же день работ (или оба без даты).
+// Отменённые заявки дублями не считаются: клиент мог оформить заявку заново
+func isDuplicate(existing, ord *orders.Order, date *time.Time) bool {
+	if existing.Status == orders.StatusCanceled || addressKey(existing.Address) != addressKey(ord.Address) {
+		return false
+	}
+	if existing.ScheduledFor == nil || date == nil {
+		return existing.ScheduledFor == nil && date == nil
+	}
+	return existing.ScheduledFor.In(existing.Location()).Format(time.DateOnly) == dateKey(date)
+}
+
+func (r *ImportReport) add(res RowResult) {
+	r.Rows = append(r.Rows, res)
+	r.Total++
+	switch res.Status {
+	case RowCreated:
+		r.Created++
+	case RowValid:
+		r.Valid++
+	case RowDuplicate:
+		r.Duplicates++
+	case RowInvalid:
+		r.Invalid++
+	case RowFailed:
+		r.Failed++
+	}
+}
@@ -0,0 +1,104 @@
+package spreadsheets
+
+import (
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/google/uuid"
+)
+
+// Столбцы выгрузки заявок. Импорт понимает те же названия (регистр, пробелы по краям
+// и пробелы вместо подчёркиваний не важны) и пропускает столбцы, которых нет в ImportColumns
+var ExportColumns = []string{
+	"id",
+	"status",
+	"client_name",
+	"client_phone",
+	"city",
+	"street",
+	"house",
+	"apartment",
+	"entrance",
+	"floor",
+	"intercom",
+	"address",
+	"client_description",
+	"category",
+	"timezone",
+	"employee_id",
+	"employee_name",
+	"scheduled_for",
+	"status_changed_at",
+	"cancel_reason",
+	"deleted_at",
+}
+
+// Столбцы, из которых импорт берёт поля заявки. address — адрес одной строкой;
+// date — предварительная дата работ (scheduled_for выгрузки тоже подходит, время не учитывается)
+var ImportColumns = []string{
+	"client_name",
+	"client_phone",
+	"city",
+	"street",
+	"house",
+	"apartment",
+	"entrance",
+	"floor",
+	"intercom",
+	"address",
+	"client_description",
+	"category",
+	"timezone",
+	"date",
+}
+
+// Форматы даты в импорте: как в выгрузке, по-русски и краткая дата Excel (мм-дд-гг)
+var dateLayouts = []string{"2006-01-02 15:04", time.DateOnly, "02.01.2006", "01-02-06"}
+
+// Больше строк за один импорт не принимается
+const MaxImportRows = 5000
+
+// Итог импорта строки таблицы
+type RowStatus string
+
+const (
+	RowCreated RowStatus = "created"
+	// Строка верна; заявка не создана, так как импорт пробный
+	RowValid RowStatus = "valid"
+	// Такая заявка уже есть в системе или выше в файле
+	RowDuplicate RowStatus = "duplicate"
+	// Строка не прошла проверку заявки
+	RowInvalid RowStatus = "invalid"
+	// Строка верна, но заявку не удалось сохранить
+	RowFailed RowStatus = "failed"
+)
+
+type RowResult struct {
+	// Номер строки в файле (заголовок — строка 1)
+	Row    int       `json:"row"`
+	Status RowStatus `json:"status"`
+	// Созданная заявка или уже существующая заявка-дубль
+	OrderID *uuid.UUID `json:"order_id,omitempty"`
+	// Строка файла, дублем которой является эта
+	DuplicateOfRow int    `json:"duplicate_of_row,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+type ImportReport struct {
+	DryRun     bool        `json:"dry_run"`
+	Total      int         `json:"total"`
+	Created    int         `json:"created"`
+	Valid      int         `json:"valid"`
+	Duplicates int         `json:"duplicates"`
+	Invalid    int         `json:"invalid"`
+	Failed     int         `json:"failed"`
+	Rows       []RowResult `json:"rows"`
+}
+
+// Строка таблицы, разобранная в заявку
+type importRow struct {
+	number int
+	order  *orders.PrimaryOrder
+	// Предварительная дата работ — календарный день из таблицы; см. importRow.dateIn
+	date *time.Time
+}
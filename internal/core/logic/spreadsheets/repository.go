@@ -0,0 +1,237 @@
+package spreadsheets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	deterrs "github.com/Owouwun/spkuznetsov/internal/errors"
+	"github.com/Owouwun/spkuznetsov/internal/tracing"
+	"github.com/Owouwun/spkuznetsov/pkg/tabular"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type OrderService interface {
+	Export(ctx context.Context, filter orders.ListFilter, fn func(*orders.Order) error) error
+	GetAll(ctx context.Context, filter orders.ListFilter) ([]*orders.Order, error)
+	Create(ctx context.Context, pord *orders.PrimaryOrder) (uuid.UUID, error)
+	Preschedule(ctx context.Context, id uuid.UUID, scheduledFor *time.Time) error
+}
+
+// SpreadsheetService выгружает заявки в таблицу и создаёт заявки из таблицы
+type SpreadsheetService struct {
+	orders OrderService
+	// Часы и календарь компании: по ним проверяются строки до создания заявок
+	scheduling orders.Scheduling
+}
+
+type SpreadsheetServiceOption func(*SpreadsheetService)
+
+func WithScheduling(scheduling orders.Scheduling) SpreadsheetServiceOption {
+	return func(s *SpreadsheetService) {
+		s.scheduling = scheduling
+	}
+}
+
+func NewSpreadsheetService(orderService OrderService, opts ...SpreadsheetServiceOption) *SpreadsheetService {
+	s := &SpreadsheetService{
+		orders: orderService,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func startSpan(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "SpreadsheetService."+method, trace.WithAttributes(attrs...))
+}
+
+func endSpan(span trace.Span, err error) {
+	// Неверная таблица — ошибка клиента, а не сбой
+	var detErr *deterrs.DetErr
+	if err != nil && !errors.As(err, &detErr) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Export пишет в w заголовок ExportColumns и заявки по фильтру, по мере их загрузки.
+// w не закрывается
+func (s *SpreadsheetService) Export(ctx context.Context, filter orders.ListFilter, w tabular.Writer) (err error) {
+	ctx, span := startSpan(ctx, "Export")
+	defer func() { endSpan(span, err) }()
+
+	if err := w.Write(ExportColumns); err != nil {
+		return err
+	}
+	return s.orders.Export(ctx, filter, func(ord *orders.Order) error {
+		return w.Write(exportRecord(ord))
+	})
+}
+
+// Import проверяет каждую строку таблицы (первая — заголовок) как новую заявку и, если
+// это не пробный импорт (dryRun), создаёт заявки по верным строкам. Строки-дубли — с тем же
+// телефоном, адресом и датой работ, что у действующей заявки или строки выше, — пропускаются.
+// Ошибка возвращается, только если таблица не разобрана целиком или недоступны заявки
+func (s *SpreadsheetService) Import(ctx context.Context, rows [][]string, dryRun bool) (_ *ImportReport, err error) {
+	ctx, span := startSpan(ctx, "Import", attribute.Bool("import.dry_run", dryRun))
+	defer func() { endSpan(span, err) }()
+
+	if len(rows) == 0 {
+		return nil, deterrs.NewDetErr(
+			deterrs.EmptyField,
+			deterrs.WithField("header"),
+		)
+	}
+	cols, err := parseHeader(rows[0])
+	if err != nil {
+		slog.WarnContext(ctx, "invalid import header", slog.Any("error", err))
+		return nil, err
+	}
+
+	// Лимит проверяется до создания заявок, чтобы не импортировать файл наполовину
+	count := 0
+	for _, cells := range rows[1:] {
+		if !isBlank(cells) {
+			count++
+		}
+	}
+	if count > MaxImportRows {
+		return nil, deterrs.NewDetErr(
+			deterrs.InvalidValue,
+			deterrs.WithField("rows"),
+			deterrs.WithOriginalError(fmt.Errorf("%d rows, at most %d allowed", count, MaxImportRows)),
+		)
+	}
+
+	report := &ImportReport{DryRun: dryRun, Rows: make([]RowResult, 0, count)}
+	imp := &importer{SpreadsheetService: s, dryRun: dryRun, seen: map[string]int{}, existing: map[string][]*orders.Order{}}
+	for i, cells := range rows[1:] {
+		if isBlank(cells) {
+			continue
+		}
+
+		number := i + 2
+		res, err := imp.importRow(ctx, cols, number, cells)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to import orders", slog.Int("row", number), slog.Any("error", err))
+			return nil, err
+		}
+		report.add(res)
+	}
+
+	span.SetAttributes(
+		attribute.Int("import.rows", report.Total),
+		attribute.Int("import.created", report.Created),
+		attribute.Int("import.invalid", report.Invalid),
+	)
+	slog.InfoContext(ctx, "orders imported",
+		slog.Bool("dry_run", dryRun),
+		slog.Int("rows", report.Total),
+		slog.Int("created", report.Created),
+		slog.Int("duplicates", report.Duplicates),
+		slog.Int("invalid", report.Invalid),
+		slog.Int("failed", report.Failed),
+	)
+	return report, nil
+}
+
+// Состояние одного импорта
+type importer struct {
+	*SpreadsheetService
+	dryRun bool
+	// Ключи дублей уже обработанных строк и номера этих строк
+	seen map[string]int
+	// Заявки клиентов по телефону, загруженные для поиска дублей
+	existing map[string][]*orders.Order
+}
+
+func (imp *importer) importRow(ctx context.Context, cols columns, number int, cells []string) (RowResult, error) {
+	res := RowResult{Row: number}
+	invalid := func(err error) (RowResult, error) {
+		res.Status, res.Error = RowInvalid, err.Error()
+		return res, nil
+	}
+
+	row, err := cols.parse(number, cells)
+	if err != nil {
+		return invalid(err)
+	}
+
+	// Строка проверяется так же, как при создании заявки и назначении даты работ
+	ord, err := row.order.CreateNewOrder(imp.scheduling)
+	if err != nil {
+		return invalid(err)
+	}
+	// День работ считается в часовом поясе строки, а без него — в поясе компании
+	date := row.dateIn(ord.Location())
+	if date != nil {
+		scheduledFor := *date
+		if err := ord.Preschedule(&scheduledFor); err != nil {
+			return invalid(err)
+		}
+	}
+
+	key := dedupeKey(ord, date)
+	if prev, ok := imp.seen[key]; ok {
+		res.Status, res.DuplicateOfRow = RowDuplicate, prev
+		return res, nil
+	}
+	imp.seen[key] = row.number
+
+	existing, err := imp.clientOrders(ctx, ord.ClientPhone)
+	if err != nil {
+		return res, err
+	}
+	for _, e := range existing {
+		if isDuplicate(e, ord, date) {
+			res.Status, res.OrderID = RowDuplicate, &e.ID
+			return res, nil
+		}
+	}
+
+	if imp.dryRun {
+		res.Status = RowValid
+		return res, nil
+	}
+
+	id, err := imp.orders.Create(ctx, row.order)
+	if err != nil {
+		var detErr *deterrs.DetErr
+		if errors.As(err, &detErr) {
+			return invalid(err)
+		}
+		res.Status, res.Error = RowFailed, err.Error()
+		return res, nil
+	}
+	res.Status, res.OrderID = RowCreated, &id
+
+	// Заявка уже создана: ошибку даты работ видно в отчёте, дату назначит диспетчер
+	if date != nil {
+		if err := imp.orders.Preschedule(ctx, id, date); err != nil {
+			res.Error = "preliminary date not set: " + err.Error()
+		}
+	}
+	return res, nil
+}
+
+func (imp *importer) clientOrders(ctx context.Context, phone string) ([]*orders.Order, error) {
+	if ords, ok := imp.existing[phone]; ok {
+		return ords, nil
+	}
+	ords, err := imp.orders.GetAll(ctx, orders.ListFilter{ClientPhone: phone})
+	if err != nil {
+		return nil, err
+	}
+	imp.existing[phone] = ords
+	return ords, nil
+}
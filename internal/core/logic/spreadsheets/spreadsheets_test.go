@@ -0,0 +1,336 @@
+package spreadsheets_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/auth"
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/spreadsheets"
+	deterrs "github.com/Owouwun/spkuznetsov/internal/errors"
+	"github.com/Owouwun/spkuznetsov/pkg/clock"
+	"github.com/google/uuid"
+)
+
+type orderBook struct {
+	existing     []*orders.Order
+	filters      []orders.ListFilter
+	created      []*orders.PrimaryOrder
+	prescheduled map[uuid.UUID]time.Time
+	createErr    error
+	getErr       error
+}
+
+func (b *orderBook) Export(ctx context.Context, filter orders.ListFilter, fn func(*orders.Order) error) error {
+	b.filters = append(b.filters, filter)
+	for _, ord := range b.existing {
+		if err := fn(ord); err != nil {
+			return err
+		}
+	}
+	return b.getErr
+}
+
+func (b *orderBook) GetAll(ctx context.Context, filter orders.ListFilter) ([]*orders.Order, error) {
+	b.filters = append(b.filters, filter)
+	var found []*orders.Order
+	for _, ord := range b.existing {
+		if ord.ClientPhone == filter.ClientPhone {
+			found = append(found, ord)
+		}
+	}
+	return found, b.getErr
+}
+
+func (b *orderBook) Create(ctx context.Context, pord *orders.PrimaryOrder) (uuid.UUID, error) {
+	if b.createErr != nil {
+		return uuid.Nil, b.createErr
+	}
+	b.created = append(b.created, pord)
+	return uuid.New(), nil
+}
+
+func (b *orderBook) Preschedule(ctx context.Context, id uuid.UUID, scheduledFor *time.Time) error {
+	b.prescheduled[id] = *scheduledFor
+	return nil
+}
+
+// Итоги строк отчёта в виде "2:created 3:invalid"
+func statuses(report *spreadsheets.ImportReport) string {
+	var parts []string
+	for _, r := range report.Rows {
+		parts = append(parts, fmt.Sprintf("%d:%s", r.Row, r.Status))
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestImport(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewManual(time.Date(2026, 10, 19, 10, 0, 0, 0, time.Local))
+	header := []string{"Client Name", "client_phone", "city", "street", "house", "apartment", "date"}
+	existingDate := time.Date(2026, 10, 26, 0, 0, 0, 0, time.Local)
+
+	cases := []struct {
+		name       string
+		rows       [][]string
+		existing   []*orders.Order
+		dryRun     bool
+		createErr  error
+		getErr     error
+		expErr     error
+		expRows    string
+		expCreated int
+	}{
+		{
+			name: "Верные строки создаются, неверные попадают в отчёт",
+			rows: [][]string{
+				header,
+				{"Иван", "8 (916) 123-45-67", "Москва", "Тверская", "1", "5", "2026-10-26"},
+				{"Пётр", "", "Москва", "Арбат", "2"},
+				{"", "", "", "", "", "", ""},
+				{"Анна", "+79160000000", "Москва", "Арбат", "3", "", "30.10.2026"},
+			},
+			expRows:    "2:created 3:invalid 5:created",
+			expCreated: 2,
+		},
+		{
+			name:    "Пробный импорт ничего не создаёт",
+			rows:    [][]string{header, {"Иван", "89161234567", "Москва", "Тверская", "1"}},
+			dryRun:  true,
+			expRows: "2:valid",
+		},
+		{
+			name: "Дата в прошлом или в неизвестном формате",
+			rows: [][]string{
+				header,
+				{"Иван", "89161234567", "Москва", "Тверская", "1", "", "2026-10-01"},
+				{"Иван", "89161234567", "Москва", "Тверская", "1", "", "1 ноября"},
+			},
+			expRows: "2:invalid 3:invalid",
+		},
+		{
+			name: "Дубль строки выше: тот же телефон, адрес и дата",
+			rows: [][]string{
+				header,
+				{"Иван", "89161234567", "Москва", "Тверская", "1", "5", "2026-10-26"},
+				{"Иван Петрович", "+7 916 123 45 67", " москва ", "ТВЕРСКАЯ", "1", "5", "26.10.2026"},
+				{"Иван", "89161234567", "Москва", "Тверская", "1", "5", "2026-10-27"},
+				{"Иван", "89161234567", "Москва", "Тверская", "1", "6", "2026-10-26"},
+			},
+			expRows:    "2:created 3:duplicate 4:created 5:created",
+			expCreated: 3,
+		},
+		{
+			name: "Дубль существующей заявки; отменённая заявка дублем не считается",
+			rows: [][]string{
+				header,
+				{"Иван", "89161234567", "Москва", "Тверская", "1", "5", "2026-10-26"},
+				{"Иван", "89161234567", "Москва", "Арбат", "2", "", ""},
+			},
+			existing: []*orders.Order{
+				{ClientPhone: "+79161234567", Address: orders.Address{City: "Москва", Street: "Тверская", House: "1", Apartment: "5"}, ScheduledFor: &existingDate},
+				{ClientPhone: "+79161234567", Address: orders.Address{City: "Москва", Street: "Арбат", House: "2"}, Status: orders.StatusCanceled},
+			},
+			dryRun:  true,
+			expRows: "2:duplicate 3:valid",
+		},
+		{
+			name:       "Сбой сохранения отмечается в строке",
+			rows:       [][]string{header, {"Иван", "89161234567", "Москва", "Тверская", "1"}},
+			createErr:  errors.New("db down"),
+			expRows:    "2:failed",
+			expCreated: 0,
+		},
+		{
+			name:   "Нет столбца телефона",
+			rows:   [][]string{{"client_name", "address"}, {"Иван", "Москва, Тверская, 1"}},
+			expErr: deterrs.NewDetErr(deterrs.InvalidValue),
+		},
+		{
+			name:   "Пустой файл",
+			expErr: deterrs.NewDetErr(deterrs.EmptyField),
+		},
+		{
+			name:   "Заявки недоступны",
+			rows:   [][]string{header, {"Иван", "89161234567", "Москва", "Тверская", "1"}},
+			getErr: errors.New("db down"),
+			expErr: errors.New("db down"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			book := &orderBook{existing: tc.existing, createErr: tc.createErr, getErr: tc.getErr, prescheduled: map[uuid.UUID]time.Time{}}
+			svc := spreadsheets.NewSpreadsheetService(book, spreadsheets.WithScheduling(orders.Scheduling{Clock: clk}))
+
+			report, err := svc.Import(ctx, tc.rows, tc.dryRun)
+			if tc.expErr != nil {
+				if err == nil || (!errors.Is(err, tc.expErr) && err.Error() != tc.expErr.Error()) {
+					t.Fatalf("expected %v, got %v", tc.expErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := statuses(report); got != tc.expRows {
+				t.Errorf("expected rows %q, got %q", tc.expRows, got)
+			}
+			if len(book.created) != tc.expCreated || report.Created != tc.expCreated {
+				t.Errorf("expected %d created, got %d (report %d)", tc.expCreated, len(book.created), report.Created)
+			}
+			if report.DryRun != tc.dryRun || report.Total != len(report.Rows) {
+				t.Errorf("unexpected report %+v", report)
+			}
+		})
+	}
+
+	t.Run("Дата работ назначается, дубль ссылается на строку", func(t *testing.T) {
+		book := &orderBook{prescheduled: map[uuid.UUID]time.Time{}}
+		svc := spreadsheets.NewSpreadsheetService(book, spreadsheets.WithScheduling(orders.Scheduling{Clock: clk}))
+
+		report, err := svc.Import(ctx, [][]string{
+			{"client_name", "client_phone", "address", "date"},
+			{"Иван", "89161234567", "Москва, Тверская, 1", "2026-10-26"},
+			{"Иван", "89161234567", "Москва,  Тверская, 1", "2026-10-26"},
+			{"Пётр", "abc", "Москва, Арбат, 2", ""},
+		}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if id := report.Rows[0].OrderID; id == nil || book.prescheduled[*id].Format(time.DateTime) != "2026-10-26 00:00:00" {
+			t.Errorf("expected created order prescheduled for 2026-10-26, got %v", book.prescheduled)
+		}
+		if report.Rows[1].DuplicateOfRow != 2 {
+			t.Errorf("expected duplicate of row 2, got %+v", report.Rows[1])
+		}
+		if !strings.Contains(report.Rows[2].Error, "client phone") {
+			t.Errorf("expected client phone error, got %q", report.Rows[2].Error)
+		}
+		if report.Created != 1 || report.Duplicates != 1 || report.Invalid != 1 {
+			t.Errorf("unexpected totals %+v", report)
+		}
+	})
+
+	t.Run("Дата работ — в часовом поясе строки", func(t *testing.T) {
+		vladivostok, err := time.LoadLocation("Asia/Vladivostok")
+		if err != nil {
+			t.Skip("no tzdata")
+		}
+		// Полночь 26.10 во Владивостоке — ещё 25.10 по UTC
+		scheduled := time.Date(2026, 10, 26, 0, 0, 0, 0, vladivostok)
+		book := &orderBook{
+			existing: []*orders.Order{{
+				ClientPhone:  "+79161234567",
+				Address:      orders.Address{City: "Владивосток", Street: "Светланская", House: "1"},
+				Timezone:     "Asia/Vladivostok",
+				ScheduledFor: &scheduled,
+			}},
+			prescheduled: map[uuid.UUID]time.Time{},
+		}
+		svc := spreadsheets.NewSpreadsheetService(book, spreadsheets.WithScheduling(orders.Scheduling{Clock: clk}))
+
+		report, err := svc.Import(ctx, [][]string{
+			{"client_name", "client_phone", "address", "timezone", "date"},
+			{"Иван", "89161234567", "Владивосток, Светланская, 1", "Asia/Vladivostok", "26.10.2026"},
+			{"Иван", "89161234567", "Владивосток, Алеутская, 2", "Asia/Vladivostok", "2026-10-27"},
+		}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := statuses(report); got != "2:duplicate 3:created" {
+			t.Fatalf("expected rows %q, got %q", "2:duplicate 3:created", got)
+		}
+		exp := time.Date(2026, 10, 27, 0, 0, 0, 0, vladivostok)
+		if got := book.prescheduled[*report.Rows[1].OrderID]; !got.Equal(exp) {
+			t.Errorf("expected order prescheduled for %v, got %v", exp, got)
+		}
+	})
+
+	t.Run("Не больше MaxImportRows строк", func(t *testing.T) {
+		book := &orderBook{prescheduled: map[uuid.UUID]time.Time{}}
+		svc := spreadsheets.NewSpreadsheetService(book)
+
+		rows := [][]string{header}
+		for i := 0; i <= spreadsheets.MaxImportRows; i++ {
+			rows = append(rows, []string{"Иван", "89161234567", "Москва", "Тверская", "1"})
+		}
+		if _, err := svc.Import(ctx, rows, false); !errors.Is(err, deterrs.NewDetErr(deterrs.InvalidValue)) {
+			t.Errorf("expected InvalidValue, got %v", err)
+		}
+		if len(book.created) != 0 {
+			t.Errorf("expected no orders created, got %d", len(book.created))
+		}
+	})
+}
+
+type tableWriter struct {
+	rows [][]string
+}
+
+func (w *tableWriter) Write(row []string) error {
+	w.rows = append(w.rows, row)
+	return nil
+}
+
+func (w *tableWriter) Close() error { return nil }
+
+func (w *tableWriter) Abort() {}
+
+func TestExport(t *testing.T) {
+	if _, err := time.LoadLocation("Europe/Moscow"); err != nil {
+		t.Skip("no tzdata")
+	}
+	scheduled := time.Date(2026, 10, 26, 6, 30, 0, 0, time.UTC)
+	id := uuid.New()
+	book := &orderBook{existing: []*orders.Order{{
+		ID:          id,
+		ClientName:  "Иван",
+		ClientPhone: "+79161234567",
+		Address:     orders.Address{City: "Москва", Street: "Тверская", House: "1"},
+		Timezone:    "Europe/Moscow",
+		Employee:    &auth.Employee{ID: 7, Name: "Пётр"},
+		Status:      orders.StatusScheduled,
+		// Время в выгрузке — в часовом поясе заявки
+		ScheduledFor: &scheduled,
+	}}}
+	svc := spreadsheets.NewSpreadsheetService(book)
+
+	w := &tableWriter{}
+	filter := orders.ListFilter{Overdue: true}
+	if err := svc.Export(context.Background(), filter, w); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(w.rows) != 2 || strings.Join(w.rows[0], ",") != strings.Join(spreadsheets.ExportColumns, ",") {
+		t.Fatalf("expected header and one row, got %q", w.rows)
+	}
+	row := map[string]string{}
+	for i, name := range spreadsheets.ExportColumns {
+		row[name] = w.rows[1][i]
+	}
+	exp := map[string]string{
+		"id":            id.String(),
+		"status":        "Scheduled",
+		"street":        "Тверская",
+		"employee_id":   "7",
+		"employee_name": "Пётр",
+		"scheduled_for": "2026-10-26 09:30",
+		"deleted_at":    "",
+	}
+	for name, v := range exp {
+		if row[name] != v {
+			t.Errorf("expected %s %q, got %q", name, v, row[name])
+		}
+	}
+	if len(book.filters) != 1 || !book.filters[0].Overdue {
+		t.Errorf("expected filter to be passed, got %+v", book.filters)
+	}
+}
@@ -1,19 +1,35 @@
 package repository_orders_postgres
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
-	"embed"
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/attachments"
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/idempotency"
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/inventory"
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/recurring"
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/reports"
+	"github.com/Owouwun/spkuznetsov/internal/core/repository/entities"
+	repository_attachments "github.com/Owouwun/spkuznetsov/internal/core/repository/services/attachments"
+	repository_idempotency "github.com/Owouwun/spkuznetsov/internal/core/repository/services/idempotency"
+	repository_inventory "github.com/Owouwun/spkuznetsov/internal/core/repository/services/inventory"
 	repository_orders "github.com/Owouwun/spkuznetsov/internal/core/repository/services/orders"
+	repository_recurring "github.com/Owouwun/spkuznetsov/internal/core/repository/services/recurring"
+	repository_reports "github.com/Owouwun/spkuznetsov/internal/core/repository/services/reports"
+	"github.com/Owouwun/spkuznetsov/internal/pii"
 	"github.com/Owouwun/spkuznetsov/internal/testutils"
+	"github.com/Owouwun/spkuznetsov/migrations"
 	"github.com/docker/go-connections/nat"
 	"github.com/golang-migrate/migrate/v4"
 	migpostgres "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
-	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/google/uuid"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
 	"gorm.io/driver/postgres"
@@ -25,9 +41,6 @@ const (
 	containerPort = "5432"
 )
 
-//go:embed migrations/*.sql
-var migrationFiles embed.FS
-
 func setupTestDB(t *testing.T) (*gorm.DB, func()) {
 	t.Helper()
 
@@ -120,7 +133,7 @@ func runTestMigrations(db *sql.DB) error {
 		return err
 	}
 
-	d, err := iofs.New(migrationFiles, "migrations")
+	d, err := migrations.Source()
 	if err != nil {
 		return err
 	}
@@ -172,7 +185,7 @@ func TestOrderRepository_UpdateOrder(t *testing.T) {
 
 	updatedOrder := createdOrder
 	updatedOrder.ID = ordID
-	updatedOrder.Address = "Updated GORM Test Address"
+	updatedOrder.Address = orders.Address{City: "Москва", Street: "Обновлённая улица", House: "2", Intercom: "25К"}
 
 	err = repo.Update(context.Background(), updatedOrder)
 	if err != nil {
@@ -186,3 +199,1375 @@ func TestOrderRepository_UpdateOrder(t *testing.T) {
 
 	testutils.ValidateOrder(t, updatedOrder, resultOrder)
 }
+
+func TestOrderRepository_SoftDelete(t *testing.T) {
+	gormDB, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := repository_orders.NewOrderRepository(gormDB)
+
+	ordID, err := repo.Create(ctx, testutils.NewTestOrder(testutils.WithEmployee(nil)))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+
+	deleted, err := repo.Delete(ctx, ordID)
+	if err != nil {
+		t.Fatalf("Failed to delete request: %v", err)
+	}
+	if deleted.DeletedAt == nil {
+		t.Errorf("Expected DeletedAt to be set")
+	}
+
+	if _, err := repo.GetByID(ctx, ordID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected deleted request to be hidden, got: %v", err)
+	}
+
+	visible, err := repo.GetAll(ctx, orders.ListFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(visible) != 0 {
+		t.Errorf("Expected no visible requests, got %d", len(visible))
+	}
+
+	all, err := repo.GetAll(ctx, orders.ListFilter{IncludeDeleted: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 || all[0].DeletedAt == nil {
+		t.Errorf("Expected the deleted request with include_deleted, got %v", all)
+	}
+}
+
+func TestOrderRepository_Archive(t *testing.T) {
+	gormDB, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := repository_orders.NewOrderRepository(gormDB)
+
+	longAgo := time.Now().AddDate(-2, 0, 0)
+	old := testutils.NewTestOrder(testutils.WithEmployee(nil), testutils.WithStatus(orders.StatusPaid))
+	old.StatusChangedAt = &longAgo
+	oldID, err := repo.Create(ctx, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	active := testutils.NewTestOrder(testutils.WithEmployee(nil))
+	active.StatusChangedAt = &longAgo
+	activeID, err := repo.Create(ctx, active)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archived, err := repo.Archive(ctx, time.Now().AddDate(-1, 0, 0), 100)
+	if err != nil {
+		t.Fatalf("Failed to archive requests: %v", err)
+	}
+	if archived != 1 {
+		t.Errorf("Expected 1 archived request, got %d", archived)
+	}
+
+	all, err := repo.GetAll(ctx, orders.ListFilter{IncludeDeleted: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 || all[0].ID != activeID {
+		t.Errorf("Expected only the active request to remain, got %v", all)
+	}
+
+	fromArchive, err := repo.GetByID(ctx, oldID)
+	if err != nil {
+		t.Fatalf("Failed to get archived request: %v", err)
+	}
+	if fromArchive.ArchivedAt == nil {
+		t.Errorf("Expected ArchivedAt to be set")
+	}
+	testutils.ValidateOrder(t, old, fromArchive)
+}
+
+func TestOrderRepository_AnonymizeByPhone(t *testing.T) {
+	gormDB, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := repository_orders.NewOrderRepository(gormDB)
+
+	longAgo := time.Now().AddDate(-2, 0, 0)
+	archived := testutils.NewTestOrder(testutils.WithEmployee(nil), testutils.WithStatus(orders.StatusCanceled))
+	archived.StatusChangedAt = &longAgo
+	archivedID, err := repo.Create(ctx, archived)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Archive(ctx, time.Now(), 100); err != nil {
+		t.Fatal(err)
+	}
+
+	activeID, err := repo.Create(ctx, testutils.NewTestOrder(testutils.WithEmployee(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherID, err := repo.Create(ctx, testutils.NewTestOrder(
+		testutils.WithEmployee(nil),
+		testutils.WithClientPhone("+79998887766"),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	audit := &orders.Erasure{Reason: orders.ErasureByRequest}
+	if err := repo.Anonymize(ctx, orders.ErasureScope{ClientPhone: testutils.ClientPhone}, audit); err != nil {
+		t.Fatalf("Failed to anonymize requests: %v", err)
+	}
+	if audit.ID == 0 || len(audit.OrderIDs) != 2 {
+		t.Errorf("Expected audit record with 2 requests, got %+v", audit)
+	}
+
+	for _, id := range []uuid.UUID{archivedID, activeID} {
+		ord, err := repo.GetByID(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ord.ClientName != "" || ord.ClientPhone != "" || ord.Address != (orders.Address{}) || ord.AnonymizedAt == nil {
+			t.Errorf("Expected request %s to be anonymized, got %+v", id, ord)
+		}
+	}
+
+	other, err := repo.GetByID(ctx, otherID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.ClientName == "" || other.AnonymizedAt != nil {
+		t.Errorf("Expected request of another client to stay intact, got %+v", other)
+	}
+}
+
+func TestOrderRepository_EncryptsPII(t *testing.T) {
+	gormDB, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	oldKeys, err := pii.NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}, bytes.Repeat([]byte{9}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKeys, err := pii.NewKeyring("k2", map[string][]byte{
+		"k1": bytes.Repeat([]byte{1}, 32),
+		"k2": bytes.Repeat([]byte{2}, 32),
+	}, bytes.Repeat([]byte{9}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := repository_orders.NewOrderRepository(gormDB, repository_orders.WithCipher(oldKeys))
+	order := testutils.NewTestOrder(testutils.WithEmployee(nil))
+	ordID, err := repo.Create(ctx, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stored entities.OrderEntity
+	if err := gormDB.First(&stored, "id = ?", ordID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.ClientPhone == order.ClientPhone || stored.ClientName == order.ClientName || strings.Contains(stored.Address, order.Address.Street) {
+		t.Errorf("Expected client data to be encrypted at rest, got %+v", stored)
+	}
+
+	rotated := repository_orders.NewOrderRepository(gormDB, repository_orders.WithCipher(newKeys))
+	updated, err := rotated.ReencryptPII(ctx, 10)
+	if err != nil {
+		t.Fatalf("Failed to re-encrypt: %v", err)
+	}
+	if updated != 1 {
+		t.Errorf("Expected 1 re-encrypted request, got %d", updated)
+	}
+
+	result, err := rotated.GetByID(ctx, ordID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutils.ValidateOrder(t, order, result)
+
+	audit := &orders.Erasure{Reason: orders.ErasureByRequest}
+	if err := rotated.Anonymize(ctx, orders.ErasureScope{ClientPhone: order.ClientPhone}, audit); err != nil {
+		t.Fatal(err)
+	}
+	if len(audit.OrderIDs) != 1 {
+		t.Errorf("Expected lookup by blind index to find the request, got %v", audit.OrderIDs)
+	}
+}
+
+func TestOrderRepository_BackfillPhoneIndex(t *testing.T) {
+	gormDB, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	keys, err := pii.NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}, bytes.Repeat([]byte{9}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := repository_orders.NewOrderRepository(gormDB, repository_orders.WithCipher(keys))
+
+	// Заявка, записанная открыто до шифрования, без слепого индекса
+	ordID, err := repository_orders.NewOrderRepository(gormDB).Create(ctx, testutils.NewTestOrder(testutils.WithEmployee(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gormDB.Exec("UPDATE public.orders SET client_phone_hash = NULL WHERE id = ?", ordID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if pending, err := repo.PhoneIndexPending(ctx); err != nil || !pending {
+		t.Fatalf("Expected the request without the phone index to be pending, got %t (%v)", pending, err)
+	}
+
+	updated, err := repo.BackfillPhoneIndex(ctx, 10)
+	if err != nil {
+		t.Fatalf("Failed to build the phone index: %v", err)
+	}
+	if updated != 1 {
+		t.Errorf("Expected 1 updated request, got %d", updated)
+	}
+	if pending, err := repo.PhoneIndexPending(ctx); err != nil || pending {
+		t.Errorf("Expected no pending requests, got %t (%v)", pending, err)
+	}
+
+	var stored entities.OrderEntity
+	if err := gormDB.First(&stored, "id = ?", ordID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.ClientPhoneHash == nil || *stored.ClientPhoneHash == testutils.ClientPhone || stored.ClientPhone == testutils.ClientPhone {
+		t.Errorf("Expected the phone to be encrypted and indexed by HMAC, got %+v", stored)
+	}
+
+	audit := &orders.Erasure{Reason: orders.ErasureByRequest}
+	if err := repo.Anonymize(ctx, orders.ErasureScope{ClientPhone: testutils.ClientPhone}, audit); err != nil {
+		t.Fatal(err)
+	}
+	if len(audit.OrderIDs) != 1 || audit.OrderIDs[0] != ordID {
+		t.Errorf("Expected lookup by blind index to find the request, got %v", audit.OrderIDs)
+	}
+}
+
+func TestOrderRepository_AnonymizeAfterPhonePatch(t *testing.T) {
+	gormDB, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	keys, err := pii.NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}, bytes.Repeat([]byte{9}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := repository_orders.NewOrderRepository(gormDB, repository_orders.WithCipher(keys))
+
+	order := testutils.NewTestOrder(testutils.WithEmployee(nil))
+	order.ID, err = repo.Create(ctx, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Телефон исправлен в произвольной записи: заявка находится по нормализованному номеру
+	phone := "8 (922) 333-44-55"
+	if err := order.Patch(&orders.OrderPatcher{ClientPhone: &phone}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Update(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+
+	scope, err := orders.NewClientErasureScope("+7 922 333 44 55")
+	if err != nil {
+		t.Fatal(err)
+	}
+	audit := &orders.Erasure{Reason: orders.ErasureByRequest}
+	if err := repo.Anonymize(ctx, scope, audit); err != nil {
+		t.Fatal(err)
+	}
+	if len(audit.OrderIDs) != 1 || audit.OrderIDs[0] != order.ID {
+		t.Fatalf("Expected the patched request to be erased, got %v", audit.OrderIDs)
+	}
+
+	erased, err := repo.GetByID(ctx, order.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if erased.ClientPhone != "" || erased.AnonymizedAt == nil {
+		t.Errorf("Expected request to be anonymized, got %+v", erased)
+	}
+}
+
+func TestOrderRepository_Each(t *testing.T) {
+	gormDB, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	keys, err := pii.NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}, bytes.Repeat([]byte{9}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := repository_orders.NewOrderRepository(gormDB, repository_orders.WithCipher(keys))
+
+	var clientIDs []uuid.UUID
+	for i := 0; i < 3; i++ {
+		id, err := repo.Create(ctx, testutils.NewTestOrder(testutils.WithEmployee(nil)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		clientIDs = append(clientIDs, id)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := repo.Create(ctx, testutils.NewTestOrder(
+			testutils.WithEmployee(nil),
+			testutils.WithClientPhone("+79998887766"),
+		)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := repo.Delete(ctx, clientIDs[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	collect := func(filter orders.ListFilter) []uuid.UUID {
+		var ids []uuid.UUID
+		err := repo.Each(ctx, filter, 2, func(ord *orders.Order) error {
+			ids = append(ids, ord.ID)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Failed to iterate requests: %v", err)
+		}
+		return ids
+	}
+
+	all := collect(orders.ListFilter{})
+	if len(all) != 4 {
+		t.Fatalf("Expected 4 requests in batches of 2, got %d", len(all))
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i-1].String() >= all[i].String() {
+			t.Errorf("Expected requests ordered by id, got %v", all)
+		}
+	}
+
+	// Телефон зашифрован, заявки клиента находятся по слепому индексу
+	client := collect(orders.ListFilter{ClientPhone: testutils.ClientPhone, IncludeDeleted: true})
+	if len(client) != 3 {
+		t.Errorf("Expected 3 requests of the client including deleted, got %v", client)
+	}
+	found, err := repo.GetAll(ctx, orders.ListFilter{ClientPhone: testutils.ClientPhone})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 || found[0].ClientPhone != testutils.ClientPhone {
+		t.Errorf("Expected 2 active requests of the client, got %+v", found)
+	}
+
+	stop := errors.New("stop")
+	visited := 0
+	err = repo.Each(ctx, orders.ListFilter{}, 2, func(*orders.Order) error {
+		visited++
+		return stop
+	})
+	if !errors.Is(err, stop) || visited != 1 {
+		t.Errorf("Expected iteration to stop on the first error, got %v after %d", err, visited)
+	}
+}
+
+func TestAttachmentRepository(t *testing.T) {
+	gormDB, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	ordersRepo := repository_orders.NewOrderRepository(gormDB)
+	repo := repository_attachments.NewAttachmentRepository(gormDB)
+
+	longAgo := time.Now().AddDate(-2, 0, 0)
+	archived := testutils.NewTestOrder(testutils.WithEmployee(nil), testutils.WithStatus(orders.StatusPaid))
+	archived.StatusChangedAt = &longAgo
+	archivedID, err := ordersRepo.Create(ctx, archived)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ordersRepo.Archive(ctx, time.Now().AddDate(-1, 0, 0), 100); err != nil {
+		t.Fatal(err)
+	}
+
+	activeID, err := ordersRepo.Create(ctx, testutils.NewTestOrder(testutils.WithEmployee(nil), testutils.WithStatus(orders.StatusInProgress)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deletedID, err := ordersRepo.Create(ctx, testutils.NewTestOrder(testutils.WithEmployee(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ordersRepo.Delete(ctx, deletedID); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name      string
+		orderID   uuid.UUID
+		expStatus orders.Status
+		expErr    error
+	}{
+		{name: "Действующая заявка", orderID: activeID, expStatus: orders.StatusInProgress},
+		{name: "Архивная заявка", orderID: archivedID, expStatus: orders.StatusPaid},
+		{name: "Удалённая заявка", orderID: deletedID, expErr: attachments.ErrNotFound},
+		{name: "Несуществующая заявка", orderID: uuid.New(), expErr: attachments.ErrNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			status, err := repo.OrderStatus(ctx, tc.orderID)
+			if !errors.Is(err, tc.expErr) {
+				t.Fatalf("Expected error %v, got %v", tc.expErr, err)
+			}
+			if tc.expErr == nil && status != tc.expStatus {
+				t.Errorf("Expected status %s, got %s", tc.expStatus.ToString(), status.ToString())
+			}
+		})
+	}
+
+	att := &attachments.Attachment{
+		ID:          uuid.New(),
+		OrderID:     activeID,
+		Kind:        attachments.KindDocument,
+		FileName:    "act.pdf",
+		ContentType: "application/pdf",
+		Size:        42,
+		CreatedAt:   time.Now().UTC().Truncate(time.Microsecond),
+	}
+	if err := repo.Create(ctx, att); err != nil {
+		t.Fatalf("Failed to create attachment: %v", err)
+	}
+
+	list, err := repo.ListByOrder(ctx, activeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ID != att.ID || list[0].FileName != att.FileName {
+		t.Errorf("Expected the created attachment, got %v", list)
+	}
+
+	if _, err := repo.GetByID(ctx, uuid.New()); !errors.Is(err, attachments.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestOrderRepository_Visits(t *testing.T) {
+	gormDB, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := repository_orders.NewOrderRepository(gormDB)
+
+	ordID, err := repo.Create(ctx, testutils.NewTestOrder(testutils.WithEmployee(nil), testutils.WithStatus(orders.StatusAssigned)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tomorrow := testutils.GetNDaysLater(1)
+	if err := repo.Schedule(ctx, ordID, &tomorrow); err != nil {
+		t.Fatalf("Failed to schedule the first visit: %v", err)
+	}
+	report := &orders.VisitReport{EmployeeDescription: "Диагностика", PartsUsed: []orders.UsedPart{{Name: "Предохранитель", Quantity: 2}}}
+	if err := repo.Progress(ctx, ordID, report); err != nil {
+		t.Fatalf("Failed to complete the first visit: %v", err)
+	}
+
+	afterFirst, err := repo.GetByID(ctx, ordID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if afterFirst.Status != orders.StatusInProgress || afterFirst.ScheduledFor != nil {
+		t.Errorf("Expected InProgress without a scheduled date, got %s %v", afterFirst.Status.ToString(), afterFirst.ScheduledFor)
+	}
+
+	if err := repo.Schedule(ctx, ordID, &tomorrow); err != nil {
+		t.Fatalf("Failed to schedule the second visit: %v", err)
+	}
+
+	visits, err := repo.GetVisits(ctx, ordID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(visits) != 2 {
+		t.Fatalf("Expected 2 visits, got %d", len(visits))
+	}
+	if visits[0].Notes != report.EmployeeDescription || visits[0].DepartedAt == nil || len(visits[0].PartsUsed) != 1 {
+		t.Errorf("Expected the first visit to be completed with the report, got %+v", visits[0])
+	}
+	if visits[1].DepartedAt != nil || visits[1].PlannedFor == nil {
+		t.Errorf("Expected the second visit to be planned, got %+v", visits[1])
+	}
+}
+
+func TestOrderRepository_AnonymizeVisits(t *testing.T) {
+	gormDB, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := repository_orders.NewOrderRepository(gormDB)
+
+	if err := gormDB.Exec("INSERT INTO public.employees (id, name) VALUES (101, 'Пётр')").Error; err != nil {
+		t.Fatal(err)
+	}
+	ordID, err := repo.Create(ctx, testutils.NewTestOrder(testutils.WithEmployee(nil), testutils.WithStatus(orders.StatusAssigned)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Assign(ctx, ordID, 101); err != nil {
+		t.Fatal(err)
+	}
+	tomorrow := testutils.GetNDaysLater(1)
+	if err := repo.Schedule(ctx, ordID, &tomorrow); err != nil {
+		t.Fatal(err)
+	}
+	position := orders.GeoPoint{Lat: 55.75, Lon: 37.61}
+	if err := repo.CheckIn(ctx, ordID, &orders.Presence{EmployeeID: 101, Position: position}); err != nil {
+		t.Fatalf("Failed to check in: %v", err)
+	}
+	report := &orders.VisitReport{EmployeeDescription: "Клиент просил звонить в домофон 12"}
+	if err := repo.CheckOut(ctx, ordID, &orders.Presence{EmployeeID: 101, Position: position}, report); err != nil {
+		t.Fatalf("Failed to check out: %v", err)
+	}
+
+	audit := &orders.Erasure{Reason: orders.ErasureByRequest}
+	if err := repo.Anonymize(ctx, orders.ErasureScope{ClientPhone: testutils.ClientPhone}, audit); err != nil {
+		t.Fatalf("Failed to anonymize requests: %v", err)
+	}
+
+	visits, err := repo.GetVisits(ctx, ordID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(visits) != 1 {
+		t.Fatalf("Expected 1 visit, got %d", len(visits))
+	}
+	if visits[0].CheckInLocation != nil || visits[0].CheckOutLocation != nil || visits[0].Notes != "" {
+		t.Errorf("Expected visit locations and notes to be erased, got %+v", visits[0])
+	}
+	if visits[0].ArrivedAt == nil || visits[0].DepartedAt == nil {
+		t.Errorf("Expected visit times to stay for statistics, got %+v", visits[0])
+	}
+}
+
+func TestOrderRepository_Geocoding(t *testing.T) {
+	gormDB, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := repository_orders.NewOrderRepository(gormDB)
+
+	ordID, err := repo.Create(ctx, testutils.NewTestOrder(testutils.WithEmployee(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	located := testutils.Address
+	located.Location = &orders.GeoPoint{Lat: 55.75, Lon: 37.62}
+	if _, err := repo.Create(ctx, testutils.NewTestOrder(testutils.WithEmployee(nil), testutils.WithAddress(located))); err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := repo.PendingGeocoding(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].ID != ordID {
+		t.Fatalf("Expected only the request without coordinates to be pending, got %d", len(pending))
+	}
+
+	if saved, err := repo.SetLocation(ctx, ordID, pending[0].Address, &orders.GeoPoint{Lat: 59.93, Lon: 30.31}); err != nil || !saved {
+		t.Fatalf("Failed to set location: %t %v", saved, err)
+	}
+	ord, err := repo.GetByID(ctx, ordID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ord.Address.Location == nil || ord.Address.Location.Lat != 59.93 || ord.Address.House != "1" {
+		t.Errorf("Expected geocoded structured address, got %+v", ord.Address)
+	}
+
+	pending, err = repo.PendingGeocoding(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("Expected no pending requests, got %d", len(pending))
+	}
+
+	// Изменение заявки без смены здания не сбрасывает координаты
+	ord.EmployeeDescription = "Позвонить заранее"
+	if err := repo.Update(ctx, ord); err != nil {
+		t.Fatal(err)
+	}
+	if pending, err = repo.PendingGeocoding(ctx, 10); err != nil || len(pending) != 0 {
+		t.Fatalf("Expected no pending requests after update, got %d (%v)", len(pending), err)
+	}
+
+	// Смена здания снова ставит заявку в очередь геокодирования
+	if err := ord.Patch(&orders.OrderPatcher{Address: &orders.Address{City: "Москва", Street: "Новая улица", House: "2"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Update(ctx, ord); err != nil {
+		t.Fatal(err)
+	}
+	if pending, err = repo.PendingGeocoding(ctx, 10); err != nil || len(pending) != 1 || pending[0].Address.Location != nil {
+		t.Fatalf("Expected the relocated request to be pending without coordinates, got %d (%v)", len(pending), err)
+	}
+
+	// Координаты прежнего адреса, найденные до смены здания, не сохраняются
+	if saved, err := repo.SetLocation(ctx, ordID, testutils.Address, &orders.GeoPoint{Lat: 59.93, Lon: 30.31}); err != nil || saved {
+		t.Fatalf("Expected stale location to be skipped, got %t (%v)", saved, err)
+	}
+	if pending, err = repo.PendingGeocoding(ctx, 10); err != nil || len(pending) != 1 {
+		t.Fatalf("Expected the relocated request to stay pending, got %d (%v)", len(pending), err)
+	}
+
+	// Координаты обезличенной заявки не сохраняются
+	relocated := pending[0].Address
+	audit := &orders.Erasure{Reason: orders.ErasureByRequest}
+	if err := repo.Anonymize(ctx, orders.ErasureScope{ClientPhone: testutils.ClientPhone}, audit); err != nil {
+		t.Fatal(err)
+	}
+	if saved, err := repo.SetLocation(ctx, ordID, relocated, &orders.GeoPoint{Lat: 55.76, Lon: 37.6}); err != nil || saved {
+		t.Fatalf("Expected location of an anonymized request to be skipped, got %t (%v)", saved, err)
+	}
+}
+
+func TestOrderRepository_GeocodingRetry(t *testing.T) {
+	gormDB, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := repository_orders.NewOrderRepository(gormDB)
+
+	failingID, err := repo.Create(ctx, testutils.NewTestOrder(testutils.WithEmployee(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherID, err := repo.Create(ctx, testutils.NewTestOrder(testutils.WithEmployee(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.GeocodingFailed(ctx, failingID); err != nil {
+		t.Fatalf("Failed to save geocoding attempt: %v", err)
+	}
+
+	pending, err := repo.PendingGeocoding(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].ID != otherID {
+		t.Fatalf("Expected the request without failed attempts to go first, got %+v", pending)
+	}
+
+	pending, err = repo.PendingGeocoding(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 || pending[1].ID != failingID {
+		t.Errorf("Expected the failed request to stay pending at the end, got %d", len(pending))
+	}
+}
+
+func TestOrderRepository_GetScheduled(t *testing.T) {
+	gormDB, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := repository_orders.NewOrderRepository(gormDB)
+
+	tomorrow := testutils.GetNDaysLater(1)
+	day := time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 0, 0, 0, 0, time.Local)
+	at := func(d time.Duration) *time.Time {
+		t := day.Add(d)
+		return &t
+	}
+
+	late, err := repo.Create(ctx, testutils.NewTestOrder(testutils.WithScheduledFor(at(15*time.Hour))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	early, err := repo.Create(ctx, testutils.NewTestOrder(testutils.WithScheduledFor(at(10*time.Hour))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ord := range []*orders.Order{
+		testutils.NewTestOrder(testutils.WithScheduledFor(at(34 * time.Hour))),
+		testutils.NewTestOrder(testutils.WithScheduledFor(at(12*time.Hour)), testutils.WithStatus(orders.StatusInProgress)),
+	} {
+		if _, err := repo.Create(ctx, ord); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scheduled, err := repo.GetScheduled(ctx, 1, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("Failed to get scheduled requests: %v", err)
+	}
+	if len(scheduled) != 2 || scheduled[0].ID != early || scheduled[1].ID != late {
+		t.Errorf("Expected 2 scheduled requests of the day ordered by time, got %d", len(scheduled))
+	}
+}
+
+func TestOrderRepository_SLA(t *testing.T) {
+	gormDB, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := repository_orders.NewOrderRepository(gormDB)
+
+	hourAgo := time.Now().Add(-time.Hour)
+	newOrder := testutils.NewTestOrder(testutils.WithEmployee(nil), testutils.WithStatus(orders.StatusNew))
+	newOrder.StatusChangedAt = &hourAgo
+	ordID, err := repo.Create(ctx, newOrder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ord, err := repo.GetByID(ctx, ordID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	candidates, err := repo.SLACandidates(ctx, orders.StatusNew, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 1 || candidates[0].ID != ordID {
+		t.Fatalf("Expected the new order to be a candidate, got %d", len(candidates))
+	}
+
+	breach := &orders.SLABreach{
+		OrderID:         ordID,
+		Status:          orders.StatusNew,
+		StatusChangedAt: *ord.StatusChangedAt,
+		Deadline:        ord.StatusChangedAt.Add(time.Hour),
+		DetectedAt:      time.Now(),
+	}
+	for i, exp := range []bool{true, false} {
+		recorded, err := repo.RecordSLABreach(ctx, breach)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if recorded != exp {
+			t.Errorf("Attempt %d: expected recorded=%v, got %v", i+1, exp, recorded)
+		}
+	}
+
+	overdue, err := repo.GetAll(ctx, orders.ListFilter{Overdue: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(overdue) != 1 || overdue[0].ID != ordID {
+		t.Fatalf("Expected the order to be overdue, got %d", len(overdue))
+	}
+	if candidates, _ := repo.SLACandidates(ctx, orders.StatusNew, time.Now()); len(candidates) != 0 {
+		t.Errorf("Expected no candidates after the breach, got %d", len(candidates))
+	}
+
+	// После смены статуса заявка перестаёт быть просроченной
+	now := time.Now()
+	ord.Status, ord.StatusChangedAt = orders.StatusPrescheduled, &now
+	if err := repo.Update(ctx, ord); err != nil {
+		t.Fatal(err)
+	}
+	if overdue, _ := repo.GetAll(ctx, orders.ListFilter{Overdue: true}); len(overdue) != 0 {
+		t.Errorf("Expected no overdue orders after the status change, got %d", len(overdue))
+	}
+}
+
+// Журнал, в который нельзя записать событие
+type failingEventRepository struct {
+	*repository_orders.GormEventRepository
+}
+
+func (failingEventRepository) Append(ctx context.Context, ev *orders.Event) (int64, error) {
+	return 0, errors.New("event log unavailable")
+}
+
+func TestOrderService_EventsInTransaction(t *testing.T) {
+	gormDB, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	orderRepo := repository_orders.NewOrderRepository(gormDB)
+	eventRepo := repository_orders.NewEventRepository(gormDB)
+	tx := repository_orders.NewTransactor(gormDB)
+	svc := orders.NewOrderService(orderRepo, orders.WithEventLog(eventRepo), orders.WithTransactor(tx))
+
+	id, err := svc.Create(ctx, &orders.PrimaryOrder{
+		ClientName:  "Иван Иванов",
+		ClientPhone: "+79161234567",
+		Address:     orders.Address{City: "Москва", Street: "ул. Примерная", House: "1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Событие не записано: отмена заявки откатывается вместе с ним
+	failing := orders.NewOrderService(orderRepo,
+		orders.WithEventLog(failingEventRepository{eventRepo}), orders.WithTransactor(tx))
+	if err := failing.Cancel(ctx, id, "клиент передумал"); err == nil {
+		t.Fatal("Expected cancel to fail without the event")
+	}
+	if ord, err := orderRepo.GetByID(ctx, id); err != nil || ord.Status != orders.StatusNew {
+		t.Fatalf("Expected order to stay New, got %+v, %v", ord, err)
+	}
+
+	if err := svc.Cancel(ctx, id, "клиент передумал"); err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		if _, err := eventRepo.Append(ctx, orders.NewEvent(orders.EventSLABreached, &orders.Order{ID: id})); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Журнал читается порциями не больше limit
+	var types []orders.EventType
+	for after := int64(0); ; {
+		page, err := eventRepo.ListSince(ctx, after, orders.EventFilter{}, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) > 2 {
+			t.Fatalf("Expected at most 2 events per page, got %d", len(page))
+		}
+		for _, ev := range page {
+			types = append(types, ev.Type)
+			after = ev.ID
+		}
+		if len(page) < 2 {
+			break
+		}
+	}
+	if len(types) != 5 || types[0] != orders.EventCreated || types[1] != orders.EventCanceled {
+		t.Errorf("Expected created, canceled and 3 more events, got %v", types)
+	}
+}
+
+func TestTemplateRepository(t *testing.T) {
+	gormDB, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	keys, err := pii.NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}, bytes.Repeat([]byte{9}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := repository_recurring.NewTemplateRepository(gormDB, repository_recurring.WithCipher(keys))
+	orderRepo := repository_orders.NewOrderRepository(gormDB, repository_orders.WithCipher(keys))
+
+	today := recurring.NewDate(time.Now())
+	pt := recurring.PrimaryTemplate{
+		Order: orders.PrimaryOrder{
+			ClientName:  "Иван",
+			ClientPhone: testutils.ClientPhone,
+			Address:     orders.Address{City: "Москва", Street: "Ленина", House: "1"},
+			Category:    "boiler",
+		},
+		RRule: "FREQ=MONTHLY",
+		Start: today,
+	}
+	tmpl, err := pt.CreateNewTemplate(today)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.CreatedAt = time.Now()
+	if err := repo.Create(ctx, tmpl); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	stored, err := repo.GetByID(ctx, tmpl.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Order.ClientName != "Иван" || stored.Order.Address.Street != "Ленина" || stored.NextOn.String() != today.String() {
+		t.Errorf("Expected stored template to match, got %+v", stored)
+	}
+
+	byClient, err := repo.GetAll(ctx, recurring.ListFilter{ClientPhone: tmpl.Order.ClientPhone})
+	if err != nil || len(byClient) != 1 {
+		t.Fatalf("Expected template found by client phone, got %d, %v", len(byClient), err)
+	}
+
+	due, err := repo.Due(ctx, today, 10)
+	if err != nil || len(due) != 1 {
+		t.Fatalf("Expected 1 due template, got %d, %v", len(due), err)
+	}
+
+	// Повторение занимается условным сохранением: второй раз с тем же исходным состоянием оно не проходит
+	claimed := *due[0]
+	if err := claimed.Advance(); err != nil {
+		t.Fatal(err)
+	}
+	for i, exp := range []bool{true, false} {
+		ok, err := repo.Save(ctx, &claimed, due[0].Status, due[0].NextOn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != exp {
+			t.Errorf("Save attempt %d: expected %v, got %v", i+1, exp, ok)
+		}
+	}
+	if due, err := repo.Due(ctx, today, 10); err != nil || len(due) != 0 {
+		t.Errorf("Expected no due templates after claim, got %d, %v", len(due), err)
+	}
+
+	pord := tmpl.NewOrder()
+	order, err := pord.CreateNewOrder(orders.Scheduling{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ordID, err := orderRepo.Create(ctx, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated, err := orderRepo.GetAll(ctx, orders.ListFilter{TemplateID: &tmpl.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(generated) != 1 || generated[0].ID != ordID || generated[0].TemplateID == nil || *generated[0].TemplateID != tmpl.ID {
+		t.Errorf("Expected the order generated from the template, got %+v", generated)
+	}
+
+	// Обезличивание клиента удаляет его шаблоны
+	audit := &orders.Erasure{Reason: orders.ErasureByRequest}
+	if err := orderRepo.Anonymize(ctx, orders.ErasureScope{ClientPhone: tmpl.Order.ClientPhone}, audit); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetByID(ctx, tmpl.ID); !errors.Is(err, recurring.ErrNotFound) {
+		t.Errorf("Expected template of erased client to be deleted, got %v", err)
+	}
+}
+
+func TestInventoryRepository(t *testing.T) {
+	gormDB, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	orderRepo := repository_orders.NewOrderRepository(gormDB)
+	repo := repository_inventory.NewInventoryRepository(gormDB)
+
+	orderID, err := orderRepo.Create(ctx, testutils.NewTestOrder(testutils.WithEmployee(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	part, err := (&inventory.PrimaryPart{SKU: "VALVE-1", Name: "Клапан", Price: 150000, LowStockThreshold: 3}).CreateNewPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreatePart(ctx, part); err != nil {
+		t.Fatalf("Failed to create part: %v", err)
+	}
+	duplicate := *part
+	duplicate.ID = uuid.New()
+	if err := repo.CreatePart(ctx, &duplicate); !errors.Is(err, inventory.ErrDuplicateSKU) {
+		t.Errorf("Expected ErrDuplicateSKU, got %v", err)
+	}
+
+	van, warehouse := inventory.Van(7), inventory.Warehouse("main")
+	for _, rc := range []inventory.Receipt{
+		{PartID: part.ID, Location: warehouse, Quantity: 5},
+		{PartID: part.ID, Location: van, Quantity: 2},
+	} {
+		if _, err := repo.Receive(ctx, &rc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.SetOrderParts(ctx, orderID, []inventory.OrderPart{{PartID: part.ID, Quantity: 4}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Резерв сначала берётся из машины, недостающее — со склада
+	const reserveEvent, consumeEvent = 1001, 1002
+	changes, err := repo.Reserve(ctx, orderID, []inventory.Location{van, warehouse}, reserveEvent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Before != 7 || changes[0].After != 3 {
+		t.Errorf("Expected available 7 -> 3, got %+v", changes)
+	}
+	if _, err := repo.Reserve(ctx, orderID, []inventory.Location{van, warehouse}, reserveEvent); !errors.Is(err, inventory.ErrEventProcessed) {
+		t.Errorf("Expected ErrEventProcessed on repeated event, got %v", err)
+	}
+	parts, err := repo.GetOrderParts(ctx, orderID)
+	if err != nil || len(parts) != 1 || parts[0].Reserved != 4 {
+		t.Fatalf("Expected 4 parts reserved, got %+v, %v", parts, err)
+	}
+
+	// Списывается 3 из 4 зарезервированных; остаток резерва на складе снимается
+	if _, err := repo.Consume(ctx, orderID, van, []inventory.Usage{{PartID: part.ID, Quantity: 3}}, consumeEvent); err != nil {
+		t.Fatal(err)
+	}
+	stock, err := repo.GetStock(ctx, inventory.StockFilter{PartID: &part.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	levels := map[inventory.Location]*inventory.Stock{}
+	for _, s := range stock {
+		levels[s.Location] = s
+	}
+	if s := levels[van]; s == nil || s.OnHand != 0 || s.Reserved != 0 {
+		t.Errorf("Expected empty van, got %+v", s)
+	}
+	if s := levels[warehouse]; s == nil || s.OnHand != 4 || s.Reserved != 0 || s.Available != 4 {
+		t.Errorf("Expected 4 available in warehouse, got %+v", s)
+	}
+
+	items, err := repo.GetLineItems(ctx, orderID)
+	if err != nil || len(items) != 1 || items[0].Quantity != 3 || items[0].Total != 450000 {
+		t.Fatalf("Expected line item for 3 parts, got %+v, %v", items, err)
+	}
+
+	if _, err := repo.Return(ctx, orderID, &inventory.Return{PartID: part.ID, Location: warehouse, Quantity: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Return(ctx, orderID, &inventory.Return{PartID: part.ID, Location: warehouse, Quantity: 5}); !errors.Is(err, inventory.ErrInsufficientStock) {
+		t.Errorf("Expected ErrInsufficientStock when returning more than consumed, got %v", err)
+	}
+	if items, _ := repo.GetLineItems(ctx, orderID); len(items) != 1 || items[0].Quantity != 2 {
+		t.Errorf("Expected line item reduced by return, got %+v", items)
+	}
+
+	if err := repo.Transfer(ctx, &inventory.Transfer{PartID: part.ID, From: warehouse, To: van, Quantity: 10}); !errors.Is(err, inventory.ErrInsufficientStock) {
+		t.Errorf("Expected ErrInsufficientStock on transfer, got %v", err)
+	}
+	if err := repo.Transfer(ctx, &inventory.Transfer{PartID: part.ID, From: warehouse, To: van, Quantity: 2}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInventoryRepository_OrderEvents(t *testing.T) {
+	gormDB, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	orderRepo := repository_orders.NewOrderRepository(gormDB)
+	eventRepo := repository_orders.NewEventRepository(gormDB)
+	repo := repository_inventory.NewInventoryRepository(gormDB)
+
+	orderID, err := orderRepo.Create(ctx, testutils.NewTestOrder(testutils.WithEmployee(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendEvent := func(eventType orders.EventType) *orders.Event {
+		t.Helper()
+		ev := &orders.Event{Type: eventType, OrderID: orderID, Status: orders.StatusScheduled}
+		id, err := eventRepo.Append(ctx, ev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ev.ID = id
+		return ev
+	}
+	first, second, third := appendEvent(orders.EventCreated), appendEvent(orders.EventScheduled), appendEvent(orders.EventCanceled)
+
+	if _, err := repo.Release(ctx, orderID, third.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.MarkProcessed(ctx, []int64{first.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Необработанное событие между обработанными остаётся в очереди и держит позицию
+	pending, err := repo.PendingOrderEvents(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].ID != second.ID {
+		t.Fatalf("Expected only the unprocessed event pending, got %+v", pending)
+	}
+	if err := repo.CompactProcessed(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	if pending, _ = repo.PendingOrderEvents(ctx, 10); len(pending) != 1 || pending[0].ID != second.ID {
+		t.Fatalf("Expected compaction to keep the unprocessed event, got %+v", pending)
+	}
+
+	if _, err := repo.Reserve(ctx, orderID, []inventory.Location{inventory.Van(7)}, second.ID); err != nil {
+		t.Fatal(err)
+	}
+	// Свежие события не сдвигают позицию, пока не пройдёт время ожидания
+	if err := repo.CompactProcessed(ctx, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Release(ctx, orderID, third.ID); !errors.Is(err, inventory.ErrEventProcessed) {
+		t.Errorf("Expected ErrEventProcessed before compaction, got %v", err)
+	}
+	if err := repo.CompactProcessed(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	if pending, _ = repo.PendingOrderEvents(ctx, 10); len(pending) != 0 {
+		t.Errorf("Expected no pending events, got %+v", pending)
+	}
+	var marks int64
+	if err := gormDB.Table("public.inventory_processed_events").Count(&marks).Error; err != nil || marks != 0 {
+		t.Errorf("Expected marks below the cursor to be deleted, got %d (%v)", marks, err)
+	}
+}
+
+func TestInventoryRepository_FailedEvents(t *testing.T) {
+	gormDB, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	orderRepo := repository_orders.NewOrderRepository(gormDB)
+	eventRepo := repository_orders.NewEventRepository(gormDB)
+	repo := repository_inventory.NewInventoryRepository(gormDB)
+
+	orderID, err := orderRepo.Create(ctx, testutils.NewTestOrder(testutils.WithEmployee(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	for _, eventType := range []orders.EventType{orders.EventScheduled, orders.EventCanceled} {
+		id, err := eventRepo.Append(ctx, &orders.Event{Type: eventType, OrderID: orderID, Status: orders.StatusScheduled})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+
+	for range 2 {
+		if err := repo.EventFailed(ctx, ids[0], errors.New("deadlock detected")); err != nil {
+			t.Fatalf("Failed to record event failure: %v", err)
+		}
+	}
+	var attempts int
+	if err := gormDB.Raw("SELECT attempts FROM public.inventory_failed_events WHERE order_event_id = ?", ids[0]).Scan(&attempts).Error; err != nil || attempts != 2 {
+		t.Errorf("Expected 2 attempts, got %d (%v)", attempts, err)
+	}
+
+	// Сбойное событие — после остальных
+	pending, err := repo.PendingOrderEvents(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 || pending[0].ID != ids[1] || pending[1].ID != ids[0] {
+		t.Fatalf("Expected the failed event to be pending last, got %+v", pending)
+	}
+
+	if err := repo.MarkProcessed(ctx, ids); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CompactProcessed(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	var failures int64
+	if err := gormDB.Table("public.inventory_failed_events").Count(&failures).Error; err != nil || failures != 0 {
+		t.Errorf("Expected failures of processed events to be deleted, got %d (%v)", failures, err)
+	}
+}
+
+func TestReportRepository(t *testing.T) {
+	gormDB, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := repository_reports.NewReportRepository(gormDB)
+	orderRepo := repository_orders.NewOrderRepository(gormDB)
+	eventRepo := repository_orders.NewEventRepository(gormDB)
+
+	if err := gormDB.Exec("INSERT INTO public.employees (id, name) VALUES (101, 'Пётр')").Error; err != nil {
+		t.Fatal(err)
+	}
+	empID := uint(101)
+
+	canceled := testutils.NewTestOrder(testutils.WithEmployee(nil), testutils.WithStatus(orders.StatusCanceled))
+	canceled.CancelReason = "  Клиент   Передумал "
+	canceledID, err := orderRepo.Create(ctx, canceled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paidID, deletedID, earlierID := uuid.New(), uuid.New(), uuid.New()
+
+	at := func(day, hour int) time.Time {
+		return time.Date(2026, time.March, day, hour, 0, 0, 0, time.UTC)
+	}
+	events := []*orders.Event{
+		{Type: orders.EventCreated, OrderID: earlierID, Status: orders.StatusNew, CreatedAt: at(-1, 10)},
+		{Type: orders.EventCreated, OrderID: paidID, Status: orders.StatusNew, CreatedAt: at(1, 9)},
+		{Type: orders.EventCreated, OrderID: canceledID, Status: orders.StatusNew, CreatedAt: at(1, 10)},
+		{Type: orders.EventCreated, OrderID: deletedID, Status: orders.StatusNew, CreatedAt: at(1, 11)},
+		{Type: orders.EventDeleted, OrderID: deletedID, Status: orders.StatusNew, CreatedAt: at(1, 12)},
+		{Type: orders.EventAssigned, OrderID: paidID, Status: orders.StatusAssigned, EmployeeID: &empID, CreatedAt: at(1, 13)},
+		{Type: orders.EventScheduled, OrderID: paidID, Status: orders.StatusScheduled, EmployeeID: &empID, CreatedAt: at(1, 14)},
+		{Type: orders.EventCompleted, OrderID: paidID, Status: orders.StatusDone, EmployeeID: &empID, CreatedAt: at(2, 9)},
+		{Type: orders.EventCanceled, OrderID: canceledID, Status: orders.StatusCanceled, CreatedAt: at(2, 10)},
+		{Type: orders.EventCompleted, OrderID: earlierID, Status: orders.StatusDone, EmployeeID: &empID, CreatedAt: at(2, 10)},
+		{Type: orders.EventClosed, OrderID: paidID, Status: orders.StatusPaid, EmployeeID: &empID, CreatedAt: at(3, 12)},
+	}
+	for _, ev := range events {
+		if _, err := eventRepo.Append(ctx, ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lineItem := &entities.OrderLineItemEntity{OrderID: paidID, PartID: uuid.New(), SKU: "VALVE-1", Name: "Клапан", Quantity: 2, UnitPrice: 150000}
+	if err := gormDB.Create(lineItem).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	period, err := reports.NewPeriod(reports.DateRange{From: "2026-03-01", To: "2026-03-02"}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	days, err := repo.Throughput(ctx, period)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expDays := []reports.DayThroughput{
+		{Date: "2026-03-01", Created: 2},
+		{Date: "2026-03-02", Completed: 2, Canceled: 1},
+	}
+	if fmt.Sprint(days) != fmt.Sprint(expDays) {
+		t.Errorf("Expected throughput %v, got %v", expDays, days)
+	}
+
+	transitions, err := repo.Transitions(ctx, period)
+	if err != nil {
+		t.Fatal(err)
+	}
+	medians := make(map[string]float64)
+	for _, tr := range transitions {
+		medians[tr.FromStatus+"->"+tr.ToStatus] = tr.MedianSeconds
+	}
+	expMedians := map[string]float64{
+		"New->Assigned":       4 * 3600,
+		"Assigned->Scheduled": 3600,
+		"Scheduled->Done":     19 * 3600,
+		"New->Canceled":       24 * 3600,
+		"New->Done":           72 * 3600,
+	}
+	if fmt.Sprint(medians) != fmt.Sprint(expMedians) {
+		t.Errorf("Expected transitions %v, got %v", expMedians, medians)
+	}
+
+	reasons, err := repo.CancelReasons(ctx, period)
+	if err != nil || len(reasons) != 1 || reasons[0].Reason != "клиент передумал" || reasons[0].Count != 1 {
+		t.Errorf("Expected one normalized cancel reason, got %+v, %v", reasons, err)
+	}
+
+	employees, err := repo.EmployeeCompletions(ctx, period)
+	if err != nil || len(employees) != 1 || employees[0].Name != "Пётр" || employees[0].Completed != 2 {
+		t.Errorf("Expected 2 completions by the employee, got %+v, %v", employees, err)
+	}
+
+	withPayment, err := reports.NewPeriod(reports.DateRange{From: "2026-03-01", To: "2026-03-03"}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revenue, err := repo.Revenue(ctx, withPayment)
+	if err != nil || len(revenue) != 1 || revenue[0].Date != "2026-03-03" || revenue[0].PaidOrders != 1 || revenue[0].Amount != 300000 {
+		t.Errorf("Expected revenue of 300000 on the payment day, got %+v, %v", revenue, err)
+	}
+}
+
+func TestIdempotencyKeyRepository(t *testing.T) {
+	gormDB, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := repository_idempotency.NewKeyRepository(gormDB)
+	now := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	rec := &idempotency.Record{Key: "k1", Fingerprint: "a", CreatedAt: now, ExpiresAt: now.Add(time.Minute)}
+
+	if existing, err := repo.Reserve(ctx, rec); err != nil || existing != nil {
+		t.Fatalf("Expected key to be reserved, got %+v, %v", existing, err)
+	}
+	existing, err := repo.Reserve(ctx, &idempotency.Record{Key: "k1", Fingerprint: "b", CreatedAt: now, ExpiresAt: now.Add(time.Minute)})
+	if err != nil || existing == nil || existing.Fingerprint != "a" || !existing.Pending() {
+		t.Fatalf("Expected pending record of the first request, got %+v, %v", existing, err)
+	}
+
+	resp := &idempotency.Response{StatusCode: 201, ContentType: "application/json", Body: []byte(`"id"`)}
+	if err := repo.Complete(ctx, "k1", "a", resp, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	// Ответ сохранён, поэтому Release ключ уже не освобождает
+	if err := repo.Release(ctx, "k1", "a"); err != nil {
+		t.Fatal(err)
+	}
+	existing, err = repo.Reserve(ctx, &idempotency.Record{Key: "k1", Fingerprint: "a", CreatedAt: now.Add(time.Minute), ExpiresAt: now.Add(2 * time.Minute)})
+	if err != nil || existing == nil || existing.StatusCode != 201 || string(existing.Body) != `"id"` {
+		t.Fatalf("Expected stored response, got %+v, %v", existing, err)
+	}
+
+	// После срока хранения ключ занимается заново
+	later := now.Add(2 * time.Hour)
+	if existing, err := repo.Reserve(ctx, &idempotency.Record{Key: "k1", Fingerprint: "c", CreatedAt: later, ExpiresAt: later.Add(time.Minute)}); err != nil || existing != nil {
+		t.Fatalf("Expected expired key to be reserved again, got %+v, %v", existing, err)
+	}
+	if err := repo.Release(ctx, "k1", "c"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"k2", "k3"} {
+		if _, err := repo.Reserve(ctx, &idempotency.Record{Key: key, Fingerprint: "a", CreatedAt: now, ExpiresAt: now.Add(time.Minute)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n, err := repo.DeleteExpired(ctx, later, 10)
+	if err != nil || n != 2 {
+		t.Errorf("Expected 2 expired keys deleted, got %d, %v", n, err)
+	}
+}
+
+func TestIdempotencyKeyRepository_ClientData(t *testing.T) {
+	gormDB, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	keys, err := pii.NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}, bytes.Repeat([]byte{9}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	repo := repository_idempotency.NewKeyRepository(gormDB, repository_idempotency.WithCipher(keys))
+	orderRepo := repository_orders.NewOrderRepository(gormDB, repository_orders.WithCipher(keys))
+	now := time.Now()
+
+	body := []byte(`{"client_phone":"+71112223344"}`)
+	for _, key := range []string{"client", "other"} {
+		if _, err := repo.Reserve(ctx, &idempotency.Record{Key: key, Fingerprint: "a", CreatedAt: now, ExpiresAt: now.Add(time.Minute)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.Complete(ctx, "client", "a", &idempotency.Response{StatusCode: 201, ContentType: "application/json", Body: body, ClientPhone: "+71112223344"}, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Complete(ctx, "other", "a", &idempotency.Response{StatusCode: 201, ContentType: "application/json", Body: []byte(`"id"`)}, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Тело хранится зашифрованным и расшифровывается при повторе
+	var raw []byte
+	if err := gormDB.Raw("SELECT body FROM public.idempotency_keys WHERE key = 'client'").Scan(&raw).Error; err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte("+71112223344")) {
+		t.Errorf("Expected encrypted response body, got %s", raw)
+	}
+	existing, err := repo.Reserve(ctx, &idempotency.Record{Key: "client", Fingerprint: "a", CreatedAt: now, ExpiresAt: now.Add(time.Minute)})
+	if err != nil || existing == nil || !bytes.Equal(existing.Body, body) {
+		t.Fatalf("Expected decrypted stored response, got %+v, %v", existing, err)
+	}
+
+	// Обезличивание клиента удаляет ответы с его данными
+	audit := &orders.Erasure{Reason: orders.ErasureByRequest}
+	if err := orderRepo.Anonymize(ctx, orders.ErasureScope{ClientPhone: "+71112223344"}, audit); err != nil {
+		t.Fatal(err)
+	}
+	var left []string
+	if err := gormDB.Raw("SELECT key FROM public.idempotency_keys ORDER BY key").Scan(&left).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 1 || left[0] != "other" {
+		t.Errorf("Expected only the response without client data to remain, got %v", left)
+	}
+}
@@ -0,0 +1,55 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/attachments"
+	"github.com/google/uuid"
+)
+
+type AttachmentEntity struct {
+	ID           uuid.UUID `gorm:"primaryKey;type:uuid"`
+	OrderID      uuid.UUID `gorm:"type:uuid;not null"`
+	Kind         string    `gorm:"not null"`
+	FileName     string    `gorm:"not null"`
+	ContentType  string    `gorm:"not null"`
+	Size         int64     `gorm:"not null"`
+	HasThumbnail bool      `gorm:"not null"`
+	CreatedAt    time.Time `gorm:"not null"`
+}
+
+func (AttachmentEntity) TableName() string {
+	return "public.order_attachments"
+}
+
+func NewAttachmentEntityFromLogic(att *attachments.Attachment) *AttachmentEntity {
+	if att == nil {
+		return nil
+	}
+	return &AttachmentEntity{
+		ID:           att.ID,
+		OrderID:      att.OrderID,
+		Kind:         string(att.Kind),
+		FileName:     att.FileName,
+		ContentType:  att.ContentType,
+		Size:         att.Size,
+		HasThumbnail: att.HasThumbnail,
+		CreatedAt:    att.CreatedAt,
+	}
+}
+
+func (ae *AttachmentEntity) ToLogicAttachment() *attachments.Attachment {
+	if ae == nil {
+		return nil
+	}
+	return &attachments.Attachment{
+		ID:           ae.ID,
+		OrderID:      ae.OrderID,
+		Kind:         attachments.Kind(ae.Kind),
+		FileName:     ae.FileName,
+		ContentType:  ae.ContentType,
+		Size:         ae.Size,
+		HasThumbnail: ae.HasThumbnail,
+		CreatedAt:    ae.CreatedAt,
+	}
+}
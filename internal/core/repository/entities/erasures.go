@@ -0,0 +1,37 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/google/uuid"
+)
+
+type ErasureEntity struct {
+	ID        int64       `gorm:"primaryKey"`
+	Reason    string      `gorm:"not null"`
+	OrderIDs  []uuid.UUID `gorm:"serializer:json;not null"`
+	RequestID string
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+func (ErasureEntity) TableName() string {
+	return "public.erasure_audit"
+}
+
+func NewErasureEntityFromLogic(er *orders.Erasure) *ErasureEntity {
+	if er == nil {
+		return nil
+	}
+	ee := &ErasureEntity{
+		ID:        er.ID,
+		Reason:    string(er.Reason),
+		OrderIDs:  er.OrderIDs,
+		RequestID: er.RequestID,
+		CreatedAt: er.CreatedAt,
+	}
+	if ee.OrderIDs == nil {
+		ee.OrderIDs = []uuid.UUID{}
+	}
+	return ee
+}
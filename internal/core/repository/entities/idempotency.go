@@ -0,0 +1,74 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/idempotency"
+)
+
+type IdempotencyKeyEntity struct {
+	Key         string `gorm:"primaryKey"`
+	Fingerprint string `gorm:"not null"`
+	StatusCode  int    `gorm:"not null"`
+	ContentType string `gorm:"not null"`
+	Body        []byte `gorm:"type:bytea"`
+	// Слепой индекс телефона клиента, данные которого есть в теле ответа
+	ClientPhoneHash *string
+	CreatedAt       time.Time `gorm:"not null"`
+	ExpiresAt       time.Time `gorm:"not null"`
+}
+
+func (IdempotencyKeyEntity) TableName() string {
+	return "public.idempotency_keys"
+}
+
+// Тело ответа шифруется так же, как персональные данные заявки
+func NewIdempotencyKeyEntityFromLogic(rec *idempotency.Record, pc PIICipher) (*IdempotencyKeyEntity, error) {
+	if rec == nil {
+		return nil, nil
+	}
+	body, err := pc.Encrypt(string(rec.Body))
+	if err != nil {
+		return nil, err
+	}
+	e := &IdempotencyKeyEntity{
+		Key:         rec.Key,
+		Fingerprint: rec.Fingerprint,
+		StatusCode:  rec.StatusCode,
+		ContentType: rec.ContentType,
+		CreatedAt:   rec.CreatedAt,
+		ExpiresAt:   rec.ExpiresAt,
+	}
+	if body != "" {
+		e.Body = []byte(body)
+	}
+	if rec.ClientPhone != "" {
+		hash := pc.BlindIndex(rec.ClientPhone)
+		e.ClientPhoneHash = &hash
+	}
+	return e, nil
+}
+
+func (e *IdempotencyKeyEntity) ToLogicRecord(pc PIICipher) (*idempotency.Record, error) {
+	if e == nil {
+		return nil, nil
+	}
+	rec := &idempotency.Record{
+		Key:         e.Key,
+		Fingerprint: e.Fingerprint,
+		Response: idempotency.Response{
+			StatusCode:  e.StatusCode,
+			ContentType: e.ContentType,
+		},
+		CreatedAt: e.CreatedAt,
+		ExpiresAt: e.ExpiresAt,
+	}
+	if len(e.Body) > 0 {
+		body, err := pc.Decrypt(string(e.Body))
+		if err != nil {
+			return nil, err
+		}
+		rec.Body = []byte(body)
+	}
+	return rec, nil
+}
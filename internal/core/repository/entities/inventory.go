@@ -0,0 +1,219 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/inventory"
+	"github.com/google/uuid"
+)
+
+type PartEntity struct {
+	ID                uuid.UUID `gorm:"primaryKey;type:uuid"`
+	SKU               string    `gorm:"column:sku;not null"`
+	Name              string    `gorm:"not null"`
+	Price             int64     `gorm:"not null"`
+	LowStockThreshold int       `gorm:"not null"`
+	CreatedAt         time.Time `gorm:"not null;default:now()"`
+}
+
+func (PartEntity) TableName() string {
+	return "public.parts"
+}
+
+func NewPartEntityFromLogic(p *inventory.Part) *PartEntity {
+	if p == nil {
+		return nil
+	}
+	return &PartEntity{
+		ID:                p.ID,
+		SKU:               p.SKU,
+		Name:              p.Name,
+		Price:             p.Price,
+		LowStockThreshold: p.LowStockThreshold,
+		CreatedAt:         p.CreatedAt,
+	}
+}
+
+func (pe *PartEntity) ToLogicPart() *inventory.Part {
+	if pe == nil {
+		return nil
+	}
+	return &inventory.Part{
+		ID:                pe.ID,
+		SKU:               pe.SKU,
+		Name:              pe.Name,
+		Price:             pe.Price,
+		LowStockThreshold: pe.LowStockThreshold,
+		CreatedAt:         pe.CreatedAt,
+	}
+}
+
+type StockLevelEntity struct {
+	PartID   uuid.UUID `gorm:"primaryKey;type:uuid"`
+	Location string    `gorm:"primaryKey"`
+	OnHand   int       `gorm:"not null"`
+	Reserved int       `gorm:"not null"`
+	// Заполняются только при чтении остатков вместе с каталогом
+	SKU  string `gorm:"column:sku;->"`
+	Name string `gorm:"->"`
+}
+
+func (StockLevelEntity) TableName() string {
+	return "public.stock_levels"
+}
+
+func (se *StockLevelEntity) ToLogicStock() *inventory.Stock {
+	if se == nil {
+		return nil
+	}
+	return &inventory.Stock{
+		PartID:    se.PartID,
+		SKU:       se.SKU,
+		Name:      se.Name,
+		Location:  inventory.Location(se.Location),
+		OnHand:    se.OnHand,
+		Reserved:  se.Reserved,
+		Available: se.OnHand - se.Reserved,
+	}
+}
+
+type StockMovementEntity struct {
+	ID        int64      `gorm:"primaryKey"`
+	PartID    uuid.UUID  `gorm:"type:uuid;not null"`
+	Location  string     `gorm:"not null"`
+	Kind      string     `gorm:"not null"`
+	Quantity  int        `gorm:"not null"`
+	OrderID   *uuid.UUID `gorm:"type:uuid"`
+	CreatedAt time.Time  `gorm:"not null;default:now()"`
+}
+
+func (StockMovementEntity) TableName() string {
+	return "public.stock_movements"
+}
+
+type OrderPartEntity struct {
+	OrderID  uuid.UUID `gorm:"primaryKey;type:uuid"`
+	PartID   uuid.UUID `gorm:"primaryKey;type:uuid"`
+	Quantity int       `gorm:"not null"`
+	// Заполняется только при чтении вместе с резервом
+	Reserved int `gorm:"->"`
+}
+
+func (OrderPartEntity) TableName() string {
+	return "public.order_parts"
+}
+
+func (ope *OrderPartEntity) ToLogicOrderPart() *inventory.OrderPart {
+	if ope == nil {
+		return nil
+	}
+	return &inventory.OrderPart{
+		PartID:   ope.PartID,
+		Quantity: ope.Quantity,
+		Reserved: ope.Reserved,
+	}
+}
+
+type PartReservationEntity struct {
+	OrderID  uuid.UUID `gorm:"primaryKey;type:uuid"`
+	PartID   uuid.UUID `gorm:"primaryKey;type:uuid"`
+	Location string    `gorm:"primaryKey"`
+	Quantity int       `gorm:"not null"`
+}
+
+func (PartReservationEntity) TableName() string {
+	return "public.part_reservations"
+}
+
+type OrderLineItemEntity struct {
+	OrderID   uuid.UUID `gorm:"primaryKey;type:uuid"`
+	PartID    uuid.UUID `gorm:"primaryKey;type:uuid"`
+	SKU       string    `gorm:"column:sku;not null"`
+	Name      string    `gorm:"not null"`
+	Quantity  int       `gorm:"not null"`
+	UnitPrice int64     `gorm:"not null"`
+}
+
+func (OrderLineItemEntity) TableName() string {
+	return "public.order_line_items"
+}
+
+func NewOrderLineItemEntityFromLogic(orderID uuid.UUID, li *inventory.LineItem) *OrderLineItemEntity {
+	if li == nil {
+		return nil
+	}
+	return &OrderLineItemEntity{
+		OrderID:   orderID,
+		PartID:    li.PartID,
+		SKU:       li.SKU,
+		Name:      li.Name,
+		Quantity:  li.Quantity,
+		UnitPrice: li.UnitPrice,
+	}
+}
+
+func (le *OrderLineItemEntity) ToLogicLineItem() *inventory.LineItem {
+	if le == nil {
+		return nil
+	}
+	return &inventory.LineItem{
+		PartID:    le.PartID,
+		SKU:       le.SKU,
+		Name:      le.Name,
+		Quantity:  le.Quantity,
+		UnitPrice: le.UnitPrice,
+		Total:     le.UnitPrice * int64(le.Quantity),
+	}
+}
+
+type InventoryEventEntity struct {
+	ID        int64     `gorm:"primaryKey"`
+	Type      string    `gorm:"not null"`
+	PartID    uuid.UUID `gorm:"type:uuid;not null"`
+	SKU       string    `gorm:"column:sku;not null"`
+	Available int       `gorm:"not null"`
+	Threshold int       `gorm:"not null"`
+	CreatedAt time.Time `gorm:"not null;default:now()"`
+}
+
+func (InventoryEventEntity) TableName() string {
+	return "public.inventory_events"
+}
+
+func NewInventoryEventEntityFromLogic(ev *inventory.Event) *InventoryEventEntity {
+	if ev == nil {
+		return nil
+	}
+	return &InventoryEventEntity{
+		Type:      string(ev.Type),
+		PartID:    ev.PartID,
+		SKU:       ev.SKU,
+		Available: ev.Available,
+		Threshold: ev.Threshold,
+	}
+}
+
+func (ie *InventoryEventEntity) ToLogicEvent() *inventory.Event {
+	if ie == nil {
+		return nil
+	}
+	return &inventory.Event{
+		ID:        ie.ID,
+		Type:      inventory.EventType(ie.Type),
+		PartID:    ie.PartID,
+		SKU:       ie.SKU,
+		Available: ie.Available,
+		Threshold: ie.Threshold,
+		CreatedAt: ie.CreatedAt,
+	}
+}
+
+// Событие заявки, уже обработанное складом
+type ProcessedOrderEventEntity struct {
+	OrderEventID int64     `gorm:"primaryKey;autoIncrement:false"`
+	ProcessedAt  time.Time `gorm:"not null;default:now()"`
+}
+
+func (ProcessedOrderEventEntity) TableName() string {
+	return "public.inventory_processed_events"
+}
@@ -0,0 +1,49 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/google/uuid"
+)
+
+type OrderEventEntity struct {
+	ID         int64     `gorm:"primaryKey" json:"id"`
+	OrderID    uuid.UUID `gorm:"type:uuid;not null" json:"order_id"`
+	Type       string    `gorm:"not null" json:"type"`
+	Status     int       `gorm:"not null" json:"status"`
+	EmployeeID *uint     `json:"employee_id"`
+	CreatedAt  time.Time `gorm:"not null;default:now()" json:"created_at"`
+}
+
+func (OrderEventEntity) TableName() string {
+	return "public.order_events"
+}
+
+func NewOrderEventEntityFromLogic(ev *orders.Event) *OrderEventEntity {
+	if ev == nil {
+		return nil
+	}
+	return &OrderEventEntity{
+		ID:         ev.ID,
+		OrderID:    ev.OrderID,
+		Type:       string(ev.Type),
+		Status:     int(ev.Status),
+		EmployeeID: ev.EmployeeID,
+		CreatedAt:  ev.CreatedAt,
+	}
+}
+
+func (oee *OrderEventEntity) ToLogicEvent() *orders.Event {
+	if oee == nil {
+		return nil
+	}
+	return &orders.Event{
+		ID:         oee.ID,
+		Type:       orders.EventType(oee.Type),
+		OrderID:    oee.OrderID,
+		Status:     orders.Status(oee.Status),
+		EmployeeID: oee.EmployeeID,
+		CreatedAt:  oee.CreatedAt,
+	}
+}
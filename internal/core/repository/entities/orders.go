@@ -1,66 +1,175 @@
 package entities
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+// Шифрование персональных данных клиента. Расшифрованные значения существуют только
+// в orders.Order: NewOrderEntityFromLogic шифрует их, ToLogicOrder — расшифровывает
+type PIICipher interface {
+	Encrypt(plaintext string) (string, error)
+	Decrypt(value string) (string, error)
+	BlindIndex(value string) string
+	// Значение зашифровано не текущим ключом или не зашифровано вовсе
+	NeedsRotation(value string) bool
+}
+
 type OrderEntity struct {
 	ID                  uuid.UUID `gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
 	ClientName          string    `gorm:"not null"`
 	ClientPhone         string    `gorm:"not null"`
-	Address             string    `gorm:"not null"`
+	ClientPhoneHash     *string
+	Address             string `gorm:"not null"`
 	ClientDescription   string
+	Category            string     `gorm:"not null;default:''"`
+	Timezone            string     `gorm:"not null;default:''"`
+	TemplateID          *uuid.UUID `gorm:"type:uuid"`
 	EmployeeID          *uint
 	CancelReason        string
 	Status              int `gorm:"not null"`
 	EmployeeDescription string
 	ScheduledFor        *time.Time
-	Employee            *EmployeeEntity `gorm:"foreignKey:EmployeeID;references:ID"`
+	StatusChangedAt     *time.Time
+	WorkSeconds         int64 `gorm:"not null;default:0"`
+	Latitude            *float64
+	Longitude           *float64
+	DeletedAt           gorm.DeletedAt
+	AnonymizedAt        *time.Time
+	// Момент геокодирования адреса (в т.ч. безуспешного); NULL — адрес ещё не геокодирован
+	GeocodedAt *time.Time
+	// Момент последней неудачной попытки геокодирования; такие заявки геокодируются последними
+	GeocodeAttemptedAt *time.Time
+	// Заполняется только при чтении заявки из архива
+	ArchivedAt *time.Time      `gorm:"->"`
+	Employee   *EmployeeEntity `gorm:"foreignKey:EmployeeID;references:ID"`
 }
 
 func (OrderEntity) TableName() string {
 	return "public.orders"
 }
 
-func NewOrderEntityFromLogic(ord *orders.Order) *OrderEntity {
+func NewOrderEntityFromLogic(ord *orders.Order, pc PIICipher) (*OrderEntity, error) {
 	if ord == nil {
-		return nil
+		return nil, nil
 	}
 	oe := &OrderEntity{
 		ID:                  ord.ID,
-		ClientName:          ord.ClientName,
-		ClientPhone:         ord.ClientPhone,
-		Address:             ord.Address,
 		ClientDescription:   ord.ClientDescription,
+		Category:            ord.Category,
+		Timezone:            ord.Timezone,
+		TemplateID:          ord.TemplateID,
 		CancelReason:        ord.CancelReason,
 		Status:              int(ord.Status),
 		EmployeeDescription: ord.EmployeeDescription,
 		ScheduledFor:        ord.ScheduledFor,
+		StatusChangedAt:     ord.StatusChangedAt,
+		WorkSeconds:         ord.WorkSeconds,
+		AnonymizedAt:        ord.AnonymizedAt,
 		Employee:            (*EmployeeEntity)(ord.Employee),
 	}
+	if loc := ord.Address.Location; loc != nil {
+		oe.Latitude, oe.Longitude = &loc.Lat, &loc.Lon
+	}
+
+	var err error
+	if oe.ClientName, err = pc.Encrypt(ord.ClientName); err != nil {
+		return nil, err
+	}
+	if oe.ClientPhone, err = pc.Encrypt(ord.ClientPhone); err != nil {
+		return nil, err
+	}
+	address, err := encodeAddress(ord.Address)
+	if err != nil {
+		return nil, err
+	}
+	if oe.Address, err = pc.Encrypt(address); err != nil {
+		return nil, err
+	}
+	if ord.ClientPhone != "" {
+		hash := pc.BlindIndex(ord.ClientPhone)
+		oe.ClientPhoneHash = &hash
+	}
+
+	if ord.DeletedAt != nil {
+		oe.DeletedAt = gorm.DeletedAt{Time: *ord.DeletedAt, Valid: true}
+	}
 	if ord.Employee != nil {
 		oe.EmployeeID = &ord.Employee.ID
 	}
-	return oe
+	return oe, nil
 }
 
-func (oe *OrderEntity) ToLogicOrder() *orders.Order {
+func (oe *OrderEntity) ToLogicOrder(pc PIICipher) (*orders.Order, error) {
 	if oe == nil {
-		return nil
+		return nil, nil
 	}
-	return &orders.Order{
+	ord := &orders.Order{
 		ID:                  oe.ID,
-		ClientName:          oe.ClientName,
-		ClientPhone:         oe.ClientPhone,
-		Address:             oe.Address,
 		ClientDescription:   oe.ClientDescription,
+		Category:            oe.Category,
+		Timezone:            oe.Timezone,
+		TemplateID:          oe.TemplateID,
 		Employee:            oe.Employee.ToLogicEmployee(),
 		CancelReason:        oe.CancelReason,
 		Status:              orders.Status(oe.Status),
 		EmployeeDescription: oe.EmployeeDescription,
 		ScheduledFor:        oe.ScheduledFor,
+		StatusChangedAt:     oe.StatusChangedAt,
+		WorkSeconds:         oe.WorkSeconds,
+		ArchivedAt:          oe.ArchivedAt,
+		AnonymizedAt:        oe.AnonymizedAt,
+	}
+
+	var err error
+	if ord.ClientName, err = pc.Decrypt(oe.ClientName); err != nil {
+		return nil, fmt.Errorf("order %s: client name: %w", oe.ID, err)
+	}
+	if ord.ClientPhone, err = pc.Decrypt(oe.ClientPhone); err != nil {
+		return nil, fmt.Errorf("order %s: client phone: %w", oe.ID, err)
+	}
+	address, err := pc.Decrypt(oe.Address)
+	if err != nil {
+		return nil, fmt.Errorf("order %s: address: %w", oe.ID, err)
+	}
+	ord.Address = decodeAddress(address)
+
+	if oe.DeletedAt.Valid {
+		ord.DeletedAt = &oe.DeletedAt.Time
+	}
+	if oe.Latitude != nil && oe.Longitude != nil {
+		ord.Address.Location = &orders.GeoPoint{Lat: *oe.Latitude, Lon: *oe.Longitude}
+	}
+	return ord, nil
+}
+
+// Адрес хранится в столбце address (зашифрованным) как JSON без координат, которые лежат
+// в отдельных столбцах. Адрес одной строкой хранится как есть — так же, как до появления
+// структурированного адреса
+func encodeAddress(a orders.Address) (string, error) {
+	a.Location = nil
+	if a == (orders.Address{Line: a.Line}) {
+		return a.Line, nil
+	}
+
+	data, err := json.Marshal(a)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Значение, не являющееся JSON-объектом, — адрес одной строкой
+func decodeAddress(value string) orders.Address {
+	var a orders.Address
+	if !strings.HasPrefix(value, "{") || json.Unmarshal([]byte(value), &a) != nil {
+		return orders.Address{Line: value}
 	}
+	return a
 }
@@ -0,0 +1,121 @@
+package entities
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/recurring"
+	"github.com/google/uuid"
+)
+
+// Шаблон повторяющейся заявки. Данные клиента шифруются так же, как в OrderEntity
+type RecurringTemplateEntity struct {
+	ID                uuid.UUID `gorm:"primaryKey;type:uuid"`
+	ClientName        string    `gorm:"not null"`
+	ClientPhone       string    `gorm:"not null"`
+	ClientPhoneHash   *string
+	Address           string `gorm:"not null"`
+	Latitude          *float64
+	Longitude         *float64
+	ClientDescription string     `gorm:"not null;default:''"`
+	Category          string     `gorm:"not null;default:''"`
+	Timezone          string     `gorm:"not null;default:''"`
+	RRule             string     `gorm:"column:rrule;not null"`
+	StartOn           time.Time  `gorm:"type:date;not null"`
+	Preschedule       bool       `gorm:"not null"`
+	Status            string     `gorm:"not null"`
+	NextOn            *time.Time `gorm:"type:date"`
+	Occurrences       int        `gorm:"not null;default:0"`
+	CreatedAt         time.Time  `gorm:"not null;default:now()"`
+}
+
+func (RecurringTemplateEntity) TableName() string {
+	return "public.recurring_templates"
+}
+
+func NewRecurringTemplateEntityFromLogic(t *recurring.Template, pc PIICipher) (*RecurringTemplateEntity, error) {
+	if t == nil {
+		return nil, nil
+	}
+	te := &RecurringTemplateEntity{
+		ID:                t.ID,
+		ClientDescription: t.Order.ClientDescription,
+		Category:          t.Order.Category,
+		Timezone:          t.Order.Timezone,
+		RRule:             t.RRule,
+		StartOn:           t.Start.Time,
+		Preschedule:       t.Preschedule,
+		Status:            string(t.Status),
+		Occurrences:       t.Occurrences,
+		CreatedAt:         t.CreatedAt,
+	}
+	if t.NextOn != nil {
+		te.NextOn = &t.NextOn.Time
+	}
+	if loc := t.Order.Address.Location; loc != nil {
+		te.Latitude, te.Longitude = &loc.Lat, &loc.Lon
+	}
+
+	var err error
+	if te.ClientName, err = pc.Encrypt(t.Order.ClientName); err != nil {
+		return nil, err
+	}
+	if te.ClientPhone, err = pc.Encrypt(t.Order.ClientPhone); err != nil {
+		return nil, err
+	}
+	address, err := encodeAddress(t.Order.Address)
+	if err != nil {
+		return nil, err
+	}
+	if te.Address, err = pc.Encrypt(address); err != nil {
+		return nil, err
+	}
+	if t.Order.ClientPhone != "" {
+		hash := pc.BlindIndex(t.Order.ClientPhone)
+		te.ClientPhoneHash = &hash
+	}
+	return te, nil
+}
+
+func (te *RecurringTemplateEntity) ToLogicTemplate(pc PIICipher) (*recurring.Template, error) {
+	if te == nil {
+		return nil, nil
+	}
+	t := &recurring.Template{
+		ID: te.ID,
+		Order: orders.PrimaryOrder{
+			ClientDescription: te.ClientDescription,
+			Category:          te.Category,
+			Timezone:          te.Timezone,
+		},
+		RRule:       te.RRule,
+		Start:       recurring.NewDate(te.StartOn),
+		Preschedule: te.Preschedule,
+		Status:      recurring.Status(te.Status),
+		Occurrences: te.Occurrences,
+		CreatedAt:   te.CreatedAt,
+	}
+	if te.NextOn != nil {
+		next := recurring.NewDate(*te.NextOn)
+		t.NextOn = &next
+	}
+
+	var err error
+	if t.Order.ClientName, err = pc.Decrypt(te.ClientName); err != nil {
+		return nil, fmt.Errorf("template %s: client name: %w", te.ID, err)
+	}
+	if t.Order.ClientPhone, err = pc.Decrypt(te.ClientPhone); err != nil {
+		return nil, fmt.Errorf("template %s: client phone: %w", te.ID, err)
+	}
+	address, err := pc.Decrypt(te.Address)
+	if err != nil {
+		return nil, fmt.Errorf("template %s: address: %w", te.ID, err)
+	}
+	t.Order.Address = decodeAddress(address)
+
+	if te.Latitude != nil && te.Longitude != nil {
+		t.Order.Address.Location = &orders.GeoPoint{Lat: *te.Latitude, Lon: *te.Longitude}
+	}
+	return t, nil
+}
@@ -0,0 +1,36 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/google/uuid"
+)
+
+type SLABreachEntity struct {
+	ID              int64     `gorm:"primaryKey"`
+	OrderID         uuid.UUID `gorm:"type:uuid;not null"`
+	Status          int       `gorm:"not null"`
+	Category        string    `gorm:"not null"`
+	StatusChangedAt time.Time `gorm:"not null"`
+	Deadline        time.Time `gorm:"not null"`
+	DetectedAt      time.Time `gorm:"not null;default:now()"`
+}
+
+func (SLABreachEntity) TableName() string {
+	return "public.order_sla_breaches"
+}
+
+func NewSLABreachEntityFromLogic(b *orders.SLABreach) *SLABreachEntity {
+	if b == nil {
+		return nil
+	}
+	return &SLABreachEntity{
+		OrderID:         b.OrderID,
+		Status:          int(b.Status),
+		Category:        b.Category,
+		StatusChangedAt: b.StatusChangedAt,
+		Deadline:        b.Deadline,
+		DetectedAt:      b.DetectedAt,
+	}
+}
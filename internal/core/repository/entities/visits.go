@@ -0,0 +1,83 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/google/uuid"
+)
+
+type VisitEntity struct {
+	ID         uuid.UUID `gorm:"primaryKey;type:uuid"`
+	OrderID    uuid.UUID `gorm:"type:uuid;not null"`
+	EmployeeID *uint
+	PlannedFor *time.Time
+	ArrivedAt  *time.Time
+	DepartedAt *time.Time
+	Notes      string
+	PartsUsed  []orders.UsedPart `gorm:"serializer:json;not null"`
+	CreatedAt  time.Time         `gorm:"not null"`
+	Employee   *EmployeeEntity   `gorm:"foreignKey:EmployeeID;references:ID"`
+
+	CheckInLocation   *orders.GeoPoint `gorm:"column:checkin_location;serializer:json"`
+	CheckInDistanceM  *float64         `gorm:"column:checkin_distance_m"`
+	CheckOutLocation  *orders.GeoPoint `gorm:"column:checkout_location;serializer:json"`
+	CheckOutDistanceM *float64         `gorm:"column:checkout_distance_m"`
+	FarFromAddress    bool             `gorm:"not null"`
+}
+
+func (VisitEntity) TableName() string {
+	return "public.order_visits"
+}
+
+func NewVisitEntityFromLogic(v *orders.Visit) *VisitEntity {
+	if v == nil {
+		return nil
+	}
+	ve := &VisitEntity{
+		ID:         v.ID,
+		OrderID:    v.OrderID,
+		PlannedFor: v.PlannedFor,
+		ArrivedAt:  v.ArrivedAt,
+		DepartedAt: v.DepartedAt,
+		Notes:      v.Notes,
+		PartsUsed:  v.PartsUsed,
+		CreatedAt:  v.CreatedAt,
+
+		CheckInLocation:   v.CheckInLocation,
+		CheckInDistanceM:  v.CheckInDistanceM,
+		CheckOutLocation:  v.CheckOutLocation,
+		CheckOutDistanceM: v.CheckOutDistanceM,
+		FarFromAddress:    v.FarFromAddress,
+	}
+	if ve.PartsUsed == nil {
+		ve.PartsUsed = []orders.UsedPart{}
+	}
+	if v.Employee != nil {
+		ve.EmployeeID = &v.Employee.ID
+	}
+	return ve
+}
+
+func (ve *VisitEntity) ToLogicVisit() *orders.Visit {
+	if ve == nil {
+		return nil
+	}
+	return &orders.Visit{
+		ID:         ve.ID,
+		OrderID:    ve.OrderID,
+		Employee:   ve.Employee.ToLogicEmployee(),
+		PlannedFor: ve.PlannedFor,
+		ArrivedAt:  ve.ArrivedAt,
+		DepartedAt: ve.DepartedAt,
+		Notes:      ve.Notes,
+		PartsUsed:  ve.PartsUsed,
+		CreatedAt:  ve.CreatedAt,
+
+		CheckInLocation:   ve.CheckInLocation,
+		CheckInDistanceM:  ve.CheckInDistanceM,
+		CheckOutLocation:  ve.CheckOutLocation,
+		CheckOutDistanceM: ve.CheckOutDistanceM,
+		FarFromAddress:    ve.FarFromAddress,
+	}
+}
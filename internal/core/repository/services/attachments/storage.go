@@ -0,0 +1,93 @@
+package repository_attachments
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/attachments"
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/Owouwun/spkuznetsov/internal/core/repository/entities"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type GormAttachmentRepository struct {
+	db *gorm.DB
+}
+
+func NewAttachmentRepository(db *gorm.DB) *GormAttachmentRepository {
+	return &GormAttachmentRepository{db: db}
+}
+
+func (r *GormAttachmentRepository) Create(ctx context.Context, att *attachments.Attachment) error {
+	return r.db.WithContext(ctx).Create(entities.NewAttachmentEntityFromLogic(att)).Error
+}
+
+func (r *GormAttachmentRepository) ListByOrder(ctx context.Context, orderID uuid.UUID) ([]*attachments.Attachment, error) {
+	var attEntities []entities.AttachmentEntity
+	result := r.db.WithContext(ctx).
+		Where("order_id = ?", orderID).
+		Order("created_at, id").
+		Find(&attEntities)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	atts := make([]*attachments.Attachment, 0, len(attEntities))
+	for i := range attEntities {
+		atts = append(atts, attEntities[i].ToLogicAttachment())
+	}
+	return atts, nil
+}
+
+func (r *GormAttachmentRepository) ListByOrders(ctx context.Context, orderIDs []uuid.UUID) ([]*attachments.Attachment, error) {
+	var attEntities []entities.AttachmentEntity
+	result := r.db.WithContext(ctx).
+		Where("order_id IN ?", orderIDs).
+		Order("created_at, id").
+		Find(&attEntities)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	atts := make([]*attachments.Attachment, 0, len(attEntities))
+	for i := range attEntities {
+		atts = append(atts, attEntities[i].ToLogicAttachment())
+	}
+	return atts, nil
+}
+
+func (r *GormAttachmentRepository) DeleteByOrders(ctx context.Context, orderIDs []uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("order_id IN ?", orderIDs).Delete(&entities.AttachmentEntity{}).Error
+}
+
+func (r *GormAttachmentRepository) GetByID(ctx context.Context, id uuid.UUID) (*attachments.Attachment, error) {
+	var attEntity entities.AttachmentEntity
+	err := r.db.WithContext(ctx).First(&attEntity, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, attachments.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return attEntity.ToLogicAttachment(), nil
+}
+
+// Удалённые заявки не видны ни в основной таблице, ни в архиве
+const selectOrderStatus = `
+SELECT status FROM public.orders WHERE id = @id AND deleted_at IS NULL
+UNION ALL
+SELECT (data->>'status')::int FROM public.orders_archive WHERE id = @id AND data->>'deleted_at' IS NULL
+LIMIT 1`
+
+func (r *GormAttachmentRepository) OrderStatus(ctx context.Context, orderID uuid.UUID) (orders.Status, error) {
+	var statuses []int
+	result := r.db.WithContext(ctx).Raw(selectOrderStatus, map[string]any{"id": orderID}).Scan(&statuses)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if len(statuses) == 0 {
+		return 0, attachments.ErrNotFound
+	}
+	return orders.Status(statuses[0]), nil
+}
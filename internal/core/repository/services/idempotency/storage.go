@@ -0,0 +1,111 @@
+package repository_idempotency
+
+import (
+	"context"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/idempotency"
+	"github.com/Owouwun/spkuznetsov/internal/core/repository/entities"
+	"github.com/Owouwun/spkuznetsov/internal/pii"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GormKeyRepository struct {
+	db     *gorm.DB
+	cipher entities.PIICipher
+}
+
+type KeyRepositoryOption func(*GormKeyRepository)
+
+// WithCipher включает шифрование сохранённых ответов. Ключ тот же, что у заявок:
+// по слепому индексу телефона ответы удаляются при обезличивании клиента
+func WithCipher(cipher entities.PIICipher) KeyRepositoryOption {
+	return func(r *GormKeyRepository) {
+		r.cipher = cipher
+	}
+}
+
+func NewKeyRepository(db *gorm.DB, opts ...KeyRepositoryOption) *GormKeyRepository {
+	r := &GormKeyRepository{
+		db:     db,
+		cipher: pii.Plaintext{},
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Ключ занимается вставкой без перезаписи: из параллельных запросов с одним ключом
+// выполняется только первый, остальные получают его запись
+func (r *GormKeyRepository) Reserve(ctx context.Context, rec *idempotency.Record) (*idempotency.Record, error) {
+	var existing *idempotency.Record
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("key = ? AND expires_at <= ?", rec.Key, rec.CreatedAt).
+			Delete(&entities.IdempotencyKeyEntity{}).Error; err != nil {
+			return err
+		}
+
+		entity, err := entities.NewIdempotencyKeyEntityFromLogic(rec, r.cipher)
+		if err != nil {
+			return err
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(entity)
+		if result.Error != nil || result.RowsAffected > 0 {
+			return result.Error
+		}
+
+		var stored entities.IdempotencyKeyEntity
+		if err := tx.Where("key = ?", rec.Key).Take(&stored).Error; err != nil {
+			return err
+		}
+		existing, err = stored.ToLogicRecord(r.cipher)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+func (r *GormKeyRepository) Complete(ctx context.Context, key, fingerprint string, resp *idempotency.Response, expiresAt time.Time) error {
+	entity, err := entities.NewIdempotencyKeyEntityFromLogic(&idempotency.Record{Response: *resp}, r.cipher)
+	if err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).
+		Model(&entities.IdempotencyKeyEntity{}).
+		Where("key = ? AND fingerprint = ?", key, fingerprint).
+		Updates(map[string]any{
+			"status_code":       entity.StatusCode,
+			"content_type":      entity.ContentType,
+			"body":              entity.Body,
+			"client_phone_hash": entity.ClientPhoneHash,
+			"expires_at":        expiresAt,
+		}).Error
+}
+
+func (r *GormKeyRepository) Release(ctx context.Context, key, fingerprint string) error {
+	return r.db.WithContext(ctx).
+		Where("key = ? AND fingerprint = ? AND status_code = 0", key, fingerprint).
+		Delete(&entities.IdempotencyKeyEntity{}).Error
+}
+
+const deleteExpiredKeys = `
+DELETE FROM public.idempotency_keys
+WHERE key IN (
+	SELECT key FROM public.idempotency_keys
+	WHERE expires_at <= ?
+	LIMIT ?
+	FOR UPDATE SKIP LOCKED
+)`
+
+func (r *GormKeyRepository) DeleteExpired(ctx context.Context, now time.Time, limit int) (int, error) {
+	result := r.db.WithContext(ctx).Exec(deleteExpiredKeys, now, limit)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return int(result.RowsAffected), nil
+}
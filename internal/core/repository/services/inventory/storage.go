@@ -0,0 +1,644 @@
+package repository_inventory
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/inventory"
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/Owouwun/spkuznetsov/internal/core/repository/entities"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Код ошибки PostgreSQL при нарушении уникальности
+const uniqueViolation = "23505"
+
+const selectOrderStatus = `
+SELECT status FROM public.orders WHERE id = @id AND deleted_at IS NULL
+UNION ALL
+SELECT (data->>'status')::int FROM public.orders_archive WHERE id = @id AND data->>'deleted_at' IS NULL
+LIMIT 1`
+
+const selectOrderParts = `
+SELECT op.order_id, op.part_id, op.quantity, COALESCE(SUM(r.quantity), 0) AS reserved
+FROM public.order_parts op
+LEFT JOIN public.part_reservations r ON r.order_id = op.order_id AND r.part_id = op.part_id
+WHERE op.order_id = ?
+GROUP BY op.order_id, op.part_id, op.quantity
+ORDER BY op.part_id`
+
+// Отметка события заявки обработанным. Если другая реплика обрабатывает то же событие,
+// вставка ждёт конца её транзакции и ничего не добавляет
+const markProcessed = `
+INSERT INTO public.inventory_processed_events (order_event_id) VALUES (?)
+ON CONFLICT DO NOTHING`
+
+// События с неудачными попытками — после остальных, начиная с самой давней попытки
+const selectPendingOrderEvents = `
+SELECT e.* FROM public.order_events e
+LEFT JOIN public.inventory_failed_events f ON f.order_event_id = e.id
+WHERE e.id > (SELECT order_event_id FROM public.inventory_cursor)
+AND NOT EXISTS (SELECT 1 FROM public.inventory_processed_events p WHERE p.order_event_id = e.id)
+ORDER BY f.failed_at NULLS FIRST, e.id
+LIMIT ?`
+
+const recordFailure = `
+INSERT INTO public.inventory_failed_events (order_event_id, last_error) VALUES (?, ?)
+ON CONFLICT (order_event_id) DO UPDATE
+SET attempts = inventory_failed_events.attempts + 1, last_error = EXCLUDED.last_error, failed_at = now()`
+
+// Позиция журнала сдвигается до последнего события старше settle, но не дальше первого
+// необработанного события
+const compactCursor = `
+UPDATE public.inventory_cursor c SET order_event_id = GREATEST(c.order_event_id, LEAST(
+    (SELECT MIN(e.id) - 1 FROM public.order_events e
+     WHERE e.id > c.order_event_id
+     AND NOT EXISTS (SELECT 1 FROM public.inventory_processed_events p WHERE p.order_event_id = e.id)),
+    settled.id))
+FROM (
+    SELECT MAX(e.id) AS id FROM public.order_events e
+    WHERE e.id > (SELECT order_event_id FROM public.inventory_cursor)
+    AND e.created_at < now() - make_interval(secs => ?)
+) settled
+WHERE settled.id IS NOT NULL`
+
+// Попытки событий, которые в итоге удалось обработать
+const deleteRecoveredFailures = `
+DELETE FROM public.inventory_failed_events f
+WHERE f.order_event_id <= (SELECT order_event_id FROM public.inventory_cursor)
+OR EXISTS (SELECT 1 FROM public.inventory_processed_events p WHERE p.order_event_id = f.order_event_id)`
+
+const deleteCompacted = `
+DELETE FROM public.inventory_processed_events
+WHERE order_event_id <= (SELECT order_event_id FROM public.inventory_cursor)`
+
+type GormInventoryRepository struct {
+	db *gorm.DB
+}
+
+func NewInventoryRepository(db *gorm.DB) *GormInventoryRepository {
+	return &GormInventoryRepository{db: db}
+}
+
+func (r *GormInventoryRepository) CreatePart(ctx context.Context, part *inventory.Part) error {
+	partEntity := entities.NewPartEntityFromLogic(part)
+	err := r.db.WithContext(ctx).Create(partEntity).Error
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return inventory.ErrDuplicateSKU
+	}
+	if err != nil {
+		return err
+	}
+	part.CreatedAt = partEntity.CreatedAt
+	return nil
+}
+
+func (r *GormInventoryRepository) GetPart(ctx context.Context, id uuid.UUID) (*inventory.Part, error) {
+	var partEntity entities.PartEntity
+	err := r.db.WithContext(ctx).First(&partEntity, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, inventory.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return partEntity.ToLogicPart(), nil
+}
+
+func (r *GormInventoryRepository) GetParts(ctx context.Context) ([]*inventory.Part, error) {
+	return r.findParts(r.db.WithContext(ctx))
+}
+
+func (r *GormInventoryRepository) GetPartsBySKU(ctx context.Context, skus []string) ([]*inventory.Part, error) {
+	return r.findParts(r.db.WithContext(ctx).Where("sku IN ?", skus))
+}
+
+func (r *GormInventoryRepository) findParts(db *gorm.DB) ([]*inventory.Part, error) {
+	var partEntities []entities.PartEntity
+	if err := db.Order("sku").Find(&partEntities).Error; err != nil {
+		return nil, err
+	}
+
+	parts := make([]*inventory.Part, 0, len(partEntities))
+	for i := range partEntities {
+		parts = append(parts, partEntities[i].ToLogicPart())
+	}
+	return parts, nil
+}
+
+func (r *GormInventoryRepository) GetStock(ctx context.Context, filter inventory.StockFilter) ([]*inventory.Stock, error) {
+	db := r.db.WithContext(ctx).
+		Table("public.stock_levels AS s").
+		Select("s.part_id, s.location, s.on_hand, s.reserved, p.sku, p.name").
+		Joins("JOIN public.parts p ON p.id = s.part_id")
+	if filter.PartID != nil {
+		db = db.Where("s.part_id = ?", *filter.PartID)
+	}
+	if filter.Location != "" {
+		db = db.Where("s.location = ?", string(filter.Location))
+	}
+
+	var levels []entities.StockLevelEntity
+	if err := db.Order("p.sku, s.location").Scan(&levels).Error; err != nil {
+		return nil, err
+	}
+
+	stock := make([]*inventory.Stock, 0, len(levels))
+	for i := range levels {
+		stock = append(stock, levels[i].ToLogicStock())
+	}
+	return stock, nil
+}
+
+func (r *GormInventoryRepository) Receive(ctx context.Context, rc *inventory.Receipt) (changes []inventory.LevelChange, err error) {
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		changes, err = trackLevels(tx, []uuid.UUID{rc.PartID}, func() error {
+			return move(tx, rc.PartID, rc.Location, inventory.MovementReceipt, rc.Quantity, nil)
+		})
+		return err
+	})
+	return changes, err
+}
+
+func (r *GormInventoryRepository) Transfer(ctx context.Context, t *inventory.Transfer) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		from, err := lockLevel(tx, t.PartID, t.From)
+		if err != nil {
+			return err
+		}
+		if from == nil || from.OnHand-from.Reserved < t.Quantity {
+			return inventory.ErrInsufficientStock
+		}
+
+		if err := move(tx, t.PartID, t.From, inventory.MovementTransferOut, -t.Quantity, nil); err != nil {
+			return err
+		}
+		return move(tx, t.PartID, t.To, inventory.MovementTransferIn, t.Quantity, nil)
+	})
+}
+
+func (r *GormInventoryRepository) OrderStatus(ctx context.Context, orderID uuid.UUID) (orders.Status, error) {
+	var statuses []int
+	result := r.db.WithContext(ctx).Raw(selectOrderStatus, map[string]any{"id": orderID}).Scan(&statuses)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if len(statuses) == 0 {
+		return 0, inventory.ErrOrderNotFound
+	}
+	return orders.Status(statuses[0]), nil
+}
+
+func (r *GormInventoryRepository) SetOrderParts(ctx context.Context, orderID uuid.UUID, parts []inventory.OrderPart) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("order_id = ?", orderID).Delete(&entities.OrderPartEntity{}).Error; err != nil {
+			return err
+		}
+		if len(parts) == 0 {
+			return nil
+		}
+
+		partEntities := make([]entities.OrderPartEntity, 0, len(parts))
+		for _, p := range parts {
+			partEntities = append(partEntities, entities.OrderPartEntity{OrderID: orderID, PartID: p.PartID, Quantity: p.Quantity})
+		}
+		return tx.Create(&partEntities).Error
+	})
+}
+
+func (r *GormInventoryRepository) GetOrderParts(ctx context.Context, orderID uuid.UUID) ([]*inventory.OrderPart, error) {
+	var partEntities []entities.OrderPartEntity
+	if err := r.db.WithContext(ctx).Raw(selectOrderParts, orderID).Scan(&partEntities).Error; err != nil {
+		return nil, err
+	}
+
+	parts := make([]*inventory.OrderPart, 0, len(partEntities))
+	for i := range partEntities {
+		parts = append(parts, partEntities[i].ToLogicOrderPart())
+	}
+	return parts, nil
+}
+
+func (r *GormInventoryRepository) Reserve(ctx context.Context, orderID uuid.UUID, sources []inventory.Location, eventID int64) (changes []inventory.LevelChange, err error) {
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := markEvent(tx, eventID); err != nil {
+			return err
+		}
+		if done, err := consumed(tx, orderID); done || err != nil {
+			return err
+		}
+
+		var planned []entities.OrderPartEntity
+		if err := tx.Where("order_id = ?", orderID).Order("part_id").Find(&planned).Error; err != nil {
+			return err
+		}
+		reservations, err := orderReservations(tx, orderID)
+		if err != nil {
+			return err
+		}
+
+		partIDs := make([]uuid.UUID, 0, len(planned)+len(reservations))
+		for _, p := range planned {
+			partIDs = append(partIDs, p.PartID)
+		}
+		for _, res := range reservations {
+			partIDs = append(partIDs, res.PartID)
+		}
+
+		changes, err = trackLevels(tx, partIDs, func() error {
+			if err := release(tx, orderID, reservations); err != nil {
+				return err
+			}
+			for _, p := range planned {
+				if err := reservePart(tx, orderID, p.PartID, p.Quantity, sources); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		return err
+	})
+	return changes, err
+}
+
+func (r *GormInventoryRepository) Release(ctx context.Context, orderID uuid.UUID, eventID int64) (changes []inventory.LevelChange, err error) {
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := markEvent(tx, eventID); err != nil {
+			return err
+		}
+		reservations, err := orderReservations(tx, orderID)
+		if err != nil {
+			return err
+		}
+
+		partIDs := make([]uuid.UUID, 0, len(reservations))
+		for _, res := range reservations {
+			partIDs = append(partIDs, res.PartID)
+		}
+		changes, err = trackLevels(tx, partIDs, func() error {
+			return release(tx, orderID, reservations)
+		})
+		return err
+	})
+	return changes, err
+}
+
+func (r *GormInventoryRepository) Consume(ctx context.Context, orderID uuid.UUID, source inventory.Location, used []inventory.Usage, eventID int64) (changes []inventory.LevelChange, err error) {
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := markEvent(tx, eventID); err != nil {
+			return err
+		}
+		if done, err := consumed(tx, orderID); done || err != nil {
+			return err
+		}
+		reservations, err := orderReservations(tx, orderID)
+		if err != nil {
+			return err
+		}
+
+		partIDs := make([]uuid.UUID, 0, len(used)+len(reservations))
+		for _, u := range used {
+			partIDs = append(partIDs, u.PartID)
+		}
+		for _, res := range reservations {
+			partIDs = append(partIDs, res.PartID)
+		}
+
+		changes, err = trackLevels(tx, partIDs, func() error {
+			for _, u := range used {
+				if err := consumePart(tx, orderID, u, source, reservations); err != nil {
+					return err
+				}
+			}
+			// Резерв, оставшийся после списания, снимается
+			return release(tx, orderID, reservations)
+		})
+		return err
+	})
+	return changes, err
+}
+
+func (r *GormInventoryRepository) Return(ctx context.Context, orderID uuid.UUID, rt *inventory.Return) (changes []inventory.LevelChange, err error) {
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var item entities.OrderLineItemEntity
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&item, "order_id = ? AND part_id = ?", orderID, rt.PartID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return inventory.ErrInsufficientStock
+		}
+		if err != nil {
+			return err
+		}
+		if item.Quantity < rt.Quantity {
+			return inventory.ErrInsufficientStock
+		}
+
+		err = tx.Model(&entities.OrderLineItemEntity{}).
+			Where("order_id = ? AND part_id = ?", orderID, rt.PartID).
+			Update("quantity", gorm.Expr("quantity - ?", rt.Quantity)).Error
+		if err != nil {
+			return err
+		}
+
+		changes, err = trackLevels(tx, []uuid.UUID{rt.PartID}, func() error {
+			return move(tx, rt.PartID, rt.Location, inventory.MovementReturn, rt.Quantity, &orderID)
+		})
+		return err
+	})
+	return changes, err
+}
+
+func (r *GormInventoryRepository) GetLineItems(ctx context.Context, orderID uuid.UUID) ([]*inventory.LineItem, error) {
+	var itemEntities []entities.OrderLineItemEntity
+	err := r.db.WithContext(ctx).
+		Where("order_id = ? AND quantity > 0", orderID).
+		Order("sku").
+		Find(&itemEntities).Error
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*inventory.LineItem, 0, len(itemEntities))
+	for i := range itemEntities {
+		items = append(items, itemEntities[i].ToLogicLineItem())
+	}
+	return items, nil
+}
+
+func (r *GormInventoryRepository) PendingOrderEvents(ctx context.Context, limit int) ([]*orders.Event, error) {
+	var eventEntities []entities.OrderEventEntity
+	if err := r.db.WithContext(ctx).Raw(selectPendingOrderEvents, limit).Scan(&eventEntities).Error; err != nil {
+		return nil, err
+	}
+
+	events := make([]*orders.Event, 0, len(eventEntities))
+	for i := range eventEntities {
+		events = append(events, eventEntities[i].ToLogicEvent())
+	}
+	return events, nil
+}
+
+func (r *GormInventoryRepository) MarkProcessed(ctx context.Context, eventIDs []int64) error {
+	if len(eventIDs) == 0 {
+		return nil
+	}
+	processed := make([]entities.ProcessedOrderEventEntity, 0, len(eventIDs))
+	for _, id := range eventIDs {
+		processed = append(processed, entities.ProcessedOrderEventEntity{OrderEventID: id})
+	}
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&processed).Error
+}
+
+func (r *GormInventoryRepository) CompactProcessed(ctx context.Context, settle time.Duration) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(compactCursor, settle.Seconds()).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(deleteRecoveredFailures).Error; err != nil {
+			return err
+		}
+		return tx.Exec(deleteCompacted).Error
+	})
+}
+
+func (r *GormInventoryRepository) EventFailed(ctx context.Context, eventID int64, cause error) error {
+	return r.db.WithContext(ctx).Exec(recordFailure, eventID, cause.Error()).Error
+}
+
+func (r *GormInventoryRepository) AppendEvent(ctx context.Context, ev *inventory.Event) (int64, error) {
+	eventEntity := entities.NewInventoryEventEntityFromLogic(ev)
+	if err := r.db.WithContext(ctx).Create(eventEntity).Error; err != nil {
+		return 0, err
+	}
+	ev.CreatedAt = eventEntity.CreatedAt
+	return eventEntity.ID, nil
+}
+
+func (r *GormInventoryRepository) EventsSince(ctx context.Context, afterID int64, limit int) ([]*inventory.Event, error) {
+	var eventEntities []entities.InventoryEventEntity
+	err := r.db.WithContext(ctx).
+		Where("id > ?", afterID).
+		Order("id").
+		Limit(limit).
+		Find(&eventEntities).Error
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*inventory.Event, 0, len(eventEntities))
+	for i := range eventEntities {
+		events = append(events, eventEntities[i].ToLogicEvent())
+	}
+	return events, nil
+}
+
+// Отметить событие заявки обработанным в транзакции действия по нему
+func markEvent(tx *gorm.DB, eventID int64) error {
+	if eventID == 0 {
+		return nil
+	}
+	result := tx.Exec(markProcessed, eventID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return inventory.ErrEventProcessed
+	}
+	return nil
+}
+
+// По заявке уже списывались запчасти
+func consumed(tx *gorm.DB, orderID uuid.UUID) (bool, error) {
+	var count int64
+	err := tx.Model(&entities.StockMovementEntity{}).
+		Where("order_id = ? AND kind = ?", orderID, string(inventory.MovementConsumption)).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Резерв заявки: сначала в машине сотрудника, затем на складах
+func orderReservations(tx *gorm.DB, orderID uuid.UUID) ([]*entities.PartReservationEntity, error) {
+	var reservations []*entities.PartReservationEntity
+	err := tx.Where("order_id = ?", orderID).
+		Order("part_id, location LIKE 'van:%' DESC, location").
+		Find(&reservations).Error
+	return reservations, err
+}
+
+// Остаток запчасти в месте хранения, заблокированный до конца транзакции; nil, если его нет
+func lockLevel(tx *gorm.DB, partID uuid.UUID, location inventory.Location) (*entities.StockLevelEntity, error) {
+	var levels []entities.StockLevelEntity
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("part_id = ? AND location = ?", partID, string(location)).
+		Find(&levels).Error
+	if err != nil || len(levels) == 0 {
+		return nil, err
+	}
+	return &levels[0], nil
+}
+
+// Изменить количество запчастей в месте хранения и записать движение
+func move(tx *gorm.DB, partID uuid.UUID, location inventory.Location, kind inventory.MovementKind, quantity int, orderID *uuid.UUID) error {
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "part_id"}, {Name: "location"}},
+		DoUpdates: clause.Assignments(map[string]any{"on_hand": gorm.Expr("stock_levels.on_hand + ?", quantity)}),
+	}).Create(&entities.StockLevelEntity{PartID: partID, Location: string(location), OnHand: quantity}).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(&entities.StockMovementEntity{
+		PartID:   partID,
+		Location: string(location),
+		Kind:     string(kind),
+		Quantity: quantity,
+		OrderID:  orderID,
+	}).Error
+}
+
+func changeReserved(tx *gorm.DB, partID uuid.UUID, location string, delta int) error {
+	return tx.Model(&entities.StockLevelEntity{}).
+		Where("part_id = ? AND location = ?", partID, location).
+		Update("reserved", gorm.Expr("reserved + ?", delta)).Error
+}
+
+// Зарезервировать под заявку сколько есть запчастей в местах хранения sources по порядку
+func reservePart(tx *gorm.DB, orderID, partID uuid.UUID, quantity int, sources []inventory.Location) error {
+	need := quantity
+	for _, source := range sources {
+		if need == 0 {
+			break
+		}
+		level, err := lockLevel(tx, partID, source)
+		if err != nil {
+			return err
+		}
+		if level == nil {
+			continue
+		}
+		take := min(need, level.OnHand-level.Reserved)
+		if take <= 0 {
+			continue
+		}
+
+		if err := changeReserved(tx, partID, level.Location, take); err != nil {
+			return err
+		}
+		err = tx.Create(&entities.PartReservationEntity{
+			OrderID:  orderID,
+			PartID:   partID,
+			Location: level.Location,
+			Quantity: take,
+		}).Error
+		if err != nil {
+			return err
+		}
+		need -= take
+	}
+	return nil
+}
+
+// Снять с остатков оставшийся резерв заявки и удалить его
+func release(tx *gorm.DB, orderID uuid.UUID, reservations []*entities.PartReservationEntity) error {
+	for _, res := range reservations {
+		if res.Quantity == 0 {
+			continue
+		}
+		if err := changeReserved(tx, res.PartID, res.Location, -res.Quantity); err != nil {
+			return err
+		}
+	}
+	return tx.Where("order_id = ?", orderID).Delete(&entities.PartReservationEntity{}).Error
+}
+
+// Списать запчасть по заявке: сначала из резерва (он уменьшается в reservations),
+// остальное — из source. Позиция заявки получает цену запчасти на момент списания
+func consumePart(tx *gorm.DB, orderID uuid.UUID, u inventory.Usage, source inventory.Location, reservations []*entities.PartReservationEntity) error {
+	need := u.Quantity
+	for _, res := range reservations {
+		if res.PartID != u.PartID || res.Quantity == 0 || need == 0 {
+			continue
+		}
+		take := min(need, res.Quantity)
+		if err := changeReserved(tx, res.PartID, res.Location, -take); err != nil {
+			return err
+		}
+		if err := move(tx, u.PartID, inventory.Location(res.Location), inventory.MovementConsumption, -take, &orderID); err != nil {
+			return err
+		}
+		res.Quantity -= take
+		need -= take
+	}
+	if need > 0 {
+		if err := move(tx, u.PartID, source, inventory.MovementConsumption, -need, &orderID); err != nil {
+			return err
+		}
+	}
+
+	var part entities.PartEntity
+	if err := tx.First(&part, "id = ?", u.PartID).Error; err != nil {
+		return err
+	}
+	item := inventory.NewLineItem(part.ToLogicPart(), u.Quantity)
+	return tx.Create(entities.NewOrderLineItemEntityFromLogic(orderID, &item)).Error
+}
+
+// Выполнить действие, вычислив изменение общего доступного остатка запчастей partIDs
+func trackLevels(tx *gorm.DB, partIDs []uuid.UUID, action func() error) ([]inventory.LevelChange, error) {
+	if len(partIDs) == 0 {
+		return nil, action()
+	}
+
+	before, err := availableTotals(tx, partIDs)
+	if err != nil {
+		return nil, err
+	}
+	if err := action(); err != nil {
+		return nil, err
+	}
+	after, err := availableTotals(tx, partIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []inventory.LevelChange
+	seen := make(map[uuid.UUID]bool, len(partIDs))
+	for _, id := range partIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if before[id] != after[id] {
+			changes = append(changes, inventory.LevelChange{PartID: id, Before: before[id], After: after[id]})
+		}
+	}
+	return changes, nil
+}
+
+func availableTotals(tx *gorm.DB, partIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	var rows []struct {
+		PartID    uuid.UUID
+		Available int
+	}
+	err := tx.Model(&entities.StockLevelEntity{}).
+		Select("part_id, SUM(on_hand - reserved) AS available").
+		Where("part_id IN ?", partIDs).
+		Group("part_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make(map[uuid.UUID]int, len(rows))
+	for _, row := range rows {
+		totals[row.PartID] = row.Available
+	}
+	return totals, nil
+}
@@ -0,0 +1,115 @@
+package repository_orders
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/Owouwun/spkuznetsov/internal/core/repository/entities"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Персональные данные заявки (в т.ч. координаты адреса) заменяются пустыми значениями;
+// статус, даты, сотрудник и причина отмены остаются для статистики
+const anonymizeOrders = `
+UPDATE public.orders
+SET client_name = '', client_phone = '', client_phone_hash = NULL, address = '', client_description = '',
+	latitude = NULL, longitude = NULL, geocoded_at = @now, anonymized_at = @now
+WHERE id IN (
+	SELECT id FROM public.orders
+	WHERE anonymized_at IS NULL AND %s
+	LIMIT @limit
+	FOR UPDATE SKIP LOCKED
+)
+RETURNING id`
+
+// То же для снимков заявок в архиве
+const anonymizeArchivedOrders = `
+UPDATE public.orders_archive
+SET data = data || jsonb_build_object(
+	'client_name', '', 'client_phone', '', 'client_phone_hash', NULL, 'address', '', 'client_description', '',
+	'latitude', NULL, 'longitude', NULL, 'anonymized_at', @now::timestamptz
+)
+WHERE id IN (
+	SELECT id FROM public.orders_archive
+	WHERE data->>'anonymized_at' IS NULL AND %s
+	LIMIT @limit
+	FOR UPDATE SKIP LOCKED
+)
+RETURNING id`
+
+// Визиты заявок хранятся отдельно и переживают архивирование, поэтому их отметки
+// и заметки сотрудника очищаются по идентификаторам обезличенных заявок
+const anonymizeVisits = `
+UPDATE public.order_visits
+SET checkin_location = NULL, checkout_location = NULL, notes = ''
+WHERE order_id IN @ids`
+
+// Шаблоны повторяющихся заявок клиента удаляются: по ним больше не должно создаваться заявок
+const deleteClientTemplates = `
+DELETE FROM public.recurring_templates WHERE client_phone_hash = @phone_hash`
+
+// Сохранённые ответы с данными клиента удаляются: повтор запроса выполнится заново
+const deleteClientIdempotencyKeys = `
+DELETE FROM public.idempotency_keys WHERE client_phone_hash = @phone_hash`
+
+func (r *GormOrderRepository) Anonymize(ctx context.Context, scope orders.ErasureScope, audit *orders.Erasure) error {
+	now := r.now()
+
+	// LIMIT NULL в PostgreSQL означает отсутствие ограничения
+	args := map[string]any{"now": now, "limit": nil}
+	if scope.Limit > 0 {
+		args["limit"] = scope.Limit
+	}
+
+	var ordersCond, archiveCond string
+	if scope.ClientPhone != "" {
+		// Телефон хранится зашифрованным, поэтому заявки клиента ищутся по слепому индексу
+		ordersCond = "client_phone_hash = @phone_hash"
+		archiveCond = "data->>'client_phone_hash' = @phone_hash"
+		args["phone_hash"] = r.cipher.BlindIndex(scope.ClientPhone)
+	} else {
+		ordersCond = "status IN @statuses AND status_changed_at < @before"
+		archiveCond = "(data->>'status')::int IN @statuses AND (data->>'status_changed_at')::timestamptz < @before"
+		args["statuses"] = closedStatuses()
+		args["before"] = scope.ClosedBefore
+	}
+
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		var ids, archivedIDs []uuid.UUID
+		if err := tx.Raw(fmt.Sprintf(anonymizeOrders, ordersCond), args).Scan(&ids).Error; err != nil {
+			return err
+		}
+		if err := tx.Raw(fmt.Sprintf(anonymizeArchivedOrders, archiveCond), args).Scan(&archivedIDs).Error; err != nil {
+			return err
+		}
+
+		if scope.ClientPhone != "" {
+			if err := tx.Exec(deleteClientTemplates, args).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec(deleteClientIdempotencyKeys, args).Error; err != nil {
+				return err
+			}
+		}
+
+		audit.OrderIDs = append(ids, archivedIDs...)
+		if len(audit.OrderIDs) > 0 {
+			if err := tx.Exec(anonymizeVisits, map[string]any{"ids": audit.OrderIDs}).Error; err != nil {
+				return err
+			}
+		}
+		if len(audit.OrderIDs) == 0 && audit.Reason == orders.ErasureByRetention {
+			return nil
+		}
+
+		audit.CreatedAt = now
+		erasureEntity := entities.NewErasureEntityFromLogic(audit)
+		if err := tx.Create(erasureEntity).Error; err != nil {
+			return err
+		}
+		audit.ID = erasureEntity.ID
+		return nil
+	})
+}
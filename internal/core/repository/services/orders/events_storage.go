@@ -0,0 +1,190 @@
+package repository_orders
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/Owouwun/spkuznetsov/internal/core/repository/entities"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm"
+)
+
+const (
+	// Канал NOTIFY, в который триггер на public.order_events публикует новые события
+	OrderEventsChannel = "order_events"
+
+	eventsPageSize        = 1000
+	listenerRetryInterval = time.Second
+)
+
+type GormEventRepository struct {
+	db *gorm.DB
+}
+
+func NewEventRepository(db *gorm.DB) *GormEventRepository {
+	return &GormEventRepository{db: db}
+}
+
+func (r *GormEventRepository) Append(ctx context.Context, ev *orders.Event) (int64, error) {
+	eventEntity := entities.NewOrderEventEntityFromLogic(ev)
+
+	result := conn(ctx, r.db).Create(&eventEntity)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return eventEntity.ID, nil
+}
+
+func (r *GormEventRepository) ListSince(ctx context.Context, afterID int64, filter orders.EventFilter, limit int) ([]*orders.Event, error) {
+	query := conn(ctx, r.db).
+		Where("id > ?", afterID)
+
+	if len(filter.Statuses) > 0 {
+		statuses := make([]int, 0, len(filter.Statuses))
+		for _, s := range filter.Statuses {
+			statuses = append(statuses, int(s))
+		}
+		query = query.Where("status IN ?", statuses)
+	}
+	if filter.EmployeeID != nil {
+		query = query.Where("employee_id = ?", *filter.EmployeeID)
+	}
+
+	var eventEntities []entities.OrderEventEntity
+	result := query.
+		Order("id").
+		Limit(limit).
+		Find(&eventEntities)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	var logicEvents []*orders.Event
+	for _, entity := range eventEntities {
+		logicEvents = append(logicEvents, entity.ToLogicEvent())
+	}
+
+	return logicEvents, nil
+}
+
+func (r *GormEventRepository) lastEventID(ctx context.Context) (int64, error) {
+	var lastID int64
+	result := conn(ctx, r.db).
+		Model(&entities.OrderEventEntity{}).
+		Select("COALESCE(MAX(id), 0)").
+		Scan(&lastID)
+
+	return lastID, result.Error
+}
+
+// Передать в fn события журнала после afterID порциями по eventsPageSize
+func (r *GormEventRepository) eachSince(ctx context.Context, afterID int64, fn func(*orders.Event)) error {
+	for {
+		events, err := r.ListSince(ctx, afterID, orders.EventFilter{}, eventsPageSize)
+		if err != nil {
+			return err
+		}
+		for _, ev := range events {
+			fn(ev)
+			afterID = ev.ID
+		}
+		if len(events) < eventsPageSize {
+			return nil
+		}
+	}
+}
+
+// Listen слушает LISTEN/NOTIFY канал событий и передаёт их в publish, пока не отменён ctx.
+// При потере соединения переподключается и досылает пропущенные события из журнала,
+// поэтому изменения, сделанные на любой реплике, доходят до подписчиков всех реплик.
+func (r *GormEventRepository) Listen(ctx context.Context, publish func(*orders.Event)) error {
+	lastID, err := r.lastEventID(ctx)
+	if err != nil {
+		return err
+	}
+
+	// События последнего окна уже были в журнале до начала прослушивания: они отмечаются
+	// доставленными, чтобы при переподключении не разослать их повторно
+	seen := orders.NewSeenEvents()
+	err = r.eachSince(ctx, orders.ReplayFrom(lastID), func(ev *orders.Event) {
+		if ev.ID <= lastID {
+			seen.Add(ev.ID)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+
+	for {
+		err := r.listenOnce(ctx, sqlDB, seen, publish)
+		if ctx.Err() != nil {
+			return nil
+		}
+		slog.WarnContext(ctx, "order events listener failed, reconnecting", slog.Any("error", err))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(listenerRetryInterval):
+		}
+	}
+}
+
+func (r *GormEventRepository) listenOnce(ctx context.Context, sqlDB *sql.DB, seen *orders.SeenEvents, publish func(*orders.Event)) error {
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	return conn.Raw(func(driverConn any) error {
+		stdConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("unsupported driver connection %T", driverConn)
+		}
+		pgConn := stdConn.Conn()
+
+		if _, err := pgConn.Exec(ctx, "LISTEN "+OrderEventsChannel); err != nil {
+			return err
+		}
+
+		// События, записанные до начала прослушивания (или во время переподключения),
+		// включая зафиксированные позже событий с большим ID
+		err := r.eachSince(ctx, seen.ReplayFrom(), func(ev *orders.Event) {
+			if seen.Add(ev.ID) {
+				publish(ev)
+			}
+		})
+		if err != nil {
+			return err
+		}
+
+		for {
+			notification, err := pgConn.WaitForNotification(ctx)
+			if err != nil {
+				return err
+			}
+
+			var eventEntity entities.OrderEventEntity
+			if err := json.Unmarshal([]byte(notification.Payload), &eventEntity); err != nil {
+				return err
+			}
+			if seen.Add(eventEntity.ID) {
+				publish(eventEntity.ToLogicEvent())
+			}
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package repository_orders
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/Owouwun/spkuznetsov/internal/core/repository/entities"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (r *GormOrderRepository) PendingGeocoding(ctx context.Context, limit int) ([]*orders.Order, error) {
+	var orderEntities []entities.OrderEntity
+	result := conn(ctx, r.db).
+		Where("geocoded_at IS NULL").
+		Order("geocode_attempted_at NULLS FIRST, status_changed_at").
+		Limit(limit).
+		Find(&orderEntities)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	pending := make([]*orders.Order, 0, len(orderEntities))
+	for _, entity := range orderEntities {
+		order, err := r.toLogicOrder(&entity)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, order)
+	}
+	return pending, nil
+}
+
+func (r *GormOrderRepository) SetLocation(ctx context.Context, id uuid.UUID, address orders.Address, location *orders.GeoPoint) (bool, error) {
+	now := r.now()
+	updates := map[string]any{
+		"latitude":    nil,
+		"longitude":   nil,
+		"geocoded_at": now,
+	}
+	if location != nil {
+		updates["latitude"], updates["longitude"] = location.Lat, location.Lon
+	}
+
+	var saved bool
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		// Адрес хранится зашифрованным, поэтому сравнивается после расшифровки,
+		// а строка блокируется до сохранения координат
+		var orderEntity entities.OrderEntity
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("anonymized_at IS NULL").
+			First(&orderEntity, "id = ?", id).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		order, err := r.toLogicOrder(&orderEntity)
+		if err != nil {
+			return err
+		}
+		if order.Address.Query() != address.Query() {
+			return nil
+		}
+
+		result := tx.
+			Model(&entities.OrderEntity{}).
+			Where("id = ? AND anonymized_at IS NULL AND address = ?", id, orderEntity.Address).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		saved = result.RowsAffected > 0
+		return nil
+	})
+	return saved, err
+}
+
+func (r *GormOrderRepository) GeocodingFailed(ctx context.Context, id uuid.UUID) error {
+	result := conn(ctx, r.db).
+		Model(&entities.OrderEntity{}).
+		Where("id = ?", id).
+		Update("geocode_attempted_at", r.now())
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
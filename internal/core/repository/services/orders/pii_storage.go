@@ -0,0 +1,171 @@
+package repository_orders
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/repository/entities"
+	"github.com/google/uuid"
+)
+
+const selectArchivedOrdersPage = `
+SELECT (jsonb_populate_record(NULL::public.orders, data)).*
+FROM public.orders_archive
+WHERE id > ? AND %s
+ORDER BY id
+LIMIT ?`
+
+// Заявки без слепого индекса телефона: записанные до шифрования или загруженные
+// из демонстрационных данных
+const (
+	missingPhoneIndex         = "client_phone_hash IS NULL AND client_phone <> ''"
+	missingArchivedPhoneIndex = "data->>'client_phone_hash' IS NULL AND data->>'client_phone' <> ''"
+)
+
+const selectPhoneIndexPending = `
+SELECT EXISTS (SELECT 1 FROM public.orders WHERE %s)
+OR EXISTS (SELECT 1 FROM public.orders_archive WHERE %s)`
+
+const updateArchivedOrderPII = `
+UPDATE public.orders_archive
+SET data = data || jsonb_build_object(
+	'client_name', @client_name::text, 'client_phone', @client_phone::text,
+	'client_phone_hash', @client_phone_hash::text, 'address', @address::text
+)
+WHERE id = @id`
+
+// ReencryptPII перешифровывает текущим ключом персональные данные заявок, включая удалённые
+// и архивные, если они зашифрованы старым ключом или записаны открыто, и пересчитывает
+// слепой индекс телефона. Возвращает число изменённых заявок
+func (r *GormOrderRepository) ReencryptPII(ctx context.Context, batchSize int) (int, error) {
+	return r.reencryptPII(ctx, batchSize, "TRUE", "TRUE")
+}
+
+// BackfillPhoneIndex строит слепой индекс телефона у заявок, у которых его нет; открыто
+// записанные данные таких заявок заодно шифруются. Возвращает число изменённых заявок
+func (r *GormOrderRepository) BackfillPhoneIndex(ctx context.Context, batchSize int) (int, error) {
+	return r.reencryptPII(ctx, batchSize, missingPhoneIndex, missingArchivedPhoneIndex)
+}
+
+// PhoneIndexPending сообщает, есть ли заявки без слепого индекса телефона
+func (r *GormOrderRepository) PhoneIndexPending(ctx context.Context) (bool, error) {
+	var pending bool
+	err := conn(ctx, r.db).
+		Raw(fmt.Sprintf(selectPhoneIndexPending, missingPhoneIndex, missingArchivedPhoneIndex)).
+		Scan(&pending).Error
+	return pending, err
+}
+
+// Перешифровать заявки, подходящие под условия для основной таблицы и архива
+func (r *GormOrderRepository) reencryptPII(ctx context.Context, batchSize int, ordersCond, archiveCond string) (int, error) {
+	db := conn(ctx, r.db)
+
+	updated, err := r.reencryptPages(batchSize,
+		func(after uuid.UUID, page *[]entities.OrderEntity) error {
+			return db.Unscoped().Where("id > ?", after).Where(ordersCond).Order("id").Limit(batchSize).Find(page).Error
+		},
+		func(old, fresh *entities.OrderEntity) (bool, error) {
+			// Строка обновляется, только если её не изменили после чтения
+			result := db.Unscoped().Model(&entities.OrderEntity{}).
+				Where("id = ? AND client_name = ? AND client_phone = ? AND address = ?",
+					old.ID, old.ClientName, old.ClientPhone, old.Address).
+				Updates(map[string]any{
+					"client_name":       fresh.ClientName,
+					"client_phone":      fresh.ClientPhone,
+					"client_phone_hash": fresh.ClientPhoneHash,
+					"address":           fresh.Address,
+				})
+			return result.RowsAffected > 0, result.Error
+		},
+	)
+	if err != nil {
+		return updated, err
+	}
+
+	archived, err := r.reencryptPages(batchSize,
+		func(after uuid.UUID, page *[]entities.OrderEntity) error {
+			return db.Raw(fmt.Sprintf(selectArchivedOrdersPage, archiveCond), after, batchSize).Scan(page).Error
+		},
+		func(_, fresh *entities.OrderEntity) (bool, error) {
+			// Архивные заявки не меняются, поэтому проверка на изменение не нужна
+			result := db.Exec(updateArchivedOrderPII, map[string]any{
+				"id":                fresh.ID,
+				"client_name":       fresh.ClientName,
+				"client_phone":      fresh.ClientPhone,
+				"client_phone_hash": fresh.ClientPhoneHash,
+				"address":           fresh.Address,
+			})
+			return result.RowsAffected > 0, result.Error
+		},
+	)
+	return updated + archived, err
+}
+
+func (r *GormOrderRepository) reencryptPages(
+	batchSize int,
+	load func(after uuid.UUID, page *[]entities.OrderEntity) error,
+	save func(old, fresh *entities.OrderEntity) (bool, error),
+) (int, error) {
+	var (
+		after   uuid.UUID
+		updated int
+	)
+	for {
+		var page []entities.OrderEntity
+		if err := load(after, &page); err != nil {
+			return updated, err
+		}
+
+		for i := range page {
+			old := &page[i]
+			after = old.ID
+
+			fresh, err := r.reencrypt(old)
+			if err != nil {
+				return updated, err
+			}
+			if fresh == nil {
+				continue
+			}
+
+			ok, err := save(old, fresh)
+			if err != nil {
+				return updated, err
+			}
+			if ok {
+				updated++
+			}
+		}
+
+		if len(page) < batchSize {
+			return updated, nil
+		}
+	}
+}
+
+// Перешифрованная копия сущности или nil, если она уже зашифрована текущим ключом
+func (r *GormOrderRepository) reencrypt(oe *entities.OrderEntity) (*entities.OrderEntity, error) {
+	order, err := oe.ToLogicOrder(r.cipher)
+	if err != nil {
+		return nil, err
+	}
+	fresh, err := entities.NewOrderEntityFromLogic(order, r.cipher)
+	if err != nil {
+		return nil, err
+	}
+
+	if !r.cipher.NeedsRotation(oe.ClientName) &&
+		!r.cipher.NeedsRotation(oe.ClientPhone) &&
+		!r.cipher.NeedsRotation(oe.Address) &&
+		equalHash(oe.ClientPhoneHash, fresh.ClientPhoneHash) {
+		return nil, nil
+	}
+	return fresh, nil
+}
+
+func equalHash(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,49 @@
+package repository_orders
+
+import (
+	"context"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/Owouwun/spkuznetsov/internal/core/repository/entities"
+	"gorm.io/gorm/clause"
+)
+
+// Заявка нарушила SLA в текущем статусе: нарушение зафиксировано для того же перехода в статус
+const overdueCondition = `EXISTS (
+	SELECT 1 FROM public.order_sla_breaches b
+	WHERE b.order_id = orders.id AND b.status = orders.status AND b.status_changed_at = orders.status_changed_at
+)`
+
+func (r *GormOrderRepository) SLACandidates(ctx context.Context, status orders.Status, changedBefore time.Time) ([]*orders.Order, error) {
+	var orderEntities []entities.OrderEntity
+	result := conn(ctx, r.db).
+		Preload("Employee").
+		Where("status = ? AND status_changed_at < ?", int(status), changedBefore).
+		Where("NOT " + overdueCondition).
+		Order("status_changed_at").
+		Find(&orderEntities)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	candidates := make([]*orders.Order, 0, len(orderEntities))
+	for _, entity := range orderEntities {
+		order, err := r.toLogicOrder(&entity)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, order)
+	}
+	return candidates, nil
+}
+
+func (r *GormOrderRepository) RecordSLABreach(ctx context.Context, breach *orders.SLABreach) (bool, error) {
+	result := conn(ctx, r.db).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(entities.NewSLABreachEntityFromLogic(breach))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -2,25 +2,73 @@ package repository_orders
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
 	"github.com/Owouwun/spkuznetsov/internal/core/repository/entities"
+	"github.com/Owouwun/spkuznetsov/internal/pii"
+	"github.com/Owouwun/spkuznetsov/pkg/clock"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type GormOrderRepository struct {
-	db *gorm.DB
+	db         *gorm.DB
+	cipher     entities.PIICipher
+	scheduling orders.Scheduling
 }
 
-func NewOrderRepository(db *gorm.DB) orders.OrderRepository {
-	return &GormOrderRepository{db: db}
+type OrderRepositoryOption func(*GormOrderRepository)
+
+// WithCipher включает шифрование персональных данных клиента.
+// Без него данные хранятся открыто
+func WithCipher(cipher entities.PIICipher) OrderRepositoryOption {
+	return func(r *GormOrderRepository) {
+		r.cipher = cipher
+	}
+}
+
+// WithScheduling задаёт часы и рабочий календарь, по которым заявки планируются
+// и проходят статусы. Без него используются системные часы без проверки рабочего времени
+func WithScheduling(scheduling orders.Scheduling) OrderRepositoryOption {
+	return func(r *GormOrderRepository) {
+		r.scheduling = scheduling
+	}
+}
+
+func NewOrderRepository(db *gorm.DB, opts ...OrderRepositoryOption) *GormOrderRepository {
+	r := &GormOrderRepository{
+		db:     db,
+		cipher: pii.Plaintext{},
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.scheduling.Clock == nil {
+		r.scheduling.Clock = clock.System{}
+	}
+	return r
+}
+
+// Расшифровать заявку и связать её с условиями планирования
+func (r *GormOrderRepository) toLogicOrder(oe *entities.OrderEntity) (*orders.Order, error) {
+	order, err := oe.ToLogicOrder(r.cipher)
+	if err != nil || order == nil {
+		return order, err
+	}
+	order.UseScheduling(r.scheduling)
+	return order, nil
+}
+
+func (r *GormOrderRepository) now() time.Time {
+	return r.scheduling.Clock.Now()
 }
 
 func (r *GormOrderRepository) getEntityByID(ctx context.Context, id uuid.UUID) (*entities.OrderEntity, error) {
 	var orderEntity *entities.OrderEntity
-	result := r.db.WithContext(ctx).
+	result := conn(ctx, r.db).
 		Preload("Employee").
 		First(&orderEntity, "id = ?", id)
 	if result.Error != nil {
@@ -32,7 +80,7 @@ func (r *GormOrderRepository) getEntityByID(ctx context.Context, id uuid.UUID) (
 
 func (r *GormOrderRepository) getEmployeeEntityByID(ctx context.Context, id uint) (*entities.EmployeeEntity, error) {
 	var empEntity *entities.EmployeeEntity
-	result := r.db.WithContext(ctx).
+	result := conn(ctx, r.db).
 		First(&empEntity, "id = ?", id)
 	if result.Error != nil {
 		return nil, result.Error
@@ -42,9 +90,18 @@ func (r *GormOrderRepository) getEmployeeEntityByID(ctx context.Context, id uint
 }
 
 func (r *GormOrderRepository) Create(ctx context.Context, ord *orders.Order) (uuid.UUID, error) {
-	orderEntity := entities.NewOrderEntityFromLogic(ord)
+	orderEntity, err := entities.NewOrderEntityFromLogic(ord, r.cipher)
+	if err != nil {
+		return uuid.Nil, err
+	}
 
-	result := r.db.WithContext(ctx).Create(&orderEntity)
+	// Координаты, переданные вместе с адресом, не нужно геокодировать
+	if orderEntity.Latitude != nil {
+		now := r.now()
+		orderEntity.GeocodedAt = &now
+	}
+
+	result := conn(ctx, r.db).Create(&orderEntity)
 	if result.Error != nil {
 		return uuid.Nil, result.Error
 	}
@@ -53,22 +110,40 @@ func (r *GormOrderRepository) Create(ctx context.Context, ord *orders.Order) (uu
 }
 
 func (r *GormOrderRepository) Update(ctx context.Context, ord *orders.Order) error {
-	orderEntity := entities.NewOrderEntityFromLogic(ord)
+	orderEntity, err := entities.NewOrderEntityFromLogic(ord, r.cipher)
+	if err != nil {
+		return err
+	}
 
-	result := r.db.WithContext(ctx).
+	fields := []any{
+		"ClientName",
+		"ClientPhone",
+		"ClientPhoneHash",
+		"Address",
+		"ClientDescription",
+		"Category",
+		"Timezone",
+		"EmployeeID",
+		"CancelReason",
+		"Status",
+		"EmployeeDescription",
+		"ScheduledFor",
+		"StatusChangedAt",
+	}
+	// Координаты меняются только вместе с адресом другого здания (см. Order.Patch):
+	// без переданных координат такой адрес снова попадёт в PendingGeocoding
+	if ord.Relocated() {
+		fields = append(fields, "Latitude", "Longitude", "GeocodedAt", "GeocodeAttemptedAt")
+		if orderEntity.Latitude != nil {
+			now := r.now()
+			orderEntity.GeocodedAt = &now
+		}
+	}
+
+	result := conn(ctx, r.db).
 		Model(&orderEntity).
 		Where("id = ?", ord.ID).
-		Select(
-			"ClientName",
-			"ClientPhone",
-			"Address",
-			"ClientDescription",
-			"EmployeeID",
-			"CancelReason",
-			"Status",
-			"EmployeeDescription",
-			"ScheduledFor",
-		).
+		Select(fields[0], fields[1:]...).
 		Updates(orderEntity)
 	if result.Error != nil {
 		return result.Error
@@ -83,17 +158,65 @@ func (r *GormOrderRepository) Update(ctx context.Context, ord *orders.Order) err
 
 func (r *GormOrderRepository) GetByID(ctx context.Context, id uuid.UUID) (*orders.Order, error) {
 	orderEntity, err := r.getEntityByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		orderEntity, err = r.getArchivedEntityByID(ctx, id)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	return orderEntity.ToLogicOrder(), nil
+	return r.toLogicOrder(orderEntity)
+}
+
+// Снимок строки orders разворачивается обратно в запись текущей схемы: новые столбцы получают NULL
+const selectArchivedOrder = `
+SELECT (jsonb_populate_record(NULL::public.orders, data)).*, archived_at
+FROM public.orders_archive
+WHERE id = ?`
+
+func (r *GormOrderRepository) getArchivedEntityByID(ctx context.Context, id uuid.UUID) (*entities.OrderEntity, error) {
+	var orderEntity entities.OrderEntity
+	result := conn(ctx, r.db).Raw(selectArchivedOrder, id).Scan(&orderEntity)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 || orderEntity.DeletedAt.Valid {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	if orderEntity.EmployeeID != nil {
+		emp, err := r.getEmployeeEntityByID(ctx, *orderEntity.EmployeeID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		orderEntity.Employee = emp
+	}
+
+	return &orderEntity, nil
 }
 
-func (r *GormOrderRepository) GetAll(ctx context.Context) ([]*orders.Order, error) {
+// Запрос заявок по фильтру списка
+func (r *GormOrderRepository) listQuery(ctx context.Context, filter orders.ListFilter) *gorm.DB {
+	db := conn(ctx, r.db)
+	if filter.IncludeDeleted {
+		db = db.Unscoped()
+	}
+	if filter.Overdue {
+		db = db.Where(overdueCondition)
+	}
+	if filter.TemplateID != nil {
+		db = db.Where("template_id = ?", *filter.TemplateID)
+	}
+	if filter.ClientPhone != "" {
+		// Телефон хранится зашифрованным, поэтому заявки клиента ищутся по слепому индексу
+		db = db.Where("client_phone_hash = ?", r.cipher.BlindIndex(filter.ClientPhone))
+	}
+	return db.Preload("Employee")
+}
+
+func (r *GormOrderRepository) GetAll(ctx context.Context, filter orders.ListFilter) ([]*orders.Order, error) {
 	var orderEntities []entities.OrderEntity
-	result := r.db.WithContext(ctx).
-		Preload("Employee").
+	result := r.listQuery(ctx, filter).
 		Find(&orderEntities)
 
 	if result.Error != nil {
@@ -102,28 +225,91 @@ func (r *GormOrderRepository) GetAll(ctx context.Context) ([]*orders.Order, erro
 
 	var logicOrders []*orders.Order
 	for _, entity := range orderEntities {
-		logicOrders = append(logicOrders, entity.ToLogicOrder())
+		order, err := r.toLogicOrder(&entity)
+		if err != nil {
+			return nil, err
+		}
+		logicOrders = append(logicOrders, order)
 	}
 
 	return logicOrders, nil
 }
 
+func (r *GormOrderRepository) Each(ctx context.Context, filter orders.ListFilter, batchSize int, fn func(*orders.Order) error) error {
+	var after uuid.UUID
+	for {
+		var page []entities.OrderEntity
+		result := r.listQuery(ctx, filter).
+			Where("id > ?", after).
+			Order("id").
+			Limit(batchSize).
+			Find(&page)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		for _, entity := range page {
+			order, err := r.toLogicOrder(&entity)
+			if err != nil {
+				return err
+			}
+			if err := fn(order); err != nil {
+				return err
+			}
+		}
+
+		if len(page) < batchSize {
+			return nil
+		}
+		after = page[len(page)-1].ID
+	}
+}
+
+func (r *GormOrderRepository) GetScheduled(ctx context.Context, employeeID uint, from, to time.Time) ([]*orders.Order, error) {
+	var orderEntities []entities.OrderEntity
+	result := conn(ctx, r.db).
+		Preload("Employee").
+		Where("employee_id = ? AND status = ?", employeeID, int(orders.StatusScheduled)).
+		Where("scheduled_for >= ? AND scheduled_for < ?", from, to).
+		Order("scheduled_for, id").
+		Find(&orderEntities)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	scheduled := make([]*orders.Order, 0, len(orderEntities))
+	for _, entity := range orderEntities {
+		order, err := r.toLogicOrder(&entity)
+		if err != nil {
+			return nil, err
+		}
+		scheduled = append(scheduled, order)
+	}
+	return scheduled, nil
+}
+
 func (r *GormOrderRepository) Preschedule(ctx context.Context, id uuid.UUID, scheduledFor *time.Time) error {
 	orderEntity, err := r.getEntityByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	order := orderEntity.ToLogicOrder()
+	order, err := r.toLogicOrder(orderEntity)
+	if err != nil {
+		return err
+	}
 
 	err = order.Preschedule(scheduledFor)
 	if err != nil {
 		return err
 	}
 
-	orderEntity = entities.NewOrderEntityFromLogic(order)
+	orderEntity, err = entities.NewOrderEntityFromLogic(order, r.cipher)
+	if err != nil {
+		return err
+	}
 
-	result := r.db.WithContext(ctx).
+	result := conn(ctx, r.db).
 		Model(&orderEntity).
 		Where("id = ?", id).
 		Updates(orderEntity)
@@ -142,7 +328,10 @@ func (r *GormOrderRepository) Assign(ctx context.Context, id uuid.UUID, empID ui
 		return err
 	}
 
-	order := orderEntity.ToLogicOrder()
+	order, err := r.toLogicOrder(orderEntity)
+	if err != nil {
+		return err
+	}
 	emp := empEntity.ToLogicEmployee()
 
 	err = order.Assign(emp)
@@ -150,9 +339,12 @@ func (r *GormOrderRepository) Assign(ctx context.Context, id uuid.UUID, empID ui
 		return err
 	}
 
-	orderEntity = entities.NewOrderEntityFromLogic(order)
+	orderEntity, err = entities.NewOrderEntityFromLogic(order, r.cipher)
+	if err != nil {
+		return err
+	}
 
-	result := r.db.WithContext(ctx).
+	result := conn(ctx, r.db).
 		Model(&orderEntity).
 		Where("id = ?", id).
 		Updates(orderEntity)
@@ -161,48 +353,60 @@ func (r *GormOrderRepository) Assign(ctx context.Context, id uuid.UUID, empID ui
 }
 
 func (r *GormOrderRepository) Schedule(ctx context.Context, id uuid.UUID, scheduledFor *time.Time) error {
-	orderEntity, err := r.getEntityByID(ctx, id)
-	if err != nil {
-		return err
-	}
-
-	order := orderEntity.ToLogicOrder()
+	return r.updateVisits(ctx, id, func(order *orders.Order) error {
+		if scheduledFor == nil {
+			return order.ConfirmSchedule()
+		}
+		return order.Schedule(scheduledFor)
+	})
+}
 
-	if scheduledFor == nil {
-		err = order.ConfirmSchedule()
-	} else {
-		err = order.Schedule(scheduledFor)
-	}
-	if err != nil {
-		return err
-	}
+func (r *GormOrderRepository) Progress(ctx context.Context, id uuid.UUID, report *orders.VisitReport) error {
+	return r.updateVisits(ctx, id, func(order *orders.Order) error {
+		return order.Progress(report)
+	})
+}
 
-	orderEntity = entities.NewOrderEntityFromLogic(order)
+func (r *GormOrderRepository) Complete(ctx context.Context, id uuid.UUID) error {
+	return r.updateVisits(ctx, id, func(order *orders.Order) error {
+		return order.Complete()
+	})
+}
 
-	result := r.db.WithContext(ctx).
-		Model(&orderEntity).
-		Where("id = ?", id).
-		Updates(orderEntity)
+func (r *GormOrderRepository) CheckIn(ctx context.Context, id uuid.UUID, p *orders.Presence) error {
+	return r.updateVisits(ctx, id, func(order *orders.Order) error {
+		return order.CheckIn(p)
+	})
+}
 
-	return result.Error
+func (r *GormOrderRepository) CheckOut(ctx context.Context, id uuid.UUID, p *orders.Presence, report *orders.VisitReport) error {
+	return r.updateVisits(ctx, id, func(order *orders.Order) error {
+		return order.CheckOut(p, report)
+	})
 }
 
-func (r *GormOrderRepository) Progress(ctx context.Context, id uuid.UUID, empDescription string) error {
+func (r *GormOrderRepository) Release(ctx context.Context, id uuid.UUID) error {
 	orderEntity, err := r.getEntityByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	order := orderEntity.ToLogicOrder()
+	order, err := r.toLogicOrder(orderEntity)
+	if err != nil {
+		return err
+	}
 
-	err = order.Progress(empDescription)
+	err = order.Release()
 	if err != nil {
 		return err
 	}
 
-	orderEntity = entities.NewOrderEntityFromLogic(order)
+	orderEntity, err = entities.NewOrderEntityFromLogic(order, r.cipher)
+	if err != nil {
+		return err
+	}
 
-	result := r.db.WithContext(ctx).
+	result := conn(ctx, r.db).
 		Model(&orderEntity).
 		Where("id = ?", id).
 		Updates(orderEntity)
@@ -210,22 +414,28 @@ func (r *GormOrderRepository) Progress(ctx context.Context, id uuid.UUID, empDes
 	return result.Error
 }
 
-func (r *GormOrderRepository) Complete(ctx context.Context, id uuid.UUID) error {
+func (r *GormOrderRepository) Close(ctx context.Context, id uuid.UUID) error {
 	orderEntity, err := r.getEntityByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	order := orderEntity.ToLogicOrder()
+	order, err := r.toLogicOrder(orderEntity)
+	if err != nil {
+		return err
+	}
 
-	err = order.Complete()
+	err = order.Close()
 	if err != nil {
 		return err
 	}
 
-	orderEntity = entities.NewOrderEntityFromLogic(order)
+	orderEntity, err = entities.NewOrderEntityFromLogic(order, r.cipher)
+	if err != nil {
+		return err
+	}
 
-	result := r.db.WithContext(ctx).
+	result := conn(ctx, r.db).
 		Model(&orderEntity).
 		Where("id = ?", id).
 		Updates(orderEntity)
@@ -233,22 +443,28 @@ func (r *GormOrderRepository) Complete(ctx context.Context, id uuid.UUID) error
 	return result.Error
 }
 
-func (r *GormOrderRepository) Close(ctx context.Context, id uuid.UUID) error {
+func (r *GormOrderRepository) Cancel(ctx context.Context, id uuid.UUID, reason string) error {
 	orderEntity, err := r.getEntityByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	order := orderEntity.ToLogicOrder()
+	order, err := r.toLogicOrder(orderEntity)
+	if err != nil {
+		return err
+	}
 
-	err = order.Close()
+	err = order.Cancel(reason)
 	if err != nil {
 		return err
 	}
 
-	orderEntity = entities.NewOrderEntityFromLogic(order)
+	orderEntity, err = entities.NewOrderEntityFromLogic(order, r.cipher)
+	if err != nil {
+		return err
+	}
 
-	result := r.db.WithContext(ctx).
+	result := conn(ctx, r.db).
 		Model(&orderEntity).
 		Where("id = ?", id).
 		Updates(orderEntity)
@@ -256,25 +472,81 @@ func (r *GormOrderRepository) Close(ctx context.Context, id uuid.UUID) error {
 	return result.Error
 }
 
-func (r *GormOrderRepository) Cancel(ctx context.Context, id uuid.UUID, reason string) error {
+func (r *GormOrderRepository) CountByStatus(ctx context.Context) (map[orders.Status]int64, error) {
+	var rows []struct {
+		Status int
+		Count  int64
+	}
+	result := conn(ctx, r.db).
+		Model(&entities.OrderEntity{}).
+		Select("status, count(*) AS count").
+		Group("status").
+		Scan(&rows)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	counts := make(map[orders.Status]int64, len(rows))
+	for _, row := range rows {
+		counts[orders.Status(row.Status)] = row.Count
+	}
+	return counts, nil
+}
+
+func (r *GormOrderRepository) Delete(ctx context.Context, id uuid.UUID) (*orders.Order, error) {
 	orderEntity, err := r.getEntityByID(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	order := orderEntity.ToLogicOrder()
+	result := conn(ctx, r.db).Delete(orderEntity)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
 
-	err = order.Cancel(reason)
+	order, err := r.toLogicOrder(orderEntity)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if order.DeletedAt == nil {
+		now := r.now()
+		order.DeletedAt = &now
 	}
+	return order, nil
+}
 
-	orderEntity = entities.NewOrderEntityFromLogic(order)
+// Перенос выполняется одним запросом: строки удаляются из orders и в той же транзакции
+// сохраняются в orders_archive. SKIP LOCKED не даёт нескольким репликам архивировать одни и те же заявки
+const archiveOrders = `
+WITH moved AS (
+	DELETE FROM public.orders
+	WHERE id IN (
+		SELECT id FROM public.orders
+		WHERE status IN ? AND status_changed_at < ?
+		ORDER BY status_changed_at
+		LIMIT ?
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING *
+)
+INSERT INTO public.orders_archive (id, data)
+SELECT id, to_jsonb(moved) FROM moved`
 
-	result := r.db.WithContext(ctx).
-		Model(&orderEntity).
-		Where("id = ?", id).
-		Updates(orderEntity)
+func (r *GormOrderRepository) Archive(ctx context.Context, before time.Time, limit int) (int, error) {
+	result := conn(ctx, r.db).Exec(archiveOrders, closedStatuses(), before, limit)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return int(result.RowsAffected), nil
+}
 
-	return result.Error
+func closedStatuses() []int {
+	statuses := make([]int, 0, len(orders.ClosedStatuses))
+	for _, s := range orders.ClosedStatuses {
+		statuses = append(statuses, int(s))
+	}
+	return statuses
 }
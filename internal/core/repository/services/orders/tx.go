@@ -0,0 +1,37 @@
+package repository_orders
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// GormTransactor открывает транзакцию и передаёт её через ctx репозиториям заявок
+// и журнала событий этого пакета
+type GormTransactor struct {
+	db *gorm.DB
+}
+
+func NewTransactor(db *gorm.DB) *GormTransactor {
+	return &GormTransactor{db: db}
+}
+
+// InTx выполняет fn в транзакции; вложенный вызов выполняется в уже открытой
+func (t *GormTransactor) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return fn(ctx)
+	}
+	return t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// Транзакция из ctx, если она открыта, иначе db
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
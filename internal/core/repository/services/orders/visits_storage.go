@@ -0,0 +1,74 @@
+package repository_orders
+
+import (
+	"context"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/Owouwun/spkuznetsov/internal/core/repository/entities"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (r *GormOrderRepository) GetVisits(ctx context.Context, orderID uuid.UUID) ([]*orders.Visit, error) {
+	var visitEntities []entities.VisitEntity
+	result := conn(ctx, r.db).
+		Preload("Employee").
+		Where("order_id = ?", orderID).
+		Order("created_at, id").
+		Find(&visitEntities)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	visits := make([]*orders.Visit, 0, len(visitEntities))
+	for i := range visitEntities {
+		visits = append(visits, visitEntities[i].ToLogicVisit())
+	}
+	return visits, nil
+}
+
+// Загрузить заявку вместе с визитами, применить к ней действие и сохранить заявку и визиты
+// в одной транзакции
+func (r *GormOrderRepository) updateVisits(ctx context.Context, id uuid.UUID, action func(order *orders.Order) error) error {
+	orderEntity, err := r.getEntityByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	order, err := r.toLogicOrder(orderEntity)
+	if err != nil {
+		return err
+	}
+	if order.Visits, err = r.GetVisits(ctx, id); err != nil {
+		return err
+	}
+
+	if err := action(order); err != nil {
+		return err
+	}
+
+	orderEntity, err = entities.NewOrderEntityFromLogic(order, r.cipher)
+	if err != nil {
+		return err
+	}
+
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		// Select, чтобы сохранить и обнулённые поля (ScheduledFor после завершения визита)
+		result := tx.
+			Model(&orderEntity).
+			Where("id = ?", id).
+			Select("EmployeeID", "Status", "EmployeeDescription", "ScheduledFor", "StatusChangedAt", "WorkSeconds").
+			Updates(orderEntity)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		for _, visit := range order.Visits {
+			if err := tx.Omit(clause.Associations).Save(entities.NewVisitEntityFromLogic(visit)).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,185 @@
+package repository_recurring
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/recurring"
+	"github.com/Owouwun/spkuznetsov/internal/core/repository/entities"
+	"github.com/Owouwun/spkuznetsov/internal/pii"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type GormTemplateRepository struct {
+	db     *gorm.DB
+	cipher entities.PIICipher
+}
+
+type TemplateRepositoryOption func(*GormTemplateRepository)
+
+// WithCipher включает шифрование персональных данных клиента.
+// Шаблоны и заявки должны шифроваться одним ключом
+func WithCipher(cipher entities.PIICipher) TemplateRepositoryOption {
+	return func(r *GormTemplateRepository) {
+		r.cipher = cipher
+	}
+}
+
+func NewTemplateRepository(db *gorm.DB, opts ...TemplateRepositoryOption) *GormTemplateRepository {
+	r := &GormTemplateRepository{
+		db:     db,
+		cipher: pii.Plaintext{},
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func (r *GormTemplateRepository) toLogicTemplates(templateEntities []entities.RecurringTemplateEntity) ([]*recurring.Template, error) {
+	templates := make([]*recurring.Template, 0, len(templateEntities))
+	for i := range templateEntities {
+		t, err := templateEntities[i].ToLogicTemplate(r.cipher)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+func (r *GormTemplateRepository) Create(ctx context.Context, t *recurring.Template) error {
+	templateEntity, err := entities.NewRecurringTemplateEntityFromLogic(t, r.cipher)
+	if err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Create(templateEntity).Error
+}
+
+func (r *GormTemplateRepository) GetByID(ctx context.Context, id uuid.UUID) (*recurring.Template, error) {
+	var templateEntity entities.RecurringTemplateEntity
+	err := r.db.WithContext(ctx).First(&templateEntity, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, recurring.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return templateEntity.ToLogicTemplate(r.cipher)
+}
+
+func (r *GormTemplateRepository) GetAll(ctx context.Context, filter recurring.ListFilter) ([]*recurring.Template, error) {
+	db := r.db.WithContext(ctx)
+	if filter.ClientPhone != "" {
+		// Телефон хранится зашифрованным, поэтому шаблоны клиента ищутся по слепому индексу
+		db = db.Where("client_phone_hash = ?", r.cipher.BlindIndex(filter.ClientPhone))
+	}
+
+	var templateEntities []entities.RecurringTemplateEntity
+	if err := db.Order("created_at, id").Find(&templateEntities).Error; err != nil {
+		return nil, err
+	}
+	return r.toLogicTemplates(templateEntities)
+}
+
+func (r *GormTemplateRepository) Save(ctx context.Context, t *recurring.Template, prevStatus recurring.Status, prevNextOn *recurring.Date) (bool, error) {
+	db := r.db.WithContext(ctx).
+		Model(&entities.RecurringTemplateEntity{}).
+		Where("id = ? AND status = ?", t.ID, string(prevStatus))
+	if prevNextOn != nil {
+		db = db.Where("next_on = ?", prevNextOn.Time)
+	} else {
+		db = db.Where("next_on IS NULL")
+	}
+
+	var nextOn any
+	if t.NextOn != nil {
+		nextOn = t.NextOn.Time
+	}
+	result := db.Updates(map[string]any{
+		"status":      string(t.Status),
+		"next_on":     nextOn,
+		"occurrences": t.Occurrences,
+	})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *GormTemplateRepository) Due(ctx context.Context, horizon recurring.Date, limit int) ([]*recurring.Template, error) {
+	var templateEntities []entities.RecurringTemplateEntity
+	result := r.db.WithContext(ctx).
+		Where("status = ? AND next_on <= ?", string(recurring.StatusActive), horizon.Time).
+		Order("next_on, id").
+		Limit(limit).
+		Find(&templateEntities)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return r.toLogicTemplates(templateEntities)
+}
+
+// ReencryptPII перешифровывает текущим ключом персональные данные шаблонов, если они
+// зашифрованы старым ключом или записаны открыто. Возвращает число изменённых шаблонов
+func (r *GormTemplateRepository) ReencryptPII(ctx context.Context, batchSize int) (int, error) {
+	db := r.db.WithContext(ctx)
+
+	var (
+		after   uuid.UUID
+		updated int
+	)
+	for {
+		var page []entities.RecurringTemplateEntity
+		if err := db.Where("id > ?", after).Order("id").Limit(batchSize).Find(&page).Error; err != nil {
+			return updated, err
+		}
+
+		for i := range page {
+			old := &page[i]
+			after = old.ID
+
+			t, err := old.ToLogicTemplate(r.cipher)
+			if err != nil {
+				return updated, err
+			}
+			fresh, err := entities.NewRecurringTemplateEntityFromLogic(t, r.cipher)
+			if err != nil {
+				return updated, err
+			}
+			if !r.cipher.NeedsRotation(old.ClientName) &&
+				!r.cipher.NeedsRotation(old.ClientPhone) &&
+				!r.cipher.NeedsRotation(old.Address) &&
+				equalHash(old.ClientPhoneHash, fresh.ClientPhoneHash) {
+				continue
+			}
+
+			// Строка обновляется, только если её не изменили после чтения
+			result := db.Model(&entities.RecurringTemplateEntity{}).
+				Where("id = ? AND client_name = ? AND client_phone = ? AND address = ?",
+					old.ID, old.ClientName, old.ClientPhone, old.Address).
+				Updates(map[string]any{
+					"client_name":       fresh.ClientName,
+					"client_phone":      fresh.ClientPhone,
+					"client_phone_hash": fresh.ClientPhoneHash,
+					"address":           fresh.Address,
+				})
+			if result.Error != nil {
+				return updated, result.Error
+			}
+			if result.RowsAffected > 0 {
+				updated++
+			}
+		}
+
+		if len(page) < batchSize {
+			return updated, nil
+		}
+	}
+}
+
+func equalHash(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,213 @@
+package repository_reports
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/reports"
+	"gorm.io/gorm"
+)
+
+// События заявок за период с номером дня (от 0) по границам дней @days.
+// Удалённые заявки не учитываются: обычно это ошибочно оформленные заявки
+const periodEvents = `
+WITH events AS (
+	SELECT e.order_id, e.type, e.employee_id,
+	       width_bucket(e.created_at, CAST(@days AS timestamptz[])) - 1 AS day
+	FROM public.order_events e
+	WHERE e.created_at >= @start AND e.created_at < @end
+	  AND NOT EXISTS (
+		SELECT 1 FROM public.order_events d WHERE d.order_id = e.order_id AND d.type = 'deleted'
+	  )
+)`
+
+const selectThroughput = periodEvents + `
+SELECT day,
+       COUNT(*) FILTER (WHERE type = 'created') AS created,
+       COUNT(*) FILTER (WHERE type = 'completed') AS completed,
+       COUNT(*) FILTER (WHERE type = 'canceled') AS canceled
+FROM events
+WHERE type IN ('created', 'completed', 'canceled')
+GROUP BY day
+ORDER BY day`
+
+// Вход в статус — событие, статус в котором отличается от предыдущего события заявки.
+// Время в статусе — от входа в него до входа в следующий; переход относится к периоду,
+// если совершён в нём
+const selectTransitions = `
+WITH changed AS (
+	SELECT e.id, e.order_id, e.status, e.created_at,
+	       LAG(e.status) OVER (PARTITION BY e.order_id ORDER BY e.id) AS prev_status
+	FROM public.order_events e
+	WHERE e.order_id IN (
+		SELECT order_id FROM public.order_events WHERE created_at >= @start AND created_at < @end
+	)
+	  AND NOT EXISTS (
+		SELECT 1 FROM public.order_events d WHERE d.order_id = e.order_id AND d.type = 'deleted'
+	  )
+), entered AS (
+	SELECT id, order_id, status, created_at FROM changed WHERE prev_status IS DISTINCT FROM status
+), transitions AS (
+	SELECT LAG(status) OVER w AS from_status, status AS to_status, created_at,
+	       CAST(EXTRACT(EPOCH FROM created_at - LAG(created_at) OVER w) AS double precision) AS seconds
+	FROM entered
+	WINDOW w AS (PARTITION BY order_id ORDER BY id)
+)
+SELECT from_status, to_status, COUNT(*) AS count,
+       percentile_cont(0.5) WITHIN GROUP (ORDER BY seconds) AS median_seconds
+FROM transitions
+WHERE from_status IS NOT NULL AND created_at >= @start AND created_at < @end
+GROUP BY from_status, to_status
+ORDER BY from_status = -1, from_status, to_status = -1, to_status`
+
+// Причина отмены хранится в заявке или в её снимке в архиве
+const selectCancelReasons = periodEvents + `
+SELECT COALESCE(
+           NULLIF(lower(btrim(regexp_replace(COALESCE(o.cancel_reason, a.data->>'cancel_reason', ''), '\s+', ' ', 'g'))), ''),
+           @unspecified
+       ) AS reason,
+       COUNT(*) AS count
+FROM events e
+LEFT JOIN public.orders o ON o.id = e.order_id
+LEFT JOIN public.orders_archive a ON a.id = e.order_id
+WHERE e.type = 'canceled'
+GROUP BY 1
+ORDER BY count DESC, reason`
+
+const selectRevenue = periodEvents + `,
+paid AS (
+	SELECT DISTINCT ON (order_id) order_id, day FROM events WHERE type = 'closed' ORDER BY order_id, day
+)
+SELECT p.day, COUNT(*) AS paid_orders, CAST(COALESCE(SUM(li.amount), 0) AS bigint) AS amount
+FROM paid p
+LEFT JOIN (
+	SELECT order_id, SUM(quantity * unit_price) AS amount
+	FROM public.order_line_items
+	GROUP BY order_id
+) li ON li.order_id = p.order_id
+GROUP BY p.day
+ORDER BY p.day`
+
+const selectEmployeeCompletions = periodEvents + `
+SELECT e.employee_id, COALESCE(emp.name, '') AS name, COUNT(*) AS completed
+FROM events e
+LEFT JOIN public.employees emp ON emp.id = e.employee_id
+WHERE e.type = 'completed' AND e.employee_id IS NOT NULL
+GROUP BY e.employee_id, emp.name
+ORDER BY completed DESC, e.employee_id`
+
+// Границы дней передаются одним параметром-массивом: срез gorm развернул бы в список значений
+type timestamps []time.Time
+
+func (ts timestamps) Value() (driver.Value, error) {
+	values := make([]string, 0, len(ts))
+	for _, t := range ts {
+		values = append(values, `"`+t.Format(time.RFC3339Nano)+`"`)
+	}
+	return "{" + strings.Join(values, ",") + "}", nil
+}
+
+type GormReportRepository struct {
+	db *gorm.DB
+}
+
+func NewReportRepository(db *gorm.DB) *GormReportRepository {
+	return &GormReportRepository{db: db}
+}
+
+func periodArgs(p reports.Period) map[string]any {
+	return map[string]any{
+		"days":  timestamps(p.DayStarts()),
+		"start": p.Start(),
+		"end":   p.End(),
+	}
+}
+
+func (r *GormReportRepository) Throughput(ctx context.Context, p reports.Period) ([]reports.DayThroughput, error) {
+	var rows []struct {
+		Day       int
+		Created   int64
+		Completed int64
+		Canceled  int64
+	}
+	if err := r.db.WithContext(ctx).Raw(selectThroughput, periodArgs(p)).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	days := make([]reports.DayThroughput, 0, len(rows))
+	for _, row := range rows {
+		days = append(days, reports.DayThroughput{
+			Date:      p.Date(row.Day),
+			Created:   row.Created,
+			Completed: row.Completed,
+			Canceled:  row.Canceled,
+		})
+	}
+	return days, nil
+}
+
+func (r *GormReportRepository) Transitions(ctx context.Context, p reports.Period) ([]reports.Transition, error) {
+	var rows []struct {
+		FromStatus    int
+		ToStatus      int
+		Count         int64
+		MedianSeconds float64
+	}
+	if err := r.db.WithContext(ctx).Raw(selectTransitions, periodArgs(p)).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	transitions := make([]reports.Transition, 0, len(rows))
+	for _, row := range rows {
+		transitions = append(transitions, reports.Transition{
+			FromStatus:    orders.Status(row.FromStatus).ToString(),
+			ToStatus:      orders.Status(row.ToStatus).ToString(),
+			Count:         row.Count,
+			MedianSeconds: row.MedianSeconds,
+		})
+	}
+	return transitions, nil
+}
+
+func (r *GormReportRepository) CancelReasons(ctx context.Context, p reports.Period) ([]reports.ReasonCount, error) {
+	args := periodArgs(p)
+	args["unspecified"] = reports.UnspecifiedReason
+
+	var reasons []reports.ReasonCount
+	if err := r.db.WithContext(ctx).Raw(selectCancelReasons, args).Scan(&reasons).Error; err != nil {
+		return nil, err
+	}
+	return reasons, nil
+}
+
+func (r *GormReportRepository) Revenue(ctx context.Context, p reports.Period) ([]reports.DayRevenue, error) {
+	var rows []struct {
+		Day        int
+		PaidOrders int64
+		Amount     int64
+	}
+	if err := r.db.WithContext(ctx).Raw(selectRevenue, periodArgs(p)).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	days := make([]reports.DayRevenue, 0, len(rows))
+	for _, row := range rows {
+		days = append(days, reports.DayRevenue{
+			Date:       p.Date(row.Day),
+			PaidOrders: row.PaidOrders,
+			Amount:     row.Amount,
+		})
+	}
+	return days, nil
+}
+
+func (r *GormReportRepository) EmployeeCompletions(ctx context.Context, p reports.Period) ([]reports.EmployeeStats, error) {
+	var employees []reports.EmployeeStats
+	if err := r.db.WithContext(ctx).Raw(selectEmployeeCompletions, periodArgs(p)).Scan(&employees).Error; err != nil {
+		return nil, err
+	}
+	return employees, nil
+}
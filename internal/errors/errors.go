@@ -56,6 +56,9 @@ const (
 	InvalidValue DetErrType = "invalid value"
 
 	OrderActionNotPermittedByStatus DetErrType = "action permitted by order status"
+	EmployeeNotAssigned             DetErrType = "employee is not assigned to the order"
+
+	TemplateActionNotPermittedByStatus DetErrType = "action permitted by recurring template status"
 
 	QueryInsertFailed = "failed to insert"
 	QueryUpdateFailed = "failed to update"
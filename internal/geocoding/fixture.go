@@ -0,0 +1,49 @@
+package geocoding
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"gopkg.in/yaml.v3"
+)
+
+// Fixture — геокодер по заранее известному списку адресов. Адрес ищется по строке
+// orders.Address.Query() без учёта регистра и знаков препинания
+type Fixture struct {
+	points map[string]orders.GeoPoint
+}
+
+// NewFixture создаёт геокодер по списку «строка адреса — координаты»
+func NewFixture(points map[string]orders.GeoPoint) *Fixture {
+	f := &Fixture{points: make(map[string]orders.GeoPoint, len(points))}
+	for query, p := range points {
+		f.points[normalize(query)] = p
+	}
+	return f
+}
+
+// LoadFixture читает список адресов из YAML-файла вида
+//
+//	"Москва, ул. Тверская, 1": {lat: 55.7577, lon: 37.6137}
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("geocoding: read fixture: %w", err)
+	}
+
+	var points map[string]orders.GeoPoint
+	if err := yaml.Unmarshal(data, &points); err != nil {
+		return nil, fmt.Errorf("geocoding: parse fixture %s: %w", path, err)
+	}
+	return NewFixture(points), nil
+}
+
+func (f *Fixture) Geocode(ctx context.Context, address orders.Address) (*orders.GeoPoint, error) {
+	p, ok := f.points[normalize(address.Query())]
+	if !ok {
+		return nil, nil
+	}
+	return &p, nil
+}
@@ -0,0 +1,34 @@
+// Package geocoding определяет координаты адресов заявок: по заранее известному списку
+// (для тестов и работы без сети) или через HTTP API, совместимый с Nominatim
+package geocoding
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/Owouwun/spkuznetsov/internal/config"
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+)
+
+// New создаёт геокодер, выбранный в конфигурации; для provider: none возвращает nil
+func New(cfg config.GeocodingConfig) (orders.Geocoder, error) {
+	switch cfg.Provider {
+	case "none":
+		return nil, nil
+	case "fixture":
+		return LoadFixture(cfg.FixtureFile)
+	case "http":
+		return NewHTTP(cfg.URL, string(cfg.APIKey), cfg.Timeout), nil
+	default:
+		return nil, fmt.Errorf("geocoding: unknown provider %q", cfg.Provider)
+	}
+}
+
+// Ключ сравнения адресов: нижний регистр, «ё» как «е», знаки препинания как пробелы
+func normalize(s string) string {
+	s = strings.ReplaceAll(strings.ToLower(s), "ё", "е")
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
@@ -0,0 +1,127 @@
+package geocoding_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/Owouwun/spkuznetsov/internal/geocoding"
+)
+
+var tverskaya = orders.GeoPoint{Lat: 55.7577, Lon: 37.6137}
+
+func TestFixture(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "addresses.yaml")
+	fixture := `"Москва, ул. Тверская, 1": {lat: 55.7577, lon: 37.6137}` + "\n"
+	if err := os.WriteFile(path, []byte(fixture), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := geocoding.LoadFixture(path)
+	if err != nil {
+		t.Fatalf("load fixture: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		address orders.Address
+		exp     *orders.GeoPoint
+	}{
+		{
+			name:    "Структурированный адрес",
+			address: orders.Address{City: "Москва", Street: "ул. Тверская", House: "1", Apartment: "15"},
+			exp:     &tverskaya,
+		},
+		{
+			name:    "Адрес одной строкой без учёта регистра и знаков препинания",
+			address: orders.Address{Line: "москва ул тверская 1"},
+			exp:     &tverskaya,
+		},
+		{
+			name:    "Неизвестный адрес",
+			address: orders.Address{City: "Москва", Street: "ул. Тверская", House: "2"},
+			exp:     nil,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p, err := g.Geocode(context.Background(), c.address)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(c.exp, p) {
+				t.Errorf("expected %v, got %v", c.exp, p)
+			}
+		})
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/search" || q.Get("format") != "jsonv2" || q.Get("key") != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch {
+		case q.Get("street") == "1 ул. Тверская" && q.Get("city") == "Москва":
+			_, _ = w.Write([]byte(`[{"lat":"55.7577","lon":"37.6137","display_name":"Тверская улица, 1"}]`))
+		case q.Get("q") == "Москва, Тверская, 1":
+			_, _ = w.Write([]byte(`[{"lat":"55.7577","lon":"37.6137"}]`))
+		case q.Get("q") == "сломать":
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			_, _ = w.Write([]byte(`[]`))
+		}
+	}))
+	defer server.Close()
+
+	g := geocoding.NewHTTP(server.URL+"/", "secret", time.Second)
+
+	cases := []struct {
+		name    string
+		address orders.Address
+		exp     *orders.GeoPoint
+		expErr  bool
+	}{
+		{
+			name:    "Структурированный адрес",
+			address: orders.Address{City: "Москва", Street: "ул. Тверская", House: "1"},
+			exp:     &tverskaya,
+		},
+		{
+			name:    "Адрес одной строкой",
+			address: orders.Address{Line: "Москва, Тверская, 1"},
+			exp:     &tverskaya,
+		},
+		{
+			name:    "Адрес не найден",
+			address: orders.Address{City: "Москва", Street: "ул. Тверская", House: "999"},
+			exp:     nil,
+		},
+		{
+			name:    "Ошибка провайдера",
+			address: orders.Address{Line: "сломать"},
+			expErr:  true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p, err := g.Geocode(context.Background(), c.address)
+			if (err != nil) != c.expErr {
+				t.Fatalf("expected error: %v, got %v", c.expErr, err)
+			}
+			if !reflect.DeepEqual(c.exp, p) {
+				t.Errorf("expected %v, got %v", c.exp, p)
+			}
+		})
+	}
+}
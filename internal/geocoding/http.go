@@ -0,0 +1,97 @@
+package geocoding
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/Owouwun/spkuznetsov/internal/tracing"
+)
+
+const userAgent = "spkuznetsov"
+
+// HTTP — геокодер, обращающийся к API, совместимому с Nominatim (GET /search)
+type HTTP struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+// NewHTTP создаёт геокодер для API по адресу baseURL. Непустой apiKey передаётся параметром key
+func NewHTTP(baseURL, apiKey string, timeout time.Duration) *HTTP {
+	return &HTTP{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+		client:  tracing.NewHTTPClient(&http.Client{Timeout: timeout}),
+	}
+}
+
+type searchResult struct {
+	Lat string `json:"lat"`
+	Lon string `json:"lon"`
+}
+
+func (g *HTTP) Geocode(ctx context.Context, address orders.Address) (*orders.GeoPoint, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.baseURL+"/search?"+g.query(address).Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("geocoding: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("geocoding: unexpected status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var results []searchResult
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("geocoding: decode response: %w", err)
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	lat, err := strconv.ParseFloat(results[0].Lat, 64)
+	if err != nil {
+		return nil, fmt.Errorf("geocoding: invalid latitude %q", results[0].Lat)
+	}
+	lon, err := strconv.ParseFloat(results[0].Lon, 64)
+	if err != nil {
+		return nil, fmt.Errorf("geocoding: invalid longitude %q", results[0].Lon)
+	}
+	return &orders.GeoPoint{Lat: lat, Lon: lon}, nil
+}
+
+// Структурированный адрес ищется по полям city и street, адрес одной строкой — по q
+func (g *HTTP) query(address orders.Address) url.Values {
+	q := url.Values{}
+	q.Set("format", "jsonv2")
+	q.Set("limit", "1")
+	if g.apiKey != "" {
+		q.Set("key", g.apiKey)
+	}
+
+	if address.Street == "" {
+		q.Set("q", address.Line)
+		return q
+	}
+	q.Set("street", strings.TrimSpace(address.House+" "+address.Street))
+	if address.City != "" {
+		q.Set("city", address.City)
+	}
+	return q
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
+)
+
+const startTimeKey = "metrics:start_time"
+
+type registerFunc func(name string, fn func(*gorm.DB)) error
+
+// RegisterDB подключает к gorm учёт длительности и ошибок запросов,
+// а в реестр — статистику пула соединений
+func (m *Metrics) RegisterDB(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []struct {
+		operation     string
+		before, after registerFunc
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+	for _, h := range hooks {
+		if err := h.before("metrics:before_"+h.operation, startQueryTimer); err != nil {
+			return fmt.Errorf("register gorm callback: %w", err)
+		}
+		if err := h.after("metrics:after_"+h.operation, m.observeQuery(h.operation)); err != nil {
+			return fmt.Errorf("register gorm callback: %w", err)
+		}
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return m.registry.Register(collectors.NewDBStatsCollector(sqlDB, "postgres"))
+}
+
+func startQueryTimer(db *gorm.DB) {
+	db.InstanceSet(startTimeKey, time.Now())
+}
+
+func (m *Metrics) observeQuery(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(startTimeKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+		m.dbQueryDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+
+		// Отсутствие записи — штатный результат, а не ошибка БД
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			m.dbQueryErrors.WithLabelValues(operation, table).Inc()
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "spkuznetsov"
+
+// Metrics — набор метрик сервиса в собственном реестре (без глобального состояния)
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	dbQueryDuration *prometheus.HistogramVec
+	dbQueryErrors   *prometheus.CounterVec
+
+	ordersCreated       prometheus.Counter
+	orderTransitions    *prometheus.CounterVec
+	orderCancellations  *prometheus.CounterVec
+	orderStatusDuration *prometheus.HistogramVec
+	cancelReasons       *labelLimiter
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "HTTP requests by route template, method and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency by route template and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+
+		dbQueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "query_duration_seconds",
+			Help:      "Database query latency by operation and table.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation", "table"}),
+		dbQueryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "query_errors_total",
+			Help:      "Failed database queries by operation and table.",
+		}, []string{"operation", "table"}),
+
+		ordersCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "orders",
+			Name:      "created_total",
+			Help:      "Created orders.",
+		}),
+		orderTransitions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "orders",
+			Name:      "transitions_total",
+			Help:      "Order status transitions by action and resulting status.",
+		}, []string{"action", "from", "to"}),
+		orderCancellations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "orders",
+			Name:      "cancellations_total",
+			Help:      "Canceled orders by normalized cancel reason.",
+		}, []string{"reason"}),
+		orderStatusDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "orders",
+			Name:      "status_duration_seconds",
+			Help:      "Time an order spent in a status before leaving it.",
+			// От минуты до месяца
+			Buckets: prometheus.ExponentialBucketsRange(60, 30*24*3600, 12),
+		}, []string{"status"}),
+		cancelReasons: newLabelLimiter(maxCancelReasons),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.dbQueryDuration,
+		m.dbQueryErrors,
+		m.ordersCreated,
+		m.orderTransitions,
+		m.orderCancellations,
+		m.orderStatusDuration,
+	)
+	return m
+}
+
+// Registry нужен для регистрации дополнительных коллекторов
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler отдаёт метрики в формате Prometheus
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// ObserveHTTPRequest учитывает обработанный HTTP-запрос.
+// route — шаблон маршрута (/api/v1/orders/:id), а не фактический путь
+func (m *Metrics) ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	if route == "" {
+		route = "unmatched"
+	}
+	m.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.httpDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
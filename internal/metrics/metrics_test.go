@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLabelLimiter(t *testing.T) {
+	l := newLabelLimiter(2)
+
+	cases := []struct {
+		name   string
+		reason string
+		want   string
+	}{
+		{
+			name:   "Пустая причина",
+			reason: "  ",
+			want:   emptyCancelReason,
+		},
+		{
+			name:   "Нормализация регистра и пробелов",
+			reason: "  Клиент   ОТКАЗАЛСЯ ",
+			want:   "клиент отказался",
+		},
+		{
+			name:   "Повтор известной причины",
+			reason: "клиент отказался",
+			want:   "клиент отказался",
+		},
+		{
+			name:   "Вторая причина в пределах лимита",
+			reason: "Дубль",
+			want:   "дубль",
+		},
+		{
+			name:   "Превышение лимита",
+			reason: "Нет запчастей",
+			want:   otherCancelReason,
+		},
+		{
+			name:   "Длинная причина обрезается",
+			reason: strings.Repeat("а", maxCancelReasonLen*2),
+			want:   otherCancelReason,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := l.label(tc.reason); got != tc.want {
+				t.Errorf("expected label %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestOrderMetrics(t *testing.T) {
+	m := New()
+
+	m.OrderCreated()
+	m.OrderTransitioned(orders.EventAssigned, orders.StatusNew, orders.StatusAssigned, time.Hour)
+	m.OrderTransitioned(orders.EventCanceled, orders.StatusAssigned, orders.StatusCanceled, 0)
+	m.OrderCanceled("Дубль")
+
+	if got := testutil.ToFloat64(m.ordersCreated); got != 1 {
+		t.Errorf("expected 1 created order, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.orderTransitions.WithLabelValues("assigned", "New", "Assigned")); got != 1 {
+		t.Errorf("expected 1 assign transition, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.orderCancellations.WithLabelValues("дубль")); got != 1 {
+		t.Errorf("expected 1 cancellation, got %v", got)
+	}
+	// Время в статусе без известного момента входа не учитывается
+	if got := testutil.CollectAndCount(m.orderStatusDuration); got != 1 {
+		t.Errorf("expected status duration only for New, got %d series", got)
+	}
+}
+
+func TestOrderStatusGauge(t *testing.T) {
+	cases := []struct {
+		name      string
+		counts    map[orders.Status]int64
+		err       error
+		expSeries int
+	}{
+		{
+			name:      "Все статусы, включая пустые",
+			counts:    map[orders.Status]int64{orders.StatusNew: 3, orders.StatusDone: 1},
+			expSeries: len(orders.Statuses),
+		},
+		{
+			name:      "Ошибка подсчёта",
+			err:       errors.New("db is down"),
+			expSeries: 0,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := New()
+			err := m.RegisterOrderStatusGauge(func(ctx context.Context) (map[orders.Status]int64, error) {
+				return tc.counts, tc.err
+			})
+			if err != nil {
+				t.Fatalf("register: %v", err)
+			}
+
+			got, err := testutil.GatherAndCount(m.Registry(), "spkuznetsov_orders_by_status")
+			if tc.err != nil {
+				if err == nil {
+					t.Error("expected gather error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("gather: %v", err)
+			}
+			if got != tc.expSeries {
+				t.Errorf("expected %d series, got %d", tc.expSeries, got)
+			}
+		})
+	}
+}
+
+func TestObserveHTTPRequest(t *testing.T) {
+	m := New()
+
+	m.ObserveHTTPRequest("GET", "/api/v1/orders/:id", 200, 10*time.Millisecond)
+	m.ObserveHTTPRequest("GET", "/api/v1/orders/:id", 200, 20*time.Millisecond)
+	m.ObserveHTTPRequest("GET", "", 404, time.Millisecond)
+
+	if got := testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "/api/v1/orders/:id", "200")); got != 2 {
+		t.Errorf("expected 2 requests, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "unmatched", "404")); got != 1 {
+		t.Errorf("expected 1 unmatched request, got %v", got)
+	}
+}
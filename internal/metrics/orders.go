@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/internal/core/logic/orders"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// Причина отмены — свободный текст; число различных значений метки ограничено
+	maxCancelReasons   = 20
+	maxCancelReasonLen = 64
+	otherCancelReason  = "other"
+	emptyCancelReason  = "unspecified"
+	statusCountTimeout = 5 * time.Second
+)
+
+var _ orders.MetricsRecorder = (*Metrics)(nil)
+
+func (m *Metrics) OrderCreated() {
+	m.ordersCreated.Inc()
+}
+
+func (m *Metrics) OrderTransitioned(action orders.EventType, from, to orders.Status, timeInStatus time.Duration) {
+	m.orderTransitions.WithLabelValues(string(action), from.ToString(), to.ToString()).Inc()
+	if timeInStatus > 0 {
+		m.orderStatusDuration.WithLabelValues(from.ToString()).Observe(timeInStatus.Seconds())
+	}
+}
+
+func (m *Metrics) OrderCanceled(reason string) {
+	m.orderCancellations.WithLabelValues(m.cancelReasons.label(reason)).Inc()
+}
+
+// labelLimiter нормализует свободный текст в значение метки
+// и после limit различных значений сводит новые к otherCancelReason
+type labelLimiter struct {
+	mu    sync.Mutex
+	limit int
+	seen  map[string]struct{}
+}
+
+func newLabelLimiter(limit int) *labelLimiter {
+	return &labelLimiter{
+		limit: limit,
+		seen:  make(map[string]struct{}),
+	}
+}
+
+func (l *labelLimiter) label(raw string) string {
+	value := strings.ToLower(strings.Join(strings.Fields(raw), " "))
+	if value == "" {
+		return emptyCancelReason
+	}
+	if r := []rune(value); len(r) > maxCancelReasonLen {
+		value = string(r[:maxCancelReasonLen])
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.seen[value]; ok {
+		return value
+	}
+	if len(l.seen) >= l.limit {
+		return otherCancelReason
+	}
+	l.seen[value] = struct{}{}
+	return value
+}
+
+// StatusCounter возвращает текущее количество заявок в каждом статусе
+type StatusCounter func(ctx context.Context) (map[orders.Status]int64, error)
+
+// RegisterOrderStatusGauge добавляет метрику числа заявок по статусам.
+// Значения запрашиваются у сервиса заявок в момент сбора метрик
+func (m *Metrics) RegisterOrderStatusGauge(count StatusCounter) error {
+	return m.registry.Register(&orderStatusCollector{
+		count: count,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "orders", "by_status"),
+			"Current number of orders in each status.",
+			[]string{"status"}, nil,
+		),
+	})
+}
+
+type orderStatusCollector struct {
+	count StatusCounter
+	desc  *prometheus.Desc
+}
+
+func (c *orderStatusCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *orderStatusCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), statusCountTimeout)
+	defer cancel()
+
+	counts, err := c.count(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to count orders by status", slog.Any("error", err))
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+
+	for _, status := range orders.Statuses {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(counts[status]), status.ToString())
+	}
+}
@@ -0,0 +1,53 @@
+package pii
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Файл ключей в формате, похожем на выгрузку из KMS:
+//
+//	primary_key: "2025-01"
+//	keys:
+//	  "2024-01": <base64, 32 байта>
+//	  "2025-01": <base64, 32 байта>
+//	blind_index_key: <base64, не меньше 32 байт>
+//
+// Для ротации в keys добавляется новый ключ и становится primary_key; старый ключ удаляется
+// из файла только после команды rotate-keys
+type keyFile struct {
+	PrimaryKey    string            `yaml:"primary_key"`
+	Keys          map[string]string `yaml:"keys"`
+	BlindIndexKey string            `yaml:"blind_index_key"`
+}
+
+// LoadKeyring читает набор ключей из файла
+func LoadKeyring(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("pii: read key file: %w", err)
+	}
+
+	var kf keyFile
+	if err := yaml.Unmarshal(data, &kf); err != nil {
+		return nil, fmt.Errorf("pii: parse key file %s: %w", path, err)
+	}
+
+	keys := make(map[string][]byte, len(kf.Keys))
+	for id, encoded := range kf.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("pii: key %q: %w", id, err)
+		}
+		keys[id] = key
+	}
+	indexKey, err := base64.StdEncoding.DecodeString(kf.BlindIndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("pii: blind index key: %w", err)
+	}
+
+	return NewKeyring(kf.PrimaryKey, keys, indexKey)
+}
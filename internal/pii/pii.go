@@ -0,0 +1,195 @@
+// Package pii шифрует персональные данные клиентов перед записью в БД.
+//
+// Используется конвертное шифрование: каждое значение шифруется собственным случайным
+// ключом данных (AES-256-GCM), а ключ данных — ключом шифрования ключей (KEK) из Keyring.
+// Идентификатор KEK хранится рядом с шифротекстом, поэтому после смены основного ключа
+// старые значения расшифровываются прежними ключами до перешифрования.
+package pii
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// Формат значения: enc:v1:<kek id>:<зашифрованный ключ данных>:<шифротекст>
+	prefix  = "enc:v1:"
+	keySize = 32
+)
+
+var (
+	ErrUnknownKey     = errors.New("pii: value is encrypted with an unknown key")
+	ErrMalformedValue = errors.New("pii: malformed encrypted value")
+)
+
+var b64 = base64.RawStdEncoding
+
+// Keyring хранит ключи шифрования ключей и ключ слепого индекса
+type Keyring struct {
+	primary  string
+	keks     map[string]cipher.AEAD
+	indexKey []byte
+}
+
+// NewKeyring создаёт набор ключей. Новые значения шифруются ключом primary,
+// остальные ключи используются только для расшифровки
+func NewKeyring(primary string, keys map[string][]byte, indexKey []byte) (*Keyring, error) {
+	if len(indexKey) < keySize {
+		return nil, fmt.Errorf("pii: blind index key must be at least %d bytes", keySize)
+	}
+
+	k := &Keyring{
+		primary:  primary,
+		keks:     make(map[string]cipher.AEAD, len(keys)),
+		indexKey: indexKey,
+	}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("pii: invalid key id %q", id)
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		k.keks[id] = aead
+	}
+	if _, ok := k.keks[primary]; !ok {
+		return nil, fmt.Errorf("pii: primary key %q is not in the keyring", primary)
+	}
+
+	return k, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != keySize {
+		return nil, fmt.Errorf("pii: key must be %d bytes, got %d", keySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt шифрует значение основным ключом. Пустая строка не шифруется
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+
+	dek := make([]byte, keySize)
+	if _, err := rand.Read(dek); err != nil {
+		return "", err
+	}
+	data, err := newAEAD(dek)
+	if err != nil {
+		return "", err
+	}
+
+	wrappedDEK := seal(k.keks[k.primary], dek, []byte(k.primary))
+	ciphertext := seal(data, []byte(plaintext), nil)
+
+	return prefix + k.primary + ":" + b64.EncodeToString(wrappedDEK) + ":" + b64.EncodeToString(ciphertext), nil
+}
+
+// Decrypt расшифровывает значение любым известным ключом.
+// Значения без префикса считаются записанными до включения шифрования и возвращаются как есть
+func (k *Keyring) Decrypt(value string) (string, error) {
+	rest, ok := strings.CutPrefix(value, prefix)
+	if !ok {
+		return value, nil
+	}
+
+	parts := strings.Split(rest, ":")
+	if len(parts) != 3 {
+		return "", ErrMalformedValue
+	}
+	kek, ok := k.keks[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownKey, parts[0])
+	}
+	wrappedDEK, err := b64.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrMalformedValue
+	}
+	ciphertext, err := b64.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrMalformedValue
+	}
+
+	dek, err := open(kek, wrappedDEK, []byte(parts[0]))
+	if err != nil {
+		return "", err
+	}
+	data, err := newAEAD(dek)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(data, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation сообщает, что значение не зашифровано или зашифровано не основным ключом
+func (k *Keyring) NeedsRotation(value string) bool {
+	if value == "" {
+		return false
+	}
+	return !strings.HasPrefix(value, prefix+k.primary+":")
+}
+
+// BlindIndex возвращает HMAC-SHA256 значения: по нему ищут точное совпадение,
+// не расшифровывая данные. Ключ индекса не ротируется вместе с KEK
+func (k *Keyring) BlindIndex(value string) string {
+	mac := hmac.New(sha256.New, k.indexKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Шифротекст вместе с одноразовым кодом (nonce) в начале
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) []byte {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		panic(err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData)
+}
+
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrMalformedValue
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("pii: decrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+// Plaintext используется, когда ключи не настроены: данные хранятся открыто,
+// а слепым индексом служит само значение
+type Plaintext struct{}
+
+func (Plaintext) Encrypt(plaintext string) (string, error) { return plaintext, nil }
+
+func (Plaintext) Decrypt(value string) (string, error) {
+	if strings.HasPrefix(value, prefix) {
+		return "", fmt.Errorf("%w: encryption keys are not configured", ErrUnknownKey)
+	}
+	return value, nil
+}
+
+func (Plaintext) NeedsRotation(string) bool { return false }
+
+func (Plaintext) BlindIndex(value string) string { return value }
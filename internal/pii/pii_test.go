@@ -0,0 +1,196 @@
+package pii_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Owouwun/spkuznetsov/internal/pii"
+)
+
+var (
+	oldKey   = bytes.Repeat([]byte{1}, 32)
+	newKey   = bytes.Repeat([]byte{2}, 32)
+	indexKey = bytes.Repeat([]byte{3}, 32)
+)
+
+func mustKeyring(t *testing.T, primary string, keys map[string][]byte) *pii.Keyring {
+	t.Helper()
+	k, err := pii.NewKeyring(primary, keys, indexKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestKeyring_EncryptDecrypt(t *testing.T) {
+	k := mustKeyring(t, "k1", map[string][]byte{"k1": oldKey})
+
+	cases := []struct {
+		name      string
+		plaintext string
+	}{
+		{name: "Телефон", plaintext: "+71112223344"},
+		{name: "Кириллица", plaintext: "ул. Примерная, д. 1"},
+		{name: "Пустое значение не шифруется", plaintext: ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			enc, err := k.Encrypt(c.plaintext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.plaintext != "" && strings.Contains(enc, c.plaintext) {
+				t.Errorf("ciphertext contains plaintext: %s", enc)
+			}
+
+			dec, err := k.Decrypt(enc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dec != c.plaintext {
+				t.Errorf("expected %q, got %q", c.plaintext, dec)
+			}
+		})
+	}
+
+	a, _ := k.Encrypt("same")
+	b, _ := k.Encrypt("same")
+	if a == b {
+		t.Errorf("equal plaintexts must produce different ciphertexts")
+	}
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	before := mustKeyring(t, "k1", map[string][]byte{"k1": oldKey})
+	after := mustKeyring(t, "k2", map[string][]byte{"k1": oldKey, "k2": newKey})
+	withoutOld := mustKeyring(t, "k2", map[string][]byte{"k2": newKey})
+
+	enc, err := before.Encrypt("Client Name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !after.NeedsRotation(enc) {
+		t.Errorf("value under the old key must need rotation")
+	}
+	dec, err := after.Decrypt(enc)
+	if err != nil || dec != "Client Name" {
+		t.Fatalf("old value must be readable after rotation, got %q, %v", dec, err)
+	}
+
+	reenc, err := after.Encrypt(dec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.NeedsRotation(reenc) {
+		t.Errorf("value under the primary key must not need rotation")
+	}
+
+	if _, err := withoutOld.Decrypt(enc); !errors.Is(err, pii.ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestKeyring_Decrypt(t *testing.T) {
+	k := mustKeyring(t, "k1", map[string][]byte{"k1": oldKey})
+	enc, err := k.Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Символ в середине шифротекста, а не в хвосте base64, где часть битов не используется
+	tampered := []byte(enc)
+	i := len(tampered) - 10
+	if tampered[i] == 'A' {
+		tampered[i] = 'B'
+	} else {
+		tampered[i] = 'A'
+	}
+
+	cases := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{name: "Значение, записанное до включения шифрования", value: "+71112223344", want: "+71112223344"},
+		{name: "Изменённый шифротекст", value: string(tampered), wantErr: true},
+		{name: "Неполное значение", value: "enc:v1:k1:abc", wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := k.Decrypt(c.value)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != c.want {
+				t.Errorf("expected %q, got %q", c.want, got)
+			}
+		})
+	}
+}
+
+func TestKeyring_BlindIndex(t *testing.T) {
+	k1 := mustKeyring(t, "k1", map[string][]byte{"k1": oldKey})
+	k2 := mustKeyring(t, "k2", map[string][]byte{"k2": newKey})
+
+	if k1.BlindIndex("+71112223344") != k2.BlindIndex("+71112223344") {
+		t.Errorf("blind index must not depend on the encryption key")
+	}
+	if k1.BlindIndex("+71112223344") == k1.BlindIndex("+79998887766") {
+		t.Errorf("different values must have different indexes")
+	}
+	if strings.Contains(k1.BlindIndex("+71112223344"), "1112223344") {
+		t.Errorf("blind index leaks the value")
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	enc := base64.StdEncoding.EncodeToString
+	cases := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name: "Корректный файл",
+			content: "primary_key: k2\nkeys:\n  k1: " + enc(oldKey) + "\n  k2: " + enc(newKey) +
+				"\nblind_index_key: " + enc(indexKey) + "\n",
+		},
+		{
+			name:    "Основного ключа нет в списке",
+			content: "primary_key: k3\nkeys:\n  k1: " + enc(oldKey) + "\nblind_index_key: " + enc(indexKey) + "\n",
+			wantErr: true,
+		},
+		{
+			name:    "Ключ неверной длины",
+			content: "primary_key: k1\nkeys:\n  k1: " + enc([]byte("short")) + "\nblind_index_key: " + enc(indexKey) + "\n",
+			wantErr: true,
+		},
+		{
+			name:    "Без ключа слепого индекса",
+			content: "primary_key: k1\nkeys:\n  k1: " + enc(oldKey) + "\n",
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "keys.yaml")
+			if err := os.WriteFile(path, []byte(c.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := pii.LoadKeyring(path)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -12,13 +12,15 @@ import (
 const (
 	ClientName          = "Client Name"             // Default value
 	ClientPhone         = "+71112223344"            // Default value
-	Address             = "Test Address"            // Default value
 	ClientDescription   = "Test Client Description" // Default value
 	EmployeeDescription = "Base Emp Description"    // Default value
 	EmptyCancelReason   = ""                        // Default value
 	FilledCancelReason  = "Клиент передумал"
 )
 
+// Default value
+var Address = orders.Address{City: "Москва", Street: "Тестовая улица", House: "1", Apartment: "2"}
+
 func GetNDaysLater(n int) time.Time {
 	return time.Now().Add(time.Duration(24*n) * time.Hour)
 }
@@ -37,7 +39,7 @@ func WithClientPhone(cp string) OrderOption {
 	}
 }
 
-func WithAddress(a string) OrderOption {
+func WithAddress(a orders.Address) OrderOption {
 	return func(r *orders.Order) {
 		r.Address = a
 	}
@@ -84,7 +86,7 @@ func NewTestOrder(opts ...OrderOption) *orders.Order {
 	req := &orders.Order{
 		ClientName:        "Иван Иванов",
 		ClientPhone:       "+71112223344",
-		Address:           "ул. Примерная, д. 1",
+		Address:           orders.Address{City: "Москва", Street: "ул. Примерная", House: "1"},
 		ClientDescription: "Обычный клиент",
 		Employee:          &auth.Employee{ID: 1, Name: "Петр Петров"},
 		Status:            orders.StatusScheduled,
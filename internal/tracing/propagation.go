@@ -0,0 +1,50 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Transport добавляет в исходящие HTTP-запросы (вебхуки, шлюзы уведомлений)
+// заголовки W3C Trace Context из контекста запроса
+type Transport struct {
+	Base http.RoundTripper
+}
+
+func NewTransport(base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{Base: base}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTripper не должен изменять исходный запрос
+	req = req.Clone(req.Context())
+	otel.GetTextMapPropagator().Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+	return t.Base.RoundTrip(req)
+}
+
+// NewHTTPClient возвращает клиент для исходящих вызовов с передачей контекста трассировки
+func NewHTTPClient(base *http.Client) *http.Client {
+	client := &http.Client{}
+	if base != nil {
+		*client = *base
+	}
+	client.Transport = NewTransport(client.Transport)
+	return client
+}
+
+// Inject записывает контекст трассировки в произвольный набор заголовков
+// (например, в метаданные письма или сообщения очереди)
+func Inject(ctx context.Context, headers map[string]string) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(headers))
+}
+
+// Extract восстанавливает контекст трассировки из набора заголовков
+func Extract(ctx context.Context, headers map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(headers))
+}
@@ -0,0 +1,107 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/Owouwun/spkuznetsov/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// Имя инструментирующей библиотеки для спанов, создаваемых сервисом
+const InstrumentationName = "github.com/Owouwun/spkuznetsov"
+
+// Tracer возвращает трассировщик из глобального провайдера.
+// До вызова Setup (и при exporter: none) спаны не записываются
+func Tracer() trace.Tracer {
+	return otel.Tracer(InstrumentationName)
+}
+
+// Provider — настроенный провайдер трассировки
+type Provider struct {
+	provider trace.TracerProvider
+	sdk      *sdktrace.TracerProvider
+	memory   *tracetest.InMemoryExporter
+}
+
+// Setup создаёт экспортёр по конфигурации, устанавливает глобальный провайдер
+// и пропагатор W3C Trace Context (traceparent/tracestate) вместе с Baggage
+func Setup(ctx context.Context, cfg config.TracingConfig) (*Provider, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	p := &Provider{}
+	var opts []sdktrace.TracerProviderOption
+
+	switch cfg.Exporter {
+	case "none", "":
+		p.provider = noop.NewTracerProvider()
+		otel.SetTracerProvider(p.provider)
+		return p, nil
+	case "stdout":
+		exp, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, fmt.Errorf("stdout trace exporter: %w", err)
+		}
+		opts = append(opts, sdktrace.WithBatcher(exp))
+	case "otlp":
+		var expOpts []otlptracehttp.Option
+		if cfg.OTLPEndpoint != "" {
+			expOpts = append(expOpts, otlptracehttp.WithEndpointURL(cfg.OTLPEndpoint))
+		}
+		exp, err := otlptracehttp.New(ctx, expOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("otlp trace exporter: %w", err)
+		}
+		opts = append(opts, sdktrace.WithBatcher(exp))
+	case "memory":
+		// Синхронная запись: спаны доступны сразу после завершения, без ожидания батча
+		p.memory = tracetest.NewInMemoryExporter()
+		opts = append(opts, sdktrace.WithSyncer(p.memory))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+
+	opts = append(opts,
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(cfg.ServiceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	p.sdk = sdktrace.NewTracerProvider(opts...)
+	p.provider = p.sdk
+	otel.SetTracerProvider(p.provider)
+
+	slog.Info("tracing enabled", slog.String("exporter", cfg.Exporter), slog.Float64("sample_ratio", cfg.SampleRatio))
+	return p, nil
+}
+
+// Spans возвращает спаны, записанные экспортёром memory (для тестов)
+func (p *Provider) Spans() tracetest.SpanStubs {
+	if p.memory == nil {
+		return nil
+	}
+	return p.memory.GetSpans()
+}
+
+// Shutdown выгружает накопленные спаны и останавливает экспортёр
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if p.sdk == nil {
+		return nil
+	}
+	if err := p.sdk.Shutdown(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return fmt.Errorf("shutdown tracer provider: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Owouwun/spkuznetsov/internal/config"
+	"github.com/Owouwun/spkuznetsov/internal/core/api/middleware"
+	"github.com/Owouwun/spkuznetsov/internal/tracing"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func setupMemory(t *testing.T) *tracing.Provider {
+	t.Helper()
+
+	cfg := config.Default().Tracing
+	cfg.Exporter = "memory"
+	provider, err := tracing.Setup(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("setup tracing: %v", err)
+	}
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	return provider
+}
+
+func TestTracing_Middleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const incomingTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+	cases := []struct {
+		name        string
+		traceparent string
+		path        string
+		expSpan     string
+		expParent   bool
+	}{
+		{
+			name:    "Новая трасса по шаблону маршрута",
+			path:    "/api/v1/orders/42",
+			expSpan: "GET /api/v1/orders/:id",
+		},
+		{
+			name:        "Продолжение трассы из traceparent",
+			traceparent: "00-" + incomingTraceID + "-00f067aa0ba902b7-01",
+			path:        "/api/v1/orders/42",
+			expSpan:     "GET /api/v1/orders/:id",
+			expParent:   true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			provider := setupMemory(t)
+
+			r := gin.New()
+			r.ContextWithFallback = true
+			r.Use(middleware.Tracing())
+			r.GET("/api/v1/orders/:id", func(c *gin.Context) {
+				_, span := tracing.Tracer().Start(c, "OrderService.GetByID")
+				span.End()
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.traceparent != "" {
+				req.Header.Set("traceparent", tc.traceparent)
+			}
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			spans := provider.Spans()
+			if len(spans) != 2 {
+				t.Fatalf("expected 2 spans, got %d", len(spans))
+			}
+			child, server := spans[0], spans[1]
+
+			if server.Name != tc.expSpan {
+				t.Errorf("expected span name %q, got %q", tc.expSpan, server.Name)
+			}
+			if server.SpanKind != trace.SpanKindServer {
+				t.Errorf("expected server span, got %v", server.SpanKind)
+			}
+			if child.Parent.SpanID() != server.SpanContext.SpanID() {
+				t.Error("service span is not a child of the request span")
+			}
+			if got := server.SpanContext.TraceID().String(); tc.expParent && got != incomingTraceID {
+				t.Errorf("expected trace %s to be continued, got %s", incomingTraceID, got)
+			}
+		})
+	}
+}
+
+func TestTracing_OutgoingPropagation(t *testing.T) {
+	setupMemory(t)
+
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("traceparent")
+	}))
+	defer srv.Close()
+
+	ctx, span := tracing.Tracer().Start(context.Background(), "notify")
+	defer span.End()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL, nil)
+	resp, err := tracing.NewHTTPClient(nil).Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+
+	if req.Header.Get("traceparent") != "" {
+		t.Error("original request must not be modified")
+	}
+	if traceID := span.SpanContext().TraceID().String(); !strings.Contains(got, traceID) {
+		t.Errorf("expected traceparent with trace %s, got %q", traceID, got)
+	}
+
+	headers := map[string]string{}
+	tracing.Inject(ctx, headers)
+	extracted := trace.SpanContextFromContext(tracing.Extract(context.Background(), headers))
+	if extracted.TraceID() != span.SpanContext().TraceID() {
+		t.Error("trace context lost in header map round trip")
+	}
+}
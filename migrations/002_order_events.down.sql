@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS trg_order_events_notify ON public.order_events;
+DROP FUNCTION IF EXISTS public.notify_order_event();
+DROP TABLE IF EXISTS public.order_events;
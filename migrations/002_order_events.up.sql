@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS public.order_events (
+    id BIGSERIAL PRIMARY KEY,
+    order_id UUID NOT NULL REFERENCES public.orders(id) ON DELETE CASCADE,
+    type TEXT NOT NULL,
+    status INTEGER NOT NULL,
+    employee_id BIGINT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_events_order_id ON public.order_events(order_id);
+
+CREATE OR REPLACE FUNCTION public.notify_order_event() RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('order_events', row_to_json(NEW)::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_order_events_notify
+    AFTER INSERT ON public.order_events
+    FOR EACH ROW EXECUTE FUNCTION public.notify_order_event();
@@ -0,0 +1 @@
+ALTER TABLE public.orders DROP COLUMN IF EXISTS status_changed_at;
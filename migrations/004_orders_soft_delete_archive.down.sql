@@ -0,0 +1,9 @@
+DELETE FROM public.order_events e
+WHERE NOT EXISTS (SELECT 1 FROM public.orders o WHERE o.id = e.order_id);
+ALTER TABLE public.order_events
+    ADD CONSTRAINT order_events_order_id_fkey FOREIGN KEY (order_id) REFERENCES public.orders(id) ON DELETE CASCADE;
+
+DROP TABLE IF EXISTS public.orders_archive;
+
+DROP INDEX IF EXISTS public.idx_orders_deleted_at;
+ALTER TABLE public.orders DROP COLUMN IF EXISTS deleted_at;
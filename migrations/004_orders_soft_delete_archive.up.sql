@@ -0,0 +1,13 @@
+ALTER TABLE public.orders ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX IF NOT EXISTS idx_orders_deleted_at ON public.orders(deleted_at);
+
+-- Архивная заявка хранится снимком строки orders в JSONB,
+-- поэтому архив не приходится менять вместе со схемой orders
+CREATE TABLE IF NOT EXISTS public.orders_archive (
+    id UUID PRIMARY KEY,
+    data JSONB NOT NULL,
+    archived_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- Журнал событий переживает перенос заявки в архив
+ALTER TABLE public.order_events DROP CONSTRAINT IF EXISTS order_events_order_id_fkey;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS public.erasure_audit;
+
+DROP INDEX IF EXISTS public.idx_orders_client_phone;
+ALTER TABLE public.orders DROP COLUMN IF EXISTS anonymized_at;
@@ -0,0 +1,11 @@
+ALTER TABLE public.orders ADD COLUMN IF NOT EXISTS anonymized_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX IF NOT EXISTS idx_orders_client_phone ON public.orders(client_phone);
+
+-- Журнал обезличивания. Персональные данные сюда не пишутся — только идентификаторы заявок
+CREATE TABLE IF NOT EXISTS public.erasure_audit (
+    id BIGSERIAL PRIMARY KEY,
+    reason TEXT NOT NULL,
+    order_ids JSONB NOT NULL DEFAULT '[]',
+    request_id TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS public.idx_orders_client_phone_hash;
+ALTER TABLE public.orders DROP COLUMN IF EXISTS client_phone_hash;
+
+CREATE INDEX IF NOT EXISTS idx_orders_client_phone ON public.orders(client_phone);
@@ -0,0 +1,8 @@
+-- Персональные данные шифруются приложением; телефон ищется по HMAC (слепому индексу).
+-- Ключа миграция не знает, поэтому client_phone_hash существующих строк остаётся пустым:
+-- индекс строит приложение после миграций (server migrate up или database.auto_migrate),
+-- а открытые данные шифрует команда rotate-keys
+ALTER TABLE public.orders ADD COLUMN IF NOT EXISTS client_phone_hash TEXT;
+
+DROP INDEX IF EXISTS public.idx_orders_client_phone;
+CREATE INDEX IF NOT EXISTS idx_orders_client_phone_hash ON public.orders(client_phone_hash);
@@ -0,0 +1,15 @@
+-- Вложения заявок (фото, акты). Содержимое файлов хранится во внешнем хранилище (blobstore),
+-- здесь — только метаданные. Внешнего ключа нет, как и у order_events: вложения остаются
+-- доступными после переноса заявки в архив
+CREATE TABLE IF NOT EXISTS public.order_attachments (
+    id UUID PRIMARY KEY,
+    order_id UUID NOT NULL,
+    kind TEXT NOT NULL,
+    file_name TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    has_thumbnail BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_attachments_order_id ON public.order_attachments(order_id, created_at);
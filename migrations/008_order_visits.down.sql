@@ -0,0 +1,2 @@
+-- Дата ближайших работ и последнее описание по-прежнему хранятся в orders, поэтому откат не теряет их
+DROP TABLE IF EXISTS public.order_visits;
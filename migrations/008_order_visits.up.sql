@@ -0,0 +1,29 @@
+-- Визиты сотрудников по заявке. Внешнего ключа на orders нет, как у order_events:
+-- история визитов сохраняется после переноса заявки в архив
+CREATE TABLE IF NOT EXISTS public.order_visits (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL,
+    employee_id BIGINT REFERENCES public.employees(id) ON DELETE SET NULL,
+    planned_for TIMESTAMP WITH TIME ZONE,
+    arrived_at TIMESTAMP WITH TIME ZONE,
+    departed_at TIMESTAMP WITH TIME ZONE,
+    notes TEXT NOT NULL DEFAULT '',
+    parts_used JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_visits_order_id ON public.order_visits(order_id, created_at);
+
+-- Перенос существующих данных. До появления визитов хранились только последнее описание работ
+-- и дата ближайших работ, поэтому на заявку создаётся не более двух визитов:
+-- завершённый (с описанием работ) и запланированный. Точное время отъезда неизвестно,
+-- вместо него берётся момент последней смены статуса
+INSERT INTO public.order_visits (order_id, employee_id, departed_at, notes, created_at)
+SELECT id, employee_id, COALESCE(status_changed_at, now()), COALESCE(employee_description, ''), COALESCE(status_changed_at, now()) - interval '1 second'
+FROM public.orders
+WHERE status IN (4, 5, 6) OR COALESCE(employee_description, '') <> '';
+
+INSERT INTO public.order_visits (order_id, employee_id, planned_for, created_at)
+SELECT id, employee_id, scheduled_for, COALESCE(status_changed_at, now())
+FROM public.orders
+WHERE status = 3;
@@ -0,0 +1,9 @@
+ALTER TABLE public.order_visits DROP COLUMN IF EXISTS far_from_address;
+ALTER TABLE public.order_visits DROP COLUMN IF EXISTS checkout_distance_m;
+ALTER TABLE public.order_visits DROP COLUMN IF EXISTS checkout_location;
+ALTER TABLE public.order_visits DROP COLUMN IF EXISTS checkin_distance_m;
+ALTER TABLE public.order_visits DROP COLUMN IF EXISTS checkin_location;
+
+ALTER TABLE public.orders DROP COLUMN IF EXISTS work_seconds;
+ALTER TABLE public.orders DROP COLUMN IF EXISTS longitude;
+ALTER TABLE public.orders DROP COLUMN IF EXISTS latitude;
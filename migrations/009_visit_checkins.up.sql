@@ -0,0 +1,11 @@
+-- Координаты адреса заявки (заполняются после геокодирования) и суммарное время работ на месте
+ALTER TABLE public.orders ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION;
+ALTER TABLE public.orders ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION;
+ALTER TABLE public.orders ADD COLUMN IF NOT EXISTS work_seconds BIGINT NOT NULL DEFAULT 0;
+
+-- Отметки сотрудника о прибытии и отъезде
+ALTER TABLE public.order_visits ADD COLUMN IF NOT EXISTS checkin_location JSONB;
+ALTER TABLE public.order_visits ADD COLUMN IF NOT EXISTS checkin_distance_m DOUBLE PRECISION;
+ALTER TABLE public.order_visits ADD COLUMN IF NOT EXISTS checkout_location JSONB;
+ALTER TABLE public.order_visits ADD COLUMN IF NOT EXISTS checkout_distance_m DOUBLE PRECISION;
+ALTER TABLE public.order_visits ADD COLUMN IF NOT EXISTS far_from_address BOOLEAN NOT NULL DEFAULT false;
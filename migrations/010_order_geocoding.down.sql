@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS public.idx_orders_geocoding_pending;
+ALTER TABLE public.orders DROP COLUMN IF EXISTS geocoded_at;
@@ -0,0 +1,8 @@
+-- Момент геокодирования адреса заявки (в т.ч. безуспешного). Заявки с NULL геокодируются
+-- фоновой задачей; обезличенные заявки и заявки с уже известными координатами не геокодируются
+ALTER TABLE public.orders ADD COLUMN IF NOT EXISTS geocoded_at TIMESTAMP WITH TIME ZONE;
+UPDATE public.orders SET geocoded_at = COALESCE(anonymized_at, now())
+WHERE geocoded_at IS NULL AND (anonymized_at IS NOT NULL OR latitude IS NOT NULL);
+
+CREATE INDEX IF NOT EXISTS idx_orders_geocoding_pending ON public.orders(status_changed_at)
+WHERE geocoded_at IS NULL AND deleted_at IS NULL;
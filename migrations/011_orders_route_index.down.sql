@@ -0,0 +1 @@
+DROP INDEX IF EXISTS public.idx_orders_employee_scheduled;
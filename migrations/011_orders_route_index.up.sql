@@ -0,0 +1,3 @@
+-- Маршрут сотрудника на день строится по его запланированным заявкам
+CREATE INDEX IF NOT EXISTS idx_orders_employee_scheduled ON public.orders(employee_id, scheduled_for)
+WHERE status = 3 AND deleted_at IS NULL;
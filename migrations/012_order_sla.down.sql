@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS public.idx_orders_sla;
+DROP TABLE IF EXISTS public.order_sla_breaches;
+ALTER TABLE public.orders DROP COLUMN IF EXISTS category;
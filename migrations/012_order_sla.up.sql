@@ -0,0 +1,19 @@
+-- Вид работ заявки: от него зависят сроки SLA
+ALTER TABLE public.orders ADD COLUMN IF NOT EXISTS category TEXT NOT NULL DEFAULT '';
+
+-- Нарушения SLA: заявка не покинула статус к сроку. Нарушение относится к одному пребыванию
+-- в статусе (status_changed_at), поэтому после смены статуса заявка перестаёт считаться просроченной.
+-- Внешнего ключа на orders нет, как у order_events: история сохраняется после архивации заявки
+CREATE TABLE IF NOT EXISTS public.order_sla_breaches (
+    id BIGSERIAL PRIMARY KEY,
+    order_id UUID NOT NULL,
+    status SMALLINT NOT NULL,
+    category TEXT NOT NULL DEFAULT '',
+    status_changed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    deadline TIMESTAMP WITH TIME ZONE NOT NULL,
+    detected_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    UNIQUE (order_id, status, status_changed_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_orders_sla ON public.orders(status, status_changed_at)
+WHERE deleted_at IS NULL;
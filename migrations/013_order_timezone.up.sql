@@ -0,0 +1,2 @@
+-- Часовой пояс клиента (IANA). Пустая строка — часовой пояс компании (calendar.timezone)
+ALTER TABLE public.orders ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT '';
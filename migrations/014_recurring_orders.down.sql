@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS public.idx_orders_template_id;
+ALTER TABLE public.orders DROP COLUMN IF EXISTS template_id;
+DROP TABLE IF EXISTS public.recurring_templates;
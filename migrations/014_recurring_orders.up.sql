@@ -0,0 +1,30 @@
+-- Шаблоны повторяющихся заявок (договоры на обслуживание). Данные клиента хранятся
+-- так же, как в orders: зашифрованными, со слепым индексом телефона
+CREATE TABLE IF NOT EXISTS public.recurring_templates (
+    id UUID PRIMARY KEY,
+    client_name TEXT NOT NULL,
+    client_phone TEXT NOT NULL,
+    client_phone_hash TEXT,
+    address TEXT NOT NULL,
+    latitude DOUBLE PRECISION,
+    longitude DOUBLE PRECISION,
+    client_description TEXT NOT NULL DEFAULT '',
+    category TEXT NOT NULL DEFAULT '',
+    timezone TEXT NOT NULL DEFAULT '',
+    rrule TEXT NOT NULL,
+    start_on DATE NOT NULL,
+    preschedule BOOLEAN NOT NULL DEFAULT false,
+    status TEXT NOT NULL,
+    next_on DATE,
+    occurrences INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_recurring_templates_due ON public.recurring_templates(next_on)
+WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_recurring_templates_client_phone_hash ON public.recurring_templates(client_phone_hash);
+
+-- Шаблон, по которому создана заявка. Внешнего ключа нет: шаблон удаляется при обезличивании
+-- клиента, а заявки остаются
+ALTER TABLE public.orders ADD COLUMN IF NOT EXISTS template_id UUID;
+CREATE INDEX IF NOT EXISTS idx_orders_template_id ON public.orders(template_id) WHERE template_id IS NOT NULL;
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS public.inventory_cursor;
+DROP TABLE IF EXISTS public.inventory_events;
+DROP TABLE IF EXISTS public.order_line_items;
+DROP TABLE IF EXISTS public.part_reservations;
+DROP TABLE IF EXISTS public.order_parts;
+DROP TABLE IF EXISTS public.stock_movements;
+DROP TABLE IF EXISTS public.stock_levels;
+DROP TABLE IF EXISTS public.parts;
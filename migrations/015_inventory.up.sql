@@ -0,0 +1,83 @@
+-- Каталог запчастей. Цена — за единицу, в копейках
+CREATE TABLE IF NOT EXISTS public.parts (
+    id UUID PRIMARY KEY,
+    sku TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    price BIGINT NOT NULL DEFAULT 0 CHECK (price >= 0),
+    low_stock_threshold INT NOT NULL DEFAULT 0 CHECK (low_stock_threshold >= 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- Остатки по местам хранения: складам ('warehouse:<код>') и машинам сотрудников ('van:<ID>').
+-- on_hand может стать отрицательным, если сотрудник списал больше, чем за ним числилось
+CREATE TABLE IF NOT EXISTS public.stock_levels (
+    part_id UUID NOT NULL REFERENCES public.parts(id),
+    location TEXT NOT NULL,
+    on_hand INT NOT NULL DEFAULT 0,
+    reserved INT NOT NULL DEFAULT 0 CHECK (reserved >= 0),
+    PRIMARY KEY (part_id, location)
+);
+
+-- Движения запчастей: поступления, перемещения, списания и возвраты по заявкам
+CREATE TABLE IF NOT EXISTS public.stock_movements (
+    id BIGSERIAL PRIMARY KEY,
+    part_id UUID NOT NULL REFERENCES public.parts(id),
+    location TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    quantity INT NOT NULL,
+    order_id UUID,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_movements_order_id ON public.stock_movements(order_id, kind)
+WHERE order_id IS NOT NULL;
+
+-- Запчасти, которые понадобятся для заявки, и их резерв. Внешнего ключа на orders нет,
+-- как у order_attachments: данные остаются доступными после переноса заявки в архив
+CREATE TABLE IF NOT EXISTS public.order_parts (
+    order_id UUID NOT NULL,
+    part_id UUID NOT NULL REFERENCES public.parts(id),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (order_id, part_id)
+);
+
+CREATE TABLE IF NOT EXISTS public.part_reservations (
+    order_id UUID NOT NULL,
+    part_id UUID NOT NULL REFERENCES public.parts(id),
+    location TEXT NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (order_id, part_id, location)
+);
+
+-- Позиции заявки: списанные запчасти по цене на момент списания
+CREATE TABLE IF NOT EXISTS public.order_line_items (
+    order_id UUID NOT NULL,
+    part_id UUID NOT NULL REFERENCES public.parts(id),
+    sku TEXT NOT NULL,
+    name TEXT NOT NULL,
+    quantity INT NOT NULL CHECK (quantity >= 0),
+    unit_price BIGINT NOT NULL,
+    PRIMARY KEY (order_id, part_id)
+);
+
+-- События склада (низкий остаток)
+CREATE TABLE IF NOT EXISTS public.inventory_events (
+    id BIGSERIAL PRIMARY KEY,
+    type TEXT NOT NULL,
+    part_id UUID NOT NULL REFERENCES public.parts(id),
+    sku TEXT NOT NULL,
+    available INT NOT NULL,
+    threshold INT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- Позиция журнала order_events, до которой склад обработал события заявок.
+-- Заявки, изменённые до появления склада, не обрабатываются
+CREATE TABLE IF NOT EXISTS public.inventory_cursor (
+    id BOOLEAN PRIMARY KEY DEFAULT true CHECK (id),
+    order_event_id BIGINT NOT NULL
+);
+
+INSERT INTO public.inventory_cursor (order_event_id)
+SELECT COALESCE(MAX(id), 0) FROM public.order_events
+ON CONFLICT DO NOTHING;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS public.idx_order_events_created_at;
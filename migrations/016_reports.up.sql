@@ -0,0 +1,2 @@
+-- Отчёты выбирают события заявок за период
+CREATE INDEX IF NOT EXISTS idx_order_events_created_at ON public.order_events(created_at);
@@ -0,0 +1,14 @@
+-- Ответы на изменяющие запросы с заголовком Idempotency-Key: повтор запроса с тем же ключом
+-- получает сохранённый ответ. Пока первый запрос выполняется, status_code = 0.
+-- fingerprint — хэш метода, адреса, токена и тела запроса: ключ нельзя использовать для другого запроса
+CREATE TABLE IF NOT EXISTS public.idempotency_keys (
+    key TEXT PRIMARY KEY,
+    fingerprint TEXT NOT NULL,
+    status_code SMALLINT NOT NULL DEFAULT 0,
+    content_type TEXT NOT NULL DEFAULT '',
+    body BYTEA,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON public.idempotency_keys(expires_at);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS public.idx_orders_geocoding_pending;
+ALTER TABLE public.orders DROP COLUMN IF EXISTS geocode_attempted_at;
+
+CREATE INDEX IF NOT EXISTS idx_orders_geocoding_pending ON public.orders(status_changed_at)
+WHERE geocoded_at IS NULL AND deleted_at IS NULL;
@@ -0,0 +1,7 @@
+-- Момент последней неудачной попытки геокодирования. Очередь геокодирования начинается
+-- с заявок без попыток, чтобы адреса, на которых геокодер ошибается, не задерживали остальные
+ALTER TABLE public.orders ADD COLUMN IF NOT EXISTS geocode_attempted_at TIMESTAMP WITH TIME ZONE;
+
+DROP INDEX IF EXISTS public.idx_orders_geocoding_pending;
+CREATE INDEX IF NOT EXISTS idx_orders_geocoding_pending ON public.orders(geocode_attempted_at NULLS FIRST, status_changed_at)
+WHERE geocoded_at IS NULL AND deleted_at IS NULL;
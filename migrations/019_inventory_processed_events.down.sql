@@ -0,0 +1 @@
+DROP TABLE IF EXISTS public.inventory_processed_events;
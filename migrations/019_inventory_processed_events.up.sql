@@ -0,0 +1,8 @@
+-- События заявок, уже обработанные складом. Отметка сохраняется в одной транзакции
+-- с изменением остатков, поэтому событие применяется один раз, даже если оно
+-- зафиксировано позже событий с большим ID. inventory_cursor теперь — позиция,
+-- до которой обработаны все события; отметки до неё удаляются
+CREATE TABLE IF NOT EXISTS public.inventory_processed_events (
+    order_event_id BIGINT PRIMARY KEY,
+    processed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
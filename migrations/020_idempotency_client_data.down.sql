@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS public.idx_idempotency_keys_client_phone_hash;
+ALTER TABLE public.idempotency_keys DROP COLUMN IF EXISTS client_phone_hash;
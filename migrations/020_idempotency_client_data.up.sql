@@ -0,0 +1,8 @@
+-- Тела сохранённых ответов шифруются ключом персональных данных; ответы, в которых есть
+-- данные клиента, помечаются слепым индексом его телефона и удаляются при обезличивании.
+-- Открыто сохранённые ответы удаляются: повтор запроса с их ключом выполнится заново
+ALTER TABLE public.idempotency_keys ADD COLUMN IF NOT EXISTS client_phone_hash TEXT;
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_client_phone_hash ON public.idempotency_keys(client_phone_hash)
+WHERE client_phone_hash IS NOT NULL;
+
+DELETE FROM public.idempotency_keys WHERE status_code <> 0;
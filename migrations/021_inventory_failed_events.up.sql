@@ -0,0 +1,9 @@
+-- Неудачные попытки применить событие заявки к складу. Такое событие остаётся
+-- необработанным, но повторяется после остальных, начиная с самой давней попытки,
+-- и не задерживает очередь
+CREATE TABLE IF NOT EXISTS public.inventory_failed_events (
+    order_event_id BIGINT PRIMARY KEY,
+    attempts INTEGER NOT NULL DEFAULT 1,
+    last_error TEXT NOT NULL DEFAULT '',
+    failed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
@@ -0,0 +1,2 @@
+-- Открытый телефон в client_phone_hash не возвращается: индекс, построенный приложением, остаётся
+SELECT 1;
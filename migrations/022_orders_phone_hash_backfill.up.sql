@@ -0,0 +1,7 @@
+-- Прежняя версия миграции 006 копировала в client_phone_hash открытый телефон.
+-- Такие значения удаляются; слепой индекс этих заявок строит приложение после миграций
+UPDATE public.orders SET client_phone_hash = NULL WHERE client_phone_hash = client_phone;
+
+UPDATE public.orders_archive
+SET data = data || jsonb_build_object('client_phone_hash', NULL)
+WHERE data->>'client_phone_hash' = data->>'client_phone';
@@ -0,0 +1,61 @@
+// Package migrations содержит миграции схемы БД и демонстрационные данные,
+// встроенные в бинарник. Один и тот же набор используется сервером и тестами.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sort"
+
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+//go:embed seed/*.sql
+var seedFiles embed.FS
+
+// Source возвращает источник миграций для golang-migrate
+func Source() (source.Driver, error) {
+	return iofs.New(files, ".")
+}
+
+// Seed заполняет БД демонстрационными данными. Выполняется только явно,
+// после применения миграций; скрипты идемпотентны
+func Seed(ctx context.Context, db *sql.DB) error {
+	names, err := fs.Glob(seedFiles, "seed/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		script, err := seedFiles.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if err := execInTx(ctx, db, string(script)); err != nil {
+			return fmt.Errorf("seed %s: %w", name, err)
+		}
+		slog.InfoContext(ctx, "seed applied", slog.String("file", name))
+	}
+	return nil
+}
+
+func execInTx(ctx context.Context, db *sql.DB, script string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
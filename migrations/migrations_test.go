@@ -0,0 +1,54 @@
+package migrations
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+func TestSource_Consistent(t *testing.T) {
+	src, err := Source()
+	if err != nil {
+		t.Fatalf("open source: %v", err)
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	if err != nil {
+		t.Fatalf("first version: %v", err)
+	}
+	if version != 1 {
+		t.Errorf("expected first version 1, got %d", version)
+	}
+
+	for {
+		if _, _, err := src.ReadUp(version); err != nil {
+			t.Errorf("version %d: missing up migration: %v", version, err)
+		}
+		if _, _, err := src.ReadDown(version); err != nil {
+			t.Errorf("version %d: missing down migration: %v", version, err)
+		}
+
+		next, err := src.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("next after %d: %v", version, err)
+		}
+		if next != version+1 {
+			t.Errorf("gap in versions: %d follows %d", next, version)
+		}
+		version = next
+	}
+}
+
+func TestSeed_Embedded(t *testing.T) {
+	names, err := fs.Glob(seedFiles, "seed/*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) == 0 {
+		t.Error("expected at least one seed script")
+	}
+}
@@ -0,0 +1,38 @@
+-- Демонстрационные данные для локального запуска. Повторный запуск ничего не меняет.
+-- Данные записываются открыто; при включённом шифровании их шифрует команда rotate-keys.
+-- Слепой индекс телефона строит приложение после загрузки
+INSERT INTO public.employees (id, name)
+VALUES (1, 'Петр Петров')
+ON CONFLICT (id) DO NOTHING;
+
+SELECT setval(
+    pg_get_serial_sequence('public.employees', 'id'),
+    GREATEST((SELECT MAX(id) FROM public.employees), 1)
+);
+
+INSERT INTO public.orders (
+    id,
+    client_name,
+    client_phone,
+    client_phone_hash,
+    address,
+    client_description,
+    employee_id,
+    cancel_reason,
+    status,
+    employee_description,
+    scheduled_for
+) VALUES (
+    '00000000-0000-4000-8000-000000000001',
+    'Тесть Тестя',
+    '+71234567890',
+    NULL,
+    'ул. Тестовая, д. 1',
+    'Что-то сломалось',
+    1,
+    NULL,
+    1,
+    NULL,
+    NULL
+)
+ON CONFLICT (id) DO NOTHING;
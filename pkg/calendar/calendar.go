@@ -0,0 +1,144 @@
+// Package calendar описывает рабочее время компании: рабочие дни недели, часы работы
+// и праздники. Используется для расчёта сроков в рабочем времени
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Дольше этого срок не ищется: защита от календаря, в котором почти не осталось рабочих дней
+const maxSearchDays = 366 * 5
+
+type Calendar struct {
+	loc       *time.Location
+	workStart time.Duration
+	workEnd   time.Duration
+	workdays  [7]bool
+	holidays  map[string]bool
+}
+
+// New создаёт календарь. Часы работы задаются смещением от полуночи в часовом поясе loc,
+// праздники — датами в формате YYYY-MM-DD
+func New(loc *time.Location, workStart, workEnd time.Duration, workdays []time.Weekday, holidays []string) (*Calendar, error) {
+	if workStart < 0 || workEnd > 24*time.Hour || workStart >= workEnd {
+		return nil, fmt.Errorf("calendar: invalid working hours %s-%s", workStart, workEnd)
+	}
+	if len(workdays) == 0 {
+		return nil, fmt.Errorf("calendar: no working days")
+	}
+
+	c := &Calendar{
+		loc:       loc,
+		workStart: workStart,
+		workEnd:   workEnd,
+		holidays:  make(map[string]bool, len(holidays)),
+	}
+	for _, d := range workdays {
+		c.workdays[d] = true
+	}
+	for _, h := range holidays {
+		if _, err := time.Parse(time.DateOnly, h); err != nil {
+			return nil, fmt.Errorf("calendar: invalid holiday %q, expected YYYY-MM-DD", h)
+		}
+		c.holidays[h] = true
+	}
+	return c, nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// ParseWeekdays разбирает дни недели, заданные сокращениями: mon, tue, ..., sun
+func ParseWeekdays(names []string) ([]time.Weekday, error) {
+	res := make([]time.Weekday, 0, len(names))
+	for _, name := range names {
+		d, ok := weekdays[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("calendar: unknown weekday %q, expected mon, tue, ..., sun", name)
+		}
+		res = append(res, d)
+	}
+	return res, nil
+}
+
+// Location — часовой пояс календаря
+func (c *Calendar) Location() *time.Location {
+	return c.loc
+}
+
+// In возвращает тот же календарь с рабочими часами в часовом поясе loc:
+// например, для заявки в другом регионе
+func (c *Calendar) In(loc *time.Location) *Calendar {
+	cp := *c
+	cp.loc = loc
+	return &cp
+}
+
+// IsWorkday сообщает, рабочий ли день, на который приходится t
+func (c *Calendar) IsWorkday(t time.Time) bool {
+	t = t.In(c.loc)
+	return c.workdays[t.Weekday()] && !c.holidays[t.Format(time.DateOnly)]
+}
+
+// IsWorkingTime сообщает, приходится ли t на рабочие часы рабочего дня
+func (c *Calendar) IsWorkingTime(t time.Time) bool {
+	if !c.IsWorkday(t) {
+		return false
+	}
+	start, end := c.hours(t)
+	return !t.Before(start) && t.Before(end)
+}
+
+// Начало и конец рабочих часов в день t
+func (c *Calendar) hours(t time.Time) (time.Time, time.Time) {
+	t = t.In(c.loc)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, c.loc)
+	return midnight.Add(c.workStart), midnight.Add(c.workEnd)
+}
+
+// AddWorkingTime возвращает момент, когда после t пройдёт d рабочего времени
+func (c *Calendar) AddWorkingTime(t time.Time, d time.Duration) time.Time {
+	day := t.In(c.loc)
+	for i := 0; i < maxSearchDays; i++ {
+		if c.IsWorkday(day) {
+			start, end := c.hours(day)
+			if t.After(start) {
+				start = t
+			}
+			if available := end.Sub(start); available > 0 {
+				if d <= available {
+					return start.Add(d)
+				}
+				d -= available
+			}
+		}
+		y, m, dd := day.Date()
+		day = time.Date(y, m, dd+1, 0, 0, 0, 0, c.loc)
+	}
+	return day
+}
+
+// AddWorkdays возвращает то же время суток, что у t, через n рабочих дней
+func (c *Calendar) AddWorkdays(t time.Time, n int) time.Time {
+	t = t.In(c.loc)
+	y, m, d := t.Date()
+	for i := 1; i <= maxSearchDays && n > 0; i++ {
+		next := time.Date(y, m, d+i, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), c.loc)
+		if c.IsWorkday(next) {
+			n--
+			if n == 0 {
+				return next
+			}
+		}
+	}
+	return t
+}
@@ -0,0 +1,125 @@
+package calendar_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/pkg/calendar"
+)
+
+// Пн–пт с 9 до 18, 2026-03-09 (понедельник) — праздник
+func newCalendar(t *testing.T) *calendar.Calendar {
+	t.Helper()
+	days, err := calendar.ParseWeekdays([]string{"mon", "tue", "wed", "thu", "fri"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := calendar.New(time.UTC, 9*time.Hour, 18*time.Hour, days, []string{"2026-03-09"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func at(day, hour, minute int) time.Time {
+	return time.Date(2026, 3, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestAddWorkingTime(t *testing.T) {
+	c := newCalendar(t)
+
+	cases := []struct {
+		name string
+		from time.Time
+		d    time.Duration
+		exp  time.Time
+	}{
+		{
+			name: "Срок внутри рабочего дня",
+			from: at(2, 10, 0),
+			d:    4 * time.Hour,
+			exp:  at(2, 14, 0),
+		},
+		{
+			name: "Срок переносится на следующий рабочий день",
+			from: at(2, 16, 0),
+			d:    4 * time.Hour,
+			exp:  at(3, 11, 0),
+		},
+		{
+			name: "Отсчёт от нерабочего времени начинается с утра",
+			from: at(2, 22, 30),
+			d:    time.Hour,
+			exp:  at(3, 10, 0),
+		},
+		{
+			name: "Выходные и праздник пропускаются",
+			from: at(6, 17, 0),
+			d:    2 * time.Hour,
+			exp:  at(10, 10, 0),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := c.AddWorkingTime(tc.from, tc.d); !got.Equal(tc.exp) {
+				t.Errorf("expected %s, got %s", tc.exp, got)
+			}
+		})
+	}
+}
+
+func TestAddWorkdays(t *testing.T) {
+	c := newCalendar(t)
+
+	// Пятница + 2 рабочих дня: понедельник — праздник, поэтому среда
+	if got, exp := c.AddWorkdays(at(6, 15, 0), 2), at(11, 15, 0); !got.Equal(exp) {
+		t.Errorf("expected %s, got %s", exp, got)
+	}
+}
+
+func TestIsWorkingTime(t *testing.T) {
+	c := newCalendar(t)
+
+	cases := map[time.Time]bool{
+		at(2, 9, 0):   true,
+		at(2, 18, 0):  false,
+		at(7, 12, 0):  false, // суббота
+		at(9, 12, 0):  false, // праздник
+		at(10, 8, 59): false,
+	}
+	for tm, exp := range cases {
+		if got := c.IsWorkingTime(tm); got != exp {
+			t.Errorf("%s: expected %v, got %v", tm, exp, got)
+		}
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	if _, err := calendar.New(time.UTC, 18*time.Hour, 9*time.Hour, []time.Weekday{time.Monday}, nil); err == nil {
+		t.Error("expected error for inverted working hours")
+	}
+	if _, err := calendar.New(time.UTC, 9*time.Hour, 18*time.Hour, []time.Weekday{time.Monday}, []string{"09.03.2026"}); err == nil {
+		t.Error("expected error for malformed holiday")
+	}
+	if _, err := calendar.ParseWeekdays([]string{"monday"}); err == nil {
+		t.Error("expected error for unknown weekday")
+	}
+}
+
+func TestIn(t *testing.T) {
+	c := newCalendar(t)
+	yekaterinburg := time.FixedZone("UTC+5", 5*60*60)
+
+	// 9:30 по Екатеринбургу — 4:30 UTC: рабочее время только для календаря в поясе UTC+5
+	at := time.Date(2026, 3, 2, 9, 30, 0, 0, yekaterinburg)
+	if c.IsWorkingTime(at) {
+		t.Errorf("%s must be outside working hours in UTC", at)
+	}
+	if !c.In(yekaterinburg).IsWorkingTime(at) {
+		t.Errorf("%s must be within working hours in UTC+5", at)
+	}
+	if c.Location() != time.UTC {
+		t.Error("In must not change the original calendar")
+	}
+}
@@ -0,0 +1,50 @@
+// Package clock отделяет получение текущего времени от бизнес-логики,
+// чтобы в тестах время можно было зафиксировать
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Источник текущего времени
+type Clock interface {
+	Now() time.Time
+}
+
+// System — системные часы
+type System struct{}
+
+func (System) Now() time.Time {
+	return time.Now()
+}
+
+// Manual — часы, которые идут только вручную. Используются в тестах
+type Manual struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewManual(now time.Time) *Manual {
+	return &Manual{now: now}
+}
+
+func (m *Manual) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.now
+}
+
+// Set переводит часы на момент t
+func (m *Manual) Set(t time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = t
+}
+
+// Advance переводит часы вперёд на d
+func (m *Manual) Advance(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = m.now.Add(d)
+}
@@ -0,0 +1,100 @@
+// Package ratelimit ограничивает частоту действий по алгоритму token bucket:
+// корзина вмещает Burst жетонов, каждое действие забирает один, а пополняется
+// корзина на один жетон каждые Every
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Частота: не больше Burst действий подряд, дальше — одно в Every.
+// Нулевой Burst снимает ограничение
+type Rate struct {
+	Burst int
+	Every time.Duration
+}
+
+func (r Rate) Unlimited() bool {
+	return r.Burst <= 0 || r.Every <= 0
+}
+
+// Хранилище корзин. Memory держит их в памяти процесса; если реплик несколько,
+// подключается общее хранилище (например, Redis) с той же семантикой Take
+type Store interface {
+	// Take забирает жетон из корзины key на момент now. Если корзина пуста,
+	// возвращает false и время, через которое появится следующий жетон
+	Take(ctx context.Context, key string, rate Rate, now time.Time) (bool, time.Duration, error)
+}
+
+// Раз в столько вызовов Take из Memory удаляются полные корзины
+const sweepEvery = 1024
+
+// Memory — хранилище корзин в памяти процесса
+type Memory struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	takes   int
+}
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+	// Момент, когда корзина снова наполнится: после него её можно забыть
+	full time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{buckets: make(map[string]*bucket)}
+}
+
+func (m *Memory) Take(_ context.Context, key string, rate Rate, now time.Time) (bool, time.Duration, error) {
+	if rate.Unlimited() {
+		return true, 0, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.takes++
+	if m.takes%sweepEvery == 0 {
+		m.sweep(now)
+	}
+
+	burst := float64(rate.Burst)
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, at: now}
+		m.buckets[key] = b
+	} else if elapsed := now.Sub(b.at); elapsed > 0 {
+		b.tokens = min(burst, b.tokens+float64(elapsed)/float64(rate.Every))
+		b.at = now
+	}
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	b.full = now.Add(time.Duration((burst - b.tokens) * float64(rate.Every)))
+
+	if !allowed {
+		return false, time.Duration((1 - b.tokens) * float64(rate.Every)), nil
+	}
+	return true, 0, nil
+}
+
+func (m *Memory) sweep(now time.Time) {
+	for key, b := range m.buckets {
+		if !now.Before(b.full) {
+			delete(m.buckets, key)
+		}
+	}
+}
+
+// Число хранимых корзин
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.buckets)
+}
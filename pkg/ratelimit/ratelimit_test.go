@@ -0,0 +1,76 @@
+package ratelimit_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/pkg/ratelimit"
+)
+
+func TestMemory_Take(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	rate := ratelimit.Rate{Burst: 2, Every: time.Minute}
+
+	steps := []struct {
+		name     string
+		key      string
+		at       time.Duration
+		expOK    bool
+		expRetry time.Duration
+	}{
+		{name: "Первый жетон", key: "a", at: 0, expOK: true},
+		{name: "Второй жетон", key: "a", at: 0, expOK: true},
+		{name: "Корзина пуста", key: "a", at: 30 * time.Second, expRetry: 30 * time.Second},
+		{name: "Другой ключ не затронут", key: "b", at: 30 * time.Second, expOK: true},
+		{name: "Жетон появился", key: "a", at: time.Minute, expOK: true},
+		{name: "Снова пуста", key: "a", at: time.Minute, expRetry: time.Minute},
+		{name: "Корзина не переполняется", key: "a", at: time.Hour, expOK: true},
+		{name: "Вторая после перерыва", key: "a", at: time.Hour, expOK: true},
+		{name: "Третья после перерыва", key: "a", at: time.Hour, expRetry: time.Minute},
+	}
+
+	store := ratelimit.NewMemory()
+	for _, step := range steps {
+		ok, retry, err := store.Take(ctx, step.key, rate, start.Add(step.at))
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if ok != step.expOK || retry != step.expRetry {
+			t.Errorf("%s: expected %v (retry %v), got %v (retry %v)", step.name, step.expOK, step.expRetry, ok, retry)
+		}
+	}
+}
+
+func TestMemory_Unlimited(t *testing.T) {
+	store := ratelimit.NewMemory()
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		if ok, _, _ := store.Take(context.Background(), "a", ratelimit.Rate{}, now); !ok {
+			t.Fatal("expected zero rate to be unlimited")
+		}
+	}
+	if store.Len() != 0 {
+		t.Errorf("expected no buckets for unlimited rate, got %d", store.Len())
+	}
+}
+
+func TestMemory_Sweep(t *testing.T) {
+	ctx := context.Background()
+	store := ratelimit.NewMemory()
+	rate := ratelimit.Rate{Burst: 1, Every: time.Minute}
+	start := time.Now()
+
+	for i := 0; i < 1000; i++ {
+		store.Take(ctx, fmt.Sprint(i), rate, start)
+	}
+	// Через минуту все корзины полны и удаляются при очередной чистке
+	for i := 0; i < 100; i++ {
+		store.Take(ctx, "fresh", rate, start.Add(2*time.Minute))
+	}
+	if store.Len() != 1 {
+		t.Errorf("expected only the fresh bucket after sweep, got %d", store.Len())
+	}
+}
@@ -0,0 +1,294 @@
+// Package routing строит порядок объезда точек за рабочий день: эвристикой ближайшего соседа
+// с последующим улучшением 2-opt. Расстояния считаются по прямой с поправочным коэффициентом
+// на дороги, поэтому пакет не зависит от внешних сервисов; его можно заменить настоящим
+// движком маршрутизации, реализовав Solver
+package routing
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+)
+
+// Географические координаты (WGS 84)
+type Point struct {
+	Lat float64
+	Lon float64
+}
+
+// Допустимое время прибытия. Нулевая граница не ограничивает
+type Window struct {
+	From time.Time
+	To   time.Time
+}
+
+// Точка маршрута
+type Stop struct {
+	ID       string
+	Location Point
+	Window   Window
+	// Время работы на точке; 0 — Options.StopDuration
+	Duration time.Duration
+}
+
+type Options struct {
+	// Откуда выезжает сотрудник; nil — маршрут начинается с первой точки
+	Start *Point
+	// Время выезда (или прибытия на первую точку, если Start не задан)
+	StartAt time.Time
+	// Средняя скорость в пути, км/ч
+	SpeedKmh float64
+	// Во сколько раз путь по дорогам длиннее прямой
+	DetourFactor float64
+	// Время работы на точке по умолчанию
+	StopDuration time.Duration
+}
+
+// Точка в построенном маршруте
+type Visit struct {
+	Stop
+	// Путь от предыдущей точки (или от Options.Start)
+	DistanceM float64
+	Travel    time.Duration
+	Arrival   time.Time
+	// Начало работ: прибытие или, если приехали раньше окна, его начало
+	StartAt   time.Time
+	Departure time.Time
+	// Прибытие позже окна
+	Late bool
+}
+
+type Plan struct {
+	Visits    []Visit
+	DistanceM float64
+	Travel    time.Duration
+	// Число точек, куда сотрудник не успевает к окну
+	Late int
+}
+
+// Построитель маршрута
+type Solver interface {
+	Solve(ctx context.Context, stops []Stop, opts Options) (*Plan, error)
+}
+
+var ErrInvalidOptions = errors.New("routing: speed and detour factor must be positive")
+
+// Heuristic — ближайший сосед и 2-opt. Результат детерминирован: при равной стоимости
+// выбирается точка, раньше стоящая во входном списке
+type Heuristic struct{}
+
+func (Heuristic) Solve(ctx context.Context, stops []Stop, opts Options) (*Plan, error) {
+	if opts.SpeedKmh <= 0 || opts.DetourFactor <= 0 {
+		return nil, ErrInvalidOptions
+	}
+	if len(stops) == 0 {
+		return &Plan{Visits: []Visit{}}, nil
+	}
+
+	p := &problem{stops: stops, opts: opts}
+	best := p.nearestNeighbour(-1)
+	if opts.Start == nil {
+		// Без точки выезда начать можно с любой точки: пробуем все
+		for first := 1; first < len(stops); first++ {
+			if order := p.nearestNeighbour(first); p.cost(order).less(p.cost(best)) {
+				best = order
+			}
+		}
+	}
+
+	best, err := p.twoOpt(ctx, best)
+	if err != nil {
+		return nil, err
+	}
+	return p.plan(best), nil
+}
+
+type problem struct {
+	stops []Stop
+	opts  Options
+}
+
+// Стоимость маршрута: сначала суммарное опоздание, затем окончание рабочего дня,
+// затем время в пути
+type cost struct {
+	lateness time.Duration
+	finish   time.Time
+	travel   time.Duration
+}
+
+func (c cost) less(o cost) bool {
+	if c.lateness != o.lateness {
+		return c.lateness < o.lateness
+	}
+	if !c.finish.Equal(o.finish) {
+		return c.finish.Before(o.finish)
+	}
+	return c.travel < o.travel
+}
+
+// Путь от точки from (nil — начало маршрута без точки выезда) до точки to
+func (p *problem) leg(from *Point, to Point) (float64, time.Duration) {
+	if from == nil {
+		return 0, 0
+	}
+	d := Distance(*from, to) * p.opts.DetourFactor
+	return d, time.Duration(d / (p.opts.SpeedKmh / 3.6) * float64(time.Second)).Round(time.Second)
+}
+
+func (p *problem) duration(s Stop) time.Duration {
+	if s.Duration > 0 {
+		return s.Duration
+	}
+	return p.opts.StopDuration
+}
+
+// Прибыть в точку s к моменту arrival: когда начнутся и закончатся работы и насколько опоздали
+func (p *problem) serve(s Stop, arrival time.Time) (start, departure time.Time, late time.Duration) {
+	start = arrival
+	if !s.Window.From.IsZero() && start.Before(s.Window.From) {
+		start = s.Window.From
+	}
+	if !s.Window.To.IsZero() && arrival.After(s.Window.To) {
+		late = arrival.Sub(s.Window.To)
+	}
+	return start, start.Add(p.duration(s)), late
+}
+
+// Жадный маршрут: из текущей точки едем туда, где раньше всего сможем начать работы,
+// не опоздав к окну; если успеть никуда нельзя — туда, где окно закрывается раньше.
+// first >= 0 задаёт первую точку
+func (p *problem) nearestNeighbour(first int) []int {
+	order := make([]int, 0, len(p.stops))
+	visited := make([]bool, len(p.stops))
+	pos, now := p.opts.Start, p.opts.StartAt
+
+	visit := func(i int) {
+		_, travel := p.leg(pos, p.stops[i].Location)
+		_, departure, _ := p.serve(p.stops[i], now.Add(travel))
+		order = append(order, i)
+		visited[i] = true
+		pos, now = &p.stops[i].Location, departure
+	}
+	if first >= 0 {
+		visit(first)
+	}
+
+	for len(order) < len(p.stops) {
+		next, urgent := -1, -1
+		var nextStart time.Time
+		var nextTravel time.Duration
+		for i, s := range p.stops {
+			if visited[i] {
+				continue
+			}
+			_, travel := p.leg(pos, s.Location)
+			start, _, late := p.serve(s, now.Add(travel))
+			if late == 0 && (next < 0 || start.Before(nextStart) || (start.Equal(nextStart) && travel < nextTravel)) {
+				next, nextStart, nextTravel = i, start, travel
+			}
+			if urgent < 0 || closesBefore(s.Window, p.stops[urgent].Window) {
+				urgent = i
+			}
+		}
+		if next < 0 {
+			next = urgent
+		}
+		visit(next)
+	}
+	return order
+}
+
+// Окно a закрывается раньше окна b; окно без конца — позже любого
+func closesBefore(a, b Window) bool {
+	if a.To.IsZero() {
+		return false
+	}
+	return b.To.IsZero() || a.To.Before(b.To)
+}
+
+// Улучшение 2-opt: разворачиваем участки маршрута, пока это уменьшает стоимость
+func (p *problem) twoOpt(ctx context.Context, order []int) ([]int, error) {
+	best := p.cost(order)
+	for improved := true; improved; {
+		improved = false
+		for i := 0; i < len(order)-1; i++ {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			for j := i + 1; j < len(order); j++ {
+				reverse(order, i, j)
+				if c := p.cost(order); c.less(best) {
+					best, improved = c, true
+					continue
+				}
+				reverse(order, i, j)
+			}
+		}
+	}
+	return order, nil
+}
+
+func reverse(order []int, i, j int) {
+	for ; i < j; i, j = i+1, j-1 {
+		order[i], order[j] = order[j], order[i]
+	}
+}
+
+func (p *problem) cost(order []int) cost {
+	var c cost
+	p.walk(order, func(v Visit, late time.Duration) {
+		c.lateness += late
+		c.finish = v.Departure
+		c.travel += v.Travel
+	})
+	return c
+}
+
+func (p *problem) plan(order []int) *Plan {
+	plan := &Plan{Visits: make([]Visit, 0, len(order))}
+	p.walk(order, func(v Visit, late time.Duration) {
+		plan.Visits = append(plan.Visits, v)
+		plan.DistanceM += v.DistanceM
+		plan.Travel += v.Travel
+		if v.Late {
+			plan.Late++
+		}
+	})
+	return plan
+}
+
+// Пройти маршрут по порядку, рассчитывая время прибытия в каждую точку
+func (p *problem) walk(order []int, fn func(v Visit, late time.Duration)) {
+	pos, now := p.opts.Start, p.opts.StartAt
+	for _, i := range order {
+		s := p.stops[i]
+		v := Visit{Stop: s}
+		v.DistanceM, v.Travel = p.leg(pos, s.Location)
+		v.Arrival = now.Add(v.Travel)
+		// Без точки выезда к первой точке приезжают сразу к началу её окна
+		if pos == nil && v.Arrival.Before(s.Window.From) {
+			v.Arrival = s.Window.From
+		}
+
+		var late time.Duration
+		v.StartAt, v.Departure, late = p.serve(s, v.Arrival)
+		v.Late = late > 0
+		fn(v, late)
+
+		pos, now = &p.stops[i].Location, v.Departure
+	}
+}
+
+// Средний радиус Земли, м
+const earthRadius = 6371000
+
+// Расстояние по поверхности Земли (формула гаверсинусов), м
+func Distance(p, q Point) float64 {
+	lat1, lat2 := p.Lat*math.Pi/180, q.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (q.Lon - p.Lon) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
@@ -0,0 +1,216 @@
+package routing_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Owouwun/spkuznetsov/pkg/routing"
+)
+
+var morning = time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+// 0.01° широты — около 1112 м; при 40 км/ч и без поправки на дороги это 100 секунд пути
+var baseOptions = routing.Options{
+	StartAt:      morning,
+	SpeedKmh:     40,
+	DetourFactor: 1,
+	StopDuration: 30 * time.Minute,
+}
+
+func stop(id string, lat float64) routing.Stop {
+	return routing.Stop{ID: id, Location: routing.Point{Lat: lat, Lon: 37}}
+}
+
+func ids(plan *routing.Plan) []string {
+	res := make([]string, 0, len(plan.Visits))
+	for _, v := range plan.Visits {
+		res = append(res, v.ID)
+	}
+	return res
+}
+
+func TestHeuristic_Order(t *testing.T) {
+	start := routing.Point{Lat: 55, Lon: 37}
+	withStart := baseOptions
+	withStart.Start = &start
+
+	windowed := stop("far-early", 55.05)
+	windowed.Window = routing.Window{From: morning, To: morning.Add(15 * time.Minute)}
+
+	afternoon := stop("near-afternoon", 55.01)
+	afternoon.Window = routing.Window{From: morning.Add(5 * time.Hour)}
+
+	cases := []struct {
+		name     string
+		stops    []routing.Stop
+		opts     routing.Options
+		expOrder []string
+		expLate  int
+	}{
+		{
+			name:     "Точки на одной линии объезжаются по порядку от точки выезда",
+			stops:    []routing.Stop{stop("c", 55.03), stop("a", 55.01), stop("d", 55.04), stop("b", 55.02)},
+			opts:     withStart,
+			expOrder: []string{"a", "b", "c", "d"},
+		},
+		{
+			name:     "Без точки выезда маршрут начинается с края",
+			stops:    []routing.Stop{stop("b", 55.02), stop("d", 55.04), stop("a", 55.01), stop("c", 55.03)},
+			opts:     baseOptions,
+			expOrder: []string{"d", "c", "b", "a"},
+		},
+		{
+			name:     "Точка с ранним окном посещается первой, хотя она дальше",
+			stops:    []routing.Stop{stop("near", 55.01), windowed},
+			opts:     withStart,
+			expOrder: []string{"far-early", "near"},
+		},
+		{
+			name:     "Точка с дневным окном откладывается, хотя она ближе",
+			stops:    []routing.Stop{afternoon, stop("far", 55.02)},
+			opts:     withStart,
+			expOrder: []string{"far", "near-afternoon"},
+		},
+		{
+			name: "Опоздание, если к окну не успеть",
+			stops: []routing.Stop{{
+				ID:       "too-far",
+				Location: routing.Point{Lat: 56, Lon: 37},
+				Window:   routing.Window{To: morning.Add(time.Hour)},
+			}},
+			opts:     withStart,
+			expOrder: []string{"too-far"},
+			expLate:  1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			plan, err := routing.Heuristic{}.Solve(context.Background(), c.stops, c.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := ids(plan); !reflect.DeepEqual(c.expOrder, got) {
+				t.Errorf("expected order %v, got %v", c.expOrder, got)
+			}
+			if plan.Late != c.expLate {
+				t.Errorf("expected %d late stops, got %d", c.expLate, plan.Late)
+			}
+		})
+	}
+}
+
+func TestHeuristic_Times(t *testing.T) {
+	start := routing.Point{Lat: 55, Lon: 37}
+	opts := baseOptions
+	opts.Start = &start
+
+	early := stop("b", 55.02)
+	early.Window = routing.Window{From: morning.Add(time.Hour)}
+
+	plan, err := routing.Heuristic{}.Solve(context.Background(), []routing.Stop{stop("a", 55.01), early}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, b := plan.Visits[0], plan.Visits[1]
+	if a.Travel != 100*time.Second || !a.Arrival.Equal(morning.Add(100*time.Second)) {
+		t.Errorf("expected 100s to the first stop, got %s (arrival %s)", a.Travel, a.Arrival)
+	}
+	if !a.Departure.Equal(a.Arrival.Add(30 * time.Minute)) {
+		t.Errorf("expected departure after the default stop duration, got %s", a.Departure)
+	}
+	if b.Travel != 100*time.Second || !b.StartAt.Equal(morning.Add(time.Hour)) {
+		t.Errorf("expected to wait for the window of the second stop, got start %s", b.StartAt)
+	}
+	if plan.Travel != 200*time.Second || plan.DistanceM < 2200 || plan.DistanceM > 2250 {
+		t.Errorf("unexpected totals: %s, %.0f m", plan.Travel, plan.DistanceM)
+	}
+}
+
+// На небольшом наборе точек эвристика отстаёт от оптимального по времени в пути маршрута не более чем на 10%
+func TestHeuristic_MatchesBruteForce(t *testing.T) {
+	stops := []routing.Stop{
+		{ID: "1", Location: routing.Point{Lat: 55.70, Lon: 37.50}},
+		{ID: "2", Location: routing.Point{Lat: 55.80, Lon: 37.70}},
+		{ID: "3", Location: routing.Point{Lat: 55.72, Lon: 37.68}},
+		{ID: "4", Location: routing.Point{Lat: 55.78, Lon: 37.52}},
+		{ID: "5", Location: routing.Point{Lat: 55.75, Lon: 37.60}},
+		{ID: "6", Location: routing.Point{Lat: 55.69, Lon: 37.61}},
+	}
+	start := routing.Point{Lat: 55.76, Lon: 37.45}
+	opts := baseOptions
+	opts.Start = &start
+
+	plan, err := routing.Heuristic{}.Solve(context.Background(), stops, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	best := time.Duration(-1)
+	permute(stops, 0, func(perm []routing.Stop) {
+		// Путь по перестановке складывается из маршрутов в одну точку
+		p, _ := routing.Heuristic{}.Solve(context.Background(), perm[:1], opts)
+		travel := p.Travel
+		prev := perm[0].Location
+		for _, s := range perm[1:] {
+			from := prev
+			o := opts
+			o.Start = &from
+			leg, _ := routing.Heuristic{}.Solve(context.Background(), []routing.Stop{s}, o)
+			travel += leg.Travel
+			prev = s.Location
+		}
+		if best < 0 || travel < best {
+			best = travel
+		}
+	})
+
+	if plan.Travel > best+best/10 {
+		t.Errorf("expected travel close to optimal %s, got %s (%v)", best, plan.Travel, ids(plan))
+	}
+}
+
+func permute(s []routing.Stop, k int, fn func([]routing.Stop)) {
+	if k == len(s) {
+		fn(s)
+		return
+	}
+	for i := k; i < len(s); i++ {
+		s[k], s[i] = s[i], s[k]
+		permute(s, k+1, fn)
+		s[k], s[i] = s[i], s[k]
+	}
+}
+
+func TestHeuristic_InvalidOptions(t *testing.T) {
+	_, err := routing.Heuristic{}.Solve(context.Background(), []routing.Stop{stop("a", 55)}, routing.Options{})
+	if !errors.Is(err, routing.ErrInvalidOptions) {
+		t.Errorf("expected ErrInvalidOptions, got %v", err)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	cases := []struct {
+		name string
+		p, q routing.Point
+		exp  float64
+	}{
+		{name: "Одна точка", p: routing.Point{Lat: 55.75, Lon: 37.62}, q: routing.Point{Lat: 55.75, Lon: 37.62}, exp: 0},
+		{name: "0.01° широты", p: routing.Point{Lat: 55, Lon: 37}, q: routing.Point{Lat: 55.01, Lon: 37}, exp: 1112},
+		{name: "Москва — Санкт-Петербург", p: routing.Point{Lat: 55.7558, Lon: 37.6173}, q: routing.Point{Lat: 59.9343, Lon: 30.3351}, exp: 634000},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d := routing.Distance(c.p, c.q)
+			if math.Abs(d-c.exp) > c.exp*0.005+1 {
+				t.Errorf("expected about %.0f m, got %.0f m", c.exp, d)
+			}
+		})
+	}
+}
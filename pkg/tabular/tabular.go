@@ -0,0 +1,211 @@
+// Package tabular читает и пишет таблицы в CSV и XLSX построчно, чтобы выгрузки
+// не собирались в памяти целиком
+package tabular
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+type Format string
+
+const (
+	CSV  Format = "csv"
+	XLSX Format = "xlsx"
+)
+
+var ErrUnknownFormat = errors.New("unknown table format, expected csv or xlsx")
+
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case CSV, XLSX:
+		return f, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+func (f Format) ContentType() string {
+	if f == XLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Лист, на который пишется и с которого по умолчанию читается таблица
+const sheet = "Sheet1"
+
+// BOM в начале CSV нужен Excel, чтобы открыть файл в UTF-8
+const bom = "\ufeff"
+
+// Символы, с которых Excel и LibreOffice начинают формулу
+const formulaPrefixes = "=+-@\t\r"
+
+// Число вроде +79161234567 или -12.5: его редактор не считает формулой
+var plainNumber = regexp.MustCompile(`^[+-]?[0-9]+([.,][0-9]+)?$`)
+
+// EscapeFormula защищает ячейку от выполнения как формулы при открытии таблицы
+// в редакторе: значение, начинающееся с символа формулы, предваряется апострофом
+func EscapeFormula(v string) string {
+	if v == "" || !strings.ContainsRune(formulaPrefixes, rune(v[0])) || plainNumber.MatchString(v) {
+		return v
+	}
+	return "'" + v
+}
+
+// Обратное EscapeFormula: выгруженная таблица загружается с исходными значениями
+func unescapeFormula(v string) string {
+	if len(v) > 1 && v[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(v[1])) {
+		return v[1:]
+	}
+	return v
+}
+
+// Построчная запись таблицы. Ячейки, похожие на формулы, экранируются (см. EscapeFormula).
+// Close дописывает таблицу; без него файл неполон.
+// Abort освобождает ресурсы, не дописывая таблицу, если запись прервана
+type Writer interface {
+	Write(row []string) error
+	Close() error
+	Abort()
+}
+
+func NewWriter(w io.Writer, f Format) (Writer, error) {
+	switch f {
+	case CSV:
+		// BOM остаётся в буфере вместе с первыми строками: пока буфер не заполнен, в w ничего не пишется
+		buf := bufio.NewWriter(w)
+		_, _ = buf.WriteString(bom)
+		return &csvWriter{w: csv.NewWriter(buf)}, nil
+	case XLSX:
+		file := excelize.NewFile()
+		sw, err := file.NewStreamWriter(sheet)
+		if err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+		return &xlsxWriter{out: w, file: file, sw: sw}, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvWriter) Write(row []string) error {
+	escaped := make([]string, len(row))
+	for i, v := range row {
+		escaped[i] = EscapeFormula(v)
+	}
+	return c.w.Write(escaped)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Abort() {}
+
+// XLSX — zip-архив, поэтому отдаётся только после записи всех строк. Строки копятся
+// во временном файле excelize, в памяти держится лишь их небольшой буфер
+type xlsxWriter struct {
+	out  io.Writer
+	file *excelize.File
+	sw   *excelize.StreamWriter
+	rows int
+}
+
+func (x *xlsxWriter) Write(row []string) error {
+	x.rows++
+	cell, err := excelize.CoordinatesToCellName(1, x.rows)
+	if err != nil {
+		return err
+	}
+	values := make([]any, len(row))
+	for i, v := range row {
+		values[i] = EscapeFormula(v)
+	}
+	return x.sw.SetRow(cell, values)
+}
+
+func (x *xlsxWriter) Close() error {
+	defer x.file.Close()
+	if err := x.sw.Flush(); err != nil {
+		return err
+	}
+	_, err := x.file.WriteTo(x.out)
+	return err
+}
+
+func (x *xlsxWriter) Abort() {
+	_ = x.file.Close()
+}
+
+// ReadAll читает строки таблицы: XLSX (первый лист) распознаётся по сигнатуре zip,
+// остальное читается как CSV с разделителем «,» или «;», как его сохраняет русский Excel.
+// Строки могут быть разной длины; экранирование формул снимается
+func ReadAll(data []byte) ([][]string, error) {
+	read := readCSV
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		read = readXLSX
+	}
+	rows, err := read(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		for i, v := range row {
+			row[i] = unescapeFormula(v)
+		}
+	}
+	return rows, nil
+}
+
+func readCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte(bom))
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.Comma = delimiter(data)
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv: %w", err)
+	}
+	return rows, nil
+}
+
+// Разделитель определяется по первой строке: тот из «,» и «;», которого в ней больше
+func delimiter(data []byte) rune {
+	header, _, _ := bytes.Cut(data, []byte("\n"))
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		return ';'
+	}
+	return ','
+}
+
+func readXLSX(data []byte) ([][]string, error) {
+	file, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid xlsx: %w", err)
+	}
+	defer file.Close()
+
+	sheets := file.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, nil
+	}
+	rows, err := file.GetRows(sheets[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid xlsx: %w", err)
+	}
+	return rows, nil
+}
@@ -0,0 +1,145 @@
+package tabular_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Owouwun/spkuznetsov/pkg/tabular"
+)
+
+func TestWriteRead(t *testing.T) {
+	rows := [][]string{
+		{"client_name", "client_phone", "address"},
+		{"Иван", "+79161234567", "Москва, ул. Тверская, 1"},
+		{"=HYPERLINK(\"http://evil.example\")", "-1+1", "@SUM(A1)"},
+		{"Пётр \"Мастер\"", "89161234567", ""},
+	}
+
+	for _, format := range []tabular.Format{tabular.CSV, tabular.XLSX} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := tabular.NewWriter(&buf, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, row := range rows {
+				if err := w.Write(row); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// До Close небольшая таблица не пишется: ответ ещё можно заменить ошибкой
+			if buf.Len() != 0 {
+				t.Errorf("expected nothing written before close, got %d bytes", buf.Len())
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := tabular.ReadAll(buf.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			// XLSX не хранит пустые ячейки в конце строки
+			if format == tabular.XLSX {
+				got[3] = append(got[3], "")
+			}
+			if !reflect.DeepEqual(got, rows) {
+				t.Errorf("expected %q, got %q", rows, got)
+			}
+		})
+	}
+}
+
+func TestEscapeFormula(t *testing.T) {
+	cases := []struct {
+		name  string
+		value string
+		exp   string
+	}{
+		{name: "Обычный текст", value: "Иван", exp: "Иван"},
+		{name: "Пустая ячейка", value: "", exp: ""},
+		{name: "Формула", value: "=1+1", exp: "'=1+1"},
+		{name: "Формула с плюсом", value: "+cmd|' /C calc'!A0", exp: "'+cmd|' /C calc'!A0"},
+		{name: "Формула с минусом", value: "-2+3", exp: "'-2+3"},
+		{name: "Функция через @", value: "@SUM(A1:A2)", exp: "'@SUM(A1:A2)"},
+		{name: "Табуляция", value: "\t=1", exp: "'\t=1"},
+		{name: "Перевод каретки", value: "\r=1", exp: "'\r=1"},
+		{name: "Телефон", value: "+79161234567", exp: "+79161234567"},
+		{name: "Отрицательное число", value: "-37,62", exp: "-37,62"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tabular.EscapeFormula(tc.value); got != tc.exp {
+				t.Errorf("expected %q, got %q", tc.exp, got)
+			}
+		})
+	}
+}
+
+func TestWrite_EscapesFormulas(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := tabular.NewWriter(&buf, tabular.CSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write([]string{"=HYPERLINK(\"http://evil.example\")", "+79161234567"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := "\ufeff\"'=HYPERLINK(\"\"http://evil.example\"\")\",+79161234567\n"
+	if buf.String() != exp {
+		t.Errorf("expected %q, got %q", exp, buf.String())
+	}
+}
+
+func TestReadAll_CSV(t *testing.T) {
+	cases := []struct {
+		name   string
+		data   string
+		exp    [][]string
+		expErr bool
+	}{
+		{
+			name: "Точка с запятой, как сохраняет русский Excel",
+			data: "client_name;address\r\nИван;Москва, ул. Тверская, 1\r\n",
+			exp:  [][]string{{"client_name", "address"}, {"Иван", "Москва, ул. Тверская, 1"}},
+		},
+		{
+			name: "BOM и строки разной длины",
+			data: "\ufeffa,b,c\n1,2\n",
+			exp:  [][]string{{"a", "b", "c"}, {"1", "2"}},
+		},
+		{
+			name:   "Незакрытая кавычка",
+			data:   "a,b\n\"1,2\n",
+			expErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tabular.ReadAll([]byte(tc.data))
+			if (err != nil) != tc.expErr {
+				t.Fatalf("expected error %v, got %v", tc.expErr, err)
+			}
+			if !tc.expErr && !reflect.DeepEqual(got, tc.exp) {
+				t.Errorf("expected %q, got %q", tc.exp, got)
+			}
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := tabular.ParseFormat("XLSX"); err != nil || f != tabular.XLSX {
+		t.Errorf("expected xlsx, got %q, %v", f, err)
+	}
+	if _, err := tabular.ParseFormat("xls"); !errors.Is(err, tabular.ErrUnknownFormat) {
+		t.Errorf("expected ErrUnknownFormat, got %v", err)
+	}
+}
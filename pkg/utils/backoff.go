@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff вычисляет задержки между повторными попытками: экспоненциальный рост от Min до Max
+// со случайным разбросом, чтобы несколько реплик не повторяли попытки одновременно
+type Backoff struct {
+	Min     time.Duration
+	Max     time.Duration
+	attempt int
+}
+
+func NewBackoff(minDelay, maxDelay time.Duration) *Backoff {
+	return &Backoff{Min: minDelay, Max: maxDelay}
+}
+
+// Next возвращает задержку перед следующей попыткой: случайное значение из [d/2, d],
+// где d = Min·2^n, но не больше Max
+func (b *Backoff) Next() time.Duration {
+	d := b.Min
+	for i := 0; i < b.attempt && d < b.Max; i++ {
+		d *= 2
+	}
+	if d > b.Max {
+		d = b.Max
+	}
+	b.attempt++
+
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + rand.N(d-half+1)
+}
+
+// Reset возвращает задержку к начальной после успешной попытки
+func (b *Backoff) Reset() {
+	b.attempt = 0
+}
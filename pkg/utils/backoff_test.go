@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	b := NewBackoff(100*time.Millisecond, time.Second)
+
+	cases := []struct {
+		name   string
+		expMin time.Duration
+		expMax time.Duration
+	}{
+		{name: "Первая попытка", expMin: 50 * time.Millisecond, expMax: 100 * time.Millisecond},
+		{name: "Удвоение", expMin: 100 * time.Millisecond, expMax: 200 * time.Millisecond},
+		{name: "Ещё удвоение", expMin: 200 * time.Millisecond, expMax: 400 * time.Millisecond},
+		{name: "Рост продолжается", expMin: 400 * time.Millisecond, expMax: 800 * time.Millisecond},
+		{name: "Ограничение сверху", expMin: 500 * time.Millisecond, expMax: time.Second},
+		{name: "Ограничение сохраняется", expMin: 500 * time.Millisecond, expMax: time.Second},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if d := b.Next(); d < tc.expMin || d > tc.expMax {
+				t.Errorf("expected delay in [%s, %s], got %s", tc.expMin, tc.expMax, d)
+			}
+		})
+	}
+
+	b.Reset()
+	if d := b.Next(); d > 100*time.Millisecond {
+		t.Errorf("expected initial delay after reset, got %s", d)
+	}
+}
@@ -5,8 +5,9 @@ import (
 	"time"
 )
 
-func MustNotPast(date *time.Time) error {
-	if date.Before(time.Now()) {
+// MustNotPast проверяет, что дата не раньше момента now
+func MustNotPast(date *time.Time, now time.Time) error {
+	if date.Before(now) {
 		return errors.New("can't be past")
 	}
 	return nil
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+
+	"golang.org/x/image/draw"
+)
+
+// Превью не должно быть крупнее оригинала, а оригинал — неразумно большим для декодирования в память
+const maxThumbnailSourcePixels = 50_000_000
+
+// Thumbnail уменьшает изображение JPEG или PNG так, чтобы большая сторона не превышала size,
+// и возвращает результат в JPEG
+func Thumbnail(r io.Reader, size int) ([]byte, error) {
+	if size <= 0 {
+		return nil, errors.New("thumbnail size must be positive")
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width*cfg.Height > maxThumbnailSourcePixels {
+		return nil, errors.New("image is too large for a thumbnail")
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	w, h := thumbnailBounds(src.Bounds().Dx(), src.Bounds().Dy(), size)
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	// JPEG не поддерживает прозрачность: прозрачные области PNG становятся белыми
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func thumbnailBounds(w, h, size int) (int, int) {
+	if w <= size && h <= size {
+		return w, h
+	}
+	if w >= h {
+		return size, max(1, h*size/w)
+	}
+	return max(1, w*size/h), size
+}
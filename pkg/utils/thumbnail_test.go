@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestThumbnail(t *testing.T) {
+	cases := []struct {
+		name       string
+		width      int
+		height     int
+		expW, expH int
+	}{
+		{name: "Горизонтальное изображение", width: 640, height: 480, expW: 320, expH: 240},
+		{name: "Вертикальное изображение", width: 300, height: 900, expW: 106, expH: 320},
+		{name: "Маленькое изображение не увеличивается", width: 100, height: 50, expW: 100, expH: 50},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			img := image.NewRGBA(image.Rect(0, 0, tc.width, tc.height))
+			img.Set(0, 0, color.Black)
+			var src bytes.Buffer
+			if err := png.Encode(&src, img); err != nil {
+				t.Fatal(err)
+			}
+
+			thumb, err := Thumbnail(&src, 320)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := jpeg.DecodeConfig(bytes.NewReader(thumb))
+			if err != nil {
+				t.Fatalf("thumbnail is not a JPEG: %v", err)
+			}
+			if cfg.Width != tc.expW || cfg.Height != tc.expH {
+				t.Errorf("expected %dx%d, got %dx%d", tc.expW, tc.expH, cfg.Width, cfg.Height)
+			}
+		})
+	}
+
+	if _, err := Thumbnail(bytes.NewReader([]byte("%PDF-1.4")), 320); err == nil {
+		t.Errorf("expected an error for a non-image")
+	}
+}